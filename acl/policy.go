@@ -451,6 +451,30 @@ func NewPolicyFromSource(id string, revision uint64, rules string, syntax Syntax
 	return policy, err
 }
 
+// KeyListWarnings returns a warning for each key rule whose meaning depends on
+// whether acl.enable_key_list_policy is set. A "read" key_prefix rule allows
+// listing the keys under the prefix only while it isn't set, and a "list"
+// key rule only applies to listing with that exact prefix.
+func (policy *Policy) KeyListWarnings() []string {
+	var warnings []string
+	for _, kp := range policy.KeyPrefixes {
+		if kp.Policy == PolicyRead {
+			warnings = append(warnings, fmt.Sprintf("key_prefix %q grants %q, which "+
+				"doesn't allow listing the keys under it if the servers enable "+
+				"acl.enable_key_list_policy. If they do, use %q to allow listing them.",
+				kp.Prefix, PolicyRead, PolicyList))
+		}
+	}
+	for _, kp := range policy.Keys {
+		if kp.Policy == PolicyList {
+			warnings = append(warnings, fmt.Sprintf("key %q grants %q, which only "+
+				"allows listing with that exact prefix. Use key_prefix to allow "+
+				"listing the keys under it.", kp.Prefix, PolicyList))
+		}
+	}
+	return warnings
+}
+
 func (policy *Policy) ConvertToLegacy() *Policy {
 	converted := &Policy{
 		ID:       policy.ID,
@@ -554,6 +554,40 @@ func TestPolicySourceParse(t *testing.T) {
 	}
 }
 
+func TestPolicy_KeyListWarnings(t *testing.T) {
+	rules := `
+key "foo" {
+	policy = "list"
+}
+key "bar" {
+	policy = "read"
+}
+key_prefix "secrets/" {
+	policy = "read"
+}
+key_prefix "public/" {
+	policy = "list"
+}
+key_prefix "app/" {
+	policy = "write"
+}`
+	p, err := NewPolicyFromSource("", 0, rules, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	warnings := p.KeyListWarnings()
+	require.Len(t, warnings, 2)
+	require.Contains(t, warnings[0], `key_prefix "secrets/" grants "read"`)
+	require.Contains(t, warnings[1], `key "foo" grants "list"`)
+
+	// Legacy key rules are prefixes.
+	p, err = NewPolicyFromSource("", 0, `key "secrets/" { policy = "read" }`, SyntaxLegacy, nil)
+	require.NoError(t, err)
+	require.Len(t, p.KeyListWarnings(), 1)
+
+	p, err = NewPolicyFromSource("", 0, `key_prefix "" { policy = "list" }`, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	require.Empty(t, p.KeyListWarnings())
+}
+
 func TestMergePolicies(t *testing.T) {
 	type mergeTest struct {
 		name     string
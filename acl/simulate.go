@@ -0,0 +1,284 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resource kinds that access can be checked for with Check and MatchRule.
+const (
+	ResourceACL           = "acl"
+	ResourceAgent         = "agent"
+	ResourceEvent         = "event"
+	ResourceIntention     = "intention"
+	ResourceKey           = "key"
+	ResourceKeyring       = "keyring"
+	ResourceNode          = "node"
+	ResourceOperator      = "operator"
+	ResourcePreparedQuery = "query"
+	ResourceService       = "service"
+	ResourceSession       = "session"
+)
+
+// IsUnsupportedResource returns true if the error is about a resource kind
+// that is unknown to this version, such as the Enterprise only ones.
+func IsUnsupportedResource(err error) bool {
+	_, ok := err.(unsupportedResourceError)
+	return ok
+}
+
+type unsupportedResourceError struct {
+	resource string
+}
+
+func (e unsupportedResourceError) Error() string {
+	return fmt.Sprintf("Unsupported resource %q, it may only be available in Consul Enterprise", e.resource)
+}
+
+// Check returns whether the authorizer allows the given access to the
+// segment of a resource. The access is one of read, write or, for keys only,
+// list. Segments are ignored for the acl, keyring and operator resources.
+func Check(authz Authorizer, resource, segment, access string) (bool, error) {
+	var read, write func() bool
+	switch resource {
+	case ResourceACL:
+		read, write = authz.ACLRead, authz.ACLWrite
+	case ResourceAgent:
+		read = func() bool { return authz.AgentRead(segment) }
+		write = func() bool { return authz.AgentWrite(segment) }
+	case ResourceEvent:
+		read = func() bool { return authz.EventRead(segment) }
+		write = func() bool { return authz.EventWrite(segment) }
+	case ResourceIntention:
+		read = func() bool { return authz.IntentionRead(segment) }
+		write = func() bool { return authz.IntentionWrite(segment) }
+	case ResourceKey:
+		if access == PolicyList {
+			return authz.KeyList(segment), nil
+		}
+		read = func() bool { return authz.KeyRead(segment) }
+		write = func() bool { return authz.KeyWrite(segment, nil) }
+	case ResourceKeyring:
+		read, write = authz.KeyringRead, authz.KeyringWrite
+	case ResourceNode:
+		read = func() bool { return authz.NodeRead(segment) }
+		write = func() bool { return authz.NodeWrite(segment, nil) }
+	case ResourceOperator:
+		read, write = authz.OperatorRead, authz.OperatorWrite
+	case ResourcePreparedQuery:
+		read = func() bool { return authz.PreparedQueryRead(segment) }
+		write = func() bool { return authz.PreparedQueryWrite(segment) }
+	case ResourceService:
+		read = func() bool { return authz.ServiceRead(segment) }
+		write = func() bool { return authz.ServiceWrite(segment, nil) }
+	case ResourceSession:
+		read = func() bool { return authz.SessionRead(segment) }
+		write = func() bool { return authz.SessionWrite(segment) }
+	default:
+		return false, unsupportedResourceError{resource}
+	}
+
+	switch access {
+	case PolicyRead:
+		return read(), nil
+	case PolicyWrite:
+		return write(), nil
+	default:
+		return false, fmt.Errorf("Invalid access %q for resource %q", access, resource)
+	}
+}
+
+// RuleMatch is the rule of a policy that decides an access.
+type RuleMatch struct {
+	// PolicyID is the ID of the policy the rule is part of.
+	PolicyID string
+
+	// Kind is the kind of the rule as written in the policy, such as
+	// "service" or "service_prefix".
+	Kind string
+
+	// Segment is the name or prefix the rule is for. It is empty for the
+	// acl, keyring and operator rules.
+	Segment string
+
+	// Field and Value are the field of the rule that decides, usually
+	// "policy", and its value as written in the policy.
+	Field string
+	Value string
+
+	// Access is the access the rule grants. It differs from Value when the
+	// access is derived, as for intentions from the service policy.
+	Access string
+}
+
+// String returns the rule the way it is written in a policy.
+func (m *RuleMatch) String() string {
+	switch m.Kind {
+	case ResourceACL, ResourceKeyring, ResourceOperator:
+		return fmt.Sprintf("%s = %q", m.Kind, m.Value)
+	default:
+		return fmt.Sprintf("%s %q { %s = %q }", m.Kind, m.Segment, m.Field, m.Value)
+	}
+}
+
+// MatchRule returns the rule of the policies that decides the access to the
+// segment of a resource, the same way an authorizer built from these policies
+// would. It returns nil if none of the rules apply, in which case the access
+// is decided by the parent of the authorizer.
+func MatchRule(policies []*Policy, resource, segment string) (*RuleMatch, error) {
+	var match *RuleMatch
+	switch resource {
+	case ResourceACL, ResourceKeyring, ResourceOperator:
+		for _, policy := range policies {
+			access := policy.ACL
+			if resource == ResourceKeyring {
+				access = policy.Keyring
+			} else if resource == ResourceOperator {
+				access = policy.Operator
+			}
+			if access != "" && (match == nil || takesPrecedenceOver(access, match.Access)) {
+				match = &RuleMatch{PolicyID: policy.ID, Kind: resource, Value: access, Access: access}
+			}
+		}
+		return match, nil
+
+	case ResourceAgent, ResourceEvent, ResourceIntention, ResourceKey,
+		ResourceNode, ResourcePreparedQuery, ResourceService, ResourceSession:
+
+	default:
+		return nil, unsupportedResourceError{resource}
+	}
+
+	// An exact rule always wins. When it doesn't set an access the parent
+	// decides, the prefix rules are not looked at.
+	for _, policy := range policies {
+		for _, rule := range segmentRules(policy, resource, false) {
+			if rule.Segment != segment {
+				continue
+			}
+			if match == nil || takesPrecedenceOver(rule.Access, match.Access) {
+				match = rule
+			}
+		}
+	}
+	if match != nil {
+		if match.Access == "" {
+			return nil, nil
+		}
+		return match, nil
+	}
+
+	// Otherwise the longest matching prefix does.
+	for _, policy := range policies {
+		for _, rule := range segmentRules(policy, resource, true) {
+			if !strings.HasPrefix(segment, rule.Segment) {
+				continue
+			}
+			if match == nil || len(rule.Segment) > len(match.Segment) ||
+				(len(rule.Segment) == len(match.Segment) && takesPrecedenceOver(rule.Access, match.Access)) {
+				match = rule
+			}
+		}
+	}
+	return match, nil
+}
+
+// segmentRules returns either the exact or the prefix rules of a policy for
+// a resource.
+func segmentRules(policy *Policy, resource string, prefix bool) []*RuleMatch {
+	kind := resource
+	if prefix {
+		kind += "_prefix"
+	}
+
+	var rules []*RuleMatch
+	add := func(segment, field, value, access string) {
+		rules = append(rules, &RuleMatch{
+			PolicyID: policy.ID,
+			Kind:     kind,
+			Segment:  segment,
+			Field:    field,
+			Value:    value,
+			Access:   access,
+		})
+	}
+
+	switch resource {
+	case ResourceAgent:
+		list := policy.Agents
+		if prefix {
+			list = policy.AgentPrefixes
+		}
+		for _, r := range list {
+			add(r.Node, "policy", r.Policy, r.Policy)
+		}
+	case ResourceEvent:
+		list := policy.Events
+		if prefix {
+			list = policy.EventPrefixes
+		}
+		for _, r := range list {
+			add(r.Event, "policy", r.Policy, r.Policy)
+		}
+	case ResourceKey:
+		list := policy.Keys
+		if prefix {
+			list = policy.KeyPrefixes
+		}
+		for _, r := range list {
+			add(r.Prefix, "policy", r.Policy, r.Policy)
+		}
+	case ResourceNode:
+		list := policy.Nodes
+		if prefix {
+			list = policy.NodePrefixes
+		}
+		for _, r := range list {
+			add(r.Name, "policy", r.Policy, r.Policy)
+		}
+	case ResourcePreparedQuery:
+		list := policy.PreparedQueries
+		if prefix {
+			list = policy.PreparedQueryPrefixes
+		}
+		for _, r := range list {
+			add(r.Prefix, "policy", r.Policy, r.Policy)
+		}
+	case ResourceSession:
+		list := policy.Sessions
+		if prefix {
+			list = policy.SessionPrefixes
+		}
+		for _, r := range list {
+			add(r.Node, "policy", r.Policy, r.Policy)
+		}
+	case ResourceService, ResourceIntention:
+		// Intention rules are part of the service rules.
+		kind = ResourceService
+		if prefix {
+			kind += "_prefix"
+		}
+		list := policy.Services
+		if prefix {
+			list = policy.ServicePrefixes
+		}
+		for _, r := range list {
+			if resource == ResourceService {
+				add(r.Name, "policy", r.Policy, r.Policy)
+				continue
+			}
+
+			// This mirrors how the authorizer derives the intentions
+			// access from the service policy when it isn't set.
+			switch {
+			case r.Intentions != "":
+				add(r.Name, "intentions", r.Intentions, r.Intentions)
+			case r.Policy == PolicyRead || r.Policy == PolicyWrite:
+				add(r.Name, "policy", r.Policy, PolicyRead)
+			default:
+				add(r.Name, "policy", r.Policy, PolicyDeny)
+			}
+		}
+	}
+	return rules
+}
@@ -0,0 +1,109 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	policy, err := NewPolicyFromSource("", 0, `
+key_prefix "config/" { policy = "list" }
+key "config/secret" { policy = "deny" }
+service_prefix "" { policy = "read" }
+service "web" { policy = "write" }
+operator = "read"
+`, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	authz, err := NewPolicyAuthorizer(DenyAll(), []*Policy{policy}, nil)
+	require.NoError(t, err)
+
+	type tcase struct {
+		resource, segment, access string
+		allowed                   bool
+	}
+	for _, tc := range []tcase{
+		{ResourceKey, "config/app", PolicyRead, true},
+		{ResourceKey, "config/app", PolicyList, true},
+		{ResourceKey, "config/app", PolicyWrite, false},
+		{ResourceKey, "config/secret", PolicyRead, false},
+		{ResourceKey, "other", PolicyRead, false},
+		{ResourceService, "web", PolicyWrite, true},
+		{ResourceService, "db", PolicyRead, true},
+		{ResourceService, "db", PolicyWrite, false},
+		{ResourceIntention, "web", PolicyRead, true},
+		{ResourceIntention, "web", PolicyWrite, false},
+		{ResourceOperator, "", PolicyRead, true},
+		{ResourceOperator, "", PolicyWrite, false},
+		{ResourceACL, "", PolicyRead, false},
+		{ResourceNode, "foo", PolicyRead, false},
+	} {
+		allowed, err := Check(authz, tc.resource, tc.segment, tc.access)
+		require.NoError(t, err)
+		require.Equal(t, tc.allowed, allowed, "%s:%s:%s", tc.resource, tc.segment, tc.access)
+	}
+
+	_, err = Check(authz, "namespace", "default", PolicyRead)
+	require.True(t, IsUnsupportedResource(err))
+
+	_, err = Check(authz, ResourceService, "web", PolicyList)
+	require.Error(t, err)
+	require.False(t, IsUnsupportedResource(err))
+}
+
+func TestMatchRule(t *testing.T) {
+	p1, err := NewPolicyFromSource("p1", 0, `
+key_prefix "config/" { policy = "read" }
+service_prefix "" { policy = "read" }
+service "web" { policy = "write" intentions = "deny" }
+operator = "read"
+`, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	p2, err := NewPolicyFromSource("p2", 0, `
+key_prefix "config/app/" { policy = "write" }
+key_prefix "config/" { policy = "deny" }
+operator = "write"
+`, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	policies := []*Policy{p1, p2}
+
+	type tcase struct {
+		resource, segment string
+		policyID, rule    string
+	}
+	for _, tc := range []tcase{
+		// The longest prefix wins
+		{ResourceKey, "config/app/db", "p2", `key_prefix "config/app/" { policy = "write" }`},
+		// Deny takes precedence for the same prefix
+		{ResourceKey, "config/other", "p2", `key_prefix "config/" { policy = "deny" }`},
+		// Exact rules win over prefixes
+		{ResourceService, "web", "p1", `service "web" { policy = "write" }`},
+		{ResourceService, "db", "p1", `service_prefix "" { policy = "read" }`},
+		// Intentions are derived from the service rules
+		{ResourceIntention, "web", "p1", `service "web" { intentions = "deny" }`},
+		{ResourceIntention, "db", "p1", `service_prefix "" { policy = "read" }`},
+		// Write takes precedence over read
+		{ResourceOperator, "", "p2", `operator = "write"`},
+		// Nothing matches
+		{ResourceNode, "foo", "", ""},
+		{ResourceKey, "other", "", ""},
+		{ResourceACL, "", "", ""},
+	} {
+		match, err := MatchRule(policies, tc.resource, tc.segment)
+		require.NoError(t, err)
+		if tc.rule == "" {
+			require.Nil(t, match, "%s:%s", tc.resource, tc.segment)
+			continue
+		}
+		require.NotNil(t, match, "%s:%s", tc.resource, tc.segment)
+		require.Equal(t, tc.policyID, match.PolicyID, "%s:%s", tc.resource, tc.segment)
+		require.Equal(t, tc.rule, match.String(), "%s:%s", tc.resource, tc.segment)
+	}
+
+	match, err := MatchRule(policies, ResourceIntention, "db")
+	require.NoError(t, err)
+	require.Equal(t, PolicyRead, match.Access)
+
+	_, err = MatchRule(policies, "namespace", "default")
+	require.True(t, IsUnsupportedResource(err))
+}
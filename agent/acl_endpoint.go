@@ -10,6 +10,7 @@ import (
 
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 // aclCreateResponse is used to wrap the ACL ID
@@ -25,8 +26,7 @@ func (s *HTTPServer) checkACLDisabled(resp http.ResponseWriter, req *http.Reques
 		return false
 	}
 
-	resp.WriteHeader(http.StatusUnauthorized)
-	fmt.Fprint(resp, "ACL support disabled")
+	s.writeError(resp, req, http.StatusUnauthorized, api.ErrCodeDisabled, "ACL support disabled", nil)
 	return true
 }
 
@@ -52,8 +52,7 @@ func (s *HTTPServer) ACLBootstrap(resp http.ResponseWriter, req *http.Request) (
 		err := s.agent.RPC("ACL.Bootstrap", &args, &out)
 		if err != nil {
 			if strings.Contains(err.Error(), structs.ACLBootstrapNotAllowedErr.Error()) {
-				resp.WriteHeader(http.StatusForbidden)
-				fmt.Fprint(resp, acl.PermissionDeniedError{Cause: err.Error()}.Error())
+				s.writeError(resp, req, http.StatusForbidden, api.ErrCodeACLDenied, acl.PermissionDeniedError{Cause: err.Error()}.Error(), nil)
 				return nil, nil
 			} else {
 				return nil, err
@@ -65,8 +64,7 @@ func (s *HTTPServer) ACLBootstrap(resp http.ResponseWriter, req *http.Request) (
 		err := s.agent.RPC("ACL.BootstrapTokens", &args, &out)
 		if err != nil {
 			if strings.Contains(err.Error(), structs.ACLBootstrapNotAllowedErr.Error()) {
-				resp.WriteHeader(http.StatusForbidden)
-				fmt.Fprint(resp, acl.PermissionDeniedError{Cause: err.Error()}.Error())
+				s.writeError(resp, req, http.StatusForbidden, api.ErrCodeACLDenied, acl.PermissionDeniedError{Cause: err.Error()}.Error(), nil)
 				return nil, nil
 			} else {
 				return nil, err
@@ -177,6 +175,38 @@ func (s *HTTPServer) ACLRulesTranslateLegacyToken(resp http.ResponseWriter, req
 	return nil, nil
 }
 
+func (s *HTTPServer) ACLSimulate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	args := structs.ACLSimulateRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+	if err := decodeBody(req, &args, nil); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Request decoding failed: %v", err)}
+	}
+	if args.Datacenter == "" {
+		args.Datacenter = s.agent.config.Datacenter
+	}
+
+	if (args.AccessorID == "") == (args.Rules == "") {
+		return nil, BadRequestError{Reason: "Exactly one of AccessorID or Rules must be provided"}
+	}
+	if len(args.Checks) == 0 {
+		return nil, BadRequestError{Reason: "At least one check must be provided"}
+	}
+
+	var out structs.ACLSimulateResponse
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("ACL.Simulate", &args, &out); err != nil {
+		return nil, err
+	}
+
+	return out.Results, nil
+}
+
 func (s *HTTPServer) ACLPolicyList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if s.checkACLDisabled(resp, req) {
 		return nil, nil
@@ -269,8 +299,9 @@ func (s *HTTPServer) ACLPolicyCreate(resp http.ResponseWriter, req *http.Request
 }
 
 // fixCreateTimeAndHash is used to help in decoding the CreateTime and Hash
-// attributes from the ACL Token/Policy create/update requests. It is needed
-// to help mapstructure decode things properly when decodeBody is used.
+// attributes from the ACL Token/Policy create/update requests, along with the
+// ExpirationTime of the token's policy links. It is needed to help
+// mapstructure decode things properly when decodeBody is used.
 func fixCreateTimeAndHash(raw interface{}) error {
 	rawMap, ok := raw.(map[string]interface{})
 	if !ok {
@@ -292,6 +323,22 @@ func fixCreateTimeAndHash(raw interface{}) error {
 			rawMap["Hash"] = []byte(sval)
 		}
 	}
+
+	if links, ok := rawMap["Policies"].([]interface{}); ok {
+		for _, link := range links {
+			linkMap, ok := link.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if sval, ok := linkMap["ExpirationTime"].(string); ok {
+				t, err := time.Parse(time.RFC3339, sval)
+				if err != nil {
+					return err
+				}
+				linkMap["ExpirationTime"] = t
+			}
+		}
+	}
 	return nil
 }
 
@@ -354,6 +401,9 @@ func (s *HTTPServer) ACLTokenList(resp http.ResponseWriter, req *http.Request) (
 	}
 
 	args.Policy = req.URL.Query().Get("policy")
+	if _, ok := req.URL.Query()["dangling"]; ok {
+		args.Dangling = true
+	}
 
 	var out structs.ACLTokenListResponse
 	defer setMeta(resp, &out.QueryMeta)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 type aclCreateResponse struct {
@@ -27,8 +28,7 @@ func (s *HTTPServer) ACLDestroy(resp http.ResponseWriter, req *http.Request) (in
 	// Pull out the acl id
 	args.ACL.ID = strings.TrimPrefix(req.URL.Path, "/v1/acl/destroy/")
 	if args.ACL.ID == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing ACL")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing ACL", nil)
 		return nil, nil
 	}
 
@@ -66,8 +66,7 @@ func (s *HTTPServer) aclSet(resp http.ResponseWriter, req *http.Request, update
 	// Handle optional request body
 	if req.ContentLength > 0 {
 		if err := decodeBody(req, &args.ACL, nil); err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Request decode failed: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 			return nil, nil
 		}
 	}
@@ -75,8 +74,7 @@ func (s *HTTPServer) aclSet(resp http.ResponseWriter, req *http.Request, update
 	// Ensure there is an ID set for update. ID is optional for
 	// create, as one will be generated if not provided.
 	if update && args.ACL.ID == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "ACL ID must be set")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "ACL ID must be set", nil)
 		return nil, nil
 	}
 
@@ -106,8 +104,7 @@ func (s *HTTPServer) ACLClone(resp http.ResponseWriter, req *http.Request) (inte
 	// Pull out the acl id
 	args.ACL = strings.TrimPrefix(req.URL.Path, "/v1/acl/clone/")
 	if args.ACL == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing ACL")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing ACL", nil)
 		return nil, nil
 	}
 
@@ -158,8 +155,7 @@ func (s *HTTPServer) ACLGet(resp http.ResponseWriter, req *http.Request) (interf
 	// Pull out the acl id
 	args.ACL = strings.TrimPrefix(req.URL.Path, "/v1/acl/info/")
 	if args.ACL == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing ACL")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing ACL", nil)
 		return nil, nil
 	}
 
@@ -40,6 +40,7 @@ func TestACL_Disabled_Response(t *testing.T) {
 		{"ACLTokenCreate", a.srv.ACLTokenCreate},
 		{"ACLTokenSelf", a.srv.ACLTokenSelf},
 		{"ACLTokenCRUD", a.srv.ACLTokenCRUD},
+		{"ACLSimulate", a.srv.ACLSimulate},
 	}
 	testrpc.WaitForLeader(t, a.RPC, "dc1")
 	for _, tt := range tests {
@@ -593,4 +594,75 @@ func TestACL_HTTP(t *testing.T) {
 			require.Equal(t, structs.ACLPolicyGlobalManagementID, token.Policies[0].ID)
 		})
 	})
+
+	t.Run("Simulate", func(t *testing.T) {
+		t.Run("Token", func(t *testing.T) {
+			input := &structs.ACLSimulateRequest{
+				AccessorID: idMap["token-test"],
+				Checks: []structs.ACLSimulateCheck{
+					{Resource: "node", Segment: "foo", Access: "read"},
+					{Resource: "acl", Access: "write"},
+				},
+			}
+			req, _ := http.NewRequest("POST", "/v1/acl/simulate?token=root", jsonBody(input))
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLSimulate(resp, req)
+			require.NoError(t, err)
+			results, ok := obj.([]structs.ACLSimulateResult)
+			require.True(t, ok)
+			require.Len(t, results, 2)
+
+			require.True(t, results[0].Allowed)
+			require.Equal(t, idMap["policy-read-all-nodes"], results[0].PolicyID)
+			require.Equal(t, "read-all-nodes", results[0].PolicyName)
+			require.Equal(t, `node_prefix "" { policy = "read" }`, results[0].Rule)
+
+			require.False(t, results[1].Allowed)
+			require.Equal(t, idMap["policy-test"], results[1].PolicyID)
+			require.Equal(t, `acl = "read"`, results[1].Rule)
+		})
+		t.Run("Rules", func(t *testing.T) {
+			input := &structs.ACLSimulateRequest{
+				Rules: `key_prefix "config/" { policy = "write" }`,
+				Checks: []structs.ACLSimulateCheck{
+					{Resource: "key", Segment: "config/app", Access: "write"},
+				},
+			}
+			req, _ := http.NewRequest("POST", "/v1/acl/simulate?token=root", jsonBody(input))
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLSimulate(resp, req)
+			require.NoError(t, err)
+			results, ok := obj.([]structs.ACLSimulateResult)
+			require.True(t, ok)
+			require.Len(t, results, 1)
+			require.True(t, results[0].Allowed)
+			require.Equal(t, `key_prefix "config/" { policy = "write" }`, results[0].Rule)
+		})
+		t.Run("Token And Rules", func(t *testing.T) {
+			input := &structs.ACLSimulateRequest{
+				AccessorID: idMap["token-test"],
+				Rules:      `acl = "read"`,
+				Checks: []structs.ACLSimulateCheck{
+					{Resource: "acl", Access: "read"},
+				},
+			}
+			req, _ := http.NewRequest("POST", "/v1/acl/simulate?token=root", jsonBody(input))
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLSimulate(resp, req)
+			require.Error(t, err)
+			require.Nil(t, obj)
+			_, ok := err.(BadRequestError)
+			require.True(t, ok)
+		})
+		t.Run("No Checks", func(t *testing.T) {
+			input := &structs.ACLSimulateRequest{
+				AccessorID: idMap["token-test"],
+			}
+			req, _ := http.NewRequest("POST", "/v1/acl/simulate?token=root", jsonBody(input))
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLSimulate(resp, req)
+			_, ok := err.(BadRequestError)
+			require.True(t, ok)
+		})
+	})
 }
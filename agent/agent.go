@@ -32,6 +32,7 @@ import (
 	"github.com/hashicorp/consul/agent/proxycfg"
 	"github.com/hashicorp/consul/agent/proxyprocess"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/agent/subscribe"
 	"github.com/hashicorp/consul/agent/systemd"
 	"github.com/hashicorp/consul/agent/token"
 	"github.com/hashicorp/consul/agent/xds"
@@ -121,6 +122,10 @@ type Agent struct {
 	// Used for writing our logs
 	logger *log.Logger
 
+	// limitedLogger writes to logger, for the messages of the hot paths
+	// that need to be rate limited. It's shared with the DNS servers.
+	limitedLogger *logger.RateLimitedLogger
+
 	// Output sink for logs
 	LogOutput io.Writer
 
@@ -157,6 +162,13 @@ type Agent struct {
 	// reap its associated service
 	checkReapAfter map[types.CheckID]time.Duration
 
+	// checkTypes maps the check ID to the definition it was registered with
+	checkTypes map[types.CheckID]*structs.CheckType
+
+	// configServices holds the IDs of the services, and of their sidecars,
+	// that were loaded from the configuration files.
+	configServices map[string]struct{}
+
 	// checkMonitors maps the check ID to an associated monitor
 	checkMonitors map[types.CheckID]*checks.CheckMonitor
 
@@ -246,6 +258,10 @@ type Agent struct {
 	// xdsServer is the Server instance that serves xDS gRPC API.
 	xdsServer *xds.Server
 
+	// subscribeServer is the Server instance that serves the subscription
+	// gRPC API.
+	subscribeServer *subscribe.Server
+
 	// grpcServer is the server instance used currently to serve xDS API for
 	// Envoy.
 	grpcServer *grpc.Server
@@ -258,6 +274,30 @@ type Agent struct {
 	// store within the data directory. This will prevent loading while writing as
 	// well as multiple concurrent writes.
 	persistedTokensLock sync.RWMutex
+
+	// lastKnown persists health service query results when
+	// discovery_resilience.persist_last_known is enabled, otherwise it is nil.
+	lastKnown *lastKnownStore
+
+	// leafPrewarm is the progress of fetching the leaf certificates of the
+	// local Connect services after startup, protected by leafPrewarmLock.
+	leafPrewarm     leafPrewarmStatus
+	leafPrewarmLock sync.RWMutex
+
+	// nodeDrain is the drain state of the node, nil if it isn't drained,
+	// protected by nodeDrainLock.
+	nodeDrain     *nodeDrainState
+	nodeDrainLock sync.Mutex
+}
+
+// nodeDrainState is the drain state of the node.
+type nodeDrainState struct {
+	// until is the value of the drained node meta key, the time the drain
+	// ends or empty if it lasts until it's disabled.
+	until string
+
+	// timer disables the drain when it ends, it's nil if until is empty.
+	timer *time.Timer
 }
 
 func New(c *config.RuntimeConfig) (*Agent, error) {
@@ -271,6 +311,8 @@ func New(c *config.RuntimeConfig) (*Agent, error) {
 	a := &Agent{
 		config:          c,
 		checkReapAfter:  make(map[types.CheckID]time.Duration),
+		checkTypes:      make(map[types.CheckID]*structs.CheckType),
+		configServices:  make(map[string]struct{}),
 		checkMonitors:   make(map[types.CheckID]*checks.CheckMonitor),
 		checkTTLs:       make(map[types.CheckID]*checks.CheckTTL),
 		checkHTTPs:      make(map[types.CheckID]*checks.CheckHTTP),
@@ -350,6 +392,7 @@ func (a *Agent) Start() error {
 		}
 		a.logger = log.New(logOutput, "", log.LstdFlags)
 	}
+	a.limitedLogger = logger.NewRateLimited(a.logger)
 
 	// Retrieve or generate the node ID before setting up the rest of the
 	// agent, which depends on it.
@@ -382,6 +425,14 @@ func (a *Agent) Start() error {
 	// create the cache
 	a.cache = cache.New(nil)
 
+	if c.DiscoveryPersistLastKnown {
+		if c.DataDir == "" {
+			a.logger.Printf("[WARN] agent: discovery_resilience.persist_last_known requires a data directory, ignoring")
+		} else {
+			a.lastKnown = newLastKnownStore(filepath.Join(c.DataDir, lastKnownDir), c.DiscoveryPersistMaxAge, a.logger)
+		}
+	}
+
 	// create the config for the rpc server/client
 	consulCfg, err := a.consulConfig()
 	if err != nil {
@@ -473,10 +524,24 @@ func (a *Agent) Start() error {
 		}
 	}()
 
+	// Fetch the leaf certificates of the local Connect services so the
+	// first connections after a restart don't have to wait for them.
+	a.leafPrewarm.Enabled = c.ConnectEnabled && c.ConnectLeafCertPrewarm
+	if a.leafPrewarm.Enabled {
+		go a.prewarmLeafCerts()
+	} else {
+		a.leafPrewarm.Done = true
+	}
+
 	// Start watching for critical services to deregister, based on their
 	// checks.
 	go a.reapServices()
 
+	// Start removing the expired discovery results.
+	if a.lastKnown != nil {
+		go a.lastKnown.run(a.shutdownCh)
+	}
+
 	// Start handling events.
 	go a.handleEvents()
 
@@ -552,6 +617,15 @@ func (a *Agent) listenAndServeGRPC() error {
 		return err
 	}
 
+	a.subscribeServer = &subscribe.Server{
+		Logger:       a.logger,
+		Cache:        a.cache,
+		Datacenter:   a.config.Datacenter,
+		DefaultToken: a.tokens.UserToken,
+	}
+	a.subscribeServer.Initialize()
+	subscribe.RegisterConsulServer(a.grpcServer, a.subscribeServer)
+
 	ln, err := a.startListeners(a.config.GRPCAddrs)
 	if err != nil {
 		return err
@@ -999,6 +1073,13 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.ACLDefaultPolicy != "" {
 		base.ACLDefaultPolicy = a.config.ACLDefaultPolicy
 	}
+	base.ACLDefaultPolicyOverride = a.config.ACLDefaultPolicyOverride
+	base.ACLDanglingTokenScanInterval = a.config.ACLDanglingTokenScanInterval
+	base.ACLDanglingTokenDeleteAfter = a.config.ACLDanglingTokenDeleteAfter
+	base.ACLUsageSampleRate = a.config.ACLUsageSampleRate
+	base.ACLUsageInterval = a.config.ACLUsageInterval
+	base.ACLUsageTopClients = a.config.ACLUsageTopClients
+	base.ACLUsageLogShare = a.config.ACLUsageLogShare
 	if a.config.ACLDownPolicy != "" {
 		base.ACLDownPolicy = a.config.ACLDownPolicy
 	}
@@ -1011,9 +1092,14 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.SessionTTLMin != 0 {
 		base.SessionTTLMin = a.config.SessionTTLMin
 	}
+	if a.config.HealthHistoryRetention != 0 {
+		base.HealthHistoryRetention = a.config.HealthHistoryRetention
+	}
+	base.KVDeletedRetention = a.config.KVDeletedRetention
 	if a.config.NonVotingServer {
 		base.NonVoter = a.config.NonVotingServer
 	}
+	base.NormalizeServiceTags = a.config.NormalizeServiceTags
 
 	// These are fully specified in the agent defaults, so we can simply
 	// copy them over.
@@ -1409,6 +1495,14 @@ func (a *Agent) RPC(method string, args interface{}, reply interface{}) error {
 		}
 	}
 	a.endpointsLock.RUnlock()
+
+	if a.lastKnown != nil && method == "Health.ServiceNodes" {
+		req, ok1 := args.(*structs.ServiceSpecificRequest)
+		out, ok2 := reply.(*structs.IndexedCheckServiceNodes)
+		if ok1 && ok2 {
+			return a.serviceNodesRPC(method, req, out)
+		}
+	}
 	return a.delegate.RPC(method, args, reply)
 }
 
@@ -1530,6 +1624,9 @@ func (a *Agent) ShutdownEndpoints() {
 	for _, srv := range a.dnsServers {
 		a.logger.Printf("[INFO] agent: Stopping DNS server %s (%s)", srv.Server.Addr, srv.Server.Net)
 		srv.Shutdown()
+		if srv.answers != nil {
+			srv.answers.stop()
+		}
 	}
 	a.dnsServers = nil
 
@@ -1724,7 +1821,8 @@ OUTER:
 					if acl.IsErrPermissionDenied(err) {
 						a.logger.Printf("[WARN] agent: Coordinate update blocked by ACLs")
 					} else {
-						a.logger.Printf("[ERR] agent: Coordinate update error: %v", err)
+						a.limitedLogger.RateLimited("agent.coordinate_update", logger.DefaultRateLimitInterval).Printf(
+							"[ERR] agent: Coordinate update error: %v", err)
 					}
 					continue OUTER
 				}
@@ -1890,8 +1988,22 @@ func (a *Agent) purgeCheck(checkID types.CheckID) error {
 // This entry is persistent and the agent will make a best effort to
 // ensure it is registered
 func (a *Agent) AddService(service *structs.NodeService, chkTypes []*structs.CheckType, persist bool, token string, source configSource) error {
+	// This isn't checked when loading persisted services, so the ones saved
+	// before a check was added still load.
+	if err := structs.ValidateServiceMetadata(service.Meta); err != nil {
+		return fmt.Errorf("Invalid Service Meta: %v", err)
+	}
+
 	a.stateLock.Lock()
 	defer a.stateLock.Unlock()
+
+	// A service registered again through the API isn't the one from the
+	// configuration files anymore.
+	id := service.ID
+	if id == "" {
+		id = service.Service
+	}
+	delete(a.configServices, id)
 	return a.addServiceLocked(service, chkTypes, persist, token, source)
 }
 
@@ -2148,10 +2260,10 @@ func (a *Agent) addCheckLocked(check *structs.HealthCheck, chkType *structs.Chec
 	}
 
 	// snapshot the current state of the health check to avoid potential flapping
-	existing := a.State.Check(check.CheckID)
+	existing := a.State.CheckState(check.CheckID)
 	defer func() {
 		if existing != nil {
-			a.State.UpdateCheck(check.CheckID, existing.Status, existing.Output)
+			a.restoreCheck(check.CheckID, existing)
 		}
 	}()
 
@@ -2201,6 +2313,18 @@ func (a *Agent) addCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 		check.ServiceTags = service.Tags
 	}
 
+	// A check that depends on another one is held back while that one is
+	// critical, see local.State.SetCheckDependency. The dependency is only
+	// recorded once nothing else can fail, so a failed registration
+	// doesn't leave it behind.
+	var dependsOn types.CheckID
+	if chkType != nil {
+		dependsOn = chkType.DependsOnCheck
+	}
+	if err := a.State.ValidateCheckDependency(check.CheckID, dependsOn); err != nil {
+		return err
+	}
+
 	// Check if already registered
 	if chkType != nil {
 		switch {
@@ -2407,9 +2531,11 @@ func (a *Agent) addCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 		} else {
 			delete(a.checkReapAfter, check.CheckID)
 		}
+
+		a.checkTypes[check.CheckID] = chkType
 	}
 
-	return nil
+	return a.State.SetCheckDependency(check.CheckID, dependsOn)
 }
 
 // RemoveCheck is used to remove a health check.
@@ -2792,6 +2918,7 @@ func (a *Agent) verifyProxyToken(token, targetService,
 func (a *Agent) cancelCheckMonitors(checkID types.CheckID) {
 	// Stop any monitors
 	delete(a.checkReapAfter, checkID)
+	delete(a.checkTypes, checkID)
 	if check, ok := a.checkMonitors[checkID]; ok {
 		check.Stop()
 		delete(a.checkMonitors, checkID)
@@ -2818,6 +2945,33 @@ func (a *Agent) cancelCheckMonitors(checkID types.CheckID) {
 	}
 }
 
+// localCheckTypes returns the definitions the local checks were registered
+// with. Checks registered without one, such as the maintenance checks, are
+// not included.
+func (a *Agent) localCheckTypes() map[types.CheckID]*structs.CheckType {
+	a.stateLock.Lock()
+	defer a.stateLock.Unlock()
+
+	chkTypes := make(map[types.CheckID]*structs.CheckType, len(a.checkTypes))
+	for id, chkType := range a.checkTypes {
+		chkTypes[id] = chkType
+	}
+	return chkTypes
+}
+
+// configServiceIDs returns the IDs of the local services that were loaded
+// from the configuration files.
+func (a *Agent) configServiceIDs() map[string]struct{} {
+	a.stateLock.Lock()
+	defer a.stateLock.Unlock()
+
+	ids := make(map[string]struct{}, len(a.configServices))
+	for id := range a.configServices {
+		ids[id] = struct{}{}
+	}
+	return ids
+}
+
 // updateTTLCheck is used to update the status of a TTL check via the Agent API.
 func (a *Agent) updateTTLCheck(checkID types.CheckID, status, output string) error {
 	a.stateLock.Lock()
@@ -3007,6 +3161,7 @@ func (a *Agent) deletePid() error {
 // definitions on disk, and load them into the local agent.
 func (a *Agent) loadServices(conf *config.RuntimeConfig) error {
 	// Register the services from config
+	a.configServices = make(map[string]struct{})
 	for _, service := range conf.Services {
 		ns := service.NodeService()
 		chkTypes, err := service.CheckTypes()
@@ -3027,12 +3182,14 @@ func (a *Agent) loadServices(conf *config.RuntimeConfig) error {
 		if err := a.addServiceLocked(ns, chkTypes, false, service.Token, ConfigSourceLocal); err != nil {
 			return fmt.Errorf("Failed to register service %q: %v", service.Name, err)
 		}
+		a.configServices[ns.ID] = struct{}{}
 
 		// If there is a sidecar service, register that too.
 		if sidecar != nil {
 			if err := a.addServiceLocked(sidecar, sidecarChecks, false, sidecarToken, ConfigSourceLocal); err != nil {
 				return fmt.Errorf("Failed to register sidecar for service %q: %v", service.Name, err)
 			}
+			a.configServices[sidecar.ID] = struct{}{}
 		}
 	}
 
@@ -3398,19 +3555,30 @@ func (a *Agent) unloadProxies() error {
 // snapshotCheckState is used to snapshot the current state of the health
 // checks. This is done before we reload our checks, so that we can properly
 // restore into the same state.
-func (a *Agent) snapshotCheckState() map[types.CheckID]*structs.HealthCheck {
-	return a.State.Checks()
+func (a *Agent) snapshotCheckState() map[types.CheckID]*local.CheckState {
+	return a.State.CheckStates()
 }
 
 // restoreCheckState is used to reset the health state based on a snapshot.
 // This is done after we finish the reload to avoid any unnecessary flaps
 // in health state and potential session invalidations.
-func (a *Agent) restoreCheckState(snap map[types.CheckID]*structs.HealthCheck) {
-	for id, check := range snap {
-		a.State.UpdateCheck(id, check.Status, check.Output)
+func (a *Agent) restoreCheckState(snap map[types.CheckID]*local.CheckState) {
+	for id, c := range snap {
+		a.restoreCheck(id, c)
 	}
 }
 
+// restoreCheck resets the health state of a check based on a snapshot. A
+// blocked check is restored to the result of its own probe, since it's
+// blocked again if its dependency is still critical.
+func (a *Agent) restoreCheck(id types.CheckID, c *local.CheckState) {
+	if c.BlockedBy != "" {
+		a.State.UpdateCheck(id, c.ProbeStatus, c.ProbeOutput)
+		return
+	}
+	a.State.UpdateCheck(id, c.Check.Status, c.Check.Output)
+}
+
 // loadMetadata loads node metadata fields from the agent config and
 // updates them on the local agent.
 func (a *Agent) loadMetadata(conf *config.RuntimeConfig) error {
@@ -3419,6 +3587,13 @@ func (a *Agent) loadMetadata(conf *config.RuntimeConfig) error {
 		meta[k] = v
 	}
 	meta[structs.MetaSegmentKey] = conf.SegmentName
+
+	// Keep the node drained across reloads.
+	a.nodeDrainLock.Lock()
+	defer a.nodeDrainLock.Unlock()
+	if a.nodeDrain != nil {
+		meta[structs.NodeDrainedMetaKey] = a.nodeDrain.until
+	}
 	return a.State.LoadMetadata(meta)
 }
 
@@ -3520,6 +3695,60 @@ func (a *Agent) DisableNodeMaintenance() {
 	a.logger.Printf("[INFO] agent: Node left maintenance mode")
 }
 
+// EnableNodeDrain drains the node, which excludes its service instances from
+// the DNS answers and the health results while keeping them in the catalog.
+// The drain is disabled after the given duration, or lasts until it's
+// disabled if the duration is zero. Draining a drained node restarts the
+// drain with the new duration.
+func (a *Agent) EnableNodeDrain(duration time.Duration) {
+	a.nodeDrainLock.Lock()
+	defer a.nodeDrainLock.Unlock()
+
+	if a.nodeDrain != nil && a.nodeDrain.timer != nil {
+		a.nodeDrain.timer.Stop()
+	}
+
+	drain := &nodeDrainState{}
+	if duration > 0 {
+		drain.until = time.Now().Add(duration).UTC().Format(time.RFC3339)
+		drain.timer = time.AfterFunc(duration, func() {
+			a.nodeDrainLock.Lock()
+			defer a.nodeDrainLock.Unlock()
+
+			// The drain may have been disabled or restarted since.
+			if a.nodeDrain != drain {
+				return
+			}
+			a.disableNodeDrainLocked()
+		})
+	}
+	a.nodeDrain = drain
+	a.State.SetMetadata(structs.NodeDrainedMetaKey, drain.until)
+	a.logger.Printf("[INFO] agent: Node drained")
+}
+
+// DisableNodeDrain undrains the node.
+func (a *Agent) DisableNodeDrain() {
+	a.nodeDrainLock.Lock()
+	defer a.nodeDrainLock.Unlock()
+
+	if a.nodeDrain == nil {
+		return
+	}
+	if a.nodeDrain.timer != nil {
+		a.nodeDrain.timer.Stop()
+	}
+	a.disableNodeDrainLocked()
+}
+
+// disableNodeDrainLocked undrains the node. It must be called with
+// nodeDrainLock held.
+func (a *Agent) disableNodeDrainLocked() {
+	a.nodeDrain = nil
+	a.State.RemoveMetadata(structs.NodeDrainedMetaKey)
+	a.logger.Printf("[INFO] agent: Node undrained")
+}
+
 func (a *Agent) loadLimits(conf *config.RuntimeConfig) {
 	a.config.RPCRateLimit = conf.RPCRateLimit
 	a.config.RPCMaxBurst = conf.RPCMaxBurst
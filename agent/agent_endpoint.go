@@ -7,7 +7,9 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +37,7 @@ import (
 	"github.com/hashicorp/logutils"
 	"github.com/hashicorp/serf/coordinate"
 	"github.com/hashicorp/serf/serf"
+	"github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -46,6 +49,7 @@ type Self struct {
 	Member      serf.Member
 	Stats       map[string]map[string]string
 	Meta        map[string]string
+	Warnings    []config.LintWarning `json:",omitempty"`
 }
 
 func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -68,20 +72,38 @@ func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (int
 		}
 	}
 
+	var warnings []config.LintWarning
+	if _, ok := req.URL.Query()["include-warnings"]; ok {
+		// Use empty list instead of nil
+		warnings = append(make([]config.LintWarning, 0), config.Lint(s.agent.config)...)
+	}
+
+	// The default ACL policy that applies in this datacenter, which may be
+	// overridden locally in a secondary datacenter.
+	var aclDefaultPolicy string
+	var aclDefaultPolicyOverride bool
+	if s.agent.config.ACLsEnabled {
+		aclDefaultPolicy, aclDefaultPolicyOverride = s.agent.config.ACLLocalDefaultPolicy()
+	}
+
 	config := struct {
-		Datacenter string
-		NodeName   string
-		NodeID     string
-		Revision   string
-		Server     bool
-		Version    string
+		Datacenter               string
+		NodeName                 string
+		NodeID                   string
+		Revision                 string
+		Server                   bool
+		Version                  string
+		ACLDefaultPolicy         string `json:",omitempty"`
+		ACLDefaultPolicyOverride bool   `json:",omitempty"`
 	}{
-		Datacenter: s.agent.config.Datacenter,
-		NodeName:   s.agent.config.NodeName,
-		NodeID:     string(s.agent.config.NodeID),
-		Revision:   s.agent.config.Revision,
-		Server:     s.agent.config.ServerMode,
-		Version:    s.agent.config.Version,
+		Datacenter:               s.agent.config.Datacenter,
+		NodeName:                 s.agent.config.NodeName,
+		NodeID:                   string(s.agent.config.NodeID),
+		Revision:                 s.agent.config.Revision,
+		Server:                   s.agent.config.ServerMode,
+		Version:                  s.agent.config.Version,
+		ACLDefaultPolicy:         aclDefaultPolicy,
+		ACLDefaultPolicyOverride: aclDefaultPolicyOverride,
 	}
 	return Self{
 		Config:      config,
@@ -90,6 +112,7 @@ func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (int
 		Member:      s.agent.LocalMember(),
 		Stats:       s.agent.Stats(),
 		Meta:        s.agent.State.Metadata(),
+		Warnings:    warnings,
 	}, nil
 }
 
@@ -114,8 +137,7 @@ func (s *HTTPServer) AgentMetrics(resp http.ResponseWriter, req *http.Request) (
 	}
 	if enablePrometheusOutput(req) {
 		if s.agent.config.Telemetry.PrometheusRetentionTime < 1 {
-			resp.WriteHeader(http.StatusUnsupportedMediaType)
-			fmt.Fprint(resp, "Prometheus is not enabled since its retention time is not positive")
+			s.writeError(resp, req, http.StatusUnsupportedMediaType, api.ErrCodeDisabled, "Prometheus is not enabled since its retention time is not positive", nil)
 			return nil, nil
 		}
 		handlerOptions := promhttp.HandlerOpts{
@@ -130,6 +152,24 @@ func (s *HTTPServer) AgentMetrics(resp http.ResponseWriter, req *http.Request) (
 	return s.agent.MemSink.DisplayMetrics(resp, req)
 }
 
+// AgentDebugTrace captures a runtime execution trace of the agent. It is
+// subject to the same access rules as the /debug/pprof endpoints, and only
+// one trace can be captured at a time.
+func (s *HTTPServer) AgentDebugTrace(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if !s.checkDebugAccess(resp, req, s.agent.config.EnableDebug) {
+		return nil, nil
+	}
+
+	if !s.profiles.acquire("trace") {
+		s.writeProfileBusy(resp, req, "trace")
+		return nil, nil
+	}
+	defer s.profiles.release("trace")
+
+	pprof.Trace(resp, req)
+	return nil, nil
+}
+
 func (s *HTTPServer) AgentReload(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Fetch the ACL token, if any, and enforce agent policy.
 	var token string
@@ -250,6 +290,164 @@ func (s *HTTPServer) AgentServices(resp http.ResponseWriter, req *http.Request)
 	return filter.Execute(agentSvcs)
 }
 
+// GET /v1/agent/services/export
+//
+// Returns the local services along with their checks in the format they are
+// registered with, so they can be registered again with another agent. The
+// sidecar services registered along with a service are folded back into its
+// Connect.SidecarService, unless the include-sidecars parameter is given in
+// which case they're returned as services of their own. Tokens are never
+// returned. The services defined in the agent's configuration files and the
+// node checks are left out, since they aren't registered through the API.
+func (s *HTTPServer) AgentServicesExport(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Fetch the ACL token, if any, and enforce agent policy. The definitions
+	// of the checks, like HTTP headers and script arguments, may hold
+	// secrets that service:read doesn't grant.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
+		return nil, acl.ErrPermissionDenied
+	}
+
+	_, includeSidecars := req.URL.Query()["include-sidecars"]
+
+	services := s.agent.State.Services()
+	for id := range s.agent.configServiceIDs() {
+		delete(services, id)
+	}
+	if err := s.agent.filterServices(token, &services); err != nil {
+		return nil, err
+	}
+	checks := s.agent.State.Checks()
+	if err := s.agent.filterChecks(token, &checks); err != nil {
+		return nil, err
+	}
+	proxies := s.agent.State.Proxies()
+	chkTypes := s.agent.localCheckTypes()
+
+	checkIDs := make([]string, 0, len(checks))
+	for id := range checks {
+		checkIDs = append(checkIDs, string(id))
+	}
+	sort.Strings(checkIDs)
+
+	serviceChecks := make(map[string]api.AgentServiceChecks)
+	for _, id := range checkIDs {
+		check := checks[types.CheckID(id)]
+		chkType, ok := chkTypes[check.CheckID]
+		if check.ServiceID == "" || !ok {
+			continue
+		}
+		serviceChecks[check.ServiceID] = append(serviceChecks[check.ServiceID],
+			buildServiceCheckRegistration(check, chkType))
+	}
+
+	serviceIDs := make([]string, 0, len(services))
+	for id := range services {
+		serviceIDs = append(serviceIDs, id)
+	}
+	sort.Strings(serviceIDs)
+
+	// Use empty list instead of nil
+	regs := make([]*api.AgentServiceRegistration, 0, len(services))
+	for _, id := range serviceIDs {
+		svc := services[id]
+		if svc.LocallyRegisteredAsSidecar && !includeSidecars {
+			continue
+		}
+		reg := buildServiceRegistration(svc, proxies, serviceChecks[id])
+
+		sidecar, ok := services[s.agent.sidecarServiceID(id)]
+		if ok && sidecar.LocallyRegisteredAsSidecar && !includeSidecars {
+			sidecarReg := buildServiceRegistration(sidecar, proxies, serviceChecks[sidecar.ID])
+
+			// The ID of a sidecar is always derived from its service.
+			sidecarReg.ID = ""
+			if reg.Connect == nil {
+				reg.Connect = &api.AgentServiceConnect{}
+			}
+			reg.Connect.SidecarService = sidecarReg
+		}
+
+		regs = append(regs, reg)
+	}
+	return regs, nil
+}
+
+// buildServiceRegistration returns the registration of a local service with
+// the given checks.
+func buildServiceRegistration(s *structs.NodeService, proxies map[string]*local.ManagedProxy,
+	checks api.AgentServiceChecks) *api.AgentServiceRegistration {
+
+	reg := &api.AgentServiceRegistration{
+		Kind:              api.ServiceKind(s.Kind),
+		ID:                s.ID,
+		Name:              s.Service,
+		Tags:              s.Tags,
+		Port:              s.Port,
+		Address:           s.Address,
+		EnableTagOverride: s.EnableTagOverride,
+		Meta:              s.Meta,
+		Checks:            checks,
+	}
+	if s.Weights != nil {
+		reg.Weights = &api.AgentWeights{
+			Passing: s.Weights.Passing,
+			Warning: s.Weights.Warning,
+		}
+	}
+	if s.Kind == structs.ServiceKindConnectProxy {
+		reg.Proxy = s.Proxy.ToAPI()
+	}
+
+	// Use the same Connect config as the services endpoint, which has the
+	// defaults filled in for managed proxies.
+	as := buildAgentService(s, proxies)
+	reg.Connect = as.Connect
+	return reg
+}
+
+// buildServiceCheckRegistration returns the registration of a local check
+// from the definition it was registered with.
+func buildServiceCheckRegistration(check *structs.HealthCheck, chkType *structs.CheckType) *api.AgentServiceCheck {
+	return &api.AgentServiceCheck{
+		CheckID:                        string(check.CheckID),
+		Name:                           check.Name,
+		Args:                           chkType.ScriptArgs,
+		DockerContainerID:              chkType.DockerContainerID,
+		Shell:                          chkType.Shell,
+		Interval:                       durationString(chkType.Interval),
+		Timeout:                        durationString(chkType.Timeout),
+		TTL:                            durationString(chkType.TTL),
+		HTTP:                           chkType.HTTP,
+		Header:                         chkType.Header,
+		Method:                         chkType.Method,
+		TCP:                            chkType.TCP,
+		Status:                         chkType.Status,
+		Notes:                          check.Notes,
+		TLSSkipVerify:                  chkType.TLSSkipVerify,
+		GRPC:                           chkType.GRPC,
+		GRPCUseTLS:                     chkType.GRPCUseTLS,
+		AliasNode:                      chkType.AliasNode,
+		AliasService:                   chkType.AliasService,
+		DeregisterCriticalServiceAfter: durationString(chkType.DeregisterCriticalServiceAfter),
+		DependsOnCheck:                 string(chkType.DependsOnCheck),
+	}
+}
+
+// durationString formats a duration the way it's registered, leaving it
+// empty when it's not set.
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
 // GET /v1/agent/service/:service_id
 //
 // Returns the service definition for a single local services and allows
@@ -322,7 +520,7 @@ func (s *HTTPServer) AgentService(resp http.ResponseWriter, req *http.Request) (
 
 	// Maybe block
 	var queryOpts structs.QueryOptions
-	if parseWait(resp, req, &queryOpts) {
+	if s.parseWait(resp, req, &queryOpts) {
 		// parseWait returns an error itself
 		return nil, nil
 	}
@@ -340,8 +538,7 @@ func (s *HTTPServer) AgentService(resp http.ResponseWriter, req *http.Request) (
 
 			svcState := s.agent.State.ServiceState(id)
 			if svcState == nil {
-				resp.WriteHeader(http.StatusNotFound)
-				fmt.Fprintf(resp, "unknown proxy service ID: %s", id)
+				s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, fmt.Sprintf("unknown proxy service ID: %s", id), nil)
 				return "", nil, nil
 			}
 
@@ -425,12 +622,16 @@ func (s *HTTPServer) AgentChecks(resp http.ResponseWriter, req *http.Request) (i
 	}
 
 	// Use empty list instead of nil
+	summarize := summarizeOutput(req)
 	for id, c := range checks {
 		if c.ServiceTags == nil {
 			clone := *c
 			clone.ServiceTags = make([]string, 0)
 			checks[id] = &clone
 		}
+		if summarize {
+			checks[id] = checks[id].SummarizeOutput()
+		}
 	}
 
 	return filter.Execute(checks)
@@ -455,8 +656,7 @@ func (s *HTTPServer) AgentMembers(resp http.ResponseWriter, req *http.Request) (
 			// key are ok, otherwise the argument doesn't apply to
 			// the WAN.
 		default:
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(resp, "Cannot provide a segment with wan=true")
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Cannot provide a segment with wan=true", nil)
 			return nil, nil
 		}
 	}
@@ -559,21 +759,18 @@ func (s *HTTPServer) AgentRegisterCheck(resp http.ResponseWriter, req *http.Requ
 		return FixupCheckType(raw)
 	}
 	if err := decodeBody(req, &args, decodeCB); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
 	// Verify the check has a name.
 	if args.Name == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing check name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing check name", nil)
 		return nil, nil
 	}
 
 	if args.Status != "" && !structs.ValidStatus(args.Status) {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Bad check status")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Bad check status", nil)
 		return nil, nil
 	}
 
@@ -584,8 +781,7 @@ func (s *HTTPServer) AgentRegisterCheck(resp http.ResponseWriter, req *http.Requ
 	chkType := args.CheckType()
 	err := chkType.Validate()
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, fmt.Errorf("Invalid check: %v", err))
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid check: %v", err), nil)
 		return nil, nil
 	}
 
@@ -693,8 +889,7 @@ type checkUpdate struct {
 func (s *HTTPServer) AgentCheckUpdate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	var update checkUpdate
 	if err := decodeBody(req, &update, nil); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
@@ -703,8 +898,7 @@ func (s *HTTPServer) AgentCheckUpdate(resp http.ResponseWriter, req *http.Reques
 	case api.HealthWarning:
 	case api.HealthCritical:
 	default:
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Invalid check status: '%s'", update.Status)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid check status: '%s'", update.Status), nil)
 		return nil, nil
 	}
 
@@ -917,23 +1111,20 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 		return nil
 	}
 	if err := decodeBody(req, &args, decodeCB); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
 	// Verify the service has a name.
 	if args.Name == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing service name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service name", nil)
 		return nil, nil
 	}
 
 	// Check the service address here and in the catalog RPC endpoint
 	// since service registration isn't synchronous.
 	if ipaddr.IsAny(args.Address) {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Invalid service address")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid service address"), nil)
 		return nil, nil
 	}
 
@@ -941,36 +1132,31 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 	ns := args.NodeService()
 	if ns.Weights != nil {
 		if err := structs.ValidateWeights(ns.Weights); err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(resp, fmt.Errorf("Invalid Weights: %v", err))
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid Weights: %v", err), nil)
 			return nil, nil
 		}
 	}
-	if err := structs.ValidateMetadata(ns.Meta, false); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, fmt.Errorf("Invalid Service Meta: %v", err))
+	if err := structs.ValidateServiceMetadata(ns.Meta); err != nil {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid Service Meta: %v", err), nil)
 		return nil, nil
 	}
 
 	// Run validation. This is the same validation that would happen on
 	// the catalog endpoint so it helps ensure the sync will work properly.
 	if err := ns.Validate(); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, err.Error())
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf(err.Error()), nil)
 		return nil, nil
 	}
 
 	// Verify the check type.
 	chkTypes, err := args.CheckTypes()
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, fmt.Errorf("Invalid check: %v", err))
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid check: %v", err), nil)
 		return nil, nil
 	}
 	for _, check := range chkTypes {
 		if check.Status != "" && !structs.ValidStatus(check.Status) {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(resp, "Status for checks must 'passing', 'warning', 'critical'")
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Status for checks must 'passing', 'warning', 'critical'", nil)
 			return nil, nil
 		}
 	}
@@ -1022,8 +1208,7 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 	// Get any proxy registrations
 	proxy, err := args.ConnectManagedProxy()
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, err.Error())
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf(err.Error()), nil)
 		return nil, nil
 	}
 
@@ -1083,24 +1268,21 @@ func (s *HTTPServer) AgentServiceMaintenance(resp http.ResponseWriter, req *http
 	// Ensure we have a service ID
 	serviceID := strings.TrimPrefix(req.URL.Path, "/v1/agent/service/maintenance/")
 	if serviceID == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing service ID")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service ID", nil)
 		return nil, nil
 	}
 
 	// Ensure we have some action
 	params := req.URL.Query()
 	if _, ok := params["enable"]; !ok {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing value for enable")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing value for enable", nil)
 		return nil, nil
 	}
 
 	raw := params.Get("enable")
 	enable, err := strconv.ParseBool(raw)
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Invalid value for enable: %q", raw)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid value for enable: %q", raw), nil)
 		return nil, nil
 	}
 
@@ -1114,14 +1296,12 @@ func (s *HTTPServer) AgentServiceMaintenance(resp http.ResponseWriter, req *http
 	if enable {
 		reason := params.Get("reason")
 		if err = s.agent.EnableServiceMaintenance(serviceID, reason, token); err != nil {
-			resp.WriteHeader(http.StatusNotFound)
-			fmt.Fprint(resp, err.Error())
+			s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, err.Error(), nil)
 			return nil, nil
 		}
 	} else {
 		if err = s.agent.DisableServiceMaintenance(serviceID); err != nil {
-			resp.WriteHeader(http.StatusNotFound)
-			fmt.Fprint(resp, err.Error())
+			s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, err.Error(), nil)
 			return nil, nil
 		}
 	}
@@ -1133,16 +1313,14 @@ func (s *HTTPServer) AgentNodeMaintenance(resp http.ResponseWriter, req *http.Re
 	// Ensure we have some action
 	params := req.URL.Query()
 	if _, ok := params["enable"]; !ok {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing value for enable")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing value for enable", nil)
 		return nil, nil
 	}
 
 	raw := params.Get("enable")
 	enable, err := strconv.ParseBool(raw)
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Invalid value for enable: %q", raw)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid value for enable: %q", raw), nil)
 		return nil, nil
 	}
 
@@ -1166,6 +1344,53 @@ func (s *HTTPServer) AgentNodeMaintenance(resp http.ResponseWriter, req *http.Re
 	return nil, nil
 }
 
+// AgentNodeDrain drains or undrains the node. A drained node keeps its service
+// instances in the catalog, but they're excluded from the DNS answers and the
+// health results.
+func (s *HTTPServer) AgentNodeDrain(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Ensure we have some action
+	params := req.URL.Query()
+	if _, ok := params["enable"]; !ok {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing value for enable", nil)
+		return nil, nil
+	}
+
+	raw := params.Get("enable")
+	enable, err := strconv.ParseBool(raw)
+	if err != nil {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid value for enable: %q", raw), nil)
+		return nil, nil
+	}
+
+	var duration time.Duration
+	if raw := params.Get("duration"); raw != "" {
+		duration, err = time.ParseDuration(raw)
+		if err != nil || duration < 0 {
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid value for duration: %q", raw), nil)
+			return nil, nil
+		}
+	}
+
+	// Get the provided token, if any, and vet against any ACL policies.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && !rule.NodeWrite(s.agent.config.NodeName, nil) {
+		return nil, acl.ErrPermissionDenied
+	}
+
+	if enable {
+		s.agent.EnableNodeDrain(duration)
+	} else {
+		s.agent.DisableNodeDrain()
+	}
+	s.syncChanges()
+	return nil, nil
+}
+
 func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Fetch the ACL token, if any, and enforce agent policy.
 	var token string
@@ -1191,8 +1416,7 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 	filter := logger.LevelFilter()
 	filter.MinLevel = logutils.LogLevel(logLevel)
 	if !logger.ValidateLevelFilter(filter.MinLevel, filter) {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Unknown log level: %s", filter.MinLevel)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Unknown log level: %s", filter.MinLevel), nil)
 		return nil, nil
 	}
 	flusher, ok := resp.(http.Flusher)
@@ -1277,8 +1501,7 @@ func (s *HTTPServer) AgentToken(resp http.ResponseWriter, req *http.Request) (in
 	// fields to this later if needed.
 	var args api.AgentToken
 	if err := decodeBody(req, &args, nil); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
@@ -1310,8 +1533,7 @@ func (s *HTTPServer) AgentToken(resp http.ResponseWriter, req *http.Request) (in
 		s.agent.tokens.UpdateReplicationToken(args.Token, token_store.TokenSourceAPI)
 
 	default:
-		resp.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(resp, "Token %q is unknown", target)
+		s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, fmt.Sprintf("Token %q is unknown", target), nil)
 		return nil, nil
 	}
 
@@ -1435,7 +1657,7 @@ func (s *HTTPServer) AgentConnectProxyConfig(resp http.ResponseWriter, req *http
 
 	// Maybe block
 	var queryOpts structs.QueryOptions
-	if parseWait(resp, req, &queryOpts) {
+	if s.parseWait(resp, req, &queryOpts) {
 		// parseWait returns an error itself
 		return nil, nil
 	}
@@ -1456,8 +1678,7 @@ func (s *HTTPServer) AgentConnectProxyConfig(resp http.ResponseWriter, req *http
 			// Retrieve the proxy specified
 			proxy := s.agent.State.Proxy(id)
 			if proxy == nil {
-				resp.WriteHeader(http.StatusNotFound)
-				fmt.Fprintf(resp, "unknown proxy service ID: %s", id)
+				s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, fmt.Sprintf("unknown proxy service ID: %s", id), nil)
 				return "", nil, nil
 			}
 
@@ -1466,8 +1687,7 @@ func (s *HTTPServer) AgentConnectProxyConfig(resp http.ResponseWriter, req *http
 			if target == nil {
 				// Not found since this endpoint is only useful for agent-managed proxies so
 				// service missing means the service was deregistered racily with this call.
-				resp.WriteHeader(http.StatusNotFound)
-				fmt.Fprintf(resp, "unknown target service ID: %s", proxy.Proxy.TargetServiceID)
+				s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, fmt.Sprintf("unknown target service ID: %s", proxy.Proxy.TargetServiceID), nil)
 				return "", nil, nil
 			}
 
@@ -1672,3 +1892,105 @@ func (s *HTTPServer) AgentHost(resp http.ResponseWriter, req *http.Request) (int
 
 	return debug.CollectHostInfo(), nil
 }
+
+// agentConnectionResponse is the readiness of the agent to handle Connect
+// traffic for its local services.
+type agentConnectionResponse struct {
+	Ready           bool
+	LeafCertPrewarm leafPrewarmStatus
+}
+
+// GET /v1/agent/connection
+//
+// Reports whether the agent is ready to serve Connect traffic for its local
+// services, which is once their leaf certificates have been pre-warmed after
+// startup. Returns a 503 until then so it can be used as a readiness check.
+// Requires an agent:read ACL token.
+func (s *HTTPServer) AgentConnection(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Fetch the ACL token, if any, and enforce agent policy.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
+		return nil, acl.ErrPermissionDenied
+	}
+
+	status := s.agent.prewarmStatus()
+	out := &agentConnectionResponse{
+		Ready:           status.Done,
+		LeafCertPrewarm: status,
+	}
+	if !out.Ready {
+		return out, CodeWithPayloadError{
+			StatusCode:  http.StatusServiceUnavailable,
+			Reason:      "Leaf certificates are still being fetched",
+			ContentType: "application/json",
+		}
+	}
+	return out, nil
+}
+
+// GET /v1/agent/dns/simulate
+//
+// Resolves a DNS query in-process, the same way the DNS interface of the
+// agent does, and returns the response along with the decisions made while
+// resolving it. Requires an agent:read ACL token.
+func (s *HTTPServer) AgentDNSSimulate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Fetch the ACL token, if any, and enforce agent policy.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
+		return nil, acl.ErrPermissionDenied
+	}
+
+	query := req.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		return nil, &BadRequestError{Reason: "Missing name"}
+	}
+	qType := dns.TypeA
+	if v := query.Get("type"); v != "" {
+		t, ok := dns.StringToType[strings.ToUpper(v)]
+		if !ok {
+			return nil, &BadRequestError{Reason: fmt.Sprintf("Invalid type %q", v)}
+		}
+		qType = t
+	}
+	network := "udp"
+	if v := query.Get("network"); v != "" {
+		if v != "udp" && v != "tcp" {
+			return nil, &BadRequestError{Reason: fmt.Sprintf("Invalid network %q, must be udp or tcp", v)}
+		}
+		network = v
+	}
+
+	if len(s.agent.dnsServers) == 0 {
+		return nil, &BadRequestError{Reason: "The DNS interface is disabled"}
+	}
+
+	// The query is made from the address of the HTTP client, which matters
+	// for the routing overrides.
+	var remoteAddr net.Addr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			if network == "tcp" {
+				remoteAddr = &net.TCPAddr{IP: ip}
+			} else {
+				remoteAddr = &net.UDPAddr{IP: ip}
+			}
+		}
+	}
+
+	out, err := s.agent.dnsServers[0].Simulate(network, name, qType, remoteAddr)
+	if err != nil {
+		return nil, &BadRequestError{Reason: err.Error()}
+	}
+	return out, nil
+}
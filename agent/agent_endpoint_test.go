@@ -271,6 +271,152 @@ func TestAgent_Services_ACLFilter(t *testing.T) {
 	})
 }
 
+func TestAgent_ServicesExport(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		services {
+			name = "config"
+			port = 9000
+			connect {
+				sidecar_service {}
+			}
+		}
+	`)
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+	args := &structs.ServiceDefinition{
+		ID:    "web1",
+		Name:  "web",
+		Port:  8080,
+		Token: "web-token",
+		Checks: structs.CheckTypes{
+			&structs.CheckType{
+				Name:   "ttl",
+				TTL:    15 * time.Second,
+				Status: api.HealthPassing,
+			},
+			&structs.CheckType{
+				CheckID:                        "web-http",
+				Name:                           "http",
+				HTTP:                           "http://127.0.0.1:8080/health",
+				Method:                         "HEAD",
+				Interval:                       10 * time.Second,
+				Timeout:                        time.Second,
+				DeregisterCriticalServiceAfter: time.Hour,
+			},
+		},
+		Connect: &structs.ServiceConnect{
+			SidecarService: &structs.ServiceDefinition{
+				Port: 21000,
+			},
+		},
+	}
+	req, _ := http.NewRequest("PUT", "/v1/agent/service/register", jsonReader(args))
+	_, err := a.srv.AgentRegisterService(nil, req)
+	require.NoError(t, err)
+
+	t.Run("sidecars folded", func(t *testing.T) {
+		require := require.New(t)
+		req, _ := http.NewRequest("GET", "/v1/agent/services/export", nil)
+		obj, err := a.srv.AgentServicesExport(nil, req)
+		require.NoError(err)
+		regs := obj.([]*api.AgentServiceRegistration)
+		require.Len(regs, 1)
+
+		web := regs[0]
+		require.Equal("web1", web.ID)
+		require.Equal("web", web.Name)
+		require.Equal(8080, web.Port)
+		require.Equal(api.AgentServiceChecks{
+			&api.AgentServiceCheck{
+				CheckID: "service:web1:1",
+				Name:    "ttl",
+				TTL:     "15s",
+				Status:  api.HealthPassing,
+			},
+			&api.AgentServiceCheck{
+				CheckID:                        "web-http",
+				Name:                           "http",
+				HTTP:                           "http://127.0.0.1:8080/health",
+				Method:                         "HEAD",
+				Interval:                       "10s",
+				Timeout:                        "1s",
+				DeregisterCriticalServiceAfter: "1h0m0s",
+			},
+		}, web.Checks)
+
+		require.NotNil(web.Connect)
+		sidecar := web.Connect.SidecarService
+		require.NotNil(sidecar)
+		require.Empty(sidecar.ID)
+		require.Equal("web-sidecar-proxy", sidecar.Name)
+		require.Equal(21000, sidecar.Port)
+		require.Equal("web1", sidecar.Proxy.DestinationServiceID)
+		require.Len(sidecar.Checks, 2)
+		require.Equal("127.0.0.1:21000", sidecar.Checks[0].TCP)
+		require.Equal("web1", sidecar.Checks[1].AliasService)
+	})
+
+	t.Run("include sidecars", func(t *testing.T) {
+		require := require.New(t)
+		req, _ := http.NewRequest("GET", "/v1/agent/services/export?include-sidecars", nil)
+		obj, err := a.srv.AgentServicesExport(nil, req)
+		require.NoError(err)
+
+		// The services from the configuration files are left out.
+		require.NotNil(a.State.Service("config"))
+		require.NotNil(a.State.Service("config-sidecar-proxy"))
+		regs := obj.([]*api.AgentServiceRegistration)
+		require.Len(regs, 2)
+		require.Equal("web1", regs[0].ID)
+		require.Nil(regs[0].Connect)
+		require.Equal("web1-sidecar-proxy", regs[1].ID)
+		require.Equal(api.ServiceKindConnectProxy, regs[1].Kind)
+		require.Len(regs[1].Checks, 2)
+	})
+}
+
+func TestAgent_ServicesExport_ACLFilter(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), TestACLConfig())
+	defer a.Shutdown()
+
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+	srv1 := &structs.NodeService{
+		ID:      "mysql",
+		Service: "mysql",
+		Port:    5000,
+	}
+	require.NoError(t, a.AddService(srv1, []*structs.CheckType{
+		&structs.CheckType{TTL: time.Minute},
+	}, false, "", ConfigSourceLocal))
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/services/export", nil)
+		_, err := a.srv.AgentServicesExport(nil, req)
+		require.True(t, acl.IsErrPermissionDenied(err), "err: %v", err)
+	})
+
+	t.Run("read-only token", func(t *testing.T) {
+		ro := makeReadOnlyAgentACL(t, a.srv)
+		req, _ := http.NewRequest("GET", "/v1/agent/services/export?token="+ro, nil)
+		obj, err := a.srv.AgentServicesExport(nil, req)
+		require.NoError(t, err)
+		require.Len(t, obj.([]*api.AgentServiceRegistration), 0)
+	})
+
+	t.Run("root token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/services/export?token=root", nil)
+		obj, err := a.srv.AgentServicesExport(nil, req)
+		require.NoError(t, err)
+		regs := obj.([]*api.AgentServiceRegistration)
+		require.Len(t, regs, 1)
+		require.Len(t, regs[0].Checks, 1)
+		require.Equal(t, "1m0s", regs[0].Checks[0].TTL)
+	})
+}
+
 func TestAgent_Service(t *testing.T) {
 	t.Parallel()
 
@@ -672,6 +818,33 @@ func TestAgent_Checks(t *testing.T) {
 	}
 }
 
+func TestAgent_Checks_SummarizeOutput(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+	chk1 := &structs.HealthCheck{
+		Node:    a.Config.NodeName,
+		CheckID: "mysql",
+		Name:    "mysql",
+		Status:  api.HealthPassing,
+		Output:  strings.Repeat("x", 4096),
+	}
+	a.State.AddCheck(chk1, "")
+
+	req, _ := http.NewRequest("GET", "/v1/agent/checks?summarize-output=true", nil)
+	obj, err := a.srv.AgentChecks(nil, req)
+	require.NoError(t, err)
+	val := obj.(map[types.CheckID]*structs.HealthCheck)
+	require.Len(t, val, 1)
+	require.Len(t, val["mysql"].Output, structs.CheckOutputSummaryLength)
+	require.Equal(t, 4096, val["mysql"].OutputDigest.Length)
+
+	// The local state is left untouched.
+	require.Len(t, a.State.Check("mysql").Output, 4096)
+}
+
 func TestAgent_ChecksWithFilter(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
@@ -1218,6 +1391,63 @@ func TestAgent_Self(t *testing.T) {
 	}
 }
 
+func TestAgent_Self_IncludeWarnings(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		disable_remote_exec = false
+		lint_suppress = ["gossip-unauthenticated"]
+	`)
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/agent/self", nil)
+	obj, err := a.srv.AgentSelf(nil, req)
+	require.NoError(t, err)
+	require.Nil(t, obj.(Self).Warnings)
+
+	req, _ = http.NewRequest("GET", "/v1/agent/self?include-warnings=true", nil)
+	obj, err = a.srv.AgentSelf(nil, req)
+	require.NoError(t, err)
+	warnings := obj.(Self).Warnings
+	require.Equal(t, config.Lint(a.config), warnings)
+
+	var ids []string
+	for _, w := range warnings {
+		ids = append(ids, w.ID)
+	}
+	require.Contains(t, ids, "remote-exec-enabled")
+	require.NotContains(t, ids, "gossip-unauthenticated")
+}
+
+func TestAgent_Self_ACLDefaultPolicyOverride(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		datacenter = "dc2"
+		primary_datacenter = "dc1"
+		acl {
+			enabled = true
+			default_policy = "deny"
+			default_policy_override = "allow"
+			down_policy = "allow"
+		}
+	`)
+	defer a.Shutdown()
+
+	testrpc.WaitForLeader(t, a.RPC, "dc2")
+
+	req, _ := http.NewRequest("GET", "/v1/agent/self", nil)
+	obj, err := a.srv.AgentSelf(nil, req)
+	require.NoError(t, err)
+
+	buf, err := json.Marshal(obj.(Self).Config)
+	require.NoError(t, err)
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &config))
+	require.Equal(t, "allow", config["ACLDefaultPolicy"])
+	require.Equal(t, true, config["ACLDefaultPolicyOverride"])
+}
+
 func TestAgent_Self_ACLDeny(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), TestACLConfig())
@@ -3968,6 +4198,113 @@ func TestAgent_NodeMaintenance_Enable(t *testing.T) {
 	}
 }
 
+func TestAgent_NodeDrain(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	srv := &structs.NodeService{
+		ID:      "web",
+		Service: "web",
+		Port:    8000,
+	}
+	require.NoError(t, a.AddService(srv, nil, false, "", ConfigSourceLocal))
+
+	healthNodes := func(r *retry.R, query string) structs.CheckServiceNodes {
+		req, _ := http.NewRequest("GET", "/v1/health/service/web"+query, nil)
+		obj, err := a.srv.HealthServiceNodes(httptest.NewRecorder(), req)
+		if err != nil {
+			r.Fatal(err)
+		}
+		return obj.(structs.CheckServiceNodes)
+	}
+
+	// Drain the node
+	req, _ := http.NewRequest("PUT", "/v1/agent/drain?enable=true", nil)
+	resp := httptest.NewRecorder()
+	_, err := a.srv.AgentNodeDrain(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.Code)
+	require.Contains(t, a.State.Metadata(), structs.NodeDrainedMetaKey)
+
+	// The instance is excluded from the health results, unless asked
+	// otherwise, but stays in the catalog.
+	retry.Run(t, func(r *retry.R) {
+		if nodes := healthNodes(r, ""); len(nodes) != 0 {
+			r.Fatalf("bad: %v", nodes)
+		}
+	})
+	retry.Run(t, func(r *retry.R) {
+		if nodes := healthNodes(r, "?exclude-drained=false"); len(nodes) != 1 {
+			r.Fatalf("bad: %v", nodes)
+		}
+	})
+	req, _ = http.NewRequest("GET", "/v1/catalog/service/web", nil)
+	obj, err := a.srv.CatalogServiceNodes(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	services := obj.(structs.ServiceNodes)
+	require.Len(t, services, 1)
+	require.True(t, services[0].Drained)
+
+	// Undrain the node
+	req, _ = http.NewRequest("PUT", "/v1/agent/drain?enable=false", nil)
+	_, err = a.srv.AgentNodeDrain(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	require.NotContains(t, a.State.Metadata(), structs.NodeDrainedMetaKey)
+	retry.Run(t, func(r *retry.R) {
+		if nodes := healthNodes(r, ""); len(nodes) != 1 {
+			r.Fatalf("bad: %v", nodes)
+		}
+	})
+
+	// A drain with a duration ends by itself
+	req, _ = http.NewRequest("PUT", "/v1/agent/drain?enable=true&duration=100ms", nil)
+	_, err = a.srv.AgentNodeDrain(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	require.Contains(t, a.State.Metadata(), structs.NodeDrainedMetaKey)
+	retry.Run(t, func(r *retry.R) {
+		if _, ok := a.State.Metadata()[structs.NodeDrainedMetaKey]; ok {
+			r.Fatal("should have undrained the node")
+		}
+	})
+
+	// The drain survives a reload
+	a.EnableNodeDrain(0)
+	require.NoError(t, a.ReloadConfig(a.Config))
+	require.Contains(t, a.State.Metadata(), structs.NodeDrainedMetaKey)
+
+	// Invalid values are rejected
+	for _, query := range []string{"", "?enable=yes", "?enable=true&duration=soon", "?enable=true&duration=-1s"} {
+		req, _ = http.NewRequest("PUT", "/v1/agent/drain"+query, nil)
+		resp = httptest.NewRecorder()
+		_, err = a.srv.AgentNodeDrain(resp, req)
+		require.NoError(t, err)
+		require.Equal(t, 400, resp.Code, query)
+	}
+}
+
+func TestAgent_NodeDrain_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/drain?enable=true", nil)
+		if _, err := a.srv.AgentNodeDrain(nil, req); !acl.IsErrPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("root token", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/drain?enable=true&token=root", nil)
+		if _, err := a.srv.AgentNodeDrain(nil, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestAgent_NodeMaintenance_Disable(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
@@ -6274,3 +6611,188 @@ func TestAgent_HostBadACL(t *testing.T) {
 	assert.Equal(http.StatusOK, resp.Code)
 	assert.Nil(respRaw)
 }
+
+func TestAgent_Connection_leafCertPrewarm(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t, t.Name(), `
+		services {
+			name = "web"
+			port = 8080
+			connect {
+				native = true
+			}
+		}
+		services {
+			name = "api"
+			port = 9090
+			connect {
+				sidecar_service {}
+			}
+		}
+		services {
+			name = "db"
+			port = 5432
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	retry.Run(t, func(r *retry.R) {
+		req, _ := http.NewRequest("GET", "/v1/agent/connection", nil)
+		resp := httptest.NewRecorder()
+		a.srv.wrap(a.srv.AgentConnection, []string{"GET"})(resp, req)
+		if resp.Code != http.StatusOK {
+			r.Fatalf("bad code %d: %s", resp.Code, resp.Body.String())
+		}
+
+		var out agentConnectionResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &out); err != nil {
+			r.Fatal(err)
+		}
+		if !out.Ready || !out.LeafCertPrewarm.Enabled || !out.LeafCertPrewarm.Done {
+			r.Fatalf("bad: %#v", out)
+		}
+		if out.LeafCertPrewarm.Services != 2 || out.LeafCertPrewarm.Failures != 0 {
+			r.Fatalf("bad: %#v", out)
+		}
+	})
+
+	// Both leaf certificates should now be served from the cache.
+	for _, service := range []string{"web", "api"} {
+		req, _ := http.NewRequest("GET", "/v1/agent/connect/ca/leaf/"+service, nil)
+		resp := httptest.NewRecorder()
+		_, err := a.srv.AgentConnectCALeafCert(resp, req)
+		require.NoError(err)
+		require.Equal("HIT", resp.Header().Get("X-Cache"), service)
+	}
+}
+
+func TestAgent_Connection_leafCertPrewarmDisabled(t *testing.T) {
+	t.Parallel()
+
+	a := NewTestAgent(t, t.Name(), `
+		connect {
+			leaf_cert_prewarm = false
+		}
+	`)
+	defer a.Shutdown()
+
+	req, _ := http.NewRequest("GET", "/v1/agent/connection", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.AgentConnection(resp, req)
+	require.NoError(t, err)
+	out := obj.(*agentConnectionResponse)
+	require.True(t, out.Ready)
+	require.False(t, out.LeafCertPrewarm.Enabled)
+}
+
+func TestAgent_Connection_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/connection", nil)
+		_, err := a.srv.AgentConnection(nil, req)
+		require.True(t, acl.IsErrPermissionDenied(err))
+	})
+
+	t.Run("agent read token", func(t *testing.T) {
+		ro := makeReadOnlyAgentACL(t, a.srv)
+		req, _ := http.NewRequest("GET", "/v1/agent/connection?token="+ro, nil)
+		_, err := a.srv.AgentConnection(httptest.NewRecorder(), req)
+		require.NoError(t, err)
+	})
+}
+
+func TestAgent_DNSSimulate(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	a := NewTestAgent(t, t.Name(), `
+		dns_config {
+			service_ttl = {
+				"db" = "10s"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			Service: "db",
+			Port:    12345,
+		},
+	}
+	var out struct{}
+	require.NoError(a.RPC("Catalog.Register", args, &out))
+
+	t.Run("service", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/dns/simulate?name=db.service.consul&type=srv", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.AgentDNSSimulate(resp, req)
+		require.NoError(err)
+
+		sim := obj.(*DNSSimulation)
+		require.Equal("db.service.consul.", sim.Name)
+		require.Equal("SRV", sim.Type)
+		require.Equal("NOERROR", sim.Rcode)
+		require.Len(sim.Answer, 1)
+		require.Contains(sim.Answer[0], "12345 foo.node.dc1.consul.")
+		require.Equal([]string{
+			`Looking up service "db" with tag "" in datacenter "dc1"`,
+			"Found 1 instances, 1 left after filtering on health (only_passing = false)",
+			"Using a TTL of 10s",
+		}, sim.Trace)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/dns/simulate?name=web.service.consul", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.AgentDNSSimulate(resp, req)
+		require.NoError(err)
+
+		sim := obj.(*DNSSimulation)
+		require.Equal("A", sim.Type)
+		require.Equal("NXDOMAIN", sim.Rcode)
+		require.Empty(sim.Answer)
+		require.Len(sim.Ns, 1)
+		require.Equal("No instances left, answering NXDOMAIN", sim.Trace[len(sim.Trace)-1])
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		for url, reason := range map[string]string{
+			"/v1/agent/dns/simulate":                                    "Missing name",
+			"/v1/agent/dns/simulate?name=db.service.consul&type=nope":   `Invalid type "nope"`,
+			"/v1/agent/dns/simulate?name=db.service.consul&network=foo": `Invalid network "foo", must be udp or tcp`,
+			"/v1/agent/dns/simulate?name=example.com":                   `Name "example.com." is not in the consul. domain`,
+		} {
+			req, _ := http.NewRequest("GET", url, nil)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.AgentDNSSimulate(resp, req)
+			require.Error(err, url)
+			require.Equal(BadRequestError{Reason: reason}.Error(), err.Error(), url)
+		}
+	})
+}
+
+func TestAgent_DNSSimulate_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/agent/dns/simulate?name=db.service.consul", nil)
+	_, err := a.srv.AgentDNSSimulate(nil, req)
+	require.True(t, acl.IsErrPermissionDenied(err))
+
+	req, _ = http.NewRequest("GET", "/v1/agent/dns/simulate?name=db.service.consul&token=root", nil)
+	_, err = a.srv.AgentDNSSimulate(nil, req)
+	require.NoError(t, err)
+}
@@ -497,6 +497,23 @@ func TestAgent_AddService(t *testing.T) {
 	}
 }
 
+func TestAgent_AddService_invalidSRVTarget(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	srv := &structs.NodeService{
+		ID:      "svcid1",
+		Service: "svcname1",
+		Port:    8100,
+		Meta:    map[string]string{structs.MetaDNSSRVTarget: "not_a host"},
+	}
+	err := a.AddService(srv, nil, false, "", ConfigSourceLocal)
+	if err == nil || !strings.Contains(err.Error(), "not a valid DNS name") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestAgent_AddServiceNoExec(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), `
@@ -1159,6 +1176,90 @@ func TestAgent_AddCheck_Alias(t *testing.T) {
 	require.Equal("", cs.Token)
 }
 
+func TestAgent_AddCheck_DependsOnCheck(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	db := &structs.HealthCheck{
+		Node:    "foo",
+		CheckID: "db",
+		Name:    "db",
+		Status:  api.HealthCritical,
+	}
+	require.NoError(a.AddCheck(db, &structs.CheckType{TTL: time.Minute}, false, "", ConfigSourceLocal))
+
+	app := &structs.HealthCheck{
+		Node:    "foo",
+		CheckID: "app",
+		Name:    "app",
+		Status:  api.HealthPassing,
+	}
+	chk := &structs.CheckType{TTL: time.Minute, DependsOnCheck: "db"}
+	require.NoError(a.AddCheck(app, chk, false, "", ConfigSourceLocal))
+
+	// The check is blocked while its dependency is critical.
+	cs := a.State.CheckState("app")
+	require.NotNil(cs)
+	require.Equal(types.CheckID("db"), cs.BlockedBy)
+	require.Equal(api.HealthCritical, cs.Check.Status)
+	require.Equal("blocked by db", cs.Check.Output)
+
+	require.NoError(a.updateTTLCheck("db", api.HealthPassing, ""))
+	cs = a.State.CheckState("app")
+	require.Equal(types.CheckID(""), cs.BlockedBy)
+	require.Equal(api.HealthPassing, cs.Check.Status)
+
+	// A dependency cycle is rejected when the check is registered.
+	chk = &structs.CheckType{TTL: time.Minute, DependsOnCheck: "app"}
+	err := a.AddCheck(db, chk, false, "", ConfigSourceLocal)
+	require.Error(err)
+	require.Contains(err.Error(), "dependency cycle")
+}
+
+func TestAgent_AddService_DependsOnCheck_failedRegistration(t *testing.T) {
+	// Not parallel since it makes the docker client fail through the
+	// environment.
+	require := require.New(t)
+	a := NewTestAgent(t, t.Name(), `
+		enable_local_script_checks = true
+	`)
+	defer a.Shutdown()
+
+	srv := &structs.NodeService{
+		ID:      "web",
+		Service: "web",
+		Port:    8000,
+	}
+	chkTypes := []*structs.CheckType{
+		&structs.CheckType{CheckID: "db", TTL: time.Minute, Status: api.HealthCritical},
+		&structs.CheckType{CheckID: "app", TTL: time.Minute, Status: api.HealthPassing},
+	}
+	require.NoError(a.AddService(srv, chkTypes, false, "", ConfigSourceLocal))
+	require.False(a.State.CheckBlocked("app"))
+
+	// Re-registering the check with a dependency fails after the
+	// dependency is validated.
+	os.Setenv("DOCKER_HOST", "invalid")
+	defer os.Unsetenv("DOCKER_HOST")
+	chkTypes[1] = &structs.CheckType{
+		CheckID:           "app",
+		DockerContainerID: "container",
+		ScriptArgs:        []string{"true"},
+		Interval:          time.Minute,
+		DependsOnCheck:    "db",
+	}
+	err := a.AddService(srv, chkTypes, false, "", ConfigSourceLocal)
+	require.Error(err)
+	require.Contains(err.Error(), "unable to parse docker host")
+
+	// The dependency wasn't left behind.
+	a.State.UpdateCheck("app", api.HealthPassing, "")
+	require.False(a.State.CheckBlocked("app"))
+}
+
 func TestAgent_AddCheck_Alias_setToken(t *testing.T) {
 	t.Parallel()
 
@@ -16,6 +16,7 @@ package cache
 
 import (
 	"container/heap"
+	"context"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -211,13 +212,20 @@ func (c *Cache) RegisterType(n string, typ Type, opts *RegisterOptions) {
 // error is returned on timeout. This matches the behavior of Consul blocking
 // queries.
 func (c *Cache) Get(t string, r Request) (interface{}, ResultMeta, error) {
-	return c.getWithIndex(t, r, r.CacheInfo().MinIndex)
+	return c.getWithIndex(context.Background(), t, r, r.CacheInfo().MinIndex)
+}
+
+// GetWithContext is like Get, but stops waiting for a new value as soon as
+// the given context is done and returns the error of the context. The fetch
+// itself carries on for the other users of the entry.
+func (c *Cache) GetWithContext(ctx context.Context, t string, r Request) (interface{}, ResultMeta, error) {
+	return c.getWithIndex(ctx, t, r, r.CacheInfo().MinIndex)
 }
 
 // getWithIndex implements the main Get functionality but allows internal
 // callers (Watch) to manipulate the blocking index separately from the actual
 // request object.
-func (c *Cache) getWithIndex(t string, r Request, minIndex uint64) (interface{}, ResultMeta, error) {
+func (c *Cache) getWithIndex(ctx context.Context, t string, r Request, minIndex uint64) (interface{}, ResultMeta, error) {
 	info := r.CacheInfo()
 	if info.Key == "" {
 		metrics.IncrCounter([]string{"consul", "cache", "bypass"}, 1)
@@ -367,6 +375,9 @@ RETRY_GET:
 	case <-timeoutCh:
 		// Timeout on the cache read, just return whatever we have.
 		return entry.Value, ResultMeta{Index: entry.Index}, nil
+
+	case <-ctx.Done():
+		return nil, ResultMeta{Index: entry.Index}, ctx.Err()
 	}
 }
 
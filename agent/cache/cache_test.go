@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -332,6 +333,49 @@ func TestCacheGet_blockingIndexTimeout(t *testing.T) {
 	}
 }
 
+// Test that a blocking get returns as soon as its context is canceled.
+func TestCacheGetWithContext_cancel(t *testing.T) {
+	t.Parallel()
+
+	typ := TestType(t)
+	defer typ.AssertExpectations(t)
+	c := TestCache(t)
+	c.RegisterType("t", typ, nil)
+
+	// Configure the type
+	triggerCh := make(chan time.Time)
+	defer close(triggerCh)
+	typ.Static(FetchResult{Value: 1, Index: 4}, nil).Once()
+	typ.Static(FetchResult{Value: 42, Index: 6}, nil).WaitUntil(triggerCh)
+
+	// Warm the cache
+	_, _, err := c.Get("t", TestRequest(t, RequestInfo{Key: "hello"}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := c.GetWithContext(ctx, "t", TestRequest(t, RequestInfo{
+			Key: "hello", MinIndex: 4, Timeout: 10 * time.Second}))
+		errCh <- err
+	}()
+
+	// Should block
+	select {
+	case <-errCh:
+		t.Fatal("should block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		require.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("should've returned")
+	}
+}
+
 // Test a get with an index set with requests returning an error
 // will return that error.
 func TestCacheGet_blockingIndexError(t *testing.T) {
@@ -86,7 +86,7 @@ func (c *Cache) notifyBlockingQuery(ctx context.Context, t string, r Request, co
 		}
 
 		// Blocking request
-		res, meta, err := c.getWithIndex(t, r, index)
+		res, meta, err := c.getWithIndex(ctx, t, r, index)
 
 		// Check context hasn't been canceled
 		if ctx.Err() != nil {
@@ -145,7 +145,7 @@ func (c *Cache) notifyPollingQuery(ctx context.Context, t string, r Request, cor
 		}
 
 		// Make the request
-		res, meta, err := c.getWithIndex(t, r, index)
+		res, meta, err := c.getWithIndex(ctx, t, r, index)
 
 		// Check context hasn't been canceled
 		if ctx.Err() != nil {
@@ -8,6 +8,7 @@ import (
 	metrics "github.com/armon/go-metrics"
 	cachetype "github.com/hashicorp/consul/agent/cache-types"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 var durations = NewDurationFixer("interval", "timeout", "deregistercriticalserviceafter")
@@ -18,8 +19,7 @@ func (s *HTTPServer) CatalogRegister(resp http.ResponseWriter, req *http.Request
 
 	var args structs.RegisterRequest
 	if err := decodeBody(req, &args, durations.FixupDurations); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
@@ -28,6 +28,10 @@ func (s *HTTPServer) CatalogRegister(resp http.ResponseWriter, req *http.Request
 		args.Datacenter = s.agent.config.Datacenter
 	}
 	s.parseToken(req, &args.Token)
+	args.CheckSyncOwner = true
+	if _, ok := req.URL.Query()["force-owner"]; ok {
+		args.ForceSyncOwner = true
+	}
 
 	// Forward to the servers
 	var out struct{}
@@ -47,8 +51,7 @@ func (s *HTTPServer) CatalogDeregister(resp http.ResponseWriter, req *http.Reque
 
 	var args structs.DeregisterRequest
 	if err := decodeBody(req, &args, nil); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
@@ -57,6 +60,10 @@ func (s *HTTPServer) CatalogDeregister(resp http.ResponseWriter, req *http.Reque
 		args.Datacenter = s.agent.config.Datacenter
 	}
 	s.parseToken(req, &args.Token)
+	args.CheckSyncOwner = true
+	if _, ok := req.URL.Query()["force-owner"]; ok {
+		args.ForceSyncOwner = true
+	}
 
 	// Forward to the servers
 	var out struct{}
@@ -74,8 +81,19 @@ func (s *HTTPServer) CatalogDatacenters(resp http.ResponseWriter, req *http.Requ
 	metrics.IncrCounterWithLabels([]string{"client", "api", "catalog_datacenters"}, 1,
 		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
 
-	var out []string
-	if err := s.agent.RPC("Catalog.ListDatacenters", struct{}{}, &out); err != nil {
+	// The detailed form includes the reachability of each datacenter.
+	var out interface{}
+	var err error
+	if _, ok := req.URL.Query()["detailed"]; ok {
+		var dcs []structs.DatacenterInfo
+		err = s.agent.RPC("Catalog.ListDatacentersDetailed", struct{}{}, &dcs)
+		out = dcs
+	} else {
+		var dcs []string
+		err = s.agent.RPC("Catalog.ListDatacenters", struct{}{}, &dcs)
+		out = dcs
+	}
+	if err != nil {
 		metrics.IncrCounterWithLabels([]string{"client", "rpc", "error", "catalog_datacenters"}, 1,
 			[]metrics.Label{{Name: "node", Value: s.nodeName()}})
 		return nil, err
@@ -195,8 +213,7 @@ func (s *HTTPServer) catalogServiceNodes(resp http.ResponseWriter, req *http.Req
 	// Pull out the service name
 	args.ServiceName = strings.TrimPrefix(req.URL.Path, pathPrefix)
 	if args.ServiceName == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing service name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service name", nil)
 		return nil, nil
 	}
 
@@ -264,8 +281,7 @@ func (s *HTTPServer) CatalogNodeServices(resp http.ResponseWriter, req *http.Req
 	// Pull out the node name
 	args.Node = strings.TrimPrefix(req.URL.Path, "/v1/catalog/node/")
 	if args.Node == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing node name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing node name", nil)
 		return nil, nil
 	}
 
@@ -309,3 +325,73 @@ RETRY_ONCE:
 		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
 	return out.NodeServices, nil
 }
+
+// CatalogRouting manages the routing override of a service.
+func (s *HTTPServer) CatalogRouting(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	service := strings.TrimPrefix(req.URL.Path, "/v1/catalog/routing/")
+	if service == "" {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service name", nil)
+		return nil, nil
+	}
+
+	switch req.Method {
+	case "GET":
+		return s.catalogRoutingGet(service, resp, req)
+
+	case "PUT":
+		return s.catalogRoutingApply(service, structs.ConfigEntryUpsert, resp, req)
+
+	case "DELETE":
+		return s.catalogRoutingApply(service, structs.ConfigEntryDelete, resp, req)
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+	}
+}
+
+// GET /v1/catalog/routing/:service
+func (s *HTTPServer) catalogRoutingGet(service string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.ServiceSpecificRequest{
+		ServiceName: service,
+	}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var out structs.IndexedServiceRouting
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("ServiceRouting.Get", &args, &out); err != nil {
+		return nil, err
+	}
+
+	if out.Routing == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return nil, nil
+	}
+	return out.Routing, nil
+}
+
+// PUT or DELETE /v1/catalog/routing/:service
+func (s *HTTPServer) catalogRoutingApply(service string, op structs.ConfigEntryOp, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.ServiceRoutingRequest{
+		Op:      op,
+		Routing: &structs.ServiceRoutingConfigEntry{},
+	}
+	s.parseDC(req, &args.Datacenter)
+	s.parseToken(req, &args.Token)
+	if op == structs.ConfigEntryUpsert {
+		if err := decodeBody(req, args.Routing, nil); err != nil {
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
+			return nil, nil
+		}
+	}
+
+	// Use the name from the URL
+	args.Routing.Name = service
+
+	var out struct{}
+	if err := s.agent.RPC("ServiceRouting.Apply", &args, &out); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
@@ -41,6 +41,43 @@ func TestCatalogRegister_Service_InvalidAddress(t *testing.T) {
 	}
 }
 
+func TestCatalogRegister_SyncOwner(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	register := func(owner, query string) *httptest.ResponseRecorder {
+		args := &structs.RegisterRequest{
+			Node:      "external",
+			Address:   "127.0.0.1",
+			SyncOwner: owner,
+		}
+		req, _ := http.NewRequest("PUT", "/v1/catalog/register"+query, jsonReader(args))
+		resp := httptest.NewRecorder()
+		a.srv.Handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := register("sync-a", "")
+	require.Equal(t, http.StatusOK, resp.Code, "body: %s", resp.Body.String())
+
+	// Another owner gets a conflict.
+	resp = register("sync-b", "")
+	require.Equal(t, http.StatusConflict, resp.Code, "body: %s", resp.Body.String())
+	require.Contains(t, resp.Body.String(), "sync-a")
+
+	// Unless it forces the owner.
+	resp = register("sync-b", "?force-owner")
+	require.Equal(t, http.StatusOK, resp.Code, "body: %s", resp.Body.String())
+
+	// The owner is exposed on the node.
+	req, _ := http.NewRequest("GET", "/v1/catalog/node/external", nil)
+	obj, err := a.srv.CatalogNodeServices(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	require.Equal(t, "sync-b", obj.(*structs.NodeServices).Node.SyncOwner)
+}
+
 func TestCatalogDeregister(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
@@ -79,6 +116,20 @@ func TestCatalogDatacenters(t *testing.T) {
 	})
 }
 
+func TestCatalogDatacenters_Detailed(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/catalog/datacenters?detailed", nil)
+	obj, err := a.srv.CatalogDatacenters(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, []structs.DatacenterInfo{
+		{Datacenter: "dc1", Servers: 1, Reachable: true},
+	}, obj)
+}
+
 func TestCatalogNodes(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
@@ -1216,3 +1267,52 @@ func TestCatalogNodeServices_WanTranslation(t *testing.T) {
 		t.Fatalf("bad: %v", service2)
 	}
 }
+
+func TestCatalogRouting(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Nothing to start with
+	req, _ := http.NewRequest("GET", "/v1/catalog/routing/web", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.CatalogRouting(resp, req)
+	require.NoError(t, err)
+	require.Nil(t, obj)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	// Set
+	body := jsonReader(map[string]interface{}{
+		"ForceTag":   "canary",
+		"Percentage": 10,
+	})
+	req, _ = http.NewRequest("PUT", "/v1/catalog/routing/web", body)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.CatalogRouting(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, true, obj)
+
+	req, _ = http.NewRequest("GET", "/v1/catalog/routing/web", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.CatalogRouting(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	routing := obj.(*structs.ServiceRoutingConfigEntry)
+	require.Equal(t, "web", routing.Name)
+	require.Equal(t, "canary", routing.ForceTag)
+	require.Equal(t, 10, routing.Percentage)
+
+	// Delete
+	req, _ = http.NewRequest("DELETE", "/v1/catalog/routing/web", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.CatalogRouting(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, true, obj)
+
+	req, _ = http.NewRequest("GET", "/v1/catalog/routing/web", nil)
+	resp = httptest.NewRecorder()
+	_, err = a.srv.CatalogRouting(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
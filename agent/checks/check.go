@@ -52,6 +52,19 @@ type CheckNotifier interface {
 	UpdateCheck(checkID types.CheckID, status, output string)
 }
 
+// CheckBlocker is implemented by a CheckNotifier that holds back the checks
+// that depend on a critical check. The checks that run periodically skip
+// their probe while they're blocked.
+type CheckBlocker interface {
+	CheckBlocked(checkID types.CheckID) bool
+}
+
+// blocked returns true when the notifier of a check reports it as blocked.
+func blocked(notify CheckNotifier, checkID types.CheckID) bool {
+	b, ok := notify.(CheckBlocker)
+	return ok && b.CheckBlocked(checkID)
+}
+
 // CheckMonitor is used to periodically invoke a script to
 // determine the health of a given check. It is compatible with
 // nagios plugins and expects the output in the same format.
@@ -97,7 +110,9 @@ func (c *CheckMonitor) run() {
 	for {
 		select {
 		case <-next:
-			c.check()
+			if !blocked(c.Notify, c.CheckID) {
+				c.check()
+			}
 			next = time.After(c.Interval)
 		case <-c.stopCh:
 			return
@@ -364,7 +379,9 @@ func (c *CheckHTTP) run() {
 	for {
 		select {
 		case <-next:
-			c.check()
+			if !blocked(c.Notify, c.CheckID) {
+				c.check()
+			}
 			next = time.After(c.Interval)
 		case <-c.stopCh:
 			return
@@ -501,7 +518,9 @@ func (c *CheckTCP) run() {
 	for {
 		select {
 		case <-next:
-			c.check()
+			if !blocked(c.Notify, c.CheckID) {
+				c.check()
+			}
 			next = time.After(c.Interval)
 		case <-c.stopCh:
 			return
@@ -573,7 +592,9 @@ func (c *CheckDocker) run() {
 	for {
 		select {
 		case <-next:
-			c.check()
+			if !blocked(c.Notify, c.CheckID) {
+				c.check()
+			}
 			next = time.After(c.Interval)
 		case <-c.stop:
 			return
@@ -680,7 +701,9 @@ func (c *CheckGRPC) run() {
 	for {
 		select {
 		case <-next:
-			c.check()
+			if !blocked(c.Notify, c.CheckID) {
+				c.check()
+			}
 			next = time.After(c.Interval)
 		case <-c.stopCh:
 			return
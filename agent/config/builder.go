@@ -680,6 +680,7 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		ACLAgentToken:             b.stringValWithDefault(c.ACL.Tokens.Agent, b.stringVal(c.ACLAgentToken)),
 		ACLDatacenter:             aclDC,
 		ACLDefaultPolicy:          b.stringValWithDefault(c.ACL.DefaultPolicy, b.stringVal(c.ACLDefaultPolicy)),
+		ACLDefaultPolicyOverride:  b.stringVal(c.ACL.DefaultPolicyOverride),
 		ACLDownPolicy:             b.stringValWithDefault(c.ACL.DownPolicy, b.stringVal(c.ACLDownPolicy)),
 		ACLEnableKeyListPolicy:    b.boolValWithDefault(c.ACL.EnableKeyListPolicy, b.boolVal(c.ACLEnableKeyListPolicy)),
 		ACLMasterToken:            b.stringValWithDefault(c.ACL.Tokens.Master, b.stringVal(c.ACLMasterToken)),
@@ -690,6 +691,13 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		ACLTokenReplication:       b.boolValWithDefault(c.ACL.TokenReplication, b.boolValWithDefault(c.EnableACLReplication, enableTokenReplication)),
 		ACLEnableTokenPersistence: b.boolValWithDefault(c.ACL.EnableTokenPersistence, false),
 
+		ACLDanglingTokenScanInterval: b.durationVal("acl.dangling_token_scan_interval", c.ACL.DanglingTokenScan),
+		ACLDanglingTokenDeleteAfter:  b.durationVal("acl.dangling_token_delete_after", c.ACL.DanglingTokenDelete),
+		ACLUsageSampleRate:           b.float64Val(c.ACL.UsageSampleRate),
+		ACLUsageInterval:             b.durationVal("acl.usage_interval", c.ACL.UsageInterval),
+		ACLUsageTopClients:           b.intVal(c.ACL.UsageTopClients),
+		ACLUsageLogShare:             b.float64Val(c.ACL.UsageLogShare),
+
 		// Autopilot
 		AutopilotCleanupDeadServers:      b.boolVal(c.Autopilot.CleanupDeadServers),
 		AutopilotDisableUpgradeMigration: b.boolVal(c.Autopilot.DisableUpgradeMigration),
@@ -719,6 +727,8 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		DNSUseCache:           b.boolVal(c.DNS.UseCache),
 		DNSCacheMaxAge:        b.durationVal("dns_config.cache_max_age", c.DNS.CacheMaxAge),
 
+		DNSAnswerCacheMaxEntries: b.intVal(c.DNS.AnswerCacheMaxEntries),
+
 		// HTTP
 		HTTPPort:            httpPort,
 		HTTPSPort:           httpsPort,
@@ -726,6 +736,8 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		HTTPSAddrs:          httpsAddrs,
 		HTTPBlockEndpoints:  c.HTTPConfig.BlockEndpoints,
 		HTTPResponseHeaders: c.HTTPConfig.ResponseHeaders,
+		HTTPPathPrefix:      strings.TrimRight(b.stringVal(c.HTTPConfig.PathPrefix), "/"),
+		HTTPJSONErrors:      b.boolVal(c.HTTPConfig.JSONErrors),
 		AllowWriteHTTPFrom:  b.cidrsVal("allow_write_http_from", c.HTTPConfig.AllowWriteHTTPFrom),
 
 		// Telemetry
@@ -768,6 +780,7 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		Checks:                                  checks,
 		ClientAddrs:                             clientAddrs,
 		ConnectEnabled:                          connectEnabled,
+		ConnectLeafCertPrewarm:                  b.boolVal(c.Connect.LeafCertPrewarm),
 		ConnectCAProvider:                       connectCAProvider,
 		ConnectCAConfig:                         connectCAConfig,
 		ConnectProxyAllowManagedRoot:            b.boolVal(c.Connect.Proxy.AllowManagedRoot),
@@ -792,6 +805,8 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		DisableUpdateCheck:                      b.boolVal(c.DisableUpdateCheck),
 		DiscardCheckOutput:                      b.boolVal(c.DiscardCheckOutput),
 		DiscoveryMaxStale:                       b.durationVal("discovery_max_stale", c.DiscoveryMaxStale),
+		DiscoveryPersistLastKnown:               b.boolVal(c.DiscoveryResilience.PersistLastKnown),
+		DiscoveryPersistMaxAge:                  b.durationVal("discovery_resilience.max_age", c.DiscoveryResilience.MaxAge),
 		EnableAgentTLSForChecks:                 b.boolVal(c.EnableAgentTLSForChecks),
 		EnableDebug:                             b.boolVal(c.EnableDebug),
 		EnableRemoteScriptChecks:                enableRemoteScriptChecks,
@@ -803,9 +818,12 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		EncryptVerifyOutgoing:                   b.boolVal(c.EncryptVerifyOutgoing),
 		GRPCPort:                                grpcPort,
 		GRPCAddrs:                               grpcAddrs,
+		HealthHistoryRetention:                  b.intVal(c.HealthHistoryRetention),
 		KeyFile:                                 b.stringVal(c.KeyFile),
+		KVDeletedRetention:                      b.durationVal("kv_deleted_retention", c.KVDeletedRetention),
 		LeaveDrainTime:                          b.durationVal("performance.leave_drain_time", c.Performance.LeaveDrainTime),
 		LeaveOnTerm:                             leaveOnTerm,
+		LintSuppress:                            c.LintSuppress,
 		LogLevel:                                b.stringVal(c.LogLevel),
 		LogFile:                                 b.stringVal(c.LogFile),
 		LogRotateBytes:                          b.intVal(c.LogRotateBytes),
@@ -814,6 +832,7 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		NodeMeta:                                c.NodeMeta,
 		NodeName:                                b.nodeName(c.NodeName),
 		NonVotingServer:                         b.boolVal(c.NonVotingServer),
+		NormalizeServiceTags:                    b.boolVal(c.NormalizeServiceTags),
 		PidFile:                                 b.stringVal(c.PidFile),
 		PrimaryDatacenter:                       primaryDatacenter,
 		RPCAdvertiseAddr:                        rpcAdvertiseAddr,
@@ -950,21 +969,72 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 	if rt.ACLDatacenter != "" && !reDatacenter.MatchString(rt.ACLDatacenter) {
 		return fmt.Errorf("acl_datacenter cannot be %q. Please use only [a-z0-9-_].", rt.ACLDatacenter)
 	}
+	switch rt.ACLDefaultPolicyOverride {
+	case "", "allow", "deny":
+	default:
+		return fmt.Errorf("acl.default_policy_override cannot be %q. Must be \"allow\" or \"deny\"", rt.ACLDefaultPolicyOverride)
+	}
+	if rt.ACLDefaultPolicyOverride != "" && rt.Datacenter == rt.ACLDatacenter {
+		b.warn("acl.default_policy_override has no effect in the primary datacenter %q", rt.ACLDatacenter)
+	}
+	if rt.ACLDanglingTokenScanInterval < 0 {
+		return fmt.Errorf("acl.dangling_token_scan_interval cannot be %s. Must be greater than or equal to zero", rt.ACLDanglingTokenScanInterval)
+	}
+	if rt.ACLDanglingTokenDeleteAfter < 0 {
+		return fmt.Errorf("acl.dangling_token_delete_after cannot be %s. Must be greater than or equal to zero", rt.ACLDanglingTokenDeleteAfter)
+	}
+	if rt.ACLDanglingTokenDeleteAfter > 0 && rt.ACLDanglingTokenScanInterval == 0 {
+		b.warn("acl.dangling_token_delete_after has no effect when acl.dangling_token_scan_interval is 0")
+	}
+	if rt.ACLUsageSampleRate < 0 || rt.ACLUsageSampleRate > 1 {
+		return fmt.Errorf("acl.usage_sample_rate cannot be %v. Must be between 0 and 1", rt.ACLUsageSampleRate)
+	}
+	if rt.ACLUsageSampleRate > 0 {
+		if rt.ACLUsageInterval <= 0 {
+			return fmt.Errorf("acl.usage_interval cannot be %s. Must be greater than zero", rt.ACLUsageInterval)
+		}
+		if rt.ACLUsageTopClients < 1 {
+			return fmt.Errorf("acl.usage_top_clients cannot be %d. Must be greater than zero", rt.ACLUsageTopClients)
+		}
+		if rt.ACLUsageLogShare <= 0 || rt.ACLUsageLogShare > 1 {
+			return fmt.Errorf("acl.usage_log_share cannot be %v. Must be greater than 0 and at most 1", rt.ACLUsageLogShare)
+		}
+	}
 	if rt.EnableUI && rt.UIDir != "" {
 		return fmt.Errorf(
 			"Both the ui and ui-dir flags were specified, please provide only one.\n" +
 				"If trying to use your own web UI resources, use the ui-dir flag.\n" +
 				"If using Consul version 0.7.0 or later, the web UI is included in the binary so use ui to enable it")
 	}
+	if rt.HTTPPathPrefix != "" && !strings.HasPrefix(rt.HTTPPathPrefix, "/") {
+		return fmt.Errorf("http_config.path_prefix %q must start with '/'", rt.HTTPPathPrefix)
+	}
 	if rt.DNSUDPAnswerLimit < 0 {
 		return fmt.Errorf("dns_config.udp_answer_limit cannot be %d. Must be greater than or equal to zero", rt.DNSUDPAnswerLimit)
 	}
 	if rt.DNSARecordLimit < 0 {
 		return fmt.Errorf("dns_config.a_record_limit cannot be %d. Must be greater than or equal to zero", rt.DNSARecordLimit)
 	}
+	if rt.DNSAnswerCacheMaxEntries < 0 {
+		return fmt.Errorf("dns_config.answer_cache_max_entries cannot be %d. Must be greater than or equal to zero", rt.DNSAnswerCacheMaxEntries)
+	}
+	if rt.DiscoveryPersistLastKnown && rt.DiscoveryPersistMaxAge <= 0 {
+		return fmt.Errorf("discovery_resilience.max_age must be positive when persist_last_known is enabled")
+	}
+	if rt.HealthHistoryRetention < 0 {
+		return fmt.Errorf("health_history_retention cannot be %d. Must be greater than or equal to zero", rt.HealthHistoryRetention)
+	}
+	if rt.KVDeletedRetention < 0 {
+		return fmt.Errorf("kv_deleted_retention cannot be %s. Must be greater than or equal to zero", rt.KVDeletedRetention)
+	}
 	if err := structs.ValidateMetadata(rt.NodeMeta, false); err != nil {
 		return fmt.Errorf("node_meta invalid: %v", err)
 	}
+	for _, id := range rt.LintSuppress {
+		if !lib.StrContains(LintIDs(), id) {
+			b.warn("lint_suppress: unknown lint warning %q", id)
+		}
+	}
 	if rt.EncryptKey != "" {
 		if _, err := decodeBytes(rt.EncryptKey); err != nil {
 			return fmt.Errorf("encrypt has invalid key: %s", err)
@@ -1151,6 +1221,7 @@ func (b *Builder) checkVal(v *CheckDefinition) *structs.CheckDefinition {
 		Timeout:                        b.durationVal(fmt.Sprintf("check[%s].timeout", id), v.Timeout),
 		TTL:                            b.durationVal(fmt.Sprintf("check[%s].ttl", id), v.TTL),
 		DeregisterCriticalServiceAfter: b.durationVal(fmt.Sprintf("check[%s].deregister_critical_service_after", id), v.DeregisterCriticalServiceAfter),
+		DependsOnCheck:                 types.CheckID(b.stringVal(v.DependsOnCheck)),
 	}
 }
 
@@ -1168,7 +1239,7 @@ func (b *Builder) serviceVal(v *ServiceDefinition) *structs.ServiceDefinition {
 	}
 
 	meta := make(map[string]string)
-	if err := structs.ValidateMetadata(v.Meta, false); err != nil {
+	if err := structs.ValidateServiceMetadata(v.Meta); err != nil {
 		b.err = multierror.Append(fmt.Errorf("invalid meta for service %s: %v", b.stringVal(v.Name), err))
 	} else {
 		meta = v.Meta
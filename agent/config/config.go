@@ -199,6 +199,7 @@ type Config struct {
 	DisableUpdateCheck               *bool                    `json:"disable_update_check,omitempty" hcl:"disable_update_check" mapstructure:"disable_update_check"`
 	DiscardCheckOutput               *bool                    `json:"discard_check_output" hcl:"discard_check_output" mapstructure:"discard_check_output"`
 	DiscoveryMaxStale                *string                  `json:"discovery_max_stale" hcl:"discovery_max_stale" mapstructure:"discovery_max_stale"`
+	DiscoveryResilience              DiscoveryResilience      `json:"discovery_resilience,omitempty" hcl:"discovery_resilience" mapstructure:"discovery_resilience"`
 	EnableACLReplication             *bool                    `json:"enable_acl_replication,omitempty" hcl:"enable_acl_replication" mapstructure:"enable_acl_replication"`
 	EnableAgentTLSForChecks          *bool                    `json:"enable_agent_tls_for_checks,omitempty" hcl:"enable_agent_tls_for_checks" mapstructure:"enable_agent_tls_for_checks"`
 	EnableDebug                      *bool                    `json:"enable_debug,omitempty" hcl:"enable_debug" mapstructure:"enable_debug"`
@@ -211,9 +212,12 @@ type Config struct {
 	GossipLAN                        GossipLANConfig          `json:"gossip_lan,omitempty" hcl:"gossip_lan" mapstructure:"gossip_lan"`
 	GossipWAN                        GossipWANConfig          `json:"gossip_wan,omitempty" hcl:"gossip_wan" mapstructure:"gossip_wan"`
 	HTTPConfig                       HTTPConfig               `json:"http_config,omitempty" hcl:"http_config" mapstructure:"http_config"`
+	HealthHistoryRetention           *int                     `json:"health_history_retention,omitempty" hcl:"health_history_retention" mapstructure:"health_history_retention"`
 	KeyFile                          *string                  `json:"key_file,omitempty" hcl:"key_file" mapstructure:"key_file"`
+	KVDeletedRetention               *string                  `json:"kv_deleted_retention,omitempty" hcl:"kv_deleted_retention" mapstructure:"kv_deleted_retention"`
 	LeaveOnTerm                      *bool                    `json:"leave_on_terminate,omitempty" hcl:"leave_on_terminate" mapstructure:"leave_on_terminate"`
 	Limits                           Limits                   `json:"limits,omitempty" hcl:"limits" mapstructure:"limits"`
+	LintSuppress                     []string                 `json:"lint_suppress,omitempty" hcl:"lint_suppress" mapstructure:"lint_suppress"`
 	LogLevel                         *string                  `json:"log_level,omitempty" hcl:"log_level" mapstructure:"log_level"`
 	LogFile                          *string                  `json:"log_file,omitempty" hcl:"log_file" mapstructure:"log_file"`
 	LogRotateDuration                *string                  `json:"log_rotate_duration,omitempty" hcl:"log_rotate_duration" mapstructure:"log_rotate_duration"`
@@ -222,6 +226,7 @@ type Config struct {
 	NodeMeta                         map[string]string        `json:"node_meta,omitempty" hcl:"node_meta" mapstructure:"node_meta"`
 	NodeName                         *string                  `json:"node_name,omitempty" hcl:"node_name" mapstructure:"node_name"`
 	NonVotingServer                  *bool                    `json:"non_voting_server,omitempty" hcl:"non_voting_server" mapstructure:"non_voting_server"`
+	NormalizeServiceTags             *bool                    `json:"normalize_service_tags,omitempty" hcl:"normalize_service_tags" mapstructure:"normalize_service_tags"`
 	Performance                      Performance              `json:"performance,omitempty" hcl:"performance" mapstructure:"performance"`
 	PidFile                          *string                  `json:"pid_file,omitempty" hcl:"pid_file" mapstructure:"pid_file"`
 	Ports                            Ports                    `json:"ports,omitempty" hcl:"ports" mapstructure:"ports"`
@@ -397,6 +402,7 @@ type CheckDefinition struct {
 	Timeout                        *string             `json:"timeout,omitempty" hcl:"timeout" mapstructure:"timeout"`
 	TTL                            *string             `json:"ttl,omitempty" hcl:"ttl" mapstructure:"ttl"`
 	DeregisterCriticalServiceAfter *string             `json:"deregister_critical_service_after,omitempty" hcl:"deregister_critical_service_after" mapstructure:"deregister_critical_service_after"`
+	DependsOnCheck                 *string             `json:"depends_on_check,omitempty" hcl:"depends_on_check" mapstructure:"depends_on_check"`
 }
 
 // ServiceConnect is the connect block within a service registration
@@ -504,6 +510,10 @@ type Connect struct {
 	ProxyDefaults ConnectProxyDefaults   `json:"proxy_defaults,omitempty" hcl:"proxy_defaults" mapstructure:"proxy_defaults"`
 	CAProvider    *string                `json:"ca_provider,omitempty" hcl:"ca_provider" mapstructure:"ca_provider"`
 	CAConfig      map[string]interface{} `json:"ca_config,omitempty" hcl:"ca_config" mapstructure:"ca_config"`
+
+	// LeafCertPrewarm fetches the leaf certificates of local Connect
+	// services when the agent starts.
+	LeafCertPrewarm *bool `json:"leaf_cert_prewarm,omitempty" hcl:"leaf_cert_prewarm" mapstructure:"leaf_cert_prewarm"`
 }
 
 // ConnectProxy is the agent-global connect proxy configuration.
@@ -557,12 +567,21 @@ type DNS struct {
 	SOA                *SOA              `json:"soa,omitempty" hcl:"soa" mapstructure:"soa"`
 	UseCache           *bool             `json:"use_cache,omitempty" hcl:"use_cache" mapstructure:"use_cache"`
 	CacheMaxAge        *string           `json:"cache_max_age,omitempty" hcl:"cache_max_age" mapstructure:"cache_max_age"`
+
+	AnswerCacheMaxEntries *int `json:"answer_cache_max_entries,omitempty" hcl:"answer_cache_max_entries" mapstructure:"answer_cache_max_entries"`
 }
 
 type HTTPConfig struct {
 	BlockEndpoints     []string          `json:"block_endpoints,omitempty" hcl:"block_endpoints" mapstructure:"block_endpoints"`
 	AllowWriteHTTPFrom []string          `json:"allow_write_http_from,omitempty" hcl:"allow_write_http_from" mapstructure:"allow_write_http_from"`
 	ResponseHeaders    map[string]string `json:"response_headers,omitempty" hcl:"response_headers" mapstructure:"response_headers"`
+	PathPrefix         *string           `json:"path_prefix,omitempty" hcl:"path_prefix" mapstructure:"path_prefix"`
+	JSONErrors         *bool             `json:"json_errors,omitempty" hcl:"json_errors" mapstructure:"json_errors"`
+}
+
+type DiscoveryResilience struct {
+	PersistLastKnown *bool   `json:"persist_last_known,omitempty" hcl:"persist_last_known" mapstructure:"persist_last_known"`
+	MaxAge           *string `json:"max_age,omitempty" hcl:"max_age" mapstructure:"max_age"`
 }
 
 type Performance struct {
@@ -636,10 +655,18 @@ type ACL struct {
 	TokenTTL               *string `json:"token_ttl,omitempty" hcl:"token_ttl" mapstructure:"token_ttl"`
 	DownPolicy             *string `json:"down_policy,omitempty" hcl:"down_policy" mapstructure:"down_policy"`
 	DefaultPolicy          *string `json:"default_policy,omitempty" hcl:"default_policy" mapstructure:"default_policy"`
+	DefaultPolicyOverride  *string `json:"default_policy_override,omitempty" hcl:"default_policy_override" mapstructure:"default_policy_override"`
+	DanglingTokenScan      *string `json:"dangling_token_scan_interval,omitempty" hcl:"dangling_token_scan_interval" mapstructure:"dangling_token_scan_interval"`
+	DanglingTokenDelete    *string `json:"dangling_token_delete_after,omitempty" hcl:"dangling_token_delete_after" mapstructure:"dangling_token_delete_after"`
 	EnableKeyListPolicy    *bool   `json:"enable_key_list_policy,omitempty" hcl:"enable_key_list_policy" mapstructure:"enable_key_list_policy"`
 	Tokens                 Tokens  `json:"tokens,omitempty" hcl:"tokens" mapstructure:"tokens"`
 	DisabledTTL            *string `json:"disabled_ttl,omitempty" hcl:"disabled_ttl" mapstructure:"disabled_ttl"`
 	EnableTokenPersistence *bool   `json:"enable_token_persistence" hcl:"enable_token_persistence" mapstructure:"enable_token_persistence"`
+
+	UsageSampleRate *float64 `json:"usage_sample_rate,omitempty" hcl:"usage_sample_rate" mapstructure:"usage_sample_rate"`
+	UsageInterval   *string  `json:"usage_interval,omitempty" hcl:"usage_interval" mapstructure:"usage_interval"`
+	UsageTopClients *int     `json:"usage_top_clients,omitempty" hcl:"usage_top_clients" mapstructure:"usage_top_clients"`
+	UsageLogShare   *float64 `json:"usage_log_share,omitempty" hcl:"usage_log_share" mapstructure:"usage_log_share"`
 }
 
 type Tokens struct {
@@ -45,6 +45,10 @@ func DefaultSource() Source {
 		acl_ttl = "30s"
 		acl = {
 			policy_ttl = "30s"
+			dangling_token_scan_interval = "1h"
+			usage_interval = "1m"
+			usage_top_clients = 10
+			usage_log_share = 0.5
 		}
 		bind_addr = "0.0.0.0"
 		bootstrap = false
@@ -97,6 +101,12 @@ func DefaultSource() Source {
 			max_stale = "87600h"
 			recursor_timeout = "2s"
 		}
+		connect = {
+			leaf_cert_prewarm = true
+		}
+		discovery_resilience = {
+			max_age = "72h"
+		}
 		limits = {
 			rpc_rate = -1
 			rpc_max_burst = 1000
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LintWarning is a warning about a configuration that is valid but is likely
+// to be a mistake. The ID identifies the rule that raised it, so it can be
+// suppressed with lint_suppress.
+type LintWarning struct {
+	ID      string
+	Message string
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("[%s] %s", w.ID, w.Message)
+}
+
+// lintRule checks the runtime config for one kind of misconfiguration and
+// returns the message of the warning, or an empty string if there's nothing
+// to warn about.
+type lintRule struct {
+	ID    string
+	Check func(rt *RuntimeConfig) string
+}
+
+// lintRules is the catalog of rules run by Lint, in the order their warnings
+// are reported.
+var lintRules = []lintRule{
+	{"dev-mode", lintDevMode},
+	{"gossip-unauthenticated", lintGossipUnauthenticated},
+	{"gossip-verify-incoming-disabled", lintGossipVerifyIncoming},
+	{"gossip-verify-outgoing-disabled", lintGossipVerifyOutgoing},
+	{"tls-incoming-unverified", lintTLSIncomingUnverified},
+	{"tls-server-hostname-unverified", lintTLSServerHostname},
+	{"tls-min-version", lintTLSMinVersion},
+	{"acl-default-allow", lintACLDefaultAllow},
+	{"acl-no-agent-token", lintACLNoAgentToken},
+	{"http-public-no-acl", lintHTTPPublicNoACL},
+	{"remote-script-checks-no-acl", lintRemoteScriptChecksNoACL},
+	{"debug-no-acl", lintDebugNoACL},
+	{"remote-exec-enabled", lintRemoteExecEnabled},
+	{"dns-recursor-self", lintDNSRecursorSelf},
+	{"server-leave-on-terminate", lintServerLeaveOnTerm},
+	{"client-no-join", lintClientNoJoin},
+	{"data-dir-temporary", lintDataDirTemporary},
+}
+
+// LintIDs returns the IDs of all the lint rules.
+func LintIDs() []string {
+	ids := make([]string, 0, len(lintRules))
+	for _, r := range lintRules {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// Lint runs the lint rules over the runtime config and returns the warnings
+// that aren't suppressed by lint_suppress.
+func Lint(rt *RuntimeConfig) []LintWarning {
+	suppressed := make(map[string]bool)
+	for _, id := range rt.LintSuppress {
+		suppressed[id] = true
+	}
+
+	var warnings []LintWarning
+	for _, r := range lintRules {
+		if suppressed[r.ID] {
+			continue
+		}
+		if msg := r.Check(rt); msg != "" {
+			warnings = append(warnings, LintWarning{ID: r.ID, Message: msg})
+		}
+	}
+	return warnings
+}
+
+func lintDevMode(rt *RuntimeConfig) string {
+	if !rt.DevMode {
+		return ""
+	}
+	return "The agent is running in dev mode, which keeps all its state in memory and isn't secured. Don't use it in production."
+}
+
+func lintGossipUnauthenticated(rt *RuntimeConfig) string {
+	if rt.EncryptKey != "" || rt.VerifyIncoming || rt.VerifyIncomingRPC {
+		return ""
+	}
+	return "Neither encrypt nor verify_incoming is set, so gossip and RPC traffic is accepted from any host that can reach the agent."
+}
+
+func lintGossipVerifyIncoming(rt *RuntimeConfig) string {
+	if rt.EncryptKey == "" || rt.EncryptVerifyIncoming {
+		return ""
+	}
+	return "encrypt_verify_incoming is disabled, so unencrypted gossip is accepted. It's only meant to be disabled while gossip encryption is rolled out."
+}
+
+func lintGossipVerifyOutgoing(rt *RuntimeConfig) string {
+	if rt.EncryptKey == "" || rt.EncryptVerifyOutgoing {
+		return ""
+	}
+	return "encrypt_verify_outgoing is disabled, so gossip is sent unencrypted. It's only meant to be disabled while gossip encryption is rolled out."
+}
+
+func lintTLSIncomingUnverified(rt *RuntimeConfig) string {
+	if rt.CertFile == "" || rt.VerifyIncoming || rt.VerifyIncomingRPC {
+		return ""
+	}
+	return "cert_file is set without verify_incoming, so the certificates of the agents making RPC calls aren't verified."
+}
+
+func lintTLSServerHostname(rt *RuntimeConfig) string {
+	if !rt.VerifyOutgoing || rt.VerifyServerHostname {
+		return ""
+	}
+	return "verify_outgoing is set without verify_server_hostname, so any agent with a certificate signed by the CA can act as a server."
+}
+
+func lintTLSMinVersion(rt *RuntimeConfig) string {
+	switch rt.TLSMinVersion {
+	case "tls10", "tls11":
+		return fmt.Sprintf("tls_min_version is %q, which allows TLS versions with known weaknesses. Use \"tls12\" or later.", rt.TLSMinVersion)
+	}
+	return ""
+}
+
+func lintACLDefaultAllow(rt *RuntimeConfig) string {
+	if !rt.ACLsEnabled || rt.ACLDefaultPolicy != "allow" {
+		return ""
+	}
+	return "ACLs are enabled with default_policy \"allow\", so requests without a token can do anything the rules don't deny."
+}
+
+func lintACLNoAgentToken(rt *RuntimeConfig) string {
+	if !rt.ACLsEnabled || rt.ACLDefaultPolicy != "deny" {
+		return ""
+	}
+	if rt.ACLAgentToken != "" || rt.ACLToken != "" || rt.ACLEnableTokenPersistence {
+		return ""
+	}
+	return "ACLs are enabled with default_policy \"deny\" but no agent or default token is set, so the agent can't register itself or its services in the catalog."
+}
+
+func lintHTTPPublicNoACL(rt *RuntimeConfig) string {
+	if rt.ACLsEnabled {
+		return ""
+	}
+	var public []string
+	for _, addr := range append(rt.HTTPAddrs, rt.HTTPSAddrs...) {
+		if tcp, ok := addr.(*net.TCPAddr); ok && !tcp.IP.IsLoopback() {
+			public = append(public, tcp.String())
+		}
+	}
+	if len(public) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("The HTTP API is bound to %s without ACLs, so anyone who can reach it has full access to the cluster.", strings.Join(public, ", "))
+}
+
+func lintRemoteScriptChecksNoACL(rt *RuntimeConfig) string {
+	if !rt.EnableRemoteScriptChecks || rt.ACLsEnabled {
+		return ""
+	}
+	return "enable_script_checks is set without ACLs, so anyone who can reach the HTTP API can run commands on this host. Use enable_local_script_checks instead."
+}
+
+func lintDebugNoACL(rt *RuntimeConfig) string {
+	if !rt.EnableDebug || rt.ACLsEnabled {
+		return ""
+	}
+	return "enable_debug is set without ACLs, so anyone who can reach the HTTP API can profile the agent."
+}
+
+func lintRemoteExecEnabled(rt *RuntimeConfig) string {
+	if rt.DisableRemoteExec {
+		return ""
+	}
+	return "disable_remote_exec is false, so \"consul exec\" can run commands on this host."
+}
+
+func lintDNSRecursorSelf(rt *RuntimeConfig) string {
+	var self []string
+	for _, r := range rt.DNSRecursors {
+		host, port, err := net.SplitHostPort(r)
+		if err != nil {
+			host, port = r, "53"
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			continue
+		}
+		for _, addr := range rt.DNSAddrs {
+			if isDNSAddr(addr, ip, port) {
+				self = append(self, r)
+				break
+			}
+		}
+	}
+	if len(self) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("recursors includes the agent's own DNS address (%s), so queries for other domains loop back to the agent.", strings.Join(self, ", "))
+}
+
+// isDNSAddr returns whether the DNS server bound to addr answers on the given
+// IP and port.
+func isDNSAddr(addr net.Addr, ip net.IP, port string) bool {
+	var bound net.IP
+	var boundPort int
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		bound, boundPort = a.IP, a.Port
+	case *net.TCPAddr:
+		bound, boundPort = a.IP, a.Port
+	default:
+		return false
+	}
+	if fmt.Sprint(boundPort) != port {
+		return false
+	}
+	if bound.Equal(ip) {
+		return true
+	}
+	return bound.IsUnspecified() && ip.IsLoopback()
+}
+
+func lintServerLeaveOnTerm(rt *RuntimeConfig) string {
+	if !rt.ServerMode || !rt.LeaveOnTerm {
+		return ""
+	}
+	return "leave_on_terminate is set on a server, so stopping it removes it from the Raft peers, which can cost the cluster its quorum during a rolling restart."
+}
+
+func lintClientNoJoin(rt *RuntimeConfig) string {
+	if rt.ServerMode || rt.DevMode {
+		return ""
+	}
+	if len(rt.RetryJoinLAN) > 0 || len(rt.StartJoinAddrsLAN) > 0 {
+		return ""
+	}
+	return "Neither retry_join nor start_join is set, so the agent won't join a cluster until \"consul join\" is run."
+}
+
+func lintDataDirTemporary(rt *RuntimeConfig) string {
+	if rt.DevMode || rt.DataDir == "" {
+		return ""
+	}
+	dir := filepath.Clean(rt.DataDir)
+	for _, tmp := range []string{os.TempDir(), "/tmp"} {
+		tmp = filepath.Clean(tmp)
+		if dir == tmp || strings.HasPrefix(dir, tmp+string(filepath.Separator)) {
+			return fmt.Sprintf("data_dir %q is in a temporary directory, which may be cleared when the host restarts.", rt.DataDir)
+		}
+	}
+	return ""
+}
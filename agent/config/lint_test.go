@@ -0,0 +1,159 @@
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lintClean is a runtime config that none of the rules warn about.
+func lintClean() *RuntimeConfig {
+	return &RuntimeConfig{
+		ServerMode:            true,
+		DataDir:               "/opt/consul",
+		EncryptKey:            "key",
+		EncryptVerifyIncoming: true,
+		EncryptVerifyOutgoing: true,
+		ACLsEnabled:           true,
+		ACLDefaultPolicy:      "deny",
+		ACLAgentToken:         "agent",
+		DisableRemoteExec:     true,
+		TLSMinVersion:         "tls12",
+		HTTPAddrs:             []net.Addr{&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8500}},
+		DNSAddrs: []net.Addr{
+			&net.TCPAddr{IP: net.ParseIP("0.0.0.0"), Port: 8600},
+			&net.UDPAddr{IP: net.ParseIP("0.0.0.0"), Port: 8600},
+		},
+		DNSRecursors: []string{"8.8.8.8"},
+	}
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		id    string
+		patch func(rt *RuntimeConfig)
+	}{
+		{"dev-mode", func(rt *RuntimeConfig) {
+			rt.DevMode = true
+		}},
+		{"gossip-unauthenticated", func(rt *RuntimeConfig) {
+			rt.EncryptKey = ""
+		}},
+		{"gossip-verify-incoming-disabled", func(rt *RuntimeConfig) {
+			rt.EncryptVerifyIncoming = false
+		}},
+		{"gossip-verify-outgoing-disabled", func(rt *RuntimeConfig) {
+			rt.EncryptVerifyOutgoing = false
+		}},
+		{"tls-incoming-unverified", func(rt *RuntimeConfig) {
+			rt.CertFile = "cert.pem"
+		}},
+		{"tls-server-hostname-unverified", func(rt *RuntimeConfig) {
+			rt.VerifyOutgoing = true
+		}},
+		{"tls-min-version", func(rt *RuntimeConfig) {
+			rt.TLSMinVersion = "tls10"
+		}},
+		{"acl-default-allow", func(rt *RuntimeConfig) {
+			rt.ACLDefaultPolicy = "allow"
+		}},
+		{"acl-no-agent-token", func(rt *RuntimeConfig) {
+			rt.ACLAgentToken = ""
+		}},
+		{"http-public-no-acl", func(rt *RuntimeConfig) {
+			rt.ACLsEnabled = false
+			rt.HTTPAddrs = []net.Addr{&net.TCPAddr{IP: net.ParseIP("0.0.0.0"), Port: 8500}}
+		}},
+		{"remote-script-checks-no-acl", func(rt *RuntimeConfig) {
+			rt.ACLsEnabled = false
+			rt.EnableRemoteScriptChecks = true
+		}},
+		{"debug-no-acl", func(rt *RuntimeConfig) {
+			rt.ACLsEnabled = false
+			rt.EnableDebug = true
+		}},
+		{"remote-exec-enabled", func(rt *RuntimeConfig) {
+			rt.DisableRemoteExec = false
+		}},
+		{"dns-recursor-self", func(rt *RuntimeConfig) {
+			rt.DNSRecursors = []string{"8.8.8.8", "127.0.0.1:8600"}
+		}},
+		{"server-leave-on-terminate", func(rt *RuntimeConfig) {
+			rt.LeaveOnTerm = true
+		}},
+		{"client-no-join", func(rt *RuntimeConfig) {
+			rt.ServerMode = false
+		}},
+		{"data-dir-temporary", func(rt *RuntimeConfig) {
+			rt.DataDir = "/tmp/consul"
+		}},
+	}
+
+	require.Empty(t, Lint(lintClean()))
+	require.Len(t, cases, len(lintRules))
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.id, func(t *testing.T) {
+			rt := lintClean()
+			tc.patch(rt)
+			warnings := Lint(rt)
+			require.Len(t, warnings, 1)
+			require.Equal(t, tc.id, warnings[0].ID)
+			require.NotEmpty(t, warnings[0].Message)
+
+			rt.LintSuppress = []string{tc.id}
+			require.Empty(t, Lint(rt))
+		})
+	}
+}
+
+func TestLint_DNSRecursorSelf(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		dnsAddr   net.Addr
+		recursors []string
+		self      bool
+	}{
+		"loopback on any address": {
+			&net.UDPAddr{IP: net.ParseIP("0.0.0.0"), Port: 8600},
+			[]string{"127.0.0.1:8600"},
+			true,
+		},
+		"same address": {
+			&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 53},
+			[]string{"10.0.0.1"},
+			true,
+		},
+		"other port": {
+			&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8600},
+			[]string{"127.0.0.1"},
+			false,
+		},
+		"other address": {
+			&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 53},
+			[]string{"10.0.0.2"},
+			false,
+		},
+		"hostname": {
+			&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53},
+			[]string{"localhost"},
+			false,
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rt := &RuntimeConfig{
+				DNSAddrs:     []net.Addr{tc.dnsAddr},
+				DNSRecursors: tc.recursors,
+			}
+			require.Equal(t, tc.self, lintDNSRecursorSelf(rt) != "")
+		})
+	}
+}
@@ -89,6 +89,50 @@ type RuntimeConfig struct {
 	// hcl: acl.default_policy = ("allow"|"deny")
 	ACLDefaultPolicy string
 
+	// ACLDefaultPolicyOverride replaces ACLDefaultPolicy for the requests
+	// handled in this datacenter when it isn't the ACLDatacenter, so that a
+	// secondary datacenter can have a different default than the primary.
+	// Tokens are still resolved the same way.
+	//
+	// hcl: acl.default_policy_override = ("allow"|"deny")
+	ACLDefaultPolicyOverride string
+
+	// ACLDanglingTokenScanInterval is how often the leader looks for tokens
+	// whose policies were all deleted, to report them. Zero disables the scan.
+	//
+	// hcl: acl.dangling_token_scan_interval = "duration"
+	ACLDanglingTokenScanInterval time.Duration
+
+	// ACLDanglingTokenDeleteAfter is how long a token has to be found dangling
+	// before the leader deletes it. Zero, the default, never deletes them.
+	//
+	// hcl: acl.dangling_token_delete_after = "duration"
+	ACLDanglingTokenDeleteAfter time.Duration
+
+	// ACLUsageSampleRate is the fraction of the RPCs handled by a server that
+	// are attributed to the accessor ID of their token, between 0 and 1. Zero,
+	// the default, disables the attribution.
+	//
+	// hcl: acl.usage_sample_rate = float64
+	ACLUsageSampleRate float64
+
+	// ACLUsageInterval is the length of the intervals the attributed RPCs
+	// are counted over.
+	//
+	// hcl: acl.usage_interval = "duration"
+	ACLUsageInterval time.Duration
+
+	// ACLUsageTopClients is how many accessors are reported for each interval.
+	//
+	// hcl: acl.usage_top_clients = int
+	ACLUsageTopClients int
+
+	// ACLUsageLogShare is the share of the RPCs or blocking queries of an
+	// interval above which a single accessor is logged.
+	//
+	// hcl: acl.usage_log_share = float64
+	ACLUsageLogShare float64
+
 	// ACLDownPolicy is used to control the ACL interaction when we cannot
 	// reach the ACLDatacenter and the token is not in the cache.
 	// There are the following modes:
@@ -220,6 +264,15 @@ type RuntimeConfig struct {
 	// hcl: dns_config { allow_stale = (true|false) }
 	DNSAllowStale bool
 
+	// DNSAnswerCacheMaxEntries is the maximum number of answers to service
+	// lookups cached by the agent. The answers are cached for the lowest TTL
+	// of their records, so only the services with a TTL are cached, and they
+	// are invalidated early when the result of the lookup changes in the
+	// agent cache. The answer cache is disabled if this is 0.
+	//
+	// hcl: dns_config { answer_cache_max_entries = int }
+	DNSAnswerCacheMaxEntries int
+
 	// DNSARecordLimit is used to limit the maximum number of DNS Resource
 	// Records returned in the ANSWER section of a DNS response for A or AAAA
 	// records for both UDP and TCP queries.
@@ -348,6 +401,21 @@ type RuntimeConfig struct {
 	// hcl: http_config { response_headers = map[string]string }
 	HTTPResponseHeaders map[string]string
 
+	// HTTPPathPrefix is the path prefix all HTTP endpoints are served
+	// under. It is used when the agent sits behind a proxy that routes a
+	// sub-path to it. The prefix is stripped before dispatching requests and
+	// added back to URLs the agent generates, such as the UI redirect.
+	//
+	// hcl: http_config { path_prefix = string }
+	HTTPPathPrefix string
+
+	// HTTPJSONErrors forces HTTP API errors to be returned as structured
+	// JSON, even when the client did not ask for it with an
+	// "Accept: application/json" header.
+	//
+	// hcl: http_config { json_errors = (true|false) }
+	HTTPJSONErrors bool
+
 	// Embed Telemetry Config
 	Telemetry lib.TelemetryConfig
 
@@ -369,6 +437,19 @@ type RuntimeConfig struct {
 	// hcl: discovery_max_stale = "duration"
 	DiscoveryMaxStale time.Duration
 
+	// DiscoveryPersistLastKnown enables persisting the last successful
+	// result of health service queries to disk so that they can still be
+	// answered, marked as degraded, while the servers are unreachable.
+	//
+	// hcl: discovery_resilience { persist_last_known = (true|false) }
+	DiscoveryPersistLastKnown bool
+
+	// DiscoveryPersistMaxAge is how long a persisted result can be served
+	// for after it was fetched from the servers.
+	//
+	// hcl: discovery_resilience { max_age = "duration" }
+	DiscoveryPersistMaxAge time.Duration
+
 	// Node name is the name we use to advertise. Defaults to hostname.
 	//
 	// NodeName is exposed via /v1/agent/self from here and
@@ -476,6 +557,7 @@ type RuntimeConfig struct {
 	//     timeout = "duration"
 	//     ttl = "duration"
 	//     deregister_critical_service_after = "duration"
+	//     depends_on_check = string
 	//   },
 	//   ...
 	// ]
@@ -500,6 +582,14 @@ type RuntimeConfig struct {
 	// and servers in a cluster for correct connect operation.
 	ConnectEnabled bool
 
+	// ConnectLeafCertPrewarm makes the agent fetch the leaf certificates of
+	// all locally registered sidecar proxies and Connect native services
+	// when it starts, so the first connections don't wait on a CSR round
+	// trip. /v1/agent/connection reports when this has completed.
+	//
+	// hcl: connect { leaf_cert_prewarm = (true|false) }
+	ConnectLeafCertPrewarm bool
+
 	// ConnectProxyBindMinPort is the inclusive start of the range of ports
 	// allocated to the agent for starting proxy listeners on where no explicit
 	// port is specified.
@@ -722,6 +812,12 @@ type RuntimeConfig struct {
 	// hcl: encrypt_verify_outgoing = (true|false)
 	EncryptVerifyOutgoing bool
 
+	// HealthHistoryRetention is the number of health check status
+	// transitions servers keep in memory for each service.
+	//
+	// hcl: health_history_retention = int
+	HealthHistoryRetention int
+
 	// GRPCPort is the port the gRPC server listens on. Currently this only
 	// exposes the xDS and ext_authz APIs for Envoy and it is disabled by default.
 	//
@@ -793,6 +889,13 @@ type RuntimeConfig struct {
 	// hcl: key_file = string
 	KeyFile string
 
+	// KVDeletedRetention is how long servers retain the KV entries that
+	// were deleted, so they can be restored. Deleted entries aren't retained
+	// if it's zero.
+	//
+	// hcl: kv_deleted_retention = "duration"
+	KVDeletedRetention time.Duration
+
 	// LeaveDrainTime is used to wait after a server has left the LAN Serf
 	// pool for RPCs to drain and new requests to be sent to other servers.
 	//
@@ -805,6 +908,12 @@ type RuntimeConfig struct {
 	// hcl: leave_on_terminate = (true|false)
 	LeaveOnTerm bool
 
+	// LintSuppress is the list of IDs of the lint warnings that aren't
+	// reported for this configuration. See Lint for the warnings.
+	//
+	// hcl: lint_suppress = []string
+	LintSuppress []string
+
 	// LogLevel is the level of the logs to write. Defaults to "INFO".
 	//
 	// hcl: log_level = string
@@ -853,6 +962,14 @@ type RuntimeConfig struct {
 	// flag: -non-voting-server
 	NonVotingServer bool
 
+	// NormalizeServiceTags controls whether the servers remove the duplicate
+	// tags of the services registered in the catalog and sort them, so the
+	// tags come back in the same order whatever order they were registered
+	// in. Only the services registered after it's enabled are normalized.
+	//
+	// hcl: normalize_service_tags = (true|false)
+	NormalizeServiceTags bool
+
 	// PidFile is the file to store our PID in.
 	//
 	// hcl: pid_file = string
@@ -1543,6 +1660,7 @@ func (c *RuntimeConfig) ClientAddress() (unixAddr, httpAddr, httpsAddr string) {
 func (c *RuntimeConfig) APIConfig(includeClientCerts bool) (*api.Config, error) {
 	cfg := &api.Config{
 		Datacenter: c.Datacenter,
+		PathPrefix: c.HTTPPathPrefix,
 		TLSConfig:  api.TLSConfig{InsecureSkipVerify: !c.VerifyOutgoing},
 	}
 
@@ -1572,6 +1690,15 @@ func (c *RuntimeConfig) APIConfig(includeClientCerts bool) (*api.Config, error)
 	return cfg, nil
 }
 
+// ACLLocalDefaultPolicy returns the default ACL policy of the requests handled
+// in this datacenter, and whether it comes from ACLDefaultPolicyOverride.
+func (c *RuntimeConfig) ACLLocalDefaultPolicy() (string, bool) {
+	if c.ACLDefaultPolicyOverride != "" && c.Datacenter != c.ACLDatacenter {
+		return c.ACLDefaultPolicyOverride, true
+	}
+	return c.ACLDefaultPolicy, false
+}
+
 // Sanitized returns a JSON/HCL compatible representation of the runtime
 // configuration where all fields with potential secrets had their
 // values replaced by 'hidden'. In addition, network addresses and
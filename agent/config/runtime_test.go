@@ -1719,6 +1719,142 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 			hcl:  []string{`dns_config = { a_record_limit = -1 }`},
 			err:  "dns_config.a_record_limit cannot be -1. Must be greater than or equal to zero",
 		},
+		{
+			desc: "dns_config.answer_cache_max_entries invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "dns_config": { "answer_cache_max_entries": -1 } }`},
+			hcl:  []string{`dns_config = { answer_cache_max_entries = -1 }`},
+			err:  "dns_config.answer_cache_max_entries cannot be -1. Must be greater than or equal to zero",
+		},
+		{
+			desc: "discovery_resilience.max_age invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "discovery_resilience": { "persist_last_known": true, "max_age": "0s" } }`},
+			hcl:  []string{`discovery_resilience = { persist_last_known = true max_age = "0s" }`},
+			err:  "discovery_resilience.max_age must be positive when persist_last_known is enabled",
+		},
+		{
+			desc: "health_history_retention invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "health_history_retention": -1 }`},
+			hcl:  []string{`health_history_retention = -1`},
+			err:  "health_history_retention cannot be -1. Must be greater than or equal to zero",
+		},
+		{
+			desc: "kv_deleted_retention invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "kv_deleted_retention": "-1s" }`},
+			hcl:  []string{`kv_deleted_retention = "-1s"`},
+			err:  "kv_deleted_retention cannot be -1s. Must be greater than or equal to zero",
+		},
+		{
+			desc: "lint_suppress unknown id",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "lint_suppress": ["dev-mode", "nope"] }`},
+			hcl:  []string{`lint_suppress = ["dev-mode", "nope"]`},
+			patch: func(rt *RuntimeConfig) {
+				rt.DataDir = dataDir
+				rt.LintSuppress = []string{"dev-mode", "nope"}
+			},
+			warns: []string{`lint_suppress: unknown lint warning "nope"`},
+		},
+		{
+			desc: "acl.default_policy_override invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "acl": { "default_policy_override": "yes" } }`},
+			hcl:  []string{`acl = { default_policy_override = "yes" }`},
+			err:  `acl.default_policy_override cannot be "yes". Must be "allow" or "deny"`,
+		},
+		{
+			desc: "acl.default_policy_override in primary datacenter",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "primary_datacenter": "dc1", "acl": { "enabled": true, "default_policy_override": "deny" } }`},
+			hcl:  []string{`primary_datacenter = "dc1" acl = { enabled = true default_policy_override = "deny" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.DataDir = dataDir
+				rt.ACLsEnabled = true
+				rt.ACLDatacenter = "dc1"
+				rt.PrimaryDatacenter = "dc1"
+				rt.ACLDefaultPolicyOverride = "deny"
+			},
+			warns: []string{`acl.default_policy_override has no effect in the primary datacenter "dc1"`},
+		},
+		{
+			desc: "acl.dangling_token_scan_interval negative",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "acl": { "dangling_token_scan_interval": "-1h" } }`},
+			hcl:  []string{`acl = { dangling_token_scan_interval = "-1h" }`},
+			err:  `acl.dangling_token_scan_interval cannot be -1h0m0s. Must be greater than or equal to zero`,
+		},
+		{
+			desc: "acl.dangling_token_delete_after without scan",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "acl": { "dangling_token_scan_interval": "0s", "dangling_token_delete_after": "24h" } }`},
+			hcl:  []string{`acl = { dangling_token_scan_interval = "0s" dangling_token_delete_after = "24h" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.DataDir = dataDir
+				rt.ACLDanglingTokenScanInterval = 0
+				rt.ACLDanglingTokenDeleteAfter = 24 * time.Hour
+			},
+			warns: []string{`acl.dangling_token_delete_after has no effect when acl.dangling_token_scan_interval is 0`},
+		},
+		{
+			desc: "acl.usage_sample_rate out of range",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "acl": { "usage_sample_rate": 1.5 } }`},
+			hcl:  []string{`acl = { usage_sample_rate = 1.5 }`},
+			err:  `acl.usage_sample_rate cannot be 1.5. Must be between 0 and 1`,
+		},
+		{
+			desc: "acl.usage_log_share out of range",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "acl": { "usage_sample_rate": 0.1, "usage_log_share": 0 } }`},
+			hcl:  []string{`acl = { usage_sample_rate = 0.1 usage_log_share = 0 }`},
+			err:  `acl.usage_log_share cannot be 0. Must be greater than 0 and at most 1`,
+		},
+		{
+			desc: "http_config.path_prefix trailing slash",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "http_config": { "path_prefix": "/consul/" } }`},
+			hcl:  []string{`http_config = { path_prefix = "/consul/" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.HTTPPathPrefix = "/consul"
+				rt.DataDir = dataDir
+			},
+		},
+		{
+			desc: "http_config.path_prefix invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "http_config": { "path_prefix": "consul" } }`},
+			hcl:  []string{`http_config = { path_prefix = "consul" }`},
+			err:  `http_config.path_prefix "consul" must start with '/'`,
+		},
 		{
 			desc: "performance.raft_multiplier < 0",
 			args: []string{
@@ -2118,6 +2254,24 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 				rt.DataDir = dataDir
 			},
 		},
+		{
+			desc: "check depends on another check",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{
+				`{ "check": { "name": "a", "ttl": "10s", "depends_on_check": "db" } }`,
+			},
+			hcl: []string{
+				`check = { name = "a", ttl = "10s", depends_on_check = "db" }`,
+			},
+			patch: func(rt *RuntimeConfig) {
+				rt.Checks = []*structs.CheckDefinition{
+					&structs.CheckDefinition{Name: "a", TTL: 10 * time.Second, DependsOnCheck: "db"},
+				}
+				rt.DataDir = dataDir
+			},
+		},
 		{
 			desc: "multiple service files",
 			args: []string{
@@ -2898,6 +3052,13 @@ func TestFullConfig(t *testing.T) {
 				"enabled" : true,
 				"down_policy" : "03eb2aee",
 				"default_policy" : "72c2e7a0",
+				"default_policy_override" : "allow",
+				"dangling_token_scan_interval" : "2707s",
+				"dangling_token_delete_after" : "9217s",
+				"usage_sample_rate" : 0.173,
+				"usage_interval" : "4613s",
+				"usage_top_clients" : 37,
+				"usage_log_share" : 0.619,
 				"enable_key_list_policy": false,
 				"enable_token_persistence": true,
 				"policy_ttl": "1123s",
@@ -3016,6 +3177,7 @@ func TestFullConfig(t *testing.T) {
 					"csr_max_concurrent": 2
 				},
 				"enabled": true,
+				"leaf_cert_prewarm": false,
 				"proxy_defaults": {
 					"exec_mode": "script",
 					"daemon_command": ["consul", "connect", "proxy"],
@@ -3054,10 +3216,15 @@ func TestFullConfig(t *testing.T) {
 			"disable_update_check": true,
 			"discard_check_output": true,
 			"discovery_max_stale": "5s",
+			"discovery_resilience": {
+				"persist_last_known": true,
+				"max_age": "17273s"
+			},
 			"domain": "7W1xXSqd",
 			"dns_config": {
 				"allow_stale": true,
 				"a_record_limit": 29907,
+				"answer_cache_max_entries": 8127,
 				"disable_compression": true,
 				"enable_truncate": true,
 				"max_stale": "29685s",
@@ -3080,16 +3247,21 @@ func TestFullConfig(t *testing.T) {
 			"encrypt": "A4wELWqH",
 			"encrypt_verify_incoming": true,
 			"encrypt_verify_outgoing": true,
+			"health_history_retention": 7264,
 			"http_config": {
 				"block_endpoints": [ "RBvAFcGD", "fWOWFznh" ],
 				"allow_write_http_from": [ "127.0.0.1/8", "22.33.44.55/32", "0.0.0.0/0" ],
 				"response_headers": {
 					"M6TKa9NP": "xjuxjOzQ",
 					"JRCrHZed": "rl0mTx81"
-				}
+				},
+				"path_prefix": "/Wp4cZsrJ",
+				"json_errors": true
 			},
 			"key_file": "IEkkwgIA",
+			"kv_deleted_retention": "31877s",
 			"leave_on_terminate": true,
+			"lint_suppress": ["client-no-join"],
 			"limits": {
 				"rpc_rate": 12029.43,
 				"rpc_max_burst": 44848
@@ -3102,6 +3274,7 @@ func TestFullConfig(t *testing.T) {
 			},
 			"node_name": "otlLxGaI",
 			"non_voting_server": true,
+			"normalize_service_tags": true,
 			"performance": {
 				"leave_drain_time": "8265s",
 				"raft_multiplier": 5,
@@ -3450,6 +3623,13 @@ func TestFullConfig(t *testing.T) {
 				enabled = true
 				down_policy = "03eb2aee"
 				default_policy = "72c2e7a0"
+				default_policy_override = "allow"
+				dangling_token_scan_interval = "2707s"
+				dangling_token_delete_after = "9217s"
+				usage_sample_rate = 0.173
+				usage_interval = "4613s"
+				usage_top_clients = 37
+				usage_log_share = 0.619
 				enable_key_list_policy = false
 				enable_token_persistence = true
 				policy_ttl = "1123s"
@@ -3570,6 +3750,7 @@ func TestFullConfig(t *testing.T) {
 					csr_max_concurrent = 2.0
 				}
 				enabled = true
+				leaf_cert_prewarm = false
 				proxy_defaults {
 					exec_mode = "script"
 					daemon_command = ["consul", "connect", "proxy"]
@@ -3610,10 +3791,15 @@ func TestFullConfig(t *testing.T) {
 			disable_update_check = true
 			discard_check_output = true
 			discovery_max_stale = "5s"
+			discovery_resilience {
+				persist_last_known = true
+				max_age = "17273s"
+			}
 			domain = "7W1xXSqd"
 			dns_config {
 				allow_stale = true
 				a_record_limit = 29907
+				answer_cache_max_entries = 8127
 				disable_compression = true
 				enable_truncate = true
 				max_stale = "29685s"
@@ -3636,6 +3822,7 @@ func TestFullConfig(t *testing.T) {
 			encrypt = "A4wELWqH"
 			encrypt_verify_incoming = true
 			encrypt_verify_outgoing = true
+			health_history_retention = 7264
 			http_config {
 				block_endpoints = [ "RBvAFcGD", "fWOWFznh" ]
 				allow_write_http_from = [ "127.0.0.1/8", "22.33.44.55/32", "0.0.0.0/0" ]
@@ -3643,9 +3830,13 @@ func TestFullConfig(t *testing.T) {
 					"M6TKa9NP" = "xjuxjOzQ"
 					"JRCrHZed" = "rl0mTx81"
 				}
+				path_prefix = "/Wp4cZsrJ"
+				json_errors = true
 			}
 			key_file = "IEkkwgIA"
+			kv_deleted_retention = "31877s"
 			leave_on_terminate = true
+			lint_suppress = ["client-no-join"]
 			limits {
 				rpc_rate = 12029.43
 				rpc_max_burst = 44848
@@ -3658,6 +3849,7 @@ func TestFullConfig(t *testing.T) {
 			}
 			node_name = "otlLxGaI"
 			non_voting_server = true
+			normalize_service_tags = true
 			performance {
 				leave_drain_time = "8265s"
 				raft_multiplier = 5
@@ -4119,6 +4311,9 @@ func TestFullConfig(t *testing.T) {
 		ACLsEnabled:                      true,
 		ACLDatacenter:                    "ejtmd43d",
 		ACLDefaultPolicy:                 "72c2e7a0",
+		ACLDefaultPolicyOverride:         "allow",
+		ACLDanglingTokenScanInterval:     2707 * time.Second,
+		ACLDanglingTokenDeleteAfter:      9217 * time.Second,
 		ACLDownPolicy:                    "03eb2aee",
 		ACLEnforceVersion8:               true,
 		ACLEnableKeyListPolicy:           false,
@@ -4129,6 +4324,10 @@ func TestFullConfig(t *testing.T) {
 		ACLPolicyTTL:                     1123 * time.Second,
 		ACLToken:                         "418fdff1",
 		ACLTokenReplication:              true,
+		ACLUsageSampleRate:               0.173,
+		ACLUsageInterval:                 4613 * time.Second,
+		ACLUsageTopClients:               37,
+		ACLUsageLogShare:                 0.619,
 		AdvertiseAddrLAN:                 ipAddr("17.99.29.16"),
 		AdvertiseAddrWAN:                 ipAddr("78.63.37.19"),
 		AutopilotCleanupDeadServers:      true,
@@ -4218,6 +4417,7 @@ func TestFullConfig(t *testing.T) {
 		CheckUpdateInterval:     16507 * time.Second,
 		ClientAddrs:             []*net.IPAddr{ipAddr("93.83.18.19")},
 		ConnectEnabled:          true,
+		ConnectLeafCertPrewarm:  false,
 		ConnectProxyBindMinPort: 2000,
 		ConnectProxyBindMaxPort: 3000,
 		ConnectSidecarMinPort:   8888,
@@ -4241,6 +4441,7 @@ func TestFullConfig(t *testing.T) {
 		},
 		DNSAddrs:                         []net.Addr{tcpAddr("93.95.95.81:7001"), udpAddr("93.95.95.81:7001")},
 		DNSARecordLimit:                  29907,
+		DNSAnswerCacheMaxEntries:         8127,
 		DNSAllowStale:                    true,
 		DNSDisableCompression:            true,
 		DNSDomain:                        "7W1xXSqd",
@@ -4269,6 +4470,8 @@ func TestFullConfig(t *testing.T) {
 		DisableUpdateCheck:               true,
 		DiscardCheckOutput:               true,
 		DiscoveryMaxStale:                5 * time.Second,
+		DiscoveryPersistLastKnown:        true,
+		DiscoveryPersistMaxAge:           17273 * time.Second,
 		EnableAgentTLSForChecks:          true,
 		EnableDebug:                      true,
 		EnableRemoteScriptChecks:         true,
@@ -4280,21 +4483,27 @@ func TestFullConfig(t *testing.T) {
 		EncryptVerifyOutgoing:            true,
 		GRPCPort:                         4881,
 		GRPCAddrs:                        []net.Addr{tcpAddr("32.31.61.91:4881")},
+		HealthHistoryRetention:           7264,
 		HTTPAddrs:                        []net.Addr{tcpAddr("83.39.91.39:7999")},
 		HTTPBlockEndpoints:               []string{"RBvAFcGD", "fWOWFznh"},
 		AllowWriteHTTPFrom:               []*net.IPNet{cidr("127.0.0.0/8"), cidr("22.33.44.55/32"), cidr("0.0.0.0/0")},
 		HTTPPort:                         7999,
 		HTTPResponseHeaders:              map[string]string{"M6TKa9NP": "xjuxjOzQ", "JRCrHZed": "rl0mTx81"},
+		HTTPPathPrefix:                   "/Wp4cZsrJ",
+		HTTPJSONErrors:                   true,
 		HTTPSAddrs:                       []net.Addr{tcpAddr("95.17.17.19:15127")},
 		HTTPSPort:                        15127,
 		KeyFile:                          "IEkkwgIA",
+		KVDeletedRetention:               31877 * time.Second,
 		LeaveDrainTime:                   8265 * time.Second,
 		LeaveOnTerm:                      true,
+		LintSuppress:                     []string{"client-no-join"},
 		LogLevel:                         "k1zo9Spt",
 		NodeID:                           types.NodeID("AsUIlw99"),
 		NodeMeta:                         map[string]string{"5mgGQMBk": "mJLtVMSG", "A7ynFMJB": "0Nx6RGab"},
 		NodeName:                         "otlLxGaI",
 		NonVotingServer:                  true,
+		NormalizeServiceTags:             true,
 		PidFile:                          "43xN80Km",
 		PrimaryDatacenter:                "ejtmd43d",
 		RPCAdvertiseAddr:                 tcpAddr("17.99.29.16:3757"),
@@ -4935,8 +5144,11 @@ func TestSanitize(t *testing.T) {
 	rtJSON := `{
 		"ACLAgentMasterToken": "hidden",
 		"ACLAgentToken": "hidden",
+		"ACLDanglingTokenDeleteAfter": "0s",
+		"ACLDanglingTokenScanInterval": "0s",
 		"ACLDatacenter": "",
 		"ACLDefaultPolicy": "",
+		"ACLDefaultPolicyOverride": "",
 		"ACLDisabledTTL": "0s",
 		"ACLDownPolicy": "",
 		"ACLEnableKeyListPolicy": false,
@@ -4948,6 +5160,10 @@ func TestSanitize(t *testing.T) {
 		"ACLTokenReplication": false,
 		"ACLTokenTTL": "0s",
 		"ACLToken": "hidden",
+		"ACLUsageInterval": "0s",
+		"ACLUsageLogShare": 0,
+		"ACLUsageSampleRate": 0,
+		"ACLUsageTopClients": 0,
 		"ACLsEnabled": false,
 		"AEInterval": "0s",
 		"AdvertiseAddrLAN": "",
@@ -4971,6 +5187,7 @@ func TestSanitize(t *testing.T) {
 		"Checks": [{
 			"AliasNode": "",
 			"AliasService": "",
+			"DependsOnCheck": "",
 			"DeregisterCriticalServiceAfter": "0s",
 			"DockerContainerID": "",
 			"GRPC": "",
@@ -4996,6 +5213,7 @@ func TestSanitize(t *testing.T) {
 		"ConnectCAConfig": {},
 		"ConnectCAProvider": "",
 		"ConnectEnabled": false,
+		"ConnectLeafCertPrewarm": false,
 		"ConnectProxyAllowManagedAPIRegistration": false,
 		"ConnectProxyAllowManagedRoot": false,
 		"ConnectProxyBindMaxPort": 0,
@@ -5033,6 +5251,7 @@ func TestSanitize(t *testing.T) {
 			"udp://1.2.3.4:5678"
 		],
 		"DNSAllowStale": false,
+		"DNSAnswerCacheMaxEntries": 0,
 		"DNSDisableCompression": false,
 		"DNSDomain": "",
 		"DNSEnableTruncate": false,
@@ -5065,6 +5284,8 @@ func TestSanitize(t *testing.T) {
 		"DisableUpdateCheck": false,
 		"DiscardCheckOutput": false,
 		"DiscoveryMaxStale": "0s",
+		"DiscoveryPersistLastKnown": false,
+		"DiscoveryPersistMaxAge": "0s",
 		"EnableAgentTLSForChecks": false,
 		"EnableDebug": false,
 		"EnableLocalScriptChecks": false,
@@ -5081,13 +5302,18 @@ func TestSanitize(t *testing.T) {
 			"unix:///var/run/foo"
 		],
 		"HTTPBlockEndpoints": [],
+		"HTTPJSONErrors": false,
+		"HTTPPathPrefix": "",
 		"HTTPPort": 0,
 		"HTTPResponseHeaders": {},
 		"HTTPSAddrs": [],
 		"HTTPSPort": 0,
+		"HealthHistoryRetention": 0,
+		"KVDeletedRetention": "0s",
 		"KeyFile": "hidden",
 		"LeaveDrainTime": "0s",
 		"LeaveOnTerm": false,
+		"LintSuppress": [],
 		"LogLevel": "",
 		"LogFile": "",
 		"LogRotateBytes": 0,
@@ -5096,6 +5322,7 @@ func TestSanitize(t *testing.T) {
 		"NodeMeta": {},
 		"NodeName": "",
 		"NonVotingServer": false,
+		"NormalizeServiceTags": false,
 		"PidFile": "",
 		"PrimaryDatacenter": "",
 		"RPCAdvertiseAddr": "",
@@ -5140,6 +5367,7 @@ func TestSanitize(t *testing.T) {
 				"AliasNode": "",
 				"AliasService": "",
 				"CheckID": "",
+				"DependsOnCheck": "",
 				"DeregisterCriticalServiceAfter": "0s",
 				"DockerContainerID": "",
 				"GRPC": "",
@@ -5301,7 +5529,8 @@ func TestRuntime_APIConfigHTTP(t *testing.T) {
 			&net.UnixAddr{Name: "/var/run/foo"},
 			&net.TCPAddr{IP: net.ParseIP("198.18.0.1"), Port: 5678},
 		},
-		Datacenter: "dc-test",
+		Datacenter:     "dc-test",
+		HTTPPathPrefix: "/consul",
 	}
 
 	cfg, err := rt.APIConfig(false)
@@ -5309,6 +5538,7 @@ func TestRuntime_APIConfigHTTP(t *testing.T) {
 	require.Equal(t, rt.Datacenter, cfg.Datacenter)
 	require.Equal(t, "198.18.0.1:5678", cfg.Address)
 	require.Equal(t, "http", cfg.Scheme)
+	require.Equal(t, "/consul", cfg.PathPrefix)
 	require.Equal(t, "", cfg.TLSConfig.CAFile)
 	require.Equal(t, "", cfg.TLSConfig.CAPath)
 	require.Equal(t, "", cfg.TLSConfig.CertFile)
@@ -5550,3 +5780,25 @@ func metaPairs(n int, format string) string {
 		panic("invalid format: " + format)
 	}
 }
+
+func TestRuntime_ACLLocalDefaultPolicy(t *testing.T) {
+	c := &RuntimeConfig{
+		Datacenter:       "dc2",
+		ACLDatacenter:    "dc1",
+		ACLDefaultPolicy: "deny",
+	}
+	policy, overridden := c.ACLLocalDefaultPolicy()
+	require.Equal(t, "deny", policy)
+	require.False(t, overridden)
+
+	c.ACLDefaultPolicyOverride = "allow"
+	policy, overridden = c.ACLLocalDefaultPolicy()
+	require.Equal(t, "allow", policy)
+	require.True(t, overridden)
+
+	// The override is ignored in the primary datacenter.
+	c.Datacenter = "dc1"
+	policy, overridden = c.ACLLocalDefaultPolicy()
+	require.Equal(t, "deny", policy)
+	require.False(t, overridden)
+}
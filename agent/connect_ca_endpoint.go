@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 // GET /v1/connect/ca/roots
@@ -63,8 +64,7 @@ func (s *HTTPServer) ConnectCAConfigurationSet(resp http.ResponseWriter, req *ht
 	s.parseDC(req, &args.Datacenter)
 	s.parseToken(req, &args.Token)
 	if err := decodeBody(req, &args.Config, nil); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
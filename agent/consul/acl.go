@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/logger"
 	"github.com/hashicorp/consul/sentinel"
 	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
@@ -143,6 +144,10 @@ type ACLResolver struct {
 	config *Config
 	logger *log.Logger
 
+	// limitedLogger writes to logger, for the messages logged on every
+	// resolution that need to be rate limited.
+	limitedLogger *logger.RateLimitedLogger
+
 	delegate ACLResolverDelegate
 	sentinel sentinel.Evaluator
 
@@ -193,13 +198,14 @@ func NewACLResolver(config *ACLResolverConfig) (*ACLResolver, error) {
 	}
 
 	return &ACLResolver{
-		config:      config.Config,
-		logger:      config.Logger,
-		delegate:    config.Delegate,
-		sentinel:    config.Sentinel,
-		cache:       cache,
-		autoDisable: config.AutoDisable,
-		down:        down,
+		config:        config.Config,
+		logger:        config.Logger,
+		limitedLogger: newLimitedLogger(config.Logger),
+		delegate:      config.Delegate,
+		sentinel:      config.Sentinel,
+		cache:         cache,
+		autoDisable:   config.AutoDisable,
+		down:          down,
 	}, nil
 }
 
@@ -226,6 +232,9 @@ func (r *ACLResolver) fetchAndCacheTokenLegacy(token string, cached *structs.Aut
 			r.cache.PutAuthorizerWithTTL(token, authorizer, cacheTTL)
 			return authorizer, acl.ErrInvalidParent
 		}
+		if policy, ok := r.config.aclLocalDefaultPolicy(); ok {
+			parent = acl.RootAuthorizer(policy)
+		}
 
 		var policies []*acl.Policy
 		policy := reply.Policy
@@ -276,7 +285,8 @@ func (r *ACLResolver) resolveTokenLegacy(token string) (acl.Authorizer, error) {
 				return nil, err
 			}
 
-			return policies.Compile(acl.RootAuthorizer(r.config.ACLDefaultPolicy), r.cache, r.sentinel)
+			defaultPolicy, _ := r.config.aclLocalDefaultPolicy()
+			return policies.Compile(acl.RootAuthorizer(defaultPolicy), r.cache, r.sentinel)
 		}
 
 		return nil, err
@@ -495,6 +505,17 @@ func (r *ACLResolver) filterPoliciesByScope(policies structs.ACLPolicies) struct
 }
 
 func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity) (structs.ACLPolicies, error) {
+	policies, err := r.resolveLinkedPoliciesForIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inline policies are stored on the identity itself so there is nothing
+	// to fetch for them.
+	return append(policies, identity.InlineACLPolicies()...), nil
+}
+
+func (r *ACLResolver) resolveLinkedPoliciesForIdentity(identity structs.ACLIdentity) (structs.ACLPolicies, error) {
 	policyIDs := identity.PolicyIDs()
 	if len(policyIDs) == 0 {
 		policy := identity.EmbeddedPolicy()
@@ -524,7 +545,8 @@ func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity) (
 			if policy != nil {
 				policies = append(policies, policy)
 			} else {
-				r.logger.Printf("[WARN] acl: policy %q not found for identity %q", policyID, identity.ID())
+				r.limitedLogger.RateLimited("acl.policy_not_found", logger.DefaultRateLimitInterval).Printf(
+					"[WARN] acl: policy %q not found for identity %q", policyID, identity.ID())
 			}
 
 			continue
@@ -672,7 +694,8 @@ func (r *ACLResolver) ResolveToken(token string) (acl.Authorizer, error) {
 	if err != nil {
 		r.disableACLsWhenUpstreamDisabled(err)
 		if IsACLRemoteError(err) {
-			r.logger.Printf("[ERR] consul.acl: %v", err)
+			r.limitedLogger.RateLimited("acl.remote_error", logger.DefaultRateLimitInterval).Printf(
+				"[ERR] consul.acl: %v", err)
 			return r.down, nil
 		}
 
@@ -680,7 +703,8 @@ func (r *ACLResolver) ResolveToken(token string) (acl.Authorizer, error) {
 	}
 
 	// Build the Authorizer
-	authorizer, err := policies.Compile(acl.RootAuthorizer(r.config.ACLDefaultPolicy), r.cache, r.sentinel)
+	defaultPolicy, _ := r.config.aclLocalDefaultPolicy()
+	authorizer, err := policies.Compile(acl.RootAuthorizer(defaultPolicy), r.cache, r.sentinel)
 	return authorizer, err
 
 }
@@ -778,6 +802,37 @@ func (f *aclFilter) filterHealthChecks(checks *structs.HealthChecks) {
 	*checks = hc
 }
 
+// filterHealthTransitions is used to filter a set of health transitions down
+// based on the configured ACL rules for a token.
+func (f *aclFilter) filterHealthTransitions(transitions *structs.HealthTransitions) {
+	ht := *transitions
+	for i := 0; i < len(ht); i++ {
+		t := ht[i]
+		if f.allowNode(t.Node) && f.allowService(t.ServiceName) {
+			continue
+		}
+		f.logger.Printf("[DEBUG] consul: dropping health transition of check %q from result due to ACLs", t.CheckID)
+		ht = append(ht[:i], ht[i+1:]...)
+		i--
+	}
+	*transitions = ht
+}
+
+// filterConnectMismatches is used to filter Connect mismatches based on ACLs.
+func (f *aclFilter) filterConnectMismatches(mismatches *structs.ConnectMismatches) {
+	cm := *mismatches
+	for i := 0; i < len(cm); i++ {
+		m := cm[i]
+		if f.allowNode(m.Node) && f.allowService(m.ServiceName) {
+			continue
+		}
+		f.logger.Printf("[DEBUG] consul: dropping Connect mismatch for service %q from result due to ACLs", m.ServiceID)
+		cm = append(cm[:i], cm[i+1:]...)
+		i--
+	}
+	*mismatches = cm
+}
+
 // filterServices is used to filter a set of services based on ACLs.
 func (f *aclFilter) filterServices(services structs.Services) {
 	for svc := range services {
@@ -1045,12 +1100,18 @@ func (r *ACLResolver) filterACLWithAuthorizer(authorizer acl.Authorizer, subj in
 	case *structs.IndexedCheckServiceNodes:
 		filt.filterCheckServiceNodes(&v.Nodes)
 
+	case *structs.IndexedConnectMismatches:
+		filt.filterConnectMismatches(&v.Mismatches)
+
 	case *structs.IndexedCoordinates:
 		filt.filterCoordinates(&v.Coordinates)
 
 	case *structs.IndexedHealthChecks:
 		filt.filterHealthChecks(&v.HealthChecks)
 
+	case *structs.IndexedHealthTransitions:
+		filt.filterHealthTransitions(&v.Transitions)
+
 	case *structs.IndexedIntentions:
 		filt.filterIntentions(&v.Intentions)
 
@@ -0,0 +1,155 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// startACLDanglingTokenScan starts the goroutine that periodically looks for
+// tokens whose policies were all deleted. It's run by the leader of each
+// datacenter, which handles the tokens it owns: all of them in the primary
+// datacenter, and the local ones elsewhere.
+func (s *Server) startACLDanglingTokenScan() {
+	if !s.ACLsEnabled() || s.config.ACLDanglingTokenScanInterval <= 0 {
+		return
+	}
+
+	s.aclDanglingLock.Lock()
+	defer s.aclDanglingLock.Unlock()
+
+	if s.aclDanglingEnabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.aclDanglingCancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(s.config.ACLDanglingTokenScanInterval)
+		defer ticker.Stop()
+
+		// firstSeen tracks when each dangling token was first found, for the
+		// grace period before it's deleted. It starts over on a new leader.
+		firstSeen := make(map[string]time.Time)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.scanDanglingTokens(firstSeen, time.Now()); err != nil {
+					s.logger.Printf("[ERR] acl: error scanning for dangling tokens: %v", err)
+				}
+			}
+		}
+	}()
+
+	s.aclDanglingEnabled = true
+}
+
+// stopACLDanglingTokenScan stops the dangling token scan when we lose
+// leadership.
+func (s *Server) stopACLDanglingTokenScan() {
+	s.aclDanglingLock.Lock()
+	defer s.aclDanglingLock.Unlock()
+
+	if !s.aclDanglingEnabled {
+		return
+	}
+
+	s.aclDanglingCancel()
+	s.aclDanglingCancel = nil
+	s.aclDanglingEnabled = false
+}
+
+// scanDanglingTokens reports the tokens whose policies were all deleted, and
+// deletes the ones that have been dangling for longer than
+// ACLDanglingTokenDeleteAfter if that's set. firstSeen is updated with the
+// time each dangling token was first found.
+func (s *Server) scanDanglingTokens(firstSeen map[string]time.Time, now time.Time) error {
+	if s.UseLegacyACLs() {
+		return nil
+	}
+
+	// Outside of the primary datacenter the policies are replicated, so
+	// there's nothing to check the local tokens against until the first
+	// round of replication is done.
+	global := s.InACLDatacenter()
+	if !global {
+		s.aclReplicationStatusLock.RLock()
+		replicated := s.aclReplicationStatus.ReplicatedIndex > 0
+		s.aclReplicationStatusLock.RUnlock()
+		if !replicated {
+			return nil
+		}
+	}
+
+	_, tokens, err := s.fsm.State().ACLTokenListDangling(nil, true, global)
+	if err != nil {
+		return err
+	}
+	metrics.SetGauge([]string{"acl", "tokens", "dangling"}, float32(len(tokens)))
+
+	dangling := make(map[string]bool, len(tokens))
+	var accessors []string
+	for _, token := range tokens {
+		dangling[token.AccessorID] = true
+		accessors = append(accessors, token.AccessorID)
+		if _, ok := firstSeen[token.AccessorID]; !ok {
+			firstSeen[token.AccessorID] = now
+		}
+	}
+	for accessor := range firstSeen {
+		if !dangling[accessor] {
+			delete(firstSeen, accessor)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	s.logger.Printf("[WARN] acl: Found %d dangling tokens, whose policies were all deleted: %s",
+		len(tokens), strings.Join(accessors, ", "))
+
+	deleteAfter := s.config.ACLDanglingTokenDeleteAfter
+	if deleteAfter <= 0 {
+		return nil
+	}
+
+	var expired structs.ACLTokens
+	for _, token := range tokens {
+		if now.Sub(firstSeen[token.AccessorID]) >= deleteAfter {
+			expired = append(expired, token)
+		}
+	}
+	for i := 0; i < len(expired); i += aclBatchDeleteSize {
+		batch := expired[i:]
+		if len(batch) > aclBatchDeleteSize {
+			batch = batch[:aclBatchDeleteSize]
+		}
+
+		req := structs.ACLTokenBatchDeleteRequest{}
+		for _, token := range batch {
+			req.TokenIDs = append(req.TokenIDs, token.AccessorID)
+		}
+		resp, err := s.raftApply(structs.ACLTokenDeleteRequestType, &req)
+		if err != nil {
+			return fmt.Errorf("Failed to apply token deletions: %v", err)
+		}
+		if respErr, ok := resp.(error); ok {
+			return fmt.Errorf("Failed to apply token deletions: %v", respErr)
+		}
+
+		for _, token := range batch {
+			s.acls.cache.RemoveIdentity(token.SecretID)
+			delete(firstSeen, token.AccessorID)
+		}
+		metrics.IncrCounter([]string{"acl", "tokens", "dangling_deleted"}, float32(len(batch)))
+		s.logger.Printf("[INFO] acl: Deleted %d tokens that were dangling for over %s: %s",
+			len(batch), deleteAfter, strings.Join(req.TokenIDs, ", "))
+	}
+	return nil
+}
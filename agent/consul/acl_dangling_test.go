@@ -0,0 +1,80 @@
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeader_ScanDanglingTokens(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDanglingTokenScanInterval = 0
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	policy, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	arg := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description: "Policy token",
+			Policies:    []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.TokenSet", &arg, &token))
+
+	// Nothing is dangling while the policy exists.
+	firstSeen := make(map[string]time.Time)
+	now := time.Now()
+	require.NoError(t, s1.scanDanglingTokens(firstSeen, now))
+	require.Empty(t, firstSeen)
+
+	del := structs.ACLPolicyDeleteRequest{
+		Datacenter:   "dc1",
+		PolicyID:     policy.ID,
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var ignored string
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.PolicyDelete", &del, &ignored))
+
+	// Dangling tokens are only reported by default.
+	require.NoError(t, s1.scanDanglingTokens(firstSeen, now))
+	require.Equal(t, map[string]time.Time{token.AccessorID: now}, firstSeen)
+
+	require.NoError(t, s1.scanDanglingTokens(firstSeen, now.Add(24*time.Hour)))
+	_, got, err := s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, now, firstSeen[token.AccessorID])
+
+	// Once deletion is enabled, they are deleted after the grace period.
+	s1.config.ACLDanglingTokenDeleteAfter = time.Hour
+	require.NoError(t, s1.scanDanglingTokens(firstSeen, now.Add(30*time.Minute)))
+	_, got, err = s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+
+	require.NoError(t, s1.scanDanglingTokens(firstSeen, now.Add(time.Hour)))
+	_, got, err = s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+	require.NoError(t, err)
+	require.Nil(t, got)
+	require.Empty(t, firstSeen)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -271,9 +272,10 @@ func (a *ACL) TokenClone(args *structs.ACLTokenSetRequest, reply *structs.ACLTok
 	cloneReq := structs.ACLTokenSetRequest{
 		Datacenter: args.Datacenter,
 		ACLToken: structs.ACLToken{
-			Policies:    token.Policies,
-			Local:       token.Local,
-			Description: token.Description,
+			Policies:       token.Policies,
+			InlinePolicies: token.InlinePolicies,
+			Local:          token.Local,
+			Description:    token.Description,
 		},
 		WriteRequest: args.WriteRequest,
 	}
@@ -405,6 +407,17 @@ func (a *ACL) tokenSetInternal(args *structs.ACLTokenSetRequest, reply *structs.
 		// Do not store the policy name within raft/memdb as the policy could be renamed in the future.
 		link.Name = ""
 
+		// The expiration time is set here rather than by the client, so it
+		// doesn't depend on the client's clock.
+		if link.ExpirationTTL < 0 {
+			return fmt.Errorf("Policy link expiration TTL for %q cannot be negative", link.ID)
+		}
+		if link.ExpirationTTL > 0 {
+			expiration := time.Now().Add(link.ExpirationTTL)
+			link.ExpirationTime = &expiration
+			link.ExpirationTTL = 0
+		}
+
 		// dedup policy links by id
 		if _, ok := policyIDs[link.ID]; !ok {
 			policies = append(policies, link)
@@ -413,6 +426,17 @@ func (a *ACL) tokenSetInternal(args *structs.ACLTokenSetRequest, reply *structs.
 	}
 	token.Policies = policies
 
+	for _, inline := range token.InlinePolicies {
+		if strings.TrimSpace(inline.Rules) == "" {
+			return fmt.Errorf("Inline policy rules cannot be empty")
+		}
+		p, err := acl.NewPolicyFromSource("", 0, inline.Rules, acl.SyntaxCurrent, a.srv.sentinel)
+		if err != nil {
+			return fmt.Errorf("Invalid inline policy rules: %v", err)
+		}
+		a.srv.warnKeyListPolicy(fmt.Sprintf("the inline policy of token %q", token.AccessorID), p)
+	}
+
 	if token.Rules != "" {
 		return fmt.Errorf("Rules cannot be specified for this token")
 	}
@@ -546,9 +570,21 @@ func (a *ACL) TokenList(args *structs.ACLTokenListRequest, reply *structs.ACLTok
 		return acl.ErrPermissionDenied
 	}
 
+	// Dangling tokens don't link to any existing policy.
+	if args.Dangling && args.Policy != "" {
+		return fmt.Errorf("Cannot filter dangling tokens by policy")
+	}
+
 	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
 		func(ws memdb.WatchSet, state *state.Store) error {
-			index, tokens, err := state.ACLTokenList(ws, args.IncludeLocal, args.IncludeGlobal, args.Policy)
+			var index uint64
+			var tokens structs.ACLTokens
+			var err error
+			if args.Dangling {
+				index, tokens, err = state.ACLTokenListDangling(ws, args.IncludeLocal, args.IncludeGlobal)
+			} else {
+				index, tokens, err = state.ACLTokenList(ws, args.IncludeLocal, args.IncludeGlobal, args.Policy)
+			}
 			if err != nil {
 				return err
 			}
@@ -738,10 +774,11 @@ func (a *ACL) PolicySet(args *structs.ACLPolicySetRequest, reply *structs.ACLPol
 	}
 
 	// validate the rules
-	_, err := acl.NewPolicyFromSource("", 0, policy.Rules, policy.Syntax, a.srv.sentinel)
+	p, err := acl.NewPolicyFromSource("", 0, policy.Rules, policy.Syntax, a.srv.sentinel)
 	if err != nil {
 		return err
 	}
+	a.srv.warnKeyListPolicy(fmt.Sprintf("policy %q", policy.Name), p)
 
 	// calculate the hash for this policy
 	policy.SetHash(true)
@@ -901,6 +938,107 @@ func (a *ACL) PolicyResolve(args *structs.ACLPolicyBatchGetRequest, reply *struc
 	return nil
 }
 
+// Simulate checks what a token, or a set of rules, would be allowed to do
+// without doing it, and which rule decides each check.
+func (a *ACL) Simulate(args *structs.ACLSimulateRequest, reply *structs.ACLSimulateResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	// The token may only be known in the ACL datacenter, like for reads.
+	if args.AccessorID != "" && !a.srv.LocalTokensEnabled() {
+		args.Datacenter = a.srv.config.ACLDatacenter
+	}
+
+	if done, err := a.srv.forward("ACL.Simulate", args, args, reply); done {
+		return err
+	}
+
+	// Reading the rules of a token requires the same privileges as reading
+	// the token itself.
+	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLRead() {
+		return acl.ErrPermissionDenied
+	}
+
+	if (args.AccessorID == "") == (args.Rules == "") {
+		return fmt.Errorf("Exactly one of an accessor ID or rules must be provided")
+	}
+
+	var policies structs.ACLPolicies
+	if args.AccessorID != "" {
+		_, token, err := a.srv.fsm.State().ACLTokenGetByAccessor(nil, args.AccessorID)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return acl.ErrNotFound
+		}
+
+		policies, err = a.srv.acls.resolveTokenToPolicies(token.SecretID)
+		if err != nil {
+			return err
+		}
+	} else {
+		policy := &structs.ACLPolicy{
+			ID:     "inline",
+			Name:   "inline",
+			Rules:  args.Rules,
+			Syntax: acl.SyntaxCurrent,
+		}
+		policy.SetHash(true)
+		policies = structs.ACLPolicies{policy}
+	}
+
+	// Parse the policies on their own so the rule deciding each check can be
+	// found, along with the authorizer that makes the actual decision.
+	names := make(map[string]string)
+	var parsed []*acl.Policy
+	for _, policy := range policies {
+		p, err := acl.NewPolicyFromSource(policy.ID, policy.ModifyIndex, policy.Rules, policy.Syntax, a.srv.sentinel)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", policy.Name, err)
+		}
+		parsed = append(parsed, p)
+		names[policy.ID] = policy.Name
+	}
+	defaultPolicy, overridden := a.srv.config.aclLocalDefaultPolicy()
+	authz, err := acl.NewPolicyAuthorizer(acl.RootAuthorizer(defaultPolicy), parsed, a.srv.sentinel)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range args.Checks {
+		result := structs.ACLSimulateResult{ACLSimulateCheck: check}
+
+		allowed, err := acl.Check(authz, check.Resource, check.Segment, check.Access)
+		if err != nil {
+			result.Error = err.Error()
+			reply.Results = append(reply.Results, result)
+			continue
+		}
+		result.Allowed = allowed
+
+		match, err := acl.MatchRule(parsed, check.Resource, check.Segment)
+		if err != nil {
+			return err
+		}
+		if match != nil {
+			result.PolicyID = match.PolicyID
+			result.PolicyName = names[match.PolicyID]
+			result.Rule = match.String()
+		} else {
+			result.DefaultPolicy = defaultPolicy
+			result.DefaultPolicyOverride = overridden
+		}
+		reply.Results = append(reply.Results, result)
+	}
+
+	a.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
 // makeACLETag returns an ETag for the given parent and policy.
 func makeACLETag(parent string, policy *acl.Policy) string {
 	return fmt.Sprintf("%s:%s", parent, policy.ID)
@@ -113,10 +113,11 @@ func aclApplyInternal(srv *Server, args *structs.ACLRequest, reply *string) erro
 		}
 
 		// Validate the rules compile
-		_, err := acl.NewPolicyFromSource("", 0, args.ACL.Rules, acl.SyntaxLegacy, srv.sentinel)
+		policy, err := acl.NewPolicyFromSource("", 0, args.ACL.Rules, acl.SyntaxLegacy, srv.sentinel)
 		if err != nil {
 			return fmt.Errorf("ACL rule compilation failed: %v", err)
 		}
+		srv.warnKeyListPolicy(fmt.Sprintf("token %q", args.ACL.Name), policy)
 
 	case structs.ACLDelete:
 		if args.ACL.ID == anonymousToken {
@@ -808,6 +808,78 @@ func TestACLEndpoint_TokenSet(t *testing.T) {
 	})
 }
 
+func TestACLEndpoint_TokenSet_inlinePolicies(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	acl := ACL{srv: s1}
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		req := structs.ACLTokenSetRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				InlinePolicies: []structs.ACLTokenInlinePolicy{
+					{Rules: `key_prefix "tmp/" { policy = "nope" }`},
+				},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		resp := structs.ACLToken{}
+		err := acl.TokenSet(&req, &resp)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Invalid inline policy rules")
+	})
+
+	t.Run("Create it", func(t *testing.T) {
+		req := structs.ACLTokenSetRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				Description: "debugging",
+				InlinePolicies: []structs.ACLTokenInlinePolicy{
+					{Rules: `key_prefix "tmp/" { policy = "write" }`},
+				},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		resp := structs.ACLToken{}
+		require.NoError(t, acl.TokenSet(&req, &resp))
+
+		tokenResp, err := retrieveTestToken(codec, "root", "dc1", resp.AccessorID)
+		require.NoError(t, err)
+		require.Len(t, tokenResp.Token.InlinePolicies, 1)
+		require.Empty(t, tokenResp.Token.Policies)
+
+		// The inline rules are enforced for the token
+		authz, err := s1.ResolveToken(resp.SecretID)
+		require.NoError(t, err)
+		require.True(t, authz.KeyWrite("tmp/foo", nil))
+		require.False(t, authz.KeyWrite("foo", nil))
+
+		// but they don't show up as a policy
+		policies := structs.ACLPolicyListResponse{}
+		policyReq := structs.ACLPolicyListRequest{
+			Datacenter:   "dc1",
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		require.NoError(t, acl.PolicyList(&policyReq, &policies))
+		for _, policy := range policies.Policies {
+			require.Equal(t, structs.ACLPolicyGlobalManagementID, policy.ID)
+		}
+	})
+}
+
 func TestACLEndpoint_TokenSet_anon(t *testing.T) {
 	t.Parallel()
 
@@ -1054,6 +1126,132 @@ func TestACLEndpoint_TokenList(t *testing.T) {
 	require.Subset(t, retrievedTokens, tokens)
 }
 
+func TestACLEndpoint_TokenList_Policy(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	policy, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	_, err = upsertTestToken(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	arg := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description: "Policy token",
+			Policies:    []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.TokenSet", &arg, &token))
+
+	acl := ACL{srv: s1}
+
+	// The policy can be given by ID or by name.
+	for _, p := range []string{policy.ID, policy.Name} {
+		req := structs.ACLTokenListRequest{
+			Datacenter:   "dc1",
+			Policy:       p,
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLTokenListResponse{}
+		require.NoError(t, acl.TokenList(&req, &resp))
+		require.Len(t, resp.Tokens, 1)
+		require.Equal(t, token.AccessorID, resp.Tokens[0].AccessorID)
+	}
+
+	// An unknown policy matches nothing.
+	req := structs.ACLTokenListRequest{
+		Datacenter:   "dc1",
+		Policy:       "nope",
+		QueryOptions: structs.QueryOptions{Token: "root"},
+	}
+	resp := structs.ACLTokenListResponse{}
+	require.NoError(t, acl.TokenList(&req, &resp))
+	require.Empty(t, resp.Tokens)
+}
+
+func TestACLEndpoint_TokenList_Dangling(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	policy, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	arg := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description: "Policy token",
+			Policies:    []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.TokenSet", &arg, &token))
+
+	acl := ACL{srv: s1}
+	dangling := func() []string {
+		req := structs.ACLTokenListRequest{
+			Datacenter:    "dc1",
+			IncludeLocal:  true,
+			IncludeGlobal: true,
+			Dangling:      true,
+			QueryOptions:  structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLTokenListResponse{}
+		require.NoError(t, acl.TokenList(&req, &resp))
+		var accessors []string
+		for _, token := range resp.Tokens {
+			accessors = append(accessors, token.AccessorID)
+		}
+		return accessors
+	}
+	require.Empty(t, dangling())
+
+	del := structs.ACLPolicyDeleteRequest{
+		Datacenter:   "dc1",
+		PolicyID:     policy.ID,
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var ignored string
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.PolicyDelete", &del, &ignored))
+	require.Equal(t, []string{token.AccessorID}, dangling())
+
+	// Dangling tokens can't be filtered by policy.
+	req := structs.ACLTokenListRequest{
+		Datacenter:   "dc1",
+		Policy:       policy.ID,
+		Dangling:     true,
+		QueryOptions: structs.QueryOptions{Token: "root"},
+	}
+	resp := structs.ACLTokenListResponse{}
+	require.Error(t, acl.TokenList(&req, &resp))
+}
+
 func TestACLEndpoint_TokenBatchRead(t *testing.T) {
 	t.Parallel()
 
@@ -1490,6 +1688,122 @@ func TestACLEndpoint_PolicyResolve(t *testing.T) {
 	require.EqualValues(t, retrievedPolicies, policies)
 }
 
+func TestACLEndpoint_Simulate(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	acl := ACL{srv: s1}
+
+	policyReq := structs.ACLPolicySetRequest{
+		Datacenter: "dc1",
+		Policy: structs.ACLPolicy{
+			Name:  "web",
+			Rules: `service "web" { policy = "write" } key_prefix "config/" { policy = "read" }`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	policy := structs.ACLPolicy{}
+	require.NoError(t, acl.PolicySet(&policyReq, &policy))
+
+	tokenReq := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Policies: []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	token := structs.ACLToken{}
+	require.NoError(t, acl.TokenSet(&tokenReq, &token))
+
+	checks := []structs.ACLSimulateCheck{
+		{Resource: "service", Segment: "web", Access: "write"},
+		{Resource: "key", Segment: "config/app", Access: "write"},
+		{Resource: "node", Segment: "foo", Access: "read"},
+		{Resource: "namespace", Segment: "default", Access: "read"},
+	}
+
+	t.Run("token", func(t *testing.T) {
+		req := structs.ACLSimulateRequest{
+			Datacenter:   "dc1",
+			AccessorID:   token.AccessorID,
+			Checks:       checks,
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLSimulateResponse{}
+		require.NoError(t, acl.Simulate(&req, &resp))
+		require.Len(t, resp.Results, 4)
+
+		require.True(t, resp.Results[0].Allowed)
+		require.Equal(t, policy.ID, resp.Results[0].PolicyID)
+		require.Equal(t, "web", resp.Results[0].PolicyName)
+		require.Equal(t, `service "web" { policy = "write" }`, resp.Results[0].Rule)
+
+		require.False(t, resp.Results[1].Allowed)
+		require.Equal(t, `key_prefix "config/" { policy = "read" }`, resp.Results[1].Rule)
+
+		// The default policy decides
+		require.False(t, resp.Results[2].Allowed)
+		require.Empty(t, resp.Results[2].Rule)
+		require.Empty(t, resp.Results[2].Error)
+
+		require.False(t, resp.Results[3].Allowed)
+		require.Contains(t, resp.Results[3].Error, "Unsupported resource")
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		req := structs.ACLSimulateRequest{
+			Datacenter:   "dc1",
+			Rules:        `node_prefix "" { policy = "read" }`,
+			Checks:       checks,
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLSimulateResponse{}
+		require.NoError(t, acl.Simulate(&req, &resp))
+		require.Len(t, resp.Results, 4)
+		require.False(t, resp.Results[0].Allowed)
+		require.True(t, resp.Results[2].Allowed)
+		require.Equal(t, "inline", resp.Results[2].PolicyName)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		req := structs.ACLSimulateRequest{
+			Datacenter:   "dc1",
+			AccessorID:   "00000000-0000-0000-0000-000000000001",
+			Checks:       checks,
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLSimulateResponse{}
+		err := acl.Simulate(&req, &resp)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "ACL not found")
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		req := structs.ACLSimulateRequest{
+			Datacenter:   "dc1",
+			AccessorID:   token.AccessorID,
+			Checks:       checks,
+			QueryOptions: structs.QueryOptions{Token: token.SecretID},
+		}
+		resp := structs.ACLSimulateResponse{}
+		err := acl.Simulate(&req, &resp)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Permission denied")
+	})
+}
+
 // upsertTestToken creates a token for testing purposes
 func upsertTestToken(codec rpc.ClientCodec, masterToken string, datacenter string) (*structs.ACLToken, error) {
 	arg := structs.ACLTokenSetRequest{
@@ -0,0 +1,134 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const (
+	// aclPolicyLinkReapInterval is how often the leader removes the expired
+	// policy links from the tokens. Expired links are already ignored when
+	// resolving tokens, so this only needs to keep the tokens tidy.
+	aclPolicyLinkReapInterval = time.Minute
+)
+
+// startACLPolicyLinkReap starts the goroutine that periodically removes the
+// expired policy links from the tokens. It's run by the leader of each
+// datacenter, which handles the tokens it owns: all of them in the primary
+// datacenter, and the local ones elsewhere.
+func (s *Server) startACLPolicyLinkReap() {
+	if !s.ACLsEnabled() {
+		return
+	}
+
+	s.aclPolicyLinkReapLock.Lock()
+	defer s.aclPolicyLinkReapLock.Unlock()
+
+	if s.aclPolicyLinkReapEnabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.aclPolicyLinkReapCancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(aclPolicyLinkReapInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.reapExpiredPolicyLinks(time.Now()); err != nil {
+					s.logger.Printf("[ERR] acl: error removing expired policy links: %v", err)
+				}
+			}
+		}
+	}()
+
+	s.aclPolicyLinkReapEnabled = true
+}
+
+// stopACLPolicyLinkReap stops the expired policy link removal when we lose
+// leadership.
+func (s *Server) stopACLPolicyLinkReap() {
+	s.aclPolicyLinkReapLock.Lock()
+	defer s.aclPolicyLinkReapLock.Unlock()
+
+	if !s.aclPolicyLinkReapEnabled {
+		return
+	}
+
+	s.aclPolicyLinkReapCancel()
+	s.aclPolicyLinkReapCancel = nil
+	s.aclPolicyLinkReapEnabled = false
+}
+
+// reapExpiredPolicyLinks removes the policy links that expired as of now from
+// the tokens. The tokens are written with check-and-set, so a token that's
+// updated concurrently is left for the next round.
+func (s *Server) reapExpiredPolicyLinks(now time.Time) error {
+	if s.UseLegacyACLs() {
+		return nil
+	}
+
+	_, tokens, err := s.fsm.State().ACLTokenList(nil, true, s.InACLDatacenter(), "")
+	if err != nil {
+		return err
+	}
+
+	var updated structs.ACLTokens
+	for _, token := range tokens {
+		var keep []structs.ACLTokenPolicyLink
+		for _, link := range token.Policies {
+			if !link.Expired(now) {
+				keep = append(keep, link)
+			}
+		}
+		if len(keep) == len(token.Policies) {
+			continue
+		}
+
+		// The tokens from the state store must not be modified.
+		token = token.Clone()
+		token.Policies = keep
+		token.SetHash(true)
+		updated = append(updated, token)
+	}
+
+	for batchStart := 0; batchStart < len(updated); {
+		batchSize := 0
+		batchEnd := batchStart
+		for ; batchEnd < len(updated) && batchSize < aclBatchUpsertSize; batchEnd++ {
+			batchSize += updated[batchEnd].EstimateSize()
+		}
+		batch := updated[batchStart:batchEnd]
+		batchStart = batchEnd
+
+		req := structs.ACLTokenBatchSetRequest{
+			Tokens: batch,
+			CAS:    true,
+		}
+		resp, err := s.raftApply(structs.ACLTokenSetRequestType, &req)
+		if err != nil {
+			return fmt.Errorf("Failed to apply token updates: %v", err)
+		}
+		if respErr, ok := resp.(error); ok {
+			return fmt.Errorf("Failed to apply token updates: %v", respErr)
+		}
+
+		var accessors []string
+		for _, token := range batch {
+			s.acls.cache.RemoveIdentity(token.SecretID)
+			accessors = append(accessors, token.AccessorID)
+		}
+		s.logger.Printf("[INFO] acl: Removed expired policy links from %d tokens: %s",
+			len(batch), strings.Join(accessors, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeader_ReapExpiredPolicyLinks(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	permanent, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+	breakGlass, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	// A negative TTL is rejected.
+	arg := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description: "On-call token",
+			Policies: []structs.ACLTokenPolicyLink{
+				{ID: permanent.ID},
+				{ID: breakGlass.ID, ExpirationTTL: -time.Hour},
+			},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	err = msgpackrpc.CallWithCodec(codec, "ACL.TokenSet", &arg, &token)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot be negative")
+
+	// The TTL is turned into an expiration time by the server.
+	start := time.Now()
+	arg.ACLToken.Policies[1].ExpirationTTL = time.Hour
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.TokenSet", &arg, &token))
+	require.Len(t, token.Policies, 2)
+	require.Nil(t, token.Policies[0].ExpirationTime)
+	expiration := token.Policies[1].ExpirationTime
+	require.NotNil(t, expiration)
+	require.Zero(t, token.Policies[1].ExpirationTTL)
+	require.False(t, expiration.Before(start.Add(time.Hour)))
+	require.Equal(t, []string{permanent.ID, breakGlass.ID}, token.PolicyIDs())
+
+	// Nothing is removed before the link expires.
+	require.NoError(t, s1.reapExpiredPolicyLinks(time.Now()))
+	_, got, err := s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+	require.NoError(t, err)
+	require.Len(t, got.Policies, 2)
+	require.Equal(t, token.ModifyIndex, got.ModifyIndex)
+
+	// Once it has expired, the link is removed and the token is modified so
+	// the caches pick up the change.
+	require.NoError(t, s1.reapExpiredPolicyLinks(expiration.Add(time.Second)))
+	_, got, err = s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+	require.NoError(t, err)
+	require.Len(t, got.Policies, 1)
+	require.Equal(t, permanent.ID, got.Policies[0].ID)
+	require.True(t, got.ModifyIndex > token.ModifyIndex)
+	require.NotEqual(t, token.Hash, got.Hash)
+}
@@ -709,3 +709,121 @@ func TestACLReplication_TokensRedacted(t *testing.T) {
 		require.True(r, status.LastError.After(minErrorTime), "Replication LastError not after the minErrorTime")
 	})
 }
+
+func TestACLReplication_DefaultPolicyOverride(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		// The override has no effect in the primary datacenter.
+		c.ACLDefaultPolicyOverride = "allow"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLDefaultPolicy = "deny"
+		c.ACLDefaultPolicyOverride = "allow"
+		c.ACLTokenReplication = true
+		c.ACLReplicationRate = 100
+		c.ACLReplicationBurst = 100
+		c.ACLReplicationApplyLimit = 1000000
+	})
+	s2.tokens.UpdateReplicationToken("root", tokenStore.TokenSourceConfig)
+	testrpc.WaitForLeader(t, s2.RPC, "dc2")
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	testrpc.WaitForLeader(t, s1.RPC, "dc2")
+
+	policyArg := structs.ACLPolicySetRequest{
+		Datacenter: "dc1",
+		Policy: structs.ACLPolicy{
+			Name:  "web",
+			Rules: `service "web" { policy = "write" } service "secret" { policy = "deny" }`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var policy structs.ACLPolicy
+	require.NoError(t, s1.RPC("ACL.PolicySet", &policyArg, &policy))
+
+	tokenArg := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Policies: []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	require.NoError(t, s1.RPC("ACL.TokenSet", &tokenArg, &token))
+
+	// Wait for the token, and the anonymous token, to be replicated.
+	retry.Run(t, func(r *retry.R) {
+		_, local, err := s2.fsm.State().ACLTokenGetBySecret(nil, token.SecretID)
+		require.NoError(r, err)
+		require.NotNil(r, local)
+		_, anon, err := s2.fsm.State().ACLTokenGetBySecret(nil, anonymousToken)
+		require.NoError(r, err)
+		require.NotNil(r, anon)
+	})
+
+	// The rules of the token apply the same way in both datacenters, only
+	// the default differs.
+	authz, err := s1.ResolveToken(token.SecretID)
+	require.NoError(t, err)
+	require.True(t, authz.ServiceWrite("web", nil))
+	require.False(t, authz.ServiceRead("db"))
+	require.False(t, authz.ServiceRead("secret"))
+
+	authz, err = s2.ResolveToken(token.SecretID)
+	require.NoError(t, err)
+	require.True(t, authz.ServiceWrite("web", nil))
+	require.True(t, authz.ServiceRead("db"))
+	require.False(t, authz.ServiceRead("secret"))
+
+	// Requests without a token get the anonymous token, which has no rules,
+	// so they're decided by the default of each datacenter.
+	authz, err = s1.ResolveToken("")
+	require.NoError(t, err)
+	require.False(t, authz.ServiceRead("db"))
+
+	authz, err = s2.ResolveToken("")
+	require.NoError(t, err)
+	require.True(t, authz.ServiceRead("db"))
+
+	// Simulations attribute the decision to the local default.
+	simulate := func(s *Server, dc string) structs.ACLSimulateResult {
+		req := structs.ACLSimulateRequest{
+			Datacenter: dc,
+			AccessorID: token.AccessorID,
+			Checks: []structs.ACLSimulateCheck{
+				{Resource: "service", Segment: "db", Access: "read"},
+			},
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		var resp structs.ACLSimulateResponse
+		require.NoError(t, s.RPC("ACL.Simulate", &req, &resp))
+		require.Len(t, resp.Results, 1)
+		return resp.Results[0]
+	}
+
+	result := simulate(s1, "dc1")
+	require.False(t, result.Allowed)
+	require.Empty(t, result.Rule)
+	require.Equal(t, "deny", result.DefaultPolicy)
+	require.False(t, result.DefaultPolicyOverride)
+
+	result = simulate(s2, "dc2")
+	require.True(t, result.Allowed)
+	require.Empty(t, result.Rule)
+	require.Equal(t, "allow", result.DefaultPolicy)
+	require.True(t, result.DefaultPolicyOverride)
+}
@@ -135,6 +135,18 @@ func (s *Server) ACLsEnabled() bool {
 	return s.config.ACLsEnabled
 }
 
+// warnKeyListPolicy logs the key rules of a policy that don't mean what they
+// did before acl.enable_key_list_policy was set, so operators opting in can
+// find the rules to update.
+func (s *Server) warnKeyListPolicy(name string, policy *acl.Policy) {
+	if !s.config.ACLEnableKeyListPolicy {
+		return
+	}
+	for _, warning := range policy.KeyListWarnings() {
+		s.logger.Printf("[WARN] consul.acl: Rules of %s: %s", name, warning)
+	}
+}
+
 func (s *Server) ResolveIdentityFromToken(token string) (bool, structs.ACLIdentity, error) {
 	// only allow remote RPC resolution when token replication is off and
 	// when not in the ACL datacenter
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/agent/consul/autopilot"
@@ -26,6 +27,10 @@ func (d *AutopilotDelegate) FetchStats(ctx context.Context, servers []serf.Membe
 	return d.server.statsFetcher.Fetch(ctx, servers)
 }
 
+func (d *AutopilotDelegate) FetchClockSkew(ctx context.Context, servers []serf.Member) map[string]time.Duration {
+	return d.server.fetchClockSkew(ctx, servers)
+}
+
 func (d *AutopilotDelegate) IsServer(m serf.Member) (*autopilot.ServerInfo, error) {
 	if m.Tags["role"] != "consul" {
 		return nil, nil
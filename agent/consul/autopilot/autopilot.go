@@ -18,6 +18,7 @@ import (
 type Delegate interface {
 	AutopilotConfig() *Config
 	FetchStats(context.Context, []serf.Member) map[string]*ServerStats
+	FetchClockSkew(context.Context, []serf.Member) map[string]time.Duration
 	IsServer(serf.Member) (*ServerInfo, error)
 	NotifyHealth(OperatorHealthReply)
 	PromoteNonVoters(*Config, OperatorHealthReply) ([]raft.Server, error)
@@ -405,7 +406,12 @@ func (a *Autopilot) updateClusterHealth() error {
 	d := time.Now().Add(a.healthInterval / 2)
 	ctx, cancel := context.WithDeadline(context.Background(), d)
 	defer cancel()
+	skewCh := make(chan map[string]time.Duration, 1)
+	go func() {
+		skewCh <- a.delegate.FetchClockSkew(ctx, serverMembers)
+	}()
 	fetchedStats := a.delegate.FetchStats(ctx, serverMembers)
+	clockSkew := <-skewCh
 
 	// Build a current list of server healths
 	leader := raftNode.Leader()
@@ -413,6 +419,8 @@ func (a *Autopilot) updateClusterHealth() error {
 	voterCount := 0
 	healthyCount := 0
 	healthyVoterCount := 0
+	var minSkew, maxSkew time.Duration
+	haveSkew := false
 	for _, server := range servers {
 		health := ServerHealth{
 			ID:          string(server.ID),
@@ -435,6 +443,16 @@ func (a *Autopilot) updateClusterHealth() error {
 		} else {
 			health.SerfStatus = serf.StatusNone
 		}
+		if skew, ok := clockSkew[string(server.ID)]; ok {
+			health.ClockSkew = skew
+			if !haveSkew || skew < minSkew {
+				minSkew = skew
+			}
+			if !haveSkew || skew > maxSkew {
+				maxSkew = skew
+			}
+			haveSkew = true
+		}
 
 		if health.Voter {
 			voterCount++
@@ -450,6 +468,10 @@ func (a *Autopilot) updateClusterHealth() error {
 	}
 	clusterHealth.Healthy = healthyCount == len(servers)
 
+	// The skews are all relative to this server, so the largest skew between
+	// any two servers is the spread between them.
+	clusterHealth.MaxClockSkew = maxSkew - minSkew
+
 	// If we have extra healthy voters, update FailureTolerance
 	requiredQuorum := voterCount/2 + 1
 	if healthyVoterCount > requiredQuorum {
@@ -81,6 +81,10 @@ type ServerHealth struct {
 
 	// StableSince is the last time this server's Healthy value changed.
 	StableSince time.Time
+
+	// ClockSkew is the estimated skew between the clock of this server and
+	// the leader's. A positive skew means this server's clock is ahead.
+	ClockSkew time.Duration
 }
 
 // IsHealthy determines whether this ServerHealth is considered healthy
@@ -146,6 +150,10 @@ type OperatorHealthReply struct {
 
 	// Servers holds the health of each server.
 	Servers []ServerHealth
+
+	// MaxClockSkew is the largest estimated skew between the clocks of any
+	// two servers.
+	MaxClockSkew time.Duration
 }
 
 func (o *OperatorHealthReply) ServerHealth(id string) *ServerHealth {
@@ -8,14 +8,20 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/consul/state"
+	"github.com/hashicorp/consul/agent/metadata"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/ipaddr"
 	"github.com/hashicorp/consul/types"
 	bexpr "github.com/hashicorp/go-bexpr"
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/serf/serf"
 )
 
+// deregisterBatchTag is the Serf tag set by servers which can apply batched
+// deregistrations as a single Raft entry.
+const deregisterBatchTag = "dereg_batch"
+
 // Catalog endpoint is used to manipulate the service catalog
 type Catalog struct {
 	srv *Server
@@ -59,6 +65,12 @@ func servicePreApply(service *structs.NodeService, rule acl.Authorizer) error {
 		return fmt.Errorf("Invalid service address")
 	}
 
+	// Validate the meta values with a special meaning here rather than in
+	// the state store, so entries already in the Raft log keep applying.
+	if err := structs.ValidateServiceMetadata(service.Meta); err != nil {
+		return fmt.Errorf("Invalid Service Meta: %v", err)
+	}
+
 	// Apply the ACL policy if any. The 'consul' service is excluded
 	// since it is managed automatically internally (that behavior
 	// is going away after version 0.8). We check this same policy
@@ -113,6 +125,14 @@ func (c *Catalog) Register(args *structs.RegisterRequest, reply *struct{}) error
 		if err := servicePreApply(args.Service, rule); err != nil {
 			return err
 		}
+
+		// Copy the service before normalizing its tags, an in-memory RPC
+		// shares it with the caller.
+		if c.srv.config.NormalizeServiceTags {
+			service := *args.Service
+			service.Tags = structs.NormalizeTags(service.Tags)
+			args.Service = &service
+		}
 	}
 
 	// Move the old format single check into the slice, and fixup IDs.
@@ -139,6 +159,8 @@ func (c *Catalog) Register(args *structs.RegisterRequest, reply *struct{}) error
 		}
 	}
 
+	// The sync owner of the node is checked when the registration is
+	// applied, so the error comes back in the response.
 	resp, err := c.srv.raftApply(structs.RegisterRequestType, args)
 	if err != nil {
 		return err
@@ -168,37 +190,134 @@ func (c *Catalog) Deregister(args *structs.DeregisterRequest, reply *struct{}) e
 	}
 
 	// Check the complete deregister request against the given ACL policy.
-	if rule != nil && c.srv.config.ACLEnforceVersion8 {
-		state := c.srv.fsm.State()
+	if err := c.vetDeregister(rule, args); err != nil {
+		return err
+	}
 
-		var ns *structs.NodeService
-		if args.ServiceID != "" {
-			_, ns, err = state.NodeService(args.Node, args.ServiceID)
-			if err != nil {
-				return fmt.Errorf("Service lookup failed: %v", err)
-			}
+	// The sync owner of the node is checked when the deregistration is
+	// applied, so the error comes back in the response.
+	resp, err := c.srv.raftApply(structs.DeregisterRequestType, args)
+	if err != nil {
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	return nil
+}
+
+// vetDeregister checks a deregister request against the given ACL policy.
+func (c *Catalog) vetDeregister(rule acl.Authorizer, args *structs.DeregisterRequest) error {
+	if rule == nil || !c.srv.config.ACLEnforceVersion8 {
+		return nil
+	}
+
+	state := c.srv.fsm.State()
+
+	var ns *structs.NodeService
+	if args.ServiceID != "" {
+		var err error
+		_, ns, err = state.NodeService(args.Node, args.ServiceID)
+		if err != nil {
+			return fmt.Errorf("Service lookup failed: %v", err)
 		}
+	}
 
-		var nc *structs.HealthCheck
-		if args.CheckID != "" {
-			_, nc, err = state.NodeCheck(args.Node, args.CheckID)
-			if err != nil {
-				return fmt.Errorf("Check lookup failed: %v", err)
-			}
+	var nc *structs.HealthCheck
+	if args.CheckID != "" {
+		var err error
+		_, nc, err = state.NodeCheck(args.Node, args.CheckID)
+		if err != nil {
+			return fmt.Errorf("Check lookup failed: %v", err)
 		}
+	}
 
-		if err := vetDeregisterWithACL(rule, args, ns, nc); err != nil {
-			return err
+	return vetDeregisterWithACL(rule, args, ns, nc)
+}
+
+// DeregisterBatch is used to remove several services and checks of a node at
+// once. Each deregistration is authorized with its own token, and the ones
+// that are allowed are applied together, so a failed entry doesn't fail the
+// rest of the batch; the outcome of each one is reported in the reply.
+func (c *Catalog) DeregisterBatch(args *structs.DeregisterBatchRequest, reply *structs.DeregisterBatchResponse) error {
+	if done, err := c.srv.forward("Catalog.DeregisterBatch", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"catalog", "deregister_batch"}, time.Now())
+
+	// Verify the args
+	if args.Node == "" {
+		return fmt.Errorf("Must provide node")
+	}
+
+	reply.Errors = make([]string, len(args.Deregistrations))
+	var accepted []structs.DeregisterRequest
+	for i := range args.Deregistrations {
+		dereg := args.Deregistrations[i]
+		dereg.Datacenter = args.Datacenter
+		dereg.Node = args.Node
+		dereg.SyncOwner = args.SyncOwner
+		dereg.ForceSyncOwner = args.ForceSyncOwner
+
+		if err := c.vetDeregisterBatchEntry(&dereg); err != nil {
+			reply.Errors[i] = err.Error()
+			continue
 		}
 
+		// The tokens have been used and mustn't end up in the Raft log.
+		dereg.Token = ""
+		accepted = append(accepted, dereg)
+	}
+	if len(accepted) == 0 {
+		return nil
 	}
 
-	if _, err := c.srv.raftApply(structs.DeregisterRequestType, args); err != nil {
+	// Servers that predate the batch message type can't apply it, so fall
+	// back to one entry per deregistration until they're all upgraded.
+	if !ServersHaveTag(c.srv.LANMembers(), deregisterBatchTag) {
+		for i := range accepted {
+			resp, err := c.srv.raftApply(structs.DeregisterRequestType, &accepted[i])
+			if err != nil {
+				return err
+			}
+			if respErr, ok := resp.(error); ok {
+				return respErr
+			}
+		}
+		return nil
+	}
+
+	batch := &structs.DeregisterBatchRequest{
+		Datacenter:      args.Datacenter,
+		Node:            args.Node,
+		Deregistrations: accepted,
+		SyncOwner:       args.SyncOwner,
+		ForceSyncOwner:  args.ForceSyncOwner,
+	}
+	resp, err := c.srv.raftApply(structs.DeregisterBatchRequestType, batch)
+	if err != nil {
 		return err
 	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
 	return nil
 }
 
+// vetDeregisterBatchEntry verifies one of the deregistrations of a batch and
+// checks it against the ACL policy of its token.
+func (c *Catalog) vetDeregisterBatchEntry(args *structs.DeregisterRequest) error {
+	if args.ServiceID == "" && args.CheckID == "" {
+		return fmt.Errorf("Must provide service or check ID")
+	}
+
+	rule, err := c.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	return c.vetDeregister(rule, args)
+}
+
 // ListDatacenters is used to query for the list of known datacenters
 func (c *Catalog) ListDatacenters(args *struct{}, reply *[]string) error {
 	dcs, err := c.srv.router.GetDatacentersByDistance()
@@ -214,6 +333,57 @@ func (c *Catalog) ListDatacenters(args *struct{}, reply *[]string) error {
 	return nil
 }
 
+// ListDatacentersDetailed is used to query for the list of known datacenters
+// along with their reachability, based on the health of their servers in the
+// WAN pool. The datacenters are sorted the same way as ListDatacenters.
+func (c *Catalog) ListDatacentersDetailed(args *struct{}, reply *[]structs.DatacenterInfo) error {
+	dcs, err := c.srv.router.GetDatacentersByDistance()
+	if err != nil {
+		return err
+	}
+
+	local := c.srv.config.Datacenter
+	if len(dcs) == 0 {
+		dcs = []string{local}
+	}
+
+	infos := make(map[string]*structs.DatacenterInfo)
+	for _, m := range c.srv.WANMembers() {
+		ok, parts := metadata.IsConsulServer(m)
+		if !ok {
+			continue
+		}
+		info, ok := infos[parts.Datacenter]
+		if !ok {
+			info = &structs.DatacenterInfo{Datacenter: parts.Datacenter}
+			infos[parts.Datacenter] = info
+		}
+		info.Servers++
+		switch m.Status {
+		case serf.StatusAlive:
+			info.Reachable = true
+		case serf.StatusFailed:
+			info.FailedServers++
+		}
+	}
+
+	out := make([]structs.DatacenterInfo, 0, len(dcs))
+	for _, dc := range dcs {
+		info := structs.DatacenterInfo{Datacenter: dc}
+		if i, ok := infos[dc]; ok {
+			info = *i
+		}
+		// The local datacenter is always reachable since it's answering.
+		if dc == local {
+			info.Reachable = true
+		}
+		out = append(out, info)
+	}
+
+	*reply = out
+	return nil
+}
+
 // ListNodes is used to query the nodes in a DC
 func (c *Catalog) ListNodes(args *structs.DCSpecificRequest, reply *structs.IndexedNodes) error {
 	if done, err := c.srv.forward("Catalog.ListNodes", args, args, reply); done {
@@ -365,6 +535,12 @@ func (c *Catalog) ServiceNodes(args *structs.ServiceSpecificRequest, reply *stru
 				reply.ServiceNodes = filtered
 			}
 
+			// The service nodes are copies, so they can be annotated.
+			now := time.Now()
+			for _, service := range reply.ServiceNodes {
+				service.Drained = structs.NodeDrained(service.NodeMeta, now)
+			}
+
 			if err := c.srv.filterACL(args.Token, reply); err != nil {
 				return err
 			}
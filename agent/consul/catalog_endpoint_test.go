@@ -16,6 +16,7 @@ import (
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/raft"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -50,6 +51,40 @@ func TestCatalog_Register(t *testing.T) {
 	}
 }
 
+func TestCatalog_Register_NormalizeServiceTags(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.NormalizeServiceTags = true
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			Service: "db",
+			Tags:    []string{"slave", "master", "slave"},
+			Port:    8000,
+		},
+	}
+	var out struct{}
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &arg, &out))
+
+	req := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "db",
+	}
+	var nodes structs.IndexedServiceNodes
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ServiceNodes", &req, &nodes))
+	require.Len(t, nodes.ServiceNodes, 1)
+	require.Equal(t, []string{"master", "slave"}, nodes.ServiceNodes[0].ServiceTags)
+}
+
 func TestCatalog_RegisterService_InvalidAddress(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -155,6 +190,72 @@ func TestCatalog_Register_NodeID(t *testing.T) {
 	}
 }
 
+func TestCatalog_Register_SyncOwner(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	register := func(owner string, force bool) error {
+		arg := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "external",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				Service: "db",
+				Port:    8000,
+			},
+			SyncOwner:      owner,
+			ForceSyncOwner: force,
+			CheckSyncOwner: true,
+		}
+		var out struct{}
+		return msgpackrpc.CallWithCodec(codec, "Catalog.Register", &arg, &out)
+	}
+	owner := func() string {
+		_, node, err := s1.fsm.State().GetNode("external")
+		require.NoError(t, err)
+		return node.SyncOwner
+	}
+
+	// The first registration claims the node.
+	require.NoError(t, register("sync-a", false))
+	require.Equal(t, "sync-a", owner())
+	require.NoError(t, register("sync-a", false))
+
+	// Other owners and registrations without an owner are rejected.
+	err := register("sync-b", false)
+	require.True(t, structs.IsErrSyncOwnerConflict(err), "err: %v", err)
+	err = register("", false)
+	require.True(t, structs.IsErrSyncOwnerConflict(err), "err: %v", err)
+	require.Equal(t, "sync-a", owner())
+
+	// Forcing replaces the owner.
+	require.NoError(t, register("sync-b", true))
+	require.Equal(t, "sync-b", owner())
+
+	// Deregistrations are fenced the same way.
+	dereg := structs.DeregisterRequest{
+		Datacenter:     "dc1",
+		Node:           "external",
+		ServiceID:      "db",
+		SyncOwner:      "sync-a",
+		CheckSyncOwner: true,
+	}
+	var out struct{}
+	err = msgpackrpc.CallWithCodec(codec, "Catalog.Deregister", &dereg, &out)
+	require.True(t, structs.IsErrSyncOwnerConflict(err), "err: %v", err)
+
+	dereg.SyncOwner = "sync-b"
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Deregister", &dereg, &out))
+	_, ns, err := s1.fsm.State().NodeServices(nil, "external")
+	require.NoError(t, err)
+	require.Empty(t, ns.Services)
+}
+
 func TestCatalog_Register_ACLDeny(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServerWithConfig(t, func(c *Config) {
@@ -535,6 +636,180 @@ func TestCatalog_Deregister(t *testing.T) {
 	}
 }
 
+func TestCatalog_DeregisterBatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		batch bool
+	}{
+		"single raft entry": {true},
+		"old servers":       {false},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir1, s1 := testServer(t)
+			defer os.RemoveAll(dir1)
+			defer s1.Shutdown()
+			codec := rpcClient(t, s1)
+			defer codec.Close()
+
+			testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+			if !tc.batch {
+				// Servers which predate batches don't set the tag.
+				tags := make(map[string]string)
+				for k, v := range s1.config.SerfLANConfig.Tags {
+					tags[k] = v
+				}
+				delete(tags, deregisterBatchTag)
+				require.NoError(t, s1.serfLAN.SetTags(tags))
+				retry.Run(t, func(r *retry.R) {
+					if ServersHaveTag(s1.LANMembers(), deregisterBatchTag) {
+						r.Fatal("tag still set")
+					}
+				})
+			}
+
+			for _, id := range []string{"db", "web"} {
+				arg := structs.RegisterRequest{
+					Datacenter: "dc1",
+					Node:       "foo",
+					Address:    "127.0.0.1",
+					Service: &structs.NodeService{
+						ID:      id,
+						Service: id,
+					},
+					Check: &structs.HealthCheck{
+						CheckID: types.CheckID(id + "-check"),
+						Name:    id + "-check",
+					},
+				}
+				var out struct{}
+				require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &arg, &out))
+			}
+
+			arg := structs.DeregisterBatchRequest{
+				Datacenter: "dc1",
+				Node:       "foo",
+				Deregistrations: []structs.DeregisterRequest{
+					{ServiceID: "db"},
+					{},
+					{ServiceID: "web"},
+					{CheckID: "db-check"},
+				},
+			}
+			var out structs.DeregisterBatchResponse
+			require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.DeregisterBatch", &arg, &out))
+			require.Equal(t, []string{"", "Must provide service or check ID", "", ""}, out.Errors)
+
+			state := s1.fsm.State()
+			_, services, err := state.NodeServices(nil, "foo")
+			require.NoError(t, err)
+			require.Empty(t, services.Services)
+			_, checks, err := state.NodeChecks(nil, "foo")
+			require.NoError(t, err)
+			require.Len(t, checks, 1)
+			require.Equal(t, types.CheckID("web-check"), checks[0].CheckID)
+
+			// The batch is a single raft entry, while old servers get one
+			// entry per deregistration.
+			var logs raft.LogStore = s1.raftInmem
+			if s1.raftInmem == nil {
+				logs = s1.raftStore
+			}
+			last, err := logs.LastIndex()
+			require.NoError(t, err)
+			counts := make(map[structs.MessageType]int)
+			for i := uint64(1); i <= last; i++ {
+				var entry raft.Log
+				require.NoError(t, logs.GetLog(i, &entry))
+				if entry.Type == raft.LogCommand {
+					counts[structs.MessageType(entry.Data[0])]++
+				}
+			}
+			if tc.batch {
+				require.Equal(t, 1, counts[structs.DeregisterBatchRequestType])
+				require.Equal(t, 0, counts[structs.DeregisterRequestType])
+			} else {
+				require.Equal(t, 0, counts[structs.DeregisterBatchRequestType])
+				require.Equal(t, 3, counts[structs.DeregisterRequestType])
+			}
+		})
+	}
+}
+
+func TestCatalog_DeregisterBatch_ACLDeny(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLEnforceVersion8 = true
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Create a token that can only write the "db" service.
+	arg := structs.ACLRequest{
+		Datacenter: "dc1",
+		Op:         structs.ACLSet,
+		ACL: structs.ACL{
+			Name: "User token",
+			Type: structs.ACLTokenTypeClient,
+			Rules: `
+service "db" {
+	policy = "write"
+}
+`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var id string
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.Apply", &arg, &id))
+
+	for _, svc := range []string{"db", "web"} {
+		argR := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				Service: svc,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var outR struct{}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &argR, &outR))
+	}
+
+	// Each deregistration is authorized with its own token.
+	argD := structs.DeregisterBatchRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Deregistrations: []structs.DeregisterRequest{
+			{ServiceID: "db", WriteRequest: structs.WriteRequest{Token: id}},
+			{ServiceID: "web", WriteRequest: structs.WriteRequest{Token: id}},
+		},
+	}
+	var out structs.DeregisterBatchResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.DeregisterBatch", &argD, &out))
+	require.Len(t, out.Errors, 2)
+	require.Empty(t, out.Errors[0])
+	require.True(t, acl.IsErrPermissionDenied(fmt.Errorf(out.Errors[1])), out.Errors[1])
+
+	_, services, err := s1.fsm.State().NodeServices(nil, "foo")
+	require.NoError(t, err)
+	require.Len(t, services.Services, 1)
+	require.Contains(t, services.Services, "web")
+}
+
 func TestCatalog_Deregister_ACLDeny(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServerWithConfig(t, func(c *Config) {
@@ -782,6 +1057,45 @@ func TestCatalog_ListDatacenters(t *testing.T) {
 	}
 }
 
+func TestCatalog_ListDatacentersDetailed(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	dir2, s2 := testServerDC(t, "dc2")
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	var out []structs.DatacenterInfo
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ListDatacentersDetailed", struct{}{}, &out))
+	require.Equal(t, []structs.DatacenterInfo{
+		{Datacenter: "dc1", Servers: 1, Reachable: true},
+		{Datacenter: "dc2", Servers: 1, Reachable: true},
+	}, out)
+
+	// Once its only server fails dc2 becomes unreachable.
+	s2.Shutdown()
+	retry.Run(t, func(r *retry.R) {
+		var out []structs.DatacenterInfo
+		if err := msgpackrpc.CallWithCodec(codec, "Catalog.ListDatacentersDetailed", struct{}{}, &out); err != nil {
+			r.Fatal(err)
+		}
+		if len(out) != 2 {
+			r.Fatalf("bad: %v", out)
+		}
+		want := structs.DatacenterInfo{Datacenter: "dc2", Servers: 1, FailedServers: 1}
+		if out[1] != want {
+			r.Fatalf("got %#v want %#v", out[1], want)
+		}
+	})
+}
+
 func TestCatalog_ListDatacenters_DistanceSort(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
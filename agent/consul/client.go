@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/consul/agent/router"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/logger"
 	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/serf/serf"
 	"golang.org/x/time/rate"
@@ -74,10 +75,21 @@ type Client struct {
 	// Logger uses the provided LogOutput
 	logger *log.Logger
 
+	// limitedLogger writes to logger, for the messages of the hot paths
+	// that need to be rate limited.
+	limitedLogger *logger.RateLimitedLogger
+
 	// serf is the Serf cluster maintained inside the DC
 	// which contains all the DC nodes
 	serf *serf.Serf
 
+	// clockSkew is the estimated skew between the local clock and the
+	// servers', from the responses to the clock skew query. clockSkewWarned
+	// is whether a warning was logged about it being above the threshold.
+	clockSkew         clockSkew
+	clockSkewWarned   bool
+	clockSkewWarnLock sync.Mutex
+
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
@@ -135,11 +147,12 @@ func NewClientLogger(config *Config, logger *log.Logger, tlsConfigurator *tlsuti
 
 	// Create client
 	c := &Client{
-		config:     config,
-		connPool:   connPool,
-		eventCh:    make(chan serf.Event, serfEventBacklog),
-		logger:     logger,
-		shutdownCh: make(chan struct{}),
+		config:        config,
+		connPool:      connPool,
+		eventCh:       make(chan serf.Event, serfEventBacklog),
+		logger:        logger,
+		limitedLogger: newLimitedLogger(logger),
+		shutdownCh:    make(chan struct{}),
 	}
 
 	c.rpcLimiter.Store(rate.NewLimiter(config.RPCRate, config.RPCMaxBurst))
@@ -184,6 +197,12 @@ func NewClientLogger(config *Config, logger *log.Logger, tlsConfigurator *tlsuti
 	// handlers depend on the router and the router depends on Serf.
 	go c.lanEventHandler()
 
+	// Start estimating the skew with the servers' clocks.
+	go c.monitorClockSkew()
+
+	// Start the metrics handlers.
+	go c.gossipStats()
+
 	if err := c.startEnterprise(); err != nil {
 		c.Shutdown()
 		return nil, err
@@ -304,7 +323,8 @@ TRY:
 	}
 
 	// Move off to another server, and see if we can retry.
-	c.logger.Printf("[ERR] consul: %q RPC failed to server %s: %v", method, server.Addr, rpcErr)
+	c.limitedLogger.RateLimited("consul.client.rpc_failed", logger.DefaultRateLimitInterval).Printf(
+		"[ERR] consul: %q RPC failed to server %s: %v", method, server.Addr, rpcErr)
 	metrics.IncrCounterWithLabels([]string{"client", "rpc", "failed"}, 1, []metrics.Label{{Name: "server", Value: server.Name}})
 	c.routers.NotifyFailedServer(server)
 	if retry := canRetry(args, rpcErr); !retry {
@@ -386,6 +406,10 @@ func (c *Client) Stats() map[string]map[string]string {
 		"runtime":  runtimeStats(),
 	}
 
+	if skew, ok := c.clockSkew.get(); ok {
+		stats["consul"]["clock_skew_ms"] = strconv.FormatInt(int64(skew/time.Millisecond), 10)
+	}
+
 	if c.ACLsEnabled() {
 		if c.UseLegacyACLs() {
 			stats["consul"]["acl"] = "legacy"
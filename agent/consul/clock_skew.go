@@ -0,0 +1,247 @@
+package consul
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/metadata"
+	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/serf/serf"
+)
+
+const (
+	// clockSkewQuery is the name of the Serf query servers answer with
+	// their current time.
+	clockSkewQuery = "consul:clock"
+
+	// clockSkewMaxRTT is the longest round trip of a clock skew query
+	// whose responses are used to estimate the skew. The estimate can be
+	// off by up to half of the round trip.
+	clockSkewMaxRTT = 500 * time.Millisecond
+
+	// clockSkewClusterQueryRate is the number of clock skew queries per
+	// second the agents of a cluster aim to send in aggregate.
+	clockSkewClusterQueryRate = 1.0
+
+	// clockSkewWeight is the weight of a new sample in the smoothed skew.
+	clockSkewWeight = 0.2
+)
+
+// clockSkew is a smoothed estimate of the skew between the local clock and
+// the clock of a remote node. A positive skew means the remote clock is
+// ahead.
+type clockSkew struct {
+	skew  time.Duration
+	known bool
+	l     sync.Mutex
+}
+
+// observe adds a sample of the skew and returns the updated estimate.
+func (c *clockSkew) observe(sample time.Duration) time.Duration {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if !c.known {
+		c.skew, c.known = sample, true
+	} else {
+		c.skew += time.Duration(clockSkewWeight * float64(sample-c.skew))
+	}
+	return c.skew
+}
+
+// get returns the current estimate, and false if there are no samples yet.
+func (c *clockSkew) get() (time.Duration, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.skew, c.known
+}
+
+// monitorClockSkew periodically queries the servers for their time over Serf
+// to estimate the skew between the agent's clock and theirs. The queries are
+// spread out so the whole cluster sends about clockSkewClusterQueryRate of
+// them per second, but each agent waits at least ClockSkewQueryInterval
+// between them.
+func (c *Client) monitorClockSkew() {
+	for {
+		intv := lib.RateScaledInterval(clockSkewClusterQueryRate, c.config.ClockSkewQueryInterval, c.serf.NumNodes())
+		select {
+		case <-time.After(intv + lib.RandomStagger(intv)):
+			c.queryClockSkew()
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// queryClockSkew sends the clock skew query to the known servers and adds the
+// responses to the estimate.
+func (c *Client) queryClockSkew() {
+	var names []string
+	for _, m := range c.serf.Members() {
+		if ok, _ := metadata.IsConsulServer(m); ok && m.Status == serf.StatusAlive {
+			names = append(names, m.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clockSkewMaxRTT)
+	defer cancel()
+	err := queryClockSkew(ctx, c.serf, names, func(_ string, sample time.Duration) {
+		c.observeClockSkew(sample)
+	})
+	if err != nil {
+		c.logger.Printf("[WARN] consul: Failed to query servers for clock skew: %v", err)
+	}
+}
+
+// observeClockSkew adds a sample of the skew between the agent and the
+// servers to the estimate, and logs when it crosses the warning threshold.
+func (c *Client) observeClockSkew(sample time.Duration) {
+	skew := c.clockSkew.observe(sample)
+	metrics.SetGauge([]string{"agent", "clock_skew_ms"}, float32(skew.Seconds()*1000))
+
+	c.clockSkewWarnLock.Lock()
+	defer c.clockSkewWarnLock.Unlock()
+	over := skew > c.config.ClockSkewWarnThreshold || -skew > c.config.ClockSkewWarnThreshold
+	switch {
+	case over && !c.clockSkewWarned:
+		c.logger.Printf("[WARN] consul: Clock skew with the servers is %v, above %v. Sessions, TTL checks and certificates may misbehave",
+			skew, c.config.ClockSkewWarnThreshold)
+	case !over && c.clockSkewWarned:
+		c.logger.Printf("[INFO] consul: Clock skew with the servers is back to %v", skew)
+	}
+	c.clockSkewWarned = over
+}
+
+// encodeClockSkewTime encodes a time for a response to the clock skew query.
+func encodeClockSkewTime(t time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(t.UnixNano()))
+	return buf
+}
+
+// decodeClockSkewTime decodes a time from a response to the clock skew query.
+func decodeClockSkewTime(buf []byte) (time.Time, bool) {
+	if len(buf) != 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(buf))), true
+}
+
+// handleClockSkewQuery answers a clock skew query with the local time.
+func (s *Server) handleClockSkewQuery(q *serf.Query) {
+	if err := q.Respond(encodeClockSkewTime(time.Now())); err != nil {
+		s.logger.Printf("[WARN] consul: Failed to respond to clock skew query: %v", err)
+	}
+}
+
+// fetchClockSkew queries the given servers for their time over Serf and
+// returns the smoothed skew of each of them with the local clock, keyed by
+// server ID. Servers without an estimate yet are left out. The query is only
+// sent once per ClockSkewQueryInterval, the last estimates are returned in
+// between.
+func (s *Server) fetchClockSkew(ctx context.Context, members []serf.Member) map[string]time.Duration {
+	s.clockSkewLock.Lock()
+	defer s.clockSkewLock.Unlock()
+
+	ids := make(map[string]string)
+	current := make(map[string]struct{})
+	var names []string
+	for _, m := range members {
+		if m.Tags["role"] != "consul" {
+			continue
+		}
+		ids[m.Name] = m.Tags["id"]
+		current[m.Tags["id"]] = struct{}{}
+		names = append(names, m.Name)
+	}
+
+	// Forget the servers that are gone.
+	for id := range s.clockSkew {
+		if _, ok := current[id]; !ok {
+			delete(s.clockSkew, id)
+		}
+	}
+
+	if time.Since(s.clockSkewQueried) >= s.config.ClockSkewQueryInterval {
+		s.queryClockSkewLocked(ctx, ids, names)
+	}
+
+	out := make(map[string]time.Duration)
+	for id, skew := range s.clockSkew {
+		if d, ok := skew.get(); ok {
+			out[id] = d
+		}
+	}
+	return out
+}
+
+// queryClockSkewLocked sends the clock skew query to the servers with the
+// given names and adds the responses to their estimates. ids maps the names
+// to server IDs. The clockSkewLock must be held.
+func (s *Server) queryClockSkewLocked(ctx context.Context, ids map[string]string, names []string) {
+	sent := time.Now()
+	err := queryClockSkew(ctx, s.serfLAN, names, func(from string, sample time.Duration) {
+		id, ok := ids[from]
+		if !ok {
+			return
+		}
+		skew, ok := s.clockSkew[id]
+		if !ok {
+			skew = &clockSkew{}
+			s.clockSkew[id] = skew
+		}
+		skew.observe(sample)
+	})
+	if err != nil {
+		s.logger.Printf("[WARN] consul: Failed to query servers for clock skew: %v", err)
+		return
+	}
+	s.clockSkewQueried = sent
+}
+
+// queryClockSkew sends the clock skew query to the servers with the given
+// names and calls observe with the skew between the local clock and the clock
+// of each server that responds in time. The remote time is assumed to be
+// taken halfway through the round trip, so responses with a long round trip
+// are ignored.
+func queryClockSkew(ctx context.Context, s *serf.Serf, names []string, observe func(from string, sample time.Duration)) error {
+	params := s.DefaultQueryParams()
+	params.FilterNodes = names
+	if deadline, ok := ctx.Deadline(); ok {
+		params.Timeout = time.Until(deadline)
+	}
+
+	sent := time.Now()
+	resp, err := s.Query(clockSkewQuery, nil, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	ch := resp.ResponseCh()
+	for {
+		select {
+		case r, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			rtt := time.Since(sent)
+			if rtt > clockSkewMaxRTT {
+				continue
+			}
+			remote, ok := decodeClockSkewTime(r.Payload)
+			if !ok {
+				continue
+			}
+			observe(r.From, remote.Sub(sent.Add(rtt/2)))
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
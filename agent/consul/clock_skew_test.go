@@ -0,0 +1,152 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockSkew_Observe(t *testing.T) {
+	t.Parallel()
+
+	var c clockSkew
+	_, ok := c.get()
+	require.False(t, ok)
+
+	// The first sample is taken as is.
+	require.Equal(t, 10*time.Second, c.observe(10*time.Second))
+	skew, ok := c.get()
+	require.True(t, ok)
+	require.Equal(t, 10*time.Second, skew)
+
+	// Later ones are smoothed.
+	require.Equal(t, 8*time.Second, c.observe(0))
+	require.Equal(t, 6400*time.Millisecond, c.observe(0))
+}
+
+func TestClient_ObserveClockSkew(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	c := &Client{
+		config: DefaultConfig(),
+		logger: log.New(&buf, "", 0),
+	}
+
+	c.observeClockSkew(2 * time.Second)
+	skew, ok := c.clockSkew.get()
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, skew)
+	require.Contains(t, buf.String(), "[WARN] consul: Clock skew with the servers is 2s")
+
+	// The warning is only logged once.
+	buf.Reset()
+	c.observeClockSkew(2 * time.Second)
+	require.Empty(t, buf.String())
+
+	// It is reported when the skew comes back below the threshold.
+	for i := 0; i < 20; i++ {
+		c.observeClockSkew(0)
+	}
+	require.Contains(t, buf.String(), "[INFO] consul: Clock skew with the servers is back to")
+}
+
+func TestClient_ClockSkewStats(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, c1 := testClientWithConfig(t, func(c *Config) {
+		c.ClockSkewQueryInterval = 100 * time.Millisecond
+	})
+	defer os.RemoveAll(dir2)
+	defer c1.Shutdown()
+
+	require.NotContains(t, c1.Stats()["consul"], "clock_skew_ms")
+
+	joinLAN(t, c1, s1)
+	retry.Run(t, func(r *retry.R) {
+		skew, ok := c1.Stats()["consul"]["clock_skew_ms"]
+		if !ok {
+			r.Fatal("missing clock skew")
+		}
+		ms, err := strconv.Atoi(skew)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if ms > 1000 || ms < -1000 {
+			r.Fatalf("bad skew: %d", ms)
+		}
+	})
+}
+
+func TestServer_FetchClockSkew(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ClockSkewQueryInterval = 0
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	dir2, s2 := testServerDCBootstrap(t, "dc1", false)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+
+	joinLAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	retry.Run(t, func(r *retry.R) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		skews := s1.fetchClockSkew(ctx, s1.LANMembers())
+		if len(skews) != 2 {
+			r.Fatalf("bad: %v", skews)
+		}
+		for _, id := range []string{string(s1.config.NodeID), string(s2.config.NodeID)} {
+			skew, ok := skews[id]
+			if !ok {
+				r.Fatalf("missing %s: %v", id, skews)
+			}
+			if skew > time.Second || skew < -time.Second {
+				r.Fatalf("bad skew for %s: %v", id, skew)
+			}
+		}
+	})
+}
+
+func TestServer_FetchClockSkew_QueryInterval(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	fetch := func() time.Time {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s1.fetchClockSkew(ctx, s1.LANMembers())
+
+		s1.clockSkewLock.Lock()
+		defer s1.clockSkewLock.Unlock()
+		return s1.clockSkewQueried
+	}
+
+	// The second fetch is within the interval and reuses the estimates.
+	queried := fetch()
+	if queried.IsZero() {
+		t.Fatalf("the servers weren't queried")
+	}
+	if again := fetch(); !again.Equal(queried) {
+		t.Fatalf("the servers were queried again at %v", again)
+	}
+}
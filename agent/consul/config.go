@@ -104,6 +104,10 @@ type Config struct {
 	// as a voting member of the Raft cluster.
 	NonVoter bool
 
+	// NormalizeServiceTags removes the duplicate tags of the services
+	// registered in the catalog and sorts them.
+	NormalizeServiceTags bool
+
 	// NotifyListen is called after the RPC listener has been configured.
 	// RPCAdvertise will be set to the listener address if it hasn't been
 	// configured at this point.
@@ -260,6 +264,34 @@ type Config struct {
 	// white-lists.
 	ACLDefaultPolicy string
 
+	// ACLDefaultPolicyOverride replaces ACLDefaultPolicy for the requests
+	// handled in this datacenter when it isn't the ACLDatacenter. It is
+	// either empty, "allow" or "deny".
+	ACLDefaultPolicyOverride string
+
+	// ACLDanglingTokenScanInterval is how often the leader looks for tokens
+	// whose policies were all deleted. Zero disables the scan.
+	ACLDanglingTokenScanInterval time.Duration
+
+	// ACLDanglingTokenDeleteAfter is how long a token has to be found
+	// dangling before the leader deletes it. Zero never deletes them.
+	ACLDanglingTokenDeleteAfter time.Duration
+
+	// ACLUsageSampleRate is the fraction of the RPCs that are attributed to
+	// the accessor ID of their token. Zero disables the attribution.
+	ACLUsageSampleRate float64
+
+	// ACLUsageInterval is the length of the intervals the attributed RPCs
+	// are counted over.
+	ACLUsageInterval time.Duration
+
+	// ACLUsageTopClients is how many accessors are reported per interval.
+	ACLUsageTopClients int
+
+	// ACLUsageLogShare is the share of an interval above which a single
+	// accessor is logged.
+	ACLUsageLogShare float64
+
 	// ACLDownPolicy controls the behavior of ACLs if the ACLDatacenter
 	// cannot be contacted. It can be either "deny" to deny all requests,
 	// "extend-cache" or "async-cache" which ignores the ACLCacheInterval and
@@ -310,9 +342,17 @@ type Config struct {
 	// to reduce overhead. It is unlikely a user would ever need to tune this.
 	TombstoneTTLGranularity time.Duration
 
+	// KVDeletedRetention is how long the deleted KV entries are retained so
+	// they can be restored. Deleted entries aren't retained if it's zero.
+	KVDeletedRetention time.Duration
+
 	// Minimum Session TTL
 	SessionTTLMin time.Duration
 
+	// HealthHistoryRetention is the number of health check status
+	// transitions kept in memory for each service.
+	HealthHistoryRetention int
+
 	// ServerUp callback can be used to trigger a notification that
 	// a Consul server is now up and known about.
 	ServerUp func()
@@ -371,6 +411,17 @@ type Config struct {
 	// dead servers.
 	AutopilotInterval time.Duration
 
+	// ClockSkewWarnThreshold is the estimated skew between the clock of an
+	// agent and the servers' above which the agent logs a warning.
+	ClockSkewWarnThreshold time.Duration
+
+	// ClockSkewQueryInterval is the minimum time between the Serf queries
+	// used to estimate the skew between the clocks of the servers, and
+	// between the clocks of an agent and the servers. Autopilot reuses the
+	// last estimates in between, and agents in large clusters query less
+	// often than this.
+	ClockSkewQueryInterval time.Duration
+
 	// ConnectEnabled is whether to enable Connect features such as the CA.
 	ConnectEnabled bool
 
@@ -414,6 +465,13 @@ func (c *Config) CheckACL() error {
 	default:
 		return fmt.Errorf("Unsupported default ACL policy: %s", c.ACLDefaultPolicy)
 	}
+	switch c.ACLDefaultPolicyOverride {
+	case "":
+	case "allow":
+	case "deny":
+	default:
+		return fmt.Errorf("Unsupported default ACL policy override: %s", c.ACLDefaultPolicyOverride)
+	}
 	switch c.ACLDownPolicy {
 	case "allow":
 	case "deny":
@@ -424,6 +482,16 @@ func (c *Config) CheckACL() error {
 	return nil
 }
 
+// aclLocalDefaultPolicy returns the default ACL policy of the requests
+// handled in this datacenter, and whether it comes from
+// ACLDefaultPolicyOverride.
+func (c *Config) aclLocalDefaultPolicy() (string, bool) {
+	if c.ACLDefaultPolicyOverride != "" && c.Datacenter != c.ACLDatacenter {
+		return c.ACLDefaultPolicyOverride, true
+	}
+	return c.ACLDefaultPolicy, false
+}
+
 // DefaultConfig returns a sane default configuration.
 func DefaultConfig() *Config {
 	hostname, err := os.Hostname()
@@ -452,6 +520,7 @@ func DefaultConfig() *Config {
 		TombstoneTTL:             15 * time.Minute,
 		TombstoneTTLGranularity:  30 * time.Second,
 		SessionTTLMin:            10 * time.Second,
+		HealthHistoryRetention:   50,
 
 		// These are tuned to provide a total throughput of 128 updates
 		// per second. If you update these, you should update the client-
@@ -482,8 +551,15 @@ func DefaultConfig() *Config {
 			},
 		},
 
-		ServerHealthInterval: 2 * time.Second,
-		AutopilotInterval:    10 * time.Second,
+		ServerHealthInterval:   2 * time.Second,
+		AutopilotInterval:      10 * time.Second,
+		ClockSkewWarnThreshold: time.Second,
+		ClockSkewQueryInterval: 5 * time.Minute,
+
+		ACLDanglingTokenScanInterval: time.Hour,
+		ACLUsageInterval:             time.Minute,
+		ACLUsageTopClients:           10,
+		ACLUsageLogShare:             0.5,
 	}
 
 	// Increase our reap interval to 3 days instead of 24h.
@@ -27,6 +27,28 @@ func FilterDirEnt(authorizer acl.Authorizer, ent structs.DirEntries) structs.Dir
 	return ent[:FilterEntries(&df)]
 }
 
+type deletedDirEntFilter struct {
+	authorizer acl.Authorizer
+	ent        structs.DeletedDirEntries
+}
+
+func (d *deletedDirEntFilter) Len() int {
+	return len(d.ent)
+}
+func (d *deletedDirEntFilter) Filter(i int) bool {
+	return !d.authorizer.KeyRead(d.ent[i].Key)
+}
+func (d *deletedDirEntFilter) Move(dst, src, span int) {
+	copy(d.ent[dst:dst+span], d.ent[src:src+span])
+}
+
+// FilterDeletedDirEnt is used to filter a list of deleted directory entries
+// by applying an ACL policy
+func FilterDeletedDirEnt(authorizer acl.Authorizer, ent structs.DeletedDirEntries) structs.DeletedDirEntries {
+	df := deletedDirEntFilter{authorizer: authorizer, ent: ent}
+	return ent[:FilterEntries(&df)]
+}
+
 type keyFilter struct {
 	authorizer acl.Authorizer
 	keys       []string
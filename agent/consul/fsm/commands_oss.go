@@ -30,6 +30,7 @@ func init() {
 	registerCommand(structs.ACLPolicyDeleteRequestType, (*FSM).applyACLPolicyDeleteOperation)
 	registerCommand(structs.ConnectCALeafRequestType, (*FSM).applyConnectCALeafOperation)
 	registerCommand(structs.ConfigEntryRequestType, (*FSM).applyConfigEntryOperation)
+	registerCommand(structs.DeregisterBatchRequestType, (*FSM).applyDeregisterBatch)
 }
 
 func (c *FSM) applyRegister(buf []byte, index uint64) interface{} {
@@ -57,21 +58,23 @@ func (c *FSM) applyDeregister(buf []byte, index uint64) interface{} {
 	// Either remove the service entry or the whole node. The precedence
 	// here is also baked into vetDeregisterWithACL() in acl.go, so if you
 	// make changes here, be sure to also adjust the code over there.
-	if req.ServiceID != "" {
-		if err := c.state.DeleteService(index, req.Node, req.ServiceID); err != nil {
-			c.logger.Printf("[WARN] consul.fsm: DeleteNodeService failed: %v", err)
-			return err
-		}
-	} else if req.CheckID != "" {
-		if err := c.state.DeleteCheck(index, req.Node, req.CheckID); err != nil {
-			c.logger.Printf("[WARN] consul.fsm: DeleteNodeCheck failed: %v", err)
-			return err
-		}
-	} else {
-		if err := c.state.DeleteNode(index, req.Node); err != nil {
-			c.logger.Printf("[WARN] consul.fsm: DeleteNode failed: %v", err)
-			return err
-		}
+	if err := c.state.Deregister(index, &req); err != nil {
+		c.logger.Printf("[WARN] consul.fsm: Deregister failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *FSM) applyDeregisterBatch(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"fsm", "deregister_batch"}, time.Now())
+	var req structs.DeregisterBatchRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := c.state.DeregisterBatch(index, &req); err != nil {
+		c.logger.Printf("[WARN] consul.fsm: DeregisterBatch failed: %v", err)
+		return err
 	}
 	return nil
 }
@@ -87,15 +90,15 @@ func (c *FSM) applyKVSOperation(buf []byte, index uint64) interface{} {
 	case api.KVSet:
 		return c.state.KVSSet(index, &req.DirEnt)
 	case api.KVDelete:
-		return c.state.KVSDelete(index, req.DirEnt.Key)
+		return c.state.KVSDelete(index, req.DirEnt.Key, req.Retention)
 	case api.KVDeleteCAS:
-		act, err := c.state.KVSDeleteCAS(index, req.DirEnt.ModifyIndex, req.DirEnt.Key)
+		act, err := c.state.KVSDeleteCAS(index, req.DirEnt.ModifyIndex, req.DirEnt.Key, req.Retention)
 		if err != nil {
 			return err
 		}
 		return act
 	case api.KVDeleteTree:
-		return c.state.KVSDeleteTree(index, req.DirEnt.Key)
+		return c.state.KVSDeleteTree(index, req.DirEnt.Key, req.Retention)
 	case api.KVCAS:
 		act, err := c.state.KVSSetCAS(index, &req.DirEnt)
 		if err != nil {
@@ -198,6 +201,8 @@ func (c *FSM) applyTombstoneOperation(buf []byte, index uint64) interface{} {
 	switch req.Op {
 	case structs.TombstoneReap:
 		return c.state.ReapTombstones(req.ReapIndex)
+	case structs.TombstoneReapKVSDeleted:
+		return c.state.ReapKVSDeleted(req.ReapIndex)
 	default:
 		c.logger.Printf("[WARN] consul.fsm: Invalid Tombstone operation '%s'", req.Op)
 		return fmt.Errorf("Invalid Tombstone operation '%s'", req.Op)
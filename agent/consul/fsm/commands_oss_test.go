@@ -271,6 +271,67 @@ func TestFSM_DeregisterCheck(t *testing.T) {
 	}
 }
 
+func TestFSM_DeregisterBatch(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	fsm, err := New(nil, os.Stderr)
+	require.NoError(err)
+
+	req := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "db",
+			Service: "db",
+			Port:    8000,
+		},
+		Checks: structs.HealthChecks{
+			&structs.HealthCheck{
+				Node:    "foo",
+				CheckID: "mem",
+				Name:    "memory util",
+				Status:  api.HealthPassing,
+			},
+			&structs.HealthCheck{
+				Node:    "foo",
+				CheckID: "disk",
+				Name:    "disk util",
+				Status:  api.HealthPassing,
+			},
+		},
+	}
+	buf, err := structs.Encode(structs.RegisterRequestType, req)
+	require.NoError(err)
+	require.Nil(fsm.Apply(makeLog(buf)))
+
+	dereg := structs.DeregisterBatchRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Deregistrations: []structs.DeregisterRequest{
+			{ServiceID: "db"},
+			{CheckID: "mem"},
+		},
+	}
+	buf, err = structs.Encode(structs.DeregisterBatchRequestType, dereg)
+	require.NoError(err)
+	require.Nil(fsm.Apply(makeLog(buf)))
+
+	// Verify the node and the other check are still registered.
+	_, node, err := fsm.state.GetNode("foo")
+	require.NoError(err)
+	require.NotNil(node)
+
+	_, services, err := fsm.state.NodeServices(nil, "foo")
+	require.NoError(err)
+	require.Empty(services.Services)
+
+	_, checks, err := fsm.state.NodeChecks(nil, "foo")
+	require.NoError(err)
+	require.Len(checks, 1)
+	require.Equal(types.CheckID("disk"), checks[0].CheckID)
+}
+
 func TestFSM_DeregisterNode(t *testing.T) {
 	t.Parallel()
 	fsm, err := New(nil, os.Stderr)
@@ -1011,7 +1072,7 @@ func TestFSM_TombstoneReap(t *testing.T) {
 		Key:   "/remove",
 		Value: []byte("foo"),
 	})
-	fsm.state.KVSDelete(12, "/remove")
+	fsm.state.KVSDelete(12, "/remove", nil)
 	idx, _, err := fsm.state.KVSList(nil, "/remove")
 	if err != nil {
 		t.Fatalf("err: %s", err)
@@ -60,6 +60,10 @@ type FSM struct {
 	state     *state.Store
 
 	gc *state.TombstoneGC
+
+	// healthHistory is handed to every state store so recorded health
+	// transitions survive snapshot restores.
+	healthHistory *state.HealthHistory
 }
 
 // New is used to construct a new FSM with a blank state.
@@ -88,6 +92,13 @@ func New(gc *state.TombstoneGC, logOutput io.Writer) (*FSM, error) {
 	return fsm, nil
 }
 
+// SetHealthHistory sets where health check status transitions are recorded.
+// It must be called before the FSM is used.
+func (c *FSM) SetHealthHistory(h *state.HealthHistory) {
+	c.healthHistory = h
+	c.state.SetHealthHistory(h)
+}
+
 // State is used to return a handle to the current state
 func (c *FSM) State() *state.Store {
 	c.stateLock.RLock()
@@ -140,6 +151,7 @@ func (c *FSM) Restore(old io.ReadCloser) error {
 	if err != nil {
 		return err
 	}
+	stateNew.SetHealthHistory(c.healthHistory)
 
 	// Set up a new restore transaction
 	restore := stateNew.Restore()
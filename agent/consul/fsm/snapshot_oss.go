@@ -28,6 +28,7 @@ func init() {
 	registerRestorer(structs.ACLTokenSetRequestType, restoreToken)
 	registerRestorer(structs.ACLPolicySetRequestType, restorePolicy)
 	registerRestorer(structs.ConfigEntryRequestType, restoreConfigEntry)
+	registerRestorer(structs.KVSDeletedType, restoreKVDeleted)
 }
 
 func persistOSS(s *snapshot, sink raft.SnapshotSink, encoder *codec.Encoder) error {
@@ -46,6 +47,9 @@ func persistOSS(s *snapshot, sink raft.SnapshotSink, encoder *codec.Encoder) err
 	if err := s.persistTombstones(sink, encoder); err != nil {
 		return err
 	}
+	if err := s.persistKVsDeleted(sink, encoder); err != nil {
+		return err
+	}
 	if err := s.persistPreparedQueries(sink, encoder); err != nil {
 		return err
 	}
@@ -92,6 +96,7 @@ func (s *snapshot) persistNodes(sink raft.SnapshotSink,
 			Address:         n.Address,
 			TaggedAddresses: n.TaggedAddresses,
 			NodeMeta:        n.Meta,
+			SyncOwner:       n.SyncOwner,
 		}
 
 		// Register the node itself
@@ -251,6 +256,24 @@ func (s *snapshot) persistTombstones(sink raft.SnapshotSink,
 	return nil
 }
 
+func (s *snapshot) persistKVsDeleted(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	entries, err := s.state.KVsDeleted()
+	if err != nil {
+		return err
+	}
+
+	for entry := entries.Next(); entry != nil; entry = entries.Next() {
+		if _, err := sink.Write([]byte{byte(structs.KVSDeletedType)}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(entry.(*structs.DeletedDirEntry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *snapshot) persistPreparedQueries(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
 	queries, err := s.state.PreparedQueries()
@@ -449,6 +472,17 @@ func restoreTombstone(header *snapshotHeader, restore *state.Restore, decoder *c
 	return nil
 }
 
+func restoreKVDeleted(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.DeletedDirEntry
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	if err := restore.KVSDeleted(&req); err != nil {
+		return err
+	}
+	return nil
+}
+
 func restoreSession(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
 	var req structs.Session
 	if err := decoder.Decode(&req); err != nil {
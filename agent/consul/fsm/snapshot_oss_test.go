@@ -116,7 +116,11 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 		Key:   "/remove",
 		Value: []byte("foo"),
 	})
-	fsm.state.KVSDelete(12, "/remove")
+	retention := &structs.KVSRetention{
+		DeletedAt: time.Now(),
+		DeletedBy: "e0f5ab2a-3c1a-4c2c-a2f4-4a0e3a5f6f3b",
+	}
+	fsm.state.KVSDelete(12, "/remove", retention)
 	idx, _, err := fsm.state.KVSList(nil, "/remove")
 	if err != nil {
 		t.Fatalf("err: %s", err)
@@ -350,6 +354,15 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 		}
 	}()
 
+	// Verify deleted KV entries are restored
+	_, deleted, err := fsm2.state.KVSDeletedGet(nil, "/remove")
+	require.NoError(err)
+	require.NotNil(deleted)
+	require.Equal([]byte("foo"), deleted.Value)
+	require.True(retention.DeletedAt.Equal(deleted.DeletedAt))
+	require.Equal(retention.DeletedBy, deleted.DeletedBy)
+	require.Equal(uint64(12), deleted.ModifyIndex)
+
 	// Verify coordinates are restored
 	_, coords, err := fsm2.state.Coordinates(nil)
 	if err != nil {
@@ -0,0 +1,80 @@
+package consul
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/serf/serf"
+)
+
+// gossipStatsInterval is how often the depths of the Serf broadcast queues
+// are sampled.
+const gossipStatsInterval = 5 * time.Second
+
+// serfQueues are the broadcast queues of a Serf pool, as named in the
+// "<queue>_queue" keys of its stats.
+var serfQueues = []string{"intent", "event", "query"}
+
+// emitSerfQueueStats sets the depth gauges of the broadcast queues of a
+// Serf pool. The labels identify the pool.
+func emitSerfQueueStats(cluster *serf.Serf, labels ...metrics.Label) {
+	stats := cluster.Stats()
+	for _, queue := range serfQueues {
+		depth, err := strconv.ParseUint(stats[queue+"_queue"], 10, 64)
+		if err != nil {
+			continue
+		}
+		queueLabels := append([]metrics.Label{{Name: "queue", Value: queue}}, labels...)
+		metrics.SetGaugeWithLabels([]string{"serf", "queue_depth"}, float32(depth), queueLabels)
+	}
+}
+
+// gossipStats is a long running routine used to capture the broadcast queue
+// depths of the LAN pool of each segment and of the WAN pool.
+func (s *Server) gossipStats() {
+	for {
+		select {
+		case <-time.After(gossipStatsInterval):
+			s.emitGossipStats()
+
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
+// emitGossipStats sets the broadcast queue depth gauges of the server's Serf
+// pools.
+func (s *Server) emitGossipStats() {
+	for name, segment := range s.LANSegments() {
+		emitSerfQueueStats(segment,
+			metrics.Label{Name: "pool", Value: "lan"},
+			metrics.Label{Name: "segment", Value: name})
+	}
+	if s.serfWAN != nil {
+		emitSerfQueueStats(s.serfWAN, metrics.Label{Name: "pool", Value: "wan"})
+	}
+}
+
+// gossipStats is a long running routine used to capture the broadcast queue
+// depths of the client's LAN pool.
+func (c *Client) gossipStats() {
+	for {
+		select {
+		case <-time.After(gossipStatsInterval):
+			c.emitGossipStats()
+
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// emitGossipStats sets the broadcast queue depth gauges of the client's Serf
+// pool.
+func (c *Client) emitGossipStats() {
+	emitSerfQueueStats(c.serf,
+		metrics.Label{Name: "pool", Value: "lan"},
+		metrics.Label{Name: "segment", Value: c.config.Segment})
+}
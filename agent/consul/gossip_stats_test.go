@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+// queueDepthGauges returns the labels of the queue depth gauges in the sink.
+func queueDepthGauges(sink *metrics.InmemSink) []map[string]string {
+	var out []map[string]string
+	for _, intv := range sink.Data() {
+		intv.RLock()
+		for _, g := range intv.Gauges {
+			if g.Name != "consul.serf.queue_depth" {
+				continue
+			}
+			labels := make(map[string]string)
+			for _, l := range g.Labels {
+				labels[l.Name] = l.Value
+			}
+			out = append(out, labels)
+		}
+		intv.RUnlock()
+	}
+	return out
+}
+
+func TestGossipStats(t *testing.T) {
+	// Not parallel since the metrics sink is global.
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	cfg := metrics.DefaultConfig("consul")
+	cfg.EnableHostname = false
+	metrics.NewGlobal(cfg, sink)
+	defer metrics.NewGlobal(metrics.DefaultConfig("consul"), &metrics.BlackholeSink{})
+
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	dir2, c1 := testClient(t)
+	defer os.RemoveAll(dir2)
+	defer c1.Shutdown()
+
+	s1.emitGossipStats()
+	c1.emitGossipStats()
+
+	gauges := queueDepthGauges(sink)
+	for _, queue := range serfQueues {
+		require.Contains(t, gauges, map[string]string{"queue": queue, "pool": "lan", "segment": ""})
+		require.Contains(t, gauges, map[string]string{"queue": queue, "pool": "wan"})
+	}
+}
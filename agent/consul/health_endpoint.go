@@ -3,6 +3,7 @@ package consul
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/agent/consul/state"
@@ -140,6 +141,31 @@ func (h *Health) ServiceChecks(args *structs.ServiceSpecificRequest,
 		})
 }
 
+// ServiceHistory returns the recent health check status transitions of a
+// service as recorded by the server handling the request.
+func (h *Health) ServiceHistory(args *structs.ServiceSpecificRequest, reply *structs.IndexedHealthTransitions) error {
+	if done, err := h.srv.forward("Health.ServiceHistory", args, args, reply); done {
+		return err
+	}
+
+	// Verify the arguments
+	if args.ServiceName == "" {
+		return fmt.Errorf("Must provide service name")
+	}
+
+	return h.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, transitions, err := state.ServiceHealthHistory(ws, args.ServiceName)
+			if err != nil {
+				return err
+			}
+			reply.Index, reply.Transitions = index, transitions
+			return h.srv.filterACL(args.Token, reply)
+		})
+}
+
 // ServiceNodes returns all the nodes registered as part of a service including health info
 func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *structs.IndexedCheckServiceNodes) error {
 	if done, err := h.srv.forward("Health.ServiceNodes", args, args, reply); done {
@@ -162,15 +188,15 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 		f = h.serviceNodesDefault
 	}
 
+	// Fetch the ACL token, if any.
+	rule, err := h.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+
 	// If we're doing a connect query, we need read access to the service
 	// we're trying to find proxies for, so check that.
 	if args.Connect {
-		// Fetch the ACL token, if any.
-		rule, err := h.srv.ResolveToken(args.Token)
-		if err != nil {
-			return err
-		}
-
 		if rule != nil && !rule.ServiceRead(args.ServiceName) {
 			// Just return nil, which will return an empty response (tested)
 			return nil
@@ -195,6 +221,19 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 			if len(args.NodeMetaFilters) > 0 {
 				reply.Nodes = nodeMetaFilter(args.NodeMetaFilters, reply.Nodes)
 			}
+			if !args.IncludeDrained {
+				reply.Nodes = drainedFilter(time.Now(), reply.Nodes)
+			}
+
+			// Include the routing override of the service so the agent can
+			// apply it, since the answer depends on the source of the query.
+			// Queries asking for specific tags are answered as is.
+			// The override is only read if the token can read the service.
+			if !args.Connect && !args.TagFilter && (rule == nil || rule.ServiceRead(args.ServiceName)) {
+				if err := h.serviceRouting(ws, state, args, reply); err != nil {
+					return err
+				}
+			}
 
 			if err := h.srv.filterACL(args.Token, reply); err != nil {
 				return err
@@ -265,3 +304,20 @@ func (h *Health) serviceNodesTagFilter(ws memdb.WatchSet, s *state.Store, args *
 func (h *Health) serviceNodesDefault(ws memdb.WatchSet, s *state.Store, args *structs.ServiceSpecificRequest) (uint64, structs.CheckServiceNodes, error) {
 	return s.CheckServiceNodes(ws, args.ServiceName)
 }
+
+// serviceRouting attaches the routing override of the requested service to
+// the reply, if there is one.
+func (h *Health) serviceRouting(ws memdb.WatchSet, s *state.Store, args *structs.ServiceSpecificRequest, reply *structs.IndexedCheckServiceNodes) error {
+	index, routing, err := s.ServiceRouting(ws, args.ServiceName)
+	if err != nil {
+		return err
+	}
+	reply.Routing = routing
+
+	// Changing or deleting the override must also unblock the query, so
+	// track the index of the config entries too.
+	if index > reply.Index {
+		reply.Index = index
+	}
+	return nil
+}
@@ -705,6 +705,68 @@ func TestHealth_ServiceNodes_MultipleServiceTags(t *testing.T) {
 	require.Equal(t, nodes[0].Checks[0].Status, api.HealthPassing)
 }
 
+func TestHealth_ServiceNodes_Drained(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Register an instance on a drained node, on a node whose drain has
+	// ended, and on a node that isn't drained.
+	metas := map[string]map[string]string{
+		"drained": {structs.NodeDrainedMetaKey: ""},
+		"ended":   {structs.NodeDrainedMetaKey: time.Now().Add(-time.Minute).Format(time.RFC3339)},
+		"active":  nil,
+	}
+	for node, meta := range metas {
+		arg := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       node,
+			Address:    "127.0.0.1",
+			NodeMeta:   meta,
+			Service: &structs.NodeService{
+				ID:      "db",
+				Service: "db",
+			},
+		}
+		var out struct{}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &arg, &out))
+	}
+
+	nodeNames := func(nodes structs.CheckServiceNodes) []string {
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.Node.Node)
+		}
+		return names
+	}
+
+	// The drained node is excluded by default.
+	req := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "db",
+	}
+	var out structs.IndexedCheckServiceNodes
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Health.ServiceNodes", &req, &out))
+	require.ElementsMatch(t, []string{"ended", "active"}, nodeNames(out.Nodes))
+
+	req.IncludeDrained = true
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Health.ServiceNodes", &req, &out))
+	require.ElementsMatch(t, []string{"drained", "ended", "active"}, nodeNames(out.Nodes))
+
+	// The catalog keeps the drained node, and annotates it.
+	var services structs.IndexedServiceNodes
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ServiceNodes", &req, &services))
+	require.Len(t, services.ServiceNodes, 3)
+	for _, service := range services.ServiceNodes {
+		require.Equal(t, service.Node == "drained", service.Drained, service.Node)
+	}
+}
+
 func TestHealth_ServiceNodes_NodeMetaFilter(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
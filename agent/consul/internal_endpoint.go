@@ -110,6 +110,28 @@ func (m *Internal) ServiceDump(args *structs.DCSpecificRequest, reply *structs.I
 		})
 }
 
+// ConnectMismatches is used to find the service instances that can't be
+// reached through Connect because their sidecar proxy is missing or critical,
+// along with sidecar proxies whose service is gone.
+func (m *Internal) ConnectMismatches(args *structs.DCSpecificRequest, reply *structs.IndexedConnectMismatches) error {
+	if done, err := m.srv.forward("Internal.ConnectMismatches", args, args, reply); done {
+		return err
+	}
+
+	return m.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, mismatches, err := state.ConnectMismatches(ws)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.Mismatches = index, mismatches
+			return m.srv.filterACL(args.Token, reply)
+		})
+}
+
 // EventFire is a bit of an odd endpoint, but it allows for a cross-DC RPC
 // call to fire an event. The primary use case is to enable user events being
 // triggered in a remote DC.
@@ -483,3 +483,55 @@ func TestInternal_ServiceDump(t *testing.T) {
 		require.Len(t, nodes, 3)
 	})
 }
+
+func TestInternal_ConnectMismatches(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	register := func(svc *structs.NodeService, check *structs.HealthCheck) {
+		t.Helper()
+		args := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service:    svc,
+			Check:      check,
+		}
+		var out struct{}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &args, &out))
+	}
+
+	register(&structs.NodeService{ID: "web1", Service: "web", Port: 8080}, nil)
+	register(&structs.NodeService{ID: "web2", Service: "web", Port: 8081}, nil)
+	register(&structs.NodeService{
+		Kind:    structs.ServiceKindConnectProxy,
+		ID:      "web1-sidecar-proxy",
+		Service: "web-sidecar-proxy",
+		Port:    20000,
+		Proxy: structs.ConnectProxyConfig{
+			DestinationServiceName: "web",
+			DestinationServiceID:   "web1",
+		},
+	}, &structs.HealthCheck{
+		Node:      "foo",
+		CheckID:   "proxy",
+		Name:      "proxy",
+		Status:    api.HealthCritical,
+		ServiceID: "web1-sidecar-proxy",
+	})
+
+	args := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var out structs.IndexedConnectMismatches
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Internal.ConnectMismatches", &args, &out))
+	require.Len(t, out.Mismatches, 2)
+	require.Equal(t, "web1", out.Mismatches[0].ServiceID)
+	require.Equal(t, structs.ConnectMismatchSidecarCritical, out.Mismatches[0].Reason)
+	require.Equal(t, "web2", out.Mismatches[1].ServiceID)
+	require.Equal(t, structs.ConnectMismatchSidecarMissing, out.Mismatches[1].Reason)
+}
@@ -28,6 +28,25 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 		return false, fmt.Errorf("Must provide key")
 	}
 
+	// Verify the value against the checksum given by the client, so a value
+	// corrupted on the way is rejected before it's committed. The checksum is
+	// only stored by the ops that write a value.
+	switch op {
+	case api.KVSet, api.KVCAS, api.KVLock, api.KVUnlock:
+		if dirEnt.Checksum != "" {
+			checksum, err := structs.ParseKVChecksum(dirEnt.Checksum)
+			if err != nil {
+				return false, err
+			}
+			dirEnt.Checksum = checksum
+			if err := dirEnt.VerifyChecksum(); err != nil {
+				return false, err
+			}
+		}
+	default:
+		dirEnt.Checksum = ""
+	}
+
 	// Apply the ACL policy if any.
 	if rule != nil {
 		switch op {
@@ -36,9 +55,17 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 				return false, acl.ErrPermissionDenied
 			}
 
-		case api.KVGet, api.KVGetTree:
+		case api.KVGet:
 			// Filtering for GETs is done on the output side.
 
+		case api.KVGetTree:
+			// Listing the keys under a prefix reveals their names, so it
+			// needs the list policy when that's enforced. The values are
+			// still filtered on the output side.
+			if srv.config.ACLEnableKeyListPolicy && !rule.KeyList(dirEnt.Key) {
+				return false, acl.ErrPermissionDenied
+			}
+
 		case api.KVCheckSession, api.KVCheckIndex:
 			// These could reveal information based on the outcome
 			// of the transaction, and they operate on individual
@@ -76,6 +103,36 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 	return true, nil
 }
 
+// kvsRetention returns the retention to set on a KV operation if it deletes
+// entries and the deleted entries are retained, or nil otherwise. It must be
+// called on the leader, which sets the time of the deletion.
+func (s *Server) kvsRetention(op api.KVOp, token string) *structs.KVSRetention {
+	if s.kvDeletedGC == nil {
+		return nil
+	}
+	switch op {
+	case api.KVDelete, api.KVDeleteCAS, api.KVDeleteTree:
+	default:
+		return nil
+	}
+
+	retention := &structs.KVSRetention{DeletedAt: time.Now()}
+	if s.ACLsEnabled() {
+		if token == "" {
+			retention.DeletedBy = structs.ACLTokenAnonymousID
+		} else if identity, err := s.acls.resolveIdentityFromToken(token); err == nil && identity != nil {
+			retention.DeletedBy = identity.ID()
+		}
+	}
+	return retention
+}
+
+// hintKVSDeleted starts the retention window of the entries deleted by the
+// operations that were just applied.
+func (s *Server) hintKVSDeleted() {
+	s.kvDeletedGC.Hint(s.raft.LastIndex())
+}
+
 // Apply is used to apply a KVS update request to the data store.
 func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 	if done, err := k.srv.forward("KVS.Apply", args, args, reply); done {
@@ -96,6 +153,7 @@ func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 		*reply = false
 		return nil
 	}
+	args.Retention = k.srv.kvsRetention(args.Op, args.Token)
 
 	// Apply the update.
 	resp, err := k.srv.raftApply(structs.KVSRequestType, args)
@@ -103,6 +161,9 @@ func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 		k.srv.logger.Printf("[ERR] consul.kvs: Apply failed: %v", err)
 		return err
 	}
+	if args.Retention != nil {
+		k.srv.hintKVSDeleted()
+	}
 	if respErr, ok := resp.(error); ok {
 		return respErr
 	}
@@ -197,6 +258,91 @@ func (k *KVS) List(args *structs.KeyRequest, reply *structs.IndexedDirEntries) e
 		})
 }
 
+// GetDeleted is used to lookup the retained deletion of a single key.
+func (k *KVS) GetDeleted(args *structs.KeyRequest, reply *structs.IndexedDeletedDirEntries) error {
+	if done, err := k.srv.forward("KVS.GetDeleted", args, args, reply); done {
+		return err
+	}
+
+	aclRule, err := k.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if aclRule != nil && !aclRule.KeyRead(args.Key) {
+		return acl.ErrPermissionDenied
+	}
+
+	return k.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, ent, err := state.KVSDeletedGet(ws, args.Key)
+			if err != nil {
+				return err
+			}
+
+			if ent == nil {
+				// Must provide non-zero index to prevent blocking
+				// Index 1 is impossible anyways (due to Raft internals)
+				if index == 0 {
+					reply.Index = 1
+				} else {
+					reply.Index = index
+				}
+				reply.Entries = nil
+			} else {
+				reply.Index = ent.ModifyIndex
+				reply.Entries = structs.DeletedDirEntries{ent}
+			}
+			return nil
+		})
+}
+
+// ListDeleted is used to list the retained deletions of all keys with a
+// given prefix.
+func (k *KVS) ListDeleted(args *structs.KeyRequest, reply *structs.IndexedDeletedDirEntries) error {
+	if done, err := k.srv.forward("KVS.ListDeleted", args, args, reply); done {
+		return err
+	}
+
+	aclToken, err := k.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+
+	if aclToken != nil && k.srv.config.ACLEnableKeyListPolicy && !aclToken.KeyList(args.Key) {
+		return acl.ErrPermissionDenied
+	}
+
+	return k.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, ent, err := state.KVSDeletedList(ws, args.Key)
+			if err != nil {
+				return err
+			}
+			if aclToken != nil {
+				ent = FilterDeletedDirEnt(aclToken, ent)
+			}
+
+			if len(ent) == 0 {
+				// Must provide non-zero index to prevent blocking
+				// Index 1 is impossible anyways (due to Raft internals)
+				if index == 0 {
+					reply.Index = 1
+				} else {
+					reply.Index = index
+				}
+				reply.Entries = nil
+			} else {
+				reply.Index = index
+				reply.Entries = ent
+			}
+			return nil
+		})
+}
+
 // ListKeys is used to list all keys with a given prefix to a separator.
 func (k *KVS) ListKeys(args *structs.KeyListRequest, reply *structs.IndexedKeyList) error {
 	if done, err := k.srv.forward("KVS.ListKeys", args, args, reply); done {
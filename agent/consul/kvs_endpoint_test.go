@@ -1,7 +1,9 @@
 package consul
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 	"github.com/pascaldekloe/goe/verify"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKVS_Apply(t *testing.T) {
@@ -70,6 +73,80 @@ func TestKVS_Apply(t *testing.T) {
 	}
 }
 
+func TestKVS_Apply_Checksum(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// A value that matches its checksum is stored along with it, with the
+	// digest normalized.
+	sum := structs.KVChecksum([]byte("test"))
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:      "test",
+			Value:    []byte("test"),
+			Checksum: "sha256:" + strings.ToUpper(sum[7:]),
+		},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+
+	state := s1.fsm.State()
+	_, d, err := state.KVSGet(nil, "test")
+	require.NoError(t, err)
+	require.Equal(t, sum, d.Checksum)
+
+	// A value that doesn't match is rejected before it's committed.
+	arg.DirEnt.Value = []byte("corrupted")
+	err = msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out)
+	require.True(t, structs.IsErrKVChecksumMismatch(err), "err: %v", err)
+
+	idx, d, err := state.KVSGet(nil, "test")
+	require.NoError(t, err)
+	require.Equal(t, []byte("test"), d.Value)
+
+	// So is a checksum that isn't well formed.
+	arg.DirEnt.Checksum = "md5:098f6bcd4621d373cade4e832627b4f6"
+	err = msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out)
+	require.Error(t, err)
+
+	// The same goes for the KV ops of a transaction.
+	txn := structs.TxnRequest{
+		Datacenter: "dc1",
+		Ops: structs.TxnOps{
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb: api.KVCAS,
+					DirEnt: structs.DirEntry{
+						Key:       "test",
+						Value:     []byte("corrupted"),
+						Checksum:  sum,
+						RaftIndex: structs.RaftIndex{ModifyIndex: idx},
+					},
+				},
+			},
+		},
+	}
+	var txnOut structs.TxnResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Txn.Apply", &txn, &txnOut))
+	require.Len(t, txnOut.Errors, 1)
+	require.True(t, structs.IsErrKVChecksumMismatch(errors.New(txnOut.Errors[0].What)))
+
+	// Writing the key without a checksum clears the stored one.
+	arg.DirEnt.Checksum = ""
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+	_, d, err = state.KVSGet(nil, "test")
+	require.NoError(t, err)
+	require.Empty(t, d.Checksum)
+}
+
 func TestKVS_Apply_ACLDeny(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServerWithConfig(t, func(c *Config) {
@@ -606,6 +683,96 @@ key "zip" {
 
 }
 
+func TestKVSEndpoint_ListDeleted(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.KVDeletedRetention = time.Hour
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	keys := []string{
+		"/test/key1",
+		"/test/key2",
+		"/test/sub/key3",
+	}
+
+	for _, key := range keys {
+		arg := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key:   key,
+				Flags: 1,
+				Value: []byte(key),
+			},
+		}
+		var out bool
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+	}
+
+	// Delete a single key, then the rest of the tree in a transaction.
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVDelete,
+		DirEnt: structs.DirEntry{
+			Key: "/test/key1",
+		},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+
+	txn := structs.TxnRequest{
+		Datacenter: "dc1",
+		Ops: structs.TxnOps{
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb: api.KVDeleteTree,
+					DirEnt: structs.DirEntry{
+						Key: "/test/",
+					},
+				},
+			},
+		},
+	}
+	var txnOut structs.TxnResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Txn.Apply", &txn, &txnOut))
+	require.Empty(t, txnOut.Errors)
+
+	getR := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        "/test/",
+	}
+	var dirent structs.IndexedDeletedDirEntries
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.ListDeleted", &getR, &dirent))
+	require.NotZero(t, dirent.Index)
+	require.Len(t, dirent.Entries, 3)
+	for i, key := range keys {
+		require.Equal(t, key, dirent.Entries[i].Key)
+		require.Equal(t, []byte(key), dirent.Entries[i].Value)
+		require.Equal(t, uint64(1), dirent.Entries[i].Flags)
+		require.False(t, dirent.Entries[i].DeletedAt.IsZero())
+	}
+
+	getR.Key = "/test/key1"
+	var single structs.IndexedDeletedDirEntries
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.GetDeleted", &getR, &single))
+	require.Len(t, single.Entries, 1)
+	require.Equal(t, "/test/key1", single.Entries[0].Key)
+	require.Equal(t, single.Entries[0].ModifyIndex, single.Index)
+
+	// Try a key that was never deleted.
+	getR.Key = "/nope"
+	var missing structs.IndexedDeletedDirEntries
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.GetDeleted", &getR, &missing))
+	require.NotZero(t, missing.Index)
+	require.Empty(t, missing.Entries)
+}
+
 func TestKVSEndpoint_ListKeys(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -218,6 +218,8 @@ WAIT:
 			s.reconcileMember(member)
 		case index := <-s.tombstoneGC.ExpireCh():
 			go s.reapTombstones(index)
+		case index := <-s.kvDeletedExpireCh():
+			go s.reapKVSDeleted(index)
 		case errCh := <-s.reassertLeaderCh:
 			errCh <- reassert()
 		}
@@ -247,6 +249,10 @@ func (s *Server) establishLeadership() error {
 	s.tombstoneGC.SetEnabled(true)
 	lastIndex := s.raft.LastIndex()
 	s.tombstoneGC.Hint(lastIndex)
+	if s.kvDeletedGC != nil {
+		s.kvDeletedGC.SetEnabled(true)
+		s.kvDeletedGC.Hint(lastIndex)
+	}
 
 	// Setup the session timers. This is done both when starting up or when
 	// a leader fail over happens. Since the timers are maintained by the leader
@@ -273,6 +279,10 @@ func (s *Server) establishLeadership() error {
 
 	s.startCARootPruning()
 
+	s.startACLDanglingTokenScan()
+
+	s.startACLPolicyLinkReap()
+
 	s.setConsistentReadReady()
 	return nil
 }
@@ -282,6 +292,9 @@ func (s *Server) establishLeadership() error {
 func (s *Server) revokeLeadership() error {
 	// Disable the tombstone GC, since it is only useful as a leader
 	s.tombstoneGC.SetEnabled(false)
+	if s.kvDeletedGC != nil {
+		s.kvDeletedGC.SetEnabled(false)
+	}
 
 	// Clear the session timers on either shutdown or step down, since we
 	// are no longer responsible for session expirations.
@@ -297,6 +310,10 @@ func (s *Server) revokeLeadership() error {
 
 	s.stopACLUpgrade()
 
+	s.stopACLDanglingTokenScan()
+
+	s.stopACLPolicyLinkReap()
+
 	s.resetConsistentReadReady()
 	s.autopilot.Stop()
 	return nil
@@ -1595,3 +1612,29 @@ func (s *Server) reapTombstones(index uint64) {
 			index, err)
 	}
 }
+
+// kvDeletedExpireCh returns the channel the expired indexes of the retained
+// deleted KV entries are sent on, or nil if they aren't retained.
+func (s *Server) kvDeletedExpireCh() <-chan uint64 {
+	if s.kvDeletedGC == nil {
+		return nil
+	}
+	return s.kvDeletedGC.ExpireCh()
+}
+
+// reapKVSDeleted is invoked by the current leader to delete the retained
+// deleted KV entries once they're older than the retention window. It works
+// like reapTombstones.
+func (s *Server) reapKVSDeleted(index uint64) {
+	defer metrics.MeasureSince([]string{"leader", "reapKVSDeleted"}, time.Now())
+	req := structs.TombstoneRequest{
+		Datacenter: s.config.Datacenter,
+		Op:         structs.TombstoneReapKVSDeleted,
+		ReapIndex:  index,
+	}
+	_, err := s.raftApply(structs.TombstoneRequestType, &req)
+	if err != nil {
+		s.logger.Printf("[ERR] consul: failed to reap deleted KV entries up to %d: %v",
+			index, err)
+	}
+}
@@ -0,0 +1,52 @@
+package consul
+
+import (
+	"github.com/hashicorp/raft"
+)
+
+// setupLeaderChangeWatch registers a Raft observer for leader changes and
+// starts the goroutine that turns them into notifications for blocking
+// status queries.
+func (s *Server) setupLeaderChangeWatch() {
+	s.leaderChangeLock.Lock()
+	s.leaderChangeCh = make(chan struct{})
+	s.leaderChangeLock.Unlock()
+
+	// The observer doesn't block Raft, so observations may be dropped
+	// while one is pending. That's fine since we only need a wake up.
+	obsCh := make(chan raft.Observation, 1)
+	observer := raft.NewObserver(obsCh, false, func(o *raft.Observation) bool {
+		_, ok := o.Data.(raft.LeaderObservation)
+		return ok
+	})
+	s.raft.RegisterObserver(observer)
+
+	go func() {
+		defer s.raft.DeregisterObserver(observer)
+		for {
+			select {
+			case <-obsCh:
+				s.notifyLeaderChange()
+			case <-s.shutdownCh:
+				return
+			}
+		}
+	}()
+}
+
+// leaderChangeWatch returns a channel that is closed the next time the Raft
+// leader changes. It must be fetched before reading the Raft state it's
+// used to watch.
+func (s *Server) leaderChangeWatch() <-chan struct{} {
+	s.leaderChangeLock.Lock()
+	defer s.leaderChangeLock.Unlock()
+	return s.leaderChangeCh
+}
+
+// notifyLeaderChange wakes up everyone waiting for a leader change.
+func (s *Server) notifyLeaderChange() {
+	s.leaderChangeLock.Lock()
+	defer s.leaderChangeLock.Unlock()
+	close(s.leaderChangeCh)
+	s.leaderChangeCh = make(chan struct{})
+}
@@ -148,6 +148,70 @@ func TestLeader_FailedMember(t *testing.T) {
 	})
 }
 
+func TestLeader_FailedMember_SyncOwner(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	dir2, c1 := testClient(t)
+	defer os.RemoveAll(dir2)
+	defer c1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	joinLAN(t, c1, s1)
+
+	state := s1.fsm.State()
+	var node *structs.Node
+	retry.Run(t, func(r *retry.R) {
+		var err error
+		_, node, err = state.GetNode(c1.config.NodeName)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if node == nil {
+			r.Fatal("client not registered")
+		}
+	})
+
+	// Hand the node over to an external sync tool.
+	arg := structs.RegisterRequest{
+		Datacenter:     "dc1",
+		ID:             node.ID,
+		Node:           node.Node,
+		Address:        node.Address,
+		SyncOwner:      "sync-a",
+		CheckSyncOwner: true,
+	}
+	var out struct{}
+	if err := s1.RPC("Catalog.Register", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Fail the member, the leader must still be able to mark it critical.
+	c1.Shutdown()
+	retry.Run(t, func(r *retry.R) {
+		_, checks, err := state.NodeChecks(nil, c1.config.NodeName)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(checks) != 1 || checks[0].CheckID != structs.SerfCheckID {
+			r.Fatalf("bad: %v", checks)
+		}
+		if got, want := checks[0].Status, api.HealthCritical; got != want {
+			r.Fatalf("got status %q want %q", got, want)
+		}
+	})
+
+	_, node, err := state.GetNode(c1.config.NodeName)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if node.SyncOwner != "sync-a" {
+		t.Fatalf("bad: %v", node)
+	}
+}
+
 func TestLeader_LeftMember(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServerWithConfig(t, func(c *Config) {
@@ -786,6 +850,49 @@ func TestLeader_ReapTombstones(t *testing.T) {
 	})
 }
 
+func TestLeader_ReapKVSDeleted(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.KVDeletedRetention = 50 * time.Millisecond
+		c.TombstoneTTLGranularity = 10 * time.Millisecond
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Create a KV entry and delete it.
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "test",
+			Value: []byte("test"),
+		},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+	arg.Op = api.KVDelete
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+
+	// The deleted entry is retained at first, then reaped once it's older
+	// than the retention window.
+	state := s1.fsm.State()
+	_, entry, err := state.KVSDeletedGet(nil, "test")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	retry.Run(t, func(r *retry.R) {
+		_, entry, err := state.KVSDeletedGet(nil, "test")
+		if err != nil {
+			r.Fatal(err)
+		}
+		if entry != nil {
+			r.Fatal("should have no deleted entry")
+		}
+	})
+}
+
 func TestLeader_RollRaftServer(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServerWithConfig(t, func(c *Config) {
@@ -0,0 +1,50 @@
+package consul
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// UsageTopClients is used to retrieve the tokens that made the most requests
+// to a server over its last completed usage interval. The report only covers
+// the requests handled by that server, the leader unless another server is
+// named in the request.
+func (op *Operator) UsageTopClients(args *structs.UsageTopClientsRequest, reply *structs.UsageTopClientsResponse) error {
+	if done, err := op.srv.forward("Operator.UsageTopClients", args, args, reply); done {
+		return err
+	}
+
+	// This action requires operator read access.
+	rule, err := op.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.OperatorRead() {
+		return acl.ErrPermissionDenied
+	}
+
+	// Send the request on to the named server.
+	if args.Server != "" && args.Server != op.srv.config.NodeName {
+		for _, server := range op.srv.serverLookup.Servers() {
+			if server.Name == args.Server {
+				return op.srv.connPool.RPC(op.srv.config.Datacenter, server.Addr, server.Version,
+					"Operator.UsageTopClients", server.UseTLS, args, reply)
+			}
+		}
+		return fmt.Errorf("unknown server %q", args.Server)
+	}
+
+	if op.srv.usage == nil || !op.srv.ACLsEnabled() {
+		return fmt.Errorf("usage tracking is disabled")
+	}
+
+	if report := op.srv.usage.report(); report != nil {
+		*reply = *report
+	} else {
+		reply.Server = op.srv.config.NodeName
+		reply.SampleRate = op.srv.usage.rate
+	}
+	return nil
+}
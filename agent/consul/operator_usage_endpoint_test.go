@@ -0,0 +1,150 @@
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperator_UsageTopClients(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLUsageSampleRate = 1
+		c.ACLUsageInterval = time.Hour
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	token, err := upsertTestToken(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	// Make some requests with the token, one of them blocking.
+	for i := 0; i < 20; i++ {
+		args := structs.DCSpecificRequest{
+			Datacenter:   "dc1",
+			QueryOptions: structs.QueryOptions{Token: token.SecretID},
+		}
+		var out structs.IndexedNodes
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ListNodes", &args, &out))
+	}
+	{
+		args := structs.DCSpecificRequest{
+			Datacenter: "dc1",
+			QueryOptions: structs.QueryOptions{
+				Token:         token.SecretID,
+				MinQueryIndex: 1 << 40,
+				MaxQueryTime:  10 * time.Millisecond,
+			},
+		}
+		var out structs.IndexedNodes
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ListNodes", &args, &out))
+	}
+	s1.usage.rotate(s1.config.NodeName, s1.resolveUsage)
+
+	// The report requires operator read access.
+	arg := structs.DCSpecificRequest{
+		Datacenter:   "dc1",
+		QueryOptions: structs.QueryOptions{Token: token.SecretID},
+	}
+	var reply structs.UsageTopClientsResponse
+	err = msgpackrpc.CallWithCodec(codec, "Operator.UsageTopClients", &arg, &reply)
+	require.True(t, acl.IsErrPermissionDenied(err), "err: %v", err)
+
+	arg.Token = "root"
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UsageTopClients", &arg, &reply))
+	require.Equal(t, s1.config.NodeName, reply.Server)
+	require.Equal(t, 1.0, reply.SampleRate)
+
+	require.NotEmpty(t, reply.RPC)
+	require.Equal(t, token.AccessorID, reply.RPC[0].AccessorID)
+	require.Equal(t, "User token", reply.RPC[0].Description)
+	require.Equal(t, uint64(21), reply.RPC[0].Count)
+
+	require.Len(t, reply.BlockingQueries, 1)
+	require.Equal(t, token.AccessorID, reply.BlockingQueries[0].AccessorID)
+	require.Equal(t, uint64(1), reply.BlockingQueries[0].Count)
+	require.Equal(t, 1.0, reply.BlockingQueries[0].Share)
+}
+
+func TestOperator_UsageTopClients_Disabled(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.DCSpecificRequest{
+		Datacenter: "dc1",
+	}
+	var reply structs.UsageTopClientsResponse
+	err := msgpackrpc.CallWithCodec(codec, "Operator.UsageTopClients", &arg, &reply)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "usage tracking is disabled")
+}
+
+func TestOperator_UsageTopClients_Server(t *testing.T) {
+	t.Parallel()
+	conf := func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLUsageSampleRate = 1
+		c.ACLUsageInterval = time.Hour
+	}
+	dir1, s1 := testServerWithConfig(t, conf)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		conf(c)
+		c.Bootstrap = false
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+
+	joinLAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	retry.Run(t, func(r *retry.R) {
+		r.Check(wantRaft([]*Server{s1, s2}))
+	})
+
+	// Each server reports on the requests it handled itself.
+	arg := structs.UsageTopClientsRequest{
+		Datacenter:   "dc1",
+		QueryOptions: structs.QueryOptions{Token: "root"},
+	}
+	var reply structs.UsageTopClientsResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UsageTopClients", &arg, &reply))
+	require.Equal(t, s1.config.NodeName, reply.Server)
+
+	arg.Server = s2.config.NodeName
+	reply = structs.UsageTopClientsResponse{}
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UsageTopClients", &arg, &reply))
+	require.Equal(t, s2.config.NodeName, reply.Server)
+
+	arg.Server = "nope"
+	err := msgpackrpc.CallWithCodec(codec, "Operator.UsageTopClients", &arg, &reply)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown server "nope"`)
+}
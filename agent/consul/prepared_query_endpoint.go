@@ -529,6 +529,9 @@ func (p *PreparedQuery) execute(query *structs.PreparedQuery,
 	nodes = nodes.FilterIgnore(query.Service.OnlyPassing,
 		query.Service.IgnoreCheckIDs)
 
+	// Filter out the drained nodes.
+	nodes = drainedFilter(time.Now(), nodes)
+
 	// Apply the node metadata filters, if any.
 	if len(query.Service.NodeMeta) > 0 {
 		nodes = nodeMetaFilter(query.Service.NodeMeta, nodes)
@@ -621,6 +624,17 @@ func nodeMetaFilter(filters map[string]string, nodes structs.CheckServiceNodes)
 	return filtered
 }
 
+// drainedFilter returns the nodes that aren't drained at the given time.
+func drainedFilter(now time.Time, nodes structs.CheckServiceNodes) structs.CheckServiceNodes {
+	var filtered structs.CheckServiceNodes
+	for _, node := range nodes {
+		if !structs.NodeDrained(node.Node.Meta, now) {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
 func serviceMetaFilter(filters map[string]string, nodes structs.CheckServiceNodes) structs.CheckServiceNodes {
 	var filtered structs.CheckServiceNodes
 	for _, node := range nodes {
@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/consul/agent/pool"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/logger"
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/memberlist"
 	msgpackrpc "github.com/hashicorp/net-rpc-msgpackrpc"
@@ -195,6 +196,17 @@ func canRetry(args interface{}, err error) bool {
 // forward is used to forward to a remote DC or to forward to the local leader
 // Returns a bool of if forwarding was performed, as well as any error
 func (s *Server) forward(method string, info structs.RPCInfo, args interface{}, reply interface{}) (bool, error) {
+	done, err := s.forwardRequest(method, info, args, reply)
+	if !done {
+		// Only the requests handled by this server are attributed, so a
+		// forwarded request is counted once, by the server handling it.
+		s.recordUsage(info.TokenSecret(), false)
+	}
+	return done, err
+}
+
+// forwardRequest does the forwarding for forward.
+func (s *Server) forwardRequest(method string, info structs.RPCInfo, args interface{}, reply interface{}) (bool, error) {
 	var firstCheck time.Time
 
 	// Handle DC forwarding
@@ -289,7 +301,8 @@ func (s *Server) forwardDC(method, dc string, args interface{}, reply interface{
 		[]metrics.Label{{Name: "datacenter", Value: dc}})
 	if err := s.connPool.RPC(dc, server.Addr, server.Version, method, server.UseTLS, args, reply); err != nil {
 		manager.NotifyFailedServer(server)
-		s.logger.Printf("[ERR] consul: RPC failed to server %s in DC %q: %v", server.Addr, dc, err)
+		s.limitedLogger.RateLimited("consul.rpc.forward_dc_failed", logger.DefaultRateLimitInterval).Printf(
+			"[ERR] consul: RPC failed to server %s in DC %q: %v", server.Addr, dc, err)
 		return err
 	}
 
@@ -370,6 +383,8 @@ func (s *Server) blockingQuery(queryOpts *structs.QueryOptions, queryMeta *struc
 		goto RUN_QUERY
 	}
 
+	s.recordUsage(queryOpts.Token, true)
+
 	// Restrict the max query time, and ensure there is always one.
 	if queryOpts.MaxQueryTime > maxQueryTime {
 		queryOpts.MaxQueryTime = maxQueryTime
@@ -28,6 +28,7 @@ import (
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/agent/token"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/logger"
 	"github.com/hashicorp/consul/sentinel"
 	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/consul/types"
@@ -103,6 +104,18 @@ type Server struct {
 	aclUpgradeLock    sync.RWMutex
 	aclUpgradeEnabled bool
 
+	// aclDanglingCancel is used to stop the dangling token scan goroutine
+	// when we lose leadership
+	aclDanglingCancel  context.CancelFunc
+	aclDanglingLock    sync.RWMutex
+	aclDanglingEnabled bool
+
+	// aclPolicyLinkReapCancel is used to stop the expired policy link
+	// removal goroutine when we lose leadership
+	aclPolicyLinkReapCancel  context.CancelFunc
+	aclPolicyLinkReapLock    sync.RWMutex
+	aclPolicyLinkReapEnabled bool
+
 	// aclReplicationCancel is used to shut down the ACL replication goroutine
 	// when we lose leadership
 	aclReplicationCancel  context.CancelFunc
@@ -161,6 +174,10 @@ type Server struct {
 	// Logger uses the provided LogOutput
 	logger *log.Logger
 
+	// limitedLogger writes to logger, for the messages of the hot paths
+	// that need to be rate limited.
+	limitedLogger *logger.RateLimitedLogger
+
 	// The raft instance is used among Consul nodes within the DC to protect
 	// operations that require strong consistency.
 	// the state directly.
@@ -228,6 +245,13 @@ type Server struct {
 	// Consul router.
 	statsFetcher *StatsFetcher
 
+	// clockSkew tracks the estimated skew between the local clock and the
+	// other servers', keyed by server ID. It is updated by autopilot, at
+	// most once per ClockSkewQueryInterval, which clockSkewQueried tracks.
+	clockSkew        map[string]*clockSkew
+	clockSkewQueried time.Time
+	clockSkewLock    sync.Mutex
+
 	// reassertLeaderCh is used to signal the leader loop should re-run
 	// leadership actions after a snapshot restore.
 	reassertLeaderCh chan chan error
@@ -236,11 +260,24 @@ type Server struct {
 	// for the KV tombstones
 	tombstoneGC *state.TombstoneGC
 
+	// kvDeletedGC is used to track the pending GC invocations for the
+	// retained deleted KV entries. It's nil if they aren't retained.
+	kvDeletedGC *state.TombstoneGC
+
+	// usage attributes a sample of the requests to the accessor ID of their
+	// token. It's nil if usage tracking is disabled.
+	usage *usageTracker
+
 	// aclReplicationStatus (and its associated lock) provide information
 	// about the health of the ACL replication goroutine.
 	aclReplicationStatus     structs.ACLReplicationStatus
 	aclReplicationStatusLock sync.RWMutex
 
+	// leaderChangeCh is closed and replaced every time Raft reports a
+	// change of leader, which wakes up blocking status queries.
+	leaderChangeCh   chan struct{}
+	leaderChangeLock sync.Mutex
+
 	// shutdown and the associated members here are used in orchestrating
 	// a clean shutdown. The shutdownCh is never written to, only closed to
 	// indicate a shutdown has been initiated.
@@ -308,6 +345,16 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store, tl
 		return nil, err
 	}
 
+	// Deleted KV entries are reaped by the same kind of GC, once they're
+	// older than the retention window.
+	var kvDeletedGC *state.TombstoneGC
+	if config.KVDeletedRetention > 0 {
+		kvDeletedGC, err = state.NewTombstoneGC(config.KVDeletedRetention, config.TombstoneTTLGranularity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create the shutdown channel - this is closed but never written to.
 	shutdownCh := make(chan struct{})
 
@@ -328,6 +375,7 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store, tl
 		eventChLAN:       make(chan serf.Event, serfEventChSize),
 		eventChWAN:       make(chan serf.Event, serfEventChSize),
 		logger:           logger,
+		limitedLogger:    newLimitedLogger(logger),
 		leaveCh:          make(chan struct{}),
 		reconcileCh:      make(chan serf.Member, reconcileChSize),
 		router:           router.NewRouter(logger, config.Datacenter),
@@ -337,8 +385,13 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store, tl
 		segmentLAN:       make(map[string]*serf.Serf, len(config.Segments)),
 		sessionTimers:    NewSessionTimers(),
 		tombstoneGC:      gc,
+		kvDeletedGC:      kvDeletedGC,
 		serverLookup:     NewServerLookup(),
 		shutdownCh:       shutdownCh,
+		clockSkew:        make(map[string]*clockSkew),
+	}
+	if config.ACLUsageSampleRate > 0 {
+		s.usage = newUsageTracker(config.ACLUsageSampleRate, config.ACLUsageTopClients)
 	}
 
 	// Initialize enterprise specific server functionality
@@ -463,6 +516,9 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store, tl
 	// since it can fire events when leadership is obtained.
 	go s.monitorLeadership()
 
+	// Start watching for leader changes for blocking status queries.
+	s.setupLeaderChangeWatch()
+
 	// Start listening for RPC requests.
 	go s.listen(s.Listener)
 
@@ -473,6 +529,10 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store, tl
 
 	// Start the metrics handlers.
 	go s.sessionStats()
+	go s.gossipStats()
+	if s.usage != nil {
+		go s.usageStats()
+	}
 
 	return s, nil
 }
@@ -637,6 +697,17 @@ func (s *Server) setupRaft() error {
 		}
 	}
 
+	// The entries already in the log are replayed into the FSM when Raft
+	// starts, so their health transitions aren't recorded as if they had
+	// just happened.
+	replayIndex, err := log.LastIndex()
+	if err != nil {
+		return err
+	}
+	history := state.NewHealthHistory(s.config.HealthHistoryRetention)
+	history.SetReplayIndex(replayIndex)
+	s.fsm.SetHealthHistory(history)
+
 	// Set up a channel for reliable leader notifications.
 	raftNotifyCh := make(chan bool, 1)
 	s.config.RaftConfig.NotifyCh = raftNotifyCh
@@ -12,6 +12,7 @@ func init() {
 	registerEndpoint(func(s *Server) interface{} { return &Operator{s} })
 	registerEndpoint(func(s *Server) interface{} { return &PreparedQuery{s} })
 	registerEndpoint(func(s *Server) interface{} { return &Session{s} })
+	registerEndpoint(func(s *Server) interface{} { return &ServiceRouting{s} })
 	registerEndpoint(func(s *Server) interface{} { return &Status{s} })
 	registerEndpoint(func(s *Server) interface{} { return &Txn{s} })
 }
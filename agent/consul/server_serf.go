@@ -56,6 +56,7 @@ func (s *Server) setupSerf(conf *serf.Config, ch chan serf.Event, path string, w
 	conf.Tags["vsn_max"] = fmt.Sprintf("%d", ProtocolVersionMax)
 	conf.Tags["raft_vsn"] = fmt.Sprintf("%d", s.config.RaftConfig.ProtocolVersion)
 	conf.Tags["build"] = s.config.Build
+	conf.Tags[deregisterBatchTag] = "1"
 	addr := listener.Addr().(*net.TCPAddr)
 	conf.Tags["port"] = fmt.Sprintf("%d", addr.Port)
 	if s.config.Bootstrap {
@@ -145,7 +146,10 @@ func (s *Server) lanEventHandler() {
 				s.localEvent(e.(serf.UserEvent))
 			case serf.EventMemberUpdate:
 				s.localMemberEvent(e.(serf.MemberEvent))
-			case serf.EventQuery: // Ignore
+			case serf.EventQuery:
+				if q := e.(*serf.Query); q.Name == clockSkewQuery {
+					s.handleClockSkewQuery(q)
+				}
 			default:
 				s.logger.Printf("[WARN] consul: Unhandled LAN Serf Event: %#v", e)
 			}
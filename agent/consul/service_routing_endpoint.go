@@ -0,0 +1,109 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/state"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// ServiceRouting manages the routing overrides of services, which are used
+// to resolve a share of the queries for a service to its canary instances.
+type ServiceRouting struct {
+	// srv is a pointer back to the server.
+	srv *Server
+}
+
+// Apply sets or deletes the routing override of a service.
+func (s *ServiceRouting) Apply(args *structs.ServiceRoutingRequest, reply *struct{}) error {
+	if done, err := s.srv.forward("ServiceRouting.Apply", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"service_routing", "apply"}, time.Now())
+
+	// Always set a non-nil entry to avoid nil-access below
+	if args.Routing == nil {
+		args.Routing = &structs.ServiceRoutingConfigEntry{}
+	}
+	if err := args.Routing.Normalize(); err != nil {
+		return err
+	}
+
+	// Perform the ACL check
+	rule, err := s.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.ServiceWrite(args.Routing.Name, nil) {
+		s.srv.logger.Printf("[WARN] consul.service_routing: Operation on routing of service '%s' denied due to ACLs", args.Routing.Name)
+		return acl.ErrPermissionDenied
+	}
+
+	switch args.Op {
+	case structs.ConfigEntryUpsert:
+		if err := args.Routing.Validate(); err != nil {
+			return err
+		}
+	case structs.ConfigEntryDelete:
+		if args.Routing.Name == "" {
+			return fmt.Errorf("missing service name")
+		}
+	default:
+		return fmt.Errorf("invalid routing operation %q", args.Op)
+	}
+
+	// Commit
+	req := &structs.ConfigEntryRequest{
+		Op:    args.Op,
+		Entry: args.Routing,
+	}
+	resp, err := s.srv.raftApply(structs.ConfigEntryRequestType, req)
+	if err != nil {
+		s.srv.logger.Printf("[ERR] consul.service_routing: Apply failed %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	return nil
+}
+
+// Get returns the routing override of a service. The reply has no routing if
+// the service has no override.
+func (s *ServiceRouting) Get(args *structs.ServiceSpecificRequest, reply *structs.IndexedServiceRouting) error {
+	if done, err := s.srv.forward("ServiceRouting.Get", args, args, reply); done {
+		return err
+	}
+
+	// Verify the arguments
+	if args.ServiceName == "" {
+		return fmt.Errorf("Must provide service name")
+	}
+
+	// Perform the ACL check
+	rule, err := s.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.ServiceRead(args.ServiceName) {
+		return acl.ErrPermissionDenied
+	}
+
+	return s.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, routing, err := state.ServiceRouting(ws, args.ServiceName)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.Routing = index, routing
+			return nil
+		})
+}
@@ -0,0 +1,203 @@
+package consul
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRouting_Apply(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Nothing to start with
+	get := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "web",
+	}
+	{
+		var resp structs.IndexedServiceRouting
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Get", &get, &resp))
+		require.Nil(resp.Routing)
+	}
+
+	// Invalid percentage
+	args := structs.ServiceRoutingRequest{
+		Datacenter: "dc1",
+		Op:         structs.ConfigEntryUpsert,
+		Routing: &structs.ServiceRoutingConfigEntry{
+			Name:       "web",
+			ForceTag:   "canary",
+			Percentage: 101,
+		},
+	}
+	var out struct{}
+	err := msgpackrpc.CallWithCodec(codec, "ServiceRouting.Apply", &args, &out)
+	require.Error(err)
+	require.Contains(err.Error(), "invalid percentage")
+
+	// Set
+	args.Routing.Percentage = 10
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Apply", &args, &out))
+	{
+		var resp structs.IndexedServiceRouting
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Get", &get, &resp))
+		require.NotNil(resp.Routing)
+		require.Equal(structs.ServiceRouting, resp.Routing.Kind)
+		require.Equal("canary", resp.Routing.ForceTag)
+		require.Equal(10, resp.Routing.Percentage)
+		require.Equal(resp.Index, resp.Routing.ModifyIndex)
+	}
+
+	// Delete
+	args.Op = structs.ConfigEntryDelete
+	args.Routing = &structs.ServiceRoutingConfigEntry{Name: "web"}
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Apply", &args, &out))
+	{
+		var resp structs.IndexedServiceRouting
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Get", &get, &resp))
+		require.Nil(resp.Routing)
+	}
+}
+
+func TestServiceRouting_ACL(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Create a token that can only read the service.
+	arg := structs.ACLRequest{
+		Datacenter: "dc1",
+		Op:         structs.ACLSet,
+		ACL: structs.ACL{
+			Name: "User token",
+			Type: structs.ACLTokenTypeClient,
+			Rules: `
+service "web" {
+	policy = "read"
+}
+`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token string
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.Apply", arg, &token))
+
+	args := structs.ServiceRoutingRequest{
+		Datacenter: "dc1",
+		Op:         structs.ConfigEntryUpsert,
+		Routing: &structs.ServiceRoutingConfigEntry{
+			Name:       "web",
+			ForceTag:   "canary",
+			Percentage: 10,
+		},
+		WriteRequest: structs.WriteRequest{Token: token},
+	}
+	var out struct{}
+	err := msgpackrpc.CallWithCodec(codec, "ServiceRouting.Apply", &args, &out)
+	require.True(acl.IsErrPermissionDenied(err), "err: %v", err)
+
+	args.WriteRequest.Token = "root"
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Apply", &args, &out))
+
+	// Reading works with the token but not anonymously.
+	get := structs.ServiceSpecificRequest{
+		Datacenter:   "dc1",
+		ServiceName:  "web",
+		QueryOptions: structs.QueryOptions{Token: token},
+	}
+	var resp structs.IndexedServiceRouting
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Get", &get, &resp))
+	require.NotNil(resp.Routing)
+
+	get.QueryOptions.Token = ""
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRouting.Get", &get, &resp)
+	require.True(acl.IsErrPermissionDenied(err), "err: %v", err)
+}
+
+func TestHealth_ServiceNodes_Routing(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	var out struct{}
+	reg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			Service: "web",
+			Tags:    []string{"canary"},
+		},
+		Check: &structs.HealthCheck{
+			Name:      "web",
+			Status:    api.HealthPassing,
+			ServiceID: "web",
+		},
+	}
+	require.NoError(msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out))
+
+	args := structs.ServiceRoutingRequest{
+		Datacenter: "dc1",
+		Op:         structs.ConfigEntryUpsert,
+		Routing: &structs.ServiceRoutingConfigEntry{
+			Name:       "web",
+			ForceTag:   "canary",
+			Percentage: 10,
+		},
+	}
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ServiceRouting.Apply", &args, &out))
+
+	// The override comes along with the nodes.
+	req := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "web",
+	}
+	var resp structs.IndexedCheckServiceNodes
+	require.NoError(msgpackrpc.CallWithCodec(codec, "Health.ServiceNodes", &req, &resp))
+	require.Len(resp.Nodes, 1)
+	require.NotNil(resp.Routing)
+	require.Equal("canary", resp.Routing.ForceTag)
+	require.True(resp.Index >= resp.Routing.ModifyIndex)
+
+	// But not when asking for specific tags.
+	req.ServiceTags = []string{"canary"}
+	req.TagFilter = true
+	var tagged structs.IndexedCheckServiceNodes
+	require.NoError(msgpackrpc.CallWithCodec(codec, "Health.ServiceNodes", &req, &tagged))
+	require.Len(tagged.Nodes, 1)
+	require.Nil(tagged.Routing)
+}
@@ -255,3 +255,60 @@ func (s *Session) Renew(args *structs.SessionSpecificRequest,
 
 	return nil
 }
+
+// RenewBatch is used to renew the TTL on several sessions at once. Sessions
+// that aren't found are reported in the results, but the whole batch is
+// rejected if the token can't write one of the sessions.
+func (s *Session) RenewBatch(args *structs.SessionBatchRenewRequest,
+	reply *structs.SessionBatchRenewResponse) error {
+	if done, err := s.srv.forward("Session.RenewBatch", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"session", "renew_batch"}, time.Now())
+
+	if len(args.Sessions) > structs.SessionBatchRenewMax {
+		return fmt.Errorf("Too many sessions in batch: %d, the maximum is %d",
+			len(args.Sessions), structs.SessionBatchRenewMax)
+	}
+
+	// Get the sessions, from local state.
+	state := s.srv.fsm.State()
+	results := make([]structs.SessionRenewResult, 0, len(args.Sessions))
+	for _, id := range args.Sessions {
+		index, session, err := state.SessionGet(nil, id)
+		if err != nil {
+			return err
+		}
+		if index > reply.Index {
+			reply.Index = index
+		}
+		results = append(results, structs.SessionRenewResult{ID: id, Session: session})
+	}
+
+	// Fetch the ACL token, if any, and apply the policy.
+	rule, err := s.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if rule != nil && s.srv.config.ACLEnforceVersion8 {
+		for _, result := range results {
+			if result.Session != nil && !rule.SessionWrite(result.Session.Node) {
+				return acl.ErrPermissionDenied
+			}
+		}
+	}
+
+	// Reset the session TTL timers.
+	for _, result := range results {
+		if result.Session == nil {
+			continue
+		}
+		if err := s.srv.resetSessionTimer(result.ID, result.Session); err != nil {
+			s.srv.logger.Printf("[ERR] consul.session: Session renew failed: %v", err)
+			return err
+		}
+	}
+
+	reply.Results = results
+	return nil
+}
@@ -2,6 +2,7 @@ package consul
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -782,6 +783,153 @@ session "foo" {
 	}
 }
 
+func TestSession_RenewBatch(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForTestAgent(t, s1.RPC, "dc1")
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	s1.fsm.State().EnsureNode(1, &structs.Node{Node: "foo", Address: "127.0.0.1"})
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		arg := structs.SessionRequest{
+			Datacenter: "dc1",
+			Op:         structs.SessionCreate,
+			Session: structs.Session{
+				Node: "foo",
+				TTL:  "10s",
+			},
+		}
+		var out string
+		if err := msgpackrpc.CallWithCodec(codec, "Session.Apply", &arg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ids = append(ids, out)
+	}
+
+	missing := generateUUID()
+	renewR := structs.SessionBatchRenewRequest{
+		Datacenter: "dc1",
+		Sessions:   append(ids, missing),
+	}
+	var out structs.SessionBatchRenewResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Session.RenewBatch", &renewR, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Index == 0 {
+		t.Fatalf("bad: %v", out)
+	}
+	if len(out.Results) != 4 {
+		t.Fatalf("bad: %v", out.Results)
+	}
+	for i, id := range ids {
+		result := out.Results[i]
+		if result.ID != id || result.Session == nil || result.Session.ID != id {
+			t.Fatalf("bad: %v", result)
+		}
+		if result.Session.TTL != "10s" {
+			t.Fatalf("bad session TTL: %s %v", result.Session.TTL, result.Session)
+		}
+	}
+	if result := out.Results[3]; result.ID != missing || result.Session != nil {
+		t.Fatalf("bad: %v", result)
+	}
+
+	// The size of a batch is capped.
+	renewR.Sessions = make([]string, structs.SessionBatchRenewMax+1)
+	for i := range renewR.Sessions {
+		renewR.Sessions[i] = missing
+	}
+	err := msgpackrpc.CallWithCodec(codec, "Session.RenewBatch", &renewR, &out)
+	if err == nil || !strings.Contains(err.Error(), "Too many sessions") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestSession_RenewBatch_ACLDeny(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLEnforceVersion8 = false
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForTestAgent(t, s1.RPC, "dc1")
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	// Create the ACL.
+	req := structs.ACLRequest{
+		Datacenter: "dc1",
+		Op:         structs.ACLSet,
+		ACL: structs.ACL{
+			Name: "User token",
+			Type: structs.ACLTokenTypeClient,
+			Rules: `
+session "foo" {
+	policy = "write"
+}
+`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+
+	var token string
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Apply", &req, &token); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Add a session on a node the token can write, and one on a node it
+	// can't.
+	s1.fsm.State().EnsureNode(1, &structs.Node{Node: "foo", Address: "127.0.0.1"})
+	s1.fsm.State().EnsureNode(2, &structs.Node{Node: "bar", Address: "127.0.0.2"})
+	ids := []string{}
+	for _, node := range []string{"foo", "bar"} {
+		arg := structs.SessionRequest{
+			Datacenter: "dc1",
+			Op:         structs.SessionCreate,
+			Session: structs.Session{
+				Node: node,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var id string
+		if err := msgpackrpc.CallWithCodec(codec, "Session.Apply", &arg, &id); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Turn on version 8 enforcement, the whole batch is rejected if one of
+	// the sessions can't be renewed.
+	s1.config.ACLEnforceVersion8 = true
+	renewR := structs.SessionBatchRenewRequest{
+		Datacenter:   "dc1",
+		Sessions:     ids,
+		QueryOptions: structs.QueryOptions{Token: token},
+	}
+	var out structs.SessionBatchRenewResponse
+	err := msgpackrpc.CallWithCodec(codec, "Session.RenewBatch", &renewR, &out)
+	if !acl.IsErrPermissionDenied(err) {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Renewing only the allowed session should go through.
+	renewR.Sessions = ids[:1]
+	if err := msgpackrpc.CallWithCodec(codec, "Session.RenewBatch", &renewR, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Results) != 1 || out.Results[0].Session == nil {
+		t.Fatalf("bad: %v", out.Results)
+	}
+}
+
 func TestSession_NodeSessions(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/go-uuid"
 )
 
 type TokenPoliciesIndex struct {
@@ -306,7 +307,7 @@ func (s *Store) fixupTokenPolicyLinks(tx *memdb.Txn, original *structs.ACLToken)
 			}
 
 			// append the corrected policy
-			token.Policies = append(token.Policies, structs.ACLTokenPolicyLink{ID: link.ID, Name: policy.Name})
+			token.Policies = append(token.Policies, structs.ACLTokenPolicyLink{ID: link.ID, Name: policy.Name, ExpirationTime: link.ExpirationTime})
 		} else if owned {
 			token.Policies = append(token.Policies, link)
 		}
@@ -505,7 +506,9 @@ func (s *Store) aclTokenGetTxn(tx *memdb.Txn, ws memdb.WatchSet, value, index st
 	return nil, nil
 }
 
-// ACLTokenList is used to list out all of the ACLs in the state store.
+// ACLTokenList is used to list out all of the ACLs in the state store. The
+// tokens can be restricted to the ones linked to a policy, given by ID or
+// by name.
 func (s *Store) ACLTokenList(ws memdb.WatchSet, local, global bool, policy string) (uint64, structs.ACLTokens, error) {
 	tx := s.db.Txn(false)
 	defer tx.Abort()
@@ -513,6 +516,22 @@ func (s *Store) ACLTokenList(ws memdb.WatchSet, local, global bool, policy strin
 	var iter memdb.ResultIterator
 	var err error
 
+	// Tokens only link to policies by ID, so resolve a policy name first.
+	// An unknown policy has no tokens.
+	if policy != "" {
+		if _, err := uuid.ParseUUID(policy); err != nil {
+			watchCh, rawPolicy, err := tx.FirstWatch("acl-policies", "name", policy)
+			if err != nil {
+				return 0, nil, fmt.Errorf("failed acl policy lookup: %v", err)
+			}
+			ws.Add(watchCh)
+			if rawPolicy == nil {
+				return maxIndexTxn(tx, "acl-tokens", "acl-policies"), nil, nil
+			}
+			policy = rawPolicy.(*structs.ACLPolicy).ID
+		}
+	}
+
 	// Note global == local works when both are true or false. It is not valid to set both
 	// to false but for defaulted structs (zero values for both) we want it to list out
 	// all tokens so our checks just ensure that global == local
@@ -564,6 +583,62 @@ func (s *Store) ACLTokenList(ws memdb.WatchSet, local, global bool, policy strin
 	return idx, result, nil
 }
 
+// ACLTokenListDangling lists the tokens that are linked to policies which
+// have all been deleted and that have no rules of their own. These tokens
+// still authenticate, but they only get the default policy. The anonymous
+// token is never listed.
+func (s *Store) ACLTokenListDangling(ws memdb.WatchSet, local, global bool) (uint64, structs.ACLTokens, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	var iter memdb.ResultIterator
+	var err error
+	if global == local {
+		iter, err = tx.Get("acl-tokens", "id")
+	} else if global {
+		iter, err = tx.Get("acl-tokens", "local", false)
+	} else {
+		iter, err = tx.Get("acl-tokens", "local", true)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl token lookup: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	// Deleting a policy can leave tokens dangling, so watch the policies too.
+	policies, err := tx.Get("acl-policies", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl policy lookup: %v", err)
+	}
+	ws.Add(policies.WatchCh())
+
+	var result structs.ACLTokens
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		token := raw.(*structs.ACLToken)
+		if token.AccessorID == structs.ACLTokenAnonymousID || len(token.Policies) == 0 ||
+			len(token.InlinePolicies) > 0 || token.EmbeddedPolicy() != nil {
+			continue
+		}
+
+		dangling := true
+		for _, link := range token.Policies {
+			policy, err := s.getPolicyWithTxn(tx, nil, link.ID, "id")
+			if err != nil {
+				return 0, nil, err
+			}
+			if policy != nil {
+				dangling = false
+				break
+			}
+		}
+		if dangling {
+			result = append(result, token)
+		}
+	}
+
+	return maxIndexTxn(tx, "acl-tokens", "acl-policies"), result, nil
+}
+
 func (s *Store) ACLTokenListUpgradeable(max int) (structs.ACLTokens, <-chan struct{}, error) {
 	tx := s.db.Txn(false)
 	defer tx.Abort()
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
 	"github.com/stretchr/testify/require"
 )
 
@@ -771,6 +772,23 @@ func TestStateStore_ACLToken_List(t *testing.T) {
 				"47eea4da-bda1-48a6-901c-3e36d2d9262f",
 			},
 		},
+		{
+			name:   "Policy Name",
+			local:  true,
+			global: true,
+			policy: "node-read",
+			accessors: []string{
+				"47eea4da-bda1-48a6-901c-3e36d2d9262f",
+				"4915fc9d-3726-4171-b588-6c271f45eecd",
+			},
+		},
+		{
+			name:      "Unknown Policy Name",
+			local:     true,
+			global:    true,
+			policy:    "nope",
+			accessors: []string{},
+		},
 		{
 			name:   "All",
 			local:  true,
@@ -801,6 +819,121 @@ func TestStateStore_ACLToken_List(t *testing.T) {
 	}
 }
 
+func TestStateStore_ACLToken_List_PolicyIndexUpdates(t *testing.T) {
+	t.Parallel()
+	s := testACLTokensStateStore(t)
+
+	token := &structs.ACLToken{
+		AccessorID: "47eea4da-bda1-48a6-901c-3e36d2d9262f",
+		SecretID:   "548bdb8e-c0d6-477b-bcc4-67fb836e9e61",
+		Policies: []structs.ACLTokenPolicyLink{
+			structs.ACLTokenPolicyLink{
+				ID: "a0625e95-9b3e-42de-a8d6-ceef5b6f3286",
+			},
+		},
+	}
+	require.NoError(t, s.ACLTokenSet(2, token, false))
+
+	accessors := func(policy string) []string {
+		_, tokens, err := s.ACLTokenList(nil, true, true, policy)
+		require.NoError(t, err)
+		var out []string
+		for _, token := range tokens {
+			out = append(out, token.AccessorID)
+		}
+		return out
+	}
+	require.Equal(t, []string{token.AccessorID}, accessors("node-read"))
+
+	// Moving the token to another policy updates the index.
+	token = token.Clone()
+	token.Policies = []structs.ACLTokenPolicyLink{
+		structs.ACLTokenPolicyLink{
+			ID: structs.ACLPolicyGlobalManagementID,
+		},
+	}
+	require.NoError(t, s.ACLTokenSet(3, token, false))
+	require.Empty(t, accessors("node-read"))
+	require.Contains(t, accessors(structs.ACLPolicyGlobalManagementID), token.AccessorID)
+
+	// So does deleting it.
+	require.NoError(t, s.ACLTokenDeleteByAccessor(4, token.AccessorID))
+	require.NotContains(t, accessors(structs.ACLPolicyGlobalManagementID), token.AccessorID)
+}
+
+func TestStateStore_ACLToken_ListDangling(t *testing.T) {
+	t.Parallel()
+	s := testACLTokensStateStore(t)
+
+	policyID := "a0625e95-9b3e-42de-a8d6-ceef5b6f3286"
+	link := []structs.ACLTokenPolicyLink{{ID: policyID}}
+	tokens := structs.ACLTokens{
+		// global token that only links the policy
+		&structs.ACLToken{
+			AccessorID: "47eea4da-bda1-48a6-901c-3e36d2d9262f",
+			SecretID:   "548bdb8e-c0d6-477b-bcc4-67fb836e9e61",
+			Policies:   link,
+		},
+		// local token that only links the policy
+		&structs.ACLToken{
+			AccessorID: "4915fc9d-3726-4171-b588-6c271f45eecd",
+			SecretID:   "f6998577-fd9b-4e6c-b202-cc3820513d32",
+			Policies:   link,
+			Local:      true,
+		},
+		// token that also links global-management
+		&structs.ACLToken{
+			AccessorID: "f1093997-b6c7-496d-bfb8-6b1b1895641b",
+			SecretID:   "34ec8eb3-095d-417a-a937-b439af7a8e8b",
+			Policies: []structs.ACLTokenPolicyLink{
+				{ID: policyID},
+				{ID: structs.ACLPolicyGlobalManagementID},
+			},
+		},
+		// token with rules of its own
+		&structs.ACLToken{
+			AccessorID:     "54866514-3cf2-4fec-8a8a-710583831834",
+			SecretID:       "8de2dd39-134d-4cb1-950b-b7ab96ea20ba",
+			Policies:       link,
+			InlinePolicies: []structs.ACLTokenInlinePolicy{{Rules: `node_prefix "" { policy = "read" }`}},
+		},
+		// token without any policies
+		&structs.ACLToken{
+			AccessorID: "2a1ea8b1-ec6b-4c2f-8a7d-1d8c4c1d3a4e",
+			SecretID:   "0c9a3c1b-4a63-4f3a-9e0a-0fd0b9bd8d2f",
+		},
+	}
+	require.NoError(t, s.ACLTokenBatchSet(2, tokens, false))
+
+	accessors := func(local, global bool) []string {
+		_, tokens, err := s.ACLTokenListDangling(nil, local, global)
+		require.NoError(t, err)
+		var out []string
+		for _, token := range tokens {
+			out = append(out, token.AccessorID)
+		}
+		return out
+	}
+	require.Empty(t, accessors(true, true))
+
+	// Deleting the policy leaves the tokens that only link it dangling,
+	// with the link still pointing at the deleted policy.
+	ws := memdb.NewWatchSet()
+	_, _, err := s.ACLTokenListDangling(ws, true, true)
+	require.NoError(t, err)
+	require.NoError(t, s.ACLPolicyDeleteByID(3, policyID))
+	require.True(t, watchFired(ws))
+
+	idx, dangling, err := s.ACLTokenListDangling(nil, true, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), idx)
+	require.Len(t, dangling, 2)
+	require.Equal(t, link, dangling[0].Policies)
+	require.ElementsMatch(t, []string{tokens[0].AccessorID, tokens[1].AccessorID}, accessors(true, true))
+	require.Equal(t, []string{tokens[0].AccessorID}, accessors(false, true))
+	require.Equal(t, []string{tokens[1].AccessorID}, accessors(true, false))
+}
+
 func TestStateStore_ACLToken_FixupPolicyLinks(t *testing.T) {
 	// This test wants to ensure a couple of things.
 	//
@@ -263,6 +263,21 @@ func (s *Store) EnsureRegistration(idx uint64, req *structs.RegisterRequest) err
 	tx := s.db.Txn(true)
 	defer tx.Abort()
 
+	owner, err := nodeSyncOwnerTxn(tx, req.Node)
+	if err != nil {
+		return err
+	}
+	if req.CheckSyncOwner {
+		if err := vetSyncOwner(owner, req.SyncOwner, req.ForceSyncOwner); err != nil {
+			return err
+		}
+	} else if req.SyncOwner != owner {
+		// Internal writes keep the owner of the node, so copy the request
+		// rather than changing the caller's.
+		r := *req
+		r.SyncOwner = owner
+		req = &r
+	}
 	if err := s.ensureRegistrationTxn(tx, idx, req); err != nil {
 		return err
 	}
@@ -271,6 +286,39 @@ func (s *Store) EnsureRegistration(idx uint64, req *structs.RegisterRequest) err
 	return nil
 }
 
+// nodeSyncOwnerTxn returns the external sync tool that manages the given
+// node, if any. It's read in the transaction of the write so that two tools
+// can't both pass the check.
+func nodeSyncOwnerTxn(tx *memdb.Txn, nodeName string) (string, error) {
+	existing, err := tx.First("nodes", "id", nodeName)
+	if err != nil {
+		return "", fmt.Errorf("node lookup failed: %s", err)
+	}
+	if existing == nil {
+		return "", nil
+	}
+	return existing.(*structs.Node).SyncOwner, nil
+}
+
+// vetSyncOwner checks that a write made through the catalog API comes from
+// the current owner of the node, if any, unless the write forces the owner.
+func vetSyncOwner(current, owner string, force bool) error {
+	if force || current == "" || current == owner {
+		return nil
+	}
+	return fmt.Errorf("%s %q", structs.ErrSyncOwnerConflict, current)
+}
+
+// syncOwnerTxn checks the sync owner of the given node for a deregistration
+// made through the catalog API.
+func syncOwnerTxn(tx *memdb.Txn, nodeName, owner string, force bool) error {
+	current, err := nodeSyncOwnerTxn(tx, nodeName)
+	if err != nil {
+		return err
+	}
+	return vetSyncOwner(current, owner, force)
+}
+
 func (s *Store) ensureCheckIfNodeMatches(tx *memdb.Txn, idx uint64, node string, check *structs.HealthCheck) error {
 	if check.Node != node {
 		return fmt.Errorf("check node %q does not match node %q",
@@ -294,6 +342,7 @@ func (s *Store) ensureRegistrationTxn(tx *memdb.Txn, idx uint64, req *structs.Re
 		Datacenter:      req.Datacenter,
 		TaggedAddresses: req.TaggedAddresses,
 		Meta:            req.NodeMeta,
+		SyncOwner:       req.SyncOwner,
 	}
 
 	// Since this gets called for all node operations (service and check
@@ -1302,6 +1351,72 @@ func (s *Store) DeleteService(idx uint64, nodeName, serviceID string) error {
 	return nil
 }
 
+// Deregister is used to remove the service of a deregistration if its
+// ServiceID is set, or else its check if its CheckID is set, or else the
+// whole node, after checking its sync owner in the same transaction if the
+// deregistration was made through the catalog API.
+func (s *Store) Deregister(idx uint64, req *structs.DeregisterRequest) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	if req.CheckSyncOwner {
+		if err := syncOwnerTxn(tx, req.Node, req.SyncOwner, req.ForceSyncOwner); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case req.ServiceID != "":
+		if err := s.deleteServiceTxn(tx, idx, req.Node, req.ServiceID); err != nil {
+			return err
+		}
+	case req.CheckID != "":
+		if err := s.deleteCheckTxn(tx, idx, req.Node, req.CheckID); err != nil {
+			return err
+		}
+	default:
+		if err := s.deleteNodeTxn(tx, idx, req.Node); err != nil {
+			return err
+		}
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// DeregisterBatch is used to delete several services and checks of a node in
+// a single transaction, after checking the sync owner of the batch if
+// CheckSyncOwner is set. Each deregistration removes its ServiceID if it's
+// set, or else its CheckID.
+func (s *Store) DeregisterBatch(idx uint64, batch *structs.DeregisterBatchRequest) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	if batch.CheckSyncOwner {
+		if err := syncOwnerTxn(tx, batch.Node, batch.SyncOwner, batch.ForceSyncOwner); err != nil {
+			return err
+		}
+	}
+
+	for _, req := range batch.Deregistrations {
+		switch {
+		case req.ServiceID != "":
+			if err := s.deleteServiceTxn(tx, idx, batch.Node, req.ServiceID); err != nil {
+				return err
+			}
+		case req.CheckID != "":
+			if err := s.deleteCheckTxn(tx, idx, batch.Node, req.CheckID); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Must provide service or check ID")
+		}
+	}
+
+	tx.Commit()
+	return nil
+}
+
 func serviceIndexName(name string) string {
 	return fmt.Sprintf("service.%s", name)
 }
@@ -1398,6 +1513,8 @@ func (s *Store) deleteServiceTxn(tx *memdb.Txn, idx uint64, nodeName, serviceID
 				return fmt.Errorf("failed updating missing service index: %s", err)
 			}
 
+			s.forgetHealthHistory(tx, svc.ServiceName)
+
 		}
 	} else {
 		return fmt.Errorf("Could not find any service %s: %s", svc.ServiceName, err)
@@ -1516,6 +1633,14 @@ func (s *Store) ensureCheckTxn(tx *memdb.Txn, idx uint64, hc *structs.HealthChec
 		if existing != nil && existing.(*structs.HealthCheck).IsSame(hc) {
 			modified = false
 		} else {
+			if existing != nil {
+				if oldStatus := existing.(*structs.HealthCheck).Status; oldStatus != hc.Status {
+					if err := s.recordHealthTransition(tx, idx, oldStatus, hc); err != nil {
+						return err
+					}
+				}
+			}
+
 			// Check has been modified, we trigger a index service change
 			if err = tx.Insert("index", &IndexEntry{serviceIndexName(svc.ServiceName), idx}); err != nil {
 				return fmt.Errorf("failed updating index: %s", err)
@@ -1525,6 +1650,14 @@ func (s *Store) ensureCheckTxn(tx *memdb.Txn, idx uint64, hc *structs.HealthChec
 		if existing != nil && existing.(*structs.HealthCheck).IsSame(hc) {
 			modified = false
 		} else {
+			if existing != nil {
+				if oldStatus := existing.(*structs.HealthCheck).Status; oldStatus != hc.Status {
+					if err := s.recordHealthTransition(tx, idx, oldStatus, hc); err != nil {
+						return err
+					}
+				}
+			}
+
 			// Since the check has been modified, it impacts all services of node
 			// Update the status for all the services associated with this node
 			err = s.updateAllServiceIndexesOfNode(tx, idx, hc.Node)
@@ -2152,6 +2285,164 @@ func (s *Store) ServiceDump(ws memdb.WatchSet) (uint64, structs.CheckServiceNode
 	return s.parseCheckServiceNodes(tx, nil, idx, "", results, err)
 }
 
+// connectInstance identifies a service instance on a node, by ID or by name.
+type connectInstance struct {
+	node    string
+	service string
+}
+
+// ConnectMismatches returns the service instances whose sidecar proxies are
+// missing or all critical, and the sidecar proxies whose destination service
+// isn't registered on their node. Whether an instance was meant to have a
+// sidecar isn't recorded in the catalog, so one is only expected if another
+// instance of the same service has a sidecar.
+func (s *Store) ConnectMismatches(ws memdb.WatchSet) (uint64, structs.ConnectMismatches, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the table index.
+	idx := maxIndexWatchTxn(tx, ws, "nodes", "services", "checks")
+
+	services, err := tx.Get("services", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed service lookup: %s", err)
+	}
+	ws.Add(services.WatchCh())
+
+	// The health of any proxy may matter so watch all checks.
+	checks, err := tx.Get("checks", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed checks lookup: %s", err)
+	}
+	ws.Add(checks.WatchCh())
+
+	// Index the service instances and proxies so they can be joined. A proxy
+	// registered without a destination service ID applies to any instance of
+	// the destination service on its node.
+	var all structs.ServiceNodes
+	byID := make(map[connectInstance]struct{})
+	byName := make(map[connectInstance]struct{})
+	proxiesByID := make(map[connectInstance]structs.ServiceNodes)
+	proxiesByName := make(map[connectInstance]structs.ServiceNodes)
+	proxied := make(map[string]struct{})
+	for service := services.Next(); service != nil; service = services.Next() {
+		sn := service.(*structs.ServiceNode)
+		all = append(all, sn)
+
+		if sn.ServiceKind != structs.ServiceKindConnectProxy {
+			byID[connectInstance{sn.Node, sn.ServiceID}] = struct{}{}
+			byName[connectInstance{sn.Node, sn.ServiceName}] = struct{}{}
+			continue
+		}
+
+		proxy := sn.ServiceProxy
+		proxied[proxy.DestinationServiceName] = struct{}{}
+		if proxy.DestinationServiceID != "" {
+			key := connectInstance{sn.Node, proxy.DestinationServiceID}
+			proxiesByID[key] = append(proxiesByID[key], sn)
+		} else {
+			key := connectInstance{sn.Node, proxy.DestinationServiceName}
+			proxiesByName[key] = append(proxiesByName[key], sn)
+		}
+	}
+
+	var results structs.ConnectMismatches
+	for _, sn := range all {
+		if sn.ServiceKind == structs.ServiceKindConnectProxy {
+			proxy := sn.ServiceProxy
+			var ok bool
+			if proxy.DestinationServiceID != "" {
+				_, ok = byID[connectInstance{sn.Node, proxy.DestinationServiceID}]
+			} else {
+				_, ok = byName[connectInstance{sn.Node, proxy.DestinationServiceName}]
+			}
+			if !ok {
+				results = append(results, &structs.ConnectMismatch{
+					Node:           sn.Node,
+					ServiceID:      proxy.DestinationServiceID,
+					ServiceName:    proxy.DestinationServiceName,
+					ProxyServiceID: sn.ServiceID,
+					Reason:         structs.ConnectMismatchDestinationMissing,
+				})
+			}
+			continue
+		}
+
+		// Native services don't need a sidecar.
+		if sn.ServiceConnect.Native {
+			continue
+		}
+		if _, ok := proxied[sn.ServiceName]; !ok {
+			continue
+		}
+
+		var proxies structs.ServiceNodes
+		proxies = append(proxies, proxiesByID[connectInstance{sn.Node, sn.ServiceID}]...)
+		proxies = append(proxies, proxiesByName[connectInstance{sn.Node, sn.ServiceName}]...)
+		if len(proxies) == 0 {
+			results = append(results, &structs.ConnectMismatch{
+				Node:        sn.Node,
+				ServiceID:   sn.ServiceID,
+				ServiceName: sn.ServiceName,
+				Reason:      structs.ConnectMismatchSidecarMissing,
+			})
+			continue
+		}
+
+		// The instance is reachable as long as one of its proxies is.
+		var mismatch *structs.ConnectMismatch
+		for _, proxy := range proxies {
+			critical, err := criticalChecksTxn(tx, proxy.Node, proxy.ServiceID)
+			if err != nil {
+				return 0, nil, err
+			}
+			if len(critical) == 0 {
+				mismatch = nil
+				break
+			}
+			if mismatch == nil {
+				mismatch = &structs.ConnectMismatch{
+					Node:           sn.Node,
+					ServiceID:      sn.ServiceID,
+					ServiceName:    sn.ServiceName,
+					ProxyServiceID: proxy.ServiceID,
+					Reason:         structs.ConnectMismatchSidecarCritical,
+					Checks:         critical,
+				}
+			}
+		}
+		if mismatch != nil {
+			results = append(results, mismatch)
+		}
+	}
+
+	return idx, results, nil
+}
+
+// criticalChecksTxn returns the critical node checks and service checks that
+// apply to the given service instance.
+func criticalChecksTxn(tx *memdb.Txn, node, serviceID string) (structs.HealthChecks, error) {
+	nodeChecks, err := tx.Get("checks", "node_service_check", node, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed check lookup: %s", err)
+	}
+	serviceChecks, err := tx.Get("checks", "node_service", node, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed check lookup: %s", err)
+	}
+
+	var critical structs.HealthChecks
+	for _, iter := range []memdb.ResultIterator{nodeChecks, serviceChecks} {
+		for check := iter.Next(); check != nil; check = iter.Next() {
+			hc := check.(*structs.HealthCheck)
+			if hc.Status == api.HealthCritical {
+				critical = append(critical, hc)
+			}
+		}
+	}
+	return critical, nil
+}
+
 // parseNodes takes an iterator over a set of nodes and returns a struct
 // containing the nodes along with all of their associated services
 // and/or health checks.
@@ -1447,6 +1447,25 @@ func TestStateStore_EnsureService(t *testing.T) {
 	}
 }
 
+func TestStateStore_EnsureService_invalidSRVTarget(t *testing.T) {
+	s := testStateStore(t)
+
+	// Service meta with a special meaning is validated before it gets to
+	// Raft, entries applied from the log must be stored as they are.
+	ns := &structs.NodeService{
+		ID:      "service1",
+		Service: "redis",
+		Port:    1111,
+		Meta:    map[string]string{structs.MetaDNSSRVTarget: "not_a host"},
+	}
+	testRegisterNode(t, s, 0, "node1")
+	require.NoError(t, s.EnsureService(10, "node1", ns))
+
+	_, out, err := s.NodeServices(nil, "node1")
+	require.NoError(t, err)
+	require.Equal(t, "not_a host", out.Services["service1"].Meta[structs.MetaDNSSRVTarget])
+}
+
 func TestStateStore_EnsureService_connectProxy(t *testing.T) {
 	assert := assert.New(t)
 	s := testStateStore(t)
@@ -2017,6 +2036,147 @@ func TestStateStore_DeleteService(t *testing.T) {
 	}
 }
 
+func TestStateStore_DeregisterBatch(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "service1")
+	testRegisterService(t, s, 3, "node1", "service2")
+	testRegisterCheck(t, s, 4, "node1", "service1", "check1", api.HealthPassing)
+	testRegisterCheck(t, s, 5, "node1", "", "check2", api.HealthPassing)
+	testRegisterCheck(t, s, 6, "node1", "", "check3", api.HealthPassing)
+
+	// A batch with an invalid deregistration is rejected as a whole.
+	err := s.DeregisterBatch(7, &structs.DeregisterBatchRequest{
+		Node: "node1",
+		Deregistrations: []structs.DeregisterRequest{
+			{ServiceID: "service1"},
+			{},
+		},
+	})
+	require.Error(err)
+	_, ns, err := s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Len(ns.Services, 2)
+
+	// Delete a service with its check, and a node check.
+	ws := memdb.NewWatchSet()
+	_, _, err = s.NodeServices(ws, "node1")
+	require.NoError(err)
+	require.NoError(s.DeregisterBatch(7, &structs.DeregisterBatchRequest{
+		Node: "node1",
+		Deregistrations: []structs.DeregisterRequest{
+			{ServiceID: "service1"},
+			{CheckID: "check2"},
+			{ServiceID: "service-missing"},
+		},
+	}))
+	require.True(watchFired(ws))
+
+	_, ns, err = s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Len(ns.Services, 1)
+	require.Contains(ns.Services, "service2")
+
+	_, checks, err := s.NodeChecks(nil, "node1")
+	require.NoError(err)
+	require.Len(checks, 1)
+	require.Equal(types.CheckID("check3"), checks[0].CheckID)
+
+	// Index tables were updated.
+	require.Equal(uint64(7), s.maxIndex("services"))
+	require.Equal(uint64(7), s.maxIndex("checks"))
+}
+
+func TestStateStore_SyncOwner(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	reg := &structs.RegisterRequest{
+		Node:           "node1",
+		Address:        "1.2.3.4",
+		SyncOwner:      "sync-a",
+		CheckSyncOwner: true,
+		Service: &structs.NodeService{
+			ID:      "service1",
+			Service: "service1",
+		},
+	}
+	require.NoError(s.EnsureRegistration(1, reg))
+
+	// Another owner can't write to the node unless it forces the owner.
+	reg.SyncOwner = "sync-b"
+	err := s.EnsureRegistration(2, reg)
+	require.True(structs.IsErrSyncOwnerConflict(err), "%v", err)
+	require.Contains(err.Error(), `"sync-a"`)
+
+	err = s.Deregister(2, &structs.DeregisterRequest{
+		Node:           "node1",
+		ServiceID:      "service1",
+		SyncOwner:      "sync-b",
+		CheckSyncOwner: true,
+	})
+	require.True(structs.IsErrSyncOwnerConflict(err), "%v", err)
+
+	err = s.DeregisterBatch(2, &structs.DeregisterBatchRequest{
+		Node:            "node1",
+		Deregistrations: []structs.DeregisterRequest{{ServiceID: "service1"}},
+		SyncOwner:       "sync-b",
+		CheckSyncOwner:  true,
+	})
+	require.True(structs.IsErrSyncOwnerConflict(err), "%v", err)
+
+	_, ns, err := s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Equal("sync-a", ns.Node.SyncOwner)
+	require.Len(ns.Services, 1)
+
+	// Internal writes, like the leader marking the node critical, aren't
+	// fenced and keep the owner.
+	require.NoError(s.EnsureRegistration(3, &structs.RegisterRequest{
+		Node:    "node1",
+		Address: "1.2.3.4",
+		Check: &structs.HealthCheck{
+			Node:    "node1",
+			CheckID: structs.SerfCheckID,
+			Name:    structs.SerfCheckName,
+			Status:  api.HealthCritical,
+		},
+		SkipNodeUpdate: true,
+	}))
+	require.NoError(s.EnsureRegistration(4, &structs.RegisterRequest{
+		Node:    "node1",
+		Address: "1.2.3.5",
+	}))
+	_, ns, err = s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Equal("sync-a", ns.Node.SyncOwner)
+	require.Equal("1.2.3.5", ns.Node.Address)
+	_, checks, err := s.NodeChecks(nil, "node1")
+	require.NoError(err)
+	require.Len(checks, 1)
+	require.Equal(api.HealthCritical, checks[0].Status)
+
+	// Forcing the owner takes the node over.
+	reg.ForceSyncOwner = true
+	require.NoError(s.EnsureRegistration(5, reg))
+	_, ns, err = s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Equal("sync-b", ns.Node.SyncOwner)
+
+	// And the owner can deregister from it.
+	require.NoError(s.Deregister(6, &structs.DeregisterRequest{
+		Node:           "node1",
+		ServiceID:      "service1",
+		SyncOwner:      "sync-b",
+		CheckSyncOwner: true,
+	}))
+	_, ns, err = s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Empty(ns.Services)
+}
+
 func TestStateStore_ConnectServiceNodes(t *testing.T) {
 	assert := assert.New(t)
 	s := testStateStore(t)
@@ -2062,6 +2222,96 @@ func TestStateStore_ConnectServiceNodes(t *testing.T) {
 	assert.True(watchFired(ws))
 }
 
+func TestStateStore_ConnectMismatches(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	// Nothing registered yet.
+	ws := memdb.NewWatchSet()
+	idx, out, err := s.ConnectMismatches(ws)
+	require.NoError(err)
+	require.Equal(uint64(0), idx)
+	require.Nil(out)
+
+	proxy := func(id, node, destID string) *structs.NodeService {
+		return &structs.NodeService{
+			Kind:    structs.ServiceKindConnectProxy,
+			ID:      id,
+			Service: "web-sidecar-proxy",
+			Port:    20000,
+			Proxy: structs.ConnectProxyConfig{
+				DestinationServiceName: "web",
+				DestinationServiceID:   destID,
+			},
+		}
+	}
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterNode(t, s, 2, "node2")
+
+	// Two instances on node1, only the first has a sidecar.
+	require.NoError(s.EnsureService(3, "node1", &structs.NodeService{ID: "web1", Service: "web", Port: 8080}))
+	require.NoError(s.EnsureService(4, "node1", &structs.NodeService{ID: "web2", Service: "web", Port: 8081}))
+	require.NoError(s.EnsureService(5, "node1", proxy("web1-sidecar-proxy", "node1", "web1")))
+	testRegisterCheck(t, s, 6, "node1", "web1-sidecar-proxy", "proxy1", api.HealthPassing)
+
+	// Two instances on node2 with sidecars, one has a critical sidecar and
+	// the other has a critical and a passing one.
+	require.NoError(s.EnsureService(7, "node2", &structs.NodeService{ID: "web1", Service: "web", Port: 8080}))
+	require.NoError(s.EnsureService(8, "node2", proxy("web1-sidecar-proxy", "node2", "web1")))
+	testRegisterCheck(t, s, 9, "node2", "web1-sidecar-proxy", "proxy1", api.HealthCritical)
+	require.NoError(s.EnsureService(10, "node2", &structs.NodeService{ID: "web2", Service: "web", Port: 8081}))
+	require.NoError(s.EnsureService(11, "node2", proxy("web2-sidecar-proxy-a", "node2", "web2")))
+	testRegisterCheck(t, s, 12, "node2", "web2-sidecar-proxy-a", "proxy2a", api.HealthCritical)
+	require.NoError(s.EnsureService(13, "node2", proxy("web2-sidecar-proxy-b", "node2", "web2")))
+
+	// A sidecar left behind after its service was deregistered.
+	require.NoError(s.EnsureService(14, "node2", proxy("web3-sidecar-proxy", "node2", "web3")))
+
+	// Services without any sidecar and native services are ignored.
+	testRegisterService(t, s, 15, "node1", "api")
+	require.NoError(s.EnsureService(16, "node2", &structs.NodeService{ID: "web-native", Service: "web", Connect: structs.ServiceConnect{Native: true}}))
+	require.True(watchFired(ws))
+
+	ws = memdb.NewWatchSet()
+	idx, out, err = s.ConnectMismatches(ws)
+	require.NoError(err)
+	require.Equal(uint64(16), idx)
+	require.Len(out, 3)
+
+	require.Equal(&structs.ConnectMismatch{
+		Node:        "node1",
+		ServiceID:   "web2",
+		ServiceName: "web",
+		Reason:      structs.ConnectMismatchSidecarMissing,
+	}, out[0])
+
+	require.Equal("node2", out[1].Node)
+	require.Equal("web1", out[1].ServiceID)
+	require.Equal("web1-sidecar-proxy", out[1].ProxyServiceID)
+	require.Equal(structs.ConnectMismatchSidecarCritical, out[1].Reason)
+	require.Len(out[1].Checks, 1)
+	require.Equal(types.CheckID("proxy1"), out[1].Checks[0].CheckID)
+
+	require.Equal(&structs.ConnectMismatch{
+		Node:           "node2",
+		ServiceID:      "web3",
+		ServiceName:    "web",
+		ProxyServiceID: "web3-sidecar-proxy",
+		Reason:         structs.ConnectMismatchDestinationMissing,
+	}, out[2])
+
+	// A critical node check makes all the sidecars on the node critical.
+	testRegisterCheck(t, s, 17, "node1", "", "serfHealth", api.HealthCritical)
+	require.True(watchFired(ws))
+	_, out, err = s.ConnectMismatches(nil)
+	require.NoError(err)
+	require.Len(out, 4)
+	require.Equal("web1", out[0].ServiceID)
+	require.Equal(structs.ConnectMismatchSidecarCritical, out[0].Reason)
+	require.Equal(types.CheckID("serfHealth"), out[0].Checks[0].CheckID)
+}
+
 func TestStateStore_Service_Snapshot(t *testing.T) {
 	s := testStateStore(t)
 
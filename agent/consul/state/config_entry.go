@@ -104,6 +104,32 @@ func (s *Store) ConfigEntry(kind, name string) (uint64, structs.ConfigEntry, err
 	return idx, conf, nil
 }
 
+// ServiceRouting returns the routing override of a service, or nil if there
+// is none. The index returned is the one of the config entries table so that
+// deleting the override is noticed by blocking queries.
+func (s *Store) ServiceRouting(ws memdb.WatchSet, service string) (uint64, *structs.ServiceRoutingConfigEntry, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the index
+	idx := maxIndexTxn(tx, configTableName)
+
+	watchCh, existing, err := tx.FirstWatch(configTableName, "id", structs.ServiceRouting, service)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed config entry lookup: %s", err)
+	}
+	ws.Add(watchCh)
+	if existing == nil {
+		return idx, nil, nil
+	}
+
+	conf, ok := existing.(*structs.ServiceRoutingConfigEntry)
+	if !ok {
+		return 0, nil, fmt.Errorf("config entry %q (%s) is an invalid type: %T", service, structs.ServiceRouting, existing)
+	}
+	return idx, conf, nil
+}
+
 // ConfigEntries is called to get all config entry objects.
 func (s *Store) ConfigEntries() (uint64, []structs.ConfigEntry, error) {
 	return s.ConfigEntriesByKind("")
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/consul/agent/structs"
+	memdb "github.com/hashicorp/go-memdb"
 	"github.com/stretchr/testify/require"
 )
 
@@ -136,3 +137,48 @@ func TestStore_ConfigEntries(t *testing.T) {
 	require.Equal(uint64(1), idx)
 	require.Equal([]structs.ConfigEntry{entry2}, entries)
 }
+
+func TestStore_ServiceRouting(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	// Nothing to start with
+	ws := memdb.NewWatchSet()
+	idx, routing, err := s.ServiceRouting(ws, "web")
+	require.NoError(err)
+	require.Equal(uint64(0), idx)
+	require.Nil(routing)
+
+	// Other kinds of entries with the same name are ignored
+	require.NoError(s.EnsureConfigEntry(1, &structs.ServiceConfigEntry{
+		Kind: structs.ServiceDefaults,
+		Name: "web",
+	}))
+	_, routing, err = s.ServiceRouting(nil, "web")
+	require.NoError(err)
+	require.Nil(routing)
+
+	expected := &structs.ServiceRoutingConfigEntry{
+		Kind:       structs.ServiceRouting,
+		Name:       "web",
+		ForceTag:   "canary",
+		Percentage: 10,
+	}
+	require.NoError(s.EnsureConfigEntry(2, expected))
+	require.True(watchFired(ws))
+
+	ws = memdb.NewWatchSet()
+	idx, routing, err = s.ServiceRouting(ws, "web")
+	require.NoError(err)
+	require.Equal(uint64(2), idx)
+	require.Equal(expected, routing)
+
+	// Delete
+	require.NoError(s.DeleteConfigEntry(3, structs.ServiceRouting, "web"))
+	require.True(watchFired(ws))
+
+	idx, routing, err = s.ServiceRouting(nil, "web")
+	require.NoError(err)
+	require.Equal(uint64(3), idx)
+	require.Nil(routing)
+}
@@ -0,0 +1,193 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// HealthHistory keeps the most recent health check status transitions of
+// each service in memory. It is not part of the replicated state: every
+// server records the transitions as it applies them, the history starts out
+// empty when a server starts and timestamps reflect when the local server
+// applied the change, so they differ slightly between servers and are late
+// on a server catching up with the leader. It is meant to answer questions
+// like "when did this service last go critical" without an external metrics
+// store, not as an audit log.
+//
+// A transition of a node check, such as serfHealth, is recorded for every
+// service on the node.
+type HealthHistory struct {
+	// retention is the number of transitions kept per service.
+	retention int
+
+	// replayIndex is the last Raft index that was already in the local log
+	// when the server started. Those entries are replayed into the state
+	// store, and their transitions are skipped rather than being stamped
+	// with the time of the replay.
+	replayIndex uint64
+
+	// services maps service names to their transitions. A service is
+	// removed once its last instance is deregistered.
+	services map[string]*transitionRing
+
+	// lock protects the services map.
+	lock sync.RWMutex
+}
+
+// transitionRing is a fixed size ring buffer of transitions.
+type transitionRing struct {
+	entries structs.HealthTransitions
+	next    int
+}
+
+// NewHealthHistory returns a history that keeps up to retention transitions
+// for each service. A retention of zero disables recording.
+func NewHealthHistory(retention int) *HealthHistory {
+	return &HealthHistory{
+		retention: retention,
+		services:  make(map[string]*transitionRing),
+	}
+}
+
+// SetReplayIndex sets the last Raft index whose transitions are skipped since
+// they're replayed from the local log. It must be called before the history
+// is used.
+func (h *HealthHistory) SetReplayIndex(idx uint64) {
+	h.replayIndex = idx
+}
+
+// record adds a transition, evicting the oldest one for the service if the
+// retention limit has been reached.
+func (h *HealthHistory) record(t *structs.HealthTransition) {
+	if h.retention <= 0 {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	ring, ok := h.services[t.ServiceName]
+	if !ok {
+		ring = &transitionRing{}
+		h.services[t.ServiceName] = ring
+	}
+
+	if len(ring.entries) < h.retention {
+		ring.entries = append(ring.entries, t)
+		return
+	}
+	ring.entries[ring.next] = t
+	ring.next = (ring.next + 1) % len(ring.entries)
+}
+
+// forget drops the transitions recorded for the given service.
+func (h *HealthHistory) forget(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.services, name)
+}
+
+// Service returns the transitions recorded for the given service, oldest
+// first.
+func (h *HealthHistory) Service(name string) structs.HealthTransitions {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	ring, ok := h.services[name]
+	if !ok {
+		return nil
+	}
+
+	out := make(structs.HealthTransitions, 0, len(ring.entries))
+	out = append(out, ring.entries[ring.next:]...)
+	out = append(out, ring.entries[:ring.next]...)
+	return out
+}
+
+// SetHealthHistory sets the history that check status transitions are
+// recorded in. It must be called before the store is used.
+func (s *Store) SetHealthHistory(h *HealthHistory) {
+	s.healthHistory = h
+}
+
+// recordHealthTransition records that the given check changed from the old
+// status once the transaction is committed. A node check's transition is
+// recorded for each service on the node.
+func (s *Store) recordHealthTransition(tx *memdb.Txn, idx uint64, oldStatus string, hc *structs.HealthCheck) error {
+	if s.healthHistory == nil || idx <= s.healthHistory.replayIndex {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	transition := func(serviceID, serviceName string) {
+		t := &structs.HealthTransition{
+			Time:        now,
+			Node:        hc.Node,
+			ServiceID:   serviceID,
+			ServiceName: serviceName,
+			CheckID:     hc.CheckID,
+			OldStatus:   oldStatus,
+			NewStatus:   hc.Status,
+		}
+		tx.Defer(func() { s.healthHistory.record(t) })
+	}
+
+	if hc.ServiceID != "" {
+		transition(hc.ServiceID, hc.ServiceName)
+		return nil
+	}
+
+	services, err := tx.Get("services", "node", hc.Node)
+	if err != nil {
+		return fmt.Errorf("failed services lookup: %s", err)
+	}
+	for service := services.Next(); service != nil; service = services.Next() {
+		svc := service.(*structs.ServiceNode)
+		transition(svc.ServiceID, svc.ServiceName)
+	}
+	return nil
+}
+
+// forgetHealthHistory drops the transitions recorded for the given service
+// once the transaction is committed. It's called when the last instance of
+// the service is deregistered so the history doesn't grow with every
+// service name ever seen.
+func (s *Store) forgetHealthHistory(tx *memdb.Txn, serviceName string) {
+	if s.healthHistory == nil {
+		return
+	}
+	tx.Defer(func() { s.healthHistory.forget(serviceName) })
+}
+
+// ServiceHealthHistory returns the recorded health transitions of the given
+// service. The index and watches are those of the service's checks and of the
+// service itself, which also changes with the checks of its nodes, so that
+// blocking queries return when a new transition might have been recorded.
+func (s *Store) ServiceHealthHistory(ws memdb.WatchSet, serviceName string) (uint64, structs.HealthTransitions, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "checks")
+
+	iter, err := tx.Get("checks", "service", serviceName)
+	if err != nil {
+		return 0, nil, err
+	}
+	ws.Add(iter.WatchCh())
+
+	ch, _, err := tx.FirstWatch("index", "id", serviceIndexName(serviceName))
+	if err != nil {
+		return 0, nil, err
+	}
+	ws.Add(ch)
+
+	if s.healthHistory == nil {
+		return idx, nil, nil
+	}
+	return idx, s.healthHistory.Service(serviceName), nil
+}
@@ -0,0 +1,154 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHistory_Retention(t *testing.T) {
+	h := NewHealthHistory(2)
+
+	for _, status := range []string{api.HealthWarning, api.HealthCritical, api.HealthPassing} {
+		h.record(&structs.HealthTransition{ServiceName: "web", NewStatus: status})
+	}
+	h.record(&structs.HealthTransition{ServiceName: "db", NewStatus: api.HealthCritical})
+
+	web := h.Service("web")
+	require.Len(t, web, 2)
+	require.Equal(t, api.HealthCritical, web[0].NewStatus)
+	require.Equal(t, api.HealthPassing, web[1].NewStatus)
+
+	require.Len(t, h.Service("db"), 1)
+	require.Nil(t, h.Service("nope"))
+
+	// Recording is disabled without retention.
+	h = NewHealthHistory(0)
+	h.record(&structs.HealthTransition{ServiceName: "web", NewStatus: api.HealthCritical})
+	require.Nil(t, h.Service("web"))
+}
+
+func TestStateStore_ServiceHealthHistory(t *testing.T) {
+	s := testStateStore(t)
+	s.SetHealthHistory(NewHealthHistory(10))
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "service1")
+	testRegisterCheck(t, s, 3, "node1", "service1", "check1", api.HealthPassing)
+
+	// Registering the check isn't a transition.
+	ws := memdb.NewWatchSet()
+	idx, transitions, err := s.ServiceHealthHistory(ws, "service1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), idx)
+	require.Empty(t, transitions)
+
+	// Changing the status records a transition and fires the watch.
+	testRegisterCheck(t, s, 4, "node1", "service1", "check1", api.HealthCritical)
+	require.True(t, watchFired(ws))
+
+	// Updating the check without changing the status doesn't.
+	testRegisterCheck(t, s, 5, "node1", "service1", "check1", api.HealthCritical)
+	testRegisterCheck(t, s, 6, "node1", "service1", "check1", api.HealthPassing)
+
+	// Node checks are recorded against every service of the node.
+	testRegisterService(t, s, 7, "node1", "service2")
+	testRegisterCheck(t, s, 8, "node1", "", "nodecheck", api.HealthPassing)
+	ws = memdb.NewWatchSet()
+	_, _, err = s.ServiceHealthHistory(ws, "service1")
+	require.NoError(t, err)
+	testRegisterCheck(t, s, 9, "node1", "", "nodecheck", api.HealthCritical)
+	require.True(t, watchFired(ws))
+
+	_, transitions, err = s.ServiceHealthHistory(nil, "service2")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, "service2", transitions[0].ServiceID)
+	require.Equal(t, "nodecheck", string(transitions[0].CheckID))
+
+	ws = memdb.NewWatchSet()
+	idx, transitions, err = s.ServiceHealthHistory(ws, "service1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), idx)
+	require.Len(t, transitions, 3)
+
+	require.Equal(t, "node1", transitions[0].Node)
+	require.Equal(t, "service1", transitions[0].ServiceID)
+	require.Equal(t, "service1", transitions[0].ServiceName)
+	require.Equal(t, "check1", string(transitions[0].CheckID))
+	require.Equal(t, api.HealthPassing, transitions[0].OldStatus)
+	require.Equal(t, api.HealthCritical, transitions[0].NewStatus)
+	require.False(t, transitions[0].Time.IsZero())
+
+	require.Equal(t, api.HealthCritical, transitions[1].OldStatus)
+	require.Equal(t, api.HealthPassing, transitions[1].NewStatus)
+
+	require.Equal(t, "service1", transitions[2].ServiceID)
+	require.Equal(t, "nodecheck", string(transitions[2].CheckID))
+	require.Equal(t, api.HealthCritical, transitions[2].NewStatus)
+
+	// Failed transactions don't record anything.
+	err = s.EnsureCheck(10, &structs.HealthCheck{
+		Node:      "node1",
+		CheckID:   "check1",
+		ServiceID: "nope",
+		Status:    api.HealthCritical,
+	})
+	require.Error(t, err)
+	_, transitions, err = s.ServiceHealthHistory(nil, "service1")
+	require.NoError(t, err)
+	require.Len(t, transitions, 3)
+}
+
+func TestStateStore_ServiceHealthHistory_Replay(t *testing.T) {
+	s := testStateStore(t)
+	h := NewHealthHistory(10)
+	h.SetReplayIndex(4)
+	s.SetHealthHistory(h)
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "service1")
+	testRegisterCheck(t, s, 3, "node1", "service1", "check1", api.HealthPassing)
+
+	// Transitions replayed from the local log aren't recorded.
+	testRegisterCheck(t, s, 4, "node1", "service1", "check1", api.HealthCritical)
+	_, transitions, err := s.ServiceHealthHistory(nil, "service1")
+	require.NoError(t, err)
+	require.Empty(t, transitions)
+
+	testRegisterCheck(t, s, 5, "node1", "service1", "check1", api.HealthPassing)
+	_, transitions, err = s.ServiceHealthHistory(nil, "service1")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, api.HealthPassing, transitions[0].NewStatus)
+}
+
+func TestStateStore_ServiceHealthHistory_Deregister(t *testing.T) {
+	s := testStateStore(t)
+	s.SetHealthHistory(NewHealthHistory(10))
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterNode(t, s, 2, "node2")
+	for i, node := range []string{"node1", "node2"} {
+		idx := uint64(3 + 3*i)
+		testRegisterService(t, s, idx, node, "service1")
+		testRegisterCheck(t, s, idx+1, node, "service1", "check1", api.HealthPassing)
+		testRegisterCheck(t, s, idx+2, node, "service1", "check1", api.HealthCritical)
+	}
+
+	// The history is kept while an instance is left.
+	require.NoError(t, s.DeleteService(9, "node1", "service1"))
+	_, transitions, err := s.ServiceHealthHistory(nil, "service1")
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+
+	// It's dropped with the last instance.
+	require.NoError(t, s.DeleteNode(10, "node2"))
+	_, transitions, err = s.ServiceHealthHistory(nil, "service1")
+	require.NoError(t, err)
+	require.Empty(t, transitions)
+	require.Empty(t, s.healthHistory.services)
+}
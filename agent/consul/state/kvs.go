@@ -324,13 +324,13 @@ func (s *Store) KVSListKeys(ws memdb.WatchSet, prefix, sep string) (uint64, []st
 }
 
 // KVSDelete is used to perform a shallow delete on a single key in the
-// the state store.
-func (s *Store) KVSDelete(idx uint64, key string) error {
+// the state store. The deleted entry is retained if retention is given.
+func (s *Store) KVSDelete(idx uint64, key string, retention *structs.KVSRetention) error {
 	tx := s.db.Txn(true)
 	defer tx.Abort()
 
 	// Perform the actual delete
-	if err := s.kvsDeleteTxn(tx, idx, key); err != nil {
+	if err := s.kvsDeleteTxn(tx, idx, key, retention); err != nil {
 		return err
 	}
 
@@ -340,7 +340,7 @@ func (s *Store) KVSDelete(idx uint64, key string) error {
 
 // kvsDeleteTxn is the inner method used to perform the actual deletion
 // of a key/value pair within an existing transaction.
-func (s *Store) kvsDeleteTxn(tx *memdb.Txn, idx uint64, key string) error {
+func (s *Store) kvsDeleteTxn(tx *memdb.Txn, idx uint64, key string, retention *structs.KVSRetention) error {
 	// Look up the entry in the state store.
 	entry, err := tx.First("kvs", "id", key)
 	if err != nil {
@@ -350,6 +350,13 @@ func (s *Store) kvsDeleteTxn(tx *memdb.Txn, idx uint64, key string) error {
 		return nil
 	}
 
+	// Keep a copy of the entry so it can be restored.
+	if retention != nil {
+		if err := s.kvsRetainTxn(tx, idx, entry.(*structs.DirEntry), retention); err != nil {
+			return err
+		}
+	}
+
 	// Create a tombstone.
 	if err := s.kvsGraveyard.InsertTxn(tx, key, idx); err != nil {
 		return fmt.Errorf("failed adding to graveyard: %s", err)
@@ -370,11 +377,11 @@ func (s *Store) kvsDeleteTxn(tx *memdb.Txn, idx uint64, key string) error {
 // raft index. If the CAS index specified is not equal to the last
 // observed index for the given key, then the call is a noop, otherwise
 // a normal KV delete is invoked.
-func (s *Store) KVSDeleteCAS(idx, cidx uint64, key string) (bool, error) {
+func (s *Store) KVSDeleteCAS(idx, cidx uint64, key string, retention *structs.KVSRetention) (bool, error) {
 	tx := s.db.Txn(true)
 	defer tx.Abort()
 
-	set, err := s.kvsDeleteCASTxn(tx, idx, cidx, key)
+	set, err := s.kvsDeleteCASTxn(tx, idx, cidx, key, retention)
 	if !set || err != nil {
 		return false, err
 	}
@@ -385,7 +392,7 @@ func (s *Store) KVSDeleteCAS(idx, cidx uint64, key string) (bool, error) {
 
 // kvsDeleteCASTxn is the inner method that does a CAS delete within an existing
 // transaction.
-func (s *Store) kvsDeleteCASTxn(tx *memdb.Txn, idx, cidx uint64, key string) (bool, error) {
+func (s *Store) kvsDeleteCASTxn(tx *memdb.Txn, idx, cidx uint64, key string, retention *structs.KVSRetention) (bool, error) {
 	// Retrieve the existing kvs entry, if any exists.
 	entry, err := tx.First("kvs", "id", key)
 	if err != nil {
@@ -401,7 +408,7 @@ func (s *Store) kvsDeleteCASTxn(tx *memdb.Txn, idx, cidx uint64, key string) (bo
 	}
 
 	// Call the actual deletion if the above passed.
-	if err := s.kvsDeleteTxn(tx, idx, key); err != nil {
+	if err := s.kvsDeleteTxn(tx, idx, key, retention); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -455,12 +462,13 @@ func (s *Store) kvsSetCASTxn(tx *memdb.Txn, idx uint64, entry *structs.DirEntry)
 
 // KVSDeleteTree is used to do a recursive delete on a key prefix
 // in the state store. If any keys are modified, the last index is
-// set, otherwise this is a no-op.
-func (s *Store) KVSDeleteTree(idx uint64, prefix string) error {
+// set, otherwise this is a no-op. The deleted entries are retained if
+// retention is given.
+func (s *Store) KVSDeleteTree(idx uint64, prefix string, retention *structs.KVSRetention) error {
 	tx := s.db.Txn(true)
 	defer tx.Abort()
 
-	if err := s.kvsDeleteTreeTxn(tx, idx, prefix); err != nil {
+	if err := s.kvsDeleteTreeTxn(tx, idx, prefix, retention); err != nil {
 		return err
 	}
 
@@ -470,7 +478,25 @@ func (s *Store) KVSDeleteTree(idx uint64, prefix string) error {
 
 // kvsDeleteTreeTxn is the inner method that does a recursive delete inside an
 // existing transaction.
-func (s *Store) kvsDeleteTreeTxn(tx *memdb.Txn, idx uint64, prefix string) error {
+func (s *Store) kvsDeleteTreeTxn(tx *memdb.Txn, idx uint64, prefix string, retention *structs.KVSRetention) error {
+
+	// Keep a copy of the entries so they can be restored. This needs a pass
+	// over the subtree, which is otherwise deleted at once.
+	if retention != nil {
+		entries, err := tx.Get("kvs", "id_prefix", prefix)
+		if err != nil {
+			return fmt.Errorf("failed kvs lookup: %s", err)
+		}
+		var retained []*structs.DirEntry
+		for entry := entries.Next(); entry != nil; entry = entries.Next() {
+			retained = append(retained, entry.(*structs.DirEntry))
+		}
+		for _, entry := range retained {
+			if err := s.kvsRetainTxn(tx, idx, entry, retention); err != nil {
+				return err
+			}
+		}
+	}
 
 	// For prefix deletes, only insert one tombstone and delete the entire subtree
 
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// kvsDeletedTableSchema returns a new table schema used for retaining the KV
+// entries that were deleted, so they can be restored.
+func kvsDeletedTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "kvs-deleted",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Key",
+					Lowercase: false,
+				},
+			},
+		},
+	}
+}
+
+func init() {
+	registerSchema(kvsDeletedTableSchema)
+}
+
+// KVsDeleted is used to pull the retained deleted KV entries for use during
+// snapshots.
+func (s *Snapshot) KVsDeleted() (memdb.ResultIterator, error) {
+	iter, err := s.tx.Get("kvs-deleted", "id_prefix")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// KVSDeleted is used when restoring from a snapshot.
+func (s *Restore) KVSDeleted(entry *structs.DeletedDirEntry) error {
+	if err := s.tx.Insert("kvs-deleted", entry); err != nil {
+		return fmt.Errorf("failed inserting deleted kvs entry: %s", err)
+	}
+
+	if err := indexUpdateMaxTxn(s.tx, entry.ModifyIndex, "kvs-deleted"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// kvsRetainTxn records a KV entry that's being deleted at the given index,
+// replacing any previous deletion of the same key.
+func (s *Store) kvsRetainTxn(tx *memdb.Txn, idx uint64, entry *structs.DirEntry, retention *structs.KVSRetention) error {
+	deleted := &structs.DeletedDirEntry{
+		Key:       entry.Key,
+		Flags:     entry.Flags,
+		Value:     entry.Value,
+		DeletedAt: retention.DeletedAt,
+		DeletedBy: retention.DeletedBy,
+		RaftIndex: structs.RaftIndex{
+			CreateIndex: idx,
+			ModifyIndex: idx,
+		},
+	}
+	if err := tx.Insert("kvs-deleted", deleted); err != nil {
+		return fmt.Errorf("failed inserting deleted kvs entry: %s", err)
+	}
+	if err := tx.Insert("index", &IndexEntry{"kvs-deleted", idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// KVSDeletedGet returns the retained deletion of a key, if any.
+func (s *Store) KVSDeletedGet(ws memdb.WatchSet, key string) (uint64, *structs.DeletedDirEntry, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "kvs-deleted")
+
+	watchCh, entry, err := tx.FirstWatch("kvs-deleted", "id", key)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed deleted kvs lookup: %s", err)
+	}
+	ws.Add(watchCh)
+	if entry == nil {
+		return idx, nil, nil
+	}
+	return idx, entry.(*structs.DeletedDirEntry), nil
+}
+
+// KVSDeletedList returns the retained deletions of the keys with the given
+// prefix.
+func (s *Store) KVSDeletedList(ws memdb.WatchSet, prefix string) (uint64, structs.DeletedDirEntries, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "kvs-deleted")
+
+	iter, err := tx.Get("kvs-deleted", "id_prefix", prefix)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed deleted kvs lookup: %s", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var entries structs.DeletedDirEntries
+	for entry := iter.Next(); entry != nil; entry = iter.Next() {
+		entries = append(entries, entry.(*structs.DeletedDirEntry))
+	}
+	return idx, entries, nil
+}
+
+// ReapKVSDeleted is used to delete the retained deleted KV entries that were
+// deleted at or before the given index. The leader calls it once they're
+// older than the retention window.
+func (s *Store) ReapKVSDeleted(index uint64) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	iter, err := tx.Get("kvs-deleted", "id")
+	if err != nil {
+		return fmt.Errorf("failed deleted kvs lookup: %s", err)
+	}
+
+	// Find eligible entries, we need to do this in a separate pass since
+	// we can't delete while iterating.
+	var reaped []interface{}
+	for entry := iter.Next(); entry != nil; entry = iter.Next() {
+		if entry.(*structs.DeletedDirEntry).ModifyIndex <= index {
+			reaped = append(reaped, entry)
+		}
+	}
+	for _, entry := range reaped {
+		if err := tx.Delete("kvs-deleted", entry); err != nil {
+			return fmt.Errorf("failed to reap deleted kvs entry: %s", err)
+		}
+	}
+
+	tx.Commit()
+	return nil
+}
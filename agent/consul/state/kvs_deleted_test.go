@@ -0,0 +1,77 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore_KVSDeleted(t *testing.T) {
+	s := testStateStore(t)
+
+	testSetKey(t, s, 1, "foo", "foo")
+	testSetKey(t, s, 2, "foo/bar", "bar")
+	testSetKey(t, s, 3, "foo/baz", "baz")
+	testSetKey(t, s, 4, "zip", "zip")
+
+	// Deletes without retention aren't recorded.
+	require.NoError(t, s.KVSDelete(5, "zip", nil))
+	idx, entries, err := s.KVSDeletedList(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), idx)
+	require.Empty(t, entries)
+
+	ws := memdb.NewWatchSet()
+	_, _, err = s.KVSDeletedList(ws, "foo")
+	require.NoError(t, err)
+
+	// Each kind of delete retains the entries it deletes.
+	retention := &structs.KVSRetention{
+		DeletedAt: time.Now(),
+		DeletedBy: "e0f5ab2a-3c1a-4c2c-a2f4-4a0e3a5f6f3b",
+	}
+	require.NoError(t, s.KVSDelete(6, "foo", retention))
+	require.True(t, watchFired(ws))
+	ok, err := s.KVSDeleteCAS(7, 2, "foo/bar", retention)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, s.KVSDeleteTree(8, "foo/", retention))
+
+	idx, entries, err = s.KVSDeletedList(nil, "foo")
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), idx)
+	require.Len(t, entries, 3)
+	require.Equal(t, "foo", entries[0].Key)
+	require.Equal(t, []byte("foo"), entries[0].Value)
+	require.Equal(t, retention.DeletedBy, entries[0].DeletedBy)
+	require.Equal(t, uint64(6), entries[0].ModifyIndex)
+	require.Equal(t, "foo/bar", entries[1].Key)
+	require.Equal(t, uint64(7), entries[1].ModifyIndex)
+	require.Equal(t, "foo/baz", entries[2].Key)
+	require.Equal(t, []byte("baz"), entries[2].Value)
+	require.Equal(t, uint64(8), entries[2].ModifyIndex)
+
+	// Only the last deletion of a key is kept.
+	testSetKey(t, s, 9, "foo", "again")
+	require.NoError(t, s.KVSDelete(10, "foo", retention))
+	idx, entry, err := s.KVSDeletedGet(nil, "foo")
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), idx)
+	require.Equal(t, []byte("again"), entry.Value)
+	require.Equal(t, uint64(10), entry.ModifyIndex)
+
+	_, entry, err = s.KVSDeletedGet(nil, "nope")
+	require.NoError(t, err)
+	require.Nil(t, entry)
+
+	// Reaping removes the entries deleted up to the index.
+	require.NoError(t, s.ReapKVSDeleted(7))
+	_, entries, err = s.KVSDeletedList(nil, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "foo", entries[0].Key)
+	require.Equal(t, "foo/baz", entries[1].Key)
+}
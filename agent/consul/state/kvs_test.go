@@ -34,7 +34,7 @@ func TestStateStore_GC(t *testing.T) {
 	testSetKey(t, s, 5, "foo/zoo", "bar")
 
 	// Delete a key and make sure the GC sees it.
-	if err := s.KVSDelete(6, "foo/zoo"); err != nil {
+	if err := s.KVSDelete(6, "foo/zoo", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	select {
@@ -47,7 +47,7 @@ func TestStateStore_GC(t *testing.T) {
 	}
 
 	// Check for the same behavior with a tree delete.
-	if err := s.KVSDeleteTree(7, "foo/moo"); err != nil {
+	if err := s.KVSDeleteTree(7, "foo/moo", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	select {
@@ -60,7 +60,7 @@ func TestStateStore_GC(t *testing.T) {
 	}
 
 	// Check for the same behavior with a CAS delete.
-	if ok, err := s.KVSDeleteCAS(8, 3, "foo/baz"); !ok || err != nil {
+	if ok, err := s.KVSDeleteCAS(8, 3, "foo/baz", nil); !ok || err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	select {
@@ -113,10 +113,10 @@ func TestStateStore_ReapTombstones(t *testing.T) {
 	testSetKey(t, s, 5, "foo/zoo", "bar")
 
 	// Call a delete on some specific keys.
-	if err := s.KVSDelete(6, "foo/baz"); err != nil {
+	if err := s.KVSDelete(6, "foo/baz", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
-	if err := s.KVSDelete(7, "foo/moo"); err != nil {
+	if err := s.KVSDelete(7, "foo/moo", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
@@ -416,7 +416,7 @@ func TestStateStore_KVSList(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
-	if err := s.KVSDelete(6, "foo/bar/baz"); err != nil {
+	if err := s.KVSDelete(6, "foo/bar/baz", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	if !watchFired(ws) {
@@ -542,7 +542,7 @@ func TestStateStore_KVSListKeys(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
-	if err := s.KVSDelete(8, "foo/bar/baz"); err != nil {
+	if err := s.KVSDelete(8, "foo/bar/baz", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	if !watchFired(ws) {
@@ -604,7 +604,7 @@ func TestStateStore_KVSDelete(t *testing.T) {
 	testSetKey(t, s, 2, "foo/bar", "bar")
 
 	// Call a delete on a specific key
-	if err := s.KVSDelete(3, "foo"); err != nil {
+	if err := s.KVSDelete(3, "foo", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
@@ -658,7 +658,7 @@ func TestStateStore_KVSDelete(t *testing.T) {
 
 	// Deleting a nonexistent key should be idempotent and not return an
 	// error
-	if err := s.KVSDelete(4, "foo"); err != nil {
+	if err := s.KVSDelete(4, "foo", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	if idx := s.maxIndex("kvs"); idx != 3 {
@@ -675,7 +675,7 @@ func TestStateStore_KVSDeleteCAS(t *testing.T) {
 	testSetKey(t, s, 3, "baz", "baz")
 
 	// Do a CAS delete with an index lower than the entry
-	ok, err := s.KVSDeleteCAS(4, 1, "bar")
+	ok, err := s.KVSDeleteCAS(4, 1, "bar", nil)
 	if ok || err != nil {
 		t.Fatalf("expected (false, nil), got: (%v, %#v)", ok, err)
 	}
@@ -695,7 +695,7 @@ func TestStateStore_KVSDeleteCAS(t *testing.T) {
 
 	// Do another CAS delete, this time with the correct index
 	// which should cause the delete to take place.
-	ok, err = s.KVSDeleteCAS(4, 2, "bar")
+	ok, err = s.KVSDeleteCAS(4, 2, "bar", nil)
 	if !ok || err != nil {
 		t.Fatalf("expected (true, nil), got: (%v, %#v)", ok, err)
 	}
@@ -740,7 +740,7 @@ func TestStateStore_KVSDeleteCAS(t *testing.T) {
 
 	// A delete on a nonexistent key should be idempotent and not return an
 	// error
-	ok, err = s.KVSDeleteCAS(6, 2, "bar")
+	ok, err = s.KVSDeleteCAS(6, 2, "bar", nil)
 	if !ok || err != nil {
 		t.Fatalf("expected (true, nil), got: (%v, %#v)", ok, err)
 	}
@@ -960,7 +960,7 @@ func TestStateStore_KVSDeleteTree(t *testing.T) {
 
 	// Calling tree deletion which affects nothing does not
 	// modify the table index.
-	if err := s.KVSDeleteTree(9, "bar"); err != nil {
+	if err := s.KVSDeleteTree(9, "bar", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 	if idx := s.maxIndex("kvs"); idx != 4 {
@@ -968,7 +968,7 @@ func TestStateStore_KVSDeleteTree(t *testing.T) {
 	}
 
 	// Call tree deletion with a nested prefix.
-	if err := s.KVSDeleteTree(5, "foo/bar"); err != nil {
+	if err := s.KVSDeleteTree(5, "foo/bar", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
@@ -1044,7 +1044,7 @@ func TestStateStore_Watches_PrefixDelete(t *testing.T) {
 	}
 
 	// Delete a key and make sure the index comes from the tombstone.
-	if err := s.KVSDeleteTree(7, "foo/bar/zip"); err != nil {
+	if err := s.KVSDeleteTree(7, "foo/bar/zip", nil); err != nil {
 		t.Fatalf("unexpected err: %s", err)
 	}
 	// Make sure watch fires
@@ -1109,7 +1109,7 @@ func TestStateStore_Watches_PrefixDelete(t *testing.T) {
 	}
 
 	// Delete all the keys, special case where tombstones are not inserted
-	if err := s.KVSDeleteTree(9, ""); err != nil {
+	if err := s.KVSDeleteTree(9, "", nil); err != nil {
 		t.Fatalf("unexpected err: %s", err)
 	}
 	wantIndex = 9
@@ -1482,7 +1482,7 @@ func TestStateStore_Tombstone_Snapshot_Restore(t *testing.T) {
 	testSetKey(t, s, 1, "foo/bar", "bar")
 	testSetKey(t, s, 2, "foo/bar/baz", "bar")
 	testSetKey(t, s, 3, "foo/bar/zoo", "bar")
-	if err := s.KVSDelete(4, "foo/bar"); err != nil {
+	if err := s.KVSDelete(4, "foo/bar", nil); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
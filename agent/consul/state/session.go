@@ -376,7 +376,7 @@ func (s *Store) deleteSessionTxn(tx *memdb.Txn, idx uint64, sessionID string) er
 	case structs.SessionKeysDelete:
 		for _, obj := range kvs {
 			e := obj.(*structs.DirEntry)
-			if err := s.kvsDeleteTxn(tx, idx, e.Key); err != nil {
+			if err := s.kvsDeleteTxn(tx, idx, e.Key, nil); err != nil {
 				return fmt.Errorf("failed kvs delete: %s", err)
 			}
 
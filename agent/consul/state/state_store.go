@@ -79,6 +79,10 @@ type Store struct {
 
 	// lockDelay holds expiration times for locks associated with keys.
 	lockDelay *Delay
+
+	// healthHistory records recent health check status transitions, it is
+	// not persisted and is nil unless set with SetHealthHistory.
+	healthHistory *HealthHistory
 }
 
 // Snapshot is used to provide a point-in-time snapshot. It
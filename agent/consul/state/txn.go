@@ -19,17 +19,17 @@ func (s *Store) txnKVS(tx *memdb.Txn, idx uint64, op *structs.TxnKVOp) (structs.
 		err = s.kvsSetTxn(tx, idx, entry, false)
 
 	case api.KVDelete:
-		err = s.kvsDeleteTxn(tx, idx, op.DirEnt.Key)
+		err = s.kvsDeleteTxn(tx, idx, op.DirEnt.Key, op.Retention)
 
 	case api.KVDeleteCAS:
 		var ok bool
-		ok, err = s.kvsDeleteCASTxn(tx, idx, op.DirEnt.ModifyIndex, op.DirEnt.Key)
+		ok, err = s.kvsDeleteCASTxn(tx, idx, op.DirEnt.ModifyIndex, op.DirEnt.Key, op.Retention)
 		if !ok && err == nil {
 			err = fmt.Errorf("failed to delete key %q, index is stale", op.DirEnt.Key)
 		}
 
 	case api.KVDeleteTree:
-		err = s.kvsDeleteTreeTxn(tx, idx, op.DirEnt.Key)
+		err = s.kvsDeleteTreeTxn(tx, idx, op.DirEnt.Key, op.Retention)
 
 	case api.KVCAS:
 		var ok bool
@@ -93,8 +93,8 @@ func (s *Store) txnKVS(tx *memdb.Txn, idx uint64, op *structs.TxnKVOp) (structs.
 	}
 
 	// For a GET we keep the value, otherwise we clone and blank out the
-	// value (we have to clone so we don't modify the entry being used by
-	// the state store).
+	// value and its checksum (we have to clone so we don't modify the entry
+	// being used by the state store).
 	if entry != nil {
 		if op.Verb == api.KVGet {
 			result := structs.TxnResult{KV: entry}
@@ -103,6 +103,7 @@ func (s *Store) txnKVS(tx *memdb.Txn, idx uint64, op *structs.TxnKVOp) (structs.
 
 		clone := entry.Clone()
 		clone.Value = nil
+		clone.Checksum = ""
 		result := structs.TxnResult{KV: clone}
 		return structs.TxnResults{&result}, nil
 	}
@@ -143,14 +144,22 @@ func (s *Store) txnNode(tx *memdb.Txn, idx uint64, op *structs.TxnNodeOp) (struc
 		}
 
 	case api.NodeSet:
-		err = s.ensureNodeTxn(tx, idx, &op.Node)
+		var node *structs.Node
+		if node, err = txnNodeSyncOwner(tx, op); err != nil {
+			break
+		}
+		err = s.ensureNodeTxn(tx, idx, node)
 		if err == nil {
 			entry, err = getNode()
 		}
 
 	case api.NodeCAS:
+		var node *structs.Node
+		if node, err = txnNodeSyncOwner(tx, op); err != nil {
+			break
+		}
 		var ok bool
-		ok, err = s.ensureNodeCASTxn(tx, idx, &op.Node)
+		ok, err = s.ensureNodeCASTxn(tx, idx, node)
 		if !ok && err == nil {
 			err = fmt.Errorf("failed to set node %q, index is stale", op.Node.Node)
 			break
@@ -158,9 +167,19 @@ func (s *Store) txnNode(tx *memdb.Txn, idx uint64, op *structs.TxnNodeOp) (struc
 		entry, err = getNode()
 
 	case api.NodeDelete:
+		if op.CheckSyncOwner {
+			if err = syncOwnerTxn(tx, op.Node.Node, op.Node.SyncOwner, op.ForceSyncOwner); err != nil {
+				break
+			}
+		}
 		err = s.deleteNodeTxn(tx, idx, op.Node.Node)
 
 	case api.NodeDeleteCAS:
+		if op.CheckSyncOwner {
+			if err = syncOwnerTxn(tx, op.Node.Node, op.Node.SyncOwner, op.ForceSyncOwner); err != nil {
+				break
+			}
+		}
 		var ok bool
 		ok, err = s.deleteNodeCASTxn(tx, idx, op.Node.ModifyIndex, op.Node.Node)
 		if !ok && err == nil {
@@ -191,11 +210,38 @@ func (s *Store) txnNode(tx *memdb.Txn, idx uint64, op *structs.TxnNodeOp) (struc
 	return nil, nil
 }
 
+// txnNodeSyncOwner returns the node to write for a node op. Ops made through
+// the API must come from the sync owner of the node, if any, and the others
+// keep the current owner the same way as in EnsureRegistration.
+func txnNodeSyncOwner(tx *memdb.Txn, op *structs.TxnNodeOp) (*structs.Node, error) {
+	owner, err := nodeSyncOwnerTxn(tx, op.Node.Node)
+	if err != nil {
+		return nil, err
+	}
+	node := op.Node
+	if op.CheckSyncOwner {
+		if err := vetSyncOwner(owner, node.SyncOwner, op.ForceSyncOwner); err != nil {
+			return nil, err
+		}
+	} else {
+		node.SyncOwner = owner
+	}
+	return &node, nil
+}
+
 // txnService handles all Service-related operations.
 func (s *Store) txnService(tx *memdb.Txn, idx uint64, op *structs.TxnServiceOp) (structs.TxnResults, error) {
 	var entry *structs.NodeService
 	var err error
 
+	// Writes made through the API must come from the sync owner of the
+	// node, if any.
+	if op.Verb != api.ServiceGet && op.CheckSyncOwner {
+		if err := syncOwnerTxn(tx, op.Node, op.SyncOwner, op.ForceSyncOwner); err != nil {
+			return nil, err
+		}
+	}
+
 	switch op.Verb {
 	case api.ServiceGet:
 		entry, err = s.getNodeServiceTxn(tx, op.Node, op.Service.ID)
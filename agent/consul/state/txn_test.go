@@ -360,6 +360,140 @@ func TestStateStore_Txn_Service(t *testing.T) {
 	verify.Values(t, "", actual, expectedServices)
 }
 
+func TestStateStore_Txn_SyncOwner(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	require.NoError(s.EnsureRegistration(1, &structs.RegisterRequest{
+		Node:           "node1",
+		Address:        "1.2.3.4",
+		SyncOwner:      "sync-a",
+		CheckSyncOwner: true,
+		Service: &structs.NodeService{
+			ID:      "service1",
+			Service: "service1",
+		},
+	}))
+
+	// Internal node writes keep the owner of the node.
+	_, errors := s.TxnRW(2, structs.TxnOps{
+		&structs.TxnOp{
+			Node: &structs.TxnNodeOp{
+				Verb: api.NodeSet,
+				Node: structs.Node{Node: "node1", Address: "1.2.3.5"},
+			},
+		},
+	})
+	require.Empty(errors)
+	_, node, err := s.GetNode("node1")
+	require.NoError(err)
+	require.Equal("sync-a", node.SyncOwner)
+	require.Equal("1.2.3.5", node.Address)
+
+	// The writes made through the API can't clear the owner or get around
+	// it, whatever the verb.
+	ops := []*structs.TxnOp{
+		{
+			Node: &structs.TxnNodeOp{
+				Verb:           api.NodeSet,
+				Node:           structs.Node{Node: "node1", Address: "1.2.3.6"},
+				CheckSyncOwner: true,
+			},
+		},
+		{
+			Node: &structs.TxnNodeOp{
+				Verb: api.NodeCAS,
+				Node: structs.Node{
+					Node:      "node1",
+					Address:   "1.2.3.6",
+					SyncOwner: "sync-b",
+					RaftIndex: structs.RaftIndex{ModifyIndex: 2},
+				},
+				CheckSyncOwner: true,
+			},
+		},
+		{
+			Node: &structs.TxnNodeOp{
+				Verb:           api.NodeDelete,
+				Node:           structs.Node{Node: "node1", SyncOwner: "sync-b"},
+				CheckSyncOwner: true,
+			},
+		},
+		{
+			Node: &structs.TxnNodeOp{
+				Verb: api.NodeDeleteCAS,
+				Node: structs.Node{
+					Node:      "node1",
+					RaftIndex: structs.RaftIndex{ModifyIndex: 2},
+				},
+				CheckSyncOwner: true,
+			},
+		},
+		{
+			Service: &structs.TxnServiceOp{
+				Verb:           api.ServiceSet,
+				Node:           "node1",
+				Service:        structs.NodeService{ID: "service2", Service: "service2"},
+				CheckSyncOwner: true,
+			},
+		},
+		{
+			Service: &structs.TxnServiceOp{
+				Verb:           api.ServiceDelete,
+				Node:           "node1",
+				Service:        structs.NodeService{ID: "service1"},
+				SyncOwner:      "sync-b",
+				CheckSyncOwner: true,
+			},
+		},
+		{
+			Service: &structs.TxnServiceOp{
+				Verb:           api.ServiceDeleteCAS,
+				Node:           "node1",
+				Service:        structs.NodeService{ID: "service1", RaftIndex: structs.RaftIndex{ModifyIndex: 1}},
+				CheckSyncOwner: true,
+			},
+		},
+	}
+	for i, op := range ops {
+		_, errors := s.TxnRW(uint64(3+i), structs.TxnOps{op})
+		require.Len(errors, 1, "op %d", i)
+		require.True(structs.IsErrSyncOwnerConflict(errors[0]), "op %d: %v", i, errors[0])
+	}
+	_, ns, err := s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Equal("sync-a", ns.Node.SyncOwner)
+	require.Equal("1.2.3.5", ns.Node.Address)
+	require.Len(ns.Services, 1)
+	require.Contains(ns.Services, "service1")
+
+	// The owner can write, and forcing the owner takes the node over.
+	_, errors = s.TxnRW(20, structs.TxnOps{
+		&structs.TxnOp{
+			Service: &structs.TxnServiceOp{
+				Verb:           api.ServiceSet,
+				Node:           "node1",
+				Service:        structs.NodeService{ID: "service2", Service: "service2"},
+				SyncOwner:      "sync-a",
+				CheckSyncOwner: true,
+			},
+		},
+		&structs.TxnOp{
+			Node: &structs.TxnNodeOp{
+				Verb:           api.NodeSet,
+				Node:           structs.Node{Node: "node1", Address: "1.2.3.5", SyncOwner: "sync-b"},
+				ForceSyncOwner: true,
+				CheckSyncOwner: true,
+			},
+		},
+	})
+	require.Empty(errors)
+	_, ns, err = s.NodeServices(nil, "node1")
+	require.NoError(err)
+	require.Equal("sync-b", ns.Node.SyncOwner)
+	require.Len(ns.Services, 2)
+}
+
 func TestStateStore_Txn_Checks(t *testing.T) {
 	require := require.New(t)
 	s := testStateStore(t)
@@ -3,10 +3,18 @@ package consul
 import (
 	"fmt"
 	"strconv"
+	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/agent/consul/autopilot"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib"
 )
 
+// statusPeersPollInterval is how often a blocking peers query checks the
+// Raft configuration, since Raft doesn't report configuration changes.
+const statusPeersPollInterval = time.Second
+
 // Status endpoint is used to check on server status
 type Status struct {
 	server *Server
@@ -41,6 +49,117 @@ func (s *Status) Peers(args struct{}, reply *[]string) error {
 	return nil
 }
 
+// BlockingLeader is used to get the address of the leader. Unlike Leader, it
+// supports blocking queries that return once the leader changes.
+func (s *Status) BlockingLeader(args *structs.StatusRequest, reply *structs.StatusLeaderResponse) error {
+	return s.blockingQuery(&args.QueryOptions, &reply.QueryMeta, 0,
+		func() (uint64, error) {
+			// Read the term before the leader, so a change in between
+			// makes the leader newer than the index and not the opposite.
+			term, err := strconv.ParseUint(s.server.raft.Stats()["term"], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("error parsing server's term value: %s", err)
+			}
+			reply.Leader = string(s.server.raft.Leader())
+
+			// The term only moves forward, but a server can learn who the
+			// leader is without a new term, so knowing the leader counts
+			// as a step of its own.
+			index := term << 1
+			if reply.Leader != "" {
+				index++
+			}
+			return index, nil
+		})
+}
+
+// BlockingPeers is used to get all the Raft peers. Unlike Peers, it supports
+// blocking queries that return once the Raft configuration changes.
+func (s *Status) BlockingPeers(args *structs.StatusRequest, reply *structs.StatusPeersResponse) error {
+	return s.blockingQuery(&args.QueryOptions, &reply.QueryMeta, statusPeersPollInterval,
+		func() (uint64, error) {
+			future := s.server.raft.GetConfiguration()
+			if err := future.Error(); err != nil {
+				return 0, err
+			}
+
+			reply.Peers = nil
+			for _, server := range future.Configuration().Servers {
+				reply.Peers = append(reply.Peers, string(server.Address))
+			}
+			return future.Index(), nil
+		})
+}
+
+// blockingQuery runs fn until it returns an index past the one the query is
+// waiting for. The Raft status doesn't live in the state store, so this is
+// woken by leader changes instead of watch sets, and by polling every poll
+// interval when that's not zero.
+func (s *Status) blockingQuery(queryOpts *structs.QueryOptions, queryMeta *structs.QueryMeta,
+	poll time.Duration, fn func() (uint64, error)) error {
+	var timeout <-chan time.Time
+	if queryOpts.MinQueryIndex > 0 {
+		s.server.recordUsage(queryOpts.Token, true)
+
+		// Restrict the max query time, and ensure there is always one.
+		if queryOpts.MaxQueryTime > maxQueryTime {
+			queryOpts.MaxQueryTime = maxQueryTime
+		} else if queryOpts.MaxQueryTime <= 0 {
+			queryOpts.MaxQueryTime = defaultQueryTime
+		}
+		queryOpts.MaxQueryTime += lib.RandomStagger(queryOpts.MaxQueryTime / jitterFraction)
+
+		timer := time.NewTimer(queryOpts.MaxQueryTime)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		// Grab the watch before running the query so we can't miss a
+		// change that happens while it runs.
+		changeCh := s.server.leaderChangeWatch()
+
+		s.server.setQueryMeta(queryMeta)
+		metrics.IncrCounter([]string{"rpc", "query"}, 1)
+		index, err := fn()
+		if err != nil {
+			return err
+		}
+
+		// Make sure we never return a zero index, otherwise the client
+		// will never block.
+		if index < 1 {
+			index = 1
+		}
+		queryMeta.Index = index
+		if queryOpts.MinQueryIndex == 0 || index > queryOpts.MinQueryIndex {
+			return nil
+		}
+
+		var pollTimer *time.Timer
+		var pollCh <-chan time.Time
+		if poll > 0 {
+			pollTimer = time.NewTimer(poll)
+			pollCh = pollTimer.C
+		}
+		expired := false
+		select {
+		case <-changeCh:
+		case <-pollCh:
+		case <-timeout:
+			expired = true
+		case <-s.server.shutdownCh:
+			expired = true
+		}
+		if pollTimer != nil {
+			pollTimer.Stop()
+		}
+		if expired {
+			return nil
+		}
+	}
+}
+
 // Used by Autopilot to query the raft stats of the local server.
 func (s *Status) RaftStats(args struct{}, reply *autopilot.ServerStats) error {
 	stats := s.server.raft.Stats()
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/agent/pool"
+	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 )
@@ -68,3 +69,146 @@ func TestStatusPeers(t *testing.T) {
 		t.Fatalf("no peers: %v", peers)
 	}
 }
+
+func TestStatus_BlockingLeader(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerDCExpect(t, "dc1", 2)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	dir2, s2 := testServerDCExpect(t, "dc1", 2)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	// There's no leader until the servers join.
+	args := structs.StatusRequest{}
+	var out structs.StatusLeaderResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Status.BlockingLeader", &args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Leader != "" {
+		t.Fatalf("unexpected leader: %v", out.Leader)
+	}
+	if out.Index == 0 {
+		t.Fatalf("bad index: %d", out.Index)
+	}
+
+	// Block until there's a leader, which has to go through at least one
+	// leadership change.
+	type result struct {
+		out structs.StatusLeaderResponse
+		err error
+	}
+	doneCh := make(chan result, 1)
+	go func() {
+		index := out.Index
+		for {
+			args := structs.StatusRequest{
+				QueryOptions: structs.QueryOptions{
+					MinQueryIndex: index,
+					MaxQueryTime:  10 * time.Second,
+				},
+			}
+			var reply structs.StatusLeaderResponse
+			err := msgpackrpc.CallWithCodec(codec, "Status.BlockingLeader", &args, &reply)
+			if err != nil || reply.Index <= index || reply.Leader != "" {
+				doneCh <- result{reply, err}
+				return
+			}
+			index = reply.Index
+		}
+	}()
+
+	joinLAN(t, s2, s1)
+
+	select {
+	case res := <-doneCh:
+		if res.err != nil {
+			t.Fatalf("err: %v", res.err)
+		}
+		if res.out.Index <= out.Index {
+			t.Fatalf("bad index: %d <= %d", res.out.Index, out.Index)
+		}
+		if res.out.Leader == "" {
+			t.Fatalf("no leader")
+		}
+		out = res.out
+	case <-time.After(10 * time.Second):
+		t.Fatalf("blocking query didn't return")
+	}
+
+	// Without a leader change, a blocking query runs until it times out.
+	args = structs.StatusRequest{
+		QueryOptions: structs.QueryOptions{
+			MinQueryIndex: out.Index,
+			MaxQueryTime:  100 * time.Millisecond,
+		},
+	}
+	var reply structs.StatusLeaderResponse
+	start := time.Now()
+	if err := msgpackrpc.CallWithCodec(codec, "Status.BlockingLeader", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.Index == out.Index && time.Since(start) < 100*time.Millisecond {
+		t.Fatalf("blocking query returned too early")
+	}
+}
+
+func TestStatus_BlockingPeers(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	dir2, s2 := testServerDCBootstrap(t, "dc1", false)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	args := structs.StatusRequest{}
+	var out structs.StatusPeersResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Status.BlockingPeers", &args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Peers) != 1 {
+		t.Fatalf("bad peers: %v", out.Peers)
+	}
+
+	// Block until the second server is added to the configuration.
+	type result struct {
+		out structs.StatusPeersResponse
+		err error
+	}
+	doneCh := make(chan result, 1)
+	go func() {
+		args := structs.StatusRequest{
+			QueryOptions: structs.QueryOptions{
+				MinQueryIndex: out.Index,
+				MaxQueryTime:  10 * time.Second,
+			},
+		}
+		var reply structs.StatusPeersResponse
+		err := msgpackrpc.CallWithCodec(codec, "Status.BlockingPeers", &args, &reply)
+		doneCh <- result{reply, err}
+	}()
+
+	joinLAN(t, s2, s1)
+
+	select {
+	case res := <-doneCh:
+		if res.err != nil {
+			t.Fatalf("err: %v", res.err)
+		}
+		if res.out.Index <= out.Index {
+			t.Fatalf("bad index: %d <= %d", res.out.Index, out.Index)
+		}
+		if len(res.out.Peers) != 2 {
+			t.Fatalf("bad peers: %v", res.out.Peers)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("blocking query didn't return")
+	}
+}
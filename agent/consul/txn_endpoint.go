@@ -74,6 +74,14 @@ func (t *Txn) preCheck(authorizer acl.Authorizer, ops structs.TxnOps) structs.Tx
 				break
 			}
 
+			// Copy the service before normalizing its tags, an in-memory RPC
+			// shares it with the caller.
+			if t.srv.config.NormalizeServiceTags {
+				serviceOp := *op.Service
+				serviceOp.Service.Tags = structs.NormalizeTags(serviceOp.Service.Tags)
+				op.Service = &serviceOp
+			}
+
 			// Check that the token has permissions for the given operation.
 			if err := vetServiceTxnOp(op.Service, authorizer); err != nil {
 				errors = append(errors, &structs.TxnError{
@@ -119,12 +127,24 @@ func (t *Txn) Apply(args *structs.TxnRequest, reply *structs.TxnResponse) error
 		return nil
 	}
 
+	// Stamp the KV deletes so the deleted entries can be retained.
+	var retained bool
+	for _, op := range args.Ops {
+		if op.KV != nil {
+			op.KV.Retention = t.srv.kvsRetention(op.KV.Verb, args.Token)
+			retained = retained || op.KV.Retention != nil
+		}
+	}
+
 	// Apply the update.
 	resp, err := t.srv.raftApply(structs.TxnRequestType, args)
 	if err != nil {
 		t.srv.logger.Printf("[ERR] consul.txn: Apply failed: %v", err)
 		return err
 	}
+	if retained {
+		t.srv.hintKVSDeleted()
+	}
 	if respErr, ok := resp.(error); ok {
 		return respErr
 	}
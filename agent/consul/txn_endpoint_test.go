@@ -298,6 +298,57 @@ func TestTxn_Apply(t *testing.T) {
 	verify.Values(t, "", out, expected)
 }
 
+func TestTxn_Apply_NormalizeServiceTags(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.NormalizeServiceTags = true
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	tags := []string{"slave", "master", "slave"}
+	arg := structs.TxnRequest{
+		Datacenter: "dc1",
+		Ops: structs.TxnOps{
+			&structs.TxnOp{
+				Node: &structs.TxnNodeOp{
+					Verb: api.NodeSet,
+					Node: structs.Node{
+						ID:      types.NodeID(testNodeID),
+						Node:    "foo",
+						Address: "127.0.0.1",
+					},
+				},
+			},
+			&structs.TxnOp{
+				Service: &structs.TxnServiceOp{
+					Verb: api.ServiceSet,
+					Node: "foo",
+					Service: structs.NodeService{
+						ID:      "db",
+						Service: "db",
+						Tags:    tags,
+					},
+				},
+			},
+		},
+	}
+	service := &arg.Ops[1].Service.Service
+
+	// The request is made in memory, so the server shares it with us.
+	var out structs.TxnResponse
+	require.NoError(t, s1.RPC("Txn.Apply", &arg, &out))
+	require.Empty(t, out.Errors)
+
+	// The tags are normalized in the catalog but not in the request.
+	require.Equal(t, []string{"slave", "master", "slave"}, service.Tags)
+	_, ns, err := s1.fsm.State().NodeServices(nil, "foo")
+	require.NoError(t, err)
+	require.Equal(t, []string{"master", "slave"}, ns.Services["db"].Tags)
+}
+
 func TestTxn_Apply_ACLDeny(t *testing.T) {
 	t.Parallel()
 
@@ -991,3 +1042,87 @@ func TestTxn_Read_ACLDeny(t *testing.T) {
 		t.Fatalf("bad %v", out)
 	}
 }
+
+func TestTxn_Read_ACLEnableKeyListPolicy(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLEnableKeyListPolicy = true
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	state := s1.fsm.State()
+	for i, key := range []string{"bar/bar1", "zip/zip1"} {
+		require.NoError(state.KVSSet(uint64(i+1), &structs.DirEntry{Key: key}))
+	}
+
+	var id string
+	{
+		arg := structs.ACLRequest{
+			Datacenter: "dc1",
+			Op:         structs.ACLSet,
+			ACL: structs.ACL{
+				Name: "User token",
+				Type: structs.ACLTokenTypeClient,
+				Rules: `
+key "" {
+	policy = "deny"
+}
+key "bar" {
+	policy = "list"
+}
+key "zip" {
+	policy = "read"
+}
+`,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.Apply", &arg, &id))
+	}
+
+	// Reading the trees needs the list policy, but reading the keys doesn't.
+	arg := structs.TxnReadRequest{
+		Datacenter: "dc1",
+		Ops: structs.TxnOps{
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb:   api.KVGetTree,
+					DirEnt: structs.DirEntry{Key: "bar"},
+				},
+			},
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb:   api.KVGetTree,
+					DirEnt: structs.DirEntry{Key: "zip"},
+				},
+			},
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb:   api.KVGet,
+					DirEnt: structs.DirEntry{Key: "zip/zip1"},
+				},
+			},
+		},
+		QueryOptions: structs.QueryOptions{Token: id},
+	}
+	var out structs.TxnReadResponse
+	require.NoError(msgpackrpc.CallWithCodec(codec, "Txn.Read", &arg, &out))
+	require.Equal(structs.TxnErrors{
+		&structs.TxnError{
+			OpIndex: 1,
+			What:    acl.ErrPermissionDenied.Error(),
+		},
+	}, out.Errors)
+}
@@ -0,0 +1,232 @@
+package consul
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const (
+	// usageMinLogSamples is the number of sampled requests a token has to make
+	// over an interval to be logged, so a server that is mostly idle doesn't
+	// log its only client.
+	usageMinLogSamples = 10
+)
+
+// usageCount is the number of sampled requests made with a token over the
+// current interval.
+type usageCount struct {
+	description string
+	rpc         uint64
+	blocking    uint64
+}
+
+// usageResolver resolves the secret of a token to its accessor ID and
+// description, returning false if the token can't be resolved.
+type usageResolver func(secretID string) (accessorID, description string, ok bool)
+
+// usageTracker attributes a sample of the requests handled by a server to the
+// accessor ID of their token, and keeps the tokens that made the most of them
+// over the last completed interval. Requests are counted by token secret, and
+// the secrets are only resolved when the interval ends, so sampling a request
+// stays cheap.
+type usageTracker struct {
+	rate float64
+	topK int
+
+	l      sync.Mutex
+	start  time.Time
+	counts map[string]*usageCount
+	last   *structs.UsageTopClientsResponse
+}
+
+// newUsageTracker returns a tracker sampling requests at the given rate and
+// keeping the top topK tokens.
+func newUsageTracker(rate float64, topK int) *usageTracker {
+	return &usageTracker{
+		rate:   rate,
+		topK:   topK,
+		start:  time.Now(),
+		counts: make(map[string]*usageCount),
+	}
+}
+
+// sample returns true if a request should be attributed.
+func (u *usageTracker) sample() bool {
+	return u.rate >= 1 || rand.Float64() < u.rate
+}
+
+// record counts a sampled request made with the given token secret. A
+// blocking query is recorded twice, once as an RPC and once as a blocking
+// query.
+func (u *usageTracker) record(secretID string, blocking bool) {
+	u.l.Lock()
+	defer u.l.Unlock()
+
+	c, ok := u.counts[secretID]
+	if !ok {
+		c = &usageCount{}
+		u.counts[secretID] = c
+	}
+	if blocking {
+		c.blocking++
+	} else {
+		c.rpc++
+	}
+}
+
+// rotate ends the current interval and returns its report, which is kept
+// until the next call. The token secrets counted over the interval are
+// resolved with the given resolver, outside of the lock, and the requests
+// made with tokens that can't be resolved aren't counted.
+func (u *usageTracker) rotate(server string, resolve usageResolver) *structs.UsageTopClientsResponse {
+	u.l.Lock()
+	start, bySecret := u.start, u.counts
+	now := time.Now()
+	u.start = now
+	u.counts = make(map[string]*usageCount)
+	u.l.Unlock()
+
+	// Different secrets can resolve to the same identity, such as the
+	// anonymous token, so the counts are merged by accessor ID.
+	counts := make(map[string]*usageCount)
+	var rpc, blocking uint64
+	for secretID, sc := range bySecret {
+		accessorID, description, ok := resolve(secretID)
+		if !ok {
+			continue
+		}
+		c, ok := counts[accessorID]
+		if !ok {
+			c = &usageCount{description: description}
+			counts[accessorID] = c
+		}
+		c.rpc += sc.rpc
+		c.blocking += sc.blocking
+		rpc += sc.rpc
+		blocking += sc.blocking
+	}
+
+	report := &structs.UsageTopClientsResponse{
+		Server:          server,
+		Start:           start,
+		End:             now,
+		SampleRate:      u.rate,
+		RPC:             u.top(counts, rpc, func(c *usageCount) uint64 { return c.rpc }),
+		BlockingQueries: u.top(counts, blocking, func(c *usageCount) uint64 { return c.blocking }),
+	}
+
+	u.l.Lock()
+	u.last = report
+	u.l.Unlock()
+	return report
+}
+
+// top returns the topK tokens with the largest counts, given the counts by
+// accessor ID and their total.
+func (u *usageTracker) top(counts map[string]*usageCount, total uint64, count func(*usageCount) uint64) []*structs.UsageClient {
+	var clients []*structs.UsageClient
+	if total == 0 {
+		return clients
+	}
+	for accessorID, c := range counts {
+		n := count(c)
+		if n == 0 {
+			continue
+		}
+		clients = append(clients, &structs.UsageClient{
+			AccessorID:  accessorID,
+			Description: c.description,
+			Count:       uint64(float64(n) / u.rate),
+			Share:       float64(n) / float64(total),
+		})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Count != clients[j].Count {
+			return clients[i].Count > clients[j].Count
+		}
+		return clients[i].AccessorID < clients[j].AccessorID
+	})
+	if len(clients) > u.topK {
+		clients = clients[:u.topK]
+	}
+	return clients
+}
+
+// report returns the report of the last completed interval, or nil if there
+// is none yet.
+func (u *usageTracker) report() *structs.UsageTopClientsResponse {
+	u.l.Lock()
+	defer u.l.Unlock()
+	return u.last
+}
+
+// recordUsage counts a request handled by this server against its token, if
+// usage tracking is enabled and the request is sampled. The token is only
+// resolved to its accessor ID when the interval ends, by resolveUsage.
+func (s *Server) recordUsage(token string, blocking bool) {
+	if s.usage == nil || !s.ACLsEnabled() || !s.usage.sample() {
+		return
+	}
+
+	if token == "" {
+		token = anonymousToken
+	}
+	s.usage.record(token, blocking)
+}
+
+// resolveUsage resolves a token counted by recordUsage to its accessor ID and
+// description. Requests whose token can't be resolved aren't counted.
+func (s *Server) resolveUsage(token string) (string, string, bool) {
+	identity, err := s.acls.resolveIdentityFromToken(token)
+	if err != nil || identity == nil {
+		return "", "", false
+	}
+	description := ""
+	if t, ok := identity.(*structs.ACLToken); ok {
+		description = t.Description
+	}
+	return identity.ID(), description, true
+}
+
+// usageStats ends a usage interval every ACLUsageInterval, to report the
+// requests made by the tokens that made the most of them as metrics and to log
+// the tokens that made more than ACLUsageLogShare of them.
+func (s *Server) usageStats() {
+	for {
+		select {
+		case <-time.After(s.config.ACLUsageInterval):
+			report := s.usage.rotate(s.config.NodeName, s.resolveUsage)
+			s.emitUsage("rpc", "RPCs", report.RPC)
+			s.emitUsage("blocking", "blocking queries", report.BlockingQueries)
+
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
+// emitUsage reports the top tokens of one kind of request. Only the total of
+// the top tokens is emitted as a metric, since a label per token would create
+// a new series for every token that's ever in the top, and leave the gauges of
+// the tokens that dropped out of it at their last value.
+func (s *Server) emitUsage(kind, what string, clients []*structs.UsageClient) {
+	var total uint64
+	for _, c := range clients {
+		total += c.Count
+	}
+	metrics.SetGauge([]string{"usage", "top_clients", kind}, float32(total))
+
+	minCount := uint64(usageMinLogSamples / s.usage.rate)
+	for _, c := range clients {
+		if c.Share <= s.config.ACLUsageLogShare || c.Count < minCount {
+			continue
+		}
+		s.logger.Printf("[WARN] consul: Token %s (%q) made %.0f%% of the %s handled in the last %s",
+			c.AccessorID, c.Description, c.Share*100, what, s.config.ACLUsageInterval)
+	}
+}
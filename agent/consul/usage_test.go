@@ -0,0 +1,63 @@
+package consul
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker(t *testing.T) {
+	t.Parallel()
+	u := newUsageTracker(0.5, 2)
+
+	for i := 0; i < 6; i++ {
+		u.record("secret-a", false)
+	}
+	for i := 0; i < 3; i++ {
+		u.record("secret-b", false)
+		u.record("secret-b", true)
+	}
+	u.record("secret-c", false)
+
+	// The secrets are resolved when the interval ends, and the requests made
+	// with a token that can't be resolved aren't counted.
+	u.record("secret-d", false)
+	resolve := func(secretID string) (string, string, bool) {
+		switch secretID {
+		case "secret-a", "secret-b", "secret-c":
+			id := strings.TrimPrefix(secretID, "secret-")
+			return id, "token " + id, true
+		}
+		return "", "", false
+	}
+
+	// Nothing is reported before the end of the first interval.
+	require.Nil(t, u.report())
+
+	report := u.rotate("server1", resolve)
+	require.Equal(t, report, u.report())
+	require.Equal(t, "server1", report.Server)
+	require.Equal(t, 0.5, report.SampleRate)
+	require.False(t, report.End.Before(report.Start))
+
+	// The counts are scaled by the sample rate, and only the top 2 tokens are
+	// kept.
+	require.Len(t, report.RPC, 2)
+	require.Equal(t, "a", report.RPC[0].AccessorID)
+	require.Equal(t, "token a", report.RPC[0].Description)
+	require.Equal(t, uint64(12), report.RPC[0].Count)
+	require.Equal(t, 0.6, report.RPC[0].Share)
+	require.Equal(t, "b", report.RPC[1].AccessorID)
+	require.Equal(t, uint64(6), report.RPC[1].Count)
+
+	require.Len(t, report.BlockingQueries, 1)
+	require.Equal(t, "b", report.BlockingQueries[0].AccessorID)
+	require.Equal(t, uint64(6), report.BlockingQueries[0].Count)
+	require.Equal(t, 1.0, report.BlockingQueries[0].Share)
+
+	// The next interval starts empty.
+	report = u.rotate("server1", resolve)
+	require.Empty(t, report.RPC)
+	require.Empty(t, report.BlockingQueries)
+}
@@ -3,12 +3,14 @@ package consul
 import (
 	"encoding/binary"
 	"fmt"
+	"log"
 	"net"
 	"runtime"
 	"strconv"
 
 	"github.com/hashicorp/consul/agent/metadata"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/logger"
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/serf/serf"
 )
@@ -284,6 +286,21 @@ func ServersMeetMinimumVersion(members []serf.Member, minVersion *version.Versio
 	return true
 }
 
+// ServersHaveTag returns whether all the alive servers in the given members
+// set the given Serf tag. It is used to gate features which can't be tied to
+// a released version.
+func ServersHaveTag(members []serf.Member, tag string) bool {
+	for _, member := range members {
+		if valid, _ := metadata.IsConsulServer(member); valid && member.Status == serf.StatusAlive {
+			if _, ok := member.Tags[tag]; !ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 func ServersGetACLMode(members []serf.Member, leader string, datacenter string) (numServers int, mode structs.ACLMode, leaderMode structs.ACLMode) {
 	numServers = 0
 	mode = structs.ACLModeEnabled
@@ -322,3 +339,9 @@ func ServersGetACLMode(members []serf.Member, leader string, datacenter string)
 
 	return
 }
+
+// newLimitedLogger returns a logger writing to the given one for the messages
+// of the hot paths, which are rate limited.
+func newLimitedLogger(l *log.Logger) *logger.RateLimitedLogger {
+	return logger.NewRateLimited(l)
+}
@@ -334,6 +334,71 @@ func TestGetPublicIPv6(t *testing.T) {
 	}
 }
 
+func TestServersHaveTag(t *testing.T) {
+	t.Parallel()
+	makeMember := func(role string, status serf.MemberStatus, tags ...string) serf.Member {
+		m := serf.Member{
+			Name: "foo",
+			Addr: net.IP([]byte{127, 0, 0, 1}),
+			Tags: map[string]string{
+				"role":     role,
+				"id":       "asdf",
+				"dc":       "east-aws",
+				"port":     "10000",
+				"build":    "1.4.4",
+				"vsn":      "1",
+				"raft_vsn": "3",
+			},
+			Status: status,
+		}
+		for _, tag := range tags {
+			m.Tags[tag] = "1"
+		}
+		return m
+	}
+
+	cases := map[string]struct {
+		members  []serf.Member
+		expected bool
+	}{
+		"no members": {nil, true},
+		"all servers tagged": {
+			[]serf.Member{
+				makeMember("consul", serf.StatusAlive, "feature"),
+				makeMember("consul", serf.StatusAlive, "feature"),
+			},
+			true,
+		},
+		"one server untagged": {
+			[]serf.Member{
+				makeMember("consul", serf.StatusAlive, "feature"),
+				makeMember("consul", serf.StatusAlive),
+			},
+			false,
+		},
+		"failed server untagged": {
+			[]serf.Member{
+				makeMember("consul", serf.StatusAlive, "feature"),
+				makeMember("consul", serf.StatusFailed),
+			},
+			true,
+		},
+		"client untagged": {
+			[]serf.Member{
+				makeMember("consul", serf.StatusAlive, "feature"),
+				makeMember("node", serf.StatusAlive),
+			},
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		if got := ServersHaveTag(tc.members, "feature"); got != tc.expected {
+			t.Fatalf("%s: got %v want %v", name, got, tc.expected)
+		}
+	}
+}
+
 func TestServersMeetMinimumVersion(t *testing.T) {
 	t.Parallel()
 	makeMember := func(version string) serf.Member {
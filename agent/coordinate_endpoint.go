@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 // checkCoordinateDisabled will return a standard response if coordinates are
@@ -16,8 +17,7 @@ func (s *HTTPServer) checkCoordinateDisabled(resp http.ResponseWriter, req *http
 		return false
 	}
 
-	resp.WriteHeader(http.StatusUnauthorized)
-	fmt.Fprint(resp, "Coordinate support disabled")
+	s.writeError(resp, req, http.StatusUnauthorized, api.ErrCodeDisabled, "Coordinate support disabled", nil)
 	return true
 }
 
@@ -151,8 +151,7 @@ func (s *HTTPServer) CoordinateUpdate(resp http.ResponseWriter, req *http.Reques
 
 	args := structs.CoordinateUpdateRequest{}
 	if err := decodeBody(req, &args, nil); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 	s.parseDC(req, &args.Datacenter)
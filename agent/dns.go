@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -14,12 +15,14 @@ import (
 	metrics "github.com/armon/go-metrics"
 	radix "github.com/armon/go-radix"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/hashicorp/consul/agent/cache"
 	cachetype "github.com/hashicorp/consul/agent/cache-types"
 	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/agent/consul"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/logger"
 	"github.com/miekg/dns"
 )
 
@@ -66,6 +69,8 @@ type dnsConfig struct {
 	ARecordLimit    int
 	NodeMetaTXT     bool
 	dnsSOAConfig    dnsSOAConfig
+
+	AnswerCacheMaxEntries int
 }
 
 // DNSServer is used to wrap an Agent and expose various
@@ -85,6 +90,14 @@ type DNSServer struct {
 	// be safely changed at runtime. It always contains a bool and is
 	// initialized with the value from config.DisableCompression.
 	disableCompression atomic.Value
+
+	// answers caches the answers to service lookups. It's nil if the
+	// answer cache is disabled.
+	answers *dnsAnswerCache
+
+	// traces holds the trace of each simulated query in progress, keyed
+	// by its request.
+	traces sync.Map
 }
 
 func NewDNSServer(a *Agent) (*DNSServer, error) {
@@ -124,6 +137,16 @@ func NewDNSServer(a *Agent) (*DNSServer, error) {
 
 	srv.disableCompression.Store(a.config.DNSDisableCompression)
 
+	if dnscfg.AnswerCacheMaxEntries > 0 {
+		// The answers are only invalidated early if the lookups go through
+		// the agent cache, otherwise they're kept for their TTL.
+		var agentCache *cache.Cache
+		if dnscfg.UseCache {
+			agentCache = a.cache
+		}
+		srv.answers = newDNSAnswerCache(dnscfg.AnswerCacheMaxEntries, agentCache, a.logger)
+	}
+
 	return srv, nil
 }
 
@@ -151,6 +174,8 @@ func GetDNSConfig(conf *config.RuntimeConfig) *dnsConfig {
 			Refresh: conf.DNSSOA.Refresh,
 			Retry:   conf.DNSSOA.Retry,
 		},
+
+		AnswerCacheMaxEntries: conf.DNSAnswerCacheMaxEntries,
 	}
 }
 
@@ -360,6 +385,16 @@ func (d *DNSServer) handleQuery(resp dns.ResponseWriter, req *dns.Msg) {
 		network = "tcp"
 	}
 
+	m := d.queryResponse(network, resp.RemoteAddr(), req)
+
+	// Write out the complete response
+	if err := resp.WriteMsg(m); err != nil {
+		d.logger.Printf("[WARN] dns: failed to respond: %v", err)
+	}
+}
+
+// queryResponse returns the response to a query in the configured domain.
+func (d *DNSServer) queryResponse(network string, remoteAddr net.Addr, req *dns.Msg) *dns.Msg {
 	// Setup the message response
 	m := new(dns.Msg)
 	m.SetReply(req)
@@ -384,17 +419,23 @@ func (d *DNSServer) handleQuery(resp dns.ResponseWriter, req *dns.Msg) {
 		m.SetRcode(req, dns.RcodeSuccess)
 
 	case dns.TypeAXFR:
+		d.tracef(req, "Zone transfers are not implemented")
 		m.SetRcode(req, dns.RcodeNotImplemented)
 
 	default:
-		ecsGlobal = d.dispatch(network, resp.RemoteAddr(), req, m)
+		ecsGlobal = d.dispatch(network, remoteAddr, req, m)
 	}
 
 	setEDNS(req, m, ecsGlobal)
+	return m
+}
 
-	// Write out the complete response
-	if err := resp.WriteMsg(m); err != nil {
-		d.logger.Printf("[WARN] dns: failed to respond: %v", err)
+// tracef adds a step to the trace of a simulated query. It does nothing for
+// the other queries.
+func (d *DNSServer) tracef(req *dns.Msg, format string, args ...interface{}) {
+	if trace, ok := d.traces.Load(req); ok {
+		steps := trace.(*[]string)
+		*steps = append(*steps, fmt.Sprintf(format, args...))
 	}
 }
 
@@ -425,7 +466,7 @@ func (d *DNSServer) addSOA(msg *dns.Msg) {
 // nameservers returns the names and ip addresses of up to three random servers
 // in the current cluster which serve as authoritative name servers for zone.
 func (d *DNSServer) nameservers(edns bool, maxRecursionLevel int) (ns []dns.RR, extra []dns.RR) {
-	out, err := d.lookupServiceNodes(d.agent.config.Datacenter, structs.ConsulServiceName, "", false, maxRecursionLevel)
+	out, err := d.lookupServiceNodes(d.agent.config.Datacenter, structs.ConsulServiceName, "", false, nil, maxRecursionLevel)
 	if err != nil {
 		d.logger.Printf("[WARN] dns: Unable to get list of servers: %s", err)
 		return nil, nil
@@ -531,7 +572,7 @@ PARSE:
 			}
 
 			// _name._tag.service.consul
-			d.serviceLookup(network, datacenter, labels[n-3][1:], tag, false, req, resp, maxRecursionLevel)
+			d.serviceLookup(network, datacenter, labels[n-3][1:], tag, false, remoteAddr, req, resp, maxRecursionLevel)
 
 			// Consul 0.3 and prior format for SRV queries
 		} else {
@@ -543,7 +584,7 @@ PARSE:
 			}
 
 			// tag[.tag].name.service.consul
-			d.serviceLookup(network, datacenter, labels[n-2], tag, false, req, resp, maxRecursionLevel)
+			d.serviceLookup(network, datacenter, labels[n-2], tag, false, remoteAddr, req, resp, maxRecursionLevel)
 		}
 
 	case "connect":
@@ -552,7 +593,7 @@ PARSE:
 		}
 
 		// name.connect.consul
-		d.serviceLookup(network, datacenter, labels[n-2], "", true, req, resp, maxRecursionLevel)
+		d.serviceLookup(network, datacenter, labels[n-2], "", true, remoteAddr, req, resp, maxRecursionLevel)
 
 	case "node":
 		if n == 1 {
@@ -638,6 +679,7 @@ PARSE:
 	return
 INVALID:
 	d.logger.Printf("[WARN] dns: QName invalid: %s", qName)
+	d.tracef(req, "Name %q is not a valid query in the %s domain, answering NXDOMAIN", qName, d.domain)
 	d.addSOA(resp)
 	resp.SetRcode(req, dns.RcodeNameError)
 	return
@@ -648,8 +690,10 @@ func (d *DNSServer) nodeLookup(network, datacenter, node string, req, resp *dns.
 	// Only handle ANY, A, AAAA, and TXT type requests
 	qType := req.Question[0].Qtype
 	if qType != dns.TypeANY && qType != dns.TypeA && qType != dns.TypeAAAA && qType != dns.TypeTXT {
+		d.tracef(req, "Node lookups only answer ANY, A, AAAA and TXT queries")
 		return
 	}
+	d.tracef(req, "Looking up node %q in datacenter %q", node, datacenter)
 
 	// Make an RPC request
 	args := &structs.NodeSpecificRequest{
@@ -663,12 +707,14 @@ func (d *DNSServer) nodeLookup(network, datacenter, node string, req, resp *dns.
 	out, err := d.lookupNode(args)
 	if err != nil {
 		d.logger.Printf("[ERR] dns: rpc error: %v", err)
+		d.tracef(req, "Node lookup failed, answering SERVFAIL: %v", err)
 		resp.SetRcode(req, dns.RcodeServerFailure)
 		return
 	}
 
 	// If we have no address, return not found!
 	if out.NodeServices == nil {
+		d.tracef(req, "Node not found, answering NXDOMAIN")
 		d.addSOA(resp)
 		resp.SetRcode(req, dns.RcodeNameError)
 		return
@@ -687,6 +733,7 @@ func (d *DNSServer) nodeLookup(network, datacenter, node string, req, resp *dns.
 	n := out.NodeServices.Node
 	edns := req.IsEdns0() != nil
 	addr := d.agent.TranslateAddress(datacenter, n.Address, n.TaggedAddresses)
+	d.tracef(req, "Node found with address %s, using a TTL of %s", addr, d.config.NodeTTL)
 	records, meta := d.formatNodeRecord(out.NodeServices.Node, addr, req.Question[0].Name, qType, d.config.NodeTTL, edns, maxRecursionLevel, generateMeta)
 	if records != nil {
 		resp.Answer = append(resp.Answer, records...)
@@ -1049,9 +1096,10 @@ func (d *DNSServer) trimDNSResponse(network string, req, resp *dns.Msg) (trimmed
 	return trimmed
 }
 
-// lookupServiceNodes returns nodes with a given service.
-func (d *DNSServer) lookupServiceNodes(datacenter, service, tag string, connect bool, maxRecursionLevel int) (structs.IndexedCheckServiceNodes, error) {
-	args := structs.ServiceSpecificRequest{
+// serviceLookupArgs returns the request used to look up the nodes with a
+// given service.
+func (d *DNSServer) serviceLookupArgs(datacenter, service, tag string, connect bool) structs.ServiceSpecificRequest {
+	return structs.ServiceSpecificRequest{
 		Connect:     connect,
 		Datacenter:  datacenter,
 		ServiceName: service,
@@ -1063,6 +1111,11 @@ func (d *DNSServer) lookupServiceNodes(datacenter, service, tag string, connect
 			MaxAge:     d.config.CacheMaxAge,
 		},
 	}
+}
+
+// lookupServiceNodes returns nodes with a given service.
+func (d *DNSServer) lookupServiceNodes(datacenter, service, tag string, connect bool, req *dns.Msg, maxRecursionLevel int) (structs.IndexedCheckServiceNodes, error) {
+	args := d.serviceLookupArgs(datacenter, service, tag, connect)
 
 	var out structs.IndexedCheckServiceNodes
 
@@ -1104,20 +1157,55 @@ func (d *DNSServer) lookupServiceNodes(datacenter, service, tag string, connect
 	nodes := make(structs.CheckServiceNodes, len(out.Nodes))
 	copy(nodes, out.Nodes)
 	out.Nodes = nodes.Filter(d.config.OnlyPassing)
+	d.tracef(req, "Found %d instances, %d left after filtering on health (only_passing = %t)",
+		len(nodes), len(out.Nodes), d.config.OnlyPassing)
 	return out, nil
 }
 
 // serviceLookup is used to handle a service query
-func (d *DNSServer) serviceLookup(network, datacenter, service, tag string, connect bool, req, resp *dns.Msg, maxRecursionLevel int) {
-	out, err := d.lookupServiceNodes(datacenter, service, tag, connect, maxRecursionLevel)
+func (d *DNSServer) serviceLookup(network, datacenter, service, tag string, connect bool, remoteAddr net.Addr, req, resp *dns.Msg, maxRecursionLevel int) {
+	if connect {
+		d.tracef(req, "Looking up Connect-capable service %q in datacenter %q", service, datacenter)
+	} else {
+		d.tracef(req, "Looking up service %q with tag %q in datacenter %q", service, tag, datacenter)
+	}
+
+	// Answers are only cached for stale queries, consistent queries always
+	// go to the servers.
+	cacheable := d.answers != nil && d.config.AllowStale
+	var key dnsAnswerKey
+	if cacheable {
+		key = newDNSAnswerKey(network, d.agent.tokens.UserToken(), req, resp, maxRecursionLevel)
+		if d.answers.get(key, req, resp) {
+			metrics.IncrCounter([]string{"dns", "answer_cache", "hit"}, 1)
+			d.tracef(req, "Answered from the DNS answer cache")
+			return
+		}
+		metrics.IncrCounter([]string{"dns", "answer_cache", "miss"}, 1)
+	}
+
+	out, err := d.lookupServiceNodes(datacenter, service, tag, connect, req, maxRecursionLevel)
 	if err != nil {
 		d.logger.Printf("[ERR] dns: rpc error: %v", err)
+		d.tracef(req, "Service lookup failed, answering SERVFAIL: %v", err)
 		resp.SetRcode(req, dns.RcodeServerFailure)
 		return
 	}
 
+	// Apply the routing override of the service, if any, so a share of the
+	// clients only see the instances with the forced tag. The answer then
+	// depends on the client, so it isn't cached.
+	if out.Routing != nil {
+		cacheable = false
+	}
+	out.Nodes = out.Routing.Route(dnsQuerySource(remoteAddr, req), out.Nodes)
+	if out.Routing != nil {
+		d.tracef(req, "Applied the routing override of the service, %d instances left", len(out.Nodes))
+	}
+
 	// If we have no nodes, return not found!
 	if len(out.Nodes) == 0 {
+		d.tracef(req, "No instances left, answering NXDOMAIN")
 		d.addSOA(resp)
 		resp.SetRcode(req, dns.RcodeNameError)
 		return
@@ -1126,8 +1214,15 @@ func (d *DNSServer) serviceLookup(network, datacenter, service, tag string, conn
 	// Perform a random shuffle
 	out.Nodes.Shuffle()
 
-	// Determine the TTL
+	// Determine the TTL, answers from a persisted result are only cached
+	// briefly so clients pick up fresh data as soon as the servers are back.
 	ttl, _ := d.GetTTLForService(service)
+	if out.Degraded {
+		ttl = degradedDNSTTL
+		d.tracef(req, "Result is degraded, using a TTL of %s", ttl)
+	} else {
+		d.tracef(req, "Using a TTL of %s", ttl)
+	}
 
 	// Add various responses depending on the request
 	qType := req.Question[0].Qtype
@@ -1137,13 +1232,21 @@ func (d *DNSServer) serviceLookup(network, datacenter, service, tag string, conn
 		d.serviceNodeRecords(datacenter, out.Nodes, req, resp, ttl, maxRecursionLevel)
 	}
 
-	d.trimDNSResponse(network, req, resp)
+	if d.trimDNSResponse(network, req, resp) {
+		d.tracef(req, "Response trimmed to %d answers to fit the %s limits", len(resp.Answer), network)
+	}
 
 	// If the answer is empty and the response isn't truncated, return not found
 	if len(resp.Answer) == 0 && !resp.Truncated {
+		d.tracef(req, "No %s records for the instances, answering with the SOA", dns.Type(qType))
 		d.addSOA(resp)
 		return
 	}
+
+	if cacheable && len(resp.Answer) > 0 {
+		args := d.serviceLookupArgs(datacenter, service, tag, connect)
+		d.answers.set(key, &args, out.Index, resp)
+	}
 }
 
 func ednsSubnetForRequest(req *dns.Msg) *dns.EDNS0_SUBNET {
@@ -1163,6 +1266,25 @@ func ednsSubnetForRequest(req *dns.Msg) *dns.EDNS0_SUBNET {
 	return nil
 }
 
+// dnsQuerySource returns the IP address of the client a query is made for,
+// which is the EDNS client subnet if there is one and the address of the
+// requester otherwise.
+func dnsQuerySource(remoteAddr net.Addr, req *dns.Msg) string {
+	if subnet := ednsSubnetForRequest(req); subnet != nil {
+		return subnet.Address.String()
+	}
+
+	switch v := remoteAddr.(type) {
+	case *net.UDPAddr:
+		return v.IP.String()
+	case *net.TCPAddr:
+		return v.IP.String()
+	case *net.IPAddr:
+		return v.IP.String()
+	}
+	return ""
+}
+
 // preparedQueryLookup is used to handle a prepared query.
 func (d *DNSServer) preparedQueryLookup(network, datacenter, query string, remoteAddr net.Addr, req, resp *dns.Msg, maxRecursionLevel int) {
 	// Execute the prepared query.
@@ -1186,31 +1308,21 @@ func (d *DNSServer) preparedQueryLookup(network, datacenter, query string, remot
 		},
 	}
 
-	subnet := ednsSubnetForRequest(req)
-
-	if subnet != nil {
-		args.Source.Ip = subnet.Address.String()
-	} else {
-		switch v := remoteAddr.(type) {
-		case *net.UDPAddr:
-			args.Source.Ip = v.IP.String()
-		case *net.TCPAddr:
-			args.Source.Ip = v.IP.String()
-		case *net.IPAddr:
-			args.Source.Ip = v.IP.String()
-		}
-	}
+	args.Source.Ip = dnsQuerySource(remoteAddr, req)
 
+	d.tracef(req, "Executing prepared query %q in datacenter %q", query, datacenter)
 	out, err := d.lookupPreparedQuery(args)
 
 	// If they give a bogus query name, treat that as a name error,
 	// not a full on server error. We have to use a string compare
 	// here since the RPC layer loses the type information.
 	if err != nil && err.Error() == consul.ErrQueryNotFound.Error() {
+		d.tracef(req, "Prepared query not found, answering NXDOMAIN")
 		d.addSOA(resp)
 		resp.SetRcode(req, dns.RcodeNameError)
 		return
 	} else if err != nil {
+		d.tracef(req, "Prepared query failed, answering SERVFAIL: %v", err)
 		resp.SetRcode(req, dns.RcodeServerFailure)
 		return
 	}
@@ -1238,8 +1350,12 @@ func (d *DNSServer) preparedQueryLookup(network, datacenter, query string, remot
 		ttl, _ = d.GetTTLForService(out.Service)
 	}
 
+	d.tracef(req, "Prepared query returned %d instances of service %q in datacenter %q, using a TTL of %s",
+		len(out.Nodes), out.Service, out.Datacenter, ttl)
+
 	// If we have no nodes, return not found!
 	if len(out.Nodes) == 0 {
+		d.tracef(req, "No instances, answering NXDOMAIN")
 		d.addSOA(resp)
 		resp.SetRcode(req, dns.RcodeNameError)
 		return
@@ -1253,10 +1369,13 @@ func (d *DNSServer) preparedQueryLookup(network, datacenter, query string, remot
 		d.serviceNodeRecords(out.Datacenter, out.Nodes, req, resp, ttl, maxRecursionLevel)
 	}
 
-	d.trimDNSResponse(network, req, resp)
+	if d.trimDNSResponse(network, req, resp) {
+		d.tracef(req, "Response trimmed to %d answers to fit the %s limits", len(resp.Answer), network)
+	}
 
 	// If the answer is empty and the response isn't truncated, return not found
 	if len(resp.Answer) == 0 && !resp.Truncated {
+		d.tracef(req, "No %s records for the instances, answering with the SOA", dns.Type(qType))
 		d.addSOA(resp)
 		return
 	}
@@ -1452,6 +1571,15 @@ func (d *DNSServer) serviceSRVRecords(dc string, nodes structs.CheckServiceNodes
 		}
 		resp.Answer = append(resp.Answer, srvRec)
 
+		// The service may ask for a specific kind of target. Invalid targets
+		// registered before they were validated get the default one.
+		if mode := node.Service.Meta[structs.MetaDNSSRVTarget]; mode != "" && structs.ValidateDNSSRVTarget(mode) == nil {
+			var extra []dns.RR
+			srvRec.Target, extra = d.serviceSRVTarget(dc, node, mode, ttl, edns, maxRecursionLevel)
+			resp.Extra = append(resp.Extra, extra...)
+			continue
+		}
+
 		// Start with the translated address but use the service address,
 		// if specified.
 		addr := d.agent.TranslateAddress(dc, node.Node.Address, node.Node.TaggedAddresses)
@@ -1498,6 +1626,71 @@ func (d *DNSServer) serviceSRVRecords(dc string, nodes structs.CheckServiceNodes
 	}
 }
 
+// serviceSRVTarget returns the SRV target for a service instance which has
+// the MetaDNSSRVTarget meta key set, along with the records to put in the
+// additional section for it.
+func (d *DNSServer) serviceSRVTarget(dc string, node structs.CheckServiceNode, mode string, ttl time.Duration, edns bool, maxRecursionLevel int) (string, []dns.RR) {
+	nodeAddr := d.agent.TranslateAddress(dc, node.Node.Address, node.Node.TaggedAddresses)
+
+	switch mode {
+	case structs.DNSSRVTargetNode:
+		target := fmt.Sprintf("%s.node.%s.%s", node.Node.Node, dc, d.domain)
+		records, meta := d.formatNodeRecord(node.Node, nodeAddr, target, dns.TypeANY, ttl, edns, maxRecursionLevel, d.config.NodeMetaTXT)
+		if meta != nil && d.config.NodeMetaTXT {
+			records = append(records, meta...)
+		}
+		return target, records
+
+	case structs.DNSSRVTargetAddress:
+		addr := nodeAddr
+		if node.Service.Address != "" {
+			addr = node.Service.Address
+		}
+		if ip := net.ParseIP(addr); ip != nil {
+			return d.addrSRVTarget(dc, ip, ttl)
+		}
+		return d.hostnameSRVTarget(addr, maxRecursionLevel)
+
+	default:
+		return d.hostnameSRVTarget(mode, maxRecursionLevel)
+	}
+}
+
+// addrSRVTarget returns a target in the 'addr.consul' domain with the IP
+// encoded in it, along with the A or AAAA record for it. We have to do this
+// because we can't put an IP in the target field of an SRV record.
+func (d *DNSServer) addrSRVTarget(dc string, ip net.IP, ttl time.Duration) (string, []dns.RR) {
+	hdr := dns.RR_Header{
+		Class: dns.ClassINET,
+		Ttl:   uint32(ttl / time.Second),
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		hdr.Name = fmt.Sprintf("%s.addr.%s.%s", hex.EncodeToString(ip4), dc, d.domain)
+		hdr.Rrtype = dns.TypeA
+		return hdr.Name, []dns.RR{&dns.A{Hdr: hdr, A: ip4}}
+	}
+
+	hdr.Name = fmt.Sprintf("%s.addr.%s.%s", hex.EncodeToString(ip), dc, d.domain)
+	hdr.Rrtype = dns.TypeAAAA
+	return hdr.Name, []dns.RR{&dns.AAAA{Hdr: hdr, AAAA: ip}}
+}
+
+// hostnameSRVTarget uses the given hostname as the SRV target. If the name
+// can be resolved, the address records for it are returned as well.
+func (d *DNSServer) hostnameSRVTarget(host string, maxRecursionLevel int) (string, []dns.RR) {
+	target := dns.Fqdn(host)
+
+	var extra []dns.RR
+	for _, rr := range d.resolveCNAME(target, maxRecursionLevel) {
+		switch rr.(type) {
+		case *dns.A, *dns.AAAA, *dns.CNAME:
+			extra = append(extra, rr)
+		}
+	}
+	return target, extra
+}
+
 // handleRecurse is used to handle recursive DNS queries
 func (d *DNSServer) handleRecurse(resp dns.ResponseWriter, req *dns.Msg) {
 	q := req.Question[0]
@@ -1539,11 +1732,13 @@ func (d *DNSServer) handleRecurse(resp dns.ResponseWriter, req *dns.Msg) {
 			}
 			return
 		}
-		d.logger.Printf("[ERR] dns: recurse failed: %v", err)
+		d.agent.limitedLogger.RateLimited("dns.recurse_failed", logger.DefaultRateLimitInterval).Printf(
+			"[ERR] dns: recurse failed: %v", err)
 	}
 
 	// If all resolvers fail, return a SERVFAIL message
-	d.logger.Printf("[ERR] dns: all resolvers failed for %v from client %s (%s)",
+	d.agent.limitedLogger.RateLimited("dns.recurse_all_failed", logger.DefaultRateLimitInterval).Printf(
+		"[ERR] dns: all resolvers failed for %v from client %s (%s)",
 		q, resp.RemoteAddr().String(), resp.RemoteAddr().Network())
 	m := &dns.Msg{}
 	m.SetReply(req)
@@ -1596,8 +1791,10 @@ func (d *DNSServer) resolveCNAME(name string, maxRecursionLevel int) []dns.RR {
 			d.logger.Printf("[DEBUG] dns: cname recurse RTT for %v (%v)", name, rtt)
 			return r.Answer
 		}
-		d.logger.Printf("[ERR] dns: cname recurse failed for %v: %v", name, err)
+		d.agent.limitedLogger.RateLimited("dns.cname_recurse_failed", logger.DefaultRateLimitInterval).Printf(
+			"[ERR] dns: cname recurse failed for %v: %v", name, err)
 	}
-	d.logger.Printf("[ERR] dns: all resolvers failed for %v", name)
+	d.agent.limitedLogger.RateLimited("dns.cname_recurse_all_failed", logger.DefaultRateLimitInterval).Printf(
+		"[ERR] dns: all resolvers failed for %v", name)
 	return nil
 }
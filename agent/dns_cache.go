@@ -0,0 +1,295 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/miekg/dns"
+)
+
+// dnsAnswerKey identifies a cached answer. Besides the question, the answer
+// depends on the token the lookup is made with, which filters the instances,
+// on the network, the EDNS buffer size and the compression, which limit how
+// many records fit in the response, and on the recursion level, which limits
+// how far the CNAMEs are resolved.
+type dnsAnswerKey struct {
+	name      string
+	qtype     uint16
+	token     string
+	network   string
+	udpSize   uint16
+	compress  bool
+	recursion int
+}
+
+// newDNSAnswerKey returns the key of the answer to a request looked up with
+// the given token.
+func newDNSAnswerKey(network, token string, req, resp *dns.Msg, maxRecursionLevel int) dnsAnswerKey {
+	key := dnsAnswerKey{
+		name:      req.Question[0].Name,
+		qtype:     req.Question[0].Qtype,
+		token:     token,
+		network:   network,
+		compress:  resp.Compress,
+		recursion: maxRecursionLevel,
+	}
+	if edns := req.IsEdns0(); edns != nil {
+		key.udpSize = edns.UDPSize()
+	}
+	return key
+}
+
+// dnsAnswer is a cached answer to a service lookup.
+type dnsAnswer struct {
+	// lookup identifies the service lookup the answer was made from, and
+	// index is the index of its result.
+	lookup string
+	index  uint64
+
+	// expires is when the record with the shortest TTL in the answer
+	// expires.
+	expires time.Time
+
+	answer    []dns.RR
+	extra     []dns.RR
+	truncated bool
+}
+
+// dnsAnswerCache caches the answers to service lookups until the record with
+// the shortest TTL in them expires. If the lookups go through the agent cache,
+// the answers are also invalidated as soon as the result of their lookup
+// changes.
+type dnsAnswerCache struct {
+	maxEntries int
+	agentCache *cache.Cache
+	logger     *log.Logger
+
+	l       sync.Mutex
+	entries map[dnsAnswerKey]*dnsAnswer
+	lookups map[string]*dnsAnswerLookup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// dnsAnswerLookup tracks the answers made from a service lookup.
+type dnsAnswerLookup struct {
+	// answers is the number of cached answers made from the lookup.
+	answers int
+
+	// cancel stops watching the result of the lookup, it's nil if the
+	// agent cache isn't used.
+	cancel context.CancelFunc
+}
+
+// newDNSAnswerCache returns a cache holding up to maxEntries answers. The
+// agent cache is used to invalidate the answers early, it may be nil.
+func newDNSAnswerCache(maxEntries int, agentCache *cache.Cache, logger *log.Logger) *dnsAnswerCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &dnsAnswerCache{
+		maxEntries: maxEntries,
+		agentCache: agentCache,
+		logger:     logger,
+		entries:    make(map[dnsAnswerKey]*dnsAnswer),
+		lookups:    make(map[string]*dnsAnswerLookup),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// get adds the cached answer for the key to the response and returns true,
+// or returns false if there is none. The TTLs of the records are lowered to
+// the time left before the answer expires, so a record is never served for
+// longer than its TTL.
+func (c *dnsAnswerCache) get(key dnsAnswerKey, req, resp *dns.Msg) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	ttl := uint32(time.Until(entry.expires) / time.Second)
+	if ttl == 0 {
+		c.remove(key, entry)
+		return false
+	}
+
+	resp.Answer = copyDNSAnswerRRs(entry.answer, ttl)
+	resp.Extra = append(resp.Extra, copyDNSAnswerRRs(entry.extra, ttl)...)
+	resp.Truncated = entry.truncated
+
+	// The answer was shuffled when it was made, shuffle it again so the
+	// clients don't all use the same instance. Answers with other records,
+	// like the CNAMEs of a hostname, are kept in order.
+	qType := req.Question[0].Qtype
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype != qType {
+			return true
+		}
+	}
+	rand.Shuffle(len(resp.Answer), func(i, j int) {
+		resp.Answer[i], resp.Answer[j] = resp.Answer[j], resp.Answer[i]
+	})
+	return true
+}
+
+// copyDNSAnswerRRs returns a copy of the records with a TTL of at most ttl.
+func copyDNSAnswerRRs(rrs []dns.RR, ttl uint32) []dns.RR {
+	out := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		rr = dns.Copy(rr)
+		if hdr := rr.Header(); hdr.Ttl > ttl {
+			hdr.Ttl = ttl
+		}
+		out = append(out, rr)
+	}
+	return out
+}
+
+// set caches the answer in the response, which was made from the result at
+// the given index of the service lookup args. Answers with a record that has
+// no TTL aren't cached, and neither are answers that don't fit in the cache
+// once the expired answers are removed, or answers looked up with another
+// token than the one in the key because the token changed in between.
+func (c *dnsAnswerCache) set(key dnsAnswerKey, args *structs.ServiceSpecificRequest, index uint64, resp *dns.Msg) {
+	info := args.CacheInfo()
+	if info.Token != key.token {
+		return
+	}
+
+	ttl := resp.Answer[0].Header().Ttl
+	for _, rrs := range [][]dns.RR{resp.Answer, resp.Extra} {
+		for _, rr := range rrs {
+			if hdr := rr.Header(); hdr.Rrtype != dns.TypeOPT && hdr.Ttl < ttl {
+				ttl = hdr.Ttl
+			}
+		}
+	}
+	if ttl == 0 {
+		return
+	}
+
+	entry := &dnsAnswer{
+		lookup:    info.Datacenter + "/" + info.Token + "/" + info.Key,
+		index:     index,
+		expires:   time.Now().Add(time.Duration(ttl) * time.Second),
+		answer:    copyDNSAnswerRRs(resp.Answer, ttl),
+		extra:     copyDNSAnswerRRs(resp.Extra, ttl),
+		truncated: resp.Truncated,
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if old, ok := c.entries[key]; ok {
+		c.remove(key, old)
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.removeExpired()
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+
+	l, ok := c.lookups[entry.lookup]
+	if !ok {
+		cancel, err := c.watch(entry.lookup, args)
+		if err != nil {
+			c.logger.Printf("[WARN] dns: not caching answer for %s: %v", key.name, err)
+			return
+		}
+		l = &dnsAnswerLookup{cancel: cancel}
+		c.lookups[entry.lookup] = l
+	}
+	l.answers++
+	c.entries[key] = entry
+}
+
+// watch starts watching the result of the service lookup in the agent cache,
+// and returns the function to stop it. It returns a nil function if the agent
+// cache isn't used.
+func (c *dnsAnswerCache) watch(lookup string, args *structs.ServiceSpecificRequest) (context.CancelFunc, error) {
+	if c.agentCache == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	ch := make(chan cache.UpdateEvent, 1)
+	if err := c.agentCache.Notify(ctx, cachetype.HealthServicesName, args, lookup, ch); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case u := <-ch:
+				if u.Err == nil {
+					c.invalidate(u.CorrelationID, u.Meta.Index)
+				}
+			}
+		}
+	}()
+	return cancel, nil
+}
+
+// invalidate removes the answers made from a result of the service lookup
+// older than the given index.
+func (c *dnsAnswerCache) invalidate(lookup string, index uint64) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.lookup == lookup && entry.index < index {
+			c.remove(key, entry)
+		}
+	}
+}
+
+// removeExpired removes the expired answers. It must be called with the lock
+// held.
+func (c *dnsAnswerCache) removeExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			c.remove(key, entry)
+		}
+	}
+}
+
+// remove removes an answer, and stops watching its lookup if no other
+// answer was made from it. It must be called with the lock held.
+func (c *dnsAnswerCache) remove(key dnsAnswerKey, entry *dnsAnswer) {
+	delete(c.entries, key)
+
+	l, ok := c.lookups[entry.lookup]
+	if !ok {
+		return
+	}
+	l.answers--
+	if l.answers > 0 {
+		return
+	}
+	if l.cancel != nil {
+		l.cancel()
+	}
+	delete(c.lookups, entry.lookup)
+}
+
+// stop stops watching the lookups and removes all the answers.
+func (c *dnsAnswerCache) stop() {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.cancel()
+	c.entries = make(map[dnsAnswerKey]*dnsAnswer)
+	c.lookups = make(map[string]*dnsAnswerLookup)
+}
@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	tokenStore "github.com/hashicorp/consul/agent/token"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// registerDNSAnswerCacheNode registers an instance of the db service on a new
+// node.
+func registerDNSAnswerCacheNode(t *testing.T, a *TestAgent, idx int) {
+	t.Helper()
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       fmt.Sprintf("foo%d", idx),
+		Address:    fmt.Sprintf("127.0.0.%d", idx),
+		Service: &structs.NodeService{
+			Service: "db",
+			Port:    12345,
+		},
+	}
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", args, &out))
+}
+
+// countDNSAnswers returns the number of answers cached by the DNS servers of
+// the agent.
+func countDNSAnswers(a *TestAgent) int {
+	n := 0
+	for _, srv := range a.dnsServers {
+		srv.answers.l.Lock()
+		n += len(srv.answers.entries)
+		srv.answers.l.Unlock()
+	}
+	return n
+}
+
+func queryDNSAnswerCache(t require.TestingT, a *TestAgent) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion("db.service.consul.", dns.TypeA)
+	c := new(dns.Client)
+	in, _, err := c.Exchange(m, a.DNSAddr())
+	require.NoError(t, err)
+	return in
+}
+
+func TestDNS_AnswerCache(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		dns_config {
+			answer_cache_max_entries = 10
+			service_ttl = {
+				"db" = "30s"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	registerDNSAnswerCacheNode(t, a, 1)
+	in := queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 1)
+
+	// Without the agent cache, the answer is kept until it expires.
+	registerDNSAnswerCacheNode(t, a, 2)
+	in = queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 1)
+	require.True(t, in.Answer[0].Header().Ttl <= 30)
+	require.Equal(t, 1, countDNSAnswers(a))
+}
+
+func TestDNS_AnswerCache_Invalidate(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		dns_config {
+			answer_cache_max_entries = 10
+			use_cache = true
+			service_ttl = {
+				"db" = "30s"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	registerDNSAnswerCacheNode(t, a, 1)
+	in := queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 1)
+
+	// The answer is invalidated when the result in the agent cache changes.
+	registerDNSAnswerCacheNode(t, a, 2)
+	retry.Run(t, func(r *retry.R) {
+		in := queryDNSAnswerCache(r, a)
+		if len(in.Answer) != 2 {
+			r.Fatalf("expected 2 answers, got %d", len(in.Answer))
+		}
+	})
+}
+
+func TestDNS_AnswerCache_Consistent(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		dns_config {
+			answer_cache_max_entries = 10
+			allow_stale = false
+			service_ttl = {
+				"db" = "30s"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	registerDNSAnswerCacheNode(t, a, 1)
+	in := queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 1)
+
+	// Consistent queries always go to the servers.
+	registerDNSAnswerCacheNode(t, a, 2)
+	in = queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 2)
+	require.Equal(t, 0, countDNSAnswers(a))
+}
+
+func TestDNS_AnswerCache_Token(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		dns_config {
+			answer_cache_max_entries = 10
+			service_ttl = {
+				"db" = "30s"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	registerDNSAnswerCacheNode(t, a, 1)
+	in := queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 1)
+
+	// The answers looked up with the previous token aren't served once it
+	// changes.
+	registerDNSAnswerCacheNode(t, a, 2)
+	a.tokens.UpdateUserToken("other", tokenStore.TokenSourceAPI)
+	in = queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 2)
+	require.Equal(t, 2, countDNSAnswers(a))
+}
+
+func TestDNS_AnswerCache_NoTTL(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		dns_config {
+			answer_cache_max_entries = 10
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	registerDNSAnswerCacheNode(t, a, 1)
+	in := queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 1)
+
+	// Answers without a TTL aren't cached.
+	registerDNSAnswerCacheNode(t, a, 2)
+	in = queryDNSAnswerCache(t, a)
+	require.Len(t, in.Answer, 2)
+}
+
+func TestDNSAnswerCache_Expiry(t *testing.T) {
+	t.Parallel()
+	c := newDNSAnswerCache(1, nil, nil)
+	req := new(dns.Msg)
+	req.SetQuestion("db.service.consul.", dns.TypeA)
+	key := newDNSAnswerKey("udp", "", req, req, maxRecursionLevelDefault)
+	args := &structs.ServiceSpecificRequest{Datacenter: "dc1", ServiceName: "db"}
+
+	newResp := func(ttl uint32) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: "db.service.consul.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+		}}
+		return resp
+	}
+
+	// Answers without a TTL aren't cached.
+	c.set(key, args, 1, newResp(0))
+	require.False(t, c.get(key, req, new(dns.Msg)))
+
+	// The TTL is lowered to the time left before the answer expires.
+	c.set(key, args, 1, newResp(30))
+	c.entries[key].expires = time.Now().Add(10500 * time.Millisecond)
+	resp := new(dns.Msg)
+	require.True(t, c.get(key, req, resp))
+	require.Equal(t, uint32(10), resp.Answer[0].Header().Ttl)
+
+	// The answer isn't served once it has less than a second left.
+	c.entries[key].expires = time.Now().Add(500 * time.Millisecond)
+	require.False(t, c.get(key, req, new(dns.Msg)))
+	require.Empty(t, c.entries)
+	require.Empty(t, c.lookups)
+
+	// The cache doesn't grow past its size.
+	c.set(key, args, 1, newResp(30))
+	other := key
+	other.qtype = dns.TypeANY
+	c.set(other, args, 1, newResp(30))
+	require.Len(t, c.entries, 1)
+	require.False(t, c.get(other, req, new(dns.Msg)))
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// DNSSimulation is the result of a DNS query resolved in-process, without
+// going through the DNS interface.
+type DNSSimulation struct {
+	Name      string
+	Type      string
+	Network   string
+	Rcode     string
+	Truncated bool
+
+	// Answer, Ns and Extra are the records of the response in their text
+	// format.
+	Answer []string
+	Ns     []string
+	Extra  []string
+
+	// Trace lists the decisions made while resolving the query.
+	Trace []string
+}
+
+// Simulate resolves a query for a name in the configured domain the same way
+// a query made over the network from remoteAddr is resolved, and returns the
+// response along with the trace of the resolution.
+func (d *DNSServer) Simulate(network, name string, qType uint16, remoteAddr net.Addr) (*DNSSimulation, error) {
+	name = dns.Fqdn(name)
+	if !dns.IsSubDomain(d.domain, strings.ToLower(name)) {
+		return nil, fmt.Errorf("Name %q is not in the %s domain", name, d.domain)
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion(name, qType)
+
+	var trace []string
+	d.traces.Store(req, &trace)
+	resp := d.queryResponse(network, remoteAddr, req)
+	d.traces.Delete(req)
+
+	sim := &DNSSimulation{
+		Name:      name,
+		Type:      dns.Type(qType).String(),
+		Network:   network,
+		Rcode:     dns.RcodeToString[resp.Rcode],
+		Truncated: resp.Truncated,
+		Answer:    dnsRecordStrings(resp.Answer),
+		Ns:        dnsRecordStrings(resp.Ns),
+		Extra:     dnsRecordStrings(resp.Extra),
+		Trace:     trace,
+	}
+	if sim.Trace == nil {
+		sim.Trace = []string{}
+	}
+	return sim, nil
+}
+
+// dnsRecordStrings returns the records in their text format.
+func dnsRecordStrings(rrs []dns.RR) []string {
+	out := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		out = append(out, rr.String())
+	}
+	return out
+}
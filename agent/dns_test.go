@@ -2344,6 +2344,132 @@ func TestDNS_ServiceLookup_ServiceAddressIPV6(t *testing.T) {
 	}
 }
 
+func TestDNS_ServiceLookup_SRVTarget(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	register := func(t *testing.T, name, address, target string) {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				Service: name,
+				Address: address,
+				Port:    12345,
+				Meta:    map[string]string{structs.MetaDNSSRVTarget: target},
+			},
+		}
+
+		var out struct{}
+		require.NoError(t, a.RPC("Catalog.Register", args, &out))
+	}
+
+	lookup := func(t *testing.T, name string) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion(name+".service.consul.", dns.TypeSRV)
+
+		c := new(dns.Client)
+		in, _, err := c.Exchange(m, a.DNSAddr())
+		require.NoError(t, err)
+		require.Len(t, in.Answer, 1)
+		return in
+	}
+
+	type extra struct {
+		name string
+		addr string
+	}
+
+	cases := []struct {
+		name    string
+		address string
+		target  string
+		want    string
+		extra   []extra
+	}{
+		{
+			name:    "node",
+			address: "127.0.0.2",
+			target:  "node",
+			want:    "foo.node.dc1.consul.",
+			extra:   []extra{{"foo.node.dc1.consul.", "127.0.0.1"}},
+		},
+		{
+			name:    "address-ipv4",
+			address: "",
+			target:  "address",
+			want:    "7f000001.addr.dc1.consul.",
+			extra:   []extra{{"7f000001.addr.dc1.consul.", "127.0.0.1"}},
+		},
+		{
+			name:    "address-ipv6",
+			address: "2607:20:4005:808::200e",
+			target:  "address",
+			want:    "2607002040050808000000000000200e.addr.dc1.consul.",
+			extra:   []extra{{"2607002040050808000000000000200e.addr.dc1.consul.", "2607:20:4005:808::200e"}},
+		},
+		{
+			name:    "address-hostname",
+			address: "foo.node.consul",
+			target:  "address",
+			want:    "foo.node.consul.",
+			extra:   []extra{{"foo.node.consul.", "127.0.0.1"}},
+		},
+		{
+			name:    "literal",
+			address: "127.0.0.2",
+			target:  "db.example.com",
+			want:    "db.example.com.",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			register(t, tc.name, tc.address, tc.target)
+			in := lookup(t, tc.name)
+
+			srvRec, ok := in.Answer[0].(*dns.SRV)
+			require.True(t, ok, "%#v", in.Answer[0])
+			require.Equal(t, uint16(12345), srvRec.Port)
+			require.Equal(t, tc.want, srvRec.Target)
+
+			require.Len(t, in.Extra, len(tc.extra))
+			for i, want := range tc.extra {
+				switch rr := in.Extra[i].(type) {
+				case *dns.A:
+					require.Equal(t, want.name, rr.Hdr.Name)
+					require.Equal(t, want.addr, rr.A.String())
+				case *dns.AAAA:
+					require.Equal(t, want.name, rr.Hdr.Name)
+					require.Equal(t, want.addr, rr.AAAA.String())
+				default:
+					t.Fatalf("unexpected record %#v", rr)
+				}
+			}
+		})
+	}
+
+	// Invalid hostnames are rejected at registration.
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			Service: "bad",
+			Port:    12345,
+			Meta:    map[string]string{structs.MetaDNSSRVTarget: "not_a host"},
+		},
+	}
+	var out struct{}
+	err := a.RPC("Catalog.Register", args, &out)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not a valid DNS name")
+}
+
 func TestDNS_ServiceLookup_WanAddress(t *testing.T) {
 	t.Parallel()
 	a1 := NewTestAgent(t, t.Name(), `
@@ -2675,6 +2801,67 @@ func TestDNS_ServiceLookup_TagPeriod(t *testing.T) {
 	}
 }
 
+func TestDNS_ServiceLookup_Routing(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Register a regular and a canary instance
+	for i, tags := range [][]string{nil, {"canary"}} {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       fmt.Sprintf("foo%d", i),
+			Address:    fmt.Sprintf("127.0.0.%d", i+1),
+			Service: &structs.NodeService{
+				Service: "db",
+				Tags:    tags,
+				Port:    12345,
+			},
+		}
+
+		var out struct{}
+		if err := a.RPC("Catalog.Register", args, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Send every query to the canary
+	args := &structs.ServiceRoutingRequest{
+		Datacenter: "dc1",
+		Op:         structs.ConfigEntryUpsert,
+		Routing: &structs.ServiceRoutingConfigEntry{
+			Name:       "db",
+			ForceTag:   "canary",
+			Percentage: 100,
+		},
+	}
+	var out struct{}
+	if err := a.RPC("ServiceRouting.Apply", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("db.service.consul.", dns.TypeA)
+
+	c := new(dns.Client)
+	in, _, err := c.Exchange(m, a.DNSAddr())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(in.Answer) != 1 {
+		t.Fatalf("Bad: %#v", in)
+	}
+	aRec, ok := in.Answer[0].(*dns.A)
+	if !ok {
+		t.Fatalf("Bad: %#v", in.Answer[0])
+	}
+	if aRec.A.String() != "127.0.0.2" {
+		t.Fatalf("Bad: %#v", in.Answer[0])
+	}
+}
+
 func TestDNS_PreparedQueryNearIPEDNS(t *testing.T) {
 	ipCoord := lib.GenerateCoordinate(1 * time.Millisecond)
 	serviceNodes := []struct {
@@ -3742,6 +3929,82 @@ func TestDNS_ServiceLookup_OnlyPassing(t *testing.T) {
 	}
 }
 
+func TestDNS_ServiceLookup_Drained(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Register an instance on a drained node and one on a node that isn't.
+	nodes := []struct {
+		name    string
+		address string
+		meta    map[string]string
+	}{
+		{"foo", "127.0.0.1", nil},
+		{"bar", "127.0.0.2", map[string]string{structs.NodeDrainedMetaKey: ""}},
+	}
+	for _, node := range nodes {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       node.name,
+			Address:    node.address,
+			NodeMeta:   node.meta,
+			Service: &structs.NodeService{
+				Service: "db",
+				Port:    12345,
+			},
+		}
+		var out struct{}
+		if err := a.RPC("Catalog.Register", args, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Register an equivalent prepared query.
+	var id string
+	{
+		args := &structs.PreparedQueryRequest{
+			Datacenter: "dc1",
+			Op:         structs.PreparedQueryCreate,
+			Query: &structs.PreparedQuery{
+				Name: "test",
+				Service: structs.ServiceQuery{
+					Service: "db",
+				},
+			},
+		}
+		if err := a.RPC("PreparedQuery.Apply", args, &id); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Look up the service directly and via prepared query.
+	questions := []string{
+		"db.service.consul.",
+		id + ".query.consul.",
+	}
+	for _, question := range questions {
+		m := new(dns.Msg)
+		m.SetQuestion(question, dns.TypeA)
+
+		c := new(dns.Client)
+		in, _, err := c.Exchange(m, a.DNSAddr())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The drained node is excluded
+		if len(in.Answer) != 1 {
+			t.Fatalf("Bad: %#v", in)
+		}
+		aRec := in.Answer[0].(*dns.A)
+		if aRec.A.String() != "127.0.0.1" {
+			t.Fatalf("Bad: %#v", in.Answer[0])
+		}
+	}
+}
+
 func TestDNS_ServiceLookup_Randomize(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
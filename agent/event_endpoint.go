@@ -2,7 +2,6 @@ package agent
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"net/http"
 	"strconv"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 const (
@@ -28,8 +28,7 @@ func (s *HTTPServer) EventFire(resp http.ResponseWriter, req *http.Request) (int
 	event := &UserEvent{}
 	event.Name = strings.TrimPrefix(req.URL.Path, "/v1/event/fire/")
 	if event.Name == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing name", nil)
 		return nil, nil
 	}
 
@@ -60,11 +59,9 @@ func (s *HTTPServer) EventFire(resp http.ResponseWriter, req *http.Request) (int
 	// Try to fire the event
 	if err := s.agent.UserEvent(dc, token, event); err != nil {
 		if acl.IsErrPermissionDenied(err) {
-			resp.WriteHeader(http.StatusForbidden)
-			fmt.Fprint(resp, acl.ErrPermissionDenied.Error())
+			s.writeError(resp, req, http.StatusForbidden, api.ErrCodeACLDenied, acl.ErrPermissionDenied.Error(), nil)
 			return nil, nil
 		}
-		resp.WriteHeader(http.StatusInternalServerError)
 		return nil, err
 	}
 
@@ -76,7 +73,7 @@ func (s *HTTPServer) EventFire(resp http.ResponseWriter, req *http.Request) (int
 func (s *HTTPServer) EventList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Parse the query options, since we simulate a blocking query
 	var b structs.QueryOptions
-	if parseWait(resp, req, &b) {
+	if s.parseWait(resp, req, &b) {
 		return nil, nil
 	}
 
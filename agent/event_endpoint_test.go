@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEventFire(t *testing.T) {
@@ -240,6 +241,61 @@ func TestEventList_ACLFilter(t *testing.T) {
 	})
 }
 
+func TestEventList_ACLFilter_Prefix(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), TestACLConfig()+`
+		acl_default_policy = "deny"
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Create a token that can only read the deploy events.
+	policyArgs := structs.ACLPolicySetRequest{
+		Datacenter: "dc1",
+		Policy: structs.ACLPolicy{
+			Name:  "deploy-events",
+			Rules: `event_prefix "deploy-" { policy = "read" }`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var policy structs.ACLPolicy
+	require.NoError(t, a.RPC("ACL.PolicySet", &policyArgs, &policy))
+
+	tokenArgs := structs.ACLTokenSetRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Policies: []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	require.NoError(t, a.RPC("ACL.TokenSet", &tokenArgs, &token))
+
+	// The token can't fire events, even the ones it can read.
+	err := a.UserEvent("dc1", token.SecretID, &UserEvent{Name: "deploy-web"})
+	require.True(t, acl.IsErrPermissionDenied(err), "err: %v", err)
+
+	// The events received over gossip are filtered by name.
+	for _, name := range []string{"deploy-web", "other", "deploy-db"} {
+		require.NoError(t, a.UserEvent("dc1", "root", &UserEvent{Name: name}))
+	}
+	retry.Run(t, func(r *retry.R) {
+		req, _ := http.NewRequest("GET", "/v1/event/list?token="+token.SecretID, nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.EventList(resp, req)
+		if err != nil {
+			r.Fatal(err)
+		}
+		list, ok := obj.([]*UserEvent)
+		if !ok {
+			r.Fatalf("bad: %#v", obj)
+		}
+		if len(list) != 2 || list[0].Name != "deploy-web" || list[1].Name != "deploy-db" {
+			r.Fatalf("bad: %#v", list)
+		}
+	})
+}
+
 func TestEventList_Blocking(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
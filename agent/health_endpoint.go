@@ -2,6 +2,7 @@ package agent
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	cachetype "github.com/hashicorp/consul/agent/cache-types"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/types"
 )
 
 func (s *HTTPServer) HealthChecksInState(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -23,8 +25,7 @@ func (s *HTTPServer) HealthChecksInState(resp http.ResponseWriter, req *http.Req
 	// Pull out the service name
 	args.State = strings.TrimPrefix(req.URL.Path, "/v1/health/state/")
 	if args.State == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing check state")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing check state", nil)
 		return nil, nil
 	}
 
@@ -53,6 +54,9 @@ RETRY_ONCE:
 			out.HealthChecks[i] = &clone
 		}
 	}
+	if summarizeOutput(req) {
+		out.HealthChecks = summarizeCheckOutputs(out.HealthChecks)
+	}
 	return out.HealthChecks, nil
 }
 
@@ -66,8 +70,7 @@ func (s *HTTPServer) HealthNodeChecks(resp http.ResponseWriter, req *http.Reques
 	// Pull out the service name
 	args.Node = strings.TrimPrefix(req.URL.Path, "/v1/health/node/")
 	if args.Node == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing node name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing node name", nil)
 		return nil, nil
 	}
 
@@ -96,9 +99,55 @@ RETRY_ONCE:
 			out.HealthChecks[i] = &clone
 		}
 	}
+	if summarizeOutput(req) {
+		out.HealthChecks = summarizeCheckOutputs(out.HealthChecks)
+	}
 	return out.HealthChecks, nil
 }
 
+// HealthNodeCheck returns a single check of a node with its full output, for
+// clients that listed the checks with their outputs summarized.
+func (s *HTTPServer) HealthNodeCheck(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Set default DC
+	args := structs.NodeSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	// Pull out the node name and check ID. Node names can't contain a slash
+	// but check IDs can, so everything after the first one is the check ID.
+	path := strings.TrimPrefix(req.URL.Path, "/v1/health/check/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing node name or check ID", nil)
+		return nil, nil
+	}
+	args.Node = parts[0]
+	checkID := types.CheckID(parts[1])
+
+	// Make the RPC request
+	var out structs.IndexedHealthChecks
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Health.NodeChecks", &args, &out); err != nil {
+		return nil, err
+	}
+
+	for _, c := range out.HealthChecks {
+		if c.CheckID != checkID {
+			continue
+		}
+		if c.ServiceTags == nil {
+			clone := *c
+			clone.ServiceTags = make([]string, 0)
+			c = &clone
+		}
+		return c, nil
+	}
+
+	s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, fmt.Sprintf("Unknown check %q on node %q", checkID, args.Node), nil)
+	return nil, nil
+}
+
 func (s *HTTPServer) HealthServiceChecks(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Set default DC
 	args := structs.ServiceSpecificRequest{}
@@ -111,8 +160,7 @@ func (s *HTTPServer) HealthServiceChecks(resp http.ResponseWriter, req *http.Req
 	// Pull out the service name
 	args.ServiceName = strings.TrimPrefix(req.URL.Path, "/v1/health/checks/")
 	if args.ServiceName == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing service name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service name", nil)
 		return nil, nil
 	}
 
@@ -141,6 +189,9 @@ RETRY_ONCE:
 			out.HealthChecks[i] = &clone
 		}
 	}
+	if summarizeOutput(req) {
+		out.HealthChecks = summarizeCheckOutputs(out.HealthChecks)
+	}
 	return out.HealthChecks, nil
 }
 
@@ -149,9 +200,84 @@ func (s *HTTPServer) HealthConnectServiceNodes(resp http.ResponseWriter, req *ht
 }
 
 func (s *HTTPServer) HealthServiceNodes(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Only /v1/health/service/<name>/history is the history, so a service
+	// named "history" can still be queried.
+	name := strings.TrimPrefix(req.URL.Path, "/v1/health/service/")
+	if strings.HasSuffix(name, "/history") && name != "/history" {
+		return s.HealthServiceHistory(resp, req)
+	}
 	return s.healthServiceNodes(resp, req, false)
 }
 
+// healthHistoryNote is returned with every health history response so that
+// consumers don't mistake it for durable data.
+const healthHistoryNote = "Health history is advisory. It is kept in memory by each server, " +
+	"is not replicated and is lost when the server restarts, so it may be incomplete or change after a leader election. " +
+	"Timestamps are when the answering server applied the change."
+
+// ServiceHealthHistory is the response of the health history endpoint.
+type ServiceHealthHistory struct {
+	Service     string
+	Transitions structs.HealthTransitions
+	Note        string
+}
+
+// HealthServiceHistory returns the recent health check status transitions
+// of a service.
+func (s *HTTPServer) HealthServiceHistory(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.ServiceSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	// Pull out the service name
+	args.ServiceName = strings.TrimPrefix(req.URL.Path, "/v1/health/service/")
+	args.ServiceName = strings.TrimSuffix(args.ServiceName, "/history")
+	if args.ServiceName == "" {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service name", nil)
+		return nil, nil
+	}
+
+	// Make the RPC request
+	var out structs.IndexedHealthTransitions
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Health.ServiceHistory", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Transitions == nil {
+		out.Transitions = make(structs.HealthTransitions, 0)
+	}
+	return ServiceHealthHistory{
+		Service:     args.ServiceName,
+		Transitions: out.Transitions,
+		Note:        healthHistoryNote,
+	}, nil
+}
+
+// HealthConnectMismatches returns the service instances that can't be reached
+// through Connect because their sidecar proxy is missing or critical.
+func (s *HTTPServer) HealthConnectMismatches(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.DCSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	// Make the RPC request
+	var out structs.IndexedConnectMismatches
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Internal.ConnectMismatches", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Mismatches == nil {
+		out.Mismatches = make(structs.ConnectMismatches, 0)
+	}
+	return out.Mismatches, nil
+}
+
 func (s *HTTPServer) healthServiceNodes(resp http.ResponseWriter, req *http.Request, connect bool) (interface{}, error) {
 	// Set default DC
 	args := structs.ServiceSpecificRequest{Connect: connect}
@@ -168,6 +294,16 @@ func (s *HTTPServer) healthServiceNodes(resp http.ResponseWriter, req *http.Requ
 		args.TagFilter = true
 	}
 
+	// The instances on drained nodes are excluded unless asked otherwise
+	if raw := params.Get("exclude-drained"); raw != "" {
+		exclude, err := strconv.ParseBool(raw)
+		if err != nil {
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Invalid value for ?exclude-drained", nil)
+			return nil, nil
+		}
+		args.IncludeDrained = !exclude
+	}
+
 	// Determine the prefix
 	prefix := "/v1/health/service/"
 	if connect {
@@ -177,8 +313,7 @@ func (s *HTTPServer) healthServiceNodes(resp http.ResponseWriter, req *http.Requ
 	// Pull out the service name
 	args.ServiceName = strings.TrimPrefix(req.URL.Path, prefix)
 	if args.ServiceName == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing service name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing service name", nil)
 		return nil, nil
 	}
 
@@ -223,8 +358,7 @@ func (s *HTTPServer) healthServiceNodes(resp http.ResponseWriter, req *http.Requ
 			var err error
 			filter, err = strconv.ParseBool(val)
 			if err != nil {
-				resp.WriteHeader(http.StatusBadRequest)
-				fmt.Fprint(resp, "Invalid value for ?passing")
+				s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Invalid value for ?passing", nil)
 				return nil, nil
 			}
 		}
@@ -234,6 +368,16 @@ func (s *HTTPServer) healthServiceNodes(resp http.ResponseWriter, req *http.Requ
 		}
 	}
 
+	// Apply the routing override of the service if asked to, the client
+	// address is used as the source so it consistently gets the same answer.
+	if params.Get("routing") == "apply" {
+		source, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			source = req.RemoteAddr
+		}
+		out.Nodes = out.Routing.Route(source, out.Nodes)
+	}
+
 	// Translate addresses after filtering so we don't waste effort.
 	s.agent.TranslateAddresses(args.Datacenter, out.Nodes)
 
@@ -258,9 +402,35 @@ func (s *HTTPServer) healthServiceNodes(resp http.ResponseWriter, req *http.Requ
 			out.Nodes[i].Service = &clone
 		}
 	}
+	if summarizeOutput(req) {
+		// Copy the nodes so a cached result isn't modified.
+		nodes := make(structs.CheckServiceNodes, len(out.Nodes))
+		for i, node := range out.Nodes {
+			node.Checks = summarizeCheckOutputs(node.Checks)
+			nodes[i] = node
+		}
+		out.Nodes = nodes
+	}
 	return out.Nodes, nil
 }
 
+// summarizeOutput returns true if the request asks for the check outputs to
+// be summarized with ?summarize-output=true.
+func summarizeOutput(req *http.Request) bool {
+	summarize, _ := strconv.ParseBool(req.URL.Query().Get("summarize-output"))
+	return summarize
+}
+
+// summarizeCheckOutputs returns the checks with their outputs summarized. The
+// checks are cloned so a cached result isn't modified.
+func summarizeCheckOutputs(checks structs.HealthChecks) structs.HealthChecks {
+	summarized := make(structs.HealthChecks, len(checks))
+	for i, c := range checks {
+		summarized[i] = c.SummarizeOutput()
+	}
+	return summarized
+}
+
 // filterNonPassing is used to filter out any nodes that have check that are not passing
 func filterNonPassing(nodes structs.CheckServiceNodes) structs.CheckServiceNodes {
 	n := len(nodes)
@@ -8,12 +8,14 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/sdk/testutil/retry"
 	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/serf/coordinate"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -553,6 +555,70 @@ func TestHealthServiceChecks_DistanceSort(t *testing.T) {
 	})
 }
 
+func TestHealthServiceHistory(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/health/service/web/history?dc=dc1", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+
+	// Should be a non-nil empty list
+	history := obj.(ServiceHealthHistory)
+	require.Equal(t, "web", history.Service)
+	require.NotNil(t, history.Transitions)
+	require.Empty(t, history.Transitions)
+	require.NotEmpty(t, history.Note)
+
+	// Register a service check and flip its status
+	for _, status := range []string{api.HealthPassing, api.HealthCritical} {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "bar",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      "web1",
+				Service: "web",
+			},
+			Check: &structs.HealthCheck{
+				Node:      "bar",
+				Name:      "web check",
+				CheckID:   "web-check",
+				ServiceID: "web1",
+				Status:    status,
+			},
+		}
+		var out struct{}
+		require.NoError(t, a.RPC("Catalog.Register", args, &out))
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/health/service/web/history?dc=dc1", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+
+	history = obj.(ServiceHealthHistory)
+	require.Len(t, history.Transitions, 1)
+	transition := history.Transitions[0]
+	require.Equal(t, "bar", transition.Node)
+	require.Equal(t, "web1", transition.ServiceID)
+	require.Equal(t, types.CheckID("web-check"), transition.CheckID)
+	require.Equal(t, api.HealthPassing, transition.OldStatus)
+	require.Equal(t, api.HealthCritical, transition.NewStatus)
+
+	// A service named "history" is still a service lookup.
+	req, _ = http.NewRequest("GET", "/v1/health/service/history?dc=dc1", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	require.IsType(t, structs.CheckServiceNodes{}, obj)
+}
+
 func TestHealthServiceNodes(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
@@ -1229,3 +1295,202 @@ func TestFilterNonPassing(t *testing.T) {
 		t.Fatalf("bad: %v", out)
 	}
 }
+
+func TestHealthConnectMismatches(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/internal/connect/mismatches?dc=dc1", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthConnectMismatches(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+
+	// Should be a non-nil empty list
+	require.NotNil(t, obj)
+	require.Empty(t, obj)
+
+	// Register a sidecar whose service is missing
+	args := structs.TestRegisterRequestProxy(t)
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", args, &out))
+
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthConnectMismatches(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+
+	mismatches := obj.(structs.ConnectMismatches)
+	require.Len(t, mismatches, 1)
+	require.Equal(t, args.Service.ID, mismatches[0].ProxyServiceID)
+	require.Equal(t, structs.ConnectMismatchDestinationMissing, mismatches[0].Reason)
+}
+
+func TestHealthServiceNodes_Routing(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	for i, tags := range [][]string{nil, {"canary"}} {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       fmt.Sprintf("node%d", i),
+			Address:    fmt.Sprintf("127.0.0.%d", i+1),
+			Service: &structs.NodeService{
+				Service: "web",
+				Tags:    tags,
+			},
+		}
+		var out struct{}
+		require.NoError(t, a.RPC("Catalog.Register", args, &out))
+	}
+
+	args := &structs.ServiceRoutingRequest{
+		Datacenter: "dc1",
+		Op:         structs.ConfigEntryUpsert,
+		Routing: &structs.ServiceRoutingConfigEntry{
+			Name:       "web",
+			ForceTag:   "canary",
+			Percentage: 100,
+		},
+	}
+	var out struct{}
+	require.NoError(t, a.RPC("ServiceRouting.Apply", args, &out))
+
+	// The override is only applied when asked for.
+	req, _ := http.NewRequest("GET", "/v1/health/service/web", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	require.Len(t, obj.(structs.CheckServiceNodes), 2)
+
+	req, _ = http.NewRequest("GET", "/v1/health/service/web?routing=apply", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	nodes := obj.(structs.CheckServiceNodes)
+	require.Len(t, nodes, 1)
+	require.Equal(t, "node1", nodes[0].Node.Node)
+}
+
+func TestHealthNodeChecks_SummarizeOutput(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	output := strings.Repeat("x", 4096)
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "bar",
+		Address:    "127.0.0.1",
+		Check: &structs.HealthCheck{
+			Node:    "bar",
+			CheckID: "disk/usage",
+			Name:    "disk",
+			Status:  api.HealthPassing,
+			Output:  output,
+		},
+	}
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", args, &out))
+
+	// The outputs are summarized when asked for.
+	req, _ := http.NewRequest("GET", "/v1/health/node/bar?summarize-output=true", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthNodeChecks(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	checks := obj.(structs.HealthChecks)
+	require.Len(t, checks, 1)
+	require.Len(t, checks[0].Output, structs.CheckOutputSummaryLength)
+	require.NotNil(t, checks[0].OutputDigest)
+	require.Equal(t, len(output), checks[0].OutputDigest.Length)
+
+	req, _ = http.NewRequest("GET", "/v1/health/state/passing?summarize-output=true", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthChecksInState(resp, req)
+	require.NoError(t, err)
+	for _, c := range obj.(structs.HealthChecks) {
+		require.NotNil(t, c.OutputDigest)
+	}
+
+	// And the full output can be fetched on its own.
+	req, _ = http.NewRequest("GET", "/v1/health/check/bar/disk/usage", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthNodeCheck(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	check := obj.(*structs.HealthCheck)
+	require.Equal(t, output, check.Output)
+	require.Nil(t, check.OutputDigest)
+
+	req, _ = http.NewRequest("GET", "/v1/health/check/bar/nope", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthNodeCheck(resp, req)
+	require.NoError(t, err)
+	require.Nil(t, obj)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	req, _ = http.NewRequest("GET", "/v1/health/check/bar", nil)
+	resp = httptest.NewRecorder()
+	_, err = a.srv.HealthNodeCheck(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestHealthServiceNodes_SummarizeOutput(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "bar",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "test",
+			Service: "test",
+		},
+		Check: &structs.HealthCheck{
+			Node:      "bar",
+			Name:      "test",
+			ServiceID: "test",
+			Output:    strings.Repeat("x", 4096),
+		},
+	}
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", args, &out))
+
+	for _, cached := range []bool{false, true} {
+		url := "/v1/health/service/test?summarize-output=true"
+		if cached {
+			url += "&cached"
+		}
+		req, _ := http.NewRequest("GET", url, nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.HealthServiceNodes(resp, req)
+		require.NoError(t, err)
+		nodes := obj.(structs.CheckServiceNodes)
+		require.Len(t, nodes, 1)
+		require.Len(t, nodes[0].Checks, 1)
+		require.Len(t, nodes[0].Checks[0].Output, structs.CheckOutputSummaryLength)
+		require.Equal(t, 4096, nodes[0].Checks[0].OutputDigest.Length)
+	}
+
+	// The cached result keeps the full output.
+	req, _ := http.NewRequest("GET", "/v1/health/service/test?cached", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	nodes := obj.(structs.CheckServiceNodes)
+	require.Len(t, nodes[0].Checks[0].Output, 4096)
+	require.Nil(t, nodes[0].Checks[0].OutputDigest)
+}
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
@@ -73,6 +74,41 @@ type HTTPServer struct {
 
 	// proto is filled by the agent to "http" or "https".
 	proto string
+
+	// profiles limits the number of concurrent runtime profiles.
+	profiles profileLimiter
+}
+
+// profileLimiter makes sure only one runtime profile of each type is being
+// captured at a time. Profiles and traces can run for a long time and are
+// expensive, so concurrent requests for the same type are rejected instead
+// of queued.
+type profileLimiter struct {
+	sync.Mutex
+	running map[string]struct{}
+}
+
+// acquire reserves the given profile type and returns false if a profile of
+// that type is already running.
+func (l *profileLimiter) acquire(name string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.running == nil {
+		l.running = make(map[string]struct{})
+	}
+	if _, ok := l.running[name]; ok {
+		return false
+	}
+	l.running[name] = struct{}{}
+	return true
+}
+
+// release frees up the given profile type.
+func (l *profileLimiter) release(name string) {
+	l.Lock()
+	defer l.Unlock()
+	delete(l.running, name)
 }
 
 type redirectFS struct {
@@ -168,28 +204,18 @@ func (s *HTTPServer) handler(enableDebug bool) http.Handler {
 	// and wraps it to add authorization and metrics
 	handlePProf := func(pattern string, handler http.HandlerFunc) {
 		wrapper := func(resp http.ResponseWriter, req *http.Request) {
-			var token string
-			s.parseToken(req, &token)
-
-			rule, err := s.agent.resolveToken(token)
-			if err != nil {
-				resp.WriteHeader(http.StatusForbidden)
+			if !s.checkDebugAccess(resp, req, enableDebug) {
 				return
 			}
 
-			// If enableDebug is not set, and ACLs are disabled, write
-			// an unauthorized response
-			if !enableDebug {
-				if s.checkACLDisabled(resp, req) {
+			// The index lists the available profiles, everything else
+			// captures one so only allow a single one of each at a time.
+			if name := strings.TrimPrefix(req.URL.Path, "/debug/pprof/"); name != "" {
+				if !s.profiles.acquire(name) {
+					s.writeProfileBusy(resp, req, name)
 					return
 				}
-			}
-
-			// If the token provided does not have the necessary permissions,
-			// write a forbidden response
-			if rule != nil && !rule.OperatorRead() {
-				resp.WriteHeader(http.StatusForbidden)
-				return
+				defer s.profiles.release(name)
 			}
 
 			// Call the pprof handler
@@ -214,7 +240,6 @@ func (s *HTTPServer) handler(enableDebug bool) http.Handler {
 	handlePProf("/debug/pprof/cmdline", pprof.Cmdline)
 	handlePProf("/debug/pprof/profile", pprof.Profile)
 	handlePProf("/debug/pprof/symbol", pprof.Symbol)
-	handlePProf("/debug/pprof/trace", pprof.Trace)
 
 	if s.IsUIEnabled() {
 		var uifs http.FileSystem
@@ -239,12 +264,81 @@ func (s *HTTPServer) handler(enableDebug bool) http.Handler {
 	if s.agent.config.DisableHTTPUnprintableCharFilter {
 		h = mux
 	}
+
+	// Serve everything under the configured path prefix, if any.
+	if prefix := s.agent.config.HTTPPathPrefix; prefix != "" {
+		h = pathPrefixHandler(prefix, h)
+	}
 	return &wrappedMux{
 		mux:     mux,
 		handler: h,
 	}
 }
 
+// pathPrefixHandler only serves requests below the given path prefix and
+// strips the prefix before handing them to the next handler, so endpoints
+// are registered and matched exactly as if there were no prefix.
+func pathPrefixHandler(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		if path == prefix {
+			path = prefix + "/"
+		}
+		if !strings.HasPrefix(path, prefix+"/") {
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Shallow copy the request so the original is left as it arrived.
+		r := new(http.Request)
+		*r = *req
+		r.URL = new(url.URL)
+		*r.URL = *req.URL
+		r.URL.Path = strings.TrimPrefix(path, prefix)
+		r.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefix)
+		next.ServeHTTP(resp, r)
+	})
+}
+
+// checkDebugAccess verifies the request is allowed to use the runtime
+// profiling endpoints. When ACLs are enabled an operator:read token is
+// required, otherwise enable_debug must be set. If access is denied the
+// response is written and false is returned.
+func (s *HTTPServer) checkDebugAccess(resp http.ResponseWriter, req *http.Request, enableDebug bool) bool {
+	var token string
+	s.parseToken(req, &token)
+
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		s.writeError(resp, req, http.StatusForbidden, api.ErrCodeACLDenied, err.Error(), nil)
+		return false
+	}
+
+	// If enableDebug is not set, and ACLs are disabled, write
+	// an unauthorized response
+	if !enableDebug {
+		if s.checkACLDisabled(resp, req) {
+			return false
+		}
+	}
+
+	// If the token provided does not have the necessary permissions,
+	// write a forbidden response
+	if rule != nil && !rule.OperatorRead() {
+		s.writeError(resp, req, http.StatusForbidden, api.ErrCodeACLDenied, acl.ErrPermissionDenied.Error(), nil)
+		return false
+	}
+
+	return true
+}
+
+// writeProfileBusy writes the response for a profile request rejected
+// because another profile of the same type is still running.
+func (s *HTTPServer) writeProfileBusy(resp http.ResponseWriter, req *http.Request, name string) {
+	s.writeError(resp, req, http.StatusTooManyRequests, api.ErrCodeRateLimited,
+		fmt.Sprintf("A %s profile is already in progress", name), nil)
+}
+
 // nodeName returns the node name of the agent
 func (s *HTTPServer) nodeName() string {
 	return s.agent.config.NodeName
@@ -301,8 +395,7 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 		if s.blacklist.Block(req.URL.Path) {
 			errMsg := "Endpoint is blocked by agent configuration"
 			s.agent.logger.Printf("[ERR] http: Request %s %v, error: %v from=%s", req.Method, logURL, err, req.RemoteAddr)
-			resp.WriteHeader(http.StatusForbidden)
-			fmt.Fprint(resp, errMsg)
+			s.writeError(resp, req, http.StatusForbidden, api.ErrCodeEndpointBlocked, errMsg, nil)
 			return
 		}
 
@@ -337,27 +430,39 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 			s.agent.logger.Printf("[ERR] http: Request %s %v, error: %v from=%s", req.Method, logURL, err, req.RemoteAddr)
 			switch {
 			case isForbidden(err):
-				resp.WriteHeader(http.StatusForbidden)
-				fmt.Fprint(resp, err.Error())
+				code := api.ErrCodeACLDenied
+				if acl.IsErrNotFound(err) {
+					code = api.ErrCodeACLNotFound
+				}
+				s.writeError(resp, req, http.StatusForbidden, code, err.Error(), nil)
 			case structs.IsErrRPCRateExceeded(err):
-				resp.WriteHeader(http.StatusTooManyRequests)
+				if s.wantsJSONErrors(req) {
+					s.writeError(resp, req, http.StatusTooManyRequests, api.ErrCodeRateLimited, err.Error(), nil)
+				} else {
+					resp.WriteHeader(http.StatusTooManyRequests)
+				}
 			case isMethodNotAllowed(err):
 				// RFC2616 states that for 405 Method Not Allowed the response
 				// MUST include an Allow header containing the list of valid
 				// methods for the requested resource.
 				// https://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html
-				addAllowHeader(err.(MethodNotAllowedError).Allow)
-				resp.WriteHeader(http.StatusMethodNotAllowed) // 405
-				fmt.Fprint(resp, err.Error())
+				allow := err.(MethodNotAllowedError).Allow
+				addAllowHeader(allow)
+				s.writeError(resp, req, http.StatusMethodNotAllowed, api.ErrCodeMethodNotAllowed, err.Error(), map[string]interface{}{"allow": allow})
 			case isBadRequest(err):
-				resp.WriteHeader(http.StatusBadRequest)
-				fmt.Fprint(resp, err.Error())
+				s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, err.Error(), nil)
+			case structs.IsErrSyncOwnerConflict(err):
+				s.writeError(resp, req, http.StatusConflict, api.ErrCodeSyncOwnerConflict, err.Error(), nil)
+			case structs.IsErrKVChecksumMismatch(err):
+				s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeChecksumMismatch, err.Error(), nil)
 			case isTooManyRequests(err):
-				resp.WriteHeader(http.StatusTooManyRequests)
-				fmt.Fprint(resp, err.Error())
+				s.writeError(resp, req, http.StatusTooManyRequests, api.ErrCodeRateLimited, err.Error(), nil)
+			case structs.IsErrNoLeader(err):
+				s.writeError(resp, req, http.StatusInternalServerError, api.ErrCodeNoLeader, err.Error(), nil)
+			case err.Error() == structs.ErrNoServers.Error():
+				s.writeError(resp, req, http.StatusInternalServerError, api.ErrCodeNoServers, err.Error(), nil)
 			default:
-				resp.WriteHeader(http.StatusInternalServerError)
-				fmt.Fprint(resp, err.Error())
+				s.writeError(resp, req, http.StatusInternalServerError, api.ErrCodeInternal, err.Error(), nil)
 			}
 		}
 
@@ -432,6 +537,66 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 	}
 }
 
+// httpErrorResponse is the body of a structured error response.
+type httpErrorResponse struct {
+	Error httpError `json:"error"`
+}
+
+type httpError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Detail  map[string]interface{} `json:"detail,omitempty"`
+}
+
+// wantsJSONErrors returns true if errors for this request should be returned
+// as structured JSON rather than plain text. Clients opt in with an
+// "Accept: application/json" header, wildcards don't count so that existing
+// clients keep getting plain text.
+func (s *HTTPServer) wantsJSONErrors(req *http.Request) bool {
+	if s.agent.config.HTTPJSONErrors {
+		return true
+	}
+	for _, accept := range req.Header["Accept"] {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if i := strings.Index(mediaType, ";"); i >= 0 {
+				mediaType = mediaType[:i]
+			}
+			if strings.EqualFold(strings.TrimSpace(mediaType), "application/json") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeError writes an error response with the given status code, either as
+// the plain text message or as a structured JSON error with the given code
+// and optional detail.
+func (s *HTTPServer) writeError(resp http.ResponseWriter, req *http.Request, status int, code, msg string, detail map[string]interface{}) {
+	if !s.wantsJSONErrors(req) {
+		resp.WriteHeader(status)
+		fmt.Fprint(resp, msg)
+		return
+	}
+
+	buf, err := s.marshalJSON(req, httpErrorResponse{
+		Error: httpError{
+			Code:    code,
+			Message: msg,
+			Detail:  detail,
+		},
+	})
+	if err != nil {
+		s.agent.logger.Printf("[ERR] http: Failed to encode error response: %v", err)
+		resp.WriteHeader(status)
+		fmt.Fprint(resp, msg)
+		return
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	resp.Write(buf)
+}
+
 // marshalJSON marshals the object into JSON, respecting the user's pretty-ness
 // configuration.
 func (s *HTTPServer) marshalJSON(req *http.Request, obj interface{}) ([]byte, error) {
@@ -472,7 +637,7 @@ func (s *HTTPServer) Index(resp http.ResponseWriter, req *http.Request) {
 	}
 
 	// Redirect to the UI endpoint
-	http.Redirect(resp, req, "/ui/", http.StatusMovedPermanently) // 301
+	http.Redirect(resp, req, s.agent.config.HTTPPathPrefix+"/ui/", http.StatusMovedPermanently) // 301
 }
 
 // decodeBody is used to decode a JSON request body
@@ -590,6 +755,9 @@ func setMeta(resp http.ResponseWriter, m *structs.QueryMeta) {
 	setLastContact(resp, m.LastContact)
 	setKnownLeader(resp, m.KnownLeader)
 	setConsistency(resp, m.ConsistencyLevel)
+	if m.Degraded {
+		resp.Header().Set("X-Consul-Degraded", "true")
+	}
 }
 
 // setCacheMeta sets http response headers to indicate cache status.
@@ -616,13 +784,12 @@ func setHeaders(resp http.ResponseWriter, headers map[string]string) {
 
 // parseWait is used to parse the ?wait and ?index query params
 // Returns true on error
-func parseWait(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
+func (s *HTTPServer) parseWait(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
 	query := req.URL.Query()
 	if wait := query.Get("wait"); wait != "" {
 		dur, err := time.ParseDuration(wait)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(resp, "Invalid wait time")
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Invalid wait time", nil)
 			return true
 		}
 		b.MaxQueryTime = dur
@@ -630,8 +797,7 @@ func parseWait(resp http.ResponseWriter, req *http.Request, b *structs.QueryOpti
 	if idx := query.Get("index"); idx != "" {
 		index, err := strconv.ParseUint(idx, 10, 64)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(resp, "Invalid index")
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Invalid index", nil)
 			return true
 		}
 		b.MinQueryIndex = index
@@ -641,7 +807,7 @@ func parseWait(resp http.ResponseWriter, req *http.Request, b *structs.QueryOpti
 
 // parseCacheControl parses the CacheControl HTTP header value. So far we only
 // support maxage directive.
-func parseCacheControl(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
+func (s *HTTPServer) parseCacheControl(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
 	raw := strings.ToLower(req.Header.Get("Cache-Control"))
 
 	if raw == "" {
@@ -658,8 +824,7 @@ func parseCacheControl(resp http.ResponseWriter, req *http.Request, b *structs.Q
 	parseDurationOrFail := func(raw string) (time.Duration, bool) {
 		i, err := strconv.Atoi(raw)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprint(resp, "Invalid Cache-Control header.")
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Invalid Cache-Control header.", nil)
 			return 0, true
 		}
 		return time.Duration(i) * time.Second, false
@@ -720,8 +885,7 @@ func (s *HTTPServer) parseConsistency(resp http.ResponseWriter, req *http.Reques
 	if maxStale := query.Get("max_stale"); maxStale != "" {
 		dur, err := time.ParseDuration(maxStale)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Invalid max_stale value %q", maxStale)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid max_stale value %q", maxStale), nil)
 			return true
 		}
 		b.MaxStaleDuration = dur
@@ -741,13 +905,11 @@ func (s *HTTPServer) parseConsistency(resp http.ResponseWriter, req *http.Reques
 		}
 	}
 	if b.AllowStale && b.RequireConsistent {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Cannot specify ?stale with ?consistent, conflicting semantics.")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Cannot specify ?stale with ?consistent, conflicting semantics.", nil)
 		return true
 	}
 	if b.UseCache && b.RequireConsistent {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Cannot specify ?cached with ?consistent, conflicting semantics.")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Cannot specify ?cached with ?consistent, conflicting semantics.", nil)
 		return true
 	}
 	return false
@@ -882,10 +1044,10 @@ func (s *HTTPServer) parseInternal(resp http.ResponseWriter, req *http.Request,
 	if s.parseConsistency(resp, req, b) {
 		return true
 	}
-	if parseCacheControl(resp, req, b) {
+	if s.parseCacheControl(resp, req, b) {
 		return true
 	}
-	return parseWait(resp, req, b)
+	return s.parseWait(resp, req, b)
 }
 
 // parse is a convenience method for endpoints that need
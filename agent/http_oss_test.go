@@ -191,7 +191,7 @@ func TestHTTPAPI_AllowedNets_OSS(t *testing.T) {
 			continue
 		}
 		for _, method := range methods {
-			if method == http.MethodGet {
+			if method == http.MethodGet || method == http.MethodHead {
 				continue
 			}
 
@@ -203,7 +203,7 @@ func TestHTTPAPI_AllowedNets_OSS(t *testing.T) {
 			continue
 		}
 		for _, method := range methods {
-			if method == http.MethodGet {
+			if method == http.MethodGet || method == http.MethodHead {
 				continue
 			}
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -18,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	tokenStore "github.com/hashicorp/consul/agent/token"
 	"github.com/hashicorp/consul/api"
@@ -319,6 +321,82 @@ func TestHTTPAPI_BlockEndpoints(t *testing.T) {
 	}
 }
 
+func TestHTTPAPI_PathPrefix(t *testing.T) {
+	t.Parallel()
+
+	a := NewTestAgent(t, t.Name(), `
+		primary_datacenter = "dc1"
+		acl {
+			enabled = true
+			default_policy = "deny"
+			tokens {
+				master = "root"
+			}
+		}
+		http_config {
+			path_prefix = "/consul"
+		}
+		ui = true
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	conf := api.DefaultConfig()
+	conf.Address = a.HTTPAddr()
+	conf.PathPrefix = "/consul"
+	conf.Token = "root"
+	client, err := api.NewClient(conf)
+	require.NoError(t, err)
+
+	t.Run("kv", func(t *testing.T) {
+		_, err := client.KV().Put(&api.KVPair{Key: "foo", Value: []byte("bar")}, nil)
+		require.NoError(t, err)
+		pair, _, err := client.KV().Get("foo", nil)
+		require.NoError(t, err)
+		require.NotNil(t, pair)
+		require.Equal(t, []byte("bar"), pair.Value)
+	})
+
+	t.Run("catalog", func(t *testing.T) {
+		nodes, _, err := client.Catalog().Nodes(nil)
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		require.Equal(t, a.Config.NodeName, nodes[0].Node)
+	})
+
+	t.Run("acl", func(t *testing.T) {
+		policy, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{
+			Name:  "web",
+			Rules: `service "web" { policy = "read" }`,
+		}, nil)
+		require.NoError(t, err)
+
+		read, _, err := client.ACL().PolicyRead(policy.ID, nil)
+		require.NoError(t, err)
+		require.Equal(t, "web", read.Name)
+	})
+
+	t.Run("unprefixed paths are not served", func(t *testing.T) {
+		resp, err := http.Get("http://" + a.HTTPAddr() + "/v1/agent/self")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, err = http.Get("http://" + a.HTTPAddr() + "/consulfoo/v1/agent/self")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("ui redirect", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/consul", nil)
+		resp := httptest.NewRecorder()
+		a.srv.Handler.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusMovedPermanently, resp.Code)
+		require.Equal(t, "/consul/ui/", resp.Header().Get("Location"))
+	})
+}
+
 func TestHTTPAPI_Ban_Nonprintable_Characters(t *testing.T) {
 	a := NewTestAgent(t, t.Name(), "")
 	defer a.Shutdown()
@@ -432,6 +510,142 @@ func TestHTTPAPIResponseHeaders(t *testing.T) {
 	}
 }
 
+func TestHTTPAPI_JSONErrors(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail map[string]interface{}
+	}{
+		{"permission denied", acl.ErrPermissionDenied, http.StatusForbidden, api.ErrCodeACLDenied, nil},
+		{"acl not found", acl.ErrNotFound, http.StatusForbidden, api.ErrCodeACLNotFound, nil},
+		{"bad request", BadRequestError{Reason: "nope"}, http.StatusBadRequest, api.ErrCodeBadRequest, nil},
+		{"rate limited", structs.ErrRPCRateExceeded, http.StatusTooManyRequests, api.ErrCodeRateLimited, nil},
+		{"no leader", structs.ErrNoLeader, http.StatusInternalServerError, api.ErrCodeNoLeader, nil},
+		{"no servers", structs.ErrNoServers, http.StatusInternalServerError, api.ErrCodeNoServers, nil},
+		{"internal", errors.New("boom"), http.StatusInternalServerError, api.ErrCodeInternal, nil},
+		{"method not allowed", MethodNotAllowedError{"PUT", []string{"GET"}}, http.StatusMethodNotAllowed, api.ErrCodeMethodNotAllowed,
+			map[string]interface{}{"allow": []interface{}{"GET"}}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+				return nil, tc.err
+			}
+
+			// Plain text unless asked for.
+			req, _ := http.NewRequest("GET", "/v1/kv/foo", nil)
+			resp := httptest.NewRecorder()
+			a.srv.wrap(handler, nil)(resp, req)
+			require.Equal(t, tc.status, resp.Code)
+			if tc.code != api.ErrCodeRateLimited {
+				require.Equal(t, tc.err.Error(), resp.Body.String())
+			}
+
+			req, _ = http.NewRequest("GET", "/v1/kv/foo", nil)
+			req.Header.Set("Accept", "text/html, application/json;q=0.9")
+			resp = httptest.NewRecorder()
+			a.srv.wrap(handler, nil)(resp, req)
+			require.Equal(t, tc.status, resp.Code)
+			require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+			var out struct {
+				Error struct {
+					Code    string
+					Message string
+					Detail  map[string]interface{}
+				}
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+			require.Equal(t, tc.code, out.Error.Code)
+			require.Equal(t, tc.err.Error(), out.Error.Message)
+			require.Equal(t, tc.detail, out.Error.Detail)
+		})
+	}
+
+	// Wildcards don't count as asking for JSON.
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return nil, errors.New("boom")
+	}
+	req, _ := http.NewRequest("GET", "/v1/kv/foo", nil)
+	req.Header.Set("Accept", "*/*")
+	resp := httptest.NewRecorder()
+	a.srv.wrap(handler, nil)(resp, req)
+	require.Equal(t, "boom", resp.Body.String())
+}
+
+func TestHTTPAPI_JSONErrors_Endpoints(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	cases := []struct {
+		name   string
+		method string
+		url    string
+		body   io.Reader
+		status int
+		code   string
+	}{
+		{"invalid wait", "GET", "/v1/catalog/nodes?wait=60foo", nil, http.StatusBadRequest, api.ErrCodeBadRequest},
+		{"conflicting consistency", "GET", "/v1/catalog/nodes?stale&consistent", nil, http.StatusBadRequest, api.ErrCodeBadRequest},
+		{"missing key", "PUT", "/v1/kv/", nil, http.StatusBadRequest, api.ErrCodeBadRequest},
+		{"value too large", "PUT", "/v1/kv/foo", bytes.NewReader(make([]byte, maxKVSize+1)), http.StatusRequestEntityTooLarge, api.ErrCodeTooLarge},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, tc.url, tc.body)
+			req.Header.Set("Accept", "application/json")
+			resp := httptest.NewRecorder()
+			a.srv.Handler.ServeHTTP(resp, req)
+			require.Equal(t, tc.status, resp.Code)
+			require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+			var out struct {
+				Error struct {
+					Code    string
+					Message string
+				}
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+			require.Equal(t, tc.code, out.Error.Code)
+			require.NotEmpty(t, out.Error.Message)
+		})
+	}
+}
+
+func TestHTTPAPI_JSONErrors_Config(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		http_config {
+			json_errors = true
+			block_endpoints = ["/v1/agent/self"]
+		}
+	`)
+	defer a.Shutdown()
+
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return nil, nil
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/agent/self", nil)
+	resp := httptest.NewRecorder()
+	a.srv.wrap(handler, []string{"GET"})(resp, req)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	require.JSONEq(t,
+		`{"error":{"code":"endpoint_blocked","message":"Endpoint is blocked by agent configuration"}}`,
+		resp.Body.String())
+}
+
 func TestContentTypeIsJSON(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
@@ -699,6 +913,9 @@ func TestParseCacheControl(t *testing.T) {
 		},
 	}
 
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			require := require.New(t)
@@ -711,7 +928,7 @@ func TestParseCacheControl(t *testing.T) {
 			rr := httptest.NewRecorder()
 			var got structs.QueryOptions
 
-			failed := parseCacheControl(rr, r, &got)
+			failed := a.srv.parseCacheControl(rr, r, &got)
 			if tt.wantErr {
 				require.True(failed)
 				require.Equal(http.StatusBadRequest, rr.Code)
@@ -730,7 +947,9 @@ func TestParseWait(t *testing.T) {
 	var b structs.QueryOptions
 
 	req, _ := http.NewRequest("GET", "/v1/catalog/nodes?wait=60s&index=1000", nil)
-	if d := parseWait(resp, req, &b); d {
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	if d := a.srv.parseWait(resp, req, &b); d {
 		t.Fatalf("unexpected done")
 	}
 
@@ -768,6 +987,41 @@ func TestPProfHandlers_DisableDebugNoACLs(t *testing.T) {
 	require.Equal(http.StatusUnauthorized, resp.Code)
 }
 
+func TestPProfHandlers_OneProfilePerType(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	a := NewTestAgent(t, t.Name(), "enable_debug = true")
+	defer a.Shutdown()
+
+	// Pretend a trace is already being captured.
+	require.True(a.srv.profiles.acquire("trace"))
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/agent/debug/trace?seconds=1", nil)
+	a.srv.Handler.ServeHTTP(resp, req)
+	require.Equal(http.StatusTooManyRequests, resp.Code)
+
+	// Other profile types are not affected.
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/debug/pprof/heap", nil)
+	a.srv.Handler.ServeHTTP(resp, req)
+	require.Equal(http.StatusOK, resp.Code)
+
+	a.srv.profiles.release("trace")
+
+	// Traces are only captured by the agent endpoint.
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/debug/pprof/trace?seconds=1", nil)
+	a.srv.Handler.ServeHTTP(resp, req)
+	require.Equal(http.StatusNotFound, resp.Code)
+
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/agent/debug/trace?seconds=1", nil)
+	a.srv.Handler.ServeHTTP(resp, req)
+	require.Equal(http.StatusOK, resp.Code)
+	require.NotEmpty(resp.Body.Bytes())
+}
+
 func TestPProfHandlers_ACLs(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -825,6 +1079,18 @@ func TestPProfHandlers_ACLs(t *testing.T) {
 			endpoint:    "/debug/pprof/heap",
 			nilResponse: true,
 		},
+		{
+			code:        http.StatusOK,
+			token:       "master",
+			endpoint:    "/v1/agent/debug/trace",
+			nilResponse: false,
+		},
+		{
+			code:        http.StatusForbidden,
+			token:       "agent",
+			endpoint:    "/v1/agent/debug/trace",
+			nilResponse: true,
+		},
 	}
 
 	defer a.Shutdown()
@@ -846,7 +1112,9 @@ func TestParseWait_InvalidTime(t *testing.T) {
 	var b structs.QueryOptions
 
 	req, _ := http.NewRequest("GET", "/v1/catalog/nodes?wait=60foo&index=1000", nil)
-	if d := parseWait(resp, req, &b); !d {
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	if d := a.srv.parseWait(resp, req, &b); !d {
 		t.Fatalf("expected done")
 	}
 
@@ -861,7 +1129,9 @@ func TestParseWait_InvalidIndex(t *testing.T) {
 	var b structs.QueryOptions
 
 	req, _ := http.NewRequest("GET", "/v1/catalog/nodes?wait=60s&index=foo", nil)
-	if d := parseWait(resp, req, &b); !d {
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	if d := a.srv.parseWait(resp, req, &b); !d {
 		t.Fatalf("expected done")
 	}
 
@@ -43,23 +43,26 @@ func (s *HTTPServer) KVSEndpoint(resp http.ResponseWriter, req *http.Request) (i
 			return s.KVSGetKeys(resp, req, &args)
 		}
 		return s.KVSGet(resp, req, &args)
+	case "HEAD":
+		return s.KVSGet(resp, req, &args)
 	case "PUT":
 		return s.KVSPut(resp, req, &args)
 	case "DELETE":
 		return s.KVSDelete(resp, req, &args)
 	default:
-		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "HEAD", "PUT", "DELETE"}}
 	}
 }
 
-// KVSGet handles a GET request
+// KVSGet handles a GET request. A HEAD request gets the same headers without
+// the body, which in raw mode gives the size of the value without sending it.
 func (s *HTTPServer) KVSGet(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
 	// Check for recurse
 	method := "KVS.Get"
 	params := req.URL.Query()
 	if _, ok := params["recurse"]; ok {
 		method = "KVS.List"
-	} else if missingKey(resp, args) {
+	} else if s.missingKey(resp, req, args) {
 		return nil, nil
 	}
 
@@ -76,18 +79,62 @@ func (s *HTTPServer) KVSGet(resp http.ResponseWriter, req *http.Request, args *s
 		return nil, nil
 	}
 
+	// Verify the values that were written with a checksum, so a value that
+	// was corrupted in storage isn't served. A recursive read leaves out the
+	// corrupted entries and names them in a header, so one of them doesn't
+	// fail the whole listing.
+	if method == "KVS.Get" {
+		if err := out.Entries[0].VerifyChecksum(); err != nil {
+			s.writeError(resp, req, http.StatusInternalServerError, api.ErrCodeChecksumMismatch, err.Error(), nil)
+			return nil, nil
+		}
+	} else {
+		var corrupted []string
+		out.Entries, corrupted = filterKVChecksums(out.Entries)
+		for _, key := range corrupted {
+			s.agent.logger.Printf("[WARN] agent: Checksum mismatch for key %q, leaving it out of the listing", key)
+			resp.Header().Add("X-Consul-KV-Checksum-Mismatch", key)
+		}
+	}
+
+	// Give the checksum of a single key in a header, so clients can check
+	// the value they receive without decoding the body
+	if method == "KVS.Get" && out.Entries[0].Checksum != "" {
+		resp.Header().Set("X-Consul-KV-Checksum", out.Entries[0].Checksum)
+	}
+
 	// Check if we are in raw mode with a normal get, write out
 	// the raw body
 	if _, ok := params["raw"]; ok && method == "KVS.Get" {
 		body := out.Entries[0].Value
 		resp.Header().Set("Content-Length", strconv.FormatInt(int64(len(body)), 10))
-		resp.Write(body)
+		if req.Method != "HEAD" {
+			resp.Write(body)
+		}
+		return nil, nil
+	}
+	if req.Method == "HEAD" {
 		return nil, nil
 	}
 
 	return out.Entries, nil
 }
 
+// filterKVChecksums returns the entries whose value matches their checksum,
+// and the keys of the ones that don't.
+func filterKVChecksums(entries structs.DirEntries) (structs.DirEntries, []string) {
+	var corrupted []string
+	valid := entries[:0]
+	for _, e := range entries {
+		if err := e.VerifyChecksum(); err != nil {
+			corrupted = append(corrupted, e.Key)
+			continue
+		}
+		valid = append(valid, e)
+	}
+	return valid, corrupted
+}
+
 // KVSGetKeys handles a GET request for keys
 func (s *HTTPServer) KVSGetKeys(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
 	// Check for a separator, due to historic spelling error,
@@ -132,10 +179,10 @@ func (s *HTTPServer) KVSGetKeys(resp http.ResponseWriter, req *http.Request, arg
 
 // KVSPut handles a PUT request
 func (s *HTTPServer) KVSPut(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
-	if missingKey(resp, args) {
+	if s.missingKey(resp, req, args) {
 		return nil, nil
 	}
-	if conflictingFlags(resp, req, "cas", "acquire", "release") {
+	if s.conflictingFlags(resp, req, "cas", "acquire", "release") {
 		return nil, nil
 	}
 	applyReq := structs.KVSRequest{
@@ -169,6 +216,16 @@ func (s *HTTPServer) KVSPut(resp http.ResponseWriter, req *http.Request, args *s
 		applyReq.Op = api.KVCAS
 	}
 
+	// Check for a checksum of the value, which the servers verify before
+	// the write is committed
+	if _, ok := params["checksum"]; ok {
+		checksum, err := structs.ParseKVChecksum(params.Get("checksum"))
+		if err != nil {
+			return nil, BadRequestError{Reason: err.Error()}
+		}
+		applyReq.DirEnt.Checksum = checksum
+	}
+
 	// Check for lock acquisition
 	if _, ok := params["acquire"]; ok {
 		applyReq.DirEnt.Session = params.Get("acquire")
@@ -183,8 +240,7 @@ func (s *HTTPServer) KVSPut(resp http.ResponseWriter, req *http.Request, args *s
 
 	// Check the content-length
 	if req.ContentLength > maxKVSize {
-		resp.WriteHeader(http.StatusRequestEntityTooLarge)
-		fmt.Fprintf(resp, "Value exceeds %d byte limit", maxKVSize)
+		s.writeError(resp, req, http.StatusRequestEntityTooLarge, api.ErrCodeTooLarge, fmt.Sprintf("Value exceeds %d byte limit", maxKVSize), nil)
 		return nil, nil
 	}
 
@@ -210,7 +266,7 @@ func (s *HTTPServer) KVSPut(resp http.ResponseWriter, req *http.Request, args *s
 
 // KVSPut handles a DELETE request
 func (s *HTTPServer) KVSDelete(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
-	if conflictingFlags(resp, req, "recurse", "cas") {
+	if s.conflictingFlags(resp, req, "recurse", "cas") {
 		return nil, nil
 	}
 	applyReq := structs.KVSRequest{
@@ -226,7 +282,7 @@ func (s *HTTPServer) KVSDelete(resp http.ResponseWriter, req *http.Request, args
 	params := req.URL.Query()
 	if _, ok := params["recurse"]; ok {
 		applyReq.Op = api.KVDeleteTree
-	} else if missingKey(resp, args) {
+	} else if s.missingKey(resp, req, args) {
 		return nil, nil
 	}
 
@@ -253,26 +309,56 @@ func (s *HTTPServer) KVSDelete(resp http.ResponseWriter, req *http.Request, args
 	return true, nil
 }
 
+// KVSDeletedEndpoint returns the retained deletions of a key, or of all the
+// keys with a prefix when recurse is given.
+func (s *HTTPServer) KVSDeletedEndpoint(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.KeyRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+	args.Key = strings.TrimPrefix(req.URL.Path, "/v1/kv-deleted/")
+
+	// Check for recurse
+	method := "KVS.GetDeleted"
+	if _, ok := req.URL.Query()["recurse"]; ok {
+		method = "KVS.ListDeleted"
+	} else if s.missingKey(resp, req, &args) {
+		return nil, nil
+	}
+
+	// Make the RPC
+	var out structs.IndexedDeletedDirEntries
+	if err := s.agent.RPC(method, &args, &out); err != nil {
+		return nil, err
+	}
+	setMeta(resp, &out.QueryMeta)
+
+	// Check if we get a not found
+	if len(out.Entries) == 0 {
+		resp.WriteHeader(http.StatusNotFound)
+		return nil, nil
+	}
+	return out.Entries, nil
+}
+
 // missingKey checks if the key is missing
-func missingKey(resp http.ResponseWriter, args *structs.KeyRequest) bool {
+func (s *HTTPServer) missingKey(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) bool {
 	if args.Key == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing key name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing key name", nil)
 		return true
 	}
 	return false
 }
 
 // conflictingFlags determines if non-composable flags were passed in a request.
-func conflictingFlags(resp http.ResponseWriter, req *http.Request, flags ...string) bool {
+func (s *HTTPServer) conflictingFlags(resp http.ResponseWriter, req *http.Request, flags ...string) bool {
 	params := req.URL.Query()
 
 	found := false
 	for _, conflict := range flags {
 		if _, ok := params[conflict]; ok {
 			if found {
-				resp.WriteHeader(http.StatusBadRequest)
-				fmt.Fprint(resp, "Conflicting flags: "+params.Encode())
+				s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Conflicting flags: "+params.Encode(), nil)
 				return true
 			}
 			found = true
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/consul/testrpc"
 
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKVSEndpoint_PUT_GET_DELETE(t *testing.T) {
@@ -428,6 +429,155 @@ func TestKVSEndpoint_GET_Raw(t *testing.T) {
 	}
 }
 
+func TestKVSEndpoint_HEAD_Raw(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	buf := bytes.NewBuffer([]byte("test"))
+	req, _ := http.NewRequest("PUT", "/v1/kv/test", buf)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if res := obj.(bool); !res {
+		t.Fatalf("should work")
+	}
+
+	// The size is returned without the value.
+	req, _ = http.NewRequest("HEAD", "/v1/kv/test?raw", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSEndpoint(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertIndex(t, resp)
+	if obj != nil {
+		t.Fatalf("bad: %v", obj)
+	}
+	if got := resp.Header().Get("Content-Length"); got != "4" {
+		t.Fatalf("bad: %s", got)
+	}
+	if resp.Body.Len() != 0 {
+		t.Fatalf("bad: %s", resp.Body.Bytes())
+	}
+
+	req, _ = http.NewRequest("HEAD", "/v1/kv/missing?raw", nil)
+	resp = httptest.NewRecorder()
+	if _, err := a.srv.KVSEndpoint(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("bad: %d", resp.Code)
+	}
+}
+
+func TestKVSEndpoint_Checksum(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+
+	sum := structs.KVChecksum([]byte("test"))
+
+	// The value is verified against the checksum before it's written.
+	req, _ := http.NewRequest("PUT", "/v1/kv/test?checksum="+sum, bytes.NewBuffer([]byte("test")))
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, true, obj)
+
+	req, _ = http.NewRequest("PUT", "/v1/kv/test?checksum="+sum, bytes.NewBuffer([]byte("corrupted")))
+	resp = httptest.NewRecorder()
+	_, err = a.srv.KVSEndpoint(resp, req)
+	require.True(t, structs.IsErrKVChecksumMismatch(err), "err: %v", err)
+
+	req, _ = http.NewRequest("PUT", "/v1/kv/test?checksum=md5:nope", bytes.NewBuffer([]byte("test")))
+	resp = httptest.NewRecorder()
+	_, err = a.srv.KVSEndpoint(resp, req)
+	require.IsType(t, BadRequestError{}, err)
+
+	// The checksum is returned with the entry and in a header.
+	req, _ = http.NewRequest("GET", "/v1/kv/test", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, sum, resp.Header().Get("X-Consul-KV-Checksum"))
+	entries := obj.(structs.DirEntries)
+	require.Len(t, entries, 1)
+	require.Equal(t, sum, entries[0].Checksum)
+	require.Equal(t, []byte("test"), entries[0].Value)
+
+	req, _ = http.NewRequest("GET", "/v1/kv/test?raw", nil)
+	resp = httptest.NewRecorder()
+	_, err = a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, sum, resp.Header().Get("X-Consul-KV-Checksum"))
+	require.Equal(t, "test", resp.Body.String())
+}
+
+func TestFilterKVChecksums(t *testing.T) {
+	t.Parallel()
+	entries := structs.DirEntries{
+		{Key: "a", Value: []byte("a"), Checksum: structs.KVChecksum([]byte("a"))},
+		{Key: "b", Value: []byte("corrupted"), Checksum: structs.KVChecksum([]byte("b"))},
+		{Key: "c", Value: []byte("c")},
+	}
+	valid, corrupted := filterKVChecksums(entries)
+	require.Len(t, valid, 2)
+	require.Equal(t, "a", valid[0].Key)
+	require.Equal(t, "c", valid[1].Key)
+	require.Equal(t, []string{"b"}, corrupted)
+}
+
+func TestKVSDeletedEndpoint(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), `
+		kv_deleted_retention = "1h"
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	for _, key := range []string{"foo/bar", "foo/baz"} {
+		req, _ := http.NewRequest("PUT", "/v1/kv/"+key, bytes.NewBuffer([]byte(key)))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.KVSEndpoint(resp, req)
+		require.NoError(t, err)
+	}
+	req, _ := http.NewRequest("DELETE", "/v1/kv/foo?recurse", nil)
+	resp := httptest.NewRecorder()
+	_, err := a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("GET", "/v1/kv-deleted/foo?recurse", nil)
+	resp = httptest.NewRecorder()
+	obj, err := a.srv.KVSDeletedEndpoint(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	entries := obj.(structs.DeletedDirEntries)
+	require.Len(t, entries, 2)
+	require.Equal(t, "foo/bar", entries[0].Key)
+	require.Equal(t, []byte("foo/bar"), entries[0].Value)
+	require.Equal(t, "foo/baz", entries[1].Key)
+
+	req, _ = http.NewRequest("GET", "/v1/kv-deleted/foo/baz", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSDeletedEndpoint(resp, req)
+	require.NoError(t, err)
+	entries = obj.(structs.DeletedDirEntries)
+	require.Len(t, entries, 1)
+	require.Equal(t, "foo/baz", entries[0].Key)
+	require.False(t, entries[0].DeletedAt.IsZero())
+
+	// A key that wasn't deleted isn't found.
+	req, _ = http.NewRequest("GET", "/v1/kv-deleted/nope", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSDeletedEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Nil(t, obj)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
 func TestKVSEndpoint_PUT_ConflictingFlags(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t, t.Name(), "")
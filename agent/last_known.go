@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/pool"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib/file"
+)
+
+const (
+	// Path to save the last known health service query results
+	lastKnownDir = "discovery"
+
+	// degradedDNSTTL is the TTL used for DNS answers built from a persisted
+	// result so that clients come back quickly once the servers recover.
+	degradedDNSTTL = time.Second
+
+	// lastKnownPruneInterval is how often the persisted results past the
+	// maximum age are removed, so the results of queries that are no longer
+	// made don't pile up.
+	lastKnownPruneInterval = time.Hour
+)
+
+// persistedServiceNodes is the on-disk format of a persisted
+// Health.ServiceNodes result.
+type persistedServiceNodes struct {
+	Saved time.Time
+	Reply *structs.IndexedCheckServiceNodes
+}
+
+// lastKnownStore persists the last successful result of health service
+// queries so they can still be answered while the servers are unreachable.
+// Results are keyed by the full request, including the ACL token, so a
+// persisted result is only ever served to a request that was allowed to see
+// it in the first place.
+type lastKnownStore struct {
+	dir    string
+	maxAge time.Duration
+	logger *log.Logger
+
+	// now is used to get the current time, it can be overridden in tests.
+	now func() time.Time
+
+	// indexes tracks the index of the result last written for each key so
+	// that blocking queries returning the same data don't rewrite the file.
+	indexes map[string]uint64
+	lock    sync.Mutex
+}
+
+func newLastKnownStore(dir string, maxAge time.Duration, logger *log.Logger) *lastKnownStore {
+	return &lastKnownStore{
+		dir:     dir,
+		maxAge:  maxAge,
+		logger:  logger,
+		now:     time.Now,
+		indexes: make(map[string]uint64),
+	}
+}
+
+// lastKnownKey returns the file name used for the given request. Options
+// that only affect how the query is performed, like blocking or staleness,
+// are not part of the key.
+func lastKnownKey(args *structs.ServiceSpecificRequest) (string, error) {
+	key := struct {
+		Datacenter      string
+		ServiceName     string
+		ServiceTags     []string
+		TagFilter       bool
+		Connect         bool
+		NodeMetaFilters map[string]string
+		Filter          string
+		IncludeDrained  bool
+		Token           string
+	}{
+		Datacenter:      args.Datacenter,
+		ServiceName:     args.ServiceName,
+		ServiceTags:     args.ServiceTags,
+		TagFilter:       args.TagFilter,
+		Connect:         args.Connect,
+		NodeMetaFilters: args.NodeMetaFilters,
+		Filter:          args.Filter,
+		IncludeDrained:  args.IncludeDrained,
+		Token:           args.Token,
+	}
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(encoded)), nil
+}
+
+// Save persists a successful result.
+func (s *lastKnownStore) Save(args *structs.ServiceSpecificRequest, reply *structs.IndexedCheckServiceNodes) error {
+	key, err := lastKnownKey(args)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if idx, ok := s.indexes[key]; ok && idx == reply.Index {
+		return nil
+	}
+
+	encoded, err := json.Marshal(persistedServiceNodes{
+		Saved: s.now(),
+		Reply: reply,
+	})
+	if err != nil {
+		return err
+	}
+	if err := file.WriteAtomicWithPerms(filepath.Join(s.dir, key), encoded, 0600); err != nil {
+		return err
+	}
+	s.indexes[key] = reply.Index
+	return nil
+}
+
+// Load returns the persisted result for the request, or nil if there is no
+// result or it is older than the maximum age. Expired results are removed.
+func (s *lastKnownStore) Load(args *structs.ServiceSpecificRequest) (*structs.IndexedCheckServiceNodes, error) {
+	key, err := lastKnownKey(args)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	path := filepath.Join(s.dir, key)
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var p persistedServiceNodes
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return nil, fmt.Errorf("failed decoding persisted result: %v", err)
+	}
+	if p.Reply == nil || s.now().Sub(p.Saved) > s.maxAge {
+		delete(s.indexes, key)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Printf("[WARN] agent: failed removing expired discovery result: %v", err)
+		}
+		return nil, nil
+	}
+	return p.Reply, nil
+}
+
+// Prune removes the persisted results that are older than the maximum age.
+// Results are only rewritten when they change, so the age is taken from the
+// modification time of their file, which is when they were saved.
+func (s *lastKnownStore) Prune() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		if fi.IsDir() || s.now().Sub(fi.ModTime()) <= s.maxAge {
+			continue
+		}
+		delete(s.indexes, fi.Name())
+		if err := os.Remove(filepath.Join(s.dir, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// run prunes the persisted results at startup and every
+// lastKnownPruneInterval until the shutdown channel is closed.
+func (s *lastKnownStore) run(shutdownCh <-chan struct{}) {
+	for {
+		if err := s.Prune(); err != nil {
+			s.logger.Printf("[WARN] agent: failed removing expired discovery results: %v", err)
+		}
+		select {
+		case <-time.After(lastKnownPruneInterval):
+		case <-shutdownCh:
+			return
+		}
+	}
+}
+
+// isServerUnavailable returns true if the error means no server could be
+// reached, as opposed to an error returned by a server.
+func isServerUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == structs.ErrNoServers || structs.IsErrNoLeader(err) {
+		return true
+	}
+	return pool.IsConnError(err)
+}
+
+// serviceNodesRPC performs a Health.ServiceNodes RPC, persisting successful
+// results and falling back to the last known result, marked as degraded, when
+// the servers can't be reached.
+func (a *Agent) serviceNodesRPC(method string, args *structs.ServiceSpecificRequest, reply *structs.IndexedCheckServiceNodes) error {
+	err := a.delegate.RPC(method, args, reply)
+	if err == nil {
+		if err := a.lastKnown.Save(args, reply); err != nil {
+			a.logger.Printf("[WARN] agent: failed persisting discovery result for service %q: %v", args.ServiceName, err)
+		}
+		return nil
+	}
+	if !isServerUnavailable(err) {
+		return err
+	}
+
+	last, lerr := a.lastKnown.Load(args)
+	if lerr != nil {
+		a.logger.Printf("[WARN] agent: failed loading discovery result for service %q: %v", args.ServiceName, lerr)
+		return err
+	}
+	// Only answer if this would unblock the query, otherwise a blocking
+	// caller such as the cache would spin on the same result.
+	if last == nil || (args.MinQueryIndex > 0 && last.Index <= args.MinQueryIndex) {
+		return err
+	}
+
+	*reply = *last
+	reply.Degraded = true
+	reply.KnownLeader = false
+	metrics.IncrCounterWithLabels([]string{"agent", "discovery", "degraded"}, 1,
+		[]metrics.Label{{Name: "service", Value: args.ServiceName}})
+	a.logger.Printf("[WARN] agent: serving last known result for service %q: %v", args.ServiceName, err)
+	return nil
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/pool"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// lastKnownDelegate answers Health.ServiceNodes with a fixed reply or error.
+type lastKnownDelegate struct {
+	delegate
+	reply *structs.IndexedCheckServiceNodes
+	err   error
+}
+
+func (d *lastKnownDelegate) RPC(method string, args interface{}, reply interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+	*reply.(*structs.IndexedCheckServiceNodes) = *d.reply
+	return nil
+}
+
+func TestLastKnownStore(t *testing.T) {
+	t.Parallel()
+	dir := testutil.TempDir(t, "last-known")
+	defer os.RemoveAll(dir)
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	s := newLastKnownStore(dir, time.Hour, logger)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	args := &structs.ServiceSpecificRequest{
+		Datacenter:   "dc1",
+		ServiceName:  "web",
+		QueryOptions: structs.QueryOptions{Token: "foo"},
+	}
+	reply := &structs.IndexedCheckServiceNodes{
+		Nodes: structs.CheckServiceNodes{
+			{
+				Node:    &structs.Node{Node: "node1", Address: "10.0.0.1"},
+				Service: &structs.NodeService{ID: "web", Service: "web", Port: 8080},
+			},
+		},
+		QueryMeta: structs.QueryMeta{Index: 5},
+	}
+
+	// Nothing persisted yet.
+	out, err := s.Load(args)
+	require.NoError(t, err)
+	require.Nil(t, out)
+
+	require.NoError(t, s.Save(args, reply))
+	out, err = s.Load(args)
+	require.NoError(t, err)
+	require.Equal(t, reply, out)
+
+	// Persisted results are private to the token that fetched them.
+	other := *args
+	other.Token = "bar"
+	out, err = s.Load(&other)
+	require.NoError(t, err)
+	require.Nil(t, out)
+
+	// The results with and without the drained nodes, like those of the
+	// HTTP API and DNS, are kept apart.
+	other = *args
+	other.IncludeDrained = true
+	out, err = s.Load(&other)
+	require.NoError(t, err)
+	require.Nil(t, out)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+
+	// Results past the maximum age are expired and removed.
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	out, err = s.Load(args)
+	require.NoError(t, err)
+	require.Nil(t, out)
+	_, err = os.Stat(filepath.Join(dir, files[0].Name()))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLastKnownStore_Prune(t *testing.T) {
+	t.Parallel()
+	dir := testutil.TempDir(t, "last-known")
+	defer os.RemoveAll(dir)
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	s := newLastKnownStore(dir, time.Hour, logger)
+
+	reply := &structs.IndexedCheckServiceNodes{QueryMeta: structs.QueryMeta{Index: 5}}
+	for _, name := range []string{"web", "db"} {
+		args := &structs.ServiceSpecificRequest{Datacenter: "dc1", ServiceName: name}
+		require.NoError(t, s.Save(args, reply))
+	}
+
+	// The result for db is never loaded again, but it's still removed once
+	// it's past the maximum age.
+	key, err := lastKnownKey(&structs.ServiceSpecificRequest{Datacenter: "dc1", ServiceName: "db"})
+	require.NoError(t, err)
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, key), old, old))
+
+	require.NoError(t, s.Prune())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.NotEqual(t, key, files[0].Name())
+}
+
+func TestAgent_RPC_lastKnownServiceNodes(t *testing.T) {
+	t.Parallel()
+	dir := testutil.TempDir(t, "last-known")
+	defer os.RemoveAll(dir)
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	d := &lastKnownDelegate{
+		reply: &structs.IndexedCheckServiceNodes{
+			Nodes: structs.CheckServiceNodes{
+				{
+					Node:    &structs.Node{Node: "node1", Address: "10.0.0.1"},
+					Service: &structs.NodeService{ID: "web", Service: "web", Port: 8080},
+				},
+			},
+			QueryMeta: structs.QueryMeta{Index: 5, KnownLeader: true},
+		},
+	}
+	a := &Agent{
+		delegate:  d,
+		logger:    logger,
+		lastKnown: newLastKnownStore(dir, time.Hour, logger),
+	}
+
+	args := &structs.ServiceSpecificRequest{Datacenter: "dc1", ServiceName: "web"}
+	var out structs.IndexedCheckServiceNodes
+	require.NoError(t, a.RPC("Health.ServiceNodes", args, &out))
+	require.False(t, out.Degraded)
+
+	// With no servers the persisted result is served and marked as degraded.
+	d.err = structs.ErrNoServers
+	out = structs.IndexedCheckServiceNodes{}
+	require.NoError(t, a.RPC("Health.ServiceNodes", args, &out))
+	require.True(t, out.Degraded)
+	require.False(t, out.KnownLeader)
+	require.Len(t, out.Nodes, 1)
+	require.Equal(t, uint64(5), out.Index)
+
+	// A blocking query that already has the persisted result gets the error
+	// rather than the same result again.
+	blocking := *args
+	blocking.MinQueryIndex = 5
+	err := a.RPC("Health.ServiceNodes", &blocking, &out)
+	require.Equal(t, structs.ErrNoServers, err)
+
+	// As are the errors of the connections to the servers.
+	d.err = &pool.ConnError{Err: fmt.Errorf("connection refused")}
+	out = structs.IndexedCheckServiceNodes{}
+	require.NoError(t, a.RPC("Health.ServiceNodes", args, &out))
+	require.True(t, out.Degraded)
+
+	// Errors returned by the servers are passed through.
+	d.err = fmt.Errorf("rpc error making call: Permission denied")
+	err = a.RPC("Health.ServiceNodes", args, &out)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Permission denied")
+}
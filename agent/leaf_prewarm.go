@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const (
+	// leafPrewarmWorkers is the number of leaf certificates fetched
+	// concurrently when pre-warming.
+	leafPrewarmWorkers = 4
+
+	// leafPrewarmTimeout bounds how long a single leaf certificate is retried
+	// for, for example while a client agent hasn't found any servers yet.
+	leafPrewarmTimeout = 2 * time.Minute
+
+	// leafPrewarmMaxBackoff is the longest wait between two attempts to fetch
+	// the same leaf certificate.
+	leafPrewarmMaxBackoff = 10 * time.Second
+)
+
+// leafPrewarmStatus is the progress of fetching the leaf certificates of the
+// local Connect services after startup.
+type leafPrewarmStatus struct {
+	// Enabled is false when pre-warming is turned off in the configuration.
+	Enabled bool
+
+	// Done is true once every leaf certificate was fetched or given up on.
+	Done bool
+
+	// Services is the number of distinct leaf certificates to fetch.
+	Services int
+
+	// Failures is the number of leaf certificates that couldn't be fetched.
+	Failures int
+
+	// Duration is how long pre-warming took, it is only set once done.
+	Duration string `json:",omitempty"`
+}
+
+// leafPrewarmRequest identifies one leaf certificate to fetch. Certificates
+// are cached per service name and token so that is what is deduplicated.
+type leafPrewarmRequest struct {
+	Service string
+	Token   string
+}
+
+// leafPrewarmRequests returns the leaf certificates used by local sidecar
+// proxies, managed proxies and Connect native services. Proxies use the
+// certificate of the service they represent.
+func (a *Agent) leafPrewarmRequests() []leafPrewarmRequest {
+	seen := make(map[leafPrewarmRequest]struct{})
+	var reqs []leafPrewarmRequest
+	for id, svc := range a.State.Services() {
+		var name string
+		switch {
+		case svc.Kind == structs.ServiceKindConnectProxy:
+			name = svc.Proxy.DestinationServiceName
+		case svc.Connect.Native:
+			name = svc.Service
+		}
+		if name == "" {
+			continue
+		}
+
+		req := leafPrewarmRequest{Service: name, Token: a.State.ServiceToken(id)}
+		if _, ok := seen[req]; ok {
+			continue
+		}
+		seen[req] = struct{}{}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// prewarmLeafCerts populates the leaf certificate cache for all the local
+// Connect services using a bounded pool of workers. It's meant to be run in a
+// goroutine once the local services have been loaded.
+func (a *Agent) prewarmLeafCerts() {
+	start := time.Now()
+	reqs := a.leafPrewarmRequests()
+
+	a.leafPrewarmLock.Lock()
+	a.leafPrewarm.Services = len(reqs)
+	a.leafPrewarmLock.Unlock()
+
+	if len(reqs) > 0 {
+		a.logger.Printf("[DEBUG] agent: Pre-warming %d Connect leaf certificates", len(reqs))
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	failures := 0
+	reqCh := make(chan leafPrewarmRequest)
+	for i := 0; i < leafPrewarmWorkers && i < len(reqs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range reqCh {
+				if err := a.prewarmLeafCert(req); err != nil {
+					a.logger.Printf("[WARN] agent: Failed to pre-warm the Connect leaf certificate for service %q: %v", req.Service, err)
+					metrics.IncrCounter([]string{"agent", "connect", "leaf_prewarm", "failures"}, 1)
+					lock.Lock()
+					failures++
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	for _, req := range reqs {
+		reqCh <- req
+	}
+	close(reqCh)
+	wg.Wait()
+
+	metrics.MeasureSince([]string{"agent", "connect", "leaf_prewarm"}, start)
+	duration := time.Since(start)
+
+	a.leafPrewarmLock.Lock()
+	a.leafPrewarm.Done = true
+	a.leafPrewarm.Failures = failures
+	a.leafPrewarm.Duration = duration.String()
+	a.leafPrewarmLock.Unlock()
+
+	if len(reqs) > 0 {
+		a.logger.Printf("[INFO] agent: Pre-warmed %d of %d Connect leaf certificates in %s",
+			len(reqs)-failures, len(reqs), duration)
+	}
+}
+
+// prewarmLeafCert fetches a single leaf certificate into the cache, retrying
+// with a backoff until it succeeds, times out or the agent shuts down.
+func (a *Agent) prewarmLeafCert(req leafPrewarmRequest) error {
+	args := cachetype.ConnectCALeafRequest{
+		Datacenter: a.config.Datacenter,
+		Service:    req.Service,
+		Token:      req.Token,
+	}
+
+	deadline := time.Now().Add(leafPrewarmTimeout)
+	backoff := time.Second
+	for {
+		_, _, err := a.cache.Get(cachetype.ConnectCALeafName, &args)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-a.shutdownCh:
+			return err
+		}
+		backoff *= 2
+		if backoff > leafPrewarmMaxBackoff {
+			backoff = leafPrewarmMaxBackoff
+		}
+	}
+}
+
+// prewarmStatus returns the progress of pre-warming the leaf certificates
+// of the local Connect services.
+func (a *Agent) prewarmStatus() leafPrewarmStatus {
+	a.leafPrewarmLock.RLock()
+	defer a.leafPrewarmLock.RUnlock()
+	return a.leafPrewarm
+}
@@ -1,6 +1,7 @@
 package local
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -78,7 +79,8 @@ type CheckState struct {
 
 	// CriticalTime is the last time the health check status went
 	// from non-critical to critical. When the health check is not
-	// in critical state the value is the zero value.
+	// in critical state, or is only critical because it's blocked by
+	// its dependency, the value is the zero value.
 	CriticalTime time.Time
 
 	// DeferCheck is used to delay the sync of a health check when
@@ -93,6 +95,14 @@ type CheckState struct {
 	// Deleted is true when the health check record has been marked as
 	// deleted but has not been removed on the server yet.
 	Deleted bool
+
+	// BlockedBy is set while the check this health check depends on is
+	// critical. The health check is then reported as critical, and the
+	// last result of its own probe is kept in ProbeStatus and ProbeOutput
+	// until the dependency recovers.
+	BlockedBy   types.CheckID
+	ProbeStatus string
+	ProbeOutput string
 }
 
 // Clone returns a shallow copy of the object. The check record and the
@@ -115,6 +125,10 @@ func (c *CheckState) CriticalFor() time.Duration {
 	return time.Since(c.CriticalTime)
 }
 
+// deregisterBatchSize is the maximum number of services and checks that are
+// deregistered in a single batch.
+const deregisterBatchSize = 128
+
 type rpc interface {
 	RPC(method string, args interface{}, reply interface{}) error
 }
@@ -173,6 +187,10 @@ type State struct {
 	// node information in sync
 	nodeInfoInSync bool
 
+	// deregisterBatchUnsupported is set when the servers don't support
+	// Catalog.DeregisterBatch yet. It is reset on every full sync.
+	deregisterBatchUnsupported bool
+
 	// Services tracks the local services
 	services map[string]*ServiceState
 
@@ -180,6 +198,9 @@ type State struct {
 	checks       map[types.CheckID]*CheckState
 	checkAliases map[string]map[types.CheckID]chan<- struct{}
 
+	// checkDependencies maps a check to the local check it depends on.
+	checkDependencies map[types.CheckID]types.CheckID
+
 	// metadata tracks the node metadata fields
 	metadata map[string]string
 
@@ -224,6 +245,7 @@ func NewState(c Config, lg *log.Logger, tokens *token.Store) *State {
 		services:             make(map[string]*ServiceState),
 		checks:               make(map[types.CheckID]*CheckState),
 		checkAliases:         make(map[string]map[types.CheckID]chan<- struct{}),
+		checkDependencies:    make(map[types.CheckID]types.CheckID),
 		metadata:             make(map[string]string),
 		tokens:               tokens,
 		notifyHandlers:       make(map[chan<- struct{}]struct{}),
@@ -495,9 +517,109 @@ func (l *State) addCheckLocked(check *structs.HealthCheck, token string) error {
 		Check: check,
 		Token: token,
 	})
+
+	// The check may be blocked by its dependency, or be the dependency of
+	// checks that were registered before it.
+	l.refreshBlockedLocked(check.CheckID)
+	l.refreshDependentsLocked(check.CheckID)
+	return nil
+}
+
+// SetCheckDependency sets the local check that the check with the given ID
+// depends on, or clears it if dependsOn is empty. While the dependency is
+// critical, the check is blocked: it's reported as critical and the result
+// of its own probe is held back until the dependency recovers. The
+// dependency doesn't have to be registered yet, but it must not lead back
+// to the check.
+func (l *State) SetCheckDependency(id, dependsOn types.CheckID) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if dependsOn == "" {
+		delete(l.checkDependencies, id)
+		l.refreshBlockedLocked(id)
+		return nil
+	}
+
+	if err := l.validateCheckDependencyLocked(id, dependsOn); err != nil {
+		return err
+	}
+
+	l.checkDependencies[id] = dependsOn
+	l.refreshBlockedLocked(id)
 	return nil
 }
 
+// ValidateCheckDependency returns an error if the check with the given ID
+// can't depend on dependsOn. It lets callers reject a dependency before
+// doing any work that would have to be undone.
+func (l *State) ValidateCheckDependency(id, dependsOn types.CheckID) error {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.validateCheckDependencyLocked(id, dependsOn)
+}
+
+func (l *State) validateCheckDependencyLocked(id, dependsOn types.CheckID) error {
+	for dep := dependsOn; dep != ""; dep = l.checkDependencies[dep] {
+		if dep == id {
+			return fmt.Errorf("Check %q cannot depend on check %q: dependency cycle", id, dependsOn)
+		}
+	}
+	return nil
+}
+
+// CheckBlocked returns true when the check with the given ID is blocked by
+// the check it depends on. It's used by the check runners to stop probing.
+func (l *State) CheckBlocked(id types.CheckID) bool {
+	l.RLock()
+	defer l.RUnlock()
+
+	c := l.checks[id]
+	return c != nil && !c.Deleted && c.BlockedBy != ""
+}
+
+// criticalDependencyLocked returns the ID of the check that the check with
+// the given ID depends on if that check is registered and critical.
+func (l *State) criticalDependencyLocked(id types.CheckID) types.CheckID {
+	dep, ok := l.checkDependencies[id]
+	if !ok {
+		return ""
+	}
+	c := l.checks[dep]
+	if c == nil || c.Deleted || c.Check.Status != api.HealthCritical {
+		return ""
+	}
+	return dep
+}
+
+// refreshBlockedLocked blocks or unblocks the check with the given ID when
+// the status of its dependency changed.
+func (l *State) refreshBlockedLocked(id types.CheckID) {
+	c := l.checks[id]
+	if c == nil || c.Deleted {
+		return
+	}
+
+	blocked := l.criticalDependencyLocked(id) != ""
+	switch {
+	case blocked && c.BlockedBy == "":
+		l.updateCheckLocked(id, c.Check.Status, c.Check.Output)
+	case !blocked && c.BlockedBy != "":
+		l.updateCheckLocked(id, c.ProbeStatus, c.ProbeOutput)
+	}
+}
+
+// refreshDependentsLocked blocks or unblocks the checks that depend on the
+// check with the given ID.
+func (l *State) refreshDependentsLocked(id types.CheckID) {
+	for dependent, dep := range l.checkDependencies {
+		if dep == id {
+			l.refreshBlockedLocked(dependent)
+		}
+	}
+}
+
 // AddAliasCheck creates an alias check. When any check for the srcServiceID is
 // changed, checkID will reflect that using the same semantics as
 // checks.CheckAlias.
@@ -543,6 +665,8 @@ func (l *State) RemoveCheck(id types.CheckID) error {
 }
 
 func (l *State) removeCheckLocked(id types.CheckID) error {
+	delete(l.checkDependencies, id)
+
 	c := l.checks[id]
 	if c == nil || c.Deleted {
 		return fmt.Errorf("Check %q does not exist", id)
@@ -555,6 +679,8 @@ func (l *State) removeCheckLocked(id types.CheckID) error {
 	c.Deleted = true
 	l.TriggerSyncChanges()
 
+	// The checks that depend on this one aren't blocked by it anymore.
+	l.refreshDependentsLocked(id)
 	return nil
 }
 
@@ -563,6 +689,10 @@ func (l *State) UpdateCheck(id types.CheckID, status, output string) {
 	l.Lock()
 	defer l.Unlock()
 
+	l.updateCheckLocked(id, status, output)
+}
+
+func (l *State) updateCheckLocked(id types.CheckID, status, output string) {
 	c := l.checks[id]
 	if c == nil || c.Deleted {
 		return
@@ -572,9 +702,29 @@ func (l *State) UpdateCheck(id types.CheckID, status, output string) {
 		output = ""
 	}
 
+	// While the check it depends on is critical, the check is reported as
+	// critical and the result of its own probe is kept for when the
+	// dependency recovers.
+	if dep := l.criticalDependencyLocked(id); dep != "" {
+		c.BlockedBy = dep
+		c.ProbeStatus, c.ProbeOutput = status, output
+		status = api.HealthCritical
+		output = fmt.Sprintf("blocked by %s", dep)
+		if c.ProbeOutput != "" {
+			output += "\n\n" + c.ProbeOutput
+		}
+	} else {
+		c.BlockedBy = ""
+		c.ProbeStatus, c.ProbeOutput = "", ""
+	}
+
 	// Update the critical time tracking (this doesn't cause a server updates
-	// so we can always keep this up to date).
-	if status == api.HealthCritical {
+	// so we can always keep this up to date). A blocked check isn't critical
+	// on its own, so it doesn't count towards
+	// deregister_critical_service_after until it's unblocked.
+	if c.BlockedBy != "" {
+		c.CriticalTime = time.Time{}
+	} else if status == api.HealthCritical {
 		if !c.Critical() {
 			c.CriticalTime = time.Now()
 		}
@@ -630,10 +780,15 @@ func (l *State) UpdateCheck(id types.CheckID, status, output string) {
 	}
 
 	// Update status and mark out of sync
+	wasCritical := c.Check.Status == api.HealthCritical
 	c.Check.Status = status
 	c.Check.Output = output
 	c.InSync = false
 	l.TriggerSyncChanges()
+
+	if wasCritical != (status == api.HealthCritical) {
+		l.refreshDependentsLocked(id)
+	}
 }
 
 // Check returns the locally registered check that the
@@ -992,6 +1147,46 @@ func (l *State) LoadMetadata(data map[string]string) error {
 	return nil
 }
 
+// SetMetadata sets a node metadata field on the local agent and syncs it to
+// the servers.
+func (l *State) SetMetadata(key, value string) {
+	l.Lock()
+	defer l.Unlock()
+
+	meta := l.copyMetadata()
+	meta[key] = value
+	l.metadata = meta
+	l.nodeInfoInSync = false
+	l.TriggerSyncChanges()
+}
+
+// RemoveMetadata removes a node metadata field from the local agent and syncs
+// the change to the servers.
+func (l *State) RemoveMetadata(key string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if _, ok := l.metadata[key]; !ok {
+		return
+	}
+	meta := l.copyMetadata()
+	delete(meta, key)
+	l.metadata = meta
+	l.nodeInfoInSync = false
+	l.TriggerSyncChanges()
+}
+
+// copyMetadata returns a copy of the node metadata fields. The fields are
+// updated on a copy since the map may be shared with an in-memory RPC to the
+// servers. It must be called with the lock held.
+func (l *State) copyMetadata() map[string]string {
+	meta := make(map[string]string, len(l.metadata))
+	for k, v := range l.metadata {
+		meta[k] = v
+	}
+	return meta
+}
+
 // UnloadMetadata resets the local metadata state
 func (l *State) UnloadMetadata() {
 	l.Lock()
@@ -1112,6 +1307,13 @@ func (l *State) updateSyncState() error {
 			copy(ls.Service.Tags, rs.Tags)
 		}
 		ls.InSync = ls.Service.IsSame(rs)
+		if !ls.InSync {
+			// The servers may have normalized the tags, which doesn't
+			// make the service out of sync.
+			normalized := *ls.Service
+			normalized.Tags = structs.NormalizeTags(ls.Service.Tags)
+			ls.InSync = normalized.IsSame(rs)
+		}
 	}
 
 	// Check which checks need syncing
@@ -1194,6 +1396,13 @@ func (l *State) SyncFull() error {
 	if err := l.updateSyncState(); err != nil {
 		return err
 	}
+
+	// Try batched deregistrations again in case the servers have been
+	// upgraded since the last full sync.
+	l.Lock()
+	l.deregisterBatchUnsupported = false
+	l.Unlock()
+
 	return l.SyncChanges()
 }
 
@@ -1207,13 +1416,22 @@ func (l *State) SyncChanges() error {
 	// updated by a service or check sync anyway, given how the register
 	// API works.
 
+	// Deregister the deleted services and checks in batches first. If that
+	// isn't possible they are deregistered one by one below.
+	batched, err := l.deleteBatched()
+	if err != nil {
+		return err
+	}
+
 	// Sync the services
 	// (logging happens in the helper methods)
 	for id, s := range l.services {
 		var err error
 		switch {
 		case s.Deleted:
-			err = l.deleteService(id)
+			if !batched {
+				err = l.deleteService(id)
+			}
 		case !s.InSync:
 			err = l.syncService(id)
 		default:
@@ -1230,7 +1448,9 @@ func (l *State) SyncChanges() error {
 		var err error
 		switch {
 		case c.Deleted:
-			err = l.deleteCheck(id)
+			if !batched {
+				err = l.deleteCheck(id)
+			}
 		case !c.InSync:
 			if c.DeferCheck != nil {
 				c.DeferCheck.Stop()
@@ -1254,6 +1474,83 @@ func (l *State) SyncChanges() error {
 	return l.syncNodeInfo()
 }
 
+// deleteBatched deregisters the deleted services and checks from the server
+// in batches of up to deregisterBatchSize, which are applied as a single
+// Raft entry each, and returns whether it did. It does nothing if there is
+// only one deregistration, or if the servers don't support batches, in which
+// case SyncChanges falls back to deregistering them one by one until the next
+// full sync.
+func (l *State) deleteBatched() (bool, error) {
+	if l.deregisterBatchUnsupported {
+		return false, nil
+	}
+
+	var deregs []structs.DeregisterRequest
+	for id, s := range l.services {
+		if s.Deleted && id != "" {
+			deregs = append(deregs, structs.DeregisterRequest{
+				ServiceID:    id,
+				WriteRequest: structs.WriteRequest{Token: l.serviceToken(id)},
+			})
+		}
+	}
+	for id, c := range l.checks {
+		if c.Deleted && id != "" {
+			deregs = append(deregs, structs.DeregisterRequest{
+				CheckID:      id,
+				WriteRequest: structs.WriteRequest{Token: l.checkToken(id)},
+			})
+		}
+	}
+	if len(deregs) < 2 {
+		return false, nil
+	}
+
+	var firstErr error
+	for len(deregs) > 0 {
+		n := len(deregs)
+		if n > deregisterBatchSize {
+			n = deregisterBatchSize
+		}
+		batch := deregs[:n]
+		deregs = deregs[n:]
+
+		req := structs.DeregisterBatchRequest{
+			Datacenter:      l.config.Datacenter,
+			Node:            l.config.NodeName,
+			Deregistrations: batch,
+			WriteRequest:    structs.WriteRequest{Token: l.tokens.AgentToken()},
+		}
+		var out structs.DeregisterBatchResponse
+		err := l.Delegate.RPC("Catalog.DeregisterBatch", &req, &out)
+		if structs.IsErrRPCMethodNotFound(err) {
+			l.deregisterBatchUnsupported = true
+			l.logger.Printf("[DEBUG] agent: Servers don't support batched deregistrations, deregistering one by one")
+			return false, nil
+		}
+		if err != nil {
+			l.logger.Printf("[WARN] agent: Deregistering batch of %d failed. %s", len(batch), err)
+			return false, err
+		}
+
+		for i, dereg := range batch {
+			var entryErr error
+			if i < len(out.Errors) && out.Errors[i] != "" {
+				entryErr = errors.New(out.Errors[i])
+			}
+			if dereg.ServiceID != "" {
+				err = l.serviceDeregistered(dereg.ServiceID, entryErr)
+			} else {
+				err = l.checkDeregistered(dereg.CheckID, entryErr)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return true, firstErr
+}
+
 // deleteService is used to delete a service from the server
 func (l *State) deleteService(id string) error {
 	if id == "" {
@@ -1268,6 +1565,12 @@ func (l *State) deleteService(id string) error {
 	}
 	var out struct{}
 	err := l.Delegate.RPC("Catalog.Deregister", &req, &out)
+	return l.serviceDeregistered(id, err)
+}
+
+// serviceDeregistered updates the local state of a service after the
+// server has handled its deregistration with the given result.
+func (l *State) serviceDeregistered(id string, err error) error {
 	switch {
 	case err == nil || strings.Contains(err.Error(), "Unknown service"):
 		delete(l.services, id)
@@ -1302,6 +1605,12 @@ func (l *State) deleteCheck(id types.CheckID) error {
 	}
 	var out struct{}
 	err := l.Delegate.RPC("Catalog.Deregister", &req, &out)
+	return l.checkDeregistered(id, err)
+}
+
+// checkDeregistered updates the local state of a check after the server
+// has handled its deregistration with the given result.
+func (l *State) checkDeregistered(id types.CheckID, err error) error {
 	switch {
 	case err == nil || strings.Contains(err.Error(), "Unknown check"):
 		c := l.checks[id]
@@ -635,6 +635,47 @@ func TestAgentAntiEntropy_EnableTagOverride(t *testing.T) {
 	}
 }
 
+func TestAgentAntiEntropy_NormalizeServiceTags(t *testing.T) {
+	t.Parallel()
+	a := &agent.TestAgent{Name: t.Name(), HCL: `
+		normalize_service_tags = true
+	`}
+	a.Start(t)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	srv := &structs.NodeService{
+		ID:      "web",
+		Service: "web",
+		Tags:    []string{"v2", "primary", "v2"},
+		Port:    80,
+	}
+	a.State.AddService(srv, "")
+	if err := a.State.SyncFull(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.NodeSpecificRequest{
+		Datacenter: "dc1",
+		Node:       a.Config.NodeName,
+	}
+	var services structs.IndexedNodeServices
+	if err := a.RPC("Catalog.NodeServices", &req, &services); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	require.Equal(t, []string{"primary", "v2"}, services.NodeServices.Services["web"].Tags)
+
+	// The local service keeps its tags, and isn't synced again because the
+	// servers normalized them.
+	if err := a.State.SyncFull(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	require.Equal(t, []string{"v2", "primary", "v2"}, a.State.Service("web").Tags)
+	if err := servicesInSync(a.State, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAgentAntiEntropy_Services_WithChecks(t *testing.T) {
 	t.Parallel()
 	a := agent.NewTestAgent(t, t.Name(), "")
@@ -1330,6 +1371,158 @@ func TestAgentAntiEntropy_Checks_ACLDeny(t *testing.T) {
 	}
 }
 
+func TestAgentAntiEntropy_DeregisterBatch(t *testing.T) {
+	t.Parallel()
+	a := &agent.TestAgent{Name: t.Name()}
+	a.Start(t)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	require := require.New(t)
+
+	for _, id := range []string{"web", "db", "cache"} {
+		require.NoError(a.State.AddService(&structs.NodeService{
+			ID:      id,
+			Service: id,
+		}, ""))
+	}
+	require.NoError(a.State.AddCheck(&structs.HealthCheck{
+		Node:    a.Config.NodeName,
+		CheckID: "mem",
+		Name:    "memory util",
+		Status:  api.HealthPassing,
+	}, ""))
+	require.NoError(a.State.SyncFull())
+
+	// Remove everything but the cache service.
+	require.NoError(a.State.RemoveService("web"))
+	require.NoError(a.State.RemoveService("db"))
+	require.NoError(a.State.RemoveCheck("mem"))
+	require.NoError(a.State.SyncChanges())
+
+	req := structs.NodeSpecificRequest{
+		Datacenter: "dc1",
+		Node:       a.Config.NodeName,
+	}
+	var services structs.IndexedNodeServices
+	require.NoError(a.RPC("Catalog.NodeServices", &req, &services))
+	require.Len(services.NodeServices.Services, 2)
+	require.Contains(services.NodeServices.Services, "cache")
+	require.Contains(services.NodeServices.Services, "consul")
+
+	var checks structs.IndexedHealthChecks
+	require.NoError(a.RPC("Health.NodeChecks", &req, &checks))
+	for _, chk := range checks.HealthChecks {
+		require.NotEqual(types.CheckID("mem"), chk.CheckID)
+	}
+
+	// The deregistrations have been removed from the local state.
+	require.Nil(a.State.ServiceState("web"))
+	require.Nil(a.State.ServiceState("db"))
+	require.Nil(a.State.CheckState("mem"))
+}
+
+// fakeCatalogRPC records the calls made by the state syncing and answers
+// Catalog.DeregisterBatch with the given function.
+type fakeCatalogRPC struct {
+	calls           []string
+	deregisterBatch func(args *structs.DeregisterBatchRequest, reply *structs.DeregisterBatchResponse) error
+}
+
+func (f *fakeCatalogRPC) RPC(method string, args interface{}, reply interface{}) error {
+	f.calls = append(f.calls, method)
+	if method == "Catalog.DeregisterBatch" {
+		return f.deregisterBatch(args.(*structs.DeregisterBatchRequest), reply.(*structs.DeregisterBatchResponse))
+	}
+	return nil
+}
+
+func TestState_DeregisterBatch(t *testing.T) {
+	t.Parallel()
+
+	state := local.NewState(local.Config{NodeName: "node1"},
+		log.New(os.Stderr, "", log.LstdFlags), &token.Store{})
+	state.TriggerSyncChanges = func() {}
+
+	fake := &fakeCatalogRPC{
+		deregisterBatch: func(args *structs.DeregisterBatchRequest, reply *structs.DeregisterBatchResponse) error {
+			reply.Errors = make([]string, len(args.Deregistrations))
+			for i, dereg := range args.Deregistrations {
+				if dereg.ServiceID == "db" {
+					reply.Errors[i] = "Permission denied"
+				}
+			}
+			return nil
+		},
+	}
+	state.Delegate = fake
+
+	require := require.New(t)
+	for _, id := range []string{"web", "db"} {
+		state.SetServiceState(&local.ServiceState{
+			Service: &structs.NodeService{ID: id, Service: id},
+			Deleted: true,
+		})
+	}
+	state.SetCheckState(&local.CheckState{
+		Check:   &structs.HealthCheck{CheckID: "mem", Name: "memory util"},
+		Deleted: true,
+	})
+	require.NoError(state.SyncChanges())
+
+	// A single batch deregisters everything, and the service that is
+	// blocked by ACLs isn't retried before the next sync.
+	require.Equal([]string{"Catalog.DeregisterBatch", "Catalog.Register"}, fake.calls)
+
+	// Only the blocked service is left to deregister.
+	fake.calls = nil
+	require.NoError(state.SyncChanges())
+	require.Equal([]string{"Catalog.Deregister"}, fake.calls)
+}
+
+func TestState_DeregisterBatch_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	state := local.NewState(local.Config{NodeName: "node1"},
+		log.New(os.Stderr, "", log.LstdFlags), &token.Store{})
+	state.TriggerSyncChanges = func() {}
+
+	fake := &fakeCatalogRPC{
+		deregisterBatch: func(args *structs.DeregisterBatchRequest, reply *structs.DeregisterBatchResponse) error {
+			return errors.New("rpc: can't find method Catalog.DeregisterBatch")
+		},
+	}
+	state.Delegate = fake
+
+	require := require.New(t)
+	for _, id := range []string{"web", "db"} {
+		state.SetServiceState(&local.ServiceState{
+			Service: &structs.NodeService{ID: id, Service: id},
+			Deleted: true,
+		})
+	}
+	require.NoError(state.SyncChanges())
+
+	// Old servers get one deregistration per service.
+	require.Equal([]string{
+		"Catalog.DeregisterBatch",
+		"Catalog.Deregister",
+		"Catalog.Deregister",
+		"Catalog.Register",
+	}, fake.calls)
+
+	// Batches aren't tried again until the next full sync.
+	fake.calls = nil
+	for _, id := range []string{"web", "db"} {
+		state.SetServiceState(&local.ServiceState{
+			Service: &structs.NodeService{ID: id, Service: id},
+			Deleted: true,
+		})
+	}
+	require.NoError(state.SyncChanges())
+	require.Equal([]string{"Catalog.Deregister", "Catalog.Deregister"}, fake.calls)
+}
+
 func TestAgent_UpdateCheck_DiscardOutput(t *testing.T) {
 	t.Parallel()
 	a := agent.NewTestAgent(t, t.Name(), `
@@ -1835,6 +2028,74 @@ func TestAgent_AliasCheck(t *testing.T) {
 	}
 }
 
+func TestAgent_CheckDependency(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	cfg := config.DefaultRuntimeConfig(`bind_addr = "127.0.0.1" data_dir = "dummy"`)
+	l := local.NewState(agent.LocalConfig(cfg), nil, new(token.Store))
+	l.TriggerSyncChanges = func() {}
+
+	// web depends on app, which depends on db. app is registered before db.
+	require.NoError(l.SetCheckDependency("app", "db"))
+	require.NoError(l.SetCheckDependency("web", "app"))
+	require.NoError(l.AddCheck(&structs.HealthCheck{CheckID: "app", Status: api.HealthPassing}, ""))
+	require.NoError(l.AddCheck(&structs.HealthCheck{CheckID: "web", Status: api.HealthPassing}, ""))
+	require.NoError(l.AddCheck(&structs.HealthCheck{CheckID: "db", Status: api.HealthCritical}, ""))
+
+	// The dependents of the critical check are blocked, and so are theirs.
+	app := l.CheckState("app")
+	require.Equal(types.CheckID("db"), app.BlockedBy)
+	require.Equal(api.HealthCritical, app.Check.Status)
+	require.Equal("blocked by db", app.Check.Output)
+	require.Equal(api.HealthPassing, app.ProbeStatus)
+	require.True(l.CheckBlocked("app"))
+
+	// Blocked checks don't count as critical for reaping their service.
+	require.False(app.Critical())
+	require.NotContains(l.CriticalCheckStates(), types.CheckID("app"))
+	require.NotContains(l.CriticalCheckStates(), types.CheckID("web"))
+	web := l.CheckState("web")
+	require.Equal(types.CheckID("app"), web.BlockedBy)
+	require.Equal(api.HealthCritical, web.Check.Status)
+
+	// Updates to a blocked check are held back.
+	l.UpdateCheck("app", api.HealthWarning, "slow")
+	app = l.CheckState("app")
+	require.Equal(api.HealthCritical, app.Check.Status)
+	require.Equal("blocked by db\n\nslow", app.Check.Output)
+	require.Equal(api.HealthWarning, app.ProbeStatus)
+
+	// They're restored once the dependency recovers.
+	l.UpdateCheck("db", api.HealthPassing, "")
+	app = l.CheckState("app")
+	require.Equal(types.CheckID(""), app.BlockedBy)
+	require.Equal(api.HealthWarning, app.Check.Status)
+	require.Equal("slow", app.Check.Output)
+	require.False(l.CheckBlocked("app"))
+	web = l.CheckState("web")
+	require.Equal(types.CheckID(""), web.BlockedBy)
+	require.Equal(api.HealthPassing, web.Check.Status)
+
+	// A check that is critical on its own starts its critical time once
+	// it's unblocked.
+	l.UpdateCheck("db", api.HealthCritical, "")
+	l.UpdateCheck("app", api.HealthCritical, "down")
+	require.False(l.CheckState("app").Critical())
+	l.UpdateCheck("db", api.HealthPassing, "")
+	require.True(l.CheckState("app").Critical())
+
+	// Removing a critical dependency unblocks its dependents.
+	l.UpdateCheck("db", api.HealthCritical, "")
+	require.True(l.CheckBlocked("app"))
+	require.NoError(l.RemoveCheck("db"))
+	require.False(l.CheckBlocked("app"))
+
+	// Cycles are rejected.
+	require.Error(l.SetCheckDependency("db", "web"))
+	require.Error(l.SetCheckDependency("db", "db"))
+}
+
 func TestAgent_sendCoordinate(t *testing.T) {
 	t.Parallel()
 	a := agent.NewTestAgent(t, t.Name(), `
@@ -47,13 +47,11 @@ func (s *HTTPServer) OperatorRaftPeer(resp http.ResponseWriter, req *http.Reques
 	}
 
 	if !hasID && !hasAddress {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Must specify either ?id with the server's ID or ?address with IP:port of peer to remove")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Must specify either ?id with the server's ID or ?address with IP:port of peer to remove", nil)
 		return nil, nil
 	}
 	if hasID && hasAddress {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Must specify only one of ?id or ?address")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Must specify only one of ?id or ?address", nil)
 		return nil, nil
 	}
 
@@ -80,8 +78,7 @@ func (s *HTTPServer) OperatorKeyringEndpoint(resp http.ResponseWriter, req *http
 	var args keyringArgs
 	if req.Method == "POST" || req.Method == "PUT" || req.Method == "DELETE" {
 		if err := decodeBody(req, &args, nil); err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Request decode failed: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 			return nil, nil
 		}
 	}
@@ -91,15 +88,13 @@ func (s *HTTPServer) OperatorKeyringEndpoint(resp http.ResponseWriter, req *http
 	if relayFactor := req.URL.Query().Get("relay-factor"); relayFactor != "" {
 		n, err := strconv.Atoi(relayFactor)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Error parsing relay factor: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Error parsing relay factor: %v", err), nil)
 			return nil, nil
 		}
 
 		args.RelayFactor, err = ParseRelayFactor(n)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Invalid relay factor: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Invalid relay factor: %v", err), nil)
 			return nil, nil
 		}
 	}
@@ -214,8 +209,7 @@ func (s *HTTPServer) OperatorAutopilotConfiguration(resp http.ResponseWriter, re
 		var conf api.AutopilotConfiguration
 		durations := NewDurationFixer("lastcontactthreshold", "serverstabilizationtime")
 		if err := decodeBody(req, &conf, durations.FixupDurations); err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Error parsing autopilot config: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Error parsing autopilot config: %v", err), nil)
 			return nil, nil
 		}
 
@@ -234,8 +228,7 @@ func (s *HTTPServer) OperatorAutopilotConfiguration(resp http.ResponseWriter, re
 		if _, ok := params["cas"]; ok {
 			casVal, err := strconv.ParseUint(params.Get("cas"), 10, 64)
 			if err != nil {
-				resp.WriteHeader(http.StatusBadRequest)
-				fmt.Fprintf(resp, "Error parsing cas value: %v", err)
+				s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Error parsing cas value: %v", err), nil)
 				return nil, nil
 			}
 			args.Config.ModifyIndex = casVal
@@ -278,6 +271,7 @@ func (s *HTTPServer) OperatorServerHealth(resp http.ResponseWriter, req *http.Re
 	out := &api.OperatorHealthReply{
 		Healthy:          reply.Healthy,
 		FailureTolerance: reply.FailureTolerance,
+		MaxClockSkew:     api.NewReadableDuration(reply.MaxClockSkew),
 	}
 	for _, server := range reply.Servers {
 		out.Servers = append(out.Servers, api.ServerHealth{
@@ -293,8 +287,33 @@ func (s *HTTPServer) OperatorServerHealth(resp http.ResponseWriter, req *http.Re
 			Healthy:     server.Healthy,
 			Voter:       server.Voter,
 			StableSince: server.StableSince.Round(time.Second).UTC(),
+			ClockSkew:   api.NewReadableDuration(server.ClockSkew),
 		})
 	}
 
 	return out, nil
 }
+
+// OperatorUsageTopClients is used to report the tokens that made the most
+// requests to a server over its last completed usage interval.
+func (s *HTTPServer) OperatorUsageTopClients(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var args structs.UsageTopClientsRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+	args.Server = req.URL.Query().Get("server")
+
+	var reply structs.UsageTopClientsResponse
+	if err := s.agent.RPC("Operator.UsageTopClients", &args, &reply); err != nil {
+		return nil, err
+	}
+
+	// Return an empty list instead of null.
+	if reply.RPC == nil {
+		reply.RPC = make([]*structs.UsageClient, 0)
+	}
+	if reply.BlockingQueries == nil {
+		reply.BlockingQueries = make([]*structs.UsageClient, 0)
+	}
+	return reply, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/hashicorp/consul/testrpc"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/consul/autopilot"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
@@ -474,3 +475,44 @@ func TestOperator_ServerHealth_Unhealthy(t *testing.T) {
 		}
 	})
 }
+
+func TestOperator_UsageTopClients(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), TestACLConfig()+`
+		acl = {
+			usage_sample_rate = 1
+			usage_interval = "50ms"
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// The endpoint requires operator read access.
+	req, _ := http.NewRequest("GET", "/v1/operator/usage/top-clients", nil)
+	resp := httptest.NewRecorder()
+	if _, err := a.srv.OperatorUsageTopClients(resp, req); !acl.IsErrPermissionDenied(err) {
+		t.Fatalf("err: %v", err)
+	}
+
+	retry.Run(t, func(r *retry.R) {
+		req, _ := http.NewRequest("GET", "/v1/operator/usage/top-clients?token=root", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.OperatorUsageTopClients(resp, req)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		out, ok := obj.(structs.UsageTopClientsResponse)
+		if !ok {
+			r.Fatalf("unexpected: %T", obj)
+		}
+		if out.Server != a.Config.NodeName || out.SampleRate != 1 {
+			r.Fatalf("bad: %v", out)
+		}
+		if out.End.IsZero() {
+			r.Fatalf("no interval has been completed yet")
+		}
+		if out.RPC == nil || out.BlockingQueries == nil {
+			r.Fatalf("bad: %v", out)
+		}
+	})
+}
@@ -400,6 +400,22 @@ START:
 	return conn, client, nil
 }
 
+// ConnError is returned by RPC when no connection could be made to the
+// server, as opposed to an error returned by the call.
+type ConnError struct {
+	Err error
+}
+
+func (e *ConnError) Error() string {
+	return fmt.Sprintf("rpc error getting client: %v", e.Err)
+}
+
+// IsConnError returns true if the error is a ConnError.
+func IsConnError(err error) bool {
+	_, ok := err.(*ConnError)
+	return ok
+}
+
 // RPC is used to make an RPC call to a remote host
 func (p *ConnPool) RPC(dc string, addr net.Addr, version int, method string, useTLS bool, args interface{}, reply interface{}) error {
 	p.once.Do(p.init)
@@ -407,7 +423,7 @@ func (p *ConnPool) RPC(dc string, addr net.Addr, version int, method string, use
 	// Get a usable client
 	conn, sc, err := p.getClient(dc, addr, version, useTLS)
 	if err != nil {
-		return fmt.Errorf("rpc error getting client: %v", err)
+		return &ConnError{Err: err}
 	}
 
 	// Make the RPC call
@@ -9,6 +9,7 @@ import (
 	cachetype "github.com/hashicorp/consul/agent/cache-types"
 	"github.com/hashicorp/consul/agent/consul"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
 // preparedQueryCreateResponse is used to wrap the query ID.
@@ -24,8 +25,7 @@ func (s *HTTPServer) preparedQueryCreate(resp http.ResponseWriter, req *http.Req
 	s.parseDC(req, &args.Datacenter)
 	s.parseToken(req, &args.Token)
 	if err := decodeBody(req, &args.Query, nil); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 		return nil, nil
 	}
 
@@ -145,8 +145,7 @@ func (s *HTTPServer) preparedQueryExecute(id string, resp http.ResponseWriter, r
 			// We have to check the string since the RPC sheds
 			// the specific error type.
 			if err.Error() == consul.ErrQueryNotFound.Error() {
-				resp.WriteHeader(http.StatusNotFound)
-				fmt.Fprint(resp, err.Error())
+				s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, err.Error(), nil)
 				return nil, nil
 			}
 			return nil, err
@@ -199,8 +198,7 @@ RETRY_ONCE:
 		// We have to check the string since the RPC sheds
 		// the specific error type.
 		if err.Error() == consul.ErrQueryNotFound.Error() {
-			resp.WriteHeader(http.StatusNotFound)
-			fmt.Fprint(resp, err.Error())
+			s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, err.Error(), nil)
 			return nil, nil
 		}
 		return nil, err
@@ -230,8 +228,7 @@ RETRY_ONCE:
 		// We have to check the string since the RPC sheds
 		// the specific error type.
 		if err.Error() == consul.ErrQueryNotFound.Error() {
-			resp.WriteHeader(http.StatusNotFound)
-			fmt.Fprint(resp, err.Error())
+			s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, err.Error(), nil)
 			return nil, nil
 		}
 		return nil, err
@@ -254,8 +251,7 @@ func (s *HTTPServer) preparedQueryUpdate(id string, resp http.ResponseWriter, re
 	s.parseToken(req, &args.Token)
 	if req.ContentLength > 0 {
 		if err := decodeBody(req, &args.Query, nil); err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Request decode failed: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 			return nil, nil
 		}
 	}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/types"
 )
 
@@ -54,8 +55,7 @@ func (s *HTTPServer) SessionCreate(resp http.ResponseWriter, req *http.Request)
 			return nil
 		}
 		if err := decodeBody(req, &args.Session, fixup); err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(resp, "Request decode failed: %v", err)
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
 			return nil, nil
 		}
 	}
@@ -141,8 +141,7 @@ func (s *HTTPServer) SessionDestroy(resp http.ResponseWriter, req *http.Request)
 	// Pull out the session id
 	args.Session.ID = strings.TrimPrefix(req.URL.Path, "/v1/session/destroy/")
 	if args.Session.ID == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing session")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing session", nil)
 		return nil, nil
 	}
 
@@ -163,8 +162,7 @@ func (s *HTTPServer) SessionRenew(resp http.ResponseWriter, req *http.Request) (
 	// Pull out the session id
 	args.Session = strings.TrimPrefix(req.URL.Path, "/v1/session/renew/")
 	if args.Session == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing session")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing session", nil)
 		return nil, nil
 	}
 
@@ -172,14 +170,49 @@ func (s *HTTPServer) SessionRenew(resp http.ResponseWriter, req *http.Request) (
 	if err := s.agent.RPC("Session.Renew", &args, &out); err != nil {
 		return nil, err
 	} else if out.Sessions == nil {
-		resp.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(resp, "Session id '%s' not found", args.Session)
+		s.writeError(resp, req, http.StatusNotFound, api.ErrCodeNotFound, fmt.Sprintf("Session id '%s' not found", args.Session), nil)
 		return nil, nil
 	}
 
 	return out.Sessions, nil
 }
 
+// SessionRenewBatch is used to renew the TTL on several sessions at once,
+// with a body holding the list of session IDs
+func (s *HTTPServer) SessionRenewBatch(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.SessionBatchRenewRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	if err := decodeBody(req, &args.Sessions, nil); err != nil {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Request decode failed: %v", err), nil)
+		return nil, nil
+	}
+	if len(args.Sessions) == 0 {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing sessions", nil)
+		return nil, nil
+	}
+	if len(args.Sessions) > structs.SessionBatchRenewMax {
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Too many sessions: %d, the maximum is %d",
+			len(args.Sessions), structs.SessionBatchRenewMax), nil)
+		return nil, nil
+	}
+	for _, id := range args.Sessions {
+		if id == "" {
+			s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing session", nil)
+			return nil, nil
+		}
+	}
+
+	var out structs.SessionBatchRenewResponse
+	if err := s.agent.RPC("Session.RenewBatch", &args, &out); err != nil {
+		return nil, err
+	}
+
+	return out.Results, nil
+}
+
 // SessionGet is used to get info for a particular session
 func (s *HTTPServer) SessionGet(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	args := structs.SessionSpecificRequest{}
@@ -190,8 +223,7 @@ func (s *HTTPServer) SessionGet(resp http.ResponseWriter, req *http.Request) (in
 	// Pull out the session id
 	args.Session = strings.TrimPrefix(req.URL.Path, "/v1/session/info/")
 	if args.Session == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing session")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing session", nil)
 		return nil, nil
 	}
 
@@ -238,8 +270,7 @@ func (s *HTTPServer) SessionsForNode(resp http.ResponseWriter, req *http.Request
 	// Pull out the node name
 	args.Node = strings.TrimPrefix(req.URL.Path, "/v1/session/node/")
 	if args.Node == "" {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprint(resp, "Missing node name")
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, "Missing node name", nil)
 		return nil, nil
 	}
 
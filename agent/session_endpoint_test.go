@@ -455,6 +455,50 @@ func TestSessionTTLRenew(t *testing.T) {
 	}
 }
 
+func TestSessionRenewBatch(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	id := makeTestSessionTTL(t, a.srv, "10s")
+	missing := "adf4238a-882b-9ddc-4a9d-5b6758e4159e"
+
+	body := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(body).Encode([]string{id, missing}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req, _ := http.NewRequest("PUT", "/v1/session/renew-batch", body)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.SessionRenewBatch(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	results, ok := obj.([]structs.SessionRenewResult)
+	if !ok {
+		t.Fatalf("should work")
+	}
+	if len(results) != 2 {
+		t.Fatalf("bad: %v", results)
+	}
+	if results[0].ID != id || results[0].Session == nil || results[0].Session.TTL != "10s" {
+		t.Fatalf("bad: %v", results[0])
+	}
+	if results[1].ID != missing || results[1].Session != nil {
+		t.Fatalf("bad: %v", results[1])
+	}
+
+	// An empty batch is rejected.
+	req, _ = http.NewRequest("PUT", "/v1/session/renew-batch", bytes.NewBufferString("[]"))
+	resp = httptest.NewRecorder()
+	if _, err := a.srv.SessionRenewBatch(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("bad: %d", resp.Code)
+	}
+}
+
 func TestSessionGet(t *testing.T) {
 	t.Parallel()
 	t.Run("", func(t *testing.T) {
@@ -2,20 +2,54 @@ package agent
 
 import (
 	"net/http"
+
+	"github.com/hashicorp/consul/agent/structs"
 )
 
 func (s *HTTPServer) StatusLeader(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
-	var out string
-	if err := s.agent.RPC("Status.Leader", struct{}{}, &out); err != nil {
+	args := structs.StatusRequest{}
+	s.parseToken(req, &args.Token)
+	if s.parseWait(resp, req, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.StatusLeaderResponse
+	err := s.agent.RPC("Status.BlockingLeader", &args, &out)
+	if structs.IsErrRPCMethodNotFound(err) {
+		// Older servers can only answer right away.
+		var leader string
+		if err := s.agent.RPC("Status.Leader", struct{}{}, &leader); err != nil {
+			return nil, err
+		}
+		return leader, nil
+	}
+	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	setMeta(resp, &out.QueryMeta)
+	return out.Leader, nil
 }
 
 func (s *HTTPServer) StatusPeers(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
-	var out []string
-	if err := s.agent.RPC("Status.Peers", struct{}{}, &out); err != nil {
+	args := structs.StatusRequest{}
+	s.parseToken(req, &args.Token)
+	if s.parseWait(resp, req, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.StatusPeersResponse
+	err := s.agent.RPC("Status.BlockingPeers", &args, &out)
+	if structs.IsErrRPCMethodNotFound(err) {
+		// Older servers can only answer right away.
+		var peers []string
+		if err := s.agent.RPC("Status.Peers", struct{}{}, &peers); err != nil {
+			return nil, err
+		}
+		return peers, nil
+	}
+	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	setMeta(resp, &out.QueryMeta)
+	return out.Peers, nil
 }
@@ -2,7 +2,9 @@ package agent
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/testrpc"
 )
@@ -14,7 +16,8 @@ func TestStatusLeader(t *testing.T) {
 	testrpc.WaitForLeader(t, a.RPC, "dc1")
 
 	req, _ := http.NewRequest("GET", "/v1/status/leader", nil)
-	obj, err := a.srv.StatusLeader(nil, req)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.StatusLeader(resp, req)
 	if err != nil {
 		t.Fatalf("Err: %v", err)
 	}
@@ -22,6 +25,7 @@ func TestStatusLeader(t *testing.T) {
 	if val == "" {
 		t.Fatalf("bad addr: %v", obj)
 	}
+	assertIndex(t, resp)
 }
 
 func TestStatusPeers(t *testing.T) {
@@ -30,7 +34,8 @@ func TestStatusPeers(t *testing.T) {
 	defer a.Shutdown()
 
 	req, _ := http.NewRequest("GET", "/v1/status/peers", nil)
-	obj, err := a.srv.StatusPeers(nil, req)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.StatusPeers(resp, req)
 	if err != nil {
 		t.Fatalf("Err: %v", err)
 	}
@@ -39,4 +44,38 @@ func TestStatusPeers(t *testing.T) {
 	if len(peers) != 1 {
 		t.Fatalf("bad peers: %v", peers)
 	}
+	assertIndex(t, resp)
+}
+
+func TestStatusLeader_Blocking(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/status/leader", nil)
+	resp := httptest.NewRecorder()
+	if _, err := a.srv.StatusLeader(resp, req); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	index := resp.Header().Get("X-Consul-Index")
+
+	// The leader doesn't change, so this waits out the query time and
+	// returns the same index.
+	start := time.Now()
+	req, _ = http.NewRequest("GET", "/v1/status/leader?wait=100ms&index="+index, nil)
+	resp = httptest.NewRecorder()
+	obj, err := a.srv.StatusLeader(resp, req)
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if obj.(string) == "" {
+		t.Fatalf("bad addr: %v", obj)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("query didn't block: %v", elapsed)
+	}
+	if got := resp.Header().Get("X-Consul-Index"); got != index {
+		t.Fatalf("bad index: %q != %q", got, index)
+	}
 }
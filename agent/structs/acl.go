@@ -113,11 +113,32 @@ type ACLIdentity interface {
 	SecretToken() string
 	PolicyIDs() []string
 	EmbeddedPolicy() *ACLPolicy
+	InlineACLPolicies() ACLPolicies
 }
 
 type ACLTokenPolicyLink struct {
 	ID   string
 	Name string `hash:"ignore"`
+
+	// ExpirationTTL is only used when setting a token. When it's set, the
+	// ExpirationTime of the link is set to that long after the write.
+	ExpirationTTL time.Duration `json:",omitempty" hash:"ignore"`
+
+	// ExpirationTime is when the link expires. An expired link no longer
+	// grants the policy, and is removed from the token by the leader.
+	ExpirationTime *time.Time `json:",omitempty"`
+}
+
+// Expired returns true if the link has an expiration time that has passed.
+func (l ACLTokenPolicyLink) Expired(now time.Time) bool {
+	return l.ExpirationTime != nil && !now.Before(*l.ExpirationTime)
+}
+
+// ACLTokenInlinePolicy is a set of rules stored directly on a token. Unlike
+// linked policies they are not shared with other tokens, do not show up in
+// the policy list and are removed together with the token.
+type ACLTokenInlinePolicy struct {
+	Rules string
 }
 
 type ACLToken struct {
@@ -135,6 +156,10 @@ type ACLToken struct {
 	// the list of policy names gets validated and the policy IDs get stored herein
 	Policies []ACLTokenPolicyLink
 
+	// List of token-local policies. These are in effect in addition to the
+	// linked Policies.
+	InlinePolicies []ACLTokenInlinePolicy `json:",omitempty"`
+
 	// Type is the V1 Token Type
 	// DEPRECATED (ACL-Legacy-Compat) - remove once we no longer support v1 ACL compat
 	// Even though we are going to auto upgrade management tokens we still
@@ -167,11 +192,16 @@ type ACLToken struct {
 func (t *ACLToken) Clone() *ACLToken {
 	t2 := *t
 	t2.Policies = nil
+	t2.InlinePolicies = nil
 
 	if len(t.Policies) > 0 {
 		t2.Policies = make([]ACLTokenPolicyLink, len(t.Policies))
 		copy(t2.Policies, t.Policies)
 	}
+	if len(t.InlinePolicies) > 0 {
+		t2.InlinePolicies = make([]ACLTokenInlinePolicy, len(t.InlinePolicies))
+		copy(t2.InlinePolicies, t.InlinePolicies)
+	}
 	return &t2
 }
 
@@ -185,7 +215,11 @@ func (t *ACLToken) SecretToken() string {
 
 func (t *ACLToken) PolicyIDs() []string {
 	var ids []string
+	now := time.Now()
 	for _, link := range t.Policies {
+		if link.Expired(now) {
+			continue
+		}
 		ids = append(ids, link.ID)
 	}
 	return ids
@@ -221,6 +255,26 @@ func (t *ACLToken) EmbeddedPolicy() *ACLPolicy {
 	return policy
 }
 
+// InlineACLPolicies returns the inline policies of the token converted to
+// ACLPolicies so they can be compiled along with the linked policies.
+func (t *ACLToken) InlineACLPolicies() ACLPolicies {
+	var policies ACLPolicies
+	for _, inline := range t.InlinePolicies {
+		hasher := fnv.New128a()
+		hasher.Write([]byte(inline.Rules))
+
+		policy := &ACLPolicy{
+			ID:     fmt.Sprintf("%x", hasher.Sum(nil)),
+			Rules:  inline.Rules,
+			Syntax: acl.SyntaxCurrent,
+		}
+		policy.Name = fmt.Sprintf("inline-policy-%s", policy.ID)
+		policy.SetHash(true)
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
 func (t *ACLToken) SetHash(force bool) []byte {
 	if force || t.Hash == nil {
 		// Initialize a 256bit Blake2 hash (32 bytes)
@@ -242,6 +296,13 @@ func (t *ACLToken) SetHash(force bool) []byte {
 
 		for _, link := range t.Policies {
 			hash.Write([]byte(link.ID))
+			if link.ExpirationTime != nil {
+				hash.Write([]byte(link.ExpirationTime.UTC().Format(time.RFC3339Nano)))
+			}
+		}
+
+		for _, inline := range t.InlinePolicies {
+			hash.Write([]byte(inline.Rules))
 		}
 
 		// Finalize the hash
@@ -259,6 +320,9 @@ func (t *ACLToken) EstimateSize() int {
 	for _, link := range t.Policies {
 		size += len(link.ID) + len(link.Name)
 	}
+	for _, inline := range t.InlinePolicies {
+		size += len(inline.Rules)
+	}
 	return size
 }
 
@@ -500,6 +564,50 @@ func (policies ACLPolicies) Merge(cache *ACLCaches, sentinel sentinel.Evaluator)
 	return acl.MergePolicies(parsed), nil
 }
 
+// ACLSimulateCheck is an operation whose access is simulated, for example
+// write access to the "web" service.
+type ACLSimulateCheck struct {
+	Resource string
+	Segment  string
+	Access   string
+}
+
+// ACLSimulateRequest is used at the RPC layer to simulate the access of a
+// token, given by its accessor ID, or of inline rules to a set of resources.
+type ACLSimulateRequest struct {
+	AccessorID string
+	Rules      string
+	Checks     []ACLSimulateCheck
+	Datacenter string // The datacenter to perform the request within
+	QueryOptions
+}
+
+func (r *ACLSimulateRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLSimulateResult is the outcome of a simulated check along with the rule
+// that decided it. Rule and Policy are empty when no rule matched and the
+// default policy decided, in which case DefaultPolicy is set, along with
+// DefaultPolicyOverride if it is the override of the local datacenter. Error
+// is set when the check couldn't be done, for example because the resource is
+// only available in Consul Enterprise.
+type ACLSimulateResult struct {
+	ACLSimulateCheck
+	Allowed               bool
+	PolicyID              string `json:",omitempty"`
+	PolicyName            string `json:",omitempty"`
+	Rule                  string `json:",omitempty"`
+	DefaultPolicy         string `json:",omitempty"`
+	DefaultPolicyOverride bool   `json:",omitempty"`
+	Error                 string `json:",omitempty"`
+}
+
+type ACLSimulateResponse struct {
+	Results []ACLSimulateResult
+	QueryMeta
+}
+
 type ACLReplicationType string
 
 const (
@@ -561,6 +669,7 @@ type ACLTokenListRequest struct {
 	IncludeLocal  bool   // Whether local tokens should be included
 	IncludeGlobal bool   // Whether global tokens should be included
 	Policy        string // Policy filter
+	Dangling      bool   // Only list the tokens whose policies were all deleted
 	Datacenter    string // The datacenter to perform the request within
 	QueryOptions
 }
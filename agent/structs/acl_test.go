@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/acl"
 
@@ -84,6 +85,30 @@ func TestStructs_ACLToken_PolicyIDs(t *testing.T) {
 		policyIDs := token.PolicyIDs()
 		require.Len(t, policyIDs, 0)
 	})
+
+	t.Run("Expired Links", func(t *testing.T) {
+		t.Parallel()
+
+		past := time.Now().Add(-time.Minute)
+		future := time.Now().Add(time.Hour)
+		token := &ACLToken{
+			Policies: []ACLTokenPolicyLink{
+				ACLTokenPolicyLink{
+					ID:             "one",
+					ExpirationTime: &past,
+				},
+				ACLTokenPolicyLink{
+					ID:             "two",
+					ExpirationTime: &future,
+				},
+				ACLTokenPolicyLink{
+					ID: "three",
+				},
+			},
+		}
+
+		require.Equal(t, []string{"two", "three"}, token.PolicyIDs())
+	})
 }
 
 func TestStructs_ACLToken_EmbeddedPolicy(t *testing.T) {
@@ -45,6 +45,12 @@ type CheckType struct {
 	// service, if any, to be deregistered if this check is critical for
 	// longer than this duration.
 	DeregisterCriticalServiceAfter time.Duration
+
+	// DependsOnCheck, if set, is the ID of another local check that this
+	// one depends on. While that check is critical, this one is blocked:
+	// it stops probing and is reported as critical, with its output
+	// prefixed by "blocked by <check>".
+	DependsOnCheck types.CheckID
 }
 type CheckTypes []*CheckType
 
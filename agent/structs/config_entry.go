@@ -2,6 +2,7 @@ package structs
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strings"
 
 	"github.com/hashicorp/go-msgpack/codec"
@@ -10,6 +11,7 @@ import (
 const (
 	ServiceDefaults string = "service-defaults"
 	ProxyDefaults   string = "proxy-defaults"
+	ServiceRouting  string = "service-routing"
 
 	ProxyConfigGlobal string = "global"
 
@@ -154,6 +156,130 @@ func (e *ProxyConfigEntry) GetRaftIndex() *RaftIndex {
 	return &e.RaftIndex
 }
 
+// ServiceRoutingConfigEntry overrides how the instances of a service are
+// resolved, for example to send a share of the queries to canary instances.
+type ServiceRoutingConfigEntry struct {
+	Kind string
+	Name string
+
+	// ForceTag restricts the answers to the instances with this tag for
+	// Percentage of the query sources. Sources are hashed so the same source
+	// consistently gets the same answer.
+	ForceTag   string
+	Percentage int
+
+	RaftIndex
+}
+
+func (e *ServiceRoutingConfigEntry) GetKind() string {
+	return ServiceRouting
+}
+
+func (e *ServiceRoutingConfigEntry) GetName() string {
+	if e == nil {
+		return ""
+	}
+
+	return e.Name
+}
+
+func (e *ServiceRoutingConfigEntry) Normalize() error {
+	if e == nil {
+		return fmt.Errorf("config entry is nil")
+	}
+
+	e.Kind = ServiceRouting
+
+	return nil
+}
+
+func (e *ServiceRoutingConfigEntry) Validate() error {
+	if e == nil {
+		return fmt.Errorf("config entry is nil")
+	}
+
+	if e.Name == "" {
+		return fmt.Errorf("missing service name")
+	}
+	if e.ForceTag == "" {
+		return fmt.Errorf("missing tag to force")
+	}
+	if e.Percentage < 0 || e.Percentage > 100 {
+		return fmt.Errorf("invalid percentage (%d), must be between 0 and 100", e.Percentage)
+	}
+
+	return nil
+}
+
+func (e *ServiceRoutingConfigEntry) GetRaftIndex() *RaftIndex {
+	if e == nil {
+		return &RaftIndex{}
+	}
+
+	return &e.RaftIndex
+}
+
+// Applies returns true if the tag should be forced for the given query source,
+// which can be any string identifying the client such as its IP address.
+func (e *ServiceRoutingConfigEntry) Applies(source string) bool {
+	if e == nil || e.Percentage <= 0 {
+		return false
+	}
+	if e.Percentage >= 100 {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(e.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(source))
+	return int(h.Sum32()%100) < e.Percentage
+}
+
+// Route returns the nodes a query from the given source should be answered
+// with. If the tag is forced for the source but no instance has it, all the
+// nodes are returned rather than none.
+func (e *ServiceRoutingConfigEntry) Route(source string, nodes CheckServiceNodes) CheckServiceNodes {
+	if !e.Applies(source) {
+		return nodes
+	}
+
+	var tagged CheckServiceNodes
+	for _, node := range nodes {
+		for _, tag := range node.Service.Tags {
+			if tag == e.ForceTag {
+				tagged = append(tagged, node)
+				break
+			}
+		}
+	}
+	if len(tagged) == 0 {
+		return nodes
+	}
+	return tagged
+}
+
+// ServiceRoutingRequest is used to set or delete the routing override of a
+// service.
+type ServiceRoutingRequest struct {
+	Datacenter string
+	Op         ConfigEntryOp
+	Routing    *ServiceRoutingConfigEntry
+	WriteRequest
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (r *ServiceRoutingRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// IndexedServiceRouting is the routing override of a service, Routing is nil
+// if there is none.
+type IndexedServiceRouting struct {
+	Routing *ServiceRoutingConfigEntry
+	QueryMeta
+}
+
 type ConfigEntryOp string
 
 const (
@@ -224,6 +350,8 @@ func makeConfigEntry(kind string) (ConfigEntry, error) {
 		return &ServiceConfigEntry{}, nil
 	case ProxyDefaults:
 		return &ProxyConfigEntry{}, nil
+	case ServiceRouting:
+		return &ServiceRoutingConfigEntry{}, nil
 	default:
 		return nil, fmt.Errorf("invalid config entry kind: %s", kind)
 	}
@@ -0,0 +1,79 @@
+package structs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRoutingConfigEntry_Applies(t *testing.T) {
+	var nilEntry *ServiceRoutingConfigEntry
+	require.False(t, nilEntry.Applies("10.0.0.1"))
+
+	e := &ServiceRoutingConfigEntry{Name: "web", ForceTag: "canary"}
+	count := func() int {
+		n := 0
+		for i := 0; i < 1000; i++ {
+			if e.Applies(fmt.Sprintf("10.0.%d.%d", i/256, i%256)) {
+				n++
+			}
+		}
+		return n
+	}
+
+	e.Percentage = 0
+	require.Equal(t, 0, count())
+
+	e.Percentage = 100
+	require.Equal(t, 1000, count())
+
+	// The split is roughly what was asked for.
+	e.Percentage = 10
+	n := count()
+	require.True(t, n > 50 && n < 150, "got %d", n)
+
+	// And the same source always gets the same answer.
+	for i := 0; i < 10; i++ {
+		require.Equal(t, e.Applies("10.0.0.1"), e.Applies("10.0.0.1"))
+	}
+}
+
+func TestServiceRoutingConfigEntry_Route(t *testing.T) {
+	node := func(name string, tags ...string) CheckServiceNode {
+		return CheckServiceNode{
+			Node:    &Node{Node: name},
+			Service: &NodeService{Service: "web", Tags: tags},
+		}
+	}
+	nodes := CheckServiceNodes{
+		node("a"),
+		node("b", "canary"),
+		node("c", "primary"),
+	}
+
+	var nilEntry *ServiceRoutingConfigEntry
+	require.Equal(t, nodes, nilEntry.Route("10.0.0.1", nodes))
+
+	e := &ServiceRoutingConfigEntry{Name: "web", ForceTag: "canary", Percentage: 0}
+	require.Equal(t, nodes, e.Route("10.0.0.1", nodes))
+
+	e.Percentage = 100
+	require.Equal(t, CheckServiceNodes{nodes[1]}, e.Route("10.0.0.1", nodes))
+
+	// Without any tagged instance every instance is returned.
+	e.ForceTag = "missing"
+	require.Equal(t, nodes, e.Route("10.0.0.1", nodes))
+}
+
+func TestServiceRoutingConfigEntry_Validate(t *testing.T) {
+	e := &ServiceRoutingConfigEntry{Name: "web", ForceTag: "canary", Percentage: 10}
+	require.NoError(t, e.Validate())
+
+	e.Percentage = -1
+	require.Error(t, e.Validate())
+
+	e.Percentage = 10
+	e.ForceTag = ""
+	require.Error(t, e.Validate())
+}
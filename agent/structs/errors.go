@@ -13,6 +13,11 @@ const (
 	errSegmentsNotSupported       = "Network segments are not supported in this version of Consul"
 	errRPCRateExceeded            = "RPC rate limit exceeded"
 	errServiceNotFound            = "Service not found: "
+	errSyncOwnerConflict          = "Node is managed by another sync owner"
+	errRPCMethodNotFound          = "rpc: can't find method "
+	errKVChecksumMismatch         = "Checksum mismatch"
+	errIndexStale                 = "index is stale"
+	errIndexCheckFailed           = "failed index check"
 )
 
 var (
@@ -22,6 +27,7 @@ var (
 	ErrNotReadyForConsistentReads = errors.New(errNotReadyForConsistentReads)
 	ErrSegmentsNotSupported       = errors.New(errSegmentsNotSupported)
 	ErrRPCRateExceeded            = errors.New(errRPCRateExceeded)
+	ErrSyncOwnerConflict          = errors.New(errSyncOwnerConflict)
 )
 
 func IsErrNoLeader(err error) bool {
@@ -35,3 +41,27 @@ func IsErrRPCRateExceeded(err error) bool {
 func IsErrServiceNotFound(err error) bool {
 	return err != nil && strings.Contains(err.Error(), errServiceNotFound)
 }
+
+func IsErrSyncOwnerConflict(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errSyncOwnerConflict)
+}
+
+// IsErrRPCMethodNotFound checks if the given error is returned by a server
+// that doesn't have the RPC method that was called, because it runs an older
+// version.
+func IsErrRPCMethodNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errRPCMethodNotFound)
+}
+
+// IsErrKVChecksumMismatch checks if the given error is returned for a KV value
+// that doesn't match its checksum.
+func IsErrKVChecksumMismatch(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errKVChecksumMismatch)
+}
+
+// IsErrCASFailed checks if the given error is returned for a transaction
+// operation whose index didn't match the current one.
+func IsErrCASFailed(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), errIndexStale) ||
+		strings.Contains(err.Error(), errIndexCheckFailed))
+}
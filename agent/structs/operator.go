@@ -2,6 +2,7 @@ package structs
 
 import (
 	"net"
+	"time"
 
 	"github.com/hashicorp/consul/agent/consul/autopilot"
 	"github.com/hashicorp/raft"
@@ -44,6 +45,67 @@ type RaftConfigurationResponse struct {
 	Index uint64
 }
 
+// UsageClient is a token that made requests to a server over an interval.
+type UsageClient struct {
+	// AccessorID and Description identify the token. The secret of the
+	// token is never reported.
+	AccessorID  string
+	Description string
+
+	// Count is the estimated number of requests made with the token, which
+	// is the number of sampled requests scaled by the sample rate.
+	Count uint64
+
+	// Share is the fraction of all the requests of the interval made with
+	// the token.
+	Share float64
+}
+
+// UsageTopClientsRequest is used to query for the tokens that made the most
+// requests to a server over its last completed interval.
+type UsageTopClientsRequest struct {
+	// Datacenter is the target this request is intended for.
+	Datacenter string
+
+	// Server is the name of the server to report on. When it's empty the
+	// report of the leader is returned, or with stale reads the one of the
+	// server handling the request.
+	Server string
+
+	QueryOptions
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (r *UsageTopClientsRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// AllowStaleRead lets any server handle a request for a named server, since
+// it's forwarded to that server rather than to the leader.
+func (r *UsageTopClientsRequest) AllowStaleRead() bool {
+	return r.Server != "" || r.QueryOptions.AllowStaleRead()
+}
+
+// UsageTopClientsResponse is returned when querying for the tokens that made
+// the most requests to a server over its last completed interval.
+type UsageTopClientsResponse struct {
+	// Server is the name of the server that counted the requests.
+	Server string
+
+	// Start and End are the bounds of the interval. They are zero if no
+	// interval has been completed yet.
+	Start time.Time
+	End   time.Time
+
+	// SampleRate is the fraction of the requests that were sampled.
+	SampleRate float64
+
+	// RPC has the tokens that made the most RPCs, and BlockingQueries the
+	// ones that made the most blocking queries, by decreasing count.
+	RPC             []*UsageClient
+	BlockingQueries []*UsageClient
+}
+
 // RaftRemovePeerRequest is used by the Operator endpoint to apply a Raft
 // operation on a specific Raft peer by address in the form of "IP:port".
 type RaftRemovePeerRequest struct {
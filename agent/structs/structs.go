@@ -2,6 +2,8 @@ package structs
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -11,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/hashicorp/consul/agent/cache"
 	"github.com/hashicorp/consul/api"
@@ -56,6 +59,8 @@ const (
 	ACLPolicyDeleteRequestType             = 20
 	ConnectCALeafRequestType               = 21
 	ConfigEntryRequestType                 = 22
+	DeregisterBatchRequestType             = 23
+	KVSDeletedType                         = 24 // FSM snapshots only.
 )
 
 const (
@@ -72,6 +77,11 @@ const (
 	// ServiceMaintPrefix is the prefix for a service in maintenance mode.
 	ServiceMaintPrefix = "_service_maintenance:"
 
+	// NodeDrainedMetaKey is the node meta key set by a drained node. Its
+	// value is the time the drain ends in RFC 3339 format, or empty if the
+	// drain lasts until it's disabled.
+	NodeDrainedMetaKey = "consul-drained"
+
 	// The meta key prefix reserved for Consul's internal use
 	metaKeyReservedPrefix = "consul-"
 
@@ -87,6 +97,18 @@ const (
 	// MetaSegmentKey is the node metadata key used to store the node's network segment
 	MetaSegmentKey = "consul-network-segment"
 
+	// MetaDNSSRVTarget is the service metadata key controlling the target of
+	// the SRV records generated for the service instance. It may be set to
+	// DNSSRVTargetNode, DNSSRVTargetAddress or a literal hostname.
+	MetaDNSSRVTarget = "dns-srv-target"
+
+	// DNSSRVTargetNode makes SRV records always target the node name.
+	DNSSRVTargetNode = "node"
+
+	// DNSSRVTargetAddress makes SRV records always target the service
+	// address, falling back to the node address when it is not set.
+	DNSSRVTargetAddress = "address"
+
 	// MaxLockDelay provides a maximum LockDelay value for
 	// a session. Any value above this will not be respected.
 	MaxLockDelay = 60 * time.Second
@@ -95,6 +117,9 @@ const (
 // metaKeyFormat checks if a metadata key string is valid
 var metaKeyFormat = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString
 
+// dnsLabelFormat checks if a string is a valid DNS label
+var dnsLabelFormat = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`).MatchString
+
 func ValidStatus(s string) bool {
 	return s == api.HealthPassing || s == api.HealthWarning || s == api.HealthCritical
 }
@@ -231,6 +256,10 @@ type QueryMeta struct {
 	// Having `discovery_max_stale` on the agent can affect whether
 	// the request was served by a leader.
 	ConsistencyLevel string
+
+	// Degraded is set by the local agent when the result was served from the
+	// last known result persisted on disk because no server could be reached.
+	Degraded bool
 }
 
 // RegisterRequest is used for the Catalog.Register endpoint
@@ -254,6 +283,19 @@ type RegisterRequest struct {
 	// node portion of this update will not apply.
 	SkipNodeUpdate bool
 
+	// SyncOwner identifies the external sync tool that manages the node.
+	// Once a node has an owner, the registrations and deregistrations for
+	// it must come from the same owner unless ForceSyncOwner is set, in
+	// which case the owner is replaced.
+	SyncOwner      string
+	ForceSyncOwner bool
+
+	// CheckSyncOwner is set for the writes made through the catalog API,
+	// which are the only ones fenced by the owner of the node and the only
+	// ones that can change it. Internal writes, such as the leader's health
+	// updates and anti-entropy, leave the owner alone.
+	CheckSyncOwner bool
+
 	WriteRequest
 }
 
@@ -281,6 +323,7 @@ func (r *RegisterRequest) ChangesNode(node *Node) bool {
 		r.Node != node.Node ||
 		r.Address != node.Address ||
 		r.Datacenter != node.Datacenter ||
+		r.SyncOwner != node.SyncOwner ||
 		!reflect.DeepEqual(r.TaggedAddresses, node.TaggedAddresses) ||
 		!reflect.DeepEqual(r.NodeMeta, node.Meta) {
 		return true
@@ -297,6 +340,13 @@ type DeregisterRequest struct {
 	Node       string
 	ServiceID  string
 	CheckID    types.CheckID
+
+	// SyncOwner and ForceSyncOwner must match the owner of the node, if
+	// any, the same way as for a RegisterRequest, when CheckSyncOwner is set.
+	SyncOwner      string
+	ForceSyncOwner bool
+	CheckSyncOwner bool
+
 	WriteRequest
 }
 
@@ -304,6 +354,35 @@ func (r *DeregisterRequest) RequestDatacenter() string {
 	return r.Datacenter
 }
 
+// DeregisterBatchRequest is used to remove several services and checks of a
+// node from the catalog at once. Each of the Deregistrations must have either
+// a ServiceID or a CheckID, and is authorized with its own Token; the Node,
+// Datacenter and SyncOwner of the entries are taken from the batch.
+type DeregisterBatchRequest struct {
+	Datacenter      string
+	Node            string
+	Deregistrations []DeregisterRequest
+
+	// SyncOwner and ForceSyncOwner must match the owner of the node, if
+	// any, the same way as for a RegisterRequest, when CheckSyncOwner is set.
+	SyncOwner      string
+	ForceSyncOwner bool
+	CheckSyncOwner bool
+
+	WriteRequest
+}
+
+func (r *DeregisterBatchRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// DeregisterBatchResponse reports the outcome of each of the deregistrations
+// of a DeregisterBatchRequest. Errors is in the same order as the
+// Deregistrations, with an empty string for the ones that succeeded.
+type DeregisterBatchResponse struct {
+	Errors []string
+}
+
 // QuerySource is used to pass along information about the source node
 // in queries so that we can adjust the response based on its network
 // coordinates.
@@ -373,6 +452,10 @@ type ServiceSpecificRequest struct {
 	// Connect if true will only search for Connect-compatible services.
 	Connect bool
 
+	// IncludeDrained if true keeps the instances on drained nodes in the
+	// health results, they're excluded by default.
+	IncludeDrained bool
+
 	QueryOptions
 }
 
@@ -414,6 +497,7 @@ func (r *ServiceSpecificRequest) CacheInfo() cache.RequestInfo {
 		r.TagFilter,
 		r.Connect,
 		r.Filter,
+		r.IncludeDrained,
 	}, nil)
 	if err == nil {
 		// If there is an error, we don't set the key. A blank key forces
@@ -486,6 +570,9 @@ type Node struct {
 	TaggedAddresses map[string]string
 	Meta            map[string]string
 
+	// SyncOwner is the external sync tool that manages the node, if any.
+	SyncOwner string
+
 	RaftIndex `bexpr:"-"`
 }
 type Nodes []*Node
@@ -497,6 +584,7 @@ func (n *Node) IsSame(other *Node) bool {
 		n.Node == other.Node &&
 		n.Address == other.Address &&
 		n.Datacenter == other.Datacenter &&
+		n.SyncOwner == other.SyncOwner &&
 		reflect.DeepEqual(n.TaggedAddresses, other.TaggedAddresses) &&
 		reflect.DeepEqual(n.Meta, other.Meta)
 }
@@ -516,6 +604,44 @@ func ValidateMetadata(meta map[string]string, allowConsulPrefix bool) error {
 	return nil
 }
 
+// ValidateServiceMetadata validates the metadata of a service. On top of the
+// checks done by ValidateMetadata it validates the values of the keys that
+// have a special meaning for services.
+func ValidateServiceMetadata(meta map[string]string) error {
+	if err := ValidateMetadata(meta, false); err != nil {
+		return err
+	}
+
+	if target, ok := meta[MetaDNSSRVTarget]; ok {
+		if err := ValidateDNSSRVTarget(target); err != nil {
+			return fmt.Errorf("Invalid value for %q: %v", MetaDNSSRVTarget, err)
+		}
+	}
+	return nil
+}
+
+// ValidateDNSSRVTarget checks that the SRV target is one of the known modes or
+// a hostname that is safe to put in a DNS answer.
+func ValidateDNSSRVTarget(target string) error {
+	switch target {
+	case DNSSRVTargetNode, DNSSRVTargetAddress:
+		return nil
+	case "":
+		return fmt.Errorf("Target cannot be blank")
+	}
+
+	name := strings.TrimSuffix(target, ".")
+	if len(name) > 253 {
+		return fmt.Errorf("Hostname %q is too long", target)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !dnsLabelFormat(label) {
+			return fmt.Errorf("Hostname %q is not a valid DNS name", target)
+		}
+	}
+	return nil
+}
+
 // ValidateWeights checks the definition of DNS weight is valid
 func ValidateWeights(weights *Weights) error {
 	if weights == nil {
@@ -553,6 +679,23 @@ func validateMetaPair(key, value string, allowConsulPrefix bool) error {
 	return nil
 }
 
+// NodeDrained returns true if a node with the given metadata is drained at the
+// given time. A drain with an end time that can't be parsed never ends.
+func NodeDrained(meta map[string]string, now time.Time) bool {
+	until, ok := meta[NodeDrainedMetaKey]
+	if !ok {
+		return false
+	}
+	if until == "" {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return true
+	}
+	return now.Before(t)
+}
+
 // SatisfiesMetaFilters returns true if the metadata map contains the given filters
 func SatisfiesMetaFilters(meta map[string]string, filters map[string]string) bool {
 	for key, value := range filters {
@@ -593,6 +736,10 @@ type ServiceNode struct {
 	ServiceProxy            ConnectProxyConfig
 	ServiceConnect          ServiceConnect
 
+	// Drained is set on catalog reads when the node is drained, it's never
+	// stored.
+	Drained bool
+
 	RaftIndex `bexpr:"-"`
 }
 
@@ -822,6 +969,26 @@ func (s *NodeService) Validate() error {
 	return result
 }
 
+// NormalizeTags returns the tags without duplicates, sorted. An empty list
+// of tags is returned as is.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return tags
+	}
+
+	seen := make(map[string]struct{}, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
 // IsSame checks if one NodeService is the same as another, without looking
 // at the Raft information (that's why we didn't call it IsEqual). This is
 // useful for seeing if an update would be idempotent for all the functional
@@ -934,11 +1101,30 @@ type HealthCheck struct {
 	ServiceName string        // optional service name
 	ServiceTags []string      // optional service tags
 
+	// OutputDigest is only set when the output has been summarized for
+	// an HTTP response, it describes the full output.
+	OutputDigest *CheckOutputDigest `json:",omitempty" bexpr:"-"`
+
 	Definition HealthCheckDefinition `bexpr:"-"`
 
 	RaftIndex `bexpr:"-"`
 }
 
+// CheckOutputSummaryLength is the number of bytes of the output that are kept
+// when the output of a check is summarized.
+const CheckOutputSummaryLength = 100
+
+// CheckOutputDigest describes the full output of a check whose output has
+// been summarized.
+type CheckOutputDigest struct {
+	// SHA256 is the hex encoded SHA256 of the full output, so clients can
+	// tell when it changes.
+	SHA256 string
+
+	// Length is the length of the full output in bytes.
+	Length int
+}
+
 type HealthCheckDefinition struct {
 	HTTP                           string              `json:",omitempty"`
 	TLSSkipVerify                  bool                `json:",omitempty"`
@@ -1036,9 +1222,81 @@ func (c *HealthCheck) Clone() *HealthCheck {
 	return clone
 }
 
+// SummarizeOutput returns a clone of the HealthCheck with the output cut to
+// its first CheckOutputSummaryLength bytes and its digest set.
+func (c *HealthCheck) SummarizeOutput() *HealthCheck {
+	clone := c.Clone()
+
+	sum := sha256.Sum256([]byte(c.Output))
+	clone.OutputDigest = &CheckOutputDigest{
+		SHA256: hex.EncodeToString(sum[:]),
+		Length: len(c.Output),
+	}
+
+	if len(c.Output) > CheckOutputSummaryLength {
+		// Don't cut a multi-byte character in half.
+		n := CheckOutputSummaryLength
+		for n > 0 && !utf8.RuneStart(c.Output[n]) {
+			n--
+		}
+		clone.Output = c.Output[:n]
+	}
+	return clone
+}
+
 // HealthChecks is a collection of HealthCheck structs.
 type HealthChecks []*HealthCheck
 
+// HealthTransition records a change in the status of a service's health
+// check. Transitions are kept in memory by the servers and are only
+// advisory, see state.HealthHistory.
+type HealthTransition struct {
+	Time        time.Time
+	Node        string
+	ServiceID   string
+	ServiceName string
+	CheckID     types.CheckID
+	OldStatus   string
+	NewStatus   string
+}
+
+type HealthTransitions []*HealthTransition
+
+const (
+	// ConnectMismatchSidecarMissing is used when a service instance has no
+	// sidecar proxy while other instances of the same service do.
+	ConnectMismatchSidecarMissing = "sidecar-missing"
+
+	// ConnectMismatchSidecarCritical is used when all the sidecar proxies of
+	// a service instance are critical.
+	ConnectMismatchSidecarCritical = "sidecar-critical"
+
+	// ConnectMismatchDestinationMissing is used when a sidecar proxy's
+	// destination service isn't registered on the same node.
+	ConnectMismatchDestinationMissing = "destination-missing"
+)
+
+// ConnectMismatch is a service instance that can't be reached through Connect
+// because of its sidecar proxy, or a sidecar proxy without its service.
+type ConnectMismatch struct {
+	Node        string
+	ServiceID   string
+	ServiceName string
+
+	// ProxyServiceID is the ID of the sidecar proxy, it is empty when the
+	// sidecar is missing.
+	ProxyServiceID string `json:",omitempty"`
+
+	// Reason is one of the ConnectMismatch constants.
+	Reason string
+
+	// Checks are the critical checks of the sidecar proxy when Reason is
+	// ConnectMismatchSidecarCritical.
+	Checks HealthChecks `json:",omitempty"`
+}
+
+type ConnectMismatches []*ConnectMismatch
+
 // CheckServiceNode is used to provide the node, its service
 // definition, as well as a HealthCheck that is associated.
 type CheckServiceNode struct {
@@ -1139,8 +1397,23 @@ type IndexedHealthChecks struct {
 	QueryMeta
 }
 
+type IndexedHealthTransitions struct {
+	Transitions HealthTransitions
+	QueryMeta
+}
+
+type IndexedConnectMismatches struct {
+	Mismatches ConnectMismatches
+	QueryMeta
+}
+
 type IndexedCheckServiceNodes struct {
 	Nodes CheckServiceNodes
+
+	// Routing is the routing override of the service, if any. It is up to
+	// the agent to apply it since it depends on the query source.
+	Routing *ServiceRoutingConfigEntry `json:",omitempty"`
+
 	QueryMeta
 }
 
@@ -1158,6 +1431,10 @@ type DirEntry struct {
 	Value     []byte
 	Session   string `json:",omitempty"`
 
+	// Checksum is the checksum of Value given by the client that wrote it,
+	// as "sha256:<hex>". It's empty if the client didn't supply one.
+	Checksum string `json:",omitempty"`
+
 	RaftIndex
 }
 
@@ -1169,6 +1446,7 @@ func (d *DirEntry) Clone() *DirEntry {
 		Flags:     d.Flags,
 		Value:     d.Value,
 		Session:   d.Session,
+		Checksum:  d.Checksum,
 		RaftIndex: RaftIndex{
 			CreateIndex: d.CreateIndex,
 			ModifyIndex: d.ModifyIndex,
@@ -1176,13 +1454,80 @@ func (d *DirEntry) Clone() *DirEntry {
 	}
 }
 
+// VerifyChecksum returns an error if the entry has a checksum that doesn't
+// match its value.
+func (d *DirEntry) VerifyChecksum() error {
+	if d.Checksum == "" {
+		return nil
+	}
+	if sum := KVChecksum(d.Value); sum != d.Checksum {
+		return fmt.Errorf("%s for key %q: expected %s, got %s", errKVChecksumMismatch, d.Key, d.Checksum, sum)
+	}
+	return nil
+}
+
+// kvChecksumPrefix is the prefix of a KV checksum, which names the only
+// supported algorithm.
+const kvChecksumPrefix = "sha256:"
+
+// KVChecksum returns the checksum of a KV value, as "sha256:<hex>".
+func KVChecksum(value []byte) string {
+	sum := sha256.Sum256(value)
+	return kvChecksumPrefix + hex.EncodeToString(sum[:])
+}
+
+// ParseKVChecksum validates a checksum given by a client and returns it in
+// canonical form, with the digest in lowercase.
+func ParseKVChecksum(checksum string) (string, error) {
+	if !strings.HasPrefix(checksum, kvChecksumPrefix) {
+		return "", fmt.Errorf("Invalid checksum %q: must be of the form \"sha256:<hex>\"", checksum)
+	}
+	digest := strings.ToLower(strings.TrimPrefix(checksum, kvChecksumPrefix))
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("Invalid checksum %q: must be of the form \"sha256:<hex>\"", checksum)
+	}
+	return kvChecksumPrefix + digest, nil
+}
+
 type DirEntries []*DirEntry
 
+// DeletedDirEntry is a KV entry that was deleted. When enabled, the servers
+// retain deleted entries for a window of time so they can be restored. Only
+// the last deletion of each key is kept, and its raft indexes are the index
+// of the deletion.
+type DeletedDirEntry struct {
+	Key   string
+	Flags uint64
+	Value []byte
+
+	// DeletedAt is the time of the deletion on the leader, and DeletedBy is
+	// the accessor ID of the token that made it, if ACLs are enabled.
+	DeletedAt time.Time
+	DeletedBy string `json:",omitempty"`
+
+	RaftIndex
+}
+
+type DeletedDirEntries []*DeletedDirEntry
+
+// KVSRetention is set by the leader on the operations that delete KV
+// entries when the servers retain deleted entries. It's recorded with each
+// entry the operation deletes.
+type KVSRetention struct {
+	DeletedAt time.Time
+	DeletedBy string
+}
+
 // KVSRequest is used to operate on the Key-Value store
 type KVSRequest struct {
 	Datacenter string
 	Op         api.KVOp // Which operation are we performing
 	DirEnt     DirEntry // Which directory entry
+
+	// Retention is set when the entries deleted by this operation are
+	// retained.
+	Retention *KVSRetention
+
 	WriteRequest
 }
 
@@ -1218,6 +1563,11 @@ type IndexedDirEntries struct {
 	QueryMeta
 }
 
+type IndexedDeletedDirEntries struct {
+	Entries DeletedDirEntries
+	QueryMeta
+}
+
 type IndexedKeyList struct {
 	Keys []string
 	QueryMeta
@@ -1285,6 +1635,34 @@ type IndexedSessions struct {
 	QueryMeta
 }
 
+// SessionBatchRenewMax is the most sessions that can be renewed in a single
+// SessionBatchRenewRequest.
+const SessionBatchRenewMax = 1024
+
+// SessionBatchRenewRequest is used to renew the TTL on several sessions at
+// once.
+type SessionBatchRenewRequest struct {
+	Datacenter string
+	Sessions   []string
+	QueryOptions
+}
+
+func (r *SessionBatchRenewRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// SessionRenewResult is the result of renewing a session in a batch. Session
+// is the renewed session, or nil if it wasn't found.
+type SessionRenewResult struct {
+	ID      string
+	Session *Session
+}
+
+type SessionBatchRenewResponse struct {
+	Results []SessionRenewResult
+	QueryMeta
+}
+
 // Coordinate stores a node name with its associated network coordinate.
 type Coordinate struct {
 	Node    string
@@ -1317,6 +1695,21 @@ type DatacenterMap struct {
 	Coordinates Coordinates
 }
 
+// DatacenterInfo describes a datacenter known over the WAN and whether it can
+// currently be reached.
+type DatacenterInfo struct {
+	Datacenter string
+
+	// Servers is the number of servers of the datacenter in the WAN pool.
+	Servers int
+
+	// FailedServers is the number of those servers that are failed.
+	FailedServers int
+
+	// Reachable is true if at least one server of the datacenter is alive.
+	Reachable bool
+}
+
 // CoordinateUpdateRequest is used to update the network coordinate of a given
 // node.
 type CoordinateUpdateRequest struct {
@@ -1355,10 +1748,34 @@ type EventFireResponse struct {
 	QueryMeta
 }
 
+// StatusRequest is used to run a blocking query against the Raft status
+// of the server answering the request.
+type StatusRequest struct {
+	QueryOptions
+}
+
+// StatusLeaderResponse is used to return the Raft leader. The index is a
+// logical leadership epoch that moves forward every time the leader changes.
+type StatusLeaderResponse struct {
+	Leader string
+	QueryMeta
+}
+
+// StatusPeersResponse is used to return the Raft peers. The index is the
+// Raft index of the latest configuration.
+type StatusPeersResponse struct {
+	Peers []string
+	QueryMeta
+}
+
 type TombstoneOp string
 
 const (
 	TombstoneReap TombstoneOp = "reap"
+
+	// TombstoneReapKVSDeleted reaps the retained deleted KV entries instead
+	// of the tombstones.
+	TombstoneReapKVSDeleted TombstoneOp = "reap-kvs-deleted"
 )
 
 // TombstoneRequest is used to trigger a reaping of the tombstones
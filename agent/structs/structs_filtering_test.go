@@ -160,6 +160,11 @@ var expectedFieldConfigNode bexpr.FieldConfigurations = bexpr.FieldConfiguration
 			},
 		},
 	},
+	"SyncOwner": &bexpr.FieldConfiguration{
+		StructFieldName:     "SyncOwner",
+		CoerceFn:            bexpr.CoerceString,
+		SupportedOperations: []bexpr.MatchOperator{bexpr.MatchEqual, bexpr.MatchNotEqual},
+	},
 }
 
 var expectedFieldConfigNodeService bexpr.FieldConfigurations = bexpr.FieldConfigurations{
@@ -304,6 +309,11 @@ var expectedFieldConfigServiceNode bexpr.FieldConfigurations = bexpr.FieldConfig
 		StructFieldName: "ServiceConnect",
 		SubFields:       expectedFieldConfigServiceConnect,
 	},
+	"Drained": &bexpr.FieldConfiguration{
+		StructFieldName:     "Drained",
+		CoerceFn:            bexpr.CoerceBool,
+		SupportedOperations: []bexpr.MatchOperator{bexpr.MatchEqual, bexpr.MatchNotEqual},
+	},
 }
 
 var expectedFieldConfigHealthCheck bexpr.FieldConfigurations = bexpr.FieldConfigurations{
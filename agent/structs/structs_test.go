@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/agent/cache"
 	"github.com/hashicorp/consul/api"
@@ -466,6 +467,28 @@ func TestStructs_NodeService_ValidateSidecarService(t *testing.T) {
 	}
 }
 
+func TestStructs_NormalizeTags(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		tags []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{}, []string{}},
+		{[]string{"b", "a"}, []string{"a", "b"}},
+		{[]string{"b", "a", "b", "a"}, []string{"a", "b"}},
+		{[]string{"Primary", "primary"}, []string{"Primary", "primary"}},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, NormalizeTags(tc.tags))
+	}
+
+	// The tags passed in are not modified.
+	tags := []string{"b", "a", "b"}
+	NormalizeTags(tags)
+	require.Equal(t, []string{"b", "a", "b"}, tags)
+}
+
 func TestStructs_NodeService_IsSame(t *testing.T) {
 	ns := &NodeService{
 		ID:      "node1",
@@ -671,6 +694,25 @@ func TestStructs_HealthCheck_Clone(t *testing.T) {
 	}
 }
 
+func TestStructs_HealthCheck_SummarizeOutput(t *testing.T) {
+	short := &HealthCheck{Output: "lgtm"}
+	summary := short.SummarizeOutput()
+	require.Equal(t, "lgtm", summary.Output)
+	require.Equal(t, 4, summary.OutputDigest.Length)
+	require.Equal(t, "cf336867433a7a7fcb13af9e5431ae39190a6da3926e7efd6b49591fa9d5e634", summary.OutputDigest.SHA256)
+	require.Nil(t, short.OutputDigest)
+
+	long := &HealthCheck{Output: strings.Repeat("a", 99) + "éé"}
+	summary = long.SummarizeOutput()
+	require.Equal(t, strings.Repeat("a", 99), summary.Output)
+	require.Equal(t, 103, summary.OutputDigest.Length)
+	require.Len(t, summary.OutputDigest.SHA256, 64)
+	require.NotEqual(t, short.SummarizeOutput().OutputDigest.SHA256, summary.OutputDigest.SHA256)
+
+	// The original is left untouched
+	require.Len(t, long.Output, 103)
+}
+
 func TestStructs_CheckServiceNodes_Shuffle(t *testing.T) {
 	// Make a huge list of nodes.
 	var nodes CheckServiceNodes
@@ -814,6 +856,7 @@ func TestStructs_DirEntry_Clone(t *testing.T) {
 		Flags:     23,
 		Value:     []byte("this is a test"),
 		Session:   "session1",
+		Checksum:  KVChecksum([]byte("this is a test")),
 		RaftIndex: RaftIndex{
 			CreateIndex: 1,
 			ModifyIndex: 2,
@@ -831,6 +874,52 @@ func TestStructs_DirEntry_Clone(t *testing.T) {
 	}
 }
 
+func TestStructs_DirEntry_VerifyChecksum(t *testing.T) {
+	e := &DirEntry{Key: "hello", Value: []byte("world")}
+	require.NoError(t, e.VerifyChecksum())
+
+	e.Checksum = KVChecksum([]byte("world"))
+	require.Equal(t, "sha256:486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7", e.Checksum)
+	require.NoError(t, e.VerifyChecksum())
+
+	e.Value = []byte("w0rld")
+	err := e.VerifyChecksum()
+	require.Error(t, err)
+	require.True(t, IsErrKVChecksumMismatch(err))
+}
+
+func TestStructs_ParseKVChecksum(t *testing.T) {
+	sum := KVChecksum([]byte("world"))
+
+	got, err := ParseKVChecksum("sha256:" + strings.ToUpper(sum[7:]))
+	require.NoError(t, err)
+	require.Equal(t, sum, got)
+
+	for _, bad := range []string{"", "sha256:", "SHA256:" + sum[7:], "md5:" + sum[7:], sum[:len(sum)-2], sum + "00", "sha256:" + strings.Repeat("zz", 32)} {
+		_, err := ParseKVChecksum(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestStructs_NodeDrained(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	cases := []struct {
+		meta map[string]string
+		want bool
+	}{
+		{nil, false},
+		{map[string]string{"key1": "value1"}, false},
+		{map[string]string{NodeDrainedMetaKey: ""}, true},
+		{map[string]string{NodeDrainedMetaKey: now.Add(time.Minute).Format(time.RFC3339)}, true},
+		{map[string]string{NodeDrainedMetaKey: now.Add(-time.Minute).Format(time.RFC3339)}, false},
+		{map[string]string{NodeDrainedMetaKey: "bogus"}, true},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, NodeDrained(tc.meta, now), "%v", tc.meta)
+	}
+}
+
 func TestStructs_ValidateMetadata(t *testing.T) {
 	// Load a valid set of key/value pairs
 	meta := map[string]string{
@@ -910,6 +999,39 @@ func TestStructs_validateMetaPair(t *testing.T) {
 	}
 }
 
+func TestStructs_ValidateServiceMetadata(t *testing.T) {
+	cases := []struct {
+		Target string
+		Error  string
+	}{
+		{DNSSRVTargetNode, ""},
+		{DNSSRVTargetAddress, ""},
+		{"db.example.com", ""},
+		{"db.example.com.", ""},
+		{"db-1", ""},
+		{"", "cannot be blank"},
+		{"db..example.com", "not a valid DNS name"},
+		{"-db.example.com", "not a valid DNS name"},
+		{"db_1.example.com", "not a valid DNS name"},
+		{strings.Repeat("a", 64) + ".com", "not a valid DNS name"},
+		{strings.Repeat("a.", 127) + "com", "too long"},
+	}
+
+	for _, tc := range cases {
+		err := ValidateServiceMetadata(map[string]string{MetaDNSSRVTarget: tc.Target})
+		if tc.Error == "" && err != nil {
+			t.Fatalf("should have succeeded: %q, %v", tc.Target, err)
+		} else if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+			t.Fatalf("should have failed: %q, %v", tc.Target, err)
+		}
+	}
+
+	// The regular metadata checks still apply.
+	if err := ValidateServiceMetadata(map[string]string{metaKeyReservedPrefix + "key": "value"}); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
 func TestSpecificServiceRequest_CacheInfo(t *testing.T) {
 	tests := []struct {
 		name     string
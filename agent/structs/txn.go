@@ -13,6 +13,10 @@ import (
 type TxnKVOp struct {
 	Verb   api.KVOp
 	DirEnt DirEntry
+
+	// Retention is set when the entries deleted by this operation are
+	// retained.
+	Retention *KVSRetention
 }
 
 // TxnKVResult is used to define the result of a single operation on the KVS
@@ -24,6 +28,11 @@ type TxnKVResult *DirEntry
 type TxnNodeOp struct {
 	Verb api.NodeOp
 	Node Node
+
+	// ForceSyncOwner and CheckSyncOwner work like for a RegisterRequest,
+	// with the owner taken from the Node.
+	ForceSyncOwner bool
+	CheckSyncOwner bool
 }
 
 // TxnNodeResult is used to define the result of a single operation on a node
@@ -36,6 +45,12 @@ type TxnServiceOp struct {
 	Verb    api.ServiceOp
 	Node    string
 	Service NodeService
+
+	// SyncOwner, ForceSyncOwner and CheckSyncOwner work like for a
+	// RegisterRequest, but a service op never changes the owner.
+	SyncOwner      string
+	ForceSyncOwner bool
+	CheckSyncOwner bool
 }
 
 // TxnServiceResult is used to define the result of a single operation on a service
@@ -99,6 +114,10 @@ func (r *TxnReadRequest) RequestDatacenter() string {
 type TxnError struct {
 	OpIndex int
 	What    string
+
+	// Code is the api error code of the failure, if known. It's set by the
+	// HTTP API.
+	Code string `json:",omitempty"`
 }
 
 // Error returns the string representation of an atomic error.
@@ -0,0 +1,315 @@
+package subscribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/structs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultQueueSize is the default value for Server.QueueSize to use
+	// when the zero value is provided.
+	DefaultQueueSize = 16
+
+	// DefaultMaxQueryTime is the default value for Server.MaxQueryTime to
+	// use when the zero value is provided.
+	DefaultMaxQueryTime = time.Minute
+
+	// maxRetryWait is the longest a subscription waits before retrying a
+	// failed query.
+	maxRetryWait = time.Minute
+)
+
+// Cache is the interface the Server needs to query the catalog. It's
+// satisfied by the agent cache, which must have the cachetype.HealthServices
+// type registered.
+type Cache interface {
+	GetWithContext(ctx context.Context, t string, r cache.Request) (interface{}, cache.ResultMeta, error)
+}
+
+// Server implements the consul.subscribe.Consul gRPC service. Each
+// subscription follows the results of blocking queries on behalf of the
+// client and only sends it what changed between them, so the client needs
+// a single long-lived stream rather than a blocking query loop. The queries
+// are served by the agent cache, so all the subscriptions to the same
+// service with the same token share a single blocking query to the servers.
+// All of its public members must be set before the gRPC server is started.
+type Server struct {
+	Logger *log.Logger
+	Cache  Cache
+
+	// Datacenter is the datacenter followed by subscriptions that don't
+	// ask for one.
+	Datacenter string
+
+	// DefaultToken returns the ACL token used for the subscriptions that
+	// don't carry one in the x-consul-token metadata.
+	DefaultToken func() string
+
+	// QueueSize is the number of query results buffered for a subscription
+	// while its client is slow to read. Once it's exceeded, the buffered
+	// results are thrown away and the client is sent a reset followed by
+	// the latest state instead.
+	QueueSize int
+
+	// MaxQueryTime is the longest each blocking query made on behalf of a
+	// subscription waits for a change in the cache.
+	MaxQueryTime time.Duration
+}
+
+// Initialize will finish configuring the Server for first use.
+func (s *Server) Initialize() {
+	if s.QueueSize <= 0 {
+		s.QueueSize = DefaultQueueSize
+	}
+	if s.MaxQueryTime == 0 {
+		s.MaxQueryTime = DefaultMaxQueryTime
+	}
+}
+
+// Subscribe implements ConsulServer.
+func (s *Server) Subscribe(req *SubscribeRequest, stream SubscribeStream) error {
+	switch req.Topic {
+	case TopicServiceHealth:
+	default:
+		return status.Errorf(codes.InvalidArgument, "unsupported topic %d", req.Topic)
+	}
+	if req.Key == "" {
+		return status.Error(codes.InvalidArgument, "a service name is required")
+	}
+
+	token := tokenFromContext(stream.Context())
+	if token == "" && s.DefaultToken != nil {
+		token = s.DefaultToken()
+	}
+	args := structs.ServiceSpecificRequest{
+		Datacenter:  req.Datacenter,
+		ServiceName: req.Key,
+		QueryOptions: structs.QueryOptions{
+			Token:        token,
+			MaxQueryTime: s.MaxQueryTime,
+		},
+	}
+	if args.Datacenter == "" {
+		args.Datacenter = s.Datacenter
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// The results are queued so that a slow client doesn't hold up the
+	// queries. The flag is raised when the queue overflowed, which means
+	// the client has to be resynced.
+	updates := make(chan *structs.IndexedCheckServiceNodes, s.QueueSize)
+	var resync int32
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.watchServiceHealth(ctx, args, updates, &resync)
+	}()
+
+	metrics.IncrCounter([]string{"grpc", "subscribe", "stream"}, 1)
+
+	// sent has the payload of every entry the client knows about. The
+	// first batch always resets the client.
+	sent := make(map[string][]byte)
+	reset := true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err := <-errCh:
+			return err
+
+		case out := <-updates:
+			if atomic.SwapInt32(&resync, 0) == 1 {
+				reset = true
+			}
+			if err := sendServiceHealth(stream, out, sent, reset); err != nil {
+				return err
+			}
+			reset = false
+		}
+	}
+}
+
+// watchServiceHealth runs blocking queries for the health of a service and
+// queues each new result until the context is canceled.
+func (s *Server) watchServiceHealth(ctx context.Context, args structs.ServiceSpecificRequest,
+	updates chan *structs.IndexedCheckServiceNodes, resync *int32) error {
+
+	var failures uint
+	first := true
+	for {
+		// The cache type updates the request it's given, so every query
+		// gets its own copy.
+		req := args
+		raw, _, err := s.Cache.GetWithContext(ctx, cachetype.HealthServicesName, &req)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if acl.IsErrNotFound(err) || acl.IsErrPermissionDenied(err) {
+				return status.Error(codes.PermissionDenied, err.Error())
+			}
+
+			failures++
+			wait := retryWait(failures)
+			s.Logger.Printf("[WARN] subscribe: failed to fetch the health of service %q, retrying in %s: %v",
+				args.ServiceName, wait, err)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		failures = 0
+
+		// The result is shared with the other users of the cache, so it's
+		// copied before its index is adjusted below.
+		result, ok := raw.(*structs.IndexedCheckServiceNodes)
+		if !ok {
+			return status.Errorf(codes.Internal, "unexpected result type %T", raw)
+		}
+		out := *result
+
+		// The query timed out without any change.
+		if !first && out.Index == args.MinQueryIndex {
+			continue
+		}
+		first = false
+
+		// A blocking query with a zero index returns straight away.
+		if out.Index < 1 {
+			out.Index = 1
+		}
+		args.MinQueryIndex = out.Index
+
+		select {
+		case updates <- &out:
+		default:
+			// The client isn't keeping up. Throw away what it hasn't been
+			// sent yet and resync it from the latest state instead.
+			metrics.IncrCounter([]string{"grpc", "subscribe", "resync"}, 1)
+			atomic.StoreInt32(resync, 1)
+		DRAIN:
+			for {
+				select {
+				case <-updates:
+				default:
+					break DRAIN
+				}
+			}
+			updates <- &out
+		}
+	}
+}
+
+// sendServiceHealth sends the client the changes between the entries it
+// knows about and the given result, and records them in sent. If reset is
+// true the client is reset and sent the full result instead.
+func sendServiceHealth(stream SubscribeStream, out *structs.IndexedCheckServiceNodes,
+	sent map[string][]byte, reset bool) error {
+
+	current := make(map[string][]byte, len(out.Nodes))
+	for _, csn := range out.Nodes {
+		payload, err := json.Marshal(csn)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		current[serviceHealthID(csn)] = payload
+	}
+
+	var events []*Event
+	if reset {
+		events = append(events, &Event{Type: EventTypeReset})
+		for id := range sent {
+			delete(sent, id)
+		}
+	}
+	for _, id := range sortedIDs(current) {
+		if prev, ok := sent[id]; ok && bytes.Equal(prev, current[id]) {
+			continue
+		}
+		events = append(events, &Event{Type: EventTypeUpsert, ID: id, Payload: current[id]})
+	}
+	for _, id := range sortedIDs(sent) {
+		if _, ok := current[id]; !ok {
+			events = append(events, &Event{Type: EventTypeDelete, ID: id})
+		}
+	}
+
+	// Don't bother the client when nothing it can see has changed.
+	if len(events) == 0 {
+		return nil
+	}
+	events = append(events, &Event{Type: EventTypeCommit})
+
+	for _, e := range events {
+		e.Index = out.Index
+		if err := stream.Send(e); err != nil {
+			return err
+		}
+	}
+
+	for id := range sent {
+		delete(sent, id)
+	}
+	for id, payload := range current {
+		sent[id] = payload
+	}
+	return nil
+}
+
+// serviceHealthID returns the ID of a service instance in the events.
+func serviceHealthID(csn structs.CheckServiceNode) string {
+	return csn.Node.Node + "/" + csn.Service.ID
+}
+
+func sortedIDs(m map[string][]byte) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// retryWait returns how long to wait before retrying a query that failed
+// the given number of times in a row.
+func retryWait(failures uint) time.Duration {
+	if failures > 6 {
+		return maxRetryWait
+	}
+	wait := time.Duration(1<<failures) * time.Second
+	if wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	return wait
+}
+
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	toks, ok := md["x-consul-token"]
+	if ok && len(toks) > 0 {
+		return toks[0]
+	}
+	return ""
+}
@@ -0,0 +1,419 @@
+package subscribe
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	apisubscribe "github.com/hashicorp/consul/api/subscribe"
+)
+
+// testDelegate is a fake of the servers that answers blocking queries for
+// the health of a service from the state it's given. It serves the queries
+// of the agent cache used by the tests.
+type testDelegate struct {
+	sync.Mutex
+	index  uint64
+	nodes  structs.CheckServiceNodes
+	err    error
+	tokens []string
+	notify chan struct{}
+
+	// queries is the number of queries made.
+	queries int
+}
+
+func newTestDelegate() *testDelegate {
+	return &testDelegate{notify: make(chan struct{})}
+}
+
+// Set changes the state and wakes up the blocking queries.
+func (d *testDelegate) Set(index uint64, nodes structs.CheckServiceNodes, err error) {
+	d.Lock()
+	defer d.Unlock()
+	d.index = index
+	d.nodes = nodes
+	d.err = err
+	close(d.notify)
+	d.notify = make(chan struct{})
+}
+
+func (d *testDelegate) RPC(method string, args interface{}, reply interface{}) error {
+	req := args.(*structs.ServiceSpecificRequest)
+	out := reply.(*structs.IndexedCheckServiceNodes)
+
+	d.Lock()
+	d.queries++
+	d.Unlock()
+
+	timeout := time.After(req.MaxQueryTime)
+	for {
+		d.Lock()
+		if len(d.tokens) == 0 || d.tokens[len(d.tokens)-1] != req.Token {
+			d.tokens = append(d.tokens, req.Token)
+		}
+		if d.err != nil {
+			err := d.err
+			d.Unlock()
+			return err
+		}
+		if d.index > req.MinQueryIndex {
+			out.Index = d.index
+			out.Nodes = d.nodes
+			d.Unlock()
+			return nil
+		}
+		notify := d.notify
+		d.Unlock()
+
+		select {
+		case <-notify:
+		case <-timeout:
+			d.Lock()
+			out.Index = d.index
+			out.Nodes = d.nodes
+			d.Unlock()
+			return nil
+		}
+	}
+}
+
+func testCheckServiceNode(node, service, status string) structs.CheckServiceNode {
+	return structs.CheckServiceNode{
+		Node: &structs.Node{Node: node, Address: "127.0.0.1"},
+		Service: &structs.NodeService{
+			ID:      service,
+			Service: "web",
+			Port:    8080,
+		},
+		Checks: structs.HealthChecks{
+			&structs.HealthCheck{
+				Node:      node,
+				CheckID:   "web-check",
+				ServiceID: service,
+				Status:    status,
+			},
+		},
+	}
+}
+
+// testCache returns an agent cache that serves the health of services from
+// the given delegate.
+func testCache(t *testing.T, d *testDelegate) *cache.Cache {
+	c := cache.TestCache(t)
+	c.RegisterType(cachetype.HealthServicesName, &cachetype.HealthServices{RPC: d}, &cache.RegisterOptions{
+		Refresh:        true,
+		RefreshTimeout: 10 * time.Minute,
+	})
+	return c
+}
+
+func testServer(t *testing.T, d *testDelegate) (*grpc.ClientConn, func()) {
+	s := &Server{
+		Logger:       log.New(os.Stderr, "", log.LstdFlags),
+		Cache:        testCache(t, d),
+		Datacenter:   "dc1",
+		DefaultToken: func() string { return "default-token" },
+		MaxQueryTime: 100 * time.Millisecond,
+	}
+	s.Initialize()
+
+	srv := grpc.NewServer()
+	RegisterConsulServer(srv, s)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(l)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return conn, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestServer_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDelegate()
+	d.Set(5, structs.CheckServiceNodes{
+		testCheckServiceNode("node1", "web1", api.HealthPassing),
+		testCheckServiceNode("node2", "web2", api.HealthPassing),
+	}, nil)
+	conn, cleanup := testServer(t, d)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := (&api.QueryOptions{Token: "root"}).WithContext(ctx)
+	sub, err := apisubscribe.ServiceHealth(conn, "web", q)
+	require.NoError(t, err)
+
+	// The full state is sent first.
+	entries, meta, err := sub.Next()
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), meta.LastIndex)
+	require.Len(t, entries, 2)
+	require.Equal(t, "node1", entries[0].Node.Node)
+	require.Equal(t, "web1", entries[0].Service.ID)
+	require.Equal(t, api.HealthPassing, entries[0].Checks[0].Status)
+	require.Equal(t, "node2", entries[1].Node.Node)
+
+	// Then only the changes.
+	d.Set(6, structs.CheckServiceNodes{
+		testCheckServiceNode("node1", "web1", api.HealthCritical),
+	}, nil)
+	entries, meta, err = sub.Next()
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), meta.LastIndex)
+	require.Len(t, entries, 1)
+	require.Equal(t, "web1", entries[0].Service.ID)
+	require.Equal(t, api.HealthCritical, entries[0].Checks[0].Status)
+
+	d.Lock()
+	require.Equal(t, []string{"root"}, d.tokens)
+	d.Unlock()
+}
+
+func TestServer_Subscribe_DefaultToken(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDelegate()
+	d.Set(5, nil, nil)
+	conn, cleanup := testServer(t, d)
+	defer cleanup()
+
+	sub, err := apisubscribe.ServiceHealth(conn, "web", nil)
+	require.NoError(t, err)
+	entries, meta, err := sub.Next()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.Equal(t, uint64(5), meta.LastIndex)
+
+	d.Lock()
+	require.Equal(t, "default-token", d.tokens[0])
+	d.Unlock()
+}
+
+func TestServer_Subscribe_ACLDenied(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDelegate()
+	d.Set(5, nil, acl.ErrNotFound)
+	conn, cleanup := testServer(t, d)
+	defer cleanup()
+
+	sub, err := apisubscribe.ServiceHealth(conn, "web", &api.QueryOptions{Token: "nope"})
+	require.NoError(t, err)
+	_, _, err = sub.Next()
+	require.Error(t, err)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_Subscribe_NoService(t *testing.T) {
+	t.Parallel()
+
+	conn, cleanup := testServer(t, newTestDelegate())
+	defer cleanup()
+
+	sub, err := apisubscribe.ServiceHealth(conn, "", nil)
+	require.NoError(t, err)
+	_, _, err = sub.Next()
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// testStream records the events sent on a subscription.
+type testStream struct {
+	grpc.ServerStream
+	events []*Event
+}
+
+func (s *testStream) Send(e *Event) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *testStream) types() []EventType {
+	var types []EventType
+	for _, e := range s.events {
+		types = append(types, e.Type)
+	}
+	s.events = nil
+	return types
+}
+
+func TestSendServiceHealth(t *testing.T) {
+	t.Parallel()
+
+	stream := &testStream{}
+	sent := make(map[string][]byte)
+
+	send := func(index uint64, reset bool, nodes ...structs.CheckServiceNode) {
+		out := &structs.IndexedCheckServiceNodes{Nodes: nodes}
+		out.Index = index
+		require.NoError(t, sendServiceHealth(stream, out, sent, reset))
+	}
+
+	send(5, true,
+		testCheckServiceNode("node1", "web1", api.HealthPassing),
+		testCheckServiceNode("node2", "web2", api.HealthPassing))
+	require.Equal(t, "node1/web1", stream.events[1].ID)
+	require.Equal(t, []EventType{EventTypeReset, EventTypeUpsert, EventTypeUpsert, EventTypeCommit}, stream.types())
+
+	// Nothing changed.
+	send(6, false,
+		testCheckServiceNode("node1", "web1", api.HealthPassing),
+		testCheckServiceNode("node2", "web2", api.HealthPassing))
+	require.Empty(t, stream.types())
+
+	send(7, false,
+		testCheckServiceNode("node1", "web1", api.HealthCritical))
+	require.Equal(t, "node1/web1", stream.events[0].ID)
+	require.Equal(t, "node2/web2", stream.events[1].ID)
+	require.Equal(t, uint64(7), stream.events[2].Index)
+	require.Equal(t, []EventType{EventTypeUpsert, EventTypeDelete, EventTypeCommit}, stream.types())
+
+	// A resync sends everything again.
+	send(8, true,
+		testCheckServiceNode("node1", "web1", api.HealthCritical))
+	require.Equal(t, []EventType{EventTypeReset, EventTypeUpsert, EventTypeCommit}, stream.types())
+	require.Len(t, sent, 1)
+}
+
+func TestServer_watchServiceHealth_Resync(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDelegate()
+	d.Set(5, nil, nil)
+	s := &Server{
+		Logger:       log.New(os.Stderr, "", log.LstdFlags),
+		Cache:        testCache(t, d),
+		QueueSize:    1,
+		MaxQueryTime: 100 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan *structs.IndexedCheckServiceNodes, s.QueueSize)
+	var resync int32
+	go s.watchServiceHealth(ctx, structs.ServiceSpecificRequest{
+		ServiceName:  "web",
+		QueryOptions: structs.QueryOptions{MaxQueryTime: s.MaxQueryTime},
+	}, updates, &resync)
+
+	waitFor := func(cond func() bool) {
+		deadline := time.After(5 * time.Second)
+		for !cond() {
+			select {
+			case <-deadline:
+				t.Fatal("timed out")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	waitFor(func() bool { return len(updates) == 1 })
+
+	// Nothing reads the updates, so the second one overflows the queue.
+	d.Set(6, nil, nil)
+	waitFor(func() bool { return atomic.LoadInt32(&resync) == 1 })
+
+	// Only the latest result is left.
+	out := <-updates
+	require.Equal(t, uint64(6), out.Index)
+	require.Len(t, updates, 0)
+}
+
+func TestServer_watchServiceHealth_Cancel(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDelegate()
+	d.Set(5, nil, nil)
+	s := &Server{
+		Logger:       log.New(os.Stderr, "", log.LstdFlags),
+		Cache:        testCache(t, d),
+		MaxQueryTime: time.Minute,
+	}
+	s.Initialize()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan *structs.IndexedCheckServiceNodes, s.QueueSize)
+	var resync int32
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.watchServiceHealth(ctx, structs.ServiceSpecificRequest{
+			ServiceName:  "web",
+			QueryOptions: structs.QueryOptions{MaxQueryTime: s.MaxQueryTime},
+		}, updates, &resync)
+	}()
+
+	// Once the first result is in, the watch blocks in the cache.
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	// It must not wait for the blocking query to time out.
+	cancel()
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("watch didn't stop")
+	}
+}
+
+func TestServer_Subscribe_SharedQuery(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDelegate()
+	d.Set(5, structs.CheckServiceNodes{
+		testCheckServiceNode("node1", "web1", api.HealthPassing),
+	}, nil)
+	conn, cleanup := testServer(t, d)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := (&api.QueryOptions{Token: "root"}).WithContext(ctx)
+
+	var subs []*apisubscribe.ServiceHealthSubscription
+	for i := 0; i < 5; i++ {
+		sub, err := apisubscribe.ServiceHealth(conn, "web", q)
+		require.NoError(t, err)
+		_, meta, err := sub.Next()
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), meta.LastIndex)
+		subs = append(subs, sub)
+	}
+
+	d.Set(6, nil, nil)
+	for _, sub := range subs {
+		_, meta, err := sub.Next()
+		require.NoError(t, err)
+		require.Equal(t, uint64(6), meta.LastIndex)
+	}
+
+	// The subscriptions share the blocking queries of the cache, which
+	// fetched the first and second states, and is waiting on the third.
+	d.Lock()
+	defer d.Unlock()
+	require.True(t, d.queries <= 3, "queries: %d", d.queries)
+}
@@ -0,0 +1,132 @@
+package subscribe
+
+import (
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// The messages of the subscription API are written by hand rather than
+// generated. They only rely on the protobuf struct tags, so any change must
+// keep the field numbers and types compatible with the existing clients.
+
+// Topic is the kind of state a subscription follows.
+type Topic int32
+
+const (
+	TopicUnknown Topic = 0
+
+	// TopicServiceHealth follows the instances of the service named by the
+	// Key of the request along with their health checks, as returned by the
+	// /v1/health/service/:service endpoint.
+	TopicServiceHealth Topic = 1
+)
+
+// EventType is the kind of an event sent on a subscription.
+type EventType int32
+
+const (
+	EventTypeUnknown EventType = 0
+
+	// EventTypeUpsert adds the entry with the ID of the event, or replaces
+	// it if it's already known.
+	EventTypeUpsert EventType = 1
+
+	// EventTypeDelete removes the entry with the ID of the event.
+	EventTypeDelete EventType = 2
+
+	// EventTypeReset asks the client to throw away all the entries it
+	// knows about. It's followed by the full state of the topic. Every
+	// subscription starts with a reset, and a reset is sent again when
+	// the client fell too far behind to be sent the changes it missed.
+	EventTypeReset EventType = 3
+
+	// EventTypeCommit marks the end of a batch of changes. Once it's been
+	// received, the entries known to the client are the state of the topic
+	// as of the Index of the event.
+	EventTypeCommit EventType = 4
+)
+
+// SubscribeRequest is the request that opens a subscription.
+type SubscribeRequest struct {
+	// Topic is the kind of state to follow.
+	Topic Topic `protobuf:"varint,1,opt,name=topic,proto3"`
+
+	// Key selects what to follow within the topic. For TopicServiceHealth
+	// it's the name of the service.
+	Key string `protobuf:"bytes,2,opt,name=key,proto3"`
+
+	// Datacenter is the datacenter to follow the state of. It defaults to
+	// the datacenter of the agent.
+	Datacenter string `protobuf:"bytes,3,opt,name=datacenter,proto3"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+
+// Event is a single change sent on a subscription.
+type Event struct {
+	Type EventType `protobuf:"varint,1,opt,name=type,proto3"`
+
+	// Index is the Raft index of the state the event belongs to.
+	Index uint64 `protobuf:"varint,2,opt,name=index,proto3"`
+
+	// ID identifies the entry that's changed. For TopicServiceHealth it's
+	// the node name and the service ID, joined by a slash.
+	ID string `protobuf:"bytes,3,opt,name=id,proto3"`
+
+	// Payload is the JSON encoded entry for upserts. For TopicServiceHealth
+	// it has the format of an element of the /v1/health/service/:service
+	// response.
+	Payload []byte `protobuf:"bytes,4,opt,name=payload,proto3"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+// ConsulServer is the server API of the consul.subscribe.Consul service.
+type ConsulServer interface {
+	Subscribe(*SubscribeRequest, SubscribeStream) error
+}
+
+// SubscribeStream is the server side of a subscription.
+type SubscribeStream interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type subscribeStream struct {
+	grpc.ServerStream
+}
+
+func (s *subscribeStream) Send(e *Event) error {
+	return s.ServerStream.SendMsg(e)
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(SubscribeRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(ConsulServer).Subscribe(req, &subscribeStream{stream})
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "consul.subscribe.Consul",
+	HandlerType: (*ConsulServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       subscribeHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "subscribe.go",
+}
+
+// RegisterConsulServer registers the consul.subscribe.Consul service with
+// the given gRPC server.
+func RegisterConsulServer(s *grpc.Server, srv ConsulServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
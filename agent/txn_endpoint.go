@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/types"
@@ -103,17 +104,15 @@ func (s *HTTPServer) convertOps(resp http.ResponseWriter, req *http.Request) (st
 	// associate the error with a given operation.
 	var ops api.TxnOps
 	if err := decodeBody(req, &ops, fixupTxnOps); err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(resp, "Failed to parse body: %v", err)
+		s.writeError(resp, req, http.StatusBadRequest, api.ErrCodeBadRequest, fmt.Sprintf("Failed to parse body: %v", err), nil)
 		return nil, 0, false
 	}
 
 	// Enforce a reasonable upper limit on the number of operations in a
 	// transaction in order to curb abuse.
 	if size := len(ops); size > maxTxnOps {
-		resp.WriteHeader(http.StatusRequestEntityTooLarge)
-		fmt.Fprintf(resp, "Transaction contains too many operations (%d > %d)",
-			size, maxTxnOps)
+		s.writeError(resp, req, http.StatusRequestEntityTooLarge, api.ErrCodeTooLarge, fmt.Sprintf("Transaction contains too many operations (%d > %d)",
+			size, maxTxnOps), nil)
 
 		return nil, 0, false
 	}
@@ -129,8 +128,7 @@ func (s *HTTPServer) convertOps(resp http.ResponseWriter, req *http.Request) (st
 		case in.KV != nil:
 			size := len(in.KV.Value)
 			if size > maxKVSize {
-				resp.WriteHeader(http.StatusRequestEntityTooLarge)
-				fmt.Fprintf(resp, "Value for key %q is too large (%d > %d bytes)", in.KV.Key, size, maxKVSize)
+				s.writeError(resp, req, http.StatusRequestEntityTooLarge, api.ErrCodeTooLarge, fmt.Sprintf("Value for key %q is too large (%d > %d bytes)", in.KV.Key, size, maxKVSize), nil)
 				return nil, 0, false
 			}
 			netKVSize += size
@@ -144,10 +142,11 @@ func (s *HTTPServer) convertOps(resp http.ResponseWriter, req *http.Request) (st
 				KV: &structs.TxnKVOp{
 					Verb: verb,
 					DirEnt: structs.DirEntry{
-						Key:     in.KV.Key,
-						Value:   in.KV.Value,
-						Flags:   in.KV.Flags,
-						Session: in.KV.Session,
+						Key:      in.KV.Key,
+						Value:    in.KV.Value,
+						Flags:    in.KV.Flags,
+						Session:  in.KV.Session,
+						Checksum: in.KV.Checksum,
 						RaftIndex: structs.RaftIndex{
 							ModifyIndex: in.KV.Index,
 						},
@@ -177,10 +176,13 @@ func (s *HTTPServer) convertOps(resp http.ResponseWriter, req *http.Request) (st
 						Datacenter:      node.Datacenter,
 						TaggedAddresses: node.TaggedAddresses,
 						Meta:            node.Meta,
+						SyncOwner:       node.SyncOwner,
 						RaftIndex: structs.RaftIndex{
 							ModifyIndex: node.ModifyIndex,
 						},
 					},
+					ForceSyncOwner: in.Node.ForceSyncOwner,
+					CheckSyncOwner: true,
 				},
 			}
 			opsRPC = append(opsRPC, out)
@@ -211,6 +213,9 @@ func (s *HTTPServer) convertOps(resp http.ResponseWriter, req *http.Request) (st
 							ModifyIndex: svc.ModifyIndex,
 						},
 					},
+					SyncOwner:      in.Service.SyncOwner,
+					ForceSyncOwner: in.Service.ForceSyncOwner,
+					CheckSyncOwner: true,
 				},
 			}
 			opsRPC = append(opsRPC, out)
@@ -274,9 +279,8 @@ func (s *HTTPServer) convertOps(resp http.ResponseWriter, req *http.Request) (st
 
 	// Enforce an overall size limit to help prevent abuse.
 	if netKVSize > maxKVSize {
-		resp.WriteHeader(http.StatusRequestEntityTooLarge)
-		fmt.Fprintf(resp, "Cumulative size of key data is too large (%d > %d bytes)",
-			netKVSize, maxKVSize)
+		s.writeError(resp, req, http.StatusRequestEntityTooLarge, api.ErrCodeTooLarge, fmt.Sprintf("Cumulative size of key data is too large (%d > %d bytes)",
+			netKVSize, maxKVSize), nil)
 
 		return nil, 0, false
 	}
@@ -315,6 +319,7 @@ func (s *HTTPServer) Txn(resp http.ResponseWriter, req *http.Request) (interface
 		setLastContact(resp, reply.LastContact)
 		setKnownLeader(resp, reply.KnownLeader)
 
+		setTxnErrorCodes(reply.Errors)
 		ret, conflict = reply, len(reply.Errors) > 0
 	} else {
 		args := structs.TxnRequest{Ops: ops}
@@ -325,6 +330,7 @@ func (s *HTTPServer) Txn(resp http.ResponseWriter, req *http.Request) (interface
 		if err := s.agent.RPC("Txn.Apply", &args, &reply); err != nil {
 			return nil, err
 		}
+		setTxnErrorCodes(reply.Errors)
 		ret, conflict = reply, len(reply.Errors) > 0
 	}
 
@@ -347,3 +353,20 @@ func (s *HTTPServer) Txn(resp http.ResponseWriter, req *http.Request) (interface
 	// Otherwise, return the results of the successful transaction.
 	return ret, nil
 }
+
+// setTxnErrorCodes sets the api error code of the errors of a transaction
+// that was rolled back.
+func setTxnErrorCodes(errors structs.TxnErrors) {
+	for _, e := range errors {
+		switch {
+		case structs.IsErrCASFailed(e):
+			e.Code = api.ErrCodeCASFailed
+		case structs.IsErrKVChecksumMismatch(e):
+			e.Code = api.ErrCodeChecksumMismatch
+		case structs.IsErrSyncOwnerConflict(e):
+			e.Code = api.ErrCodeSyncOwnerConflict
+		case acl.IsErrPermissionDenied(e):
+			e.Code = api.ErrCodeACLDenied
+		}
+	}
+}
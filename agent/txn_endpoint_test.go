@@ -2,6 +2,7 @@ package agent
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +14,7 @@ import (
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/pascaldekloe/goe/verify"
+	"github.com/stretchr/testify/require"
 
 	"github.com/hashicorp/consul/agent/structs"
 )
@@ -150,7 +152,8 @@ func TestTxnEndpoint_KV_Actions(t *testing.T) {
              "Key": "key",
              "Value": "aGVsbG8gd29ybGQ=",
              "Flags": 23,
-             "Session": %q
+             "Session": %q,
+             "Checksum": "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
          }
      },
      {
@@ -200,6 +203,7 @@ func TestTxnEndpoint_KV_Actions(t *testing.T) {
 							Value:     []byte("hello world"),
 							Flags:     23,
 							Session:   id,
+							Checksum:  "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
 							LockIndex: 1,
 							RaftIndex: structs.RaftIndex{
 								CreateIndex: index,
@@ -265,6 +269,7 @@ func TestTxnEndpoint_KV_Actions(t *testing.T) {
 								Value:     []byte("hello world"),
 								Flags:     23,
 								Session:   id,
+								Checksum:  "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
 								LockIndex: 1,
 								RaftIndex: structs.RaftIndex{
 									CreateIndex: index,
@@ -278,6 +283,7 @@ func TestTxnEndpoint_KV_Actions(t *testing.T) {
 								Value:     []byte("hello world"),
 								Flags:     23,
 								Session:   id,
+								Checksum:  "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
 								LockIndex: 1,
 								RaftIndex: structs.RaftIndex{
 									CreateIndex: index,
@@ -568,3 +574,94 @@ func TestTxnEndpoint_UpdateCheck(t *testing.T) {
 	}
 	verify.Values(t, "", txnResp, expected)
 }
+
+func TestTxnEndpoint_SyncOwner(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	args := &structs.RegisterRequest{
+		Datacenter:     "dc1",
+		Node:           "external",
+		Address:        "1.2.3.4",
+		SyncOwner:      "sync-a",
+		CheckSyncOwner: true,
+		Service: &structs.NodeService{
+			ID:      "web",
+			Service: "web",
+		},
+	}
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", args, &out))
+
+	txn := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/v1/txn", strings.NewReader(body))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.Txn(resp, req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Writes without the owner neither clear it nor get around it.
+	for _, body := range []string{
+		`[{"Node": {"Verb": "set", "Node": {"Node": "external", "Address": "1.2.3.5"}}}]`,
+		`[{"Node": {"Verb": "delete", "Node": {"Node": "external"}}}]`,
+		`[{"Service": {"Verb": "set", "Node": "external", "Service": {"ID": "api", "Service": "api"}}}]`,
+		`[{"Service": {"Verb": "delete", "Node": "external", "Service": {"ID": "web", "Service": "web"}, "SyncOwner": "sync-b"}}]`,
+	} {
+		resp := txn(body)
+		require.Equal(t, http.StatusConflict, resp.Code, body)
+		require.Contains(t, resp.Body.String(), structs.ErrSyncOwnerConflict.Error(), body)
+
+		var txnResp structs.TxnResponse
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &txnResp))
+		require.Len(t, txnResp.Errors, 1)
+		require.Equal(t, api.ErrCodeSyncOwnerConflict, txnResp.Errors[0].Code)
+	}
+
+	var services structs.IndexedNodeServices
+	req := structs.NodeSpecificRequest{Datacenter: "dc1", Node: "external"}
+	require.NoError(t, a.RPC("Catalog.NodeServices", &req, &services))
+	require.Equal(t, "sync-a", services.NodeServices.Node.SyncOwner)
+	require.Equal(t, "1.2.3.4", services.NodeServices.Node.Address)
+	require.Len(t, services.NodeServices.Services, 1)
+
+	// The owner can write to the node, and keeps it.
+	resp := txn(`[{"Node": {"Verb": "set", "Node": {"Node": "external", "Address": "1.2.3.5", "SyncOwner": "sync-a"}}},
+		{"Service": {"Verb": "set", "Node": "external", "Service": {"ID": "api", "Service": "api"}, "SyncOwner": "sync-a"}}]`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, a.RPC("Catalog.NodeServices", &req, &services))
+	require.Equal(t, "sync-a", services.NodeServices.Node.SyncOwner)
+	require.Equal(t, "1.2.3.5", services.NodeServices.Node.Address)
+	require.Len(t, services.NodeServices.Services, 2)
+
+	// Forcing the owner takes the node over.
+	resp = txn(`[{"Node": {"Verb": "set", "Node": {"Node": "external", "Address": "1.2.3.5", "SyncOwner": "sync-b"}, "ForceSyncOwner": true}}]`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, a.RPC("Catalog.NodeServices", &req, &services))
+	require.Equal(t, "sync-b", services.NodeServices.Node.SyncOwner)
+}
+
+func TestTxnEndpoint_CASFailed(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t, t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	buf := bytes.NewBufferString(`[
+		{"KV": {"Verb": "set", "Key": "key", "Value": "aGVsbG8gd29ybGQ="}},
+		{"KV": {"Verb": "cas", "Key": "key", "Value": "aGVsbG8gd29ybGQ=", "Index": 1}}
+	]`)
+	req, _ := http.NewRequest("PUT", "/v1/txn", buf)
+	resp := httptest.NewRecorder()
+	_, err := a.srv.Txn(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.Code)
+
+	var txnResp structs.TxnResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &txnResp))
+	require.Len(t, txnResp.Errors, 1)
+	require.Equal(t, 1, txnResp.Errors[0].OpIndex)
+	require.Equal(t, api.ErrCodeCASFailed, txnResp.Errors[0].Code)
+}
@@ -18,6 +18,20 @@ const (
 type ACLTokenPolicyLink struct {
 	ID   string
 	Name string
+
+	// ExpirationTTL makes the link expire that long after the token is
+	// written. It's only used when creating or updating a token.
+	ExpirationTTL time.Duration `json:",omitempty"`
+
+	// ExpirationTime is when the link expires, if it does. An expired link
+	// no longer grants the policy.
+	ExpirationTime *time.Time `json:",omitempty"`
+}
+
+// ACLTokenInlinePolicy is a set of rules that only applies to the token it is
+// defined on.
+type ACLTokenInlinePolicy struct {
+	Rules string
 }
 
 // ACLToken represents an ACL Token
@@ -32,6 +46,10 @@ type ACLToken struct {
 	CreateTime  time.Time `json:",omitempty"`
 	Hash        []byte    `json:",omitempty"`
 
+	// InlinePolicies are rules stored directly on the token. They are
+	// deleted along with the token.
+	InlinePolicies []*ACLTokenInlinePolicy `json:",omitempty"`
+
 	// DEPRECATED (ACL-Legacy-Compat)
 	// Rules will only be present for legacy tokens returned via the new APIs
 	Rules string `json:",omitempty"`
@@ -94,6 +112,38 @@ type ACLPolicyListEntry struct {
 	ModifyIndex uint64
 }
 
+// ACLSimulateCheck is an access to check with Simulate, such as write
+// access to the "web" service.
+type ACLSimulateCheck struct {
+	Resource string
+	Segment  string
+	Access   string
+}
+
+// ACLSimulateRequest gives either the accessor ID of a token or inline rules
+// to check the access of.
+type ACLSimulateRequest struct {
+	AccessorID string `json:",omitempty"`
+	Rules      string `json:",omitempty"`
+	Checks     []ACLSimulateCheck
+}
+
+// ACLSimulateResult is the outcome of a check along with the policy and rule
+// that decided it. Rule is empty when the default policy decided, in which
+// case DefaultPolicy is set, and DefaultPolicyOverride tells whether it's the
+// override of the local datacenter. Error is set when the check couldn't be
+// done, for example for resources only available in Consul Enterprise.
+type ACLSimulateResult struct {
+	ACLSimulateCheck
+	Allowed               bool
+	PolicyID              string
+	PolicyName            string
+	Rule                  string
+	DefaultPolicy         string
+	DefaultPolicyOverride bool
+	Error                 string
+}
+
 // ACL can be used to query the ACL endpoints
 type ACL struct {
 	c *Client
@@ -412,8 +462,31 @@ func (a *ACL) TokenReadSelf(q *QueryOptions) (*ACLToken, *QueryMeta, error) {
 // TokenList lists all tokens. The listing does not contain any SecretIDs as those
 // may only be retrieved by a call to TokenRead.
 func (a *ACL) TokenList(q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, error) {
+	return a.tokenList("", false, q)
+}
+
+// TokenListByPolicy lists only the tokens linked to the given policy, which
+// can be given by ID or by name.
+func (a *ACL) TokenListByPolicy(policy string, q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, error) {
+	return a.tokenList(policy, false, q)
+}
+
+// TokenListDangling lists only the tokens whose linked policies were all
+// deleted and that have no rules of their own. These tokens still
+// authenticate, but they only get the default policy.
+func (a *ACL) TokenListDangling(q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, error) {
+	return a.tokenList("", true, q)
+}
+
+func (a *ACL) tokenList(policy string, dangling bool, q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, error) {
 	r := a.c.newRequest("GET", "/v1/acl/tokens")
 	r.setQueryOptions(q)
+	if policy != "" {
+		r.params.Set("policy", policy)
+	}
+	if dangling {
+		r.params.Set("dangling", "")
+	}
 	rtt, resp, err := requireOK(a.c.doRequest(r))
 	if err != nil {
 		return nil, nil, err
@@ -586,3 +659,26 @@ func (a *ACL) RulesTranslateToken(tokenID string) (string, error) {
 
 	return string(ruleBytes), nil
 }
+
+// Simulate checks the access a token, or inline rules, would have without
+// doing anything, and returns which rule decided each check.
+func (a *ACL) Simulate(args *ACLSimulateRequest, q *QueryOptions) ([]*ACLSimulateResult, *QueryMeta, error) {
+	r := a.c.newRequest("POST", "/v1/acl/simulate")
+	r.setQueryOptions(q)
+	r.obj = args
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out []*ACLSimulateResult
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return out, qm, nil
+}
@@ -595,6 +595,25 @@ func TestAPI_ACLToken_List(t *testing.T) {
 	token5, ok := tokenMap[root.AccessorID]
 	require.True(t, ok)
 	require.NotNil(t, token5)
+
+	// filter by the name of a policy
+	tokens, _, err = acl.TokenListByPolicy(policies[1].Name, nil)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, created2.AccessorID, tokens[0].AccessorID)
+
+	// only list the tokens whose policies were all deleted
+	tokens, _, err = acl.TokenListDangling(nil)
+	require.NoError(t, err)
+	require.Len(t, tokens, 0)
+
+	_, err = acl.PolicyDelete(policies[2].ID, nil)
+	require.NoError(t, err)
+
+	tokens, _, err = acl.TokenListDangling(nil)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, created3.AccessorID, tokens[0].AccessorID)
 }
 
 func TestAPI_ACLToken_Clone(t *testing.T) {
@@ -725,3 +744,41 @@ query_prefix "" {
 	require.NoError(t, err)
 	require.Equal(t, expected, rules)
 }
+
+func TestAPI_ACLSimulate(t *testing.T) {
+	t.Parallel()
+	c, s := makeACLClient(t)
+	defer s.Stop()
+
+	acl := c.ACL()
+
+	policy, _, err := acl.PolicyCreate(&ACLPolicy{
+		Name:  "web",
+		Rules: `service "web" { policy = "write" }`,
+	}, nil)
+	require.NoError(t, err)
+
+	token, _, err := acl.TokenCreate(&ACLToken{
+		Policies: []*ACLTokenPolicyLink{&ACLTokenPolicyLink{ID: policy.ID}},
+	}, nil)
+	require.NoError(t, err)
+
+	results, qm, err := acl.Simulate(&ACLSimulateRequest{
+		AccessorID: token.AccessorID,
+		Checks: []ACLSimulateCheck{
+			{Resource: "service", Segment: "web", Access: "write"},
+			{Resource: "key", Segment: "config/app", Access: "read"},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, qm)
+	require.Len(t, results, 2)
+
+	require.True(t, results[0].Allowed)
+	require.Equal(t, policy.ID, results[0].PolicyID)
+	require.Equal(t, "web", results[0].PolicyName)
+	require.Equal(t, `service "web" { policy = "write" }`, results[0].Rule)
+
+	require.False(t, results[1].Allowed)
+	require.Empty(t, results[1].Rule)
+}
@@ -2,11 +2,10 @@ package api
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // ServiceKind is the kind of service being registered.
@@ -65,6 +64,10 @@ type AgentCheck struct {
 	ServiceID   string
 	ServiceName string
 	Definition  HealthCheckDefinition
+
+	// OutputDigest is only set when the output was summarized, see
+	// QueryOptions.SummarizeOutput.
+	OutputDigest *CheckOutputDigest `json:",omitempty"`
 }
 
 // AgentWeights represent optional weights for a service
@@ -212,6 +215,11 @@ type AgentServiceCheck struct {
 	// then its associated service (and all of its associated checks) will
 	// automatically be deregistered.
 	DeregisterCriticalServiceAfter string `json:",omitempty"`
+
+	// DependsOnCheck is the ID of another check on the same agent. While
+	// that check is critical, this one stops probing and is reported as
+	// critical, with its output prefixed by "blocked by <check>".
+	DependsOnCheck string `json:",omitempty"`
 }
 type AgentServiceChecks []*AgentServiceCheck
 
@@ -220,6 +228,20 @@ type AgentToken struct {
 	Token string
 }
 
+// AgentDNSSimulation is the result of a DNS query resolved by the agent
+// in-process, along with the decisions made while resolving it.
+type AgentDNSSimulation struct {
+	Name      string
+	Type      string
+	Network   string
+	Rcode     string
+	Truncated bool
+	Answer    []string
+	Ns        []string
+	Extra     []string
+	Trace     []string
+}
+
 // Metrics info is used to store different types of metric values from the agent.
 type MetricsInfo struct {
 	Timestamp string
@@ -343,6 +365,30 @@ func (a *Agent) Host() (map[string]interface{}, error) {
 	return out, nil
 }
 
+// DNSSimulate resolves a DNS query for the name and record type the same way
+// the DNS interface of the agent does, without making a DNS query, and
+// returns the response with the trace of the resolution. The type defaults to
+// A if it's empty. Requires an agent:read ACL token.
+func (a *Agent) DNSSimulate(name, qtype string, q *QueryOptions) (*AgentDNSSimulation, error) {
+	r := a.c.newRequest("GET", "/v1/agent/dns/simulate")
+	r.setQueryOptions(q)
+	r.params.Set("name", name)
+	if qtype != "" {
+		r.params.Set("type", qtype)
+	}
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out AgentDNSSimulation
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // Metrics is used to query the agent we are speaking to for
 // its current internal metric data
 func (a *Agent) Metrics() (*MetricsInfo, error) {
@@ -393,8 +439,14 @@ func (a *Agent) Checks() (map[string]*AgentCheck, error) {
 // ChecksWithFilter returns a subset of the locally registered checks that match
 // the given filter expression
 func (a *Agent) ChecksWithFilter(filter string) (map[string]*AgentCheck, error) {
+	return a.ChecksOpts(&QueryOptions{Filter: filter})
+}
+
+// ChecksOpts returns the locally registered checks using query options, such
+// as a filter or SummarizeOutput to leave out most of the check outputs.
+func (a *Agent) ChecksOpts(q *QueryOptions) (map[string]*AgentCheck, error) {
 	r := a.c.newRequest("GET", "/v1/agent/checks")
-	r.filterQuery(filter)
+	r.setQueryOptions(q)
 	_, resp, err := requireOK(a.c.doRequest(r))
 	if err != nil {
 		return nil, err
@@ -432,6 +484,32 @@ func (a *Agent) ServicesWithFilter(filter string) (map[string]*AgentService, err
 	return out, nil
 }
 
+// ServicesExport returns the locally registered services along with their
+// checks in the format they are registered with, so they can be registered
+// again with ServiceRegister. Sidecar services are folded back into the
+// Connect.SidecarService of the service they were registered with, unless
+// includeSidecars is true in which case they're returned as services of
+// their own. The services from the agent's configuration files and the node
+// checks are left out.
+func (a *Agent) ServicesExport(includeSidecars bool) ([]*AgentServiceRegistration, error) {
+	r := a.c.newRequest("GET", "/v1/agent/services/export")
+	if includeSidecars {
+		r.params.Set("include-sidecars", "")
+	}
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out []*AgentServiceRegistration
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
 // AgentHealthServiceByID returns for a given serviceID: the aggregated health status, the service definition or an error if any
 // - If the service is not found, will return status (critical, nil, nil)
 // - If the service is found, will return (critical|passing|warning), AgentServiceChecksInfo, nil)
@@ -894,6 +972,36 @@ func (a *Agent) DisableNodeMaintenance() error {
 	return nil
 }
 
+// EnableNodeDrain drains the node of the agent we are connected to. Its
+// service instances stay in the catalog but are excluded from the DNS answers
+// and the health results. The drain ends after the given duration, or lasts
+// until DisableNodeDrain is called if the duration is zero.
+func (a *Agent) EnableNodeDrain(duration time.Duration) error {
+	r := a.c.newRequest("PUT", "/v1/agent/drain")
+	r.params.Set("enable", "true")
+	if duration > 0 {
+		r.params.Set("duration", duration.String())
+	}
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DisableNodeDrain undrains the node of the agent we are connected to.
+func (a *Agent) DisableNodeDrain() error {
+	r := a.c.newRequest("PUT", "/v1/agent/drain")
+	r.params.Set("enable", "false")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
 // Monitor returns a channel which will receive streaming logs from the agent
 // Providing a non-nil stopCh can be used to close the connection and stop the
 // log stream. An empty string will be sent down the given channel when there's
@@ -1026,9 +1134,7 @@ func (a *Agent) updateTokenOnce(target, token string, q *WriteOptions) (*WriteMe
 	wm := &WriteMeta{RequestTime: rtt}
 
 	if resp.StatusCode != 200 {
-		var buf bytes.Buffer
-		io.Copy(&buf, resp.Body)
-		return wm, resp.StatusCode, fmt.Errorf("Unexpected response code: %d (%s)", resp.StatusCode, buf.Bytes())
+		return wm, resp.StatusCode, generateUnexpectedResponseCodeError(resp)
 	}
 
 	return wm, resp.StatusCode, nil
@@ -79,6 +79,26 @@ func TestAPI_AgentHost(t *testing.T) {
 	})
 }
 
+func TestAPI_AgentDNSSimulate(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+	s.WaitForSerfCheck(t)
+
+	sim, err := agent.DNSSimulate("consul.service.consul", "srv", nil)
+	require.NoError(t, err)
+	require.Equal(t, "consul.service.consul.", sim.Name)
+	require.Equal(t, "SRV", sim.Type)
+	require.Equal(t, "NOERROR", sim.Rcode)
+	require.Len(t, sim.Answer, 1)
+	require.NotEmpty(t, sim.Trace)
+
+	_, err = agent.DNSSimulate("example.com", "", nil)
+	require.Error(t, err)
+}
+
 func TestAPI_AgentReload(t *testing.T) {
 	t.Parallel()
 
@@ -1309,6 +1329,55 @@ func TestAPI_NodeMaintenance(t *testing.T) {
 	}
 }
 
+func TestAPI_NodeDrain(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+	s.WaitForSerfCheck(t)
+
+	reg := &AgentServiceRegistration{
+		Name: "web",
+		Port: 8000,
+	}
+	require.NoError(t, agent.ServiceRegister(reg))
+
+	// Drain the node
+	require.NoError(t, agent.EnableNodeDrain(time.Minute))
+
+	// The instance is excluded from the health results, unless asked
+	// otherwise, and is annotated in the catalog.
+	retry.Run(t, func(r *retry.R) {
+		entries, _, err := c.Health().Service("web", "", false, nil)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if len(entries) != 0 {
+			r.Fatalf("bad: %v", entries)
+		}
+	})
+	entries, _, err := c.Health().Service("web", "", false, &QueryOptions{IncludeDrained: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	services, _, err := c.Catalog().Service("web", "", nil)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.True(t, services[0].Drained)
+
+	// Undrain the node
+	require.NoError(t, agent.DisableNodeDrain())
+	retry.Run(t, func(r *retry.R) {
+		entries, _, err := c.Health().Service("web", "", false, nil)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if len(entries) != 1 {
+			r.Fatalf("bad: %v", entries)
+		}
+	})
+}
+
 func TestAPI_AgentUpdateToken(t *testing.T) {
 	t.Parallel()
 	c, s := makeACLClient(t)
@@ -38,6 +38,10 @@ const (
 	// whether or not to use HTTPS.
 	HTTPSSLEnvName = "CONSUL_HTTP_SSL"
 
+	// HTTPPathPrefixEnvName defines an environment variable name which sets
+	// the path prefix the agent's HTTP API is served under.
+	HTTPPathPrefixEnvName = "CONSUL_HTTP_PATH_PREFIX"
+
 	// HTTPCAFile defines an environment variable name which sets the
 	// CA file to use for talking to Consul over TLS.
 	HTTPCAFile = "CONSUL_CACERT"
@@ -150,6 +154,16 @@ type QueryOptions struct {
 	// Filter requests filtering data prior to it being returned. The string
 	// is a go-bexpr compatible expression.
 	Filter string
+
+	// SummarizeOutput replaces the output of the returned checks with its
+	// first bytes and sets their OutputDigest. This currently affects the
+	// health and agent check endpoints.
+	SummarizeOutput bool
+
+	// IncludeDrained keeps the service instances on drained nodes in the
+	// results, they're excluded by default. This currently affects the health
+	// service endpoints.
+	IncludeDrained bool
 }
 
 func (o *QueryOptions) Context() context.Context {
@@ -234,6 +248,10 @@ type QueryMeta struct {
 	// CacheAge is set if request was ?cached and indicates how stale the cached
 	// response is.
 	CacheAge time.Duration
+
+	// Degraded is true if the agent could not reach any server and served
+	// the last known result it persisted instead.
+	Degraded bool
 }
 
 // WriteMeta is used to return meta data about a write
@@ -259,6 +277,11 @@ type Config struct {
 	// Scheme is the URI scheme for the Consul server
 	Scheme string
 
+	// PathPrefix is prepended to the path of every request. It is needed when
+	// the agent is configured with http_config.path_prefix or sits behind a
+	// proxy that routes a sub-path to it.
+	PathPrefix string
+
 	// Datacenter to use. If not provided, the default agent datacenter is used.
 	Datacenter string
 
@@ -280,6 +303,15 @@ type Config struct {
 	// which overrides the agent's default token.
 	Token string
 
+	// VerifyKVChecksums makes the KV and Txn APIs send a checksum with every
+	// value they write, which the servers verify before committing it, and
+	// verify the values they read against the checksum stored with them.
+	VerifyKVChecksums bool
+
+	// TxnParallelism is the maximum number of transactions KV.GetMany runs
+	// at once. It defaults to 4.
+	TxnParallelism int
+
 	TLSConfig TLSConfig
 }
 
@@ -347,6 +379,10 @@ func defaultConfig(transportFn func() *http.Transport) *Config {
 		config.Token = token
 	}
 
+	if prefix := os.Getenv(HTTPPathPrefixEnvName); prefix != "" {
+		config.PathPrefix = prefix
+	}
+
 	if auth := os.Getenv(HTTPAuthEnvName); auth != "" {
 		var username, password string
 		if strings.Contains(auth, ":") {
@@ -455,7 +491,8 @@ func (c *Config) GenerateEnv() []string {
 		fmt.Sprintf("%s=%s", HTTPClientCert, c.TLSConfig.CertFile),
 		fmt.Sprintf("%s=%s", HTTPClientKey, c.TLSConfig.KeyFile),
 		fmt.Sprintf("%s=%s", HTTPTLSServerName, c.TLSConfig.Address),
-		fmt.Sprintf("%s=%t", HTTPSSLVerifyEnvName, !c.TLSConfig.InsecureSkipVerify))
+		fmt.Sprintf("%s=%t", HTTPSSLVerifyEnvName, !c.TLSConfig.InsecureSkipVerify),
+		fmt.Sprintf("%s=%s", HTTPPathPrefixEnvName, c.PathPrefix))
 
 	if c.HttpAuth != nil {
 		env = append(env, fmt.Sprintf("%s=%s:%s", HTTPAuthEnvName, c.HttpAuth.Username, c.HttpAuth.Password))
@@ -632,6 +669,12 @@ func (r *request) setQueryOptions(q *QueryOptions) {
 	if q.Connect {
 		r.params.Set("connect", "true")
 	}
+	if q.SummarizeOutput {
+		r.params.Set("summarize-output", "true")
+	}
+	if q.IncludeDrained {
+		r.params.Set("exclude-drained", "false")
+	}
 	if q.UseCache && !q.RequireConsistent {
 		r.params.Set("cached", "")
 
@@ -737,6 +780,19 @@ func (r *request) toHTTP() (*http.Request, error) {
 	return req, nil
 }
 
+// pathWithPrefix joins the configured path prefix and an API path. A
+// prefix without a leading slash gets one, and trailing slashes are dropped.
+func pathWithPrefix(prefix, path string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return path
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix + path
+}
+
 // newRequest is used to create a new request
 func (c *Client) newRequest(method, path string) *request {
 	r := &request{
@@ -745,11 +801,13 @@ func (c *Client) newRequest(method, path string) *request {
 		url: &url.URL{
 			Scheme: c.config.Scheme,
 			Host:   c.config.Address,
-			Path:   path,
+			Path:   pathWithPrefix(c.config.PathPrefix, path),
 		},
 		params: make(map[string][]string),
 		header: make(http.Header),
 	}
+	// Ask for structured errors, agents that don't support them ignore this.
+	r.header.Set("Accept", "application/json")
 	if c.config.Datacenter != "" {
 		r.params.Set("dc", c.config.Datacenter)
 	}
@@ -857,6 +915,9 @@ func parseQueryMeta(resp *http.Response, q *QueryMeta) error {
 		q.AddressTranslationEnabled = false
 	}
 
+	// Parse X-Consul-Degraded
+	q.Degraded = header.Get("X-Consul-Degraded") == "true"
+
 	// Parse Cache info
 	if cacheStr := header.Get("X-Cache"); cacheStr != "" {
 		q.CacheHit = strings.EqualFold(cacheStr, "HIT")
@@ -897,10 +958,7 @@ func requireOK(d time.Duration, resp *http.Response, e error) (time.Duration, *h
 		return d, nil, e
 	}
 	if resp.StatusCode != 200 {
-		var buf bytes.Buffer
-		io.Copy(&buf, resp.Body)
-		resp.Body.Close()
-		return d, nil, fmt.Errorf("Unexpected response code: %d (%s)", resp.StatusCode, buf.Bytes())
+		return d, nil, generateUnexpectedResponseCodeError(resp)
 	}
 	return d, resp, nil
 }
@@ -411,6 +411,8 @@ func TestAPI_DefaultConfig_env(t *testing.T) {
 	defer os.Setenv(HTTPTLSServerName, "")
 	os.Setenv(HTTPSSLVerifyEnvName, "0")
 	defer os.Setenv(HTTPSSLVerifyEnvName, "")
+	os.Setenv(HTTPPathPrefixEnvName, "/consul")
+	defer os.Setenv(HTTPPathPrefixEnvName, "")
 
 	for i, config := range []*Config{DefaultConfig(), DefaultNonPooledConfig()} {
 		if config.Address != addr {
@@ -431,6 +433,9 @@ func TestAPI_DefaultConfig_env(t *testing.T) {
 		if config.Scheme != "https" {
 			t.Errorf("expected %q to be %q", config.Scheme, "https")
 		}
+		if config.PathPrefix != "/consul" {
+			t.Errorf("expected %q to be %q", config.PathPrefix, "/consul")
+		}
 		if config.TLSConfig.CAFile != "ca.pem" {
 			t.Errorf("expected %q to be %q", config.TLSConfig.CAFile, "ca.pem")
 		}
@@ -676,6 +681,23 @@ func TestAPI_ClientTLSOptions(t *testing.T) {
 	})
 }
 
+func TestAPI_PathPrefix(t *testing.T) {
+	t.Parallel()
+
+	for prefix, expected := range map[string]string{
+		"":         "/v1/kv/foo",
+		"/consul":  "/consul/v1/kv/foo",
+		"/consul/": "/consul/v1/kv/foo",
+		"consul":   "/consul/v1/kv/foo",
+	} {
+		c, err := NewClient(&Config{Address: "127.0.0.1:8500", PathPrefix: prefix})
+		require.NoError(t, err)
+
+		r := c.newRequest("GET", "/v1/kv/foo")
+		require.Equal(t, expected, r.url.Path, "prefix %q", prefix)
+	}
+}
+
 func TestAPI_SetQueryOptions(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -898,6 +920,7 @@ func TestAPI_GenerateEnv(t *testing.T) {
 		"CONSUL_CLIENT_KEY=",
 		"CONSUL_TLS_SERVER_NAME=",
 		"CONSUL_HTTP_SSL_VERIFY=false",
+		"CONSUL_HTTP_PATH_PREFIX=",
 		"CONSUL_HTTP_AUTH=",
 	}
 
@@ -908,9 +931,10 @@ func TestAPI_GenerateEnvHTTPS(t *testing.T) {
 	t.Parallel()
 
 	c := &Config{
-		Address: "127.0.0.1:8500",
-		Token:   "test",
-		Scheme:  "https",
+		Address:    "127.0.0.1:8500",
+		Token:      "test",
+		Scheme:     "https",
+		PathPrefix: "/consul",
 		TLSConfig: TLSConfig{
 			CAFile:             "/var/consul/ca.crt",
 			CAPath:             "/var/consul/ca.dir",
@@ -935,6 +959,7 @@ func TestAPI_GenerateEnvHTTPS(t *testing.T) {
 		"CONSUL_CLIENT_KEY=/var/consul/ssl/server.key",
 		"CONSUL_TLS_SERVER_NAME=127.0.0.1:8500",
 		"CONSUL_HTTP_SSL_VERIFY=true",
+		"CONSUL_HTTP_PATH_PREFIX=/consul",
 		"CONSUL_HTTP_AUTH=user:password",
 	}
 
@@ -12,6 +12,7 @@ type Node struct {
 	Datacenter      string
 	TaggedAddresses map[string]string
 	Meta            map[string]string
+	SyncOwner       string
 	CreateIndex     uint64
 	ModifyIndex     uint64
 }
@@ -37,6 +38,10 @@ type CatalogService struct {
 	CreateIndex  uint64
 	Checks       HealthChecks
 	ModifyIndex  uint64
+
+	// Drained is true if the node is drained, its service instances are
+	// excluded from the DNS answers and the health results.
+	Drained bool
 }
 
 type CatalogNode struct {
@@ -55,6 +60,13 @@ type CatalogRegistration struct {
 	Check           *AgentCheck
 	Checks          HealthChecks
 	SkipNodeUpdate  bool
+
+	// SyncOwner identifies the external sync tool that manages the node.
+	// Once a node has an owner, the registrations and deregistrations for
+	// it must use the same owner unless ForceSyncOwner is set, in which
+	// case the owner is replaced.
+	SyncOwner      string
+	ForceSyncOwner bool `json:"-"`
 }
 
 type CatalogDeregistration struct {
@@ -63,6 +75,31 @@ type CatalogDeregistration struct {
 	Datacenter string
 	ServiceID  string
 	CheckID    string
+
+	// SyncOwner and ForceSyncOwner must match the owner of the node, if
+	// any, the same way as for a CatalogRegistration.
+	SyncOwner      string
+	ForceSyncOwner bool `json:"-"`
+}
+
+// DatacenterInfo describes a datacenter and whether it can currently be
+// reached over the WAN.
+type DatacenterInfo struct {
+	Datacenter    string
+	Servers       int
+	FailedServers int
+	Reachable     bool
+}
+
+// ServiceRouting is the routing override of a service. Percentage of the
+// query sources only get the instances tagged with ForceTag.
+type ServiceRouting struct {
+	Name       string
+	ForceTag   string
+	Percentage int
+
+	CreateIndex uint64
+	ModifyIndex uint64
 }
 
 // Catalog can be used to query the Catalog endpoints
@@ -78,6 +115,9 @@ func (c *Client) Catalog() *Catalog {
 func (c *Catalog) Register(reg *CatalogRegistration, q *WriteOptions) (*WriteMeta, error) {
 	r := c.c.newRequest("PUT", "/v1/catalog/register")
 	r.setWriteOptions(q)
+	if reg.ForceSyncOwner {
+		r.params.Set("force-owner", "")
+	}
 	r.obj = reg
 	rtt, resp, err := requireOK(c.c.doRequest(r))
 	if err != nil {
@@ -94,6 +134,9 @@ func (c *Catalog) Register(reg *CatalogRegistration, q *WriteOptions) (*WriteMet
 func (c *Catalog) Deregister(dereg *CatalogDeregistration, q *WriteOptions) (*WriteMeta, error) {
 	r := c.c.newRequest("PUT", "/v1/catalog/deregister")
 	r.setWriteOptions(q)
+	if dereg.ForceSyncOwner {
+		r.params.Set("force-owner", "")
+	}
 	r.obj = dereg
 	rtt, resp, err := requireOK(c.c.doRequest(r))
 	if err != nil {
@@ -123,6 +166,25 @@ func (c *Catalog) Datacenters() ([]string, error) {
 	return out, nil
 }
 
+// DatacentersDetailed is used to query for all the known datacenters along
+// with their reachability, based on the health of their servers in the WAN
+// pool.
+func (c *Catalog) DatacentersDetailed() ([]*DatacenterInfo, error) {
+	r := c.c.newRequest("GET", "/v1/catalog/datacenters")
+	r.params.Set("detailed", "")
+	_, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out []*DatacenterInfo
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Nodes is used to query all the known nodes
 func (c *Catalog) Nodes(q *QueryOptions) ([]*Node, *QueryMeta, error) {
 	r := c.c.newRequest("GET", "/v1/catalog/nodes")
@@ -242,3 +304,63 @@ func (c *Catalog) Node(node string, q *QueryOptions) (*CatalogNode, *QueryMeta,
 	}
 	return out, qm, nil
 }
+
+// Routing returns the routing override of a service, or nil if it has none.
+func (c *Catalog) Routing(service string, q *QueryOptions) (*ServiceRouting, *QueryMeta, error) {
+	r := c.c.newRequest("GET", "/v1/catalog/routing/"+service)
+	r.setQueryOptions(q)
+	rtt, resp, err := c.c.doRequest(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	if resp.StatusCode == 404 {
+		return nil, qm, nil
+	} else if resp.StatusCode != 200 {
+		return nil, nil, generateUnexpectedResponseCodeError(resp)
+	}
+
+	var out ServiceRouting
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
+
+// SetRouting sets the routing override of a service.
+func (c *Catalog) SetRouting(routing *ServiceRouting, q *WriteOptions) (*WriteMeta, error) {
+	r := c.c.newRequest("PUT", "/v1/catalog/routing/"+routing.Name)
+	r.setWriteOptions(q)
+	r.obj = routing
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	wm := &WriteMeta{}
+	wm.RequestTime = rtt
+
+	return wm, nil
+}
+
+// DeleteRouting deletes the routing override of a service.
+func (c *Catalog) DeleteRouting(service string, q *WriteOptions) (*WriteMeta, error) {
+	r := c.c.newRequest("DELETE", "/v1/catalog/routing/"+service)
+	r.setWriteOptions(q)
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	wm := &WriteMeta{}
+	wm.RequestTime = rtt
+
+	return wm, nil
+}
@@ -27,6 +27,27 @@ func TestAPI_CatalogDatacenters(t *testing.T) {
 	})
 }
 
+func TestAPI_CatalogDatacentersDetailed(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	catalog := c.Catalog()
+	retry.Run(t, func(r *retry.R) {
+		datacenters, err := catalog.DatacentersDetailed()
+		if err != nil {
+			r.Fatal(err)
+		}
+		if len(datacenters) != 1 {
+			r.Fatalf("got %d datacenters want 1", len(datacenters))
+		}
+		want := &DatacenterInfo{Datacenter: "dc1", Servers: 1, Reachable: true}
+		if !reflect.DeepEqual(datacenters[0], want) {
+			r.Fatalf("got %#v want %#v", datacenters[0], want)
+		}
+	})
+}
+
 func TestAPI_CatalogNodes(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -1036,3 +1057,36 @@ func TestAPI_CatalogEnableTagOverride(t *testing.T) {
 		}
 	})
 }
+
+func TestAPI_CatalogRouting(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	catalog := c.Catalog()
+
+	routing, _, err := catalog.Routing("web", nil)
+	require.NoError(t, err)
+	require.Nil(t, routing)
+
+	_, err = catalog.SetRouting(&ServiceRouting{
+		Name:       "web",
+		ForceTag:   "canary",
+		Percentage: 10,
+	}, nil)
+	require.NoError(t, err)
+
+	routing, qm, err := catalog.Routing("web", nil)
+	require.NoError(t, err)
+	require.NotNil(t, routing)
+	require.Equal(t, "canary", routing.ForceTag)
+	require.Equal(t, 10, routing.Percentage)
+	require.Equal(t, qm.LastIndex, routing.ModifyIndex)
+
+	_, err = catalog.DeleteRouting("web", nil)
+	require.NoError(t, err)
+
+	routing, _, err = catalog.Routing("web", nil)
+	require.NoError(t, err)
+	require.Nil(t, routing)
+}
@@ -64,7 +64,7 @@ func (d *Debug) Profile(seconds int) ([]byte, error) {
 
 // Trace returns an execution trace
 func (d *Debug) Trace(seconds int) ([]byte, error) {
-	r := d.c.newRequest("GET", "/debug/pprof/trace")
+	r := d.c.newRequest("GET", "/v1/agent/debug/trace")
 
 	// Capture a trace for the specified number of seconds
 	r.params.Set("seconds", strconv.Itoa(seconds))
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error codes returned by the agent in structured error responses. These are
+// stable and can be relied on instead of matching error messages.
+const (
+	ErrCodeACLDenied         = "acl_denied"
+	ErrCodeACLNotFound       = "acl_not_found"
+	ErrCodeNotFound          = "not_found"
+	ErrCodeBadRequest        = "bad_request"
+	ErrCodeMethodNotAllowed  = "method_not_allowed"
+	ErrCodeEndpointBlocked   = "endpoint_blocked"
+	ErrCodeRateLimited       = "rate_limited"
+	ErrCodeNoLeader          = "no_leader"
+	ErrCodeNoServers         = "no_servers"
+	ErrCodeInternal          = "internal"
+	ErrCodeSyncOwnerConflict = "sync_owner_conflict"
+	ErrCodeChecksumMismatch  = "checksum_mismatch"
+	ErrCodeCASFailed         = "cas_failed"
+	ErrCodeTooLarge          = "too_large"
+	ErrCodeDisabled          = "disabled"
+)
+
+// StatusError is returned when the agent responds with a non-200 status code.
+// Newer agents return structured errors and the fields are filled in from
+// those, for older agents Code is inferred from the plain text message.
+type StatusError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Code is one of the ErrCode constants, or empty if unknown.
+	Code string
+
+	// Message is the human readable error message.
+	Message string
+
+	// Detail holds additional information about some errors, for example
+	// the allowed methods for ErrCodeMethodNotAllowed.
+	Detail map[string]interface{}
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("Unexpected response code: %d (%s)", e.StatusCode, e.Message)
+}
+
+// ErrorCode returns the error code of err if it is a StatusError, or an empty
+// string otherwise.
+func ErrorCode(err error) string {
+	if e, ok := err.(StatusError); ok {
+		return e.Code
+	}
+	return ""
+}
+
+// errorResponse is the body of a structured error response.
+type errorResponse struct {
+	Error *struct {
+		Code    string                 `json:"code"`
+		Message string                 `json:"message"`
+		Detail  map[string]interface{} `json:"detail"`
+	} `json:"error"`
+}
+
+// generateUnexpectedResponseCodeError consumes the body of a non-200 response
+// and turns it into a StatusError.
+func generateUnexpectedResponseCodeError(resp *http.Response) error {
+	var buf bytes.Buffer
+	io.Copy(&buf, resp.Body)
+	resp.Body.Close()
+
+	e := StatusError{StatusCode: resp.StatusCode}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var body errorResponse
+		if err := json.Unmarshal(buf.Bytes(), &body); err == nil && body.Error != nil {
+			e.Code = body.Error.Code
+			e.Message = body.Error.Message
+			e.Detail = body.Error.Detail
+			return e
+		}
+	}
+
+	// Fall back to matching the plain text returned by older agents.
+	e.Message = buf.String()
+	e.Code = inferErrorCode(resp.StatusCode, e.Message)
+	return e
+}
+
+// inferErrorCode maps the plain text errors of agents that don't support
+// structured errors to an error code.
+func inferErrorCode(status int, msg string) string {
+	switch {
+	case strings.Contains(msg, "ACL not found"):
+		return ErrCodeACLNotFound
+	case strings.Contains(msg, "Permission denied"):
+		return ErrCodeACLDenied
+	case strings.Contains(msg, "Endpoint is blocked by agent configuration"):
+		return ErrCodeEndpointBlocked
+	case strings.Contains(msg, "No cluster leader"):
+		return ErrCodeNoLeader
+	case strings.Contains(msg, "No known Consul servers"):
+		return ErrCodeNoServers
+	case strings.Contains(msg, "Node is managed by another sync owner"):
+		return ErrCodeSyncOwnerConflict
+	case strings.Contains(msg, "Checksum mismatch"):
+		return ErrCodeChecksumMismatch
+	case strings.Contains(msg, "index is stale"), strings.Contains(msg, "failed index check"):
+		return ErrCodeCASFailed
+	case strings.Contains(msg, "support disabled"):
+		return ErrCodeDisabled
+	}
+
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodeTooLarge
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusInternalServerError:
+		return ErrCodeInternal
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_GenerateUnexpectedResponseCodeError(t *testing.T) {
+	t.Parallel()
+
+	respond := func(status int, contentType, body string) *http.Response {
+		resp := &http.Response{
+			StatusCode: status,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}
+		if contentType != "" {
+			resp.Header.Set("Content-Type", contentType)
+		}
+		return resp
+	}
+
+	t.Run("structured", func(t *testing.T) {
+		err := generateUnexpectedResponseCodeError(respond(405, "application/json",
+			`{"error":{"code":"method_not_allowed","message":"method PUT not allowed","detail":{"allow":["GET"]}}}`))
+		require.Equal(t, "Unexpected response code: 405 (method PUT not allowed)", err.Error())
+		require.Equal(t, ErrCodeMethodNotAllowed, ErrorCode(err))
+		require.Equal(t, []interface{}{"GET"}, err.(StatusError).Detail["allow"])
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		err := generateUnexpectedResponseCodeError(respond(403, "", "ACL not found"))
+		require.Equal(t, "Unexpected response code: 403 (ACL not found)", err.Error())
+		require.Equal(t, ErrCodeACLNotFound, ErrorCode(err))
+
+		err = generateUnexpectedResponseCodeError(respond(403, "", "Permission denied"))
+		require.Equal(t, ErrCodeACLDenied, ErrorCode(err))
+
+		err = generateUnexpectedResponseCodeError(respond(500, "", "rpc error making call: No cluster leader"))
+		require.Equal(t, ErrCodeNoLeader, ErrorCode(err))
+		require.True(t, IsRetryableError(err))
+
+		err = generateUnexpectedResponseCodeError(respond(429, "", ""))
+		require.Equal(t, ErrCodeRateLimited, ErrorCode(err))
+	})
+
+	t.Run("invalid JSON falls back to text", func(t *testing.T) {
+		err := generateUnexpectedResponseCodeError(respond(500, "application/json", "not json"))
+		require.Equal(t, "Unexpected response code: 500 (not json)", err.Error())
+		require.Equal(t, ErrCodeInternal, ErrorCode(err))
+	})
+}
@@ -37,12 +37,23 @@ type HealthCheck struct {
 	ServiceName string
 	ServiceTags []string
 
+	// OutputDigest is only set when the output was summarized, see
+	// QueryOptions.SummarizeOutput.
+	OutputDigest *CheckOutputDigest `json:",omitempty"`
+
 	Definition HealthCheckDefinition
 
 	CreateIndex uint64
 	ModifyIndex uint64
 }
 
+// CheckOutputDigest describes the full output of a check whose output was
+// summarized. The full output can be fetched with Health().Check().
+type CheckOutputDigest struct {
+	SHA256 string
+	Length int
+}
+
 // HealthCheckDefinition is used to store the details about
 // a health check's execution.
 type HealthCheckDefinition struct {
@@ -214,6 +225,34 @@ func (h *Health) Node(node string, q *QueryOptions) (HealthChecks, *QueryMeta, e
 	return out, qm, nil
 }
 
+// Check is used to return a single check of a node with its full output. It
+// returns nil if the node has no such check.
+func (h *Health) Check(node, checkID string, q *QueryOptions) (*HealthCheck, *QueryMeta, error) {
+	r := h.c.newRequest("GET", "/v1/health/check/"+node+"/"+checkID)
+	r.setQueryOptions(q)
+	rtt, resp, err := h.c.doRequest(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	if resp.StatusCode == 404 {
+		return nil, qm, nil
+	} else if resp.StatusCode != 200 {
+		return nil, nil, generateUnexpectedResponseCodeError(resp)
+	}
+
+	var out HealthCheck
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
+
 // Checks is used to return the checks associated with a service
 func (h *Health) Checks(service string, q *QueryOptions) (HealthChecks, *QueryMeta, error) {
 	r := h.c.newRequest("GET", "/v1/health/checks/"+service)
@@ -235,6 +274,96 @@ func (h *Health) Checks(service string, q *QueryOptions) (HealthChecks, *QueryMe
 	return out, qm, nil
 }
 
+// HealthTransition is a change in the status of a service's health check.
+type HealthTransition struct {
+	Time        time.Time
+	Node        string
+	ServiceID   string
+	ServiceName string
+	CheckID     string
+	OldStatus   string
+	NewStatus   string
+}
+
+// ServiceHealthHistory is the recent health history of a service. The
+// history is kept in memory by the servers and is only advisory, Note
+// describes its limitations.
+type ServiceHealthHistory struct {
+	Service     string
+	Transitions []*HealthTransition
+	Note        string
+}
+
+// ServiceHistory is used to return the recent health check status
+// transitions of a service, oldest first.
+func (h *Health) ServiceHistory(service string, q *QueryOptions) (*ServiceHealthHistory, *QueryMeta, error) {
+	r := h.c.newRequest("GET", "/v1/health/service/"+service+"/history")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(h.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out ServiceHealthHistory
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
+
+const (
+	// ConnectMismatchSidecarMissing is used when a service instance has no
+	// sidecar proxy while other instances of the same service do.
+	ConnectMismatchSidecarMissing = "sidecar-missing"
+
+	// ConnectMismatchSidecarCritical is used when all the sidecar proxies of
+	// a service instance are critical.
+	ConnectMismatchSidecarCritical = "sidecar-critical"
+
+	// ConnectMismatchDestinationMissing is used when a sidecar proxy's
+	// destination service isn't registered on the same node.
+	ConnectMismatchDestinationMissing = "destination-missing"
+)
+
+// ConnectMismatch is a service instance that can't be reached through Connect
+// because of its sidecar proxy, or a sidecar proxy without its service.
+type ConnectMismatch struct {
+	Node           string
+	ServiceID      string
+	ServiceName    string
+	ProxyServiceID string
+	Reason         string
+	Checks         HealthChecks
+}
+
+// ConnectMismatches is used to return the service instances whose sidecar
+// proxies are missing or critical, and the sidecar proxies whose service is
+// missing.
+func (h *Health) ConnectMismatches(q *QueryOptions) ([]*ConnectMismatch, *QueryMeta, error) {
+	r := h.c.newRequest("GET", "/v1/internal/connect/mismatches")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(h.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out []*ConnectMismatch
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return out, qm, nil
+}
+
 // Service is used to query health information along with service info
 // for a given service. It can optionally do server-side filtering on a tag
 // or nodes with passing health checks only.
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/sdk/testutil"
@@ -36,6 +37,47 @@ func TestAPI_HealthNode(t *testing.T) {
 	})
 }
 
+func TestAPI_HealthNode_SummarizeOutput(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	catalog := c.Catalog()
+	health := c.Health()
+
+	output := strings.Repeat("x", 4096)
+	reg := &CatalogRegistration{
+		Datacenter: "dc1",
+		Node:       "foobar",
+		Address:    "192.168.10.10",
+		Check: &AgentCheck{
+			Node:    "foobar",
+			CheckID: "disk",
+			Name:    "disk",
+			Status:  HealthPassing,
+			Output:  output,
+		},
+	}
+	_, err := catalog.Register(reg, nil)
+	require.NoError(t, err)
+
+	checks, _, err := health.Node("foobar", &QueryOptions{SummarizeOutput: true})
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	require.Len(t, checks[0].Output, 100)
+	require.NotNil(t, checks[0].OutputDigest)
+	require.Equal(t, len(output), checks[0].OutputDigest.Length)
+
+	check, _, err := health.Check("foobar", "disk", nil)
+	require.NoError(t, err)
+	require.NotNil(t, check)
+	require.Equal(t, output, check.Output)
+
+	check, _, err = health.Check("foobar", "nope", nil)
+	require.NoError(t, err)
+	require.Nil(t, check)
+}
+
 func TestAPI_HealthNode_Filter(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -2,11 +2,17 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // KVPair is used to represent a single K/V entry
@@ -40,11 +46,57 @@ type KVPair struct {
 	// interactions with this key over the same session must specify the same
 	// session ID.
 	Session string
+
+	// Checksum is the checksum of the value, as "sha256:<hex>", if the client
+	// that wrote it supplied one. This is a read-only field, checksums are
+	// sent on writes when Config.VerifyKVChecksums is set.
+	Checksum string `json:",omitempty"`
+}
+
+// kvChecksum returns the checksum of a KV value, as "sha256:<hex>".
+func kvChecksum(value []byte) string {
+	sum := sha256.Sum256(value)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// verifyChecksum returns an error if the pair has a checksum that doesn't
+// match its value.
+func (p *KVPair) verifyChecksum() error {
+	if p.Checksum == "" {
+		return nil
+	}
+	if sum := kvChecksum(p.Value); !strings.EqualFold(sum, p.Checksum) {
+		return fmt.Errorf("Checksum mismatch for key %q: expected %s, got %s", p.Key, p.Checksum, sum)
+	}
+	return nil
 }
 
 // KVPairs is a list of KVPair objects
 type KVPairs []*KVPair
 
+// KVDeletedPair is a K/V entry that was deleted, as retained by the servers
+// when kv_deleted_retention is set.
+type KVDeletedPair struct {
+	// Key is the name of the key that was deleted.
+	Key string
+
+	// Flags and Value are the flags and value the key had when it was
+	// deleted.
+	Flags uint64
+	Value []byte
+
+	// DeletedAt is the time the key was deleted.
+	DeletedAt time.Time
+
+	// DeletedBy is the accessor ID of the token that deleted the key, if
+	// ACLs are enabled.
+	DeletedBy string `json:",omitempty"`
+
+	// CreateIndex and ModifyIndex hold the index of the deletion.
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
 // KV is used to manipulate the K/V API
 type KV struct {
 	c *Client
@@ -71,6 +123,9 @@ func (k *KV) Get(key string, q *QueryOptions) (*KVPair, *QueryMeta, error) {
 	if err := decodeBody(resp, &entries); err != nil {
 		return nil, nil, err
 	}
+	if err := k.verifyChecksums(entries); err != nil {
+		return nil, nil, err
+	}
 	if len(entries) > 0 {
 		return entries[0], qm, nil
 	}
@@ -92,9 +147,68 @@ func (k *KV) List(prefix string, q *QueryOptions) (KVPairs, *QueryMeta, error) {
 	if err := decodeBody(resp, &entries); err != nil {
 		return nil, nil, err
 	}
+	if err := k.verifyChecksums(entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
+// GetDeleted is used to lookup the retained deletion of a single key. The
+// returned pointer will be nil if the key wasn't deleted within the retention
+// window.
+func (k *KV) GetDeleted(key string, q *QueryOptions) (*KVDeletedPair, *QueryMeta, error) {
+	resp, qm, err := k.getPath("/v1/kv-deleted/", key, nil, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp == nil {
+		return nil, qm, nil
+	}
+	defer resp.Body.Close()
+
+	var entries []*KVDeletedPair
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	if len(entries) > 0 {
+		return entries[0], qm, nil
+	}
+	return nil, qm, nil
+}
+
+// ListDeleted is used to lookup the retained deletions of all the keys under
+// a prefix.
+func (k *KV) ListDeleted(prefix string, q *QueryOptions) ([]*KVDeletedPair, *QueryMeta, error) {
+	resp, qm, err := k.getPath("/v1/kv-deleted/", prefix, map[string]string{"recurse": ""}, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp == nil {
+		return nil, qm, nil
+	}
+	defer resp.Body.Close()
+
+	var entries []*KVDeletedPair
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
 	return entries, qm, nil
 }
 
+// verifyChecksums checks the values read against their checksums if
+// Config.VerifyKVChecksums is set.
+func (k *KV) verifyChecksums(entries []*KVPair) error {
+	if !k.c.config.VerifyKVChecksums {
+		return nil
+	}
+	for _, e := range entries {
+		if err := e.verifyChecksum(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Keys is used to list all the keys under a prefix. Optionally,
 // a separator can be used to limit the responses.
 func (k *KV) Keys(prefix, separator string, q *QueryOptions) ([]string, *QueryMeta, error) {
@@ -118,8 +232,38 @@ func (k *KV) Keys(prefix, separator string, q *QueryOptions) ([]string, *QueryMe
 	return entries, qm, nil
 }
 
+// ValueSize is used to look up the size of a key's value in bytes without
+// fetching the value. It returns false if the key doesn't exist.
+func (k *KV) ValueSize(key string, q *QueryOptions) (int64, bool, *QueryMeta, error) {
+	r := k.c.newRequest("HEAD", "/v1/kv/"+strings.TrimPrefix(key, "/"))
+	r.setQueryOptions(q)
+	r.params.Set("raw", "")
+	rtt, resp, err := k.c.doRequest(r)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	if resp.StatusCode == 404 {
+		return 0, false, qm, nil
+	} else if resp.StatusCode != 200 {
+		return 0, false, nil, generateUnexpectedResponseCodeError(resp)
+	}
+	return resp.ContentLength, true, qm, nil
+}
+
 func (k *KV) getInternal(key string, params map[string]string, q *QueryOptions) (*http.Response, *QueryMeta, error) {
-	r := k.c.newRequest("GET", "/v1/kv/"+strings.TrimPrefix(key, "/"))
+	return k.getPath("/v1/kv/", key, params, q)
+}
+
+// getPath reads a key from the given endpoint. The response is nil if the key
+// isn't found.
+func (k *KV) getPath(endpoint, key string, params map[string]string, q *QueryOptions) (*http.Response, *QueryMeta, error) {
+	r := k.c.newRequest("GET", endpoint+strings.TrimPrefix(key, "/"))
 	r.setQueryOptions(q)
 	for param, val := range params {
 		r.params.Set(param, val)
@@ -137,8 +281,7 @@ func (k *KV) getInternal(key string, params map[string]string, q *QueryOptions)
 		resp.Body.Close()
 		return nil, qm, nil
 	} else if resp.StatusCode != 200 {
-		resp.Body.Close()
-		return nil, nil, fmt.Errorf("Unexpected response code: %d", resp.StatusCode)
+		return nil, nil, generateUnexpectedResponseCodeError(resp)
 	}
 	return resp, qm, nil
 }
@@ -200,6 +343,9 @@ func (k *KV) put(key string, params map[string]string, body []byte, q *WriteOpti
 	for param, val := range params {
 		r.params.Set(param, val)
 	}
+	if k.c.config.VerifyKVChecksums {
+		r.params.Set("checksum", kvChecksum(body))
+	}
 	r.body = bytes.NewReader(body)
 	rtt, resp, err := requireOK(k.c.doRequest(r))
 	if err != nil {
@@ -284,3 +430,153 @@ func (k *KV) Txn(txn KVTxnOps, q *QueryOptions) (bool, *KVTxnResponse, *QueryMet
 	}
 	return respOk, &kvResp, qm, nil
 }
+
+const (
+	// kvGetManyBatchSize is the number of keys GetMany reads in a single
+	// transaction, which is the most operations a transaction can have.
+	kvGetManyBatchSize = 64
+
+	// defaultTxnParallelism is the number of transactions GetMany runs at
+	// once if Config.TxnParallelism isn't set.
+	defaultTxnParallelism = 4
+)
+
+// KVGetManyError is returned by GetMany when some of the keys couldn't be
+// read. The pairs of the other keys are still returned.
+type KVGetManyError struct {
+	// Errors maps the keys that couldn't be read to the reason. It's either
+	// the error of the request made for their batch, or the error the
+	// servers returned for the key's operation.
+	Errors map[string]error
+}
+
+func (e *KVGetManyError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("Failed to get %d keys, first %q: %v", len(keys), keys[0], e.Errors[keys[0]])
+}
+
+// GetMany is used to lookup several keys at once. The keys are read in
+// batches of get operations in transactions, which are run concurrently up to
+// Config.TxnParallelism. The returned pairs are in the order of the keys, and
+// the pair of a key that doesn't exist is nil. As with Get, a key the token
+// can't read is reported as not existing. If some keys couldn't be read, a
+// *KVGetManyError is returned along with the pairs of the other keys.
+func (k *KV) GetMany(keys []string, q *QueryOptions) (KVPairs, error) {
+	parallelism := k.c.config.TxnParallelism
+	if parallelism <= 0 {
+		parallelism = defaultTxnParallelism
+	}
+
+	pairs := make(KVPairs, len(keys))
+	var l sync.Mutex
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	for start := 0; start < len(keys); start += kvGetManyBatchSize {
+		end := start + kvGetManyBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			batchErrs := k.getBatch(keys[start:end], pairs[start:end], q)
+			l.Lock()
+			for key, err := range batchErrs {
+				errs[key] = err
+			}
+			l.Unlock()
+		}(start, end)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return pairs, &KVGetManyError{Errors: errs}
+	}
+	return pairs, nil
+}
+
+// getBatch reads the keys in a transaction and stores their pairs in the
+// pairs slice of the same length. It returns the errors of the keys that
+// couldn't be read.
+//
+// A get operation on a key that doesn't exist rolls back the transaction,
+// which reports all the operations that failed, so the keys that don't exist
+// or failed are dropped and the transaction is retried with the remaining
+// keys until it succeeds.
+func (k *KV) getBatch(keys []string, pairs KVPairs, q *QueryOptions) map[string]error {
+	errs := make(map[string]error)
+
+	// remaining holds the positions of the keys left to read.
+	remaining := make([]int, len(keys))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	for len(remaining) > 0 {
+		ops := make(TxnOps, 0, len(remaining))
+		for _, i := range remaining {
+			ops = append(ops, &TxnOp{KV: &KVTxnOp{Verb: KVGet, Key: keys[i]}})
+		}
+
+		ok, resp, _, err := k.c.txn(ops, q)
+		if err != nil {
+			for _, i := range remaining {
+				errs[keys[i]] = err
+			}
+			return errs
+		}
+
+		// The results of the keys the token can't read are left out, so
+		// they're matched by key, and those keys get a nil pair as if they
+		// didn't exist.
+		if ok {
+			found := make(map[string]*KVPair, len(resp.Results))
+			for _, result := range resp.Results {
+				if result.KV != nil {
+					found[result.KV.Key] = result.KV
+				}
+			}
+			for _, i := range remaining {
+				pairs[i] = found[keys[i]]
+			}
+			return errs
+		}
+
+		failed := make(map[int]bool)
+		for _, txnErr := range resp.Errors {
+			if txnErr.OpIndex < 0 || txnErr.OpIndex >= len(remaining) {
+				continue
+			}
+			failed[txnErr.OpIndex] = true
+			if !strings.HasSuffix(txnErr.What, "doesn't exist") {
+				errs[keys[remaining[txnErr.OpIndex]]] = errors.New(txnErr.What)
+			}
+		}
+		if len(failed) == 0 {
+			err := fmt.Errorf("Transaction was rolled back without an error")
+			for _, i := range remaining {
+				errs[keys[i]] = err
+			}
+			return errs
+		}
+
+		var next []int
+		for j, i := range remaining {
+			if !failed[j] {
+				next = append(next, i)
+			}
+		}
+		remaining = next
+	}
+	return errs
+}
@@ -2,10 +2,16 @@ package api
 
 import (
 	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAPI_ClientPutGetDelete(t *testing.T) {
@@ -74,6 +80,92 @@ func TestAPI_ClientPutGetDelete(t *testing.T) {
 	}
 }
 
+func TestAPI_ClientKVChecksums(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithConfig(t, func(conf *Config) {
+		conf.VerifyKVChecksums = true
+	}, nil)
+	defer s.Stop()
+
+	kv := c.KV()
+	s.WaitForSerfCheck(t)
+
+	// The checksum is sent with the value and stored along with it.
+	key := testKey()
+	value := []byte("test")
+	_, err := kv.Put(&KVPair{Key: key, Value: value}, nil)
+	require.NoError(t, err)
+
+	pair, _, err := kv.Get(key, nil)
+	require.NoError(t, err)
+	require.Equal(t, kvChecksum(value), pair.Checksum)
+
+	pairs, _, err := kv.List(key, nil)
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	require.Equal(t, kvChecksum(value), pairs[0].Checksum)
+
+	// So are the values written in a transaction.
+	ok, resp, _, err := c.Txn().Txn(TxnOps{
+		&TxnOp{KV: &KVTxnOp{Verb: KVCAS, Key: key, Value: []byte("txn"), Index: pair.ModifyIndex}},
+		&TxnOp{KV: &KVTxnOp{Verb: KVGet, Key: key}},
+	}, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, resp.Results, 2)
+	require.Empty(t, resp.Results[0].KV.Checksum)
+	require.Equal(t, kvChecksum([]byte("txn")), resp.Results[1].KV.Checksum)
+
+	// A value that doesn't match its checksum is rejected.
+	r := c.newRequest("PUT", "/v1/kv/"+key)
+	r.params.Set("checksum", kvChecksum(value))
+	r.body = bytes.NewReader([]byte("corrupted"))
+	_, _, err = requireOK(c.doRequest(r))
+	require.Error(t, err)
+	require.Equal(t, ErrCodeChecksumMismatch, ErrorCode(err))
+
+	pair = &KVPair{Key: key, Value: []byte("corrupted"), Checksum: kvChecksum(value)}
+	require.Error(t, pair.verifyChecksum())
+}
+
+func TestAPI_ClientKVDeleted(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithConfig(t, nil, func(conf *testutil.TestServerConfig) {
+		conf.KVDeletedRetention = "1h"
+	})
+	defer s.Stop()
+
+	kv := c.KV()
+	s.WaitForSerfCheck(t)
+
+	prefix := testKey()
+	key := prefix + "/test"
+	_, err := kv.Put(&KVPair{Key: key, Flags: 42, Value: []byte("test")}, nil)
+	require.NoError(t, err)
+
+	// Nothing was deleted yet.
+	pair, _, err := kv.GetDeleted(key, nil)
+	require.NoError(t, err)
+	require.Nil(t, pair)
+
+	_, err = kv.DeleteTree(prefix, nil)
+	require.NoError(t, err)
+
+	pair, meta, err := kv.GetDeleted(key, nil)
+	require.NoError(t, err)
+	require.NotNil(t, pair)
+	require.Equal(t, key, pair.Key)
+	require.Equal(t, uint64(42), pair.Flags)
+	require.Equal(t, []byte("test"), pair.Value)
+	require.False(t, pair.DeletedAt.IsZero())
+	require.Equal(t, pair.ModifyIndex, meta.LastIndex)
+
+	pairs, _, err := kv.ListDeleted(prefix, nil)
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	require.Equal(t, key, pairs[0].Key)
+}
+
 func TestAPI_ClientList_DeleteRecurse(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -458,6 +550,100 @@ func TestAPI_ClientAcquireRelease(t *testing.T) {
 	}
 }
 
+func TestAPI_ClientGetMany(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithConfig(t, func(conf *Config) {
+		conf.TxnParallelism = 2
+	}, nil)
+	defer s.Stop()
+
+	kv := c.KV()
+
+	// Spread the keys over several batches, with missing keys and a
+	// duplicate in between.
+	var keys []string
+	for i := 0; i < 3*kvGetManyBatchSize; i++ {
+		key := fmt.Sprintf("getmany/%d", i)
+		if i%5 == 0 {
+			keys = append(keys, key+"/missing")
+			continue
+		}
+		_, err := kv.Put(&KVPair{Key: key, Value: []byte(key)}, nil)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	keys = append(keys, keys[1])
+
+	pairs, err := kv.GetMany(keys, nil)
+	require.NoError(t, err)
+	require.Len(t, pairs, len(keys))
+	for i, key := range keys {
+		if strings.HasSuffix(key, "/missing") {
+			require.Nil(t, pairs[i], key)
+			continue
+		}
+		require.NotNil(t, pairs[i], key)
+		require.Equal(t, key, pairs[i].Key)
+		require.Equal(t, []byte(key), pairs[i].Value)
+	}
+
+	pairs, err = kv.GetMany(nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, pairs)
+}
+
+func TestAPI_ClientGetMany_ACL(t *testing.T) {
+	t.Parallel()
+	c, s := makeACLClient(t)
+	defer s.Stop()
+
+	kv := c.KV()
+	for _, key := range []string{"allowed/foo", "denied/foo"} {
+		_, err := kv.Put(&KVPair{Key: key, Value: []byte("test")}, nil)
+		require.NoError(t, err)
+	}
+
+	policy, _, err := c.ACL().PolicyCreate(&ACLPolicy{
+		Name:  "getmany",
+		Rules: `key_prefix "allowed/" { policy = "read" }`,
+	}, nil)
+	require.NoError(t, err)
+	token, _, err := c.ACL().TokenCreate(&ACLToken{
+		Policies: []*ACLTokenPolicyLink{{ID: policy.ID}},
+	}, nil)
+	require.NoError(t, err)
+
+	// As with Get, the denied key is reported as not existing, and the other
+	// pairs stay in order.
+	q := &QueryOptions{Token: token.SecretID}
+	pairs, err := kv.GetMany([]string{"denied/foo", "allowed/foo", "allowed/missing"}, q)
+	require.NoError(t, err)
+	require.Len(t, pairs, 3)
+	require.Nil(t, pairs[0])
+	require.NotNil(t, pairs[1])
+	require.Equal(t, "allowed/foo", pairs[1].Key)
+	require.Nil(t, pairs[2])
+}
+
+func TestAPI_ClientGetMany_RequestError(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(&Config{Address: srv.Listener.Addr().String()})
+	require.NoError(t, err)
+
+	// The keys of a failed request get its error.
+	pairs, err := c.KV().GetMany([]string{"foo", "bar"}, nil)
+	require.Len(t, pairs, 2)
+	getManyErr, ok := err.(*KVGetManyError)
+	require.True(t, ok, "bad: %v", err)
+	require.Len(t, getManyErr.Errors, 2)
+	require.Contains(t, getManyErr.Error(), "Failed to get 2 keys")
+}
+
 func TestAPI_KVClientTxn(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -92,6 +92,10 @@ type ServerHealth struct {
 
 	// StableSince is the last time this server's Healthy value changed.
 	StableSince time.Time
+
+	// ClockSkew is the estimated skew between the clock of this server and
+	// the leader's. A positive skew means this server's clock is ahead.
+	ClockSkew *ReadableDuration
 }
 
 // OperatorHealthReply is a representation of the overall health of the cluster
@@ -105,6 +109,10 @@ type OperatorHealthReply struct {
 
 	// Servers holds the health of each server.
 	Servers []ServerHealth
+
+	// MaxClockSkew is the largest estimated skew between the clocks of any
+	// two servers.
+	MaxClockSkew *ReadableDuration
 }
 
 // ReadableDuration is a duration type that is serialized to JSON in human readable format.
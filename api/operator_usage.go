@@ -0,0 +1,57 @@
+package api
+
+import (
+	"time"
+)
+
+// UsageClient is a token that made requests to a server over an interval.
+type UsageClient struct {
+	// AccessorID and Description identify the token.
+	AccessorID  string
+	Description string
+
+	// Count is the estimated number of requests made with the token.
+	Count uint64
+
+	// Share is the fraction of all the requests of the interval made with
+	// the token.
+	Share float64
+}
+
+// UsageTopClients is returned when querying for the tokens that made the most
+// requests to a server over its last completed interval.
+type UsageTopClients struct {
+	// Server is the name of the server that counted the requests.
+	Server string
+
+	// Start and End are the bounds of the interval. They are zero if no
+	// interval has been completed yet.
+	Start time.Time
+	End   time.Time
+
+	// SampleRate is the fraction of the requests that were sampled.
+	SampleRate float64
+
+	// RPC has the tokens that made the most RPCs, and BlockingQueries the
+	// ones that made the most blocking queries, by decreasing count.
+	RPC             []*UsageClient
+	BlockingQueries []*UsageClient
+}
+
+// UsageTopClients is used to query the tokens that made the most requests to
+// a server over its last completed usage interval.
+func (op *Operator) UsageTopClients(q *QueryOptions) (*UsageTopClients, error) {
+	r := op.c.newRequest("GET", "/v1/operator/usage/top-clients")
+	r.setQueryOptions(q)
+	_, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out UsageTopClients
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
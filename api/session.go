@@ -129,7 +129,7 @@ func (s *Session) Renew(id string, q *WriteOptions) (*SessionEntry, *WriteMeta,
 	if resp.StatusCode == 404 {
 		return nil, wm, nil
 	} else if resp.StatusCode != 200 {
-		return nil, nil, fmt.Errorf("Unexpected response code: %d", resp.StatusCode)
+		return nil, nil, generateUnexpectedResponseCodeError(resp)
 	}
 
 	var entries []*SessionEntry
@@ -142,10 +142,36 @@ func (s *Session) Renew(id string, q *WriteOptions) (*SessionEntry, *WriteMeta,
 	return nil, wm, nil
 }
 
+// SessionRenewResult is the result of renewing a session with RenewBatch.
+// Session is the renewed session, or nil if it wasn't found.
+type SessionRenewResult struct {
+	ID      string
+	Session *SessionEntry
+}
+
+// RenewBatch renews the TTL on several sessions with a single request
+func (s *Session) RenewBatch(ids []string, q *WriteOptions) ([]*SessionRenewResult, *WriteMeta, error) {
+	var out []*SessionRenewResult
+	wm, err := s.c.write("/v1/session/renew-batch", ids, &out, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, wm, nil
+}
+
 // RenewPeriodic is used to periodically invoke Session.Renew on a
 // session until a doneCh is closed. This is meant to be used in a long running
 // goroutine to ensure a session stays valid.
 func (s *Session) RenewPeriodic(initialTTL string, id string, q *WriteOptions, doneCh <-chan struct{}) error {
+	return s.RenewPeriodicBatch(initialTTL, []string{id}, q, doneCh)
+}
+
+// RenewPeriodicBatch is like RenewPeriodic for several sessions, which are
+// renewed together with RenewBatch. It returns ErrSessionExpired as soon as
+// one of the sessions has expired, and destroys all of them once doneCh is
+// closed. The sessions are renewed one at a time on agents that don't
+// support RenewBatch.
+func (s *Session) RenewPeriodicBatch(initialTTL string, ids []string, q *WriteOptions, doneCh <-chan struct{}) error {
 	ctx := q.Context()
 
 	ttl, err := time.ParseDuration(initialTTL)
@@ -162,24 +188,35 @@ func (s *Session) RenewPeriodic(initialTTL string, id string, q *WriteOptions, d
 		}
 		select {
 		case <-time.After(waitDur):
-			entry, _, err := s.Renew(id, q)
+			entries, err := s.renewAll(ids, q)
 			if err != nil {
 				waitDur = time.Second
 				lastErr = err
 				continue
 			}
-			if entry == nil {
-				return ErrSessionExpired
-			}
 
-			// Handle the server updating the TTL
-			ttl, _ = time.ParseDuration(entry.TTL)
+			// Handle the server updating the TTL, the sessions must be
+			// renewed before the shortest one expires.
+			var shortest time.Duration
+			for _, entry := range entries {
+				if entry == nil {
+					return ErrSessionExpired
+				}
+				if d, _ := time.ParseDuration(entry.TTL); d > 0 && (shortest == 0 || d < shortest) {
+					shortest = d
+				}
+			}
+			if shortest > 0 {
+				ttl = shortest
+			}
 			waitDur = ttl / 2
 			lastRenewTime = time.Now()
 
 		case <-doneCh:
 			// Attempt a session destroy
-			s.Destroy(id, q)
+			for _, id := range ids {
+				s.Destroy(id, q)
+			}
 			return nil
 
 		case <-ctx.Done():
@@ -190,6 +227,48 @@ func (s *Session) RenewPeriodic(initialTTL string, id string, q *WriteOptions, d
 	}
 }
 
+// sessionRenewBatchMax is the most sessions the agent renews in a single
+// RenewBatch request.
+const sessionRenewBatchMax = 1024
+
+// renewAll renews the given sessions and returns their entries in the same
+// order, nil for the sessions that weren't found. The sessions are split in
+// as many RenewBatch requests as needed, or renewed one at a time if the
+// agent doesn't support RenewBatch.
+func (s *Session) renewAll(ids []string, q *WriteOptions) ([]*SessionEntry, error) {
+	entries := make([]*SessionEntry, 0, len(ids))
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > sessionRenewBatchMax {
+			batch = batch[:sessionRenewBatchMax]
+		}
+		ids = ids[len(batch):]
+
+		results, _, err := s.RenewBatch(batch, q)
+		if e, ok := err.(StatusError); ok && e.StatusCode == 404 {
+			// The endpoint is unknown to older agents.
+			for _, id := range batch {
+				entry, _, err := s.Renew(id, q)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, entry)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != len(batch) {
+			return nil, fmt.Errorf("Unexpected number of results: %d for %d sessions", len(results), len(batch))
+		}
+		for _, result := range results {
+			entries = append(entries, result.Session)
+		}
+	}
+	return entries, nil
+}
+
 // Info looks up a single session
 func (s *Session) Info(id string, q *QueryOptions) (*SessionEntry, *QueryMeta, error) {
 	var entries []*SessionEntry
@@ -2,11 +2,15 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/pascaldekloe/goe/verify"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAPI_SessionCreateDestroy(t *testing.T) {
@@ -94,6 +98,51 @@ func TestAPI_SessionCreateRenewDestroy(t *testing.T) {
 	}
 }
 
+func TestAPI_SessionRenewBatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	session := c.Session()
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		id, _, err := session.Create(&SessionEntry{TTL: "10s"}, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer session.Destroy(id, nil)
+		ids = append(ids, id)
+	}
+
+	// Destroy the second session so it isn't found.
+	if _, err := session.Destroy(ids[1], nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	results, meta, err := session.RenewBatch(ids, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if meta.RequestTime == 0 {
+		t.Fatalf("bad: %v", meta)
+	}
+	if len(results) != 2 {
+		t.Fatalf("bad: %v", results)
+	}
+	if results[0].ID != ids[0] || results[0].Session == nil {
+		t.Fatalf("bad: %v", results[0])
+	}
+	if results[0].Session.TTL != "10s" {
+		t.Fatalf("should get session with TTL")
+	}
+	if results[1].ID != ids[1] || results[1].Session != nil {
+		t.Fatalf("bad: %v", results[1])
+	}
+}
+
 func TestAPI_SessionCreateRenewDestroyRenew(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -433,3 +482,81 @@ func TestAPI_SessionList(t *testing.T) {
 		t.Fatalf("bad: %v", qm)
 	}
 }
+
+func TestAPI_SessionRenewPeriodicBatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	session := c.Session()
+	entry := &SessionEntry{
+		Behavior: SessionBehaviorDelete,
+		TTL:      "500s", // disable ttl
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		id, _, err := session.Create(entry, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Closing the done channel destroys all the sessions.
+	errCh := make(chan error, 1)
+	doneCh := make(chan struct{})
+	go func() { errCh <- session.RenewPeriodicBatch("1s", ids, nil, doneCh) }()
+	close(doneCh)
+
+	select {
+	case <-time.After(1 * time.Second):
+		t.Fatal("renewal loop didn't terminate")
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	for _, id := range ids {
+		sess, _, err := session.Info(id, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if sess != nil {
+			t.Fatalf("session %s was not destroyed", id)
+		}
+	}
+}
+
+func TestAPI_SessionRenewPeriodicBatch_noBatchEndpoint(t *testing.T) {
+	t.Parallel()
+
+	// An older agent without the batch endpoint, which knows one session.
+	var renewed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/session/renew/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")
+		renewed = append(renewed, id)
+		if id != "known" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `[{"ID": %q, "TTL": "10s"}]`, id)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(&Config{Address: srv.Listener.Addr().String()})
+	require.NoError(t, err)
+
+	entries, err := c.Session().renewAll([]string{"known", "unknown"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"known", "unknown"}, renewed)
+	require.Len(t, entries, 2)
+	require.Equal(t, "10s", entries[0].TTL)
+	require.Nil(t, entries[1])
+}
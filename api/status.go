@@ -12,32 +12,58 @@ func (c *Client) Status() *Status {
 
 // Leader is used to query for a known leader
 func (s *Status) Leader() (string, error) {
+	leader, _, err := s.LeaderWithQueryOptions(nil)
+	return leader, err
+}
+
+// LeaderWithQueryOptions is used to query for a known leader. It supports
+// blocking queries, which return once the leader changes. The index is a
+// logical leadership epoch, it can only be compared with other indexes
+// returned by this endpoint.
+func (s *Status) LeaderWithQueryOptions(q *QueryOptions) (string, *QueryMeta, error) {
 	r := s.c.newRequest("GET", "/v1/status/leader")
-	_, resp, err := requireOK(s.c.doRequest(r))
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(s.c.doRequest(r))
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	defer resp.Body.Close()
 
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
 	var leader string
 	if err := decodeBody(resp, &leader); err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return leader, nil
+	return leader, qm, nil
 }
 
 // Peers is used to query for a known raft peers
 func (s *Status) Peers() ([]string, error) {
+	peers, _, err := s.PeersWithQueryOptions(nil)
+	return peers, err
+}
+
+// PeersWithQueryOptions is used to query for a known raft peers. It supports
+// blocking queries, which return once the raft configuration changes.
+func (s *Status) PeersWithQueryOptions(q *QueryOptions) ([]string, *QueryMeta, error) {
 	r := s.c.newRequest("GET", "/v1/status/peers")
-	_, resp, err := requireOK(s.c.doRequest(r))
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(s.c.doRequest(r))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
 	var peers []string
 	if err := decodeBody(resp, &peers); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return peers, nil
+	return peers, qm, nil
 }
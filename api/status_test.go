@@ -2,6 +2,7 @@ package api
 
 import (
 	"testing"
+	"time"
 )
 
 func TestAPI_StatusLeader(t *testing.T) {
@@ -37,3 +38,60 @@ func TestAPI_StatusPeers(t *testing.T) {
 		t.Fatalf("Expected peers ")
 	}
 }
+
+func TestAPI_StatusLeaderWithQueryOptions(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+	s.WaitForSerfCheck(t)
+
+	status := c.Status()
+
+	leader, meta, err := status.LeaderWithQueryOptions(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader == "" {
+		t.Fatalf("Expected leader")
+	}
+	if meta.LastIndex == 0 {
+		t.Fatalf("Expected index")
+	}
+
+	// The leader doesn't change, so this blocks until the wait time.
+	start := time.Now()
+	opts := &QueryOptions{WaitIndex: meta.LastIndex, WaitTime: 100 * time.Millisecond}
+	leader2, meta2, err := status.LeaderWithQueryOptions(opts)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader2 != leader {
+		t.Fatalf("bad leader: %q != %q", leader2, leader)
+	}
+	if meta2.LastIndex != meta.LastIndex {
+		t.Fatalf("bad index: %d != %d", meta2.LastIndex, meta.LastIndex)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("query didn't block: %v", elapsed)
+	}
+}
+
+func TestAPI_StatusPeersWithQueryOptions(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+	s.WaitForSerfCheck(t)
+
+	status := c.Status()
+
+	peers, meta, err := status.PeersWithQueryOptions(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(peers) == 0 {
+		t.Fatalf("Expected peers ")
+	}
+	if meta.LastIndex == 0 {
+		t.Fatalf("Expected index")
+	}
+}
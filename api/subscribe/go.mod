@@ -0,0 +1,14 @@
+module github.com/hashicorp/consul/api/subscribe
+
+go 1.12
+
+replace github.com/hashicorp/consul/api => ../
+
+replace github.com/hashicorp/consul/sdk => ../../sdk
+
+require (
+	github.com/golang/protobuf v1.2.0
+	github.com/hashicorp/consul/api v1.0.1
+	github.com/stretchr/testify v1.3.0
+	google.golang.org/grpc v0.0.0-20180920234847-8997b5fa0873
+)
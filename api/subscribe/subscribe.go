@@ -0,0 +1,166 @@
+// Package subscribe is a client for the gRPC subscription API of Consul
+// agents. It's a separate module from the api package so that only the
+// users of the subscriptions depend on gRPC.
+//
+// A subscription follows the state of a topic, such as the health of the
+// instances of a service, and is sent the changes to it as they happen
+// rather than the full state on every change as the blocking queries of the
+// HTTP API are. The gRPC API is only served by agents which have a gRPC port
+// configured.
+package subscribe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/consul/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Topic is the kind of state a subscription follows.
+type Topic int32
+
+const (
+	TopicUnknown       Topic = 0
+	TopicServiceHealth Topic = 1
+)
+
+// EventType is the kind of an event sent on a subscription.
+type EventType int32
+
+const (
+	EventTypeUnknown EventType = 0
+	EventTypeUpsert  EventType = 1
+	EventTypeDelete  EventType = 2
+	EventTypeReset   EventType = 3
+	EventTypeCommit  EventType = 4
+)
+
+// SubscribeRequest is the request that opens a subscription. It's the wire
+// format of the consul.subscribe.Consul gRPC service.
+type SubscribeRequest struct {
+	Topic      Topic  `protobuf:"varint,1,opt,name=topic,proto3"`
+	Key        string `protobuf:"bytes,2,opt,name=key,proto3"`
+	Datacenter string `protobuf:"bytes,3,opt,name=datacenter,proto3"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+
+// Event is a single change sent on a subscription. It's the wire format of
+// the consul.subscribe.Consul gRPC service.
+type Event struct {
+	Type    EventType `protobuf:"varint,1,opt,name=type,proto3"`
+	Index   uint64    `protobuf:"varint,2,opt,name=index,proto3"`
+	ID      string    `protobuf:"bytes,3,opt,name=id,proto3"`
+	Payload []byte    `protobuf:"bytes,4,opt,name=payload,proto3"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+const subscribeMethod = "/consul.subscribe.Consul/Subscribe"
+
+var subscribeStreamDesc = grpc.StreamDesc{
+	StreamName:    "Subscribe",
+	ServerStreams: true,
+}
+
+// ServiceHealthSubscription follows the health of the instances of a service
+// over the gRPC API of an agent.
+type ServiceHealthSubscription struct {
+	stream  grpc.ClientStream
+	entries map[string]*api.ServiceEntry
+}
+
+// ServiceHealth opens a subscription to the health of the instances of a
+// service on the given connection to the gRPC port of an agent. The
+// Datacenter and Token of the query options are used, and the context of the
+// query options ends the subscription.
+func ServiceHealth(conn *grpc.ClientConn, service string, q *api.QueryOptions) (*ServiceHealthSubscription, error) {
+	req := &SubscribeRequest{
+		Topic: TopicServiceHealth,
+		Key:   service,
+	}
+	ctx := context.Background()
+	if q != nil {
+		req.Datacenter = q.Datacenter
+		ctx = q.Context()
+		if q.Token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-consul-token", q.Token)
+		}
+	}
+
+	stream, err := conn.NewStream(ctx, &subscribeStreamDesc, subscribeMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &ServiceHealthSubscription{
+		stream:  stream,
+		entries: make(map[string]*api.ServiceEntry),
+	}, nil
+}
+
+// Next blocks until the next batch of changes has been received and returns
+// the instances of the service once they're applied. The instances are
+// sorted by their node name and service ID, joined by a slash. The
+// LastIndex of the query meta is the index of the batch.
+func (s *ServiceHealthSubscription) Next() ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	for {
+		var e Event
+		if err := s.stream.RecvMsg(&e); err != nil {
+			return nil, nil, err
+		}
+		if done, err := s.apply(&e); err != nil {
+			return nil, nil, err
+		} else if done {
+			return s.list(), &api.QueryMeta{LastIndex: e.Index}, nil
+		}
+	}
+}
+
+// apply applies an event to the known instances and returns whether it
+// ends a batch.
+func (s *ServiceHealthSubscription) apply(e *Event) (bool, error) {
+	switch e.Type {
+	case EventTypeUpsert:
+		var entry api.ServiceEntry
+		if err := json.Unmarshal(e.Payload, &entry); err != nil {
+			return false, fmt.Errorf("failed to decode %q: %v", e.ID, err)
+		}
+		s.entries[e.ID] = &entry
+	case EventTypeDelete:
+		delete(s.entries, e.ID)
+	case EventTypeReset:
+		s.entries = make(map[string]*api.ServiceEntry)
+	case EventTypeCommit:
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *ServiceHealthSubscription) list() []*api.ServiceEntry {
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]*api.ServiceEntry, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, s.entries[id])
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package subscribe
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceHealthSubscription_apply(t *testing.T) {
+	t.Parallel()
+
+	s := &ServiceHealthSubscription{entries: make(map[string]*api.ServiceEntry)}
+	apply := func(e *Event) bool {
+		done, err := s.apply(e)
+		require.NoError(t, err)
+		return done
+	}
+	ids := func() []string {
+		var out []string
+		for _, entry := range s.list() {
+			out = append(out, entry.Node.Node+"/"+entry.Service.ID)
+		}
+		return out
+	}
+
+	require.False(t, apply(&Event{Type: EventTypeReset}))
+	require.False(t, apply(&Event{Type: EventTypeUpsert, ID: "node2/web2",
+		Payload: []byte(`{"Node":{"Node":"node2"},"Service":{"ID":"web2"}}`)}))
+	require.False(t, apply(&Event{Type: EventTypeUpsert, ID: "node1/web1",
+		Payload: []byte(`{"Node":{"Node":"node1"},"Service":{"ID":"web1"}}`)}))
+	require.True(t, apply(&Event{Type: EventTypeCommit}))
+	require.Equal(t, []string{"node1/web1", "node2/web2"}, ids())
+
+	require.False(t, apply(&Event{Type: EventTypeDelete, ID: "node1/web1"}))
+	require.Equal(t, []string{"node2/web2"}, ids())
+
+	require.False(t, apply(&Event{Type: EventTypeReset}))
+	require.Empty(t, ids())
+
+	_, err := s.apply(&Event{Type: EventTypeUpsert, ID: "bad", Payload: []byte("{")})
+	require.Error(t, err)
+}
@@ -41,9 +41,12 @@ type TxnResult struct {
 type TxnResults []*TxnResult
 
 // TxnError is used to return information about an operation in a transaction.
+// Code is one of the ErrCode constants, for example ErrCodeCASFailed for an
+// operation whose index didn't match, or empty if unknown.
 type TxnError struct {
 	OpIndex int
 	What    string
+	Code    string
 }
 
 // TxnErrors is a list of TxnError objects.
@@ -81,6 +84,11 @@ type KVTxnOp struct {
 	Flags   uint64
 	Index   uint64
 	Session string
+
+	// Checksum is the checksum of Value, as "sha256:<hex>", for the ops that
+	// write a value. It's filled in automatically when
+	// Config.VerifyKVChecksums is set.
+	Checksum string `json:",omitempty"`
 }
 
 // KVTxnOps defines a set of operations to be performed inside a single
@@ -104,10 +112,13 @@ const (
 	NodeDeleteCAS NodeOp = "delete-cas"
 )
 
-// NodeTxnOp defines a single operation inside a transaction.
+// NodeTxnOp defines a single operation inside a transaction. The SyncOwner
+// of the Node must match the owner of the node, if any, the same way as for
+// a CatalogRegistration unless ForceSyncOwner is set.
 type NodeTxnOp struct {
-	Verb NodeOp
-	Node Node
+	Verb           NodeOp
+	Node           Node
+	ForceSyncOwner bool
 }
 
 // ServiceOp constants give possible operations available in a transaction.
@@ -121,11 +132,15 @@ const (
 	ServiceDeleteCAS ServiceOp = "delete-cas"
 )
 
-// ServiceTxnOp defines a single operation inside a transaction.
+// ServiceTxnOp defines a single operation inside a transaction. SyncOwner
+// and ForceSyncOwner must match the owner of the node, if any, the same way
+// as for a CatalogRegistration.
 type ServiceTxnOp struct {
-	Verb    ServiceOp
-	Node    string
-	Service AgentService
+	Verb           ServiceOp
+	Node           string
+	Service        AgentService
+	SyncOwner      string
+	ForceSyncOwner bool
 }
 
 // CheckOp constants give possible operations available in a transaction.
@@ -202,6 +217,9 @@ func (c *Client) txn(txn TxnOps, q *QueryOptions) (bool, *TxnResponse, *QueryMet
 	r := c.newRequest("PUT", "/v1/txn")
 	r.setQueryOptions(q)
 
+	if c.config.VerifyKVChecksums {
+		txn = withKVChecksums(txn)
+	}
 	r.obj = txn
 	rtt, resp, err := c.doRequest(r)
 	if err != nil {
@@ -218,6 +236,22 @@ func (c *Client) txn(txn TxnOps, q *QueryOptions) (bool, *TxnResponse, *QueryMet
 		if err := decodeBody(resp, &txnResp); err != nil {
 			return false, nil, nil, err
 		}
+		for _, e := range txnResp.Errors {
+			// Older agents don't return the codes.
+			if e.Code == "" {
+				e.Code = inferErrorCode(0, e.What)
+			}
+		}
+		if c.config.VerifyKVChecksums {
+			for _, result := range txnResp.Results {
+				if result.KV == nil {
+					continue
+				}
+				if err := result.KV.verifyChecksum(); err != nil {
+					return false, nil, nil, err
+				}
+			}
+		}
 
 		return resp.StatusCode == http.StatusOK, &txnResp, qm, nil
 	}
@@ -228,3 +262,21 @@ func (c *Client) txn(txn TxnOps, q *QueryOptions) (bool, *TxnResponse, *QueryMet
 	}
 	return false, nil, nil, fmt.Errorf("Failed request: %s", buf.String())
 }
+
+// withKVChecksums returns a copy of the ops where the KV ops that write a value
+// have its checksum, leaving the ops of the caller untouched.
+func withKVChecksums(txn TxnOps) TxnOps {
+	out := make(TxnOps, 0, len(txn))
+	for _, op := range txn {
+		if op.KV != nil && op.KV.Checksum == "" {
+			switch op.KV.Verb {
+			case KVSet, KVCAS, KVLock, KVUnlock:
+				kv := *op.KV
+				kv.Checksum = kvChecksum(kv.Value)
+				op = &TxnOp{KV: &kv}
+			}
+		}
+		out = append(out, op)
+	}
+	return out
+}
@@ -288,3 +288,30 @@ func TestAPI_ClientTxn(t *testing.T) {
 		t.Fatalf("unexpected value: %#v", meta)
 	}
 }
+
+func TestAPI_ClientTxn_CASFailed(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	txn := c.Txn()
+	ops := TxnOps{
+		&TxnOp{
+			KV: &KVTxnOp{
+				Verb:  KVCAS,
+				Key:   "test/cas",
+				Value: []byte("test"),
+				Index: 42,
+			},
+		},
+	}
+	ok, ret, _, err := txn.Txn(ops, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	} else if ok {
+		t.Fatalf("transaction should have failed")
+	}
+	if len(ret.Errors) != 1 || ret.Errors[0].Code != ErrCodeCASFailed {
+		t.Fatalf("bad: %v", ret.Errors)
+	}
+}
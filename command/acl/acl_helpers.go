@@ -3,7 +3,9 @@ package acl
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	consulacl "github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/mitchellh/cli"
@@ -22,7 +24,13 @@ func PrintToken(token *api.ACLToken, ui cli.Ui, showMeta bool) {
 	}
 	ui.Info(fmt.Sprintf("Policies:"))
 	for _, policy := range token.Policies {
-		ui.Info(fmt.Sprintf("   %s - %s", policy.ID, policy.Name))
+		ui.Info(fmt.Sprintf("   %s", policyLinkString(policy)))
+	}
+	if len(token.InlinePolicies) > 0 {
+		ui.Info(fmt.Sprintf("Inline Policies:"))
+		for _, inline := range token.InlinePolicies {
+			ui.Info(inline.Rules)
+		}
 	}
 	if token.Rules != "" {
 		ui.Info(fmt.Sprintf("Rules:"))
@@ -43,8 +51,23 @@ func PrintTokenListEntry(token *api.ACLTokenListEntry, ui cli.Ui, showMeta bool)
 	}
 	ui.Info(fmt.Sprintf("Policies:"))
 	for _, policy := range token.Policies {
-		ui.Info(fmt.Sprintf("   %s - %s", policy.ID, policy.Name))
+		ui.Info(fmt.Sprintf("   %s", policyLinkString(policy)))
+	}
+}
+
+// policyLinkString formats a policy link of a token, along with the time left
+// until it expires if it does.
+func policyLinkString(link *api.ACLTokenPolicyLink) string {
+	out := fmt.Sprintf("%s - %s", link.ID, link.Name)
+	if link.ExpirationTime != nil {
+		left := time.Until(*link.ExpirationTime).Round(time.Second)
+		if left > 0 {
+			out += fmt.Sprintf(" (expires in %s)", left)
+		} else {
+			out += " (expired)"
+		}
 	}
+	return out
 }
 
 func PrintPolicy(policy *api.ACLPolicy, ui cli.Ui, showMeta bool) {
@@ -61,6 +84,20 @@ func PrintPolicy(policy *api.ACLPolicy, ui cli.Ui, showMeta bool) {
 	ui.Info(policy.Rules)
 }
 
+// PrintKeyListWarnings warns about the key rules that only mean what they
+// appear to depending on whether the servers set acl.enable_key_list_policy.
+// The CLI can't tell whether they do, so the warnings say when they apply.
+// Rules that don't parse are left to the servers to reject.
+func PrintKeyListWarnings(rules string, ui cli.Ui) {
+	policy, err := consulacl.NewPolicyFromSource("", 0, rules, consulacl.SyntaxCurrent, nil)
+	if err != nil {
+		return
+	}
+	for _, warning := range policy.KeyListWarnings() {
+		ui.Warn(fmt.Sprintf("Warning: %s", warning))
+	}
+}
+
 func PrintPolicyListEntry(policy *api.ACLPolicyListEntry, ui cli.Ui, showMeta bool) {
 	ui.Info(fmt.Sprintf("%s:", policy.Name))
 	ui.Info(fmt.Sprintf("   ID:           %s", policy.ID))
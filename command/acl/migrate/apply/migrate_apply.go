@@ -0,0 +1,232 @@
+package migrateapply
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl/migrate"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/helpers"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// testStdin is the input for testing
+	testStdin io.Reader
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	plan, err := c.planFromArgs(c.flags.Args())
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error! %v", err))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	total := len(plan.ManagementTokens)
+	for _, policy := range plan.Policies {
+		total += len(policy.Tokens)
+	}
+
+	failed := false
+	done := 0
+	for _, accessorID := range plan.ManagementTokens {
+		done++
+		msg, err := c.upgradeToken(client, accessorID, structs.ACLPolicyGlobalManagementID)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("[%d/%d] Failed to upgrade management token %s: %v", done, total, accessorID, err))
+			failed = true
+			continue
+		}
+		c.UI.Info(fmt.Sprintf("[%d/%d] Management token %s %s", done, total, accessorID, msg))
+	}
+
+	for _, planned := range plan.Policies {
+		policyID, err := c.ensurePolicy(client, planned)
+		if err != nil {
+			c.UI.Error(err.Error())
+			failed = true
+			done += len(planned.Tokens)
+			continue
+		}
+
+		for _, accessorID := range planned.Tokens {
+			done++
+			msg, err := c.upgradeToken(client, accessorID, policyID)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("[%d/%d] Failed to upgrade token %s: %v", done, total, accessorID, err))
+				failed = true
+				continue
+			}
+			c.UI.Info(fmt.Sprintf("[%d/%d] Token %s %s", done, total, accessorID, msg))
+		}
+	}
+
+	if len(plan.RedundantTokens) > 0 {
+		c.UI.Info("")
+		c.UI.Info("The following tokens grant the same privileges as another token and may be removed manually:")
+		for _, accessorID := range plan.RedundantTokens {
+			c.UI.Info(fmt.Sprintf("   %s", accessorID))
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// ensurePolicy returns the ID of the policy described by the plan, creating it
+// if needed. An existing policy with the same name is reused only if its rules
+// match so that applying a plan more than once is safe.
+func (c *cmd) ensurePolicy(client *api.Client, planned *migrate.PlanPolicy) (string, error) {
+	policies, _, err := client.ACL().PolicyList(nil)
+	if err != nil {
+		return "", fmt.Errorf("Failed to retrieve the policy list: %v", err)
+	}
+
+	for _, entry := range policies {
+		if entry.Name != planned.Name {
+			continue
+		}
+
+		existing, _, err := client.ACL().PolicyRead(entry.ID, nil)
+		if err != nil {
+			return "", fmt.Errorf("Error reading policy %q: %v", planned.Name, err)
+		}
+		if strings.TrimSpace(existing.Rules) != strings.TrimSpace(planned.Rules) {
+			return "", fmt.Errorf("Policy %q already exists with different rules", planned.Name)
+		}
+		c.UI.Info(fmt.Sprintf("Policy %q already exists", planned.Name))
+		return existing.ID, nil
+	}
+
+	policy, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:        planned.Name,
+		Description: planned.Description,
+		Rules:       planned.Rules,
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create policy %q: %v", planned.Name, err)
+	}
+
+	c.UI.Info(fmt.Sprintf("Policy %q created", planned.Name))
+	return policy.ID, nil
+}
+
+// upgradeToken replaces the legacy rules of the token with a link to the given
+// policy. Tokens which were already upgraded to that policy are left alone.
+func (c *cmd) upgradeToken(client *api.Client, accessorID, policyID string) (string, error) {
+	token, _, err := client.ACL().TokenRead(accessorID, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Legacy management tokens usually have no rules, so whether the token is
+	// still a legacy token is decided by the legacy API returning its type.
+	info, _, err := client.ACL().Info(token.SecretID, nil)
+	if err != nil {
+		return "", fmt.Errorf("error reading the legacy type: %v", err)
+	}
+
+	if info == nil || info.Type == "" {
+		for _, link := range token.Policies {
+			if link.ID == policyID {
+				return "already migrated", nil
+			}
+		}
+		return "", fmt.Errorf("token is no longer a legacy token")
+	}
+
+	token.Rules = ""
+	token.Policies = []*api.ACLTokenPolicyLink{{ID: policyID}}
+	if _, _, err := client.ACL().TokenUpdate(token, nil); err != nil {
+		return "", err
+	}
+	return "upgraded", nil
+}
+
+func (c *cmd) planFromArgs(args []string) (*migrate.Plan, error) {
+	switch len(args) {
+	case 0:
+		return nil, fmt.Errorf("Missing PLAN argument")
+	case 1:
+	default:
+		return nil, fmt.Errorf("Too many arguments: expected 1 got %d", len(args))
+	}
+
+	data, err := helpers.LoadDataSource(args[0], c.testStdin)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan migrate.Plan
+	if err := json.Unmarshal([]byte(data), &plan); err != nil {
+		return nil, fmt.Errorf("Failed to decode plan: %v", err)
+	}
+	return &plan, nil
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "Apply a legacy ACL token migration plan"
+const help = `
+Usage: consul acl migrate apply [options] PLAN
+
+  Executes a plan generated by "consul acl migrate plan". Each proposed policy
+  is created and the legacy tokens listed with it are upgraded to use the
+  policy instead of their rules. Legacy management tokens are upgraded to the
+  builtin global-management policy. The secrets of upgraded tokens are
+  unchanged.
+
+  Applying the same plan more than once is safe: existing policies with
+  matching rules are reused and tokens which were already upgraded are
+  skipped. No tokens are deleted.
+
+  Apply a plan from a file:
+
+      $ consul acl migrate apply @plan.json
+
+  Apply a plan from stdin:
+
+      $ consul acl migrate plan | consul acl migrate apply -
+`
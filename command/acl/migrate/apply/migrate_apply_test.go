@@ -0,0 +1,206 @@
+package migrateapply
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl/migrate"
+	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateApplyCommand_noTabs(t *testing.T) {
+	t.Parallel()
+
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestMigrateApplyCommand(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	testDir := testutil.TempDir(t, "acl")
+	defer os.RemoveAll(testDir)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	var secrets []string
+	for i := 0; i < 2; i++ {
+		secret, _, err := client.ACL().Create(&api.ACLEntry{
+			Name:  "web",
+			Type:  "client",
+			Rules: `service "web" { policy = "write" }`,
+		}, &api.WriteOptions{Token: "root"})
+		require.NoError(err)
+		secrets = append(secrets, secret)
+	}
+	management, _, err := client.ACL().Create(&api.ACLEntry{
+		Name:  "admin",
+		Type:  "management",
+		Rules: `key "" { policy = "deny" }`,
+	}, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	// Legacy tokens are assigned an AccessorID in the background.
+	var legacy []*migrate.LegacyToken
+	retry.Run(t, func(r *retry.R) {
+		legacy = nil
+		for _, secret := range append(secrets, management) {
+			token, _, err := client.ACL().TokenReadSelf(&api.QueryOptions{Token: secret})
+			require.NoError(err)
+			if token.AccessorID == "" {
+				r.Fatal("legacy token not upgraded yet")
+			}
+			legacy = append(legacy, &migrate.LegacyToken{
+				AccessorID: token.AccessorID,
+				Rules:      token.Rules,
+			})
+		}
+	})
+	legacy[len(legacy)-1].Type = "management"
+
+	plan, err := migrate.NewPlan(legacy)
+	require.NoError(err)
+	raw, err := json.Marshal(plan)
+	require.NoError(err)
+
+	// Applying twice must be a no-op the second time.
+	for i := 0; i < 2; i++ {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		cmd.testStdin = strings.NewReader(string(raw))
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-",
+		})
+		require.Equal(0, code, ui.ErrorWriter.String())
+		require.Empty(ui.ErrorWriter.String())
+		require.Contains(ui.OutputWriter.String(), plan.RedundantTokens[0])
+		if i == 0 {
+			require.Contains(ui.OutputWriter.String(), "upgraded")
+		} else {
+			require.Contains(ui.OutputWriter.String(), "already migrated")
+		}
+	}
+
+	policies, _, err := client.ACL().PolicyList(&api.QueryOptions{Token: "root"})
+	require.NoError(err)
+	var policyID string
+	for _, policy := range policies {
+		if policy.Name == plan.Policies[0].Name {
+			policyID = policy.ID
+		}
+	}
+	require.NotEmpty(policyID)
+
+	for _, secret := range secrets {
+		token, _, err := client.ACL().TokenReadSelf(&api.QueryOptions{Token: secret})
+		require.NoError(err)
+		require.Empty(token.Rules)
+		require.Len(token.Policies, 1)
+		require.Equal(policyID, token.Policies[0].ID)
+	}
+
+	token, _, err := client.ACL().TokenReadSelf(&api.QueryOptions{Token: management})
+	require.NoError(err)
+	require.Empty(token.Rules)
+	require.Len(token.Policies, 1)
+	require.Equal(structs.ACLPolicyGlobalManagementID, token.Policies[0].ID)
+}
+
+func TestMigrateApplyCommand_managementWithoutRules(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	management, _, err := client.ACL().Create(&api.ACLEntry{
+		Name: "admin",
+		Type: "management",
+	}, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	var accessorID string
+	retry.Run(t, func(r *retry.R) {
+		token, _, err := client.ACL().TokenReadSelf(&api.QueryOptions{Token: management})
+		require.NoError(err)
+		if token.AccessorID == "" {
+			r.Fatal("legacy token not upgraded yet")
+		}
+		accessorID = token.AccessorID
+	})
+
+	plan, err := migrate.NewPlan([]*migrate.LegacyToken{
+		{AccessorID: accessorID, Type: "management"},
+	})
+	require.NoError(err)
+	raw, err := json.Marshal(plan)
+	require.NoError(err)
+
+	for i, expected := range []string{"upgraded", "already migrated"} {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		cmd.testStdin = strings.NewReader(string(raw))
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-",
+		})
+		require.Equal(0, code, "run %d: %s", i, ui.ErrorWriter.String())
+		require.Contains(ui.OutputWriter.String(), expected)
+	}
+
+	// The token is no longer visible through the legacy API.
+	info, _, err := client.ACL().Info(management, &api.QueryOptions{Token: "root"})
+	require.NoError(err)
+	require.Nil(info)
+
+	token, _, err := client.ACL().TokenReadSelf(&api.QueryOptions{Token: management})
+	require.NoError(err)
+	require.Len(token.Policies, 1)
+	require.Equal(structs.ACLPolicyGlobalManagementID, token.Policies[0].ID)
+}
+
+func TestMigrateApplyCommand_missingPlan(t *testing.T) {
+	t.Parallel()
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	require.Equal(t, 1, cmd.Run(nil))
+	require.Contains(t, ui.ErrorWriter.String(), "Missing PLAN argument")
+}
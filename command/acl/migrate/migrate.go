@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New() *cmd {
+	return &cmd{}
+}
+
+type cmd struct{}
+
+func (c *cmd) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(help, nil)
+}
+
+// Plan is the executable description of a legacy token migration. It is
+// produced by "consul acl migrate plan" and consumed by "consul acl migrate
+// apply".
+type Plan struct {
+	// Policies are the policies that will be created, each consolidating the
+	// rules shared by one or more legacy tokens.
+	Policies []*PlanPolicy
+
+	// RedundantTokens are the AccessorIDs of legacy tokens that grant exactly
+	// the same privileges as another token in the plan. Nothing is deleted
+	// automatically, these are listed as candidates for manual cleanup.
+	RedundantTokens []string
+
+	// ManagementTokens are the AccessorIDs of legacy management tokens. Their
+	// rules are ignored, so they are upgraded to the builtin global-management
+	// policy instead.
+	ManagementTokens []string
+
+	// SkippedTokens are the AccessorIDs of legacy tokens that have no rules
+	// and therefore nothing to migrate.
+	SkippedTokens []string
+}
+
+// PlanPolicy is a single policy to be created and the legacy tokens that
+// will be upgraded to use it.
+type PlanPolicy struct {
+	Name        string
+	Description string
+	Rules       string
+	Tokens      []string
+}
+
+// LegacyToken is the subset of a legacy token needed to build a plan.
+type LegacyToken struct {
+	AccessorID  string
+	Description string
+	Type        string
+	Rules       string
+}
+
+// NewPlan groups the given legacy tokens by their translated rules and
+// proposes one policy per distinct rule set. Management tokens are set apart
+// to be upgraded to the global-management policy. The output is deterministic for
+// a given set of tokens so that re-running plan produces the same policy
+// names.
+func NewPlan(tokens []*LegacyToken) (*Plan, error) {
+	plan := &Plan{}
+	byName := make(map[string]*PlanPolicy)
+
+	for _, token := range tokens {
+		if token.Type == api.ACLManagementType {
+			plan.ManagementTokens = append(plan.ManagementTokens, token.AccessorID)
+			continue
+		}
+		if strings.TrimSpace(token.Rules) == "" {
+			plan.SkippedTokens = append(plan.SkippedTokens, token.AccessorID)
+			continue
+		}
+
+		translated, err := acl.TranslateLegacyRules([]byte(token.Rules))
+		if err != nil {
+			return nil, fmt.Errorf("Error translating rules for token %s: %v", token.AccessorID, err)
+		}
+		rules := strings.TrimSpace(string(translated))
+
+		name := PolicyName(rules)
+		policy, ok := byName[name]
+		if !ok {
+			policy = &PlanPolicy{
+				Name:        name,
+				Description: "Migrated from legacy ACL token rules",
+				Rules:       rules,
+			}
+			byName[name] = policy
+			plan.Policies = append(plan.Policies, policy)
+		}
+		policy.Tokens = append(policy.Tokens, token.AccessorID)
+	}
+
+	sort.Slice(plan.Policies, func(i, j int) bool {
+		return plan.Policies[i].Name < plan.Policies[j].Name
+	})
+
+	for _, policy := range plan.Policies {
+		sort.Strings(policy.Tokens)
+		plan.RedundantTokens = append(plan.RedundantTokens, policy.Tokens[1:]...)
+	}
+	sort.Strings(plan.ManagementTokens)
+	sort.Strings(plan.SkippedTokens)
+
+	return plan, nil
+}
+
+// PolicyName returns the name used for a policy holding the given rules. It
+// is derived from the rules themselves so that identical rule sets always map
+// to the same policy.
+func PolicyName(rules string) string {
+	sum := sha256.Sum256([]byte(rules))
+	return fmt.Sprintf("legacy-rules-%x", sum[:6])
+}
+
+const synopsis = "Migrate legacy ACL tokens to policies"
+const help = `
+Usage: consul acl migrate <subcommand> [options] [args]
+
+  This command has subcommands for migrating legacy ACL tokens to the new
+  ACL system. Tokens with identical rules are consolidated into a single
+  policy. No tokens are ever deleted by these commands.
+
+  Write a migration plan to a file:
+
+      $ consul acl migrate plan > plan.json
+
+  Apply a migration plan:
+
+      $ consul acl migrate apply @plan.json
+
+  For more examples, ask for subcommand help or view the documentation.
+`
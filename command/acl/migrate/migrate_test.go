@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := NewPlan([]*LegacyToken{
+		{AccessorID: "c", Rules: `service "web" { policy = "write" }`},
+		{AccessorID: "a", Rules: `service "web" { policy = "write" }`},
+		{AccessorID: "b", Rules: `key "foo/" { policy = "read" }`},
+		{AccessorID: "d", Rules: "  "},
+		{AccessorID: "e", Type: "management", Rules: `key "" { policy = "deny" }`},
+	})
+	require.NoError(t, err)
+
+	// Management tokens ignore their rules.
+	require.Equal(t, []string{"e"}, plan.ManagementTokens)
+
+	require.Len(t, plan.Policies, 2)
+	var web, kv *PlanPolicy
+	for _, p := range plan.Policies {
+		if strings.Contains(p.Rules, "service_prefix") {
+			web = p
+		} else {
+			kv = p
+		}
+	}
+	require.NotNil(t, web)
+	require.NotNil(t, kv)
+	require.Equal(t, []string{"a", "c"}, web.Tokens)
+	require.Equal(t, []string{"b"}, kv.Tokens)
+	require.Equal(t, PolicyName(web.Rules), web.Name)
+	require.True(t, strings.HasPrefix(web.Name, "legacy-rules-"))
+	require.Contains(t, kv.Rules, `key_prefix "foo/"`)
+
+	require.Equal(t, []string{"c"}, plan.RedundantTokens)
+	require.Equal(t, []string{"d"}, plan.SkippedTokens)
+
+	// Planning again must produce the same result.
+	again, err := NewPlan([]*LegacyToken{
+		{AccessorID: "b", Rules: `key "foo/" { policy = "read" }`},
+		{AccessorID: "a", Rules: `service "web" { policy = "write" }`},
+		{AccessorID: "c", Rules: `service "web" { policy = "write" }`},
+		{AccessorID: "e", Type: "management", Rules: `key "" { policy = "deny" }`},
+		{AccessorID: "d", Rules: "  "},
+	})
+	require.NoError(t, err)
+	require.Equal(t, plan, again)
+}
+
+func TestNewPlan_invalidRules(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewPlan([]*LegacyToken{{AccessorID: "a", Rules: "not hcl {"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "token a")
+}
@@ -0,0 +1,146 @@
+package migrateplan
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl/migrate"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	entries, _, err := client.ACL().TokenList(nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to retrieve the token list: %v", err))
+		return 1
+	}
+
+	var legacy []*migrate.LegacyToken
+	for _, entry := range entries {
+		// Legacy management tokens usually have no rules and are linked to the
+		// global-management policy when they are assigned an AccessorID, so
+		// they are not flagged as legacy by the token list.
+		if !entry.Legacy && !linksGlobalManagement(entry) {
+			continue
+		}
+
+		if entry.AccessorID == "" {
+			// Legacy tokens are assigned an AccessorID asynchronously by the
+			// leader and cannot be upgraded until that has happened.
+			c.UI.Warn("Skipping a legacy token that has not been assigned an AccessorID yet, re-run the plan shortly")
+			continue
+		}
+
+		// The list endpoint doesn't return rules so each legacy token has to
+		// be read individually.
+		token, _, err := client.ACL().TokenRead(entry.AccessorID, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading token %s: %v", entry.AccessorID, err))
+			return 1
+		}
+
+		// The type of a legacy token is only returned by the legacy API.
+		info, _, err := client.ACL().Info(token.SecretID, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading the legacy type of token %s: %v", token.AccessorID, err))
+			return 1
+		}
+		if info == nil {
+			if !entry.Legacy {
+				// A new style token linked to global-management.
+				continue
+			}
+			c.UI.Error(fmt.Sprintf("Error reading the legacy type of token %s: not found", token.AccessorID))
+			return 1
+		}
+
+		legacy = append(legacy, &migrate.LegacyToken{
+			AccessorID:  token.AccessorID,
+			Description: token.Description,
+			Type:        info.Type,
+			Rules:       token.Rules,
+		})
+	}
+
+	plan, err := migrate.NewPlan(legacy)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	out, err := json.MarshalIndent(plan, "", "    ")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error encoding plan: %v", err))
+		return 1
+	}
+
+	c.UI.Output(string(out))
+	return 0
+}
+
+func linksGlobalManagement(entry *api.ACLTokenListEntry) bool {
+	for _, link := range entry.Policies {
+		if link.ID == structs.ACLPolicyGlobalManagementID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "Plan the migration of legacy ACL tokens"
+const help = `
+Usage: consul acl migrate plan [options]
+
+  Inspects all legacy ACL tokens and writes a JSON migration plan to stdout.
+  Tokens with identical rules are grouped together and one policy is proposed
+  per distinct rule set. Tokens that grant the same privileges as another
+  token are listed as redundant, but nothing is deleted.
+
+  The plan can be reviewed, edited and then executed with
+  "consul acl migrate apply".
+
+      $ consul acl migrate plan > plan.json
+`
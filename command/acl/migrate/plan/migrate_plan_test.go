@@ -0,0 +1,123 @@
+package migrateplan
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl/migrate"
+	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratePlanCommand_noTabs(t *testing.T) {
+	t.Parallel()
+
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestMigratePlanCommand(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	testDir := testutil.TempDir(t, "acl")
+	defer os.RemoveAll(testDir)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	for i := 0; i < 2; i++ {
+		_, _, err := client.ACL().Create(&api.ACLEntry{
+			Name:  "web",
+			Type:  "client",
+			Rules: `service "web" { policy = "write" }`,
+		}, &api.WriteOptions{Token: "root"})
+		require.NoError(err)
+	}
+	_, _, err := client.ACL().Create(&api.ACLEntry{
+		Name:  "kv",
+		Type:  "client",
+		Rules: `key "foo/" { policy = "read" }`,
+	}, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	// A management token ignores its rules.
+	_, _, err = client.ACL().Create(&api.ACLEntry{
+		Name:  "admin",
+		Type:  "management",
+		Rules: `key "" { policy = "deny" }`,
+	}, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	// Management tokens normally have no rules at all.
+	_, _, err = client.ACL().Create(&api.ACLEntry{
+		Name: "admin2",
+		Type: "management",
+	}, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	// New style tokens must not be part of the plan.
+	_, _, err = client.ACL().TokenCreate(&api.ACLToken{Description: "new"},
+		&api.WriteOptions{Token: "root"})
+	require.NoError(err)
+	_, _, err = client.ACL().TokenCreate(&api.ACLToken{
+		Description: "new management",
+		Policies:    []*api.ACLTokenPolicyLink{{ID: structs.ACLPolicyGlobalManagementID}},
+	}, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	// Legacy tokens are assigned an AccessorID in the background.
+	retry.Run(t, func(r *retry.R) {
+		tokens, _, err := client.ACL().TokenList(&api.QueryOptions{Token: "root"})
+		require.NoError(err)
+		for _, token := range tokens {
+			if token.AccessorID == "" {
+				r.Fatal("legacy token not upgraded yet")
+			}
+		}
+	})
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	code := cmd.Run([]string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+	})
+	require.Equal(0, code, ui.ErrorWriter.String())
+	require.Empty(ui.ErrorWriter.String())
+
+	var plan migrate.Plan
+	require.NoError(json.Unmarshal(ui.OutputWriter.Bytes(), &plan))
+	require.Len(plan.Policies, 2)
+	require.Len(plan.RedundantTokens, 1)
+	// The master token is a legacy management token as well.
+	require.Len(plan.ManagementTokens, 3)
+
+	tokens := 0
+	for _, policy := range plan.Policies {
+		tokens += len(policy.Tokens)
+	}
+	require.Equal(3, tokens)
+}
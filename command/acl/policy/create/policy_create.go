@@ -119,6 +119,7 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	aclhelpers.PrintKeyListWarnings(policy.Rules, c.UI)
 	aclhelpers.PrintPolicy(policy, c.UI, c.showMeta)
 	return 0
 }
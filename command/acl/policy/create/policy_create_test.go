@@ -61,3 +61,38 @@ func TestPolicyCreateCommand(t *testing.T) {
 	assert.Equal(code, 0)
 	assert.Empty(ui.ErrorWriter.String())
 }
+
+func TestPolicyCreateCommand_KeyListWarnings(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		enable_key_list_policy = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+		"-name=secrets",
+		`-rules=key_prefix "secrets/" { policy = "read" }`,
+	}
+
+	code := cmd.Run(args)
+	assert.Equal(code, 0)
+	assert.Contains(ui.ErrorWriter.String(), `Warning: key_prefix "secrets/" grants "read"`)
+	assert.Contains(ui.OutputWriter.String(), "secrets")
+}
@@ -0,0 +1,115 @@
+package policyorphans
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	showMeta bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.showMeta, "meta", false, "Indicates that policy metadata such "+
+		"as the content hash and raft indices should be shown for each entry")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	policies, _, err := client.ACL().PolicyList(nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to retrieve the policy list: %v", err))
+		return 1
+	}
+
+	// Policies are global, but local tokens are only known to their own
+	// datacenter, so the tokens of every datacenter have to be checked.
+	dcs, err := client.Catalog().Datacenters()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to retrieve the datacenters: %v", err))
+		return 1
+	}
+
+	// The links of the tokens only include the policies that still exist.
+	used := make(map[string]struct{})
+	for _, dc := range dcs {
+		tokens, _, err := client.ACL().TokenList(&api.QueryOptions{Datacenter: dc})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to retrieve the token list of datacenter %q: %v", dc, err))
+			return 1
+		}
+		for _, token := range tokens {
+			for _, link := range token.Policies {
+				used[link.ID] = struct{}{}
+			}
+		}
+	}
+
+	for _, policy := range policies {
+		// The builtin global management policy can't be deleted anyway.
+		if policy.ID == structs.ACLPolicyGlobalManagementID {
+			continue
+		}
+		if _, ok := used[policy.ID]; ok {
+			continue
+		}
+		acl.PrintPolicyListEntry(policy, c.UI, c.showMeta)
+	}
+
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "Lists ACL Policies that no token uses"
+const help = `
+Usage: consul acl policy orphans [options]
+
+    Lists the ACL policies that are not linked to any token, including the
+    anonymous token. These are candidates for cleanup. The tokens of every
+    datacenter are checked, since local tokens of other datacenters may use
+    the policies, and the command fails if any datacenter can't be reached.
+    The builtin global management policy is never listed.
+
+    Example:
+
+        $ consul acl policy orphans
+`
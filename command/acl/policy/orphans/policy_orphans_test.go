@@ -0,0 +1,142 @@
+package policyorphans
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyOrphansCommand_noTabs(t *testing.T) {
+	t.Parallel()
+
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestPolicyOrphansCommand(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	createPolicy := func(name string) *api.ACLPolicy {
+		policy, _, err := client.ACL().PolicyCreate(
+			&api.ACLPolicy{Name: name},
+			&api.WriteOptions{Token: "root"},
+		)
+		require.NoError(err)
+		return policy
+	}
+	used := createPolicy("used-policy")
+	orphan := createPolicy("orphan-policy")
+
+	_, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{
+			Description: "test token",
+			Policies:    []*api.ACLTokenPolicyLink{{ID: used.ID}},
+		},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+	}
+
+	require.Equal(0, cmd.Run(args))
+	require.Empty(ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Contains(output, orphan.ID)
+	require.NotContains(output, used.ID)
+	require.NotContains(output, structs.ACLPolicyGlobalManagementID)
+}
+
+func TestPolicyOrphansCommand_localTokensOfOtherDatacenters(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a1 := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+	defer a1.Shutdown()
+	testrpc.WaitForLeader(t, a1.RPC, "dc1")
+
+	a2 := agent.NewTestAgent(t, t.Name(), `
+	datacenter = "dc2"
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		enable_token_replication = true
+		tokens {
+			agent = "root"
+			replication = "root"
+		}
+	}`)
+	defer a2.Shutdown()
+	testrpc.WaitForLeader(t, a2.RPC, "dc2")
+
+	_, err := a2.JoinWAN([]string{a1.Config.SerfBindAddrWAN.String()})
+	require.NoError(err)
+
+	client := a1.Client()
+	policy, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "dc2-policy"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	// The token is local to dc2 and only known there.
+	retry.Run(t, func(r *retry.R) {
+		_, _, err := a2.Client().ACL().TokenCreate(
+			&api.ACLToken{
+				Description: "dc2 token",
+				Local:       true,
+				Policies:    []*api.ACLTokenPolicyLink{{ID: policy.ID}},
+			},
+			&api.WriteOptions{Token: "root"},
+		)
+		if err != nil {
+			r.Fatal(err)
+		}
+	})
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	args := []string{
+		"-http-addr=" + a1.HTTPAddr(),
+		"-token=root",
+	}
+
+	require.Equal(0, cmd.Run(args), ui.ErrorWriter.String())
+	require.NotContains(ui.OutputWriter.String(), policy.ID)
+}
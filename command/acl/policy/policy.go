@@ -42,6 +42,10 @@ Usage: consul acl policy <subcommand> [options] [args]
 
       $ consul acl policy list
 
+  List the policies that no token uses:
+
+      $ consul acl policy orphans
+
   Update a policy:
 
       $ consul acl policy update -name "other-policy" -datacenter "dc1"
@@ -147,6 +147,7 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	c.UI.Info(fmt.Sprintf("Policy updated successfully"))
+	acl.PrintKeyListWarnings(policy.Rules, c.UI)
 	acl.PrintPolicy(policy, c.UI, c.showMeta)
 	return 0
 }
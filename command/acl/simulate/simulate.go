@@ -0,0 +1,176 @@
+package simulate
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/helpers"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	tokenAccessor string
+	rules         string
+	checks        []string
+
+	// testStdin is the input for testing
+	testStdin io.Reader
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.tokenAccessor, "token-accessor", "", "The accessor ID of "+
+		"the token to simulate. It may be specified as a unique ID prefix but will "+
+		"error if the prefix matches multiple token accessor IDs")
+	c.flags.StringVar(&c.rules, "rules", "", "Rules to simulate instead of a "+
+		"token. This may be given as a literal string, a file path prefixed "+
+		"with '@' or '-' to read from stdin")
+	c.flags.Var((*flags.AppendSliceValue)(&c.checks), "check", "An access to "+
+		"check in the form resource:segment:access, such as service:web:write "+
+		"or operator:read. May be specified multiple times")
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if (c.tokenAccessor == "") == (c.rules == "") {
+		c.UI.Error("Must specify exactly one of the -token-accessor or -rules parameters")
+		return 1
+	}
+	if len(c.checks) == 0 {
+		c.UI.Error("Must specify at least one -check")
+		return 1
+	}
+
+	req := &api.ACLSimulateRequest{}
+	for _, raw := range c.checks {
+		check, err := parseCheck(raw)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		req.Checks = append(req.Checks, check)
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	if c.tokenAccessor != "" {
+		req.AccessorID, err = acl.GetTokenIDFromPartial(client, c.tokenAccessor)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error determining token ID: %v", err))
+			return 1
+		}
+	} else {
+		req.Rules, err = helpers.LoadDataSource(c.rules, c.testStdin)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error loading rules: %v", err))
+			return 1
+		}
+	}
+
+	results, _, err := client.ACL().Simulate(req, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error simulating access: %v", err))
+		return 1
+	}
+
+	out := []string{"Check\x1fResult\x1fPolicy\x1fRule"}
+	for i, r := range results {
+		result, policy, rule := "denied", r.PolicyName, r.Rule
+		switch {
+		case r.Error != "":
+			result, rule = "error", r.Error
+		case r.Allowed:
+			result = "allowed"
+		}
+		if r.Error == "" && rule == "" {
+			policy = "-"
+			rule = "(default policy)"
+			if r.DefaultPolicyOverride {
+				rule = "(local default policy override)"
+			}
+		}
+		if policy == "" {
+			policy = "-"
+		}
+		out = append(out, fmt.Sprintf("%s\x1f%s\x1f%s\x1f%s", c.checks[i], result, policy, rule))
+	}
+	c.UI.Output(columnize.Format(out, &columnize.Config{Delim: string([]byte{0x1f})}))
+	return 0
+}
+
+// parseCheck parses a check in the form resource:segment:access. The segment
+// may contain colons, and may be left out for resources such as operator that
+// don't have one.
+func parseCheck(raw string) (api.ACLSimulateCheck, error) {
+	first, last := strings.Index(raw, ":"), strings.LastIndex(raw, ":")
+	if first == -1 {
+		return api.ACLSimulateCheck{}, fmt.Errorf("Invalid check %q, expected resource:segment:access", raw)
+	}
+
+	check := api.ACLSimulateCheck{
+		Resource: raw[:first],
+		Access:   raw[last+1:],
+	}
+	if first != last {
+		check.Segment = raw[first+1 : last]
+	}
+	if check.Resource == "" || check.Access == "" {
+		return api.ACLSimulateCheck{}, fmt.Errorf("Invalid check %q, expected resource:segment:access", raw)
+	}
+	return check, nil
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "Simulate the access of an ACL token or rules"
+const help = `
+Usage: consul acl simulate [options] -check <resource:segment:access> ...
+
+    Checks what an ACL token, or a set of rules, would be allowed to do
+    without doing it, and prints the policy and rule that decided each
+    check. Checks that no rule matches are decided by the default policy.
+
+    Simulate a token:
+
+        $ consul acl simulate -token-accessor=fdabbcb5 \
+                              -check 'service:web:write' \
+                              -check 'key:config/app:read'
+
+    Simulate rules from a file:
+
+        $ consul acl simulate -rules=@rules.hcl -check 'operator:read'
+`
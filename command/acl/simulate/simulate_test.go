@@ -0,0 +1,120 @@
+package simulate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateCommand_noTabs(t *testing.T) {
+	t.Parallel()
+
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestSimulateCommand_parseCheck(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]api.ACLSimulateCheck{
+		"service:web:write":   {Resource: "service", Segment: "web", Access: "write"},
+		"key:config/app:read": {Resource: "key", Segment: "config/app", Access: "read"},
+		"key:a:b:c:list":      {Resource: "key", Segment: "a:b:c", Access: "list"},
+		"node::read":          {Resource: "node", Segment: "", Access: "read"},
+		"operator:read":       {Resource: "operator", Access: "read"},
+	}
+	for raw, expected := range cases {
+		check, err := parseCheck(raw)
+		require.NoError(t, err, raw)
+		require.Equal(t, expected, check, raw)
+	}
+
+	for _, raw := range []string{"service", ":web:read", "service:web:"} {
+		_, err := parseCheck(raw)
+		require.Error(t, err, raw)
+	}
+}
+
+func TestSimulateCommand(t *testing.T) {
+	t.Parallel()
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		default_policy = "deny"
+		tokens {
+			master = "root"
+		}
+	}`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	policy, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "web", Rules: `service "web" { policy = "write" }`},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(t, err)
+	token, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{Policies: []*api.ACLTokenPolicyLink{{ID: policy.ID}}},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(t, err)
+
+	t.Run("token", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-token-accessor=" + token.AccessorID,
+			"-check=service:web:write",
+			"-check=key:config/app:read",
+			"-check=namespace:default:read",
+		}
+		require.Equal(t, 0, cmd.Run(args), ui.ErrorWriter.String())
+
+		lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+		require.Len(t, lines, 4)
+		require.Contains(t, lines[1], "allowed")
+		require.Contains(t, lines[1], `service "web" { policy = "write" }`)
+		require.Contains(t, lines[2], "denied")
+		require.Contains(t, lines[2], "(default policy)")
+		require.Contains(t, lines[3], "error")
+		require.Contains(t, lines[3], "Consul Enterprise")
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		cmd.testStdin = strings.NewReader(`operator = "read"`)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-rules=-",
+			"-check=operator:write",
+		}
+		require.Equal(t, 0, cmd.Run(args), ui.ErrorWriter.String())
+		output := ui.OutputWriter.String()
+		require.Contains(t, output, "denied")
+		require.Contains(t, output, `operator = "read"`)
+	})
+
+	t.Run("missing checks", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token-accessor=" + token.AccessorID,
+		}
+		require.Equal(t, 1, cmd.Run(args))
+		require.Contains(t, ui.ErrorWriter.String(), "-check")
+	})
+}
@@ -3,10 +3,12 @@ package tokencreate
 import (
 	"flag"
 	"fmt"
+	"io"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/helpers"
 	"github.com/mitchellh/cli"
 )
 
@@ -24,9 +26,13 @@ type cmd struct {
 
 	policyIDs   []string
 	policyNames []string
+	rules       []string
 	description string
 	local       bool
 	showMeta    bool
+
+	// testStdin is the input for testing
+	testStdin io.Reader
 }
 
 func (c *cmd) init() {
@@ -39,6 +45,10 @@ func (c *cmd) init() {
 		"policy to use for this token. May be specified multiple times")
 	c.flags.Var((*flags.AppendSliceValue)(&c.policyNames), "policy-name", "Name of a "+
 		"policy to use for this token. May be specified multiple times")
+	c.flags.Var((*flags.AppendSliceValue)(&c.rules), "rules", "Rules for an inline "+
+		"policy that only applies to this token and is deleted along with it. "+
+		"This can be a literal rules string, \"@\" followed by a file path or "+
+		"\"-\" to read from stdin. May be specified multiple times")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
@@ -50,8 +60,8 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	if len(c.policyNames) == 0 && len(c.policyIDs) == 0 {
-		c.UI.Error(fmt.Sprintf("Cannot create a token without specifying -policy-name or -policy-id at least once"))
+	if len(c.policyNames) == 0 && len(c.policyIDs) == 0 && len(c.rules) == 0 {
+		c.UI.Error(fmt.Sprintf("Cannot create a token without specifying -policy-name, -policy-id or -rules at least once"))
 		return 1
 	}
 
@@ -81,6 +91,15 @@ func (c *cmd) Run(args []string) int {
 		newToken.Policies = append(newToken.Policies, &api.ACLTokenPolicyLink{ID: policyID})
 	}
 
+	for _, rules := range c.rules {
+		data, err := helpers.LoadDataSource(rules, c.testStdin)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error loading rules: %v", err))
+			return 1
+		}
+		newToken.InlinePolicies = append(newToken.InlinePolicies, &api.ACLTokenInlinePolicy{Rules: data})
+	}
+
 	token, _, err := client.ACL().TokenCreate(newToken, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to create new token: %v", err))
@@ -107,9 +126,18 @@ Usage: consul acl token create [options]
   or the -policy-name options. When specifying policies by IDs you may use a
   unique prefix of the UUID as a shortcut for specifying the entire UUID.
 
+  Rules that should only apply to this token can be given with -rules. They
+  are stored on the token itself and are deleted along with it, which is
+  useful for short lived tokens with narrow privileges.
+
   Create a new token:
 
           $ consul acl token create -description "Replication token"
                                             -policy-id b52fc3de-5
                                             -policy-name "acl-replication"
+
+  Create a token with inline rules:
+
+          $ consul acl token create -description "Debugging"
+                                    -rules 'key_prefix "tmp/" { policy = "write" }'
 `
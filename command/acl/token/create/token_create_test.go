@@ -82,4 +82,22 @@ func TestTokenCreateCommand(t *testing.T) {
 		assert.Equal(code, 0)
 		assert.Empty(ui.ErrorWriter.String())
 	}
+
+	// create with inline rules
+	{
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-rules=" + `key_prefix "tmp/" { policy = "write" }`,
+			"-description=test token",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+		assert.Contains(ui.OutputWriter.String(), "Inline Policies:")
+		assert.Contains(ui.OutputWriter.String(), `key_prefix "tmp/"`)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
@@ -22,12 +23,18 @@ type cmd struct {
 	help  string
 
 	showMeta bool
+	policy   string
+	dangling bool
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.BoolVar(&c.showMeta, "meta", false, "Indicates that token metadata such "+
 		"as the content hash and Raft indices should be shown for each entry")
+	c.flags.StringVar(&c.policy, "policy", "", "Only list the tokens linked to the "+
+		"policy with this ID or name")
+	c.flags.BoolVar(&c.dangling, "dangling", false, "Only list the tokens whose "+
+		"policies were all deleted, which only get the default policy")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
@@ -45,7 +52,17 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	tokens, _, err := client.ACL().TokenList(nil)
+	if c.dangling && c.policy != "" {
+		c.UI.Error("Cannot use both -dangling and -policy")
+		return 1
+	}
+
+	var tokens []*api.ACLTokenListEntry
+	if c.dangling {
+		tokens, _, err = client.ACL().TokenListDangling(nil)
+	} else {
+		tokens, _, err = client.ACL().TokenListByPolicy(c.policy, nil)
+	}
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to retrieve the token list: %v", err))
 		return 1
@@ -79,4 +96,12 @@ Usage: consul acl token list [options]
   List all the ALC tokens
 
           $ consul acl token list
+
+  Or only the ones linked to a policy, for example before deleting it:
+
+          $ consul acl token list -policy=node-read
+
+  Or the ones whose policies were all deleted:
+
+          $ consul acl token list -dangling
 `
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/consul/sdk/testutil"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTokenListCommand_noTabs(t *testing.T) {
@@ -78,3 +79,122 @@ func TestTokenListCommand(t *testing.T) {
 		assert.Contains(output, v)
 	}
 }
+
+func TestTokenListCommand_Policy(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	policy, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "test-policy"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	linked, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{
+			Description: "linked token",
+			Policies:    []*api.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	other, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{Description: "other token"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+		"-policy=test-policy",
+	}
+
+	require.Equal(0, cmd.Run(args))
+	require.Empty(ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Contains(output, linked.AccessorID)
+	require.NotContains(output, other.AccessorID)
+}
+
+func TestTokenListCommand_Dangling(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	policy, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "test-policy"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	dangling, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{
+			Description: "dangling token",
+			Policies:    []*api.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	other, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{Description: "other token"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	_, err = client.ACL().PolicyDelete(policy.ID, &api.WriteOptions{Token: "root"})
+	require.NoError(err)
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+		"-dangling",
+	}
+
+	require.Equal(0, cmd.Run(args))
+	require.Empty(ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Contains(output, dangling.AccessorID)
+	require.NotContains(output, other.AccessorID)
+
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	args = append(args, "-policy=test-policy")
+	require.Equal(1, cmd.Run(args))
+	require.Contains(ui.ErrorWriter.String(), "Cannot use both -dangling and -policy")
+}
@@ -3,6 +3,7 @@ package tokenupdate
 import (
 	"flag"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
@@ -27,6 +28,7 @@ type cmd struct {
 	policyNames   []string
 	description   string
 	mergePolicies bool
+	policyTTL     time.Duration
 	showMeta      bool
 	upgradeLegacy bool
 }
@@ -45,6 +47,10 @@ func (c *cmd) init() {
 		"policy to use for this token. May be specified multiple times")
 	c.flags.Var((*flags.AppendSliceValue)(&c.policyNames), "policy-name", "Name of a "+
 		"policy to use for this token. May be specified multiple times")
+	c.flags.DurationVar(&c.policyTTL, "policy-ttl", 0, "Make the policies given "+
+		"by -policy-id and -policy-name expire after this long, such as \"4h\". "+
+		"With -merge-policies this also applies to those policies if the token "+
+		"already has them")
 	c.flags.BoolVar(&c.upgradeLegacy, "upgrade-legacy", false, "Add new polices "+
 		"to a legacy token replacing all existing rules. This will cause the legacy "+
 		"token to behave exactly like a new token but keep the same Secret.\n"+
@@ -67,6 +73,15 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if c.policyTTL < 0 {
+		c.UI.Error(fmt.Sprintf("The -policy-ttl cannot be negative"))
+		return 1
+	}
+	if c.policyTTL > 0 && len(c.policyIDs) == 0 && len(c.policyNames) == 0 {
+		c.UI.Error(fmt.Sprintf("The -policy-ttl requires -policy-id or -policy-name"))
+		return 1
+	}
+
 	client, err := c.http.APIClient()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
@@ -113,6 +128,7 @@ func (c *cmd) Run(args []string) int {
 			for _, link := range token.Policies {
 				if link.Name == policyName {
 					found = true
+					c.setPolicyTTL(link)
 					break
 				}
 			}
@@ -120,7 +136,7 @@ func (c *cmd) Run(args []string) int {
 			if !found {
 				// We could resolve names to IDs here but there isn't any reason why its would be better
 				// than allowing the agent to do it.
-				token.Policies = append(token.Policies, &api.ACLTokenPolicyLink{Name: policyName})
+				token.Policies = append(token.Policies, c.policyLink(&api.ACLTokenPolicyLink{Name: policyName}))
 			}
 		}
 
@@ -135,12 +151,13 @@ func (c *cmd) Run(args []string) int {
 			for _, link := range token.Policies {
 				if link.ID == policyID {
 					found = true
+					c.setPolicyTTL(link)
 					break
 				}
 			}
 
 			if !found {
-				token.Policies = append(token.Policies, &api.ACLTokenPolicyLink{ID: policyID})
+				token.Policies = append(token.Policies, c.policyLink(&api.ACLTokenPolicyLink{ID: policyID}))
 			}
 		}
 	} else {
@@ -149,7 +166,7 @@ func (c *cmd) Run(args []string) int {
 		for _, policyName := range c.policyNames {
 			// We could resolve names to IDs here but there isn't any reason why its would be better
 			// than allowing the agent to do it.
-			token.Policies = append(token.Policies, &api.ACLTokenPolicyLink{Name: policyName})
+			token.Policies = append(token.Policies, c.policyLink(&api.ACLTokenPolicyLink{Name: policyName}))
 		}
 
 		for _, policyID := range c.policyIDs {
@@ -158,7 +175,7 @@ func (c *cmd) Run(args []string) int {
 				c.UI.Error(fmt.Sprintf("Error resolving policy ID %s: %v", policyID, err))
 				return 1
 			}
-			token.Policies = append(token.Policies, &api.ACLTokenPolicyLink{ID: policyID})
+			token.Policies = append(token.Policies, c.policyLink(&api.ACLTokenPolicyLink{ID: policyID}))
 		}
 	}
 
@@ -173,6 +190,21 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// policyLink sets the -policy-ttl on a new policy link.
+func (c *cmd) policyLink(link *api.ACLTokenPolicyLink) *api.ACLTokenPolicyLink {
+	c.setPolicyTTL(link)
+	return link
+}
+
+// setPolicyTTL sets the -policy-ttl on a policy link, if it's given. The
+// expiration is then set by the servers when the token is written.
+func (c *cmd) setPolicyTTL(link *api.ACLTokenPolicyLink) {
+	if c.policyTTL > 0 {
+		link.ExpirationTTL = c.policyTTL
+		link.ExpirationTime = nil
+	}
+}
+
 func (c *cmd) Synopsis() string {
 	return synopsis
 }
@@ -195,4 +227,8 @@ Usage: consul acl token update [options]
       Update all editable fields of the token:
 
           $ consul acl token update -id abcd -description "replication" -policy-name "token-replication"
+
+      Grant a policy for a limited time on top of the existing ones:
+
+          $ consul acl token update -id abcd -merge-policies -policy-name "break-glass" -policy-ttl 4h
 `
@@ -189,3 +189,90 @@ func TestTokenUpdateCommand(t *testing.T) {
 		assert.Equal(legacyToken.SecretID, gotToken.SecretID)
 	}
 }
+
+func TestTokenUpdateCommand_PolicyTTL(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	permanent, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "permanent"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+	breakGlass, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "break-glass"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+	token, _, err := client.ACL().TokenCreate(
+		&api.ACLToken{
+			Description: "on-call",
+			Policies:    []*api.ACLTokenPolicyLink{{ID: permanent.ID}},
+		},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	// The TTL needs policies to apply to.
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	code := cmd.Run([]string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-id=" + token.AccessorID,
+		"-token=root",
+		"-policy-ttl=4h",
+	})
+	require.Equal(1, code)
+	require.Contains(ui.ErrorWriter.String(), "requires -policy-id or -policy-name")
+
+	// The new policy expires, and the existing one doesn't.
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	code = cmd.Run([]string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-id=" + token.AccessorID,
+		"-token=root",
+		"-merge-policies",
+		"-policy-name=break-glass",
+		"-policy-ttl=4h",
+	})
+	require.Equal(0, code, ui.ErrorWriter.String())
+	require.Contains(ui.OutputWriter.String(), breakGlass.ID+" - break-glass (expires in ")
+
+	token, _, err = client.ACL().TokenRead(token.AccessorID, &api.QueryOptions{Token: "root"})
+	require.NoError(err)
+	require.Len(token.Policies, 2)
+	require.Nil(token.Policies[0].ExpirationTime)
+	require.NotNil(token.Policies[1].ExpirationTime)
+	expiration := *token.Policies[1].ExpirationTime
+
+	// Another update keeps the expiration of the link.
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	code = cmd.Run([]string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-id=" + token.AccessorID,
+		"-token=root",
+		"-merge-policies",
+		"-description=on-call token",
+	})
+	require.Equal(0, code, ui.ErrorWriter.String())
+
+	token, _, err = client.ACL().TokenRead(token.AccessorID, &api.QueryOptions{Token: "root"})
+	require.NoError(err)
+	require.Len(token.Policies, 2)
+	require.NotNil(token.Policies[1].ExpirationTime)
+	require.True(expiration.Equal(*token.Policies[1].ExpirationTime))
+}
@@ -95,6 +95,9 @@ func (c *cmd) readConfig() *config.RuntimeConfig {
 	for _, w := range b.Warnings {
 		c.UI.Warn(w)
 	}
+	for _, w := range config.Lint(&cfg) {
+		c.UI.Warn(fmt.Sprintf("lint: %s", w))
+	}
 	return &cfg
 }
 
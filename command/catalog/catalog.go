@@ -46,5 +46,13 @@ Usage: consul catalog <subcommand> [options] [args]
 
       $ consul catalog services
 
+  Show the recent health history of a service:
+
+      $ consul catalog history web
+
+  Send 10% of the clients of a service to its canary instances:
+
+      $ consul catalog routing set -tag canary -percentage 10 web
+
   For more examples, ask for subcommand help or view the documentation.
 `
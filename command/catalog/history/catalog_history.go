@@ -0,0 +1,138 @@
+package history
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// now is used to compute how long the current status has lasted, it
+	// can be overridden for testing.
+	now func() time.Time
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+	c.now = time.Now
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(fmt.Sprintf("Expected exactly one service name (got %d)", len(args)))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	history, _, err := client.Health().ServiceHistory(args[0], nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error retrieving health history: %s", err))
+		return 1
+	}
+
+	if len(history.Transitions) == 0 {
+		c.UI.Output(fmt.Sprintf("No health history recorded for service %q", args[0]))
+		return 0
+	}
+
+	c.UI.Output(c.format(history.Transitions))
+	return 0
+}
+
+// format prints one row per transition. The duration is how long the check
+// stayed in the new status, which is either until its next transition or, for
+// the latest one, until now.
+func (c *cmd) format(transitions []*api.HealthTransition) string {
+	type checkKey struct {
+		node, checkID string
+	}
+	next := make(map[checkKey]time.Time)
+	durations := make([]string, len(transitions))
+	for i := len(transitions) - 1; i >= 0; i-- {
+		t := transitions[i]
+		key := checkKey{t.Node, t.CheckID}
+
+		end, ok := next[key]
+		if !ok {
+			end = c.now()
+		}
+		durations[i] = end.Sub(t.Time).Truncate(time.Second).String()
+		if !ok {
+			durations[i] += " (current)"
+		}
+		next[key] = t.Time
+	}
+
+	result := []string{"Time|Node|ServiceID|CheckID|Status|Duration"}
+	for i, t := range transitions {
+		result = append(result, strings.Join([]string{
+			t.Time.Local().Format(time.RFC3339),
+			t.Node,
+			t.ServiceID,
+			t.CheckID,
+			fmt.Sprintf("%s -> %s", t.OldStatus, t.NewStatus),
+			durations[i],
+		}, "|"))
+	}
+
+	return columnize.SimpleFormat(result)
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Shows recent health status changes of a service"
+const help = `
+Usage: consul catalog history [options] SERVICE
+
+  Shows the recent health check status transitions of all instances of a
+  service, oldest first, along with how long each status lasted.
+
+  The history is kept in memory by the Consul servers and is only advisory:
+  it is not replicated, starts out empty when a server restarts and may
+  differ after a leader election. The number of transitions kept per service
+  is controlled by the health_history_retention server option.
+
+  To show the history of the "web" service:
+
+      $ consul catalog history web
+
+  For a full list of options and examples, please see the Consul documentation.
+`
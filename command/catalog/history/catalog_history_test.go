@@ -0,0 +1,115 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+)
+
+func TestCatalogHistoryCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCatalogHistoryCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	code := c.Run([]string{})
+	if code == 0 {
+		t.Fatal("expected non-zero exit")
+	}
+	if got, want := ui.ErrorWriter.String(), "Expected exactly one service name"; !strings.Contains(got, want) {
+		t.Fatalf("expected %q to contain %q", got, want)
+	}
+}
+
+func TestCatalogHistoryCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	for _, status := range []string{api.HealthPassing, api.HealthCritical, api.HealthPassing} {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      "web1",
+				Service: "web",
+			},
+			Check: &structs.HealthCheck{
+				Node:      "foo",
+				Name:      "web check",
+				CheckID:   "web-check",
+				ServiceID: "web1",
+				Status:    status,
+			},
+		}
+		var out struct{}
+		if err := a.RPC("Catalog.Register", args, &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "web"})
+	if code != 0 {
+		t.Fatalf("bad exit code %d: %s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{"passing -> critical", "critical -> passing", "web-check", "(current)"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q to contain %q", output, expected)
+		}
+	}
+
+	// A service without history isn't an error.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-http-addr=" + a.HTTPAddr(), "db"})
+	if code != 0 {
+		t.Fatalf("bad exit code %d: %s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); !strings.Contains(output, "No health history recorded") {
+		t.Errorf("bad output: %q", output)
+	}
+	if ui.ErrorWriter.String() != "" {
+		t.Errorf("unexpected error output: %q", ui.ErrorWriter.String())
+	}
+}
+
+func TestCatalogHistoryCommand_durations(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	c := New(nil)
+	c.now = func() time.Time { return start.Add(time.Hour) }
+
+	output := c.format([]*api.HealthTransition{
+		{Time: start, Node: "foo", CheckID: "a", OldStatus: "passing", NewStatus: "critical"},
+		{Time: start.Add(time.Minute), Node: "bar", CheckID: "a", OldStatus: "passing", NewStatus: "critical"},
+		{Time: start.Add(2 * time.Minute), Node: "foo", CheckID: "a", OldStatus: "critical", NewStatus: "passing"},
+	})
+
+	lines := strings.Split(output, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("bad: %q", output)
+	}
+	for i, expected := range []string{"2m0s", "59m0s (current)", "58m0s (current)"} {
+		if !strings.HasSuffix(strings.TrimSpace(lines[i+1]), expected) {
+			t.Errorf("expected %q to end with %q", lines[i+1], expected)
+		}
+	}
+}
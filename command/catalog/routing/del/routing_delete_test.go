@@ -0,0 +1,44 @@
+package del
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogRoutingDeleteCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCatalogRoutingDeleteCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	_, err := client.Catalog().SetRouting(&api.ServiceRouting{
+		Name:       "web",
+		ForceTag:   "canary",
+		Percentage: 10,
+	}, nil)
+	require.NoError(t, err)
+
+	ui := cli.NewMockUi()
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"web",
+	}
+	code := New(ui).Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	routing, _, err := client.Catalog().Routing("web", nil)
+	require.NoError(t, err)
+	require.Nil(t, routing)
+}
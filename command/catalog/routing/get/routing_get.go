@@ -0,0 +1,91 @@
+package get
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Error! Missing SERVICE argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	routing, _, err := client.Catalog().Routing(args[0], &api.QueryOptions{
+		AllowStale: c.http.Stale(),
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error querying routing of service %s: %s", args[0], err))
+		return 1
+	}
+	if routing == nil {
+		c.UI.Error(fmt.Sprintf("Error! No routing override for service: %s", args[0]))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Service:    %s", routing.Name))
+	c.UI.Info(fmt.Sprintf("Tag:        %s", routing.ForceTag))
+	c.UI.Info(fmt.Sprintf("Percentage: %d", routing.Percentage))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Shows the routing override of a service"
+const help = `
+Usage: consul catalog routing get [options] SERVICE
+
+  Shows the routing override of a service. The command fails if the service
+  has no override.
+
+      $ consul catalog routing get web
+`
@@ -0,0 +1,49 @@
+package get
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogRoutingGetCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCatalogRoutingGetCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"web",
+	}
+
+	// Fails without an override
+	ui := cli.NewMockUi()
+	require.Equal(t, 1, New(ui).Run(args))
+	require.Contains(t, ui.ErrorWriter.String(), "No routing override")
+
+	_, err := client.Catalog().SetRouting(&api.ServiceRouting{
+		Name:       "web",
+		ForceTag:   "canary",
+		Percentage: 10,
+	}, nil)
+	require.NoError(t, err)
+
+	ui = cli.NewMockUi()
+	code := New(ui).Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Contains(t, output, "canary")
+	require.Contains(t, output, "10")
+}
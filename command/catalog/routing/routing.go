@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New() *cmd {
+	return &cmd{}
+}
+
+type cmd struct{}
+
+func (c *cmd) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(help, nil)
+}
+
+const synopsis = "Manage the routing overrides of services"
+const help = `
+Usage: consul catalog routing <subcommand> [options] SERVICE
+
+  This command has subcommands for managing the routing override of a
+  service. While an override is set, a percentage of the clients resolving
+  the service through DNS, or through the health endpoint with
+  ?routing=apply, only get the instances with a given tag. This is useful
+  to send part of the traffic to canary instances.
+
+  Send 10% of the clients to the instances of "web" tagged "canary":
+
+      $ consul catalog routing set -tag canary -percentage 10 web
+
+  Show the routing override of "web":
+
+      $ consul catalog routing get web
+
+  Remove it:
+
+      $ consul catalog routing delete web
+
+  For more examples, ask for subcommand help or view the documentation.
+`
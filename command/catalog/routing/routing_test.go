@@ -0,0 +1,13 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogRoutingCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New().Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
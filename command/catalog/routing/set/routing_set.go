@@ -0,0 +1,110 @@
+package set
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	tag        string
+	percentage int
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.tag, "tag", "",
+		"Tag of the instances to send the clients to. This is required.")
+	c.flags.IntVar(&c.percentage, "percentage", 100,
+		"Percentage of the clients, between 0 and 100, that only get the "+
+			"instances with the tag. The default value is 100.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Error! Missing SERVICE argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	if c.tag == "" {
+		c.UI.Error("Must specify the -tag flag")
+		return 1
+	}
+	if c.percentage < 0 || c.percentage > 100 {
+		c.UI.Error("The -percentage flag must be between 0 and 100")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	routing := &api.ServiceRouting{
+		Name:       args[0],
+		ForceTag:   c.tag,
+		Percentage: c.percentage,
+	}
+	if _, err := client.Catalog().SetRouting(routing, nil); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting routing of service %s: %s", args[0], err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Success! Sending %d%% of the clients of %s to the instances tagged %q",
+		c.percentage, args[0], c.tag))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Sets the routing override of a service"
+const help = `
+Usage: consul catalog routing set [options] SERVICE
+
+  Sets the routing override of a service. The given percentage of the
+  clients resolving the service only get the instances with the given tag.
+  Clients are picked by hashing their address, so a client consistently
+  gets the same answer. If no healthy instance has the tag, every instance
+  is returned.
+
+  Send 10% of the clients to the instances of "web" tagged "canary":
+
+      $ consul catalog routing set -tag canary -percentage 10 web
+`
@@ -0,0 +1,88 @@
+package set
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogRoutingSetCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCatalogRoutingSetCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no service": {
+			[]string{"-tag", "canary"},
+			"Missing SERVICE argument",
+		},
+		"extra args": {
+			[]string{"-tag", "canary", "foo", "bar"},
+			"Too many arguments",
+		},
+		"no tag": {
+			[]string{"web"},
+			"Must specify the -tag flag",
+		},
+		"bad percentage": {
+			[]string{"-tag", "canary", "-percentage", "101", "web"},
+			"must be between 0 and 100",
+		},
+	}
+
+	for name, tc := range cases {
+		c.init()
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestCatalogRoutingSetCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-tag", "canary",
+		"-percentage", "10",
+		"web",
+	}
+	code := c.Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	routing, _, err := client.Catalog().Routing("web", nil)
+	require.NoError(t, err)
+	require.NotNil(t, routing)
+	require.Equal(t, "canary", routing.ForceTag)
+	require.Equal(t, 10, routing.Percentage)
+}
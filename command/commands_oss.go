@@ -4,13 +4,18 @@ import (
 	"github.com/hashicorp/consul/command/acl"
 	aclagent "github.com/hashicorp/consul/command/acl/agenttokens"
 	aclbootstrap "github.com/hashicorp/consul/command/acl/bootstrap"
+	aclmigrate "github.com/hashicorp/consul/command/acl/migrate"
+	aclmapply "github.com/hashicorp/consul/command/acl/migrate/apply"
+	aclmplan "github.com/hashicorp/consul/command/acl/migrate/plan"
 	aclpolicy "github.com/hashicorp/consul/command/acl/policy"
 	aclpcreate "github.com/hashicorp/consul/command/acl/policy/create"
 	aclpdelete "github.com/hashicorp/consul/command/acl/policy/delete"
 	aclplist "github.com/hashicorp/consul/command/acl/policy/list"
+	aclporphans "github.com/hashicorp/consul/command/acl/policy/orphans"
 	aclpread "github.com/hashicorp/consul/command/acl/policy/read"
 	aclpupdate "github.com/hashicorp/consul/command/acl/policy/update"
 	aclrules "github.com/hashicorp/consul/command/acl/rules"
+	aclsimulate "github.com/hashicorp/consul/command/acl/simulate"
 	acltoken "github.com/hashicorp/consul/command/acl/token"
 	acltclone "github.com/hashicorp/consul/command/acl/token/clone"
 	acltcreate "github.com/hashicorp/consul/command/acl/token/create"
@@ -20,17 +25,26 @@ import (
 	acltupdate "github.com/hashicorp/consul/command/acl/token/update"
 	"github.com/hashicorp/consul/command/agent"
 	"github.com/hashicorp/consul/command/catalog"
+	cathistory "github.com/hashicorp/consul/command/catalog/history"
 	catlistdc "github.com/hashicorp/consul/command/catalog/list/dc"
 	catlistnodes "github.com/hashicorp/consul/command/catalog/list/nodes"
 	catlistsvc "github.com/hashicorp/consul/command/catalog/list/services"
+	catrouting "github.com/hashicorp/consul/command/catalog/routing"
+	catroutingdel "github.com/hashicorp/consul/command/catalog/routing/del"
+	catroutingget "github.com/hashicorp/consul/command/catalog/routing/get"
+	catroutingset "github.com/hashicorp/consul/command/catalog/routing/set"
 	"github.com/hashicorp/consul/command/connect"
 	"github.com/hashicorp/consul/command/connect/ca"
 	caget "github.com/hashicorp/consul/command/connect/ca/get"
 	caset "github.com/hashicorp/consul/command/connect/ca/set"
+	"github.com/hashicorp/consul/command/connect/doctor"
 	"github.com/hashicorp/consul/command/connect/envoy"
 	"github.com/hashicorp/consul/command/connect/proxy"
 	"github.com/hashicorp/consul/command/debug"
+	"github.com/hashicorp/consul/command/dns"
+	dnssimulate "github.com/hashicorp/consul/command/dns/simulate"
 	"github.com/hashicorp/consul/command/event"
+	eventwatch "github.com/hashicorp/consul/command/event/watch"
 	"github.com/hashicorp/consul/command/exec"
 	"github.com/hashicorp/consul/command/forceleave"
 	"github.com/hashicorp/consul/command/info"
@@ -48,7 +62,9 @@ import (
 	kvexp "github.com/hashicorp/consul/command/kv/exp"
 	kvget "github.com/hashicorp/consul/command/kv/get"
 	kvimp "github.com/hashicorp/consul/command/kv/imp"
+	kvls "github.com/hashicorp/consul/command/kv/ls"
 	kvput "github.com/hashicorp/consul/command/kv/put"
+	kvrestorekey "github.com/hashicorp/consul/command/kv/restorekey"
 	"github.com/hashicorp/consul/command/leave"
 	"github.com/hashicorp/consul/command/lock"
 	"github.com/hashicorp/consul/command/maint"
@@ -65,6 +81,8 @@ import (
 	"github.com/hashicorp/consul/command/rtt"
 	"github.com/hashicorp/consul/command/services"
 	svcsderegister "github.com/hashicorp/consul/command/services/deregister"
+	svcsexp "github.com/hashicorp/consul/command/services/exp"
+	svcsimp "github.com/hashicorp/consul/command/services/imp"
 	svcsregister "github.com/hashicorp/consul/command/services/register"
 	"github.com/hashicorp/consul/command/snapshot"
 	snapinspect "github.com/hashicorp/consul/command/snapshot/inspect"
@@ -94,10 +112,15 @@ func init() {
 	Register("acl policy", func(cli.Ui) (cli.Command, error) { return aclpolicy.New(), nil })
 	Register("acl policy create", func(ui cli.Ui) (cli.Command, error) { return aclpcreate.New(ui), nil })
 	Register("acl policy list", func(ui cli.Ui) (cli.Command, error) { return aclplist.New(ui), nil })
+	Register("acl policy orphans", func(ui cli.Ui) (cli.Command, error) { return aclporphans.New(ui), nil })
 	Register("acl policy read", func(ui cli.Ui) (cli.Command, error) { return aclpread.New(ui), nil })
 	Register("acl policy update", func(ui cli.Ui) (cli.Command, error) { return aclpupdate.New(ui), nil })
 	Register("acl policy delete", func(ui cli.Ui) (cli.Command, error) { return aclpdelete.New(ui), nil })
+	Register("acl migrate", func(cli.Ui) (cli.Command, error) { return aclmigrate.New(), nil })
+	Register("acl migrate plan", func(ui cli.Ui) (cli.Command, error) { return aclmplan.New(ui), nil })
+	Register("acl migrate apply", func(ui cli.Ui) (cli.Command, error) { return aclmapply.New(ui), nil })
 	Register("acl translate-rules", func(ui cli.Ui) (cli.Command, error) { return aclrules.New(ui), nil })
+	Register("acl simulate", func(ui cli.Ui) (cli.Command, error) { return aclsimulate.New(ui), nil })
 	Register("acl set-agent-token", func(ui cli.Ui) (cli.Command, error) { return aclagent.New(ui), nil })
 	Register("acl token", func(cli.Ui) (cli.Command, error) { return acltoken.New(), nil })
 	Register("acl token create", func(ui cli.Ui) (cli.Command, error) { return acltcreate.New(ui), nil })
@@ -111,7 +134,12 @@ func init() {
 	})
 	Register("catalog", func(cli.Ui) (cli.Command, error) { return catalog.New(), nil })
 	Register("catalog datacenters", func(ui cli.Ui) (cli.Command, error) { return catlistdc.New(ui), nil })
+	Register("catalog history", func(ui cli.Ui) (cli.Command, error) { return cathistory.New(ui), nil })
 	Register("catalog nodes", func(ui cli.Ui) (cli.Command, error) { return catlistnodes.New(ui), nil })
+	Register("catalog routing", func(cli.Ui) (cli.Command, error) { return catrouting.New(), nil })
+	Register("catalog routing delete", func(ui cli.Ui) (cli.Command, error) { return catroutingdel.New(ui), nil })
+	Register("catalog routing get", func(ui cli.Ui) (cli.Command, error) { return catroutingget.New(ui), nil })
+	Register("catalog routing set", func(ui cli.Ui) (cli.Command, error) { return catroutingset.New(ui), nil })
 	Register("catalog services", func(ui cli.Ui) (cli.Command, error) { return catlistsvc.New(ui), nil })
 	Register("connect", func(ui cli.Ui) (cli.Command, error) { return connect.New(), nil })
 	Register("connect ca", func(ui cli.Ui) (cli.Command, error) { return ca.New(), nil })
@@ -119,8 +147,12 @@ func init() {
 	Register("connect ca set-config", func(ui cli.Ui) (cli.Command, error) { return caset.New(ui), nil })
 	Register("connect proxy", func(ui cli.Ui) (cli.Command, error) { return proxy.New(ui, MakeShutdownCh()), nil })
 	Register("connect envoy", func(ui cli.Ui) (cli.Command, error) { return envoy.New(ui), nil })
+	Register("connect doctor", func(ui cli.Ui) (cli.Command, error) { return doctor.New(ui), nil })
 	Register("debug", func(ui cli.Ui) (cli.Command, error) { return debug.New(ui, MakeShutdownCh()), nil })
+	Register("dns", func(cli.Ui) (cli.Command, error) { return dns.New(), nil })
+	Register("dns simulate", func(ui cli.Ui) (cli.Command, error) { return dnssimulate.New(ui), nil })
 	Register("event", func(ui cli.Ui) (cli.Command, error) { return event.New(ui), nil })
+	Register("event watch", func(ui cli.Ui) (cli.Command, error) { return eventwatch.New(ui, MakeShutdownCh()), nil })
 	Register("exec", func(ui cli.Ui) (cli.Command, error) { return exec.New(ui, MakeShutdownCh()), nil })
 	Register("force-leave", func(ui cli.Ui) (cli.Command, error) { return forceleave.New(ui), nil })
 	Register("info", func(ui cli.Ui) (cli.Command, error) { return info.New(ui), nil })
@@ -138,7 +170,9 @@ func init() {
 	Register("kv export", func(ui cli.Ui) (cli.Command, error) { return kvexp.New(ui), nil })
 	Register("kv get", func(ui cli.Ui) (cli.Command, error) { return kvget.New(ui), nil })
 	Register("kv import", func(ui cli.Ui) (cli.Command, error) { return kvimp.New(ui), nil })
+	Register("kv ls", func(ui cli.Ui) (cli.Command, error) { return kvls.New(ui), nil })
 	Register("kv put", func(ui cli.Ui) (cli.Command, error) { return kvput.New(ui), nil })
+	Register("kv restore-key", func(ui cli.Ui) (cli.Command, error) { return kvrestorekey.New(ui), nil })
 	Register("leave", func(ui cli.Ui) (cli.Command, error) { return leave.New(ui), nil })
 	Register("lock", func(ui cli.Ui) (cli.Command, error) { return lock.New(ui), nil })
 	Register("maint", func(ui cli.Ui) (cli.Command, error) { return maint.New(ui), nil })
@@ -156,6 +190,8 @@ func init() {
 	Register("services", func(cli.Ui) (cli.Command, error) { return services.New(), nil })
 	Register("services register", func(ui cli.Ui) (cli.Command, error) { return svcsregister.New(ui), nil })
 	Register("services deregister", func(ui cli.Ui) (cli.Command, error) { return svcsderegister.New(ui), nil })
+	Register("services export", func(ui cli.Ui) (cli.Command, error) { return svcsexp.New(ui), nil })
+	Register("services import", func(ui cli.Ui) (cli.Command, error) { return svcsimp.New(ui), nil })
 	Register("snapshot", func(cli.Ui) (cli.Command, error) { return snapshot.New(), nil })
 	Register("snapshot inspect", func(ui cli.Ui) (cli.Command, error) { return snapinspect.New(ui), nil })
 	Register("snapshot restore", func(ui cli.Ui) (cli.Command, error) { return snaprestore.New(ui), nil })
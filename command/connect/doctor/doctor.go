@@ -0,0 +1,122 @@
+package doctor
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(c.flags.Args())))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	mismatches, _, err := client.Health().ConnectMismatches(&api.QueryOptions{
+		AllowStale: c.http.Stale(),
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error retrieving Connect mismatches: %s", err))
+		return 1
+	}
+
+	if len(mismatches) == 0 {
+		c.UI.Info("No problems found")
+		return 0
+	}
+
+	c.UI.Output(c.format(mismatches))
+
+	// Exit with an error so the command can be used in scripts.
+	return 2
+}
+
+// format prints one row per mismatch, naming the critical checks of the
+// sidecar when there are any.
+func (c *cmd) format(mismatches []*api.ConnectMismatch) string {
+	result := []string{"Node|Service|ServiceID|Sidecar|Problem|Checks"}
+	for _, m := range mismatches {
+		checks := make([]string, 0, len(m.Checks))
+		for _, check := range m.Checks {
+			checks = append(checks, check.CheckID)
+		}
+		result = append(result, strings.Join([]string{
+			m.Node,
+			m.ServiceName,
+			m.ServiceID,
+			m.ProxyServiceID,
+			m.Reason,
+			strings.Join(checks, ","),
+		}, "|"))
+	}
+	return columnize.SimpleFormat(result)
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Finds services that can't be reached because of their sidecar proxy"
+const help = `
+Usage: consul connect doctor [options]
+
+  Lists the service instances that can't be reached through Connect because
+  their sidecar proxy is missing or critical, along with sidecar proxies left
+  behind after their service was deregistered.
+
+  Whether an instance was meant to have a sidecar isn't recorded in the
+  catalog, so an instance is only reported as missing its sidecar when other
+  instances of the same service have one. The problems reported are:
+
+    sidecar-missing      The instance has no sidecar proxy on its node.
+    sidecar-critical     All the sidecar proxies of the instance are critical.
+    destination-missing  The sidecar proxy's service isn't registered on its
+                         node.
+
+  The command exits with status 2 when any problem is found.
+
+      $ consul connect doctor
+
+  For a full list of options and examples, please see the Consul documentation.
+`
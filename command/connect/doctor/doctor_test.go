@@ -0,0 +1,44 @@
+package doctor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectDoctorCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestConnectDoctorCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{"-http-addr=" + a.HTTPAddr()}
+	require.Equal(t, 0, c.Run(args), ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "No problems found")
+
+	// Register a sidecar without its service.
+	reg := structs.TestRegisterRequestProxy(t)
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", reg, &out))
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	require.Equal(t, 2, c.Run(args), ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Contains(t, output, reg.Service.ID)
+	require.Contains(t, output, structs.ConnectMismatchDestinationMissing)
+}
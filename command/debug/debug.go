@@ -259,13 +259,17 @@ func (c *cmd) prepare() (version string, err error) {
 		return version, fmt.Errorf("agent response did not contain debug key")
 	}
 
+	// With ACLs enabled the profiling endpoints are available to operator:read
+	// tokens regardless of enable_debug.
+	aclsEnabled, _ := self["DebugConfig"]["ACLsEnabled"].(bool)
+
 	// If none are specified we will collect information from
 	// all by default
 	if len(c.capture) == 0 {
 		c.capture = c.defaultTargets()
 	}
 
-	if !debugEnabled && c.configuredTarget("pprof") {
+	if !debugEnabled && !aclsEnabled && c.configuredTarget("pprof") {
 		cs := c.capture
 		for i := 0; i < len(cs); i++ {
 			if cs[i] == "pprof" {
@@ -273,7 +277,7 @@ func (c *cmd) prepare() (version string, err error) {
 				i--
 			}
 		}
-		c.UI.Warn("[WARN] Unable to capture pprof. Set enable_debug to true on target agent to enable profiling.")
+		c.UI.Warn("[WARN] Unable to capture pprof. Set enable_debug to true or enable ACLs on target agent to enable profiling.")
 	}
 
 	for _, t := range c.capture {
@@ -485,3 +485,53 @@ func TestDebugCommand_DebugDisabled(t *testing.T) {
 		t.Errorf("expected warn output, got %s", errOutput)
 	}
 }
+
+func TestDebugCommand_ProfilesWithACLs(t *testing.T) {
+	t.Parallel()
+
+	testDir := testutil.TempDir(t, "debug")
+	defer os.RemoveAll(testDir)
+
+	a := agent.NewTestAgent(t, t.Name(), `
+	enable_debug = false
+	acl_datacenter = "dc1"
+	acl_master_token = "root"
+	acl_default_policy = "deny"
+	`)
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	ui := cli.NewMockUi()
+	cmd := New(ui, nil)
+	cmd.validateTiming = false
+
+	outputPath := fmt.Sprintf("%s/debug", testDir)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+		"-output=" + outputPath,
+		"-archive=false",
+		"-capture=pprof",
+		// CPU profile has a minimum of 1s
+		"-duration=1s",
+		"-interval=1s",
+	}
+
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("should exit 0, got code: %d\n%s", code, ui.ErrorWriter.String())
+	}
+
+	profiles := []string{"heap.prof", "profile.prof", "goroutine.prof", "trace.out"}
+	for _, v := range profiles {
+		fs, _ := filepath.Glob(fmt.Sprintf("%s/*/%s", outputPath, v))
+		if len(fs) == 0 {
+			t.Errorf("output data should exist for %s", v)
+		}
+	}
+
+	errOutput := ui.ErrorWriter.String()
+	if strings.Contains(errOutput, "Unable to capture pprof") {
+		t.Errorf("expected no warn output, got %s", errOutput)
+	}
+}
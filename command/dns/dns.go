@@ -0,0 +1,36 @@
+package dns
+
+import (
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New() *cmd {
+	return &cmd{}
+}
+
+type cmd struct{}
+
+func (c *cmd) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(help, nil)
+}
+
+const synopsis = "Interact with the DNS interface of the agent"
+const help = `
+Usage: consul dns <subcommand> [options] [args]
+
+  This command has subcommands for debugging the DNS interface of the local
+  agent. Simulate a lookup and show the decisions made to answer it:
+
+      $ consul dns simulate web.service.consul
+
+  For more examples, ask for subcommand help or view the documentation.
+`
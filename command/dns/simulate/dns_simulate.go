@@ -0,0 +1,116 @@
+package simulate
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+	qtype string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.qtype, "type", "A",
+		"The record type to look up, like A, AAAA, SRV or TXT.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing NAME argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	sim, err := client.Agent().DNSSimulate(args[0], c.qtype, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error simulating the DNS lookup: %s", err))
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Name:      %s", sim.Name))
+	c.UI.Output(fmt.Sprintf("Type:      %s", sim.Type))
+	c.UI.Output(fmt.Sprintf("Network:   %s", sim.Network))
+	c.UI.Output(fmt.Sprintf("Rcode:     %s", sim.Rcode))
+	c.UI.Output(fmt.Sprintf("Truncated: %t", sim.Truncated))
+
+	c.UI.Output("\nTrace:")
+	for i, step := range sim.Trace {
+		c.UI.Output(fmt.Sprintf("  %d. %s", i+1, step))
+	}
+	c.outputRecords("Answer", sim.Answer)
+	c.outputRecords("Authority", sim.Ns)
+	c.outputRecords("Additional", sim.Extra)
+	return 0
+}
+
+// outputRecords prints a section of the response, if it has records.
+func (c *cmd) outputRecords(section string, records []string) {
+	if len(records) == 0 {
+		return
+	}
+	c.UI.Output(fmt.Sprintf("\n%s:", section))
+	for _, rr := range records {
+		c.UI.Output("  " + strings.Replace(rr, "\t", " ", -1))
+	}
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Simulate a DNS lookup and show how it's answered"
+const help = `
+Usage: consul dns simulate [options] NAME
+
+  Resolves a name in the Consul domain the same way the DNS interface of the
+  agent does, without making a DNS query, and shows the records of the answer
+  along with the decisions made to produce it: the instances found, how many
+  were filtered on health, whether the response was trimmed and the TTL used.
+
+  Simulate an A lookup of a service:
+
+      $ consul dns simulate web.service.consul
+
+  Simulate an SRV lookup of a service with a tag:
+
+      $ consul dns simulate -type=SRV primary.db.service.consul
+`
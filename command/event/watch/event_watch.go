@@ -0,0 +1,205 @@
+package eventwatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/exec"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+// retryInterval is how long to wait before polling the event list again
+// after an error.
+const retryInterval = 5 * time.Second
+
+func New(ui cli.Ui, shutdownCh <-chan struct{}) *cmd {
+	c := &cmd{UI: ui, shutdownCh: shutdownCh}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	shutdownCh <-chan struct{}
+
+	// flags
+	name string
+	exec string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.name, "name", "",
+		"Name of the events to watch. If empty, all the events the token can "+
+			"read are watched.")
+	c.flags.StringVar(&c.exec, "exec", "",
+		"Command to run for each new event, with the payload of the event on "+
+			"stdin. If empty, the events are printed as JSON.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(c.flags.Args())))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.shutdownCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Only the events fired from now on are handled, so start from the
+	// ones the agent already knows about.
+	events := client.Event()
+	list, meta, err := events.List(c.name, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error listing events: %s", err))
+		return 1
+	}
+	index, lastID := meta.LastIndex, lastEventID(list)
+
+	for {
+		q := (&api.QueryOptions{WaitIndex: index}).WithContext(ctx)
+		list, meta, err := events.List(c.name, q)
+		if ctx.Err() != nil {
+			return 0
+		}
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error listing events: %s", err))
+			select {
+			case <-time.After(retryInterval):
+				continue
+			case <-ctx.Done():
+				return 0
+			}
+		}
+
+		for _, event := range newEvents(list, lastID) {
+			c.handle(event)
+		}
+		index, lastID = meta.LastIndex, lastEventID(list)
+	}
+}
+
+// handle runs the handler for an event, or prints it if there is none. A
+// failing handler is reported but doesn't stop the watch.
+func (c *cmd) handle(event *api.UserEvent) {
+	if c.exec == "" {
+		buf, err := json.Marshal(event)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error encoding event %s: %s", event.ID, err))
+			return
+		}
+		c.UI.Output(string(buf))
+		return
+	}
+
+	cmd, err := exec.Script(c.exec)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error creating handler for event %s: %s", event.ID, err))
+		return
+	}
+	cmd.Env = append(os.Environ(),
+		"CONSUL_EVENT_ID="+event.ID,
+		"CONSUL_EVENT_NAME="+event.Name,
+		"CONSUL_EVENT_LTIME="+strconv.FormatUint(event.LTime, 10),
+	)
+	cmd.Stdin = bytes.NewReader(event.Payload)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error starting handler for event %s: %s", event.ID, err))
+		return
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	agent.ForwardSignals(cmd, func(err error) {
+		c.UI.Error(fmt.Sprintf("Warning, could not forward signal: %s", err))
+	}, doneCh)
+
+	if err := cmd.Wait(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error running handler for event %s: %s", event.ID, err))
+	}
+}
+
+// lastEventID returns the ID of the most recent event of a list, or an
+// empty string if the list is empty.
+func lastEventID(events []*api.UserEvent) string {
+	if len(events) == 0 {
+		return ""
+	}
+	return events[len(events)-1].ID
+}
+
+// newEvents returns the events of a list that came after the event with the
+// given ID. The list is a window over the most recent events, so if the
+// event isn't in it anymore all of them are new.
+func newEvents(events []*api.UserEvent, lastID string) []*api.UserEvent {
+	if lastID == "" {
+		return events
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ID == lastID {
+			return events[i+1:]
+		}
+	}
+	return events
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Watch for new events"
+const help = `
+Usage: consul event watch [options]
+
+  Watches for new events, optionally with a given name, and runs a handler
+  for each of them with the payload of the event on stdin. The ID, name and
+  Lamport time of the event are given in the CONSUL_EVENT_ID,
+  CONSUL_EVENT_NAME and CONSUL_EVENT_LTIME environment variables.
+
+  Only the events fired after the watch starts are handled, and only those
+  the token is allowed to read.
+
+      $ consul event watch -name=deploy -exec=/bin/handler
+
+  Without a handler, the events are printed as JSON lines.
+`
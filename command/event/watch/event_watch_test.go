@@ -0,0 +1,102 @@
+package eventwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventWatchCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi(), nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestEventWatchCommand_newEvents(t *testing.T) {
+	t.Parallel()
+
+	a, b, c := &api.UserEvent{ID: "a"}, &api.UserEvent{ID: "b"}, &api.UserEvent{ID: "c"}
+	require.Equal(t, []*api.UserEvent{a, b, c}, newEvents([]*api.UserEvent{a, b, c}, ""))
+	require.Equal(t, []*api.UserEvent{b, c}, newEvents([]*api.UserEvent{a, b, c}, "a"))
+	require.Empty(t, newEvents([]*api.UserEvent{a, b, c}, "c"))
+
+	// The last event seen was pushed out of the window.
+	require.Equal(t, []*api.UserEvent{b, c}, newEvents([]*api.UserEvent{b, c}, "z"))
+}
+
+func TestEventWatchCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	client := a.Client()
+
+	// An event fired before the watch starts is not handled.
+	_, _, err := client.Event().Fire(&api.UserEvent{Name: "deploy", Payload: []byte("old")}, nil)
+	require.NoError(t, err)
+	retry.Run(t, func(r *retry.R) {
+		list, _, err := client.Event().List("deploy", nil)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if len(list) != 1 {
+			r.Fatalf("bad: %v", list)
+		}
+	})
+
+	dir := testutil.TempDir(t, "event-watch")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	ui := cli.NewMockUi()
+	shutdownCh := make(chan struct{})
+	cmd := New(ui, shutdownCh)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-name=deploy",
+		"-exec=cat >> " + out + " && echo \" $CONSUL_EVENT_NAME\" >> " + out,
+	}
+
+	codeCh := make(chan int, 1)
+	go func() {
+		codeCh <- cmd.Run(args)
+	}()
+
+	// Keep firing until the watch picks an event up, since it may not have
+	// listed the existing events yet.
+	retry.Run(t, func(r *retry.R) {
+		if _, _, err := client.Event().Fire(&api.UserEvent{Name: "other", Payload: []byte("nope")}, nil); err != nil {
+			r.Fatal(err)
+		}
+		if _, _, err := client.Event().Fire(&api.UserEvent{Name: "deploy", Payload: []byte("new")}, nil); err != nil {
+			r.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if !strings.Contains(string(data), "new deploy\n") {
+			r.Fatalf("bad: %q", data)
+		}
+	})
+
+	close(shutdownCh)
+	require.Equal(t, 0, <-codeCh, ui.ErrorWriter.String())
+
+	data, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "old")
+	require.NotContains(t, string(data), "nope")
+}
@@ -9,6 +9,7 @@ import (
 type HTTPFlags struct {
 	// client api flags
 	address       StringValue
+	pathPrefix    StringValue
 	token         StringValue
 	caFile        StringValue
 	caPath        StringValue
@@ -29,6 +30,10 @@ func (f *HTTPFlags) ClientFlags() *flag.FlagSet {
 			"also be specified via the CONSUL_HTTP_ADDR environment variable. The "+
 			"default value is http://127.0.0.1:8500. The scheme can also be set to "+
 			"HTTPS by setting the environment variable CONSUL_HTTP_SSL=true.")
+	fs.Var(&f.pathPrefix, "http-path-prefix",
+		"The path prefix the Consul HTTP API is served under, for agents "+
+			"configured with http_config.path_prefix or behind a proxy. This can "+
+			"also be specified via the CONSUL_HTTP_PATH_PREFIX environment variable.")
 	fs.Var(&f.token, "token",
 		"ACL token to use in the request. This can also be specified via the "+
 			"CONSUL_HTTP_TOKEN environment variable. If unspecified, the query will "+
@@ -98,6 +103,7 @@ func (f *HTTPFlags) APIClient() (*api.Client, error) {
 
 func (f *HTTPFlags) MergeOntoConfig(c *api.Config) {
 	f.address.Merge(&c.Address)
+	f.pathPrefix.Merge(&c.PathPrefix)
 	f.token.Merge(&c.Token)
 	f.caFile.Merge(&c.TLSConfig.CAFile)
 	f.caPath.Merge(&c.TLSConfig.CAPath)
@@ -0,0 +1,257 @@
+package info
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// The statuses of a check, in increasing order of severity.
+const (
+	statusPass = "PASS"
+	statusWarn = "WARN"
+	statusFail = "FAIL"
+)
+
+var statusSeverity = map[string]int{
+	statusPass: 0,
+	statusWarn: 1,
+	statusFail: 2,
+}
+
+// statusExitCode is the exit code of "consul info -check" for the most severe
+// status of its checks. 1 is left to the errors running the command, so that
+// a probe can tell them apart from a verdict.
+var statusExitCode = map[string]int{
+	statusPass: 0,
+	statusWarn: 2,
+	statusFail: 3,
+}
+
+// checkResult is the verdict of one of the checks of "consul info -check".
+type checkResult struct {
+	Name   string
+	Status string
+	Output string
+}
+
+// checkReport is the output of "consul info -check".
+type checkReport struct {
+	Status string
+	Checks []checkResult
+}
+
+// newCheckReport sets the overall status of the report to the most severe
+// status of its checks.
+func newCheckReport(results []checkResult) checkReport {
+	report := checkReport{Status: statusPass, Checks: results}
+	for _, r := range results {
+		if statusSeverity[r.Status] > statusSeverity[report.Status] {
+			report.Status = r.Status
+		}
+	}
+	return report
+}
+
+// checkThresholds are the thresholds of the checks, set by the flags.
+type checkThresholds struct {
+	minMembers     int
+	maxLastContact time.Duration
+	dnsTimeout     time.Duration
+}
+
+// runChecks evaluates the checks against the response of /v1/agent/self.
+func runChecks(self map[string]map[string]interface{}, t checkThresholds) []checkResult {
+	stats := make(map[string]map[string]string)
+	if raw, ok := self["Stats"]; ok {
+		for section, values := range raw {
+			m, _ := values.(map[string]interface{})
+			stats[section] = make(map[string]string)
+			for k, v := range m {
+				stats[section][k] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	results := []checkResult{checkSerf(stats["serf_lan"], t.minMembers)}
+	if stats["consul"]["server"] == "true" {
+		results = append(results, checkRaft(stats["consul"], stats["raft"], t.maxLastContact))
+	} else {
+		results = append(results, checkServers(stats["consul"]))
+	}
+	results = append(results, checkACL(stats["consul"]))
+	results = append(results, checkDNS(self["DebugConfig"], t.dnsTimeout))
+	return results
+}
+
+// checkSerf compares the members of the LAN gossip pool that are alive with
+// the members the agent knows about.
+func checkSerf(stats map[string]string, minMembers int) checkResult {
+	r := checkResult{Name: "serf"}
+
+	members, err1 := strconv.Atoi(stats["members"])
+	failed, err2 := strconv.Atoi(stats["failed"])
+	left, err3 := strconv.Atoi(stats["left"])
+	if err1 != nil || err2 != nil || err3 != nil {
+		r.Status, r.Output = statusFail, "LAN gossip stats are missing"
+		return r
+	}
+
+	known := members - left
+	alive := known - failed
+	r.Output = fmt.Sprintf("%d of %d members alive", alive, known)
+	switch {
+	case alive < minMembers:
+		r.Status = statusFail
+		r.Output += fmt.Sprintf(", expected at least %d", minMembers)
+	case failed > 0:
+		r.Status = statusWarn
+	default:
+		r.Status = statusPass
+	}
+	return r
+}
+
+// checkRaft checks that a server has a leader that it heard from recently.
+func checkRaft(consul, raft map[string]string, maxLastContact time.Duration) checkResult {
+	r := checkResult{Name: "raft"}
+
+	if consul["leader"] == "true" {
+		r.Status, r.Output = statusPass, "this server is the leader"
+		return r
+	}
+	if consul["leader_addr"] == "" {
+		r.Status, r.Output = statusFail, "no known leader"
+		return r
+	}
+
+	lastContact := raft["last_contact"]
+	if lastContact == "never" || lastContact == "" {
+		r.Status, r.Output = statusFail, "never heard from the leader"
+		return r
+	}
+	d, err := time.ParseDuration(lastContact)
+	if err != nil {
+		r.Status, r.Output = statusFail, fmt.Sprintf("invalid last contact %q", lastContact)
+		return r
+	}
+
+	r.Output = fmt.Sprintf("last contact with the leader %s ago", d)
+	if d > maxLastContact {
+		r.Status = statusWarn
+		r.Output += fmt.Sprintf(", over %s", maxLastContact)
+	} else {
+		r.Status = statusPass
+	}
+	return r
+}
+
+// checkServers checks that a client agent knows of servers to send its
+// requests to.
+func checkServers(consul map[string]string) checkResult {
+	r := checkResult{Name: "servers"}
+
+	n, err := strconv.Atoi(consul["known_servers"])
+	if err != nil {
+		r.Status, r.Output = statusFail, "known servers stat is missing"
+		return r
+	}
+	r.Output = fmt.Sprintf("%d known servers", n)
+	if n == 0 {
+		r.Status = statusFail
+	} else {
+		r.Status = statusPass
+	}
+	return r
+}
+
+// checkACL reports the ACL mode of the agent. The token given to the command
+// has already been resolved by the agent to read its stats, so resolution
+// itself works by the time this runs.
+func checkACL(consul map[string]string) checkResult {
+	r := checkResult{Name: "acl"}
+
+	switch consul["acl"] {
+	case "disabled":
+		r.Status, r.Output = statusPass, "ACLs are disabled"
+	case "enabled":
+		r.Status, r.Output = statusPass, "token resolved"
+	case "legacy":
+		r.Status, r.Output = statusWarn, "token resolved, but ACLs are in legacy mode until all servers are upgraded"
+	default:
+		r.Status, r.Output = statusFail, "ACL mode is unknown"
+	}
+	return r
+}
+
+// checkDNS sends a query to the DNS interface of the agent. Any answer passes,
+// except for a server failure, which means the agent can't reach the servers.
+func checkDNS(debugConfig map[string]interface{}, timeout time.Duration) checkResult {
+	r := checkResult{Name: "dns"}
+
+	network, addr := dnsAddr(debugConfig["DNSAddrs"])
+	if addr == "" {
+		r.Status, r.Output = statusPass, "DNS interface is disabled"
+		return r
+	}
+	domain, _ := debugConfig["DNSDomain"].(string)
+	if domain == "" {
+		domain = "consul."
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn("consul.service."+domain), dns.TypeA)
+	c := &dns.Client{Net: network, Timeout: timeout}
+	in, _, err := c.Exchange(m, addr)
+	if err != nil {
+		r.Status, r.Output = statusFail, fmt.Sprintf("no answer from %s: %v", addr, err)
+		return r
+	}
+
+	r.Output = fmt.Sprintf("%s answered %s", addr, dns.RcodeToString[in.Rcode])
+	if in.Rcode == dns.RcodeServerFailure {
+		r.Status = statusWarn
+	} else {
+		r.Status = statusPass
+	}
+	return r
+}
+
+// dnsAddr picks the address to query from the DNSAddrs of the sanitized
+// runtime config, which are given as "udp://ip:port" and "tcp://ip:port". UDP
+// is preferred, and unspecified IPs are replaced with the loopback address.
+func dnsAddr(raw interface{}) (string, string) {
+	addrs, _ := raw.([]interface{})
+	var network, addr string
+	for _, a := range addrs {
+		s, _ := a.(string)
+		parts := strings.SplitN(s, "://", 2)
+		if len(parts) != 2 || (parts[0] != "udp" && parts[0] != "tcp") {
+			continue
+		}
+		if addr != "" && network == "udp" {
+			continue
+		}
+		network, addr = parts[0], parts[1]
+	}
+	if addr == "" {
+		return "", ""
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", ""
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		if ip.To4() != nil {
+			host = "127.0.0.1"
+		} else {
+			host = "::1"
+		}
+	}
+	return network, net.JoinHostPort(host, port)
+}
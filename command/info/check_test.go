@@ -0,0 +1,188 @@
+package info
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCheckReport(t *testing.T) {
+	t.Parallel()
+	pass := checkResult{Name: "serf", Status: statusPass}
+	warn := checkResult{Name: "acl", Status: statusWarn}
+	fail := checkResult{Name: "dns", Status: statusFail}
+
+	cases := []struct {
+		results []checkResult
+		status  string
+		code    int
+	}{
+		{nil, statusPass, 0},
+		{[]checkResult{pass, pass}, statusPass, 0},
+		{[]checkResult{pass, warn}, statusWarn, 2},
+		{[]checkResult{fail, warn, pass}, statusFail, 3},
+	}
+	for _, tc := range cases {
+		report := newCheckReport(tc.results)
+		require.Equal(t, tc.status, report.Status)
+		require.Equal(t, tc.code, statusExitCode[report.Status])
+	}
+}
+
+func TestCheckSerf(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		stats      map[string]string
+		minMembers int
+		status     string
+		output     string
+	}{
+		"all alive": {
+			map[string]string{"members": "3", "failed": "0", "left": "0"},
+			1, statusPass, "3 of 3 members alive",
+		},
+		"left members are ignored": {
+			map[string]string{"members": "4", "failed": "0", "left": "1"},
+			3, statusPass, "3 of 3 members alive",
+		},
+		"failed member": {
+			map[string]string{"members": "3", "failed": "1", "left": "0"},
+			1, statusWarn, "2 of 3 members alive",
+		},
+		"too few alive": {
+			map[string]string{"members": "3", "failed": "2", "left": "0"},
+			2, statusFail, "1 of 3 members alive, expected at least 2",
+		},
+		"missing stats": {
+			map[string]string{},
+			1, statusFail, "LAN gossip stats are missing",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := checkSerf(tc.stats, tc.minMembers)
+			require.Equal(t, tc.status, r.Status)
+			require.Equal(t, tc.output, r.Output)
+		})
+	}
+}
+
+func TestCheckRaft(t *testing.T) {
+	t.Parallel()
+
+	follower := map[string]string{"leader": "false", "leader_addr": "10.0.0.1:8300"}
+	cases := map[string]struct {
+		consul map[string]string
+		raft   map[string]string
+		status string
+	}{
+		"leader": {
+			map[string]string{"leader": "true", "leader_addr": "10.0.0.2:8300"},
+			map[string]string{"last_contact": "0"},
+			statusPass,
+		},
+		"recent contact": {
+			follower, map[string]string{"last_contact": "50ms"}, statusPass,
+		},
+		"late contact": {
+			follower, map[string]string{"last_contact": "1.5s"}, statusWarn,
+		},
+		"never contacted": {
+			follower, map[string]string{"last_contact": "never"}, statusFail,
+		},
+		"no leader": {
+			map[string]string{"leader": "false", "leader_addr": ""},
+			map[string]string{"last_contact": "50ms"},
+			statusFail,
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r := checkRaft(tc.consul, tc.raft, 200*time.Millisecond)
+			require.Equal(t, tc.status, r.Status, r.Output)
+		})
+	}
+}
+
+func TestCheckServers(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, statusPass, checkServers(map[string]string{"known_servers": "3"}).Status)
+	require.Equal(t, statusFail, checkServers(map[string]string{"known_servers": "0"}).Status)
+	require.Equal(t, statusFail, checkServers(map[string]string{}).Status)
+}
+
+func TestCheckACL(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, statusPass, checkACL(map[string]string{"acl": "disabled"}).Status)
+	require.Equal(t, statusPass, checkACL(map[string]string{"acl": "enabled"}).Status)
+	require.Equal(t, statusWarn, checkACL(map[string]string{"acl": "legacy"}).Status)
+	require.Equal(t, statusFail, checkACL(map[string]string{}).Status)
+}
+
+func TestCheckDNS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled", func(t *testing.T) {
+		r := checkDNS(map[string]interface{}{"DNSAddrs": []interface{}{}}, time.Second)
+		require.Equal(t, statusPass, r.Status)
+	})
+
+	t.Run("no answer", func(t *testing.T) {
+		// Nothing answers on a UDP socket that is open but never read.
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		r := checkDNS(map[string]interface{}{
+			"DNSAddrs": []interface{}{"udp://" + conn.LocalAddr().String()},
+		}, 100*time.Millisecond)
+		require.Equal(t, statusFail, r.Status)
+	})
+}
+
+func TestDNSAddr(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		addrs   []interface{}
+		network string
+		addr    string
+	}{
+		"prefers udp": {
+			[]interface{}{"tcp://10.0.0.1:8600", "udp://10.0.0.1:8600"},
+			"udp", "10.0.0.1:8600",
+		},
+		"tcp only": {
+			[]interface{}{"tcp://10.0.0.1:8600"},
+			"tcp", "10.0.0.1:8600",
+		},
+		"any v4 address": {
+			[]interface{}{"udp://0.0.0.0:8600"},
+			"udp", "127.0.0.1:8600",
+		},
+		"any v6 address": {
+			[]interface{}{"udp://[::]:8600"},
+			"udp", "[::1]:8600",
+		},
+		"unix socket": {
+			[]interface{}{"unix:///tmp/dns.sock"},
+			"", "",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			network, addr := dnsAddr(tc.addrs)
+			require.Equal(t, tc.network, network)
+			require.Equal(t, tc.addr, addr)
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package info
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -20,10 +23,26 @@ type cmd struct {
 	flags *flag.FlagSet
 	http  *flags.HTTPFlags
 	help  string
+
+	check      bool
+	format     string
+	thresholds checkThresholds
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.check, "check", false,
+		"Evaluate the health of the agent instead of printing its stats, and "+
+			"exit with 0 if all the checks pass, 2 if any warns, or 3 if any fails.")
+	c.flags.StringVar(&c.format, "format", "text",
+		"Output format of -check. Must be 'text' or 'json'.")
+	c.flags.IntVar(&c.thresholds.minMembers, "min-members", 1,
+		"The number of LAN gossip members that must be alive for the serf check to pass.")
+	c.flags.DurationVar(&c.thresholds.maxLastContact, "max-last-contact", 200*time.Millisecond,
+		"How long a server may go without contact from the leader before the raft "+
+			"check warns.")
+	c.flags.DurationVar(&c.thresholds.dnsTimeout, "dns-timeout", 2*time.Second,
+		"How long to wait for the DNS interface of the agent to answer.")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	c.help = flags.Usage(help, c.flags)
@@ -34,12 +53,21 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if c.format != "text" && c.format != "json" {
+		c.UI.Error("-format must be either 'text' or 'json'")
+		return 1
+	}
+
 	client, err := c.http.APIClient()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
 		return 1
 	}
 
+	if c.check {
+		return c.runCheck(client.Agent().Self())
+	}
+
 	self, err := client.Agent().Self()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error querying agent: %s", err))
@@ -83,6 +111,41 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// runCheck outputs the checks evaluated against the response of
+// /v1/agent/self and returns the exit code. An agent that can't be queried
+// fails the check.
+func (c *cmd) runCheck(self map[string]map[string]interface{}, err error) int {
+	var results []checkResult
+	if err != nil {
+		results = []checkResult{{
+			Name:   "agent",
+			Status: statusFail,
+			Output: fmt.Sprintf("Error querying agent: %s", err),
+		}}
+	} else {
+		results = runChecks(self, c.thresholds)
+	}
+	report := newCheckReport(results)
+
+	if c.format == "json" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error encoding report: %s", err))
+			return 1
+		}
+		c.UI.Output(string(out))
+	} else {
+		out := []string{"Check\x1fStatus\x1fOutput"}
+		for _, r := range report.Checks {
+			out = append(out, fmt.Sprintf("%s\x1f%s\x1f%s", r.Name, r.Status, r.Output))
+		}
+		c.UI.Output(columnize.Format(out, &columnize.Config{Delim: string([]byte{0x1f})}))
+		c.UI.Output("")
+		c.UI.Output("Overall: " + report.Status)
+	}
+	return statusExitCode[report.Status]
+}
+
 func (c *cmd) Synopsis() string {
 	return synopsis
 }
@@ -96,4 +159,21 @@ const help = `
 Usage: consul info [options]
 
   Provides debugging information for operators.
+
+  With -check, the stats of the agent are evaluated instead, so the command can
+  be used as a health probe of the node. Each check prints PASS, WARN or FAIL:
+
+    serf     The LAN gossip members that are alive, out of the known ones.
+    raft     On servers, whether there is a leader that was heard from
+             within -max-last-contact.
+    servers  On clients, whether any servers are known.
+    acl      Whether the token was resolved, and the ACL mode.
+    dns      Whether the DNS interface of the agent answers.
+
+  Only the local agent is queried, so the checks work while the servers are
+  unreachable. The exit code is 0 if all the checks pass, 2 if any warns, and
+  3 if any fails or the agent can't be queried. It is 1 if the command can't
+  run, such as when given an invalid flag.
+
+      $ consul info -check -min-members=3
 `
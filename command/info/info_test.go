@@ -1,11 +1,14 @@
 package info
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/testrpc"
 	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInfoCommand_noTabs(t *testing.T) {
@@ -33,3 +36,77 @@ func TestInfoCommand(t *testing.T) {
 		t.Fatalf("bad: %#v", ui.OutputWriter.String())
 	}
 }
+
+func TestInfoCommand_Check(t *testing.T) {
+	t.Parallel()
+	a1 := agent.NewTestAgent(t, t.Name(), ``)
+	defer a1.Shutdown()
+	testrpc.WaitForLeader(t, a1.RPC, "dc1")
+
+	t.Run("text", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{"-http-addr=" + a1.HTTPAddr(), "-check"}
+
+		code := cmd.Run(args)
+		require.Equal(t, 0, code, ui.OutputWriter.String()+ui.ErrorWriter.String())
+		output := ui.OutputWriter.String()
+		require.Contains(t, output, "serf")
+		require.Contains(t, output, "raft")
+		require.Contains(t, output, "acl")
+		require.Contains(t, output, "answered NOERROR")
+		require.Contains(t, output, "Overall: PASS")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{"-http-addr=" + a1.HTTPAddr(), "-check", "-format=json"}
+
+		code := cmd.Run(args)
+		require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+		var report checkReport
+		require.NoError(t, json.Unmarshal(ui.OutputWriter.Bytes(), &report))
+		require.Equal(t, statusPass, report.Status)
+
+		var names []string
+		for _, r := range report.Checks {
+			names = append(names, r.Name)
+			require.Equal(t, statusPass, r.Status, r.Output)
+		}
+		require.Equal(t, []string{"serf", "raft", "acl", "dns"}, names)
+	})
+
+	t.Run("too few members", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{"-http-addr=" + a1.HTTPAddr(), "-check", "-min-members=3"}
+
+		code := cmd.Run(args)
+		require.Equal(t, 3, code)
+		require.Contains(t, ui.OutputWriter.String(), "1 of 1 members alive, expected at least 3")
+		require.Contains(t, ui.OutputWriter.String(), "Overall: FAIL")
+	})
+}
+
+func TestInfoCommand_CheckAgentDown(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	args := []string{"-http-addr=127.0.0.1:0", "-check"}
+
+	code := cmd.Run(args)
+	require.Equal(t, 3, code)
+	require.Contains(t, ui.OutputWriter.String(), "Error querying agent")
+}
+
+func TestInfoCommand_CheckInvalidFormat(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+
+	code := cmd.Run([]string{"-check", "-format=yaml"})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "-format must be either 'text' or 'json'")
+}
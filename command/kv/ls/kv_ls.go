@@ -0,0 +1,153 @@
+package ls
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI        cli.Ui
+	flags     *flag.FlagSet
+	http      *flags.HTTPFlags
+	help      string
+	separator string
+	sizes     bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.separator, "separator", "/",
+		"String to use as a separator between directories. The default value "+
+			"is \"/\".")
+	c.flags.BoolVar(&c.sizes, "sizes", true,
+		"Show the size of the value of each key. Sizes are looked up one key at "+
+			"a time without fetching the values. The default value is true.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	prefix := ""
+
+	// Check for arg validation
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		prefix = ""
+	case 1:
+		prefix = args[0]
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	if c.separator == "" {
+		c.UI.Error("Error! The separator can't be empty")
+		return 1
+	}
+
+	// Keys can't start with a /, and listing "config" is meant as listing the
+	// "config/" directory.
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, c.separator) {
+		prefix += c.separator
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	opts := &api.QueryOptions{
+		AllowStale: c.http.Stale(),
+	}
+	keys, _, err := client.KV().Keys(prefix, c.separator, opts)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error querying Consul agent: %s", err))
+		return 1
+	}
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 2, 4, ' ', 0)
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, prefix)
+
+		// The directory itself may also be a key.
+		if name == "" {
+			continue
+		}
+
+		// Directories are returned with a trailing separator.
+		if strings.HasSuffix(name, c.separator) || !c.sizes {
+			fmt.Fprintf(tw, "%s\t\n", name)
+			continue
+		}
+
+		size, ok, _, err := client.KV().ValueSize(key, opts)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error querying Consul agent: %s", err))
+			return 1
+		}
+		if !ok {
+			// The key was deleted since it was listed.
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", name, size)
+	}
+	tw.Flush()
+
+	if b.Len() > 0 {
+		c.UI.Info(strings.TrimSuffix(b.String(), "\n"))
+	}
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Lists the immediate children of a KV directory"
+const help = `
+Usage: consul kv ls [options] [PREFIX]
+
+  Lists the keys and directories directly under the given prefix, like a
+  filesystem directory listing. Directories are shown with a trailing
+  separator and keys with the size of their value in bytes. Values are never
+  fetched, so this can be used to browse large trees level by level.
+
+  To list the top level of the KV store:
+
+      $ consul kv ls
+
+  To list the contents of the "redis/config" directory:
+
+      $ consul kv ls redis/config
+
+  For a full list of options and examples, please see the Consul documentation.
+`
@@ -0,0 +1,60 @@
+package ls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVLsCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestKVLsCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	for key, value := range map[string]string{
+		"config/":             "",
+		"config/a":            "hello",
+		"config/b":            "",
+		"config/app/db/url":   "postgres://",
+		"config/app/db/users": "admin",
+		"other":               "x",
+	} {
+		_, err := client.KV().Put(&api.KVPair{Key: key, Value: []byte(value)}, nil)
+		require.NoError(t, err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		ui := cli.NewMockUi()
+		c := New(ui)
+		code := c.Run(append([]string{"-http-addr=" + a.HTTPAddr()}, args...))
+		require.Equal(t, 0, code, ui.ErrorWriter.String())
+		return ui.OutputWriter.String()
+	}
+
+	lines := func(s string) []string {
+		var out []string
+		for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+			out = append(out, strings.Join(strings.Fields(line), " "))
+		}
+		return out
+	}
+
+	require.Equal(t, []string{"config/", "other 1"}, lines(run()))
+	require.Equal(t, []string{"a 5", "app/", "b 0"}, lines(run("config")))
+	require.Equal(t, []string{"a 5", "app/", "b 0"}, lines(run("/config/")))
+	require.Equal(t, []string{"db/"}, lines(run("config/app")))
+	require.Equal(t, []string{"url", "users"}, lines(run("-sizes=false", "config/app/db")))
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
@@ -33,6 +34,12 @@ type cmd struct {
 	acquire       bool
 	release       bool
 
+	fromDir       string
+	prefix        string
+	concurrency   int
+	dryRun        bool
+	deleteMissing bool
+
 	// testStdin is the input for testing.
 	testStdin io.Reader
 }
@@ -64,6 +71,25 @@ func (c *cmd) init() {
 		"Forfeit the lock on the key at the given path. This requires the "+
 			"-session flag to be set. The key must be held by the session in order to "+
 			"be unlocked. The default value is false.")
+	c.flags.StringVar(&c.fromDir, "from-dir", "",
+		"Write each file under the given directory to a key, named after its "+
+			"path relative to the directory and prefixed with -prefix. The files "+
+			"are written in transactions, and the ones that can't be written are "+
+			"reported without stopping the others. No KEY or DATA may be given.")
+	c.flags.StringVar(&c.prefix, "prefix", "",
+		"Prefix of the keys the files are written to with -from-dir, such as "+
+			"\"app/\". The default value is empty.")
+	c.flags.IntVar(&c.concurrency, "concurrency", 4,
+		"Number of transactions to apply at the same time with -from-dir. The "+
+			"default value is 4.")
+	c.flags.BoolVar(&c.dryRun, "dry-run", false,
+		"Show the keys -from-dir would write and delete without changing "+
+			"anything. The default value is false.")
+	c.flags.BoolVar(&c.deleteMissing, "delete-missing", false,
+		"Delete the keys under -prefix that have no file in the directory "+
+			"given with -from-dir, like rsync's --delete. This requires a -prefix "+
+			"ending with \"/\". "+
+			"The default value is false.")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -78,6 +104,13 @@ func (c *cmd) Run(args []string) int {
 
 	// Check for arg validation
 	args = c.flags.Args()
+	if c.fromDir != "" {
+		return c.runFromDir(args)
+	} else if c.prefix != "" || c.dryRun || c.deleteMissing {
+		c.UI.Error("Error! -prefix, -dry-run and -delete-missing require -from-dir")
+		return 1
+	}
+
 	key, data, err := c.dataFromArgs(args)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error! %s", err))
@@ -170,6 +203,35 @@ func (c *cmd) Run(args []string) int {
 	}
 }
 
+// runFromDir validates the flags used with -from-dir and writes the
+// directory.
+func (c *cmd) runFromDir(args []string) int {
+	switch {
+	case len(args) > 0:
+		c.UI.Error("Error! Cannot specify KEY or DATA with -from-dir")
+		return 1
+	case c.cas || c.acquire || c.release || c.session != "" || c.base64encoded:
+		c.UI.Error("Error! Cannot use -cas, -acquire, -release, -session or -base64 with -from-dir")
+		return 1
+	case c.deleteMissing && c.prefix == "":
+		c.UI.Error("Error! Must specify -prefix with -delete-missing")
+		return 1
+	case c.deleteMissing && !strings.HasSuffix(c.prefix, "/"):
+		// Without the trailing slash, -prefix=app would also delete the
+		// keys under app-staging/.
+		c.UI.Error("Error! -prefix must end with \"/\" with -delete-missing")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+	return c.putFromDir(client)
+}
+
 func (c *cmd) dataFromArgs(args []string) (string, string, error) {
 	switch len(args) {
 	case 0:
@@ -231,5 +293,12 @@ Usage: consul kv put [options] KEY [DATA]
 
       $ consul kv put -cas -modify-index=844 config/redis/maxconns 5
 
+  To write every file under a directory to the keys under a prefix, in
+  transactions, specify -from-dir. With -delete-missing, the keys under the
+  prefix that have no file are deleted, and -dry-run shows the changes
+  without making them:
+
+      $ consul kv put -from-dir=./config -prefix=app/ -delete-missing -dry-run
+
   Additional flags and more advanced use cases are detailed below.
 `
@@ -0,0 +1,240 @@
+package put
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// maxTxnOps and maxKVSize mirror the limits of the agent's transaction
+	// endpoint, on the number of operations and on both the size of a single
+	// value and the total size of the values in a transaction.
+	maxTxnOps = 64
+	maxKVSize = 512 * 1024
+)
+
+// dirEntry is a file found in the directory given to -from-dir, along with
+// the key it's written to.
+type dirEntry struct {
+	key   string
+	value []byte
+}
+
+// putFromDir writes the files under c.fromDir to the keys under c.prefix,
+// and deletes the keys under the prefix that have no file if
+// c.deleteMissing is set. The files that can't be written are reported one
+// at a time without stopping the others.
+func (c *cmd) putFromDir(client *api.Client) int {
+	entries, failed, err := c.readDir()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error! Cannot read %s: %s", c.fromDir, err))
+		return 1
+	}
+
+	var deletes []string
+	if c.deleteMissing {
+		keys, _, err := client.KV().Keys(c.prefix, "", nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error querying Consul agent: %s", err))
+			return 1
+		}
+
+		// The keys of the files that were skipped count as present, so a
+		// file that's too large doesn't get its key deleted.
+		present := make(map[string]bool, len(entries)+len(failed))
+		for _, e := range entries {
+			present[e.key] = true
+		}
+		for _, key := range failed {
+			present[key] = true
+		}
+		for _, key := range keys {
+			if !present[key] {
+				deletes = append(deletes, key)
+			}
+		}
+	}
+
+	if c.dryRun {
+		for _, e := range entries {
+			c.UI.Output(fmt.Sprintf("Would write: %s (%d bytes)", e.key, len(e.value)))
+		}
+		for _, key := range deletes {
+			c.UI.Output(fmt.Sprintf("Would delete: %s", key))
+		}
+		c.UI.Info(fmt.Sprintf("Dry run! Would write %d keys and delete %d keys, %d errors",
+			len(entries), len(deletes), len(failed)))
+		if len(failed) > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	var ops api.TxnOps
+	for _, e := range entries {
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{
+			Verb:  api.KVSet,
+			Key:   e.key,
+			Value: e.value,
+			Flags: c.kvflags,
+		}})
+	}
+	for _, key := range deletes {
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{
+			Verb: api.KVDelete,
+			Key:  key,
+		}})
+	}
+
+	errors := c.applyBatches(client, batchOps(ops))
+	summary := fmt.Sprintf("Wrote %d keys and deleted %d keys, %d errors",
+		len(entries)-errors.writes, len(deletes)-errors.deletes, len(failed)+errors.writes+errors.deletes)
+	if len(failed) > 0 || errors.writes > 0 || errors.deletes > 0 {
+		c.UI.Error("Error! " + summary)
+		return 1
+	}
+	c.UI.Info("Success! " + summary)
+	return 0
+}
+
+// readDir walks c.fromDir and returns the files to write, sorted by key, and
+// the keys of the files that can't be written, which are reported.
+func (c *cmd) readDir() ([]*dirEntry, []string, error) {
+	var entries []*dirEntry
+	var failed []string
+	err := filepath.Walk(c.fromDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(c.fromDir, path)
+		if err != nil {
+			return err
+		}
+		key := c.prefix + filepath.ToSlash(rel)
+
+		if info.Size() > maxKVSize {
+			c.UI.Error(fmt.Sprintf("Error! Did not write to %s: value exceeds %d byte limit", key, maxKVSize))
+			failed = append(failed, key)
+			return nil
+		}
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error! Did not write to %s: %s", key, err))
+			failed = append(failed, key)
+			return nil
+		}
+		if isBinary(value) {
+			c.UI.Error(fmt.Sprintf("Error! Did not write to %s: binary file", key))
+			failed = append(failed, key)
+			return nil
+		}
+
+		entries = append(entries, &dirEntry{key: key, value: value})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries, failed, nil
+}
+
+// isBinary returns true if a value doesn't look like text.
+func isBinary(value []byte) bool {
+	return bytes.IndexByte(value, 0) != -1 || !utf8.Valid(value)
+}
+
+// batchOps splits the operations into transactions that are within the
+// limits on the number of operations and the size of the values.
+func batchOps(ops api.TxnOps) []api.TxnOps {
+	var batches []api.TxnOps
+	var batch api.TxnOps
+	size := 0
+	for _, op := range ops {
+		if len(batch) == maxTxnOps || size+len(op.KV.Value) > maxKVSize {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, op)
+		size += len(op.KV.Value)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// batchErrors counts the writes and deletes that failed.
+type batchErrors struct {
+	writes  int
+	deletes int
+}
+
+// applyBatches applies the transactions, up to c.concurrency at a time. A
+// transaction that fails is rolled back as a whole, so every key in it is
+// reported.
+func (c *cmd) applyBatches(client *api.Client, batches []api.TxnOps) batchErrors {
+	var (
+		l      sync.Mutex
+		errors batchErrors
+		wg     sync.WaitGroup
+	)
+	report := func(batch api.TxnOps, reason string) {
+		l.Lock()
+		defer l.Unlock()
+		for _, op := range batch {
+			if op.KV.Verb == api.KVDelete {
+				c.UI.Error(fmt.Sprintf("Error! Did not delete %s: %s", op.KV.Key, reason))
+				errors.deletes++
+			} else {
+				c.UI.Error(fmt.Sprintf("Error! Did not write to %s: %s", op.KV.Key, reason))
+				errors.writes++
+			}
+		}
+	}
+
+	concurrency := c.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	work := make(chan api.TxnOps)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				ok, resp, _, err := client.Txn().Txn(batch, nil)
+				switch {
+				case err != nil:
+					report(batch, err.Error())
+				case !ok:
+					var what []string
+					for _, e := range resp.Errors {
+						what = append(what, e.What)
+					}
+					report(batch, "transaction rolled back: "+strings.Join(what, ", "))
+				}
+			}
+		}()
+	}
+	for _, batch := range batches {
+		work <- batch
+	}
+	close(work)
+	wg.Wait()
+	return errors
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/base64"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -13,6 +15,7 @@ import (
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/sdk/testutil"
 	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKVPutCommand_noTabs(t *testing.T) {
@@ -51,6 +54,26 @@ func TestKVPutCommand_Validation(t *testing.T) {
 			[]string{"foo", "bar", "baz"},
 			"Too many arguments",
 		},
+		"-from-dir and key": {
+			[]string{"-from-dir", "config", "foo"},
+			"Cannot specify KEY or DATA with -from-dir",
+		},
+		"-from-dir and -cas": {
+			[]string{"-from-dir", "config", "-cas"},
+			"Cannot use -cas",
+		},
+		"-delete-missing without -prefix": {
+			[]string{"-from-dir", "config", "-delete-missing"},
+			"Must specify -prefix with -delete-missing",
+		},
+		"-delete-missing without trailing slash": {
+			[]string{"-from-dir", "config", "-prefix", "app", "-delete-missing"},
+			`-prefix must end with "/" with -delete-missing`,
+		},
+		"-prefix without -from-dir": {
+			[]string{"-prefix", "app/", "foo"},
+			"require -from-dir",
+		},
 	}
 
 	for name, tc := range cases {
@@ -381,3 +404,98 @@ func TestKVPutCommand_CAS(t *testing.T) {
 		t.Errorf("bad: %#v", data.Value)
 	}
 }
+
+func TestKVPutCommand_FromDir(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	dir := testutil.TempDir(t, "kv-put-from-dir")
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{
+		"db/host":   []byte("10.0.0.5"),
+		"db/port":   []byte("5432"),
+		"name":      []byte("web"),
+		"logo.png":  []byte{0x89, 'P', 'N', 'G', 0},
+		"huge.json": bytes.Repeat([]byte("a"), maxKVSize+1),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	}
+
+	for _, key := range []string{"app/stale", "app/huge.json", "other/keep"} {
+		_, err := client.KV().Put(&api.KVPair{Key: key, Value: []byte("old")}, nil)
+		require.NoError(t, err)
+	}
+
+	// A dry run only shows the changes.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-from-dir", dir,
+		"-prefix", "app/",
+		"-delete-missing",
+		"-dry-run",
+	})
+	require.Equal(t, 1, code)
+	output := ui.OutputWriter.String()
+	require.Contains(t, output, "Would write: app/db/host (8 bytes)")
+	require.Contains(t, output, "Would delete: app/stale")
+	require.Contains(t, output, "Would write 3 keys and delete 1 keys, 2 errors")
+	pair, _, err := client.KV().Get("app/name", nil)
+	require.NoError(t, err)
+	require.Nil(t, pair)
+
+	// The files that can't be written are reported without stopping the
+	// others, and their keys aren't deleted.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-from-dir", dir,
+		"-prefix", "app/",
+		"-delete-missing",
+		"-concurrency", "2",
+	})
+	require.Equal(t, 1, code)
+	errors := ui.ErrorWriter.String()
+	require.Contains(t, errors, "Did not write to app/logo.png: binary file")
+	require.Contains(t, errors, "Did not write to app/huge.json: value exceeds")
+	require.Contains(t, errors, "Wrote 3 keys and deleted 1 keys, 2 errors")
+
+	keys, _, err := client.KV().Keys("", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"app/db/host", "app/db/port", "app/huge.json", "app/name", "other/keep"}, keys)
+	pair, _, err = client.KV().Get("app/db/port", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("5432"), pair.Value)
+}
+
+func TestKVPutCommand_batchOps(t *testing.T) {
+	t.Parallel()
+
+	var ops api.TxnOps
+	for i := 0; i < maxTxnOps+1; i++ {
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVSet, Key: strconv.Itoa(i)}})
+	}
+	batches := batchOps(ops)
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], maxTxnOps)
+	require.Len(t, batches[1], 1)
+
+	// The values of a batch stay within the size limit.
+	big := bytes.Repeat([]byte("a"), maxKVSize/2+1)
+	ops = api.TxnOps{
+		&api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVSet, Key: "a", Value: big}},
+		&api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVSet, Key: "b", Value: big}},
+		&api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDelete, Key: "c"}},
+	}
+	batches = batchOps(ops)
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], 1)
+	require.Len(t, batches[1], 2)
+}
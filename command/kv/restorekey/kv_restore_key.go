@@ -0,0 +1,152 @@
+package restorekey
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI      cli.Ui
+	flags   *flag.FlagSet
+	http    *flags.HTTPFlags
+	help    string
+	recurse bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.recurse, "recurse", false,
+		"Restore all the deleted keys which start with the given prefix. The "+
+			"default value is false.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	key := ""
+
+	// Check for arg validation
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		key = ""
+	case 1:
+		key = args[0]
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// This is just a "nice" thing to do. Since pairs cannot start with a /, but
+	// users will likely put "/" or "/foo", lets go ahead and strip that for them
+	// here.
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+
+	// If the key is empty and we are not doing a recursive restore, this is an
+	// error.
+	if key == "" && !c.recurse {
+		c.UI.Error("Error! Missing KEY argument")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	var deleted []*api.KVDeletedPair
+	if c.recurse {
+		deleted, _, err = client.KV().ListDeleted(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error querying Consul agent: %s", err))
+			return 1
+		}
+		if len(deleted) == 0 {
+			c.UI.Error(fmt.Sprintf("Error! No deleted keys are retained with prefix: %s", key))
+			return 1
+		}
+	} else {
+		pair, _, err := client.KV().GetDeleted(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error querying Consul agent: %s", err))
+			return 1
+		}
+		if pair == nil {
+			c.UI.Error(fmt.Sprintf("Error! No deleted key is retained at: %s", key))
+			return 1
+		}
+		deleted = append(deleted, pair)
+	}
+
+	// The keys are put with a CAS index of 0, so a key that was written again
+	// since it was deleted isn't overwritten.
+	code := 0
+	for _, d := range deleted {
+		pair := &api.KVPair{
+			Key:   d.Key,
+			Flags: d.Flags,
+			Value: d.Value,
+		}
+		ok, _, err := client.KV().CAS(pair, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error! Did not restore key %s: %s", d.Key, err))
+			code = 1
+			continue
+		}
+		if !ok {
+			c.UI.Error(fmt.Sprintf("Error! Did not restore key %s: the key exists", d.Key))
+			code = 1
+			continue
+		}
+		c.UI.Info(fmt.Sprintf("Success! Restored key: %s", d.Key))
+	}
+	return code
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Restores deleted data to the KV store"
+const help = `
+Usage: consul kv restore-key [options] KEY_OR_PREFIX
+
+  Restores the last value of a key that was deleted from Consul's key-value
+  store. Deleted keys are only retained by the servers when
+  kv_deleted_retention is set, and only for that long. A key that exists is
+  never overwritten.
+
+  To restore the key named "foo" in the key-value store:
+
+      $ consul kv restore-key foo
+
+  To restore all the deleted keys which start with "foo", specify the
+  -recurse option:
+
+      $ consul kv restore-key -recurse foo
+`
@@ -0,0 +1,117 @@
+package restorekey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVRestoreKeyCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestKVRestoreKeyCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no key": {
+			[]string{},
+			"Missing KEY argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar", "baz"},
+			"Too many arguments",
+		},
+	}
+
+	for name, tc := range cases {
+		c.init()
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestKVRestoreKeyCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), `
+		kv_deleted_retention = "1h"
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+	client := a.Client()
+
+	for _, k := range []string{"foo/a", "foo/b"} {
+		_, err := client.KV().Put(&api.KVPair{Key: k, Flags: 7, Value: []byte(k)}, nil)
+		require.NoError(t, err)
+	}
+	_, err := client.KV().DeleteTree("foo", nil)
+	require.NoError(t, err)
+
+	// A single key is restored with its last value and flags.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "foo/a"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "Restored key: foo/a")
+
+	pair, _, err := client.KV().Get("foo/a", nil)
+	require.NoError(t, err)
+	require.NotNil(t, pair)
+	require.Equal(t, []byte("foo/a"), pair.Value)
+	require.Equal(t, uint64(7), pair.Flags)
+
+	// A key that exists isn't overwritten, but the rest of the prefix is
+	// still restored.
+	_, err = client.KV().Put(&api.KVPair{Key: "foo/a", Value: []byte("new")}, nil)
+	require.NoError(t, err)
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-recurse", "foo"})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "Did not restore key foo/a: the key exists")
+	require.Contains(t, ui.OutputWriter.String(), "Restored key: foo/b")
+
+	pair, _, err = client.KV().Get("foo/a", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), pair.Value)
+	pair, _, err = client.KV().Get("foo/b", nil)
+	require.NoError(t, err)
+	require.NotNil(t, pair)
+	require.Equal(t, []byte("foo/b"), pair.Value)
+
+	// A key that was never deleted can't be restored.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-http-addr=" + a.HTTPAddr(), "nope"})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "No deleted key is retained at: nope")
+}
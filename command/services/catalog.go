@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// ExternalNodeMeta is the node meta key marking the nodes registered
+	// with "-catalog" as external nodes, with no agent of their own.
+	ExternalNodeMeta = "external-node"
+
+	// ExternalProbeMeta is the node meta key that asks an external prober,
+	// such as consul-esm, to run the checks of an external node.
+	ExternalProbeMeta = "external-probe"
+)
+
+// CatalogRegistration returns the catalog register call for a service on an
+// external node, for registering it without a local agent. The checks of the
+// service are converted into catalog checks that an external prober runs, so
+// the check types that need a local agent are rejected.
+//
+// A catalog register replaces the meta of the node, so the node is always
+// marked for probing, even for a service without checks, so the checks of
+// the services registered on it before keep running.
+func CatalogRegistration(svc *api.AgentServiceRegistration, node, address string) (*api.CatalogRegistration, error) {
+	if svc.Connect != nil && svc.Connect.SidecarService != nil {
+		return nil, fmt.Errorf("Service %q: sidecar services require a local agent", svc.Name)
+	}
+
+	id := svc.ID
+	if id == "" {
+		id = svc.Name
+	}
+	weights := api.AgentWeights{Passing: 1, Warning: 1}
+	if svc.Weights != nil {
+		weights = *svc.Weights
+	}
+
+	reg := &api.CatalogRegistration{
+		Node:    node,
+		Address: address,
+		NodeMeta: map[string]string{
+			ExternalNodeMeta:  "true",
+			ExternalProbeMeta: "true",
+		},
+		Service: &api.AgentService{
+			Kind:              svc.Kind,
+			ID:                id,
+			Service:           svc.Name,
+			Tags:              svc.Tags,
+			Meta:              svc.Meta,
+			Port:              svc.Port,
+			Address:           svc.Address,
+			Weights:           weights,
+			EnableTagOverride: svc.EnableTagOverride,
+			Proxy:             svc.Proxy,
+			Connect:           svc.Connect,
+		},
+	}
+
+	var checks api.AgentServiceChecks
+	if svc.Check != nil {
+		checks = append(checks, svc.Check)
+	}
+	checks = append(checks, svc.Checks...)
+	for i, check := range checks {
+		// The check IDs are the ones the agent would give the checks.
+		checkID := check.CheckID
+		if checkID == "" {
+			checkID = fmt.Sprintf("service:%s", id)
+			if len(checks) > 1 {
+				checkID += fmt.Sprintf(":%d", i+1)
+			}
+		}
+
+		hc, err := catalogCheck(check)
+		if err != nil {
+			return nil, fmt.Errorf("Check %q: %s", checkID, err)
+		}
+		hc.Node = node
+		hc.CheckID = checkID
+		hc.ServiceID = id
+		hc.ServiceName = svc.Name
+		if hc.Name == "" {
+			hc.Name = fmt.Sprintf("Service '%s' check", svc.Name)
+		}
+		reg.Checks = append(reg.Checks, hc)
+	}
+
+	return reg, nil
+}
+
+// catalogCheck converts a service check into a catalog check with a
+// definition, which is what an external prober runs. Only HTTP and TCP
+// checks can be run that way.
+func catalogCheck(check *api.AgentServiceCheck) (*api.HealthCheck, error) {
+	switch {
+	case len(check.Args) > 0:
+		return nil, fmt.Errorf("script checks require a local agent")
+	case check.DockerContainerID != "":
+		return nil, fmt.Errorf("Docker checks require a local agent")
+	case check.TTL != "":
+		return nil, fmt.Errorf("TTL checks require a local agent")
+	case check.AliasNode != "" || check.AliasService != "":
+		return nil, fmt.Errorf("alias checks require a local agent")
+	case check.GRPC != "":
+		return nil, fmt.Errorf("gRPC checks require a local agent")
+	case check.DependsOnCheck != "":
+		return nil, fmt.Errorf("check dependencies require a local agent")
+	case check.HTTP == "" && check.TCP == "":
+		return nil, fmt.Errorf("only HTTP and TCP checks can be registered in the catalog")
+	}
+
+	interval, err := parseCheckDuration("interval", check.Interval)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseCheckDuration("timeout", check.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	deregister, err := parseCheckDuration("deregister_critical_service_after", check.DeregisterCriticalServiceAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	status := check.Status
+	if status == "" {
+		status = api.HealthCritical
+	}
+
+	return &api.HealthCheck{
+		Name:   check.Name,
+		Status: status,
+		Notes:  check.Notes,
+		Definition: api.HealthCheckDefinition{
+			HTTP:                                   check.HTTP,
+			Header:                                 check.Header,
+			Method:                                 check.Method,
+			TLSSkipVerify:                          check.TLSSkipVerify,
+			TCP:                                    check.TCP,
+			IntervalDuration:                       interval,
+			TimeoutDuration:                        timeout,
+			DeregisterCriticalServiceAfterDuration: deregister,
+		},
+	}, nil
+}
+
+// parseCheckDuration parses a duration of a check definition, which may be
+// empty.
+func parseCheckDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", name, value, err)
+	}
+	return d, nil
+}
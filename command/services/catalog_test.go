@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogRegistration(t *testing.T) {
+	t.Parallel()
+
+	svc := &api.AgentServiceRegistration{
+		Name: "db",
+		Port: 5432,
+		Tags: []string{"primary"},
+		Checks: api.AgentServiceChecks{
+			&api.AgentServiceCheck{
+				HTTP:     "http://10.0.0.5:8080/health",
+				Interval: "10s",
+				Timeout:  "1s",
+			},
+			&api.AgentServiceCheck{
+				CheckID: "db-tcp",
+				Name:    "tcp",
+				TCP:     "10.0.0.5:5432",
+				Status:  api.HealthPassing,
+			},
+		},
+	}
+
+	reg, err := CatalogRegistration(svc, "db-host", "10.0.0.5")
+	require.NoError(t, err)
+	require.Equal(t, "db-host", reg.Node)
+	require.Equal(t, "10.0.0.5", reg.Address)
+	require.Equal(t, map[string]string{
+		ExternalNodeMeta:  "true",
+		ExternalProbeMeta: "true",
+	}, reg.NodeMeta)
+	require.Equal(t, &api.AgentService{
+		ID:      "db",
+		Service: "db",
+		Tags:    []string{"primary"},
+		Port:    5432,
+		Weights: api.AgentWeights{Passing: 1, Warning: 1},
+	}, reg.Service)
+
+	require.Len(t, reg.Checks, 2)
+	require.Equal(t, &api.HealthCheck{
+		Node:        "db-host",
+		CheckID:     "service:db:1",
+		Name:        "Service 'db' check",
+		Status:      api.HealthCritical,
+		ServiceID:   "db",
+		ServiceName: "db",
+		Definition: api.HealthCheckDefinition{
+			HTTP:             "http://10.0.0.5:8080/health",
+			IntervalDuration: 10 * time.Second,
+			TimeoutDuration:  time.Second,
+		},
+	}, reg.Checks[0])
+	require.Equal(t, "db-tcp", reg.Checks[1].CheckID)
+	require.Equal(t, "tcp", reg.Checks[1].Name)
+	require.Equal(t, api.HealthPassing, reg.Checks[1].Status)
+	require.Equal(t, "10.0.0.5:5432", reg.Checks[1].Definition.TCP)
+
+	// The node is still marked for probing without checks, since the
+	// registration replaces the meta set for the other services.
+	reg, err = CatalogRegistration(&api.AgentServiceRegistration{Name: "db"}, "db-host", "10.0.0.5")
+	require.NoError(t, err)
+	require.Empty(t, reg.Checks)
+	require.Equal(t, map[string]string{
+		ExternalNodeMeta:  "true",
+		ExternalProbeMeta: "true",
+	}, reg.NodeMeta)
+}
+
+func TestCatalogRegistration_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		check *api.AgentServiceCheck
+		err   string
+	}{
+		"script": {
+			&api.AgentServiceCheck{Args: []string{"/bin/true"}, Interval: "10s"},
+			`Check "service:web": script checks require a local agent`,
+		},
+		"docker": {
+			&api.AgentServiceCheck{DockerContainerID: "abc", Args: nil, Shell: "/bin/sh", Interval: "10s"},
+			`Check "service:web": Docker checks require a local agent`,
+		},
+		"ttl": {
+			&api.AgentServiceCheck{TTL: "10s"},
+			`Check "service:web": TTL checks require a local agent`,
+		},
+		"invalid interval": {
+			&api.AgentServiceCheck{HTTP: "http://localhost", Interval: "nope"},
+			`Check "service:web": invalid interval "nope"`,
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			svc := &api.AgentServiceRegistration{
+				Name:  "web",
+				Check: tc.check,
+			}
+			_, err := CatalogRegistration(svc, "node", "127.0.0.1")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
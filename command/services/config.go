@@ -10,9 +10,28 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+// ExportFile is the format of the services written by "consul services
+// export" and read by "consul services import".
+type ExportFile struct {
+	Services []*api.AgentServiceRegistration
+}
+
 // ServicesFromFiles returns the list of agent service registration structs
 // from a set of file arguments.
 func ServicesFromFiles(files []string) ([]*api.AgentServiceRegistration, error) {
+	return servicesFromConfig(files, nil)
+}
+
+// ServicesFromSources returns the list of agent service registration structs
+// from a set of config sources, such as the files rendered by
+// RenderTemplates.
+func ServicesFromSources(sources []config.Source) ([]*api.AgentServiceRegistration, error) {
+	return servicesFromConfig(nil, sources)
+}
+
+// servicesFromConfig returns the services defined in the given files and
+// sources.
+func servicesFromConfig(files []string, sources []config.Source) ([]*api.AgentServiceRegistration, error) {
 	// We set devMode to true so we can get the basic valid default
 	// configuration. devMode doesn't set any services by default so this
 	// is okay since we only look at services.
@@ -24,6 +43,7 @@ func ServicesFromFiles(files []string) ([]*api.AgentServiceRegistration, error)
 	if err != nil {
 		return nil, err
 	}
+	b.Sources = append(b.Sources, sources...)
 
 	cfg, err := b.BuildAndValidate()
 	if err != nil {
@@ -73,6 +93,15 @@ func serviceToAgentService(svc *structs.ServiceDefinition) (*api.AgentServiceReg
 		result.Checks = nil
 	}
 
+	// The script arguments have a different field name in the API struct,
+	// so mapstructure doesn't copy them.
+	if result.Check != nil {
+		result.Check.Args = svc.Check.ScriptArgs
+	}
+	for i, check := range result.Checks {
+		check.Args = svc.Checks[i].ScriptArgs
+	}
+
 	return &result, nil
 }
 
@@ -88,6 +88,23 @@ func TestStructsToAgentService(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Service with a script check",
+			&structs.ServiceDefinition{
+				Name: "web",
+				Check: structs.CheckType{
+					Name:       "script",
+					ScriptArgs: []string{"/bin/true"},
+				},
+			},
+			&api.AgentServiceRegistration{
+				Name: "web",
+				Check: &api.AgentServiceCheck{
+					Name: "script",
+					Args: []string{"/bin/true"},
+				},
+			},
+		},
 		{
 			"Proxy service",
 			&structs.ServiceDefinition{
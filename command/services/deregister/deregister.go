@@ -22,12 +22,20 @@ type cmd struct {
 	http   *flags.HTTPFlags
 	help   string
 	flagId string
+
+	flagCatalog bool
+	flagNode    string
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.StringVar(&c.flagId, "id", "",
 		"ID to delete. This must not be set if arguments are given.")
+	c.flags.BoolVar(&c.flagCatalog, "catalog", false,
+		"Deregister the services from an external node in the catalog instead "+
+			"of from the local agent. This requires -node.")
+	c.flags.StringVar(&c.flagNode, "node", "",
+		"Name of the external node to deregister the services from with -catalog.")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -49,6 +57,13 @@ func (c *cmd) Run(args []string) int {
 		c.UI.Error("Service deregistration requires arguments or -id, not both.")
 		return 1
 	}
+	if c.flagCatalog && c.flagNode == "" {
+		c.UI.Error("Catalog deregistration requires -node.")
+		return 1
+	} else if !c.flagCatalog && c.flagNode != "" {
+		c.UI.Error("Cannot use -node without -catalog.")
+		return 1
+	}
 
 	svcs := []*api.AgentServiceRegistration{&api.AgentServiceRegistration{
 		ID: c.flagId}}
@@ -78,6 +93,21 @@ func (c *cmd) Run(args []string) int {
 			continue
 		}
 
+		if c.flagCatalog {
+			dereg := &api.CatalogDeregistration{
+				Node:      c.flagNode,
+				ServiceID: id,
+			}
+			if _, err := client.Catalog().Deregister(dereg, nil); err != nil {
+				c.UI.Error(fmt.Sprintf("Error deregistering service %q: %s",
+					id, err))
+				return 1
+			}
+
+			c.UI.Output(fmt.Sprintf("Deregistered service: %s (node %s)", id, c.flagNode))
+			continue
+		}
+
 		if err := client.Agent().ServiceDeregister(id); err != nil {
 			c.UI.Error(fmt.Sprintf("Error registering service %q: %s",
 				svc.Name, err))
@@ -113,4 +143,9 @@ Usage: consul services deregister [options] [FILE...]
 
   Services are deregistered from the local agent catalog. This command must
   be run against the same agent where the service was registered.
+
+  Services registered with "consul services register -catalog" are
+  deregistered from their external node with -catalog and -node:
+
+      $ consul services deregister -catalog -node=db-host db.json
 `
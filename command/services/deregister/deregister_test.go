@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/consul/agent"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/testrpc"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/require"
 )
@@ -37,6 +38,14 @@ func TestCommand_Validation(t *testing.T) {
 			[]string{"-id", "web", "foo.json"},
 			"not both",
 		},
+		"-catalog without -node": {
+			[]string{"-catalog", "-id", "web"},
+			"requires -node",
+		},
+		"-node without -catalog": {
+			[]string{"-node", "db-host", "-id", "web"},
+			"without -catalog",
+		},
 	}
 
 	for name, tc := range cases {
@@ -164,6 +173,43 @@ func TestCommand_Flag(t *testing.T) {
 	require.NotNil(svcs["db"])
 }
 
+func TestCommand_Catalog(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+	client := a.Client()
+
+	// Register services on an external node
+	for _, name := range []string{"web", "db"} {
+		_, err := client.Catalog().Register(&api.CatalogRegistration{
+			Node:    "ext",
+			Address: "10.0.0.5",
+			Service: &api.AgentService{ID: name, Service: name},
+		}, nil)
+		require.NoError(err)
+	}
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-catalog",
+		"-node", "ext",
+		"-id", "web",
+	}
+
+	require.Equal(0, c.Run(args), ui.ErrorWriter.String())
+
+	node, _, err := client.Catalog().Node("ext", nil)
+	require.NoError(err)
+	require.Len(node.Services, 1)
+	require.NotNil(node.Services["db"])
+}
+
 func testFile(t *testing.T, suffix string) *os.File {
 	f := testutil.TempFile(t, "register-test-file")
 	if err := f.Close(); err != nil {
@@ -0,0 +1,109 @@
+package exp
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/services"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	flagOutput          string
+	flagIncludeSidecars bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.flagOutput, "o", "",
+		"File to write the services to. If this isn't set, they're written "+
+			"to stdout.")
+	c.flags.BoolVar(&c.flagIncludeSidecars, "include-sidecars", false,
+		"Export the sidecar services registered along with a service as "+
+			"services of their own, rather than as part of the service's "+
+			"sidecar_service block.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error("Service export takes no arguments.")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	svcs, err := client.Agent().ServicesExport(c.flagIncludeSidecars)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error exporting services: %s", err))
+		return 1
+	}
+
+	out, err := json.MarshalIndent(services.ExportFile{Services: svcs}, "", "  ")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error encoding services: %s", err))
+		return 1
+	}
+
+	if c.flagOutput == "" {
+		c.UI.Output(string(out))
+		return 0
+	}
+
+	if err := ioutil.WriteFile(c.flagOutput, append(out, '\n'), 0644); err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing %q: %s", c.flagOutput, err))
+		return 1
+	}
+	c.UI.Info(fmt.Sprintf("Exported %d services to %s", len(svcs), c.flagOutput))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Export the services registered with the local agent"
+const help = `
+Usage: consul services export [options]
+
+  Export the services registered with the local agent along with their
+  checks, in the format they are registered with. The services can be
+  registered again with another agent using "consul services import".
+  Tokens are not exported.
+
+      $ consul services export -o services.json
+
+  Additional flags and more advanced use cases are detailed below.
+`
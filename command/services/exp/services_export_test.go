@@ -0,0 +1,94 @@
+package exp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/services"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	require.Equal(t, 1, c.Run([]string{"foo"}))
+	require.Contains(t, ui.ErrorWriter.String(), "takes no arguments")
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	require.NoError(t, client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+		Name:  "web",
+		Port:  8080,
+		Tags:  []string{"v1"},
+		Check: &api.AgentServiceCheck{TTL: "15s"},
+		Connect: &api.AgentServiceConnect{
+			SidecarService: &api.AgentServiceRegistration{},
+		},
+	}))
+
+	f := testutil.TempFile(t, "services-export")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	t.Run("stdout", func(t *testing.T) {
+		require := require.New(t)
+		ui := cli.NewMockUi()
+		c := New(ui)
+
+		args := []string{"-http-addr=" + a.HTTPAddr()}
+		require.Equal(0, c.Run(args), ui.ErrorWriter.String())
+
+		var file services.ExportFile
+		require.NoError(json.Unmarshal(ui.OutputWriter.Bytes(), &file))
+		require.Len(file.Services, 1)
+		require.Equal("web", file.Services[0].Name)
+		require.Equal([]string{"v1"}, file.Services[0].Tags)
+		require.Len(file.Services[0].Checks, 1)
+		require.Equal("15s", file.Services[0].Checks[0].TTL)
+		require.NotNil(file.Services[0].Connect.SidecarService)
+	})
+
+	t.Run("file with sidecars", func(t *testing.T) {
+		require := require.New(t)
+		ui := cli.NewMockUi()
+		c := New(ui)
+
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-include-sidecars",
+			"-o", f.Name(),
+		}
+		require.Equal(0, c.Run(args), ui.ErrorWriter.String())
+		require.Contains(ui.OutputWriter.String(), "Exported 2 services")
+
+		data, err := ioutil.ReadFile(f.Name())
+		require.NoError(err)
+		var file services.ExportFile
+		require.NoError(json.Unmarshal(data, &file))
+		require.Len(file.Services, 2)
+		require.Equal("web-sidecar-proxy", file.Services[1].ID)
+	})
+}
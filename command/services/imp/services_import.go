@@ -0,0 +1,146 @@
+package imp
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/services"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	flagReplace bool
+
+	// testStdin is the input for testing.
+	testStdin io.Reader
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.flagReplace, "replace", false,
+		"Register the services that are already registered with the agent "+
+			"again, replacing them. By default they're skipped.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = c.flags.Args()
+	if len(args) != 1 {
+		c.UI.Error("Service import requires exactly one argument, the file to import.")
+		return 1
+	}
+
+	data, err := c.readFile(args[0])
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading %q: %s", args[0], err))
+		return 1
+	}
+
+	var file services.ExportFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		c.UI.Error(fmt.Sprintf("Cannot unmarshal data: %s", err))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	existing, err := client.Agent().Services()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error listing services: %s", err))
+		return 1
+	}
+
+	// Carry on past the services that fail, so a single bad one doesn't
+	// stop the others from being imported.
+	failed := false
+	for _, svc := range file.Services {
+		id := svc.ID
+		if id == "" {
+			id = svc.Name
+		}
+
+		if _, ok := existing[id]; ok && !c.flagReplace {
+			c.UI.Output(fmt.Sprintf("Skipped service %q: already registered", id))
+			continue
+		}
+
+		if err := client.Agent().ServiceRegister(svc); err != nil {
+			c.UI.Error(fmt.Sprintf("Error registering service %q: %s", id, err))
+			failed = true
+			continue
+		}
+
+		c.UI.Output(fmt.Sprintf("Registered service: %s", id))
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// readFile reads the file to import, or stdin if it's "-".
+func (c *cmd) readFile(path string) ([]byte, error) {
+	if path != "-" {
+		return ioutil.ReadFile(path)
+	}
+
+	var stdin io.Reader = os.Stdin
+	if c.testStdin != nil {
+		stdin = c.testStdin
+	}
+	return ioutil.ReadAll(stdin)
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Import services into the local agent"
+const help = `
+Usage: consul services import [options] FILE
+
+  Register the services written by "consul services export" with the local
+  agent, which doesn't need to be the one they were exported from. The
+  services that are already registered are skipped unless -replace is set.
+  If FILE is "-", the services are read from stdin.
+
+      $ consul services import services.json
+
+  Additional flags and more advanced use cases are detailed below.
+`
@@ -0,0 +1,282 @@
+package imp
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/services/exp"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		output string
+	}{
+		"no args": {
+			[]string{},
+			"",
+			"exactly one argument",
+		},
+		"too many args": {
+			[]string{"foo", "bar"},
+			"",
+			"exactly one argument",
+		},
+		"bad json": {
+			[]string{"-"},
+			"{",
+			"Cannot unmarshal",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			c.init()
+			c.testStdin = strings.NewReader(tc.stdin)
+
+			// Ensure our buffer is always clear
+			if ui.ErrorWriter != nil {
+				ui.ErrorWriter.Reset()
+			}
+			if ui.OutputWriter != nil {
+				ui.OutputWriter.Reset()
+			}
+
+			require.Equal(1, c.Run(tc.args))
+			output := ui.ErrorWriter.String()
+			require.Contains(output, tc.output)
+		})
+	}
+}
+
+func TestCommand_Replace(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	require.NoError(client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+		Name: "web",
+		Port: 8080,
+	}))
+
+	contents := `{
+  "Services": [
+    { "Name": "web", "Port": 9090 },
+    { "ID": "db1", "Name": "db", "Port": 5432 }
+  ]
+}`
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	c.testStdin = strings.NewReader(contents)
+	require.Equal(0, c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-"}), ui.ErrorWriter.String())
+	require.Contains(ui.OutputWriter.String(), `Skipped service "web": already registered`)
+	require.Contains(ui.OutputWriter.String(), "Registered service: db1")
+
+	svcs, err := client.Agent().Services()
+	require.NoError(err)
+	require.Len(svcs, 2)
+	require.Equal(8080, svcs["web"].Port)
+	require.Equal(5432, svcs["db1"].Port)
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	c.testStdin = strings.NewReader(contents)
+	require.Equal(0, c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-replace", "-"}), ui.ErrorWriter.String())
+	require.Contains(ui.OutputWriter.String(), "Registered service: web")
+
+	svcs, err = client.Agent().Services()
+	require.NoError(err)
+	require.Equal(9090, svcs["web"].Port)
+}
+
+func TestCommand_Failed(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	contents := `{
+  "Services": [
+    { "Name": "bad", "Check": { "TTL": "nope" } },
+    { "Name": "web", "Port": 8080 }
+  ]
+}`
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	c.testStdin = strings.NewReader(contents)
+	require.Equal(1, c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-"}))
+	require.Contains(ui.ErrorWriter.String(), `Error registering service "bad"`)
+	require.Contains(ui.OutputWriter.String(), "Registered service: web")
+
+	svcs, err := client.Agent().Services()
+	require.NoError(err)
+	require.Len(svcs, 1)
+}
+
+// TestCommand_RoundTrip exports the services of one agent and imports them
+// into another, which must then export exactly the same services.
+func TestCommand_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	config := `enable_script_checks = true`
+	a1 := agent.NewTestAgent(t, t.Name()+"-a1", config)
+	defer a1.Shutdown()
+	a2 := agent.NewTestAgent(t, t.Name()+"-a2", config)
+	defer a2.Shutdown()
+	client1 := a1.Client()
+	client2 := a2.Client()
+
+	regs := []*api.AgentServiceRegistration{
+		{
+			ID:                "web1",
+			Name:              "web",
+			Tags:              []string{"v1", "primary"},
+			Port:              8080,
+			Address:           "10.0.0.1",
+			EnableTagOverride: true,
+			Meta:              map[string]string{"version": "1.0"},
+			Weights:           &api.AgentWeights{Passing: 5, Warning: 2},
+			Checks: api.AgentServiceChecks{
+				{
+					Name:   "ttl",
+					TTL:    "30s",
+					Notes:  "updated by the app",
+					Status: api.HealthPassing,
+				},
+				{
+					CheckID:                        "web-http",
+					Name:                           "http",
+					HTTP:                           "http://127.0.0.1:8080/health",
+					Header:                         map[string][]string{"X-Check": {"1"}},
+					Method:                         "POST",
+					Interval:                       "10s",
+					Timeout:                        "2s",
+					TLSSkipVerify:                  true,
+					DeregisterCriticalServiceAfter: "1h0m0s",
+				},
+				{
+					Name:     "tcp",
+					TCP:      "127.0.0.1:8080",
+					Interval: "15s",
+				},
+				{
+					Name:       "grpc",
+					GRPC:       "127.0.0.1:8502/health",
+					GRPCUseTLS: true,
+					Interval:   "20s",
+				},
+				{
+					Name:     "script",
+					Args:     []string{"/bin/true", "-v"},
+					Interval: "1m0s",
+				},
+				{
+					Name:              "docker",
+					DockerContainerID: "abc123",
+					Shell:             "/bin/sh",
+					Args:              []string{"check.sh"},
+					Interval:          "30s",
+				},
+			},
+			Connect: &api.AgentServiceConnect{
+				SidecarService: &api.AgentServiceRegistration{
+					Port: 21000,
+					Tags: []string{"proxy"},
+					Proxy: &api.AgentServiceConnectProxyConfig{
+						Config: map[string]interface{}{"protocol": "http"},
+						Upstreams: []api.Upstream{
+							{
+								DestinationType: api.UpstreamDestTypeService,
+								DestinationName: "db",
+								LocalBindPort:   9191,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "native",
+			Port: 8181,
+			Connect: &api.AgentServiceConnect{
+				Native: true,
+			},
+		},
+		{
+			Kind: api.ServiceKindConnectProxy,
+			ID:   "db-proxy",
+			Name: "db-proxy",
+			Port: 22000,
+			Proxy: &api.AgentServiceConnectProxyConfig{
+				DestinationServiceName: "db",
+				DestinationServiceID:   "db1",
+				LocalServicePort:       5432,
+			},
+			Check: &api.AgentServiceCheck{
+				Name:         "alias",
+				AliasService: "db1",
+			},
+		},
+	}
+	for _, reg := range regs {
+		require.NoError(client1.Agent().ServiceRegister(reg))
+	}
+
+	f := testutil.TempFile(t, "services-import")
+	f.Close()
+	defer os.Remove(f.Name())
+
+	ui := cli.NewMockUi()
+	require.Equal(0, exp.New(ui).Run([]string{"-http-addr=" + a1.HTTPAddr(), "-o", f.Name()}), ui.ErrorWriter.String())
+
+	ui = cli.NewMockUi()
+	require.Equal(0, New(ui).Run([]string{"-http-addr=" + a2.HTTPAddr(), f.Name()}), ui.ErrorWriter.String())
+
+	exported1, err := client1.Agent().ServicesExport(true)
+	require.NoError(err)
+	exported2, err := client2.Agent().ServicesExport(true)
+	require.NoError(err)
+	require.Len(exported1, 4)
+	require.Equal(exported1, exported2)
+
+	web := exported2[2]
+	require.Equal("web1", web.ID)
+	require.Equal([]string{"v1", "primary"}, web.Tags)
+	require.True(web.EnableTagOverride)
+	require.Equal(&api.AgentWeights{Passing: 5, Warning: 2}, web.Weights)
+	require.Len(web.Checks, 6)
+
+	// Importing again doesn't change anything.
+	ui = cli.NewMockUi()
+	require.Equal(0, New(ui).Run([]string{"-http-addr=" + a2.HTTPAddr(), f.Name()}), ui.ErrorWriter.String())
+	require.Equal(3, strings.Count(ui.OutputWriter.String(), "Skipped service"))
+}
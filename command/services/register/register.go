@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 
+	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/hashicorp/consul/command/services"
@@ -29,6 +30,15 @@ type cmd struct {
 	flagPort    int
 	flagTags    []string
 	flagMeta    map[string]string
+
+	flagCatalog     bool
+	flagNode        string
+	flagNodeAddress string
+
+	flagTemplate bool
+	flagVars     map[string]string
+	flagVarFile  string
+	flagDryRun   bool
 }
 
 func (c *cmd) init() {
@@ -48,6 +58,27 @@ func (c *cmd) init() {
 	c.flags.Var((*flags.AppendSliceValue)(&c.flagTags), "tag",
 		"Tag to add to the service. This flag can be specified multiple "+
 			"times to set multiple tags.")
+	c.flags.BoolVar(&c.flagCatalog, "catalog", false,
+		"Register the services in the catalog on an external node instead of "+
+			"with the local agent, for services that don't have an agent. This "+
+			"requires -node and -node-address.")
+	c.flags.StringVar(&c.flagNode, "node", "",
+		"Name of the external node to register the services on with -catalog.")
+	c.flags.StringVar(&c.flagNodeAddress, "node-address", "",
+		"Address of the external node to register the services on with -catalog.")
+	c.flags.BoolVar(&c.flagTemplate, "template", false,
+		"Render the service definition files as Go templates before parsing "+
+			"them, with the variables given by -var and -var-file. Using a "+
+			"variable that isn't defined is an error.")
+	c.flags.Var((*flags.FlagMapValue)(&c.flagVars), "var",
+		"Variable for -template, formatted as name=value. This flag may be "+
+			"specified multiple times to set multiple variables, and takes "+
+			"precedence over -var-file.")
+	c.flags.StringVar(&c.flagVarFile, "var-file", "",
+		"Path to a JSON file with an object of variables for -template.")
+	c.flags.BoolVar(&c.flagDryRun, "dry-run", false,
+		"Print the rendered service definitions of -template instead of "+
+			"registering the services.")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -78,8 +109,41 @@ func (c *cmd) Run(args []string) int {
 		c.UI.Error("Service registration requires arguments or -id, not both.")
 		return 1
 	}
+	if c.flagCatalog && (c.flagNode == "" || c.flagNodeAddress == "") {
+		c.UI.Error("Catalog registration requires -node and -node-address.")
+		return 1
+	} else if !c.flagCatalog && (c.flagNode != "" || c.flagNodeAddress != "") {
+		c.UI.Error("Cannot use -node or -node-address without -catalog.")
+		return 1
+	}
+	if c.flagTemplate && len(args) == 0 {
+		c.UI.Error("Template registration requires at least one file argument.")
+		return 1
+	} else if !c.flagTemplate && (len(c.flagVars) > 0 || c.flagVarFile != "" || c.flagDryRun) {
+		c.UI.Error("Cannot use -var, -var-file or -dry-run without -template.")
+		return 1
+	}
 
-	if len(args) > 0 {
+	// Templates are rendered here, before the definitions are parsed, so
+	// the agent only ever sees the rendered services.
+	var sources []config.Source
+	if c.flagTemplate {
+		vars, err := services.TemplateVars(c.flagVarFile, c.flagVars)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error: %s", err))
+			return 1
+		}
+		sources, err = services.RenderTemplates(args, vars)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error: %s", err))
+			return 1
+		}
+		svcs, err = services.ServicesFromSources(sources)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error: %s", err))
+			return 1
+		}
+	} else if len(args) > 0 {
 		var err error
 		svcs, err = services.ServicesFromFiles(args)
 		if err != nil {
@@ -88,6 +152,28 @@ func (c *cmd) Run(args []string) int {
 		}
 	}
 
+	// Convert the services up front, so none are registered if any of
+	// them can't be registered in the catalog.
+	var regs []*api.CatalogRegistration
+	if c.flagCatalog {
+		for _, svc := range svcs {
+			reg, err := services.CatalogRegistration(svc, c.flagNode, c.flagNodeAddress)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error: %s", err))
+				return 1
+			}
+			regs = append(regs, reg)
+		}
+	}
+
+	if c.flagDryRun {
+		for _, source := range sources {
+			c.UI.Output(fmt.Sprintf("# %s", source.Name))
+			c.UI.Output(source.Data)
+		}
+		return 0
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -95,6 +181,20 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if c.flagCatalog {
+		for _, reg := range regs {
+			if _, err := client.Catalog().Register(reg, nil); err != nil {
+				c.UI.Error(fmt.Sprintf("Error registering service %q: %s",
+					reg.Service.Service, err))
+				return 1
+			}
+
+			c.UI.Output(fmt.Sprintf("Registered service: %s (node %s)",
+				reg.Service.Service, reg.Node))
+		}
+		return 0
+	}
+
 	// Create all the services
 	for _, svc := range svcs {
 		if err := client.Agent().ServiceRegister(svc); err != nil {
@@ -129,5 +229,20 @@ Usage: consul services register [options] [FILE...]
 
       $ consul services register web.json
 
+  Services that can't run an agent can be registered in the catalog on an
+  external node instead, which is created with "external-node" meta. Their
+  HTTP and TCP checks are registered for an external prober such as
+  consul-esm to run, and other check types are rejected:
+
+      $ consul services register -catalog -node=db-host \
+          -node-address=10.0.0.5 db.json
+
+  Service definition files can be rendered as Go templates first, to
+  register many similar services. Use -dry-run to print the rendered
+  definitions instead of registering them:
+
+      $ consul services register -template -var name=web-1 -var port=8080 \
+          web.json
+
   Additional flags and more advanced use cases are detailed below.
 `
@@ -4,9 +4,11 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/agent"
 	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/testrpc"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/require"
 )
@@ -36,6 +38,22 @@ func TestCommand_Validation(t *testing.T) {
 			[]string{"-name", "web", "foo.json"},
 			"not both",
 		},
+		"-catalog without -node": {
+			[]string{"-catalog", "-name", "web"},
+			"requires -node and -node-address",
+		},
+		"-node without -catalog": {
+			[]string{"-node", "db-host", "-name", "web"},
+			"without -catalog",
+		},
+		"-template without files": {
+			[]string{"-template", "-name", "web"},
+			"at least one file argument",
+		},
+		"-var without -template": {
+			[]string{"-var", "port=8080", "foo.json"},
+			"without -template",
+		},
 	}
 
 	for name, tc := range cases {
@@ -92,6 +110,71 @@ func TestCommand_File(t *testing.T) {
 	require.NotNil(svc)
 }
 
+func TestCommand_Template(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	f := testFile(t, "json")
+	defer os.Remove(f.Name())
+	_, err := f.WriteString(`{ "Service": { "Name": "{{.name}}", "Port": {{.port}}, "Tags": ["{{.env}}"] } }`)
+	require.NoError(err)
+
+	vars := testFile(t, "json")
+	defer os.Remove(vars.Name())
+	_, err = vars.WriteString(`{ "name": "web-0", "port": 80, "env": "prod" }`)
+	require.NoError(err)
+
+	// A dry run prints the rendered definition without registering it.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-template",
+		"-var-file", vars.Name(),
+		"-var", "name=web-1",
+		"-var", "port=8080",
+		"-dry-run",
+		f.Name(),
+	}
+	require.Equal(0, c.Run(args), ui.ErrorWriter.String())
+	require.Contains(ui.OutputWriter.String(),
+		`{ "Service": { "Name": "web-1", "Port": 8080, "Tags": ["prod"] } }`)
+
+	svcs, err := client.Agent().Services()
+	require.NoError(err)
+	require.Len(svcs, 0)
+
+	// The -var flags take precedence over the variable file.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	args = append(args[:len(args)-2], f.Name())
+	require.Equal(0, c.Run(args), ui.ErrorWriter.String())
+
+	svcs, err = client.Agent().Services()
+	require.NoError(err)
+	require.Len(svcs, 1)
+	svc := svcs["web-1"]
+	require.NotNil(svc)
+	require.Equal(8080, svc.Port)
+	require.Equal([]string{"prod"}, svc.Tags)
+
+	// A variable that isn't defined is an error.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	args = []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-template",
+		"-var", "name=web-2",
+		f.Name(),
+	}
+	require.Equal(1, c.Run(args))
+	require.Contains(ui.ErrorWriter.String(), `map has no entry for key "port"`)
+}
+
 func TestCommand_Flags(t *testing.T) {
 	t.Parallel()
 
@@ -118,6 +201,85 @@ func TestCommand_Flags(t *testing.T) {
 	require.NotNil(svc)
 }
 
+func TestCommand_Catalog(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	contents := `{ "Service": { "Name": "db", "Port": 5432, "Check": { "Name": "tcp", "TCP": "10.0.0.5:5432", "Interval": "10s" } } }`
+	f := testFile(t, "json")
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-catalog",
+		"-node", "db-host",
+		"-node-address", "10.0.0.5",
+		f.Name(),
+	}
+
+	require.Equal(0, c.Run(args), ui.ErrorWriter.String())
+
+	// The service is on the external node, not the local agent.
+	svcs, err := client.Agent().Services()
+	require.NoError(err)
+	require.Empty(svcs)
+
+	node, _, err := client.Catalog().Node("db-host", nil)
+	require.NoError(err)
+	require.NotNil(node)
+	require.Equal("10.0.0.5", node.Node.Address)
+	require.Equal("true", node.Node.Meta["external-node"])
+	require.Equal("true", node.Node.Meta["external-probe"])
+	require.Contains(node.Services, "db")
+	require.Equal(5432, node.Services["db"].Port)
+
+	checks, _, err := client.Health().Checks("db", nil)
+	require.NoError(err)
+	require.Len(checks, 1)
+	require.Equal("service:db", checks[0].CheckID)
+	require.Equal("10.0.0.5:5432", checks[0].Definition.TCP)
+	require.Equal(10*time.Second, checks[0].Definition.IntervalDuration)
+}
+
+func TestCommand_Catalog_AgentCheck(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	contents := `{ "Service": { "Name": "db", "Check": { "Name": "script", "Args": ["/bin/true"], "Interval": "10s" } } }`
+	f := testFile(t, "json")
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+
+	// The check is rejected before any agent is contacted.
+	args := []string{
+		"-http-addr=127.0.0.1:0",
+		"-catalog",
+		"-node", "db-host",
+		"-node-address", "10.0.0.5",
+		f.Name(),
+	}
+
+	require.Equal(1, c.Run(args))
+	require.Contains(ui.ErrorWriter.String(), `Check "service:db": script checks require a local agent`)
+}
+
 func testFile(t *testing.T, suffix string) *os.File {
 	f := testutil.TempFile(t, "register-test-file")
 	if err := f.Close(); err != nil {
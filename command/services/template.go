@@ -0,0 +1,65 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
+
+	"github.com/hashicorp/consul/agent/config"
+)
+
+// RenderTemplates renders service definition files as Go templates with the
+// given variables, and returns the rendered files as config sources. Using a
+// variable that isn't defined is an error, so a typo doesn't render an empty
+// value.
+func RenderTemplates(files []string, vars map[string]interface{}) ([]config.Source, error) {
+	var sources []config.Source
+	for _, file := range files {
+		format := config.FormatFrom(file)
+		if format == "" {
+			return nil, fmt.Errorf("Template %s must have a .json or .hcl extension", file)
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading template %s: %s", file, err)
+		}
+		tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing template %s: %s", file, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("Error rendering template %s: %s", file, err)
+		}
+
+		sources = append(sources, config.Source{
+			Name:   file,
+			Format: format,
+			Data:   buf.String(),
+		})
+	}
+	return sources, nil
+}
+
+// TemplateVars returns the variables for RenderTemplates from a JSON object in
+// varFile, if it's set, and from the given values, which take precedence.
+func TemplateVars(varFile string, values map[string]string) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	if varFile != "" {
+		data, err := ioutil.ReadFile(varFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading variable file %s: %s", varFile, err)
+		}
+		if err := json.Unmarshal(data, &vars); err != nil {
+			return nil, fmt.Errorf("Error parsing variable file %s: %s", varFile, err)
+		}
+	}
+	for k, v := range values {
+		vars[k] = v
+	}
+	return vars, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+	dir := testutil.TempDir(t, "services-template")
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+	hcl := write("web.hcl", `service { name = "{{.name}}" port = {{.port}} }`)
+	varFile := write("vars.json", `{ "name": "web", "port": 80 }`)
+
+	vars, err := TemplateVars(varFile, map[string]string{"port": "8080"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "web", "port": "8080"}, vars)
+
+	sources, err := RenderTemplates([]string{hcl}, vars)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.Equal(t, hcl, sources[0].Name)
+	require.Equal(t, "hcl", sources[0].Format)
+	require.Equal(t, `service { name = "web" port = 8080 }`, sources[0].Data)
+
+	svcs, err := ServicesFromSources(sources)
+	require.NoError(t, err)
+	require.Len(t, svcs, 1)
+	require.Equal(t, "web", svcs[0].Name)
+	require.Equal(t, 8080, svcs[0].Port)
+
+	// The format comes from the extension.
+	_, err = RenderTemplates([]string{write("web.tpl", `{}`)}, vars)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must have a .json or .hcl extension")
+}
@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 
@@ -22,6 +23,8 @@ type cmd struct {
 	// format independent of their extension.
 	configFormat string
 	quiet        bool
+	lint         bool
+	format       string
 	help         string
 }
 
@@ -31,6 +34,12 @@ func (c *cmd) init() {
 		"Config files are in this format irrespective of their extension. Must be 'hcl' or 'json'")
 	c.flags.BoolVar(&c.quiet, "quiet", false,
 		"When given, a successful run will produce no output.")
+	c.flags.BoolVar(&c.lint, "lint", false,
+		"When given, also report the settings that are valid but are likely to be "+
+			"mistakes. The warnings don't change the exit code.")
+	c.flags.StringVar(&c.format, "format", "text",
+		"Output format of the -lint warnings. Must be 'text' or 'json'. With 'json', "+
+			"only the warnings are written to stdout, as a JSON array.")
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -51,15 +60,39 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if c.format != "text" && c.format != "json" {
+		c.UI.Error("-format must be either 'text' or 'json'")
+		return 1
+	}
+
 	b, err := config.NewBuilder(config.Flags{ConfigFiles: configFiles, ConfigFormat: &c.configFormat})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Config validation failed: %v", err.Error()))
 		return 1
 	}
-	if _, err := b.BuildAndValidate(); err != nil {
+	cfg, err := b.BuildAndValidate()
+	if err != nil {
 		c.UI.Error(fmt.Sprintf("Config validation failed: %v", err.Error()))
 		return 1
 	}
+
+	if c.lint {
+		warnings := config.Lint(&cfg)
+		if c.format == "json" {
+			// Use empty list instead of nil
+			out, err := json.MarshalIndent(append(make([]config.LintWarning, 0), warnings...), "", "  ")
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error encoding warnings: %s", err))
+				return 1
+			}
+			c.UI.Output(string(out))
+			return 0
+		}
+		for _, w := range warnings {
+			c.UI.Warn(w.String())
+		}
+	}
+
 	if !c.quiet {
 		c.UI.Output("Configuration is valid!")
 	}
@@ -88,5 +121,9 @@ Usage: consul validate [options] FILE_OR_DIRECTORY...
   to be loaded by the agent. This command cannot operate on partial
   configuration fragments since those won't pass the full agent validation.
 
+  With -lint, the settings that are valid but are likely to be mistakes are
+  reported as warnings, each with an ID that can be given to lint_suppress in
+  the configuration to silence it.
+
   Returns 0 if the configuration is valid, or 1 if there are problems.
 `
@@ -1,12 +1,14 @@
 package validate
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/sdk/testutil"
 	"github.com/mitchellh/cli"
 	require "github.com/stretchr/testify/require"
@@ -143,3 +145,50 @@ func TestValidateCommand_Quiet(t *testing.T) {
 	require.Equalf(t, 0, code, "return code - expected: 0, bad: %d, %s", code, ui.ErrorWriter.String())
 	require.Equal(t, "", ui.OutputWriter.String())
 }
+
+func TestValidateCommand_Lint(t *testing.T) {
+	t.Parallel()
+	td := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(td)
+
+	fp := filepath.Join(td, "config.hcl")
+	err := ioutil.WriteFile(fp, []byte(`
+bind_addr = "10.0.0.1"
+data_dir = "`+td+`"
+disable_remote_exec = false
+lint_suppress = ["data-dir-temporary"]
+`), 0644)
+	require.NoError(t, err)
+
+	t.Run("text", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{"-lint", fp})
+		require.Equal(t, 0, code)
+		require.Contains(t, ui.ErrorWriter.String(), "[remote-exec-enabled] ")
+		require.NotContains(t, ui.ErrorWriter.String(), "[data-dir-temporary]")
+		require.Contains(t, ui.OutputWriter.String(), "Configuration is valid!")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{"-lint", "-format=json", fp})
+		require.Equal(t, 0, code)
+
+		var warnings []config.LintWarning
+		require.NoError(t, json.Unmarshal(ui.OutputWriter.Bytes(), &warnings))
+		var ids []string
+		for _, w := range warnings {
+			ids = append(ids, w.ID)
+		}
+		require.Contains(t, ids, "remote-exec-enabled")
+		require.Contains(t, ids, "client-no-join")
+		require.NotContains(t, ids, "data-dir-temporary")
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{"-lint", "-format=yaml", fp})
+		require.Equal(t, 1, code)
+		require.Contains(t, ui.ErrorWriter.String(), "-format must be")
+	})
+}
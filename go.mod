@@ -4,6 +4,8 @@ go 1.12
 
 replace github.com/hashicorp/consul/api => ./api
 
+replace github.com/hashicorp/consul/api/subscribe => ./api/subscribe
+
 replace github.com/hashicorp/consul/sdk => ./sdk
 
 require (
@@ -52,6 +54,7 @@ require (
 	github.com/google/gofuzz v0.0.0-20170612174753-24818f796faf
 	github.com/gotestyourself/gotestyourself v2.2.0+incompatible // indirect
 	github.com/hashicorp/consul/api v1.0.1
+	github.com/hashicorp/consul/api/subscribe v0.0.0-00010101000000-000000000000
 	github.com/hashicorp/consul/sdk v0.1.0
 	github.com/hashicorp/go-bexpr v0.1.0
 	github.com/hashicorp/go-checkpoint v0.0.0-20171009173528-1545e56e46de
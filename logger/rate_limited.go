@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// DefaultRateLimitInterval is the interval used for the rate limited messages
+// of the agent.
+const DefaultRateLimitInterval = 10 * time.Second
+
+// maxRateLimitKeys bounds the number of keys a RateLimitedLogger tracks. The
+// keys are meant to be constants naming the kind of message, so this is only
+// a safeguard. Messages with keys beyond it are logged without a limit.
+const maxRateLimitKeys = 256
+
+// RateLimitedLogger limits how often messages are written to a logger, for
+// the hot paths that can log the same problem thousands of times per second.
+// The messages are grouped by a key naming their kind, see RateLimited.
+type RateLimitedLogger struct {
+	logger *log.Logger
+
+	lock   sync.Mutex
+	limits map[string]*RateLimit
+}
+
+// NewRateLimited returns a rate limited logger writing to the given logger.
+func NewRateLimited(logger *log.Logger) *RateLimitedLogger {
+	return &RateLimitedLogger{
+		logger: logger,
+		limits: make(map[string]*RateLimit),
+	}
+}
+
+// RateLimited returns the limit of the messages with the given key. The
+// first message is logged, the ones that follow within the interval are
+// suppressed, and a summary of how many were suppressed is logged at the end
+// of the interval. The key must be a constant rather than carry details of
+// the message, and labels the metric of the suppressed messages. The interval
+// is set by the first call for a key.
+func (r *RateLimitedLogger) RateLimited(key string, interval time.Duration) *RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if l, ok := r.limits[key]; ok {
+		return l
+	}
+
+	l := &RateLimit{
+		logger:   r.logger,
+		key:      key,
+		interval: interval,
+	}
+	if len(r.limits) < maxRateLimitKeys {
+		r.limits[key] = l
+	} else {
+		l.interval = 0
+	}
+	return l
+}
+
+// RateLimit limits how often the messages with a key are logged.
+type RateLimit struct {
+	logger   *log.Logger
+	key      string
+	interval time.Duration
+
+	lock sync.Mutex
+
+	// logged is when the last message or summary was logged.
+	logged time.Time
+
+	// suppressed is the number of messages suppressed since then, and
+	// message is the last of them. A summary is pending while it's
+	// greater than zero.
+	suppressed int
+	message    string
+}
+
+// Printf logs the message unless a message with the same key was logged
+// within the interval.
+func (l *RateLimit) Printf(format string, args ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if l.suppressed == 0 && now.Sub(l.logged) >= l.interval {
+		l.logger.Printf(format, args...)
+		l.logged = now
+		return
+	}
+
+	metrics.IncrCounterWithLabels([]string{"log", "suppressed"}, 1,
+		[]metrics.Label{{Name: "key", Value: l.key}})
+	l.message = fmt.Sprintf(format, args...)
+	l.suppressed++
+	if l.suppressed == 1 {
+		time.AfterFunc(l.logged.Add(l.interval).Sub(now), l.flush)
+	}
+}
+
+// flush logs how many messages were suppressed during the interval that
+// just ended. If messages keep coming, they're limited for another interval.
+func (l *RateLimit) flush() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	level := levelOf(l.message)
+	l.logger.Printf("%s logger: suppressed %d similar messages for %q in the last %s, the last one was: %s",
+		level, l.suppressed, l.key, l.interval, strings.TrimSpace(strings.TrimPrefix(l.message, level)))
+	l.logged = time.Now()
+	l.suppressed = 0
+	l.message = ""
+}
+
+// levelOf returns the level prefix of a message, such as "[ERR]", so the
+// summary is logged at the same level as the messages it stands for.
+func levelOf(message string) string {
+	if strings.HasPrefix(message, "[") {
+		if i := strings.Index(message, "]"); i > 0 {
+			return message[:i+1]
+		}
+	}
+	return "[WARN]"
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+)
+
+// syncBuffer is a buffer that can be written by the flush timer while the
+// test reads it.
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Lines() []string {
+	b.Lock()
+	defer b.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func TestRateLimited(t *testing.T) {
+	t.Parallel()
+
+	buf := &syncBuffer{}
+	r := NewRateLimited(log.New(buf, "", 0))
+
+	// The messages with the same key are limited even when they differ,
+	// while the ones with another key are still logged.
+	for i := 0; i < 5; i++ {
+		r.RateLimited("test.failed", 50*time.Millisecond).Printf("[ERR] test: failed %d", i)
+	}
+	r.RateLimited("test.other", 50*time.Millisecond).Printf("[ERR] test: other")
+
+	retry.Run(t, func(r *retry.R) {
+		lines := buf.Lines()
+		if len(lines) != 3 {
+			r.Fatalf("bad: %#v", lines)
+		}
+		if lines[0] != "[ERR] test: failed 0" || lines[1] != "[ERR] test: other" {
+			r.Fatalf("bad: %#v", lines)
+		}
+		if lines[2] != `[ERR] logger: suppressed 4 similar messages for "test.failed" in the last 50ms, the last one was: test: failed 4` {
+			r.Fatalf("bad: %q", lines[2])
+		}
+	})
+
+	// Once an interval passes without the message, it's logged straight
+	// away.
+	time.Sleep(100 * time.Millisecond)
+	r.RateLimited("test.failed", 50*time.Millisecond).Printf("[ERR] test: failed %d", 5)
+	lines := buf.Lines()
+	if lines[len(lines)-1] != "[ERR] test: failed 5" {
+		t.Fatalf("bad: %#v", lines)
+	}
+}
+
+func TestRateLimited_boundedKeys(t *testing.T) {
+	t.Parallel()
+
+	buf := &syncBuffer{}
+	r := NewRateLimited(log.New(buf, "", 0))
+
+	// Keys past the bound aren't tracked, and aren't limited either.
+	for i := 0; i < maxRateLimitKeys+10; i++ {
+		key := fmt.Sprintf("test.%d", i)
+		r.RateLimited(key, time.Hour).Printf("[ERR] test: %d", i)
+		r.RateLimited(key, time.Hour).Printf("[ERR] test: %d", i)
+	}
+	if len(r.limits) != maxRateLimitKeys {
+		t.Fatalf("bad: %d", len(r.limits))
+	}
+	if lines := buf.Lines(); len(lines) != maxRateLimitKeys+20 {
+		t.Fatalf("bad: %d", len(lines))
+	}
+}
+
+func TestRateLimited_perLogger(t *testing.T) {
+	t.Parallel()
+
+	buf1, buf2 := &syncBuffer{}, &syncBuffer{}
+	r1 := NewRateLimited(log.New(buf1, "", 0))
+	r2 := NewRateLimited(log.New(buf2, "", 0))
+
+	// The loggers don't limit each other's messages.
+	r1.RateLimited("test.failed", time.Hour).Printf("[ERR] test: failed")
+	r2.RateLimited("test.failed", time.Hour).Printf("[ERR] test: failed")
+	r1.RateLimited("test.failed", time.Hour).Printf("[ERR] test: failed")
+	if lines := buf1.Lines(); len(lines) != 1 {
+		t.Fatalf("bad: %#v", lines)
+	}
+	if lines := buf2.Lines(); len(lines) != 1 {
+		t.Fatalf("bad: %#v", lines)
+	}
+}
+
+func TestRateLimited_levelOf(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"[ERR] agent: failed":   "[ERR]",
+		"[DEBUG] agent: failed": "[DEBUG]",
+		"agent: failed":         "[WARN]",
+	}
+	for format, level := range cases {
+		if got := levelOf(format); got != level {
+			t.Fatalf("bad: %q for %q", got, format)
+		}
+	}
+}
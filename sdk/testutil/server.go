@@ -94,6 +94,7 @@ type TestServerConfig struct {
 	CAFile              string                 `json:"ca_file,omitempty"`
 	CertFile            string                 `json:"cert_file,omitempty"`
 	KeyFile             string                 `json:"key_file,omitempty"`
+	KVDeletedRetention  string                 `json:"kv_deleted_retention,omitempty"`
 	VerifyIncoming      bool                   `json:"verify_incoming,omitempty"`
 	VerifyIncomingRPC   bool                   `json:"verify_incoming_rpc,omitempty"`
 	VerifyIncomingHTTPS bool                   `json:"verify_incoming_https,omitempty"`
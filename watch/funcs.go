@@ -3,6 +3,8 @@ package watch
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
 )
@@ -27,6 +29,8 @@ func init() {
 		"connect_leaf":         connectLeafWatch,
 		"connect_proxy_config": connectProxyConfigWatch,
 		"agent_service":        agentServiceWatch,
+		"datacenters":          datacentersWatch,
+		"leader":               leaderWatch,
 	}
 }
 
@@ -255,7 +259,10 @@ func connectRootsWatch(params map[string]interface{}) (WatcherFunc, error) {
 }
 
 // connectLeafWatch is used to watch for changes to Connect Leaf certificates
-// for given local service id.
+// for given local service id. If before_expiry is set the handler is also
+// invoked once per certificate when it is within that duration of expiring,
+// even if the certificate didn't change, so that applications can reload it
+// preemptively.
 func connectLeafWatch(params map[string]interface{}) (WatcherFunc, error) {
 	// We don't support stale since certs are cached locally in the agent.
 
@@ -264,16 +271,57 @@ func connectLeafWatch(params map[string]interface{}) (WatcherFunc, error) {
 		return nil, err
 	}
 
+	var beforeExpiry time.Duration
+	if err := assignValueDuration(params, "before_expiry", &beforeExpiry); err != nil {
+		return nil, err
+	}
+
+	// These are only accessed from the watcher which is never run
+	// concurrently for a single plan.
+	var last *consulapi.LeafCert
+	var notifiedSerial string
+
 	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
 		agent := p.client.Agent()
 		opts := makeQueryOptionsWithContext(p, false)
 		defer p.cancelFunc()
 
+		var notifyAt time.Time
+		if beforeExpiry > 0 && last != nil && last.SerialNumber != notifiedSerial {
+			notifyAt = last.ValidBefore.Add(-beforeExpiry)
+		}
+
+		// Stop blocking when the expiry notification is due.
+		if !notifyAt.IsZero() {
+			if time.Now().Before(notifyAt) {
+				ctx, cancel := context.WithDeadline(opts.Context(), notifyAt)
+				defer cancel()
+				opts = *opts.WithContext(ctx)
+			} else {
+				opts.WaitIndex = 0
+			}
+		}
+
 		leaf, meta, err := agent.ConnectCALeaf(serviceName, &opts)
+		if err != nil && !notifyAt.IsZero() && !time.Now().Before(notifyAt) && !p.shouldStop() {
+			// The blocking query was cut short for the notification, fetch
+			// the current certificate without blocking.
+			opts := makeQueryOptionsWithContext(p, false)
+			defer p.cancelFunc()
+			opts.WaitIndex = 0
+			leaf, meta, err = agent.ConnectCALeaf(serviceName, &opts)
+		}
 		if err != nil {
 			return nil, nil, err
 		}
 
+		if !notifyAt.IsZero() && !time.Now().Before(notifyAt) &&
+			leaf.SerialNumber == last.SerialNumber {
+			notifiedSerial = leaf.SerialNumber
+			p.forceNotify = true
+		}
+		last = leaf
+
 		return WaitIndexVal(meta.LastIndex), leaf, err
 	}
 	return fn, nil
@@ -333,6 +381,205 @@ func agentServiceWatch(params map[string]interface{}) (WatcherFunc, error) {
 	return fn, nil
 }
 
+// DatacenterChanges is the result of a datacenters watch. Datacenters is the
+// current list of datacenters and the other fields hold the names of the
+// datacenters that changed since the handler was last invoked. Changed holds
+// the ones whose number of servers or failed servers changed while they
+// stayed reachable or unreachable.
+type DatacenterChanges struct {
+	Datacenters []*consulapi.DatacenterInfo
+	Added       []string
+	Removed     []string
+	Reachable   []string
+	Unreachable []string
+	Changed     []string
+}
+
+// datacenterTracker keeps the datacenters that have been in the same state for
+// at least the stable period, so that flapping datacenters don't trigger the
+// handler every time their servers miss a health check.
+type datacenterTracker struct {
+	stablePeriod time.Duration
+
+	// stable is nil until the first update.
+	stable map[string]*consulapi.DatacenterInfo
+
+	// pending holds the datacenters whose observed state differs from the
+	// stable one, and since when that state has been observed.
+	pending map[string]pendingDatacenter
+}
+
+type pendingDatacenter struct {
+	info  *consulapi.DatacenterInfo
+	since time.Time
+}
+
+// update records the datacenters observed at the given time. It returns the
+// changes and true if the stable state changed.
+func (t *datacenterTracker) update(dcs []*consulapi.DatacenterInfo, now time.Time) (*DatacenterChanges, bool) {
+	observed := make(map[string]*consulapi.DatacenterInfo, len(dcs))
+	for _, dc := range dcs {
+		observed[dc.Datacenter] = dc
+	}
+
+	var changes DatacenterChanges
+	if t.stable == nil {
+		t.stable = observed
+		t.pending = make(map[string]pendingDatacenter)
+		for _, dc := range dcs {
+			changes.Added = append(changes.Added, dc.Datacenter)
+		}
+		changes.Datacenters = t.datacenters()
+		return &changes, true
+	}
+
+	names := make(map[string]struct{})
+	for name := range observed {
+		names[name] = struct{}{}
+	}
+	for name := range t.stable {
+		names[name] = struct{}{}
+	}
+
+	changed := false
+	for name := range names {
+		cur, obs := t.stable[name], observed[name]
+		if sameDatacenterState(cur, obs) {
+			delete(t.pending, name)
+			continue
+		}
+
+		p, ok := t.pending[name]
+		if !ok || !sameDatacenterState(p.info, obs) {
+			p = pendingDatacenter{since: now}
+		}
+		p.info = obs
+		t.pending[name] = p
+		if now.Sub(p.since) < t.stablePeriod {
+			continue
+		}
+
+		delete(t.pending, name)
+		changed = true
+		switch {
+		case obs == nil:
+			delete(t.stable, name)
+			changes.Removed = append(changes.Removed, name)
+		case cur == nil:
+			t.stable[name] = obs
+			changes.Added = append(changes.Added, name)
+		case obs.Reachable == cur.Reachable:
+			t.stable[name] = obs
+			changes.Changed = append(changes.Changed, name)
+		case obs.Reachable:
+			t.stable[name] = obs
+			changes.Reachable = append(changes.Reachable, name)
+		default:
+			t.stable[name] = obs
+			changes.Unreachable = append(changes.Unreachable, name)
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Reachable)
+	sort.Strings(changes.Unreachable)
+	sort.Strings(changes.Changed)
+	changes.Datacenters = t.datacenters()
+	return &changes, true
+}
+
+// datacenters returns the stable datacenters sorted by name.
+func (t *datacenterTracker) datacenters() []*consulapi.DatacenterInfo {
+	out := make([]*consulapi.DatacenterInfo, 0, len(t.stable))
+	for _, dc := range t.stable {
+		out = append(out, dc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Datacenter < out[j].Datacenter })
+	return out
+}
+
+// sameDatacenterState returns true if a and b, either of which may be nil for
+// a missing datacenter, are in the same state as far as the watch is concerned.
+func sameDatacenterState(a, b *consulapi.DatacenterInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// datacentersWatch is used to watch for datacenters joining or leaving the WAN
+// and becoming reachable or unreachable. The datacenters list can't be blocked
+// on so it is polled, and a change must hold for stable_period before the
+// handler is invoked with it.
+func datacentersWatch(params map[string]interface{}) (WatcherFunc, error) {
+	// We don't support stale since the list doesn't come from the state store.
+
+	pollInterval := 10 * time.Second
+	if err := assignValueDuration(params, "poll_interval", &pollInterval); err != nil {
+		return nil, err
+	}
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("poll_interval must be positive")
+	}
+
+	tracker := &datacenterTracker{stablePeriod: 30 * time.Second}
+	if err := assignValueDuration(params, "stable_period", &tracker.stablePeriod); err != nil {
+		return nil, err
+	}
+
+	// These are only accessed from the watcher which is never run
+	// concurrently for a single plan. The index is incremented every time
+	// the handler needs to be invoked.
+	var index uint64
+	var last *DatacenterChanges
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		if index > 0 {
+			select {
+			case <-time.After(pollInterval):
+			case <-p.stopCh:
+				return WaitIndexVal(index), last, nil
+			}
+		}
+
+		dcs, err := p.client.Catalog().DatacentersDetailed()
+		if err != nil {
+			// Keep the index so the handler isn't invoked again with the
+			// same changes once the agent recovers.
+			return WaitIndexVal(index), nil, err
+		}
+
+		if changes, ok := tracker.update(dcs, time.Now()); ok {
+			index++
+			last = changes
+		}
+		return WaitIndexVal(index), last, nil
+	}
+	return fn, nil
+}
+
+// leaderWatch is used to watch the raft leader for changes
+func leaderWatch(params map[string]interface{}) (WatcherFunc, error) {
+	// We don't support stale since the leader doesn't come from the state
+	// store.
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		status := p.client.Status()
+		opts := makeQueryOptionsWithContext(p, false)
+		defer p.cancelFunc()
+		leader, meta, err := status.LeaderWithQueryOptions(&opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return WaitIndexVal(meta.LastIndex), leader, err
+	}
+	return fn, nil
+}
+
 func makeQueryOptionsWithContext(p *Plan, stale bool) consulapi.QueryOptions {
 	ctx, cancel := context.WithCancel(context.Background())
 	p.setCancelFunc(cancel)
@@ -664,6 +664,67 @@ func TestConnectLeafWatch(t *testing.T) {
 	wg.Wait()
 }
 
+func TestConnectLeafWatch_beforeExpiry(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Register a web service to get certs for
+	{
+		agent := a.Client().Agent()
+		reg := consulapi.AgentServiceRegistration{
+			ID:   "web",
+			Name: "web",
+			Port: 9090,
+		}
+		err := agent.ServiceRegister(&reg)
+		require.Nil(t, err)
+	}
+
+	// The default leaf TTL is well under a year so the expiry notification is
+	// due as soon as the first cert has been delivered.
+	invoke := make(chan *consulapi.LeafCert, 10)
+	plan := mustParse(t, `{"type":"connect_leaf", "service":"web", "before_expiry":"8760h"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		v, ok := raw.(*consulapi.LeafCert)
+		if !ok || v == nil {
+			return // ignore
+		}
+		invoke <- v
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(a.HTTPAddr()); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+
+	var certs []*consulapi.LeafCert
+	for i := 0; i < 2; i++ {
+		select {
+		case cert := <-invoke:
+			certs = append(certs, cert)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("handler not invoked")
+		}
+	}
+	require.Equal(t, certs[0].SerialNumber, certs[1].SerialNumber)
+
+	// The notification is only sent once per certificate.
+	select {
+	case <-invoke:
+		t.Fatalf("handler invoked again")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	plan.Stop()
+	wg.Wait()
+}
+
 func TestConnectProxyConfigWatch(t *testing.T) {
 	t.Parallel()
 	a := agent.NewTestAgent(t, t.Name(), `
@@ -793,6 +854,84 @@ func TestAgentServiceWatch(t *testing.T) {
 	wg.Wait()
 }
 
+func TestDatacentersWatch(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	invoke := makeInvokeCh()
+	plan := mustParse(t, `{"type":"datacenters", "poll_interval":"100ms"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		v, ok := raw.(*watch.DatacenterChanges)
+		if !ok || v == nil {
+			return // ignore
+		}
+		if len(v.Datacenters) != 1 || !v.Datacenters[0].Reachable {
+			invoke <- errBadContent
+			return
+		}
+		if len(v.Added) != 1 || v.Added[0] != "dc1" {
+			invoke <- errBadContent
+			return
+		}
+		invoke <- nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(a.HTTPAddr()); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+
+	if err := <-invoke; err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	plan.Stop()
+	wg.Wait()
+}
+
+func TestLeaderWatch(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t, t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	invoke := makeInvokeCh()
+	plan := mustParse(t, `{"type":"leader"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		v, ok := raw.(string)
+		if !ok || v == "" {
+			return // ignore
+		}
+		if idx == 0 {
+			invoke <- errBadContent
+			return
+		}
+		invoke <- nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(a.HTTPAddr()); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+
+	if err := <-invoke; err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	plan.Stop()
+	wg.Wait()
+}
+
 func mustParse(t *testing.T, q string) *watch.Plan {
 	t.Helper()
 	var params map[string]interface{}
@@ -97,15 +97,20 @@ OUTER:
 		// Clear the failures
 		failures = 0
 
+		// The watcher may ask for the handler to be run even though nothing
+		// changed, for example when a certificate is close to expiring.
+		force := p.forceNotify
+		p.forceNotify = false
+
 		// If the index is unchanged do nothing
-		if p.lastParamVal != nil && p.lastParamVal.Equal(blockParamVal) {
+		if !force && p.lastParamVal != nil && p.lastParamVal.Equal(blockParamVal) {
 			continue
 		}
 
 		// Update the index, look for change
 		oldParamVal := p.lastParamVal
 		p.lastParamVal = blockParamVal.Next(oldParamVal)
-		if oldParamVal != nil && reflect.DeepEqual(p.lastResult, result) {
+		if !force && oldParamVal != nil && reflect.DeepEqual(p.lastResult, result) {
 			continue
 		}
 
@@ -36,6 +36,10 @@ type Plan struct {
 	lastParamVal BlockingParamVal
 	lastResult   interface{}
 
+	// forceNotify is set by a watcher to have the handler invoked for the
+	// next result even if it is unchanged from the last one.
+	forceNotify bool
+
 	stop       bool
 	stopCh     chan struct{}
 	stopLock   sync.Mutex
@@ -233,6 +237,24 @@ func assignValueBool(params map[string]interface{}, name string, out *bool) erro
 	return nil
 }
 
+// assignValueDuration is used to extract a value ensuring it is a string
+// holding a valid duration
+func assignValueDuration(params map[string]interface{}, name string, out *time.Duration) error {
+	var raw string
+	if err := assignValue(params, name, &raw); err != nil {
+		return err
+	}
+	if raw == "" {
+		return nil
+	}
+	val, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("Failed to parse %s: %v", name, err)
+	}
+	*out = val
+	return nil
+}
+
 // Parse the 'http_handler_config' parameters
 func parseHttpHandlerConfig(configParams interface{}) (*HttpHandlerConfig, error) {
 	var config HttpHandlerConfig
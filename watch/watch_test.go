@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseBasic(t *testing.T) {
@@ -40,6 +44,57 @@ func TestParse_exempt(t *testing.T) {
 	}
 }
 
+func TestDatacenterTracker(t *testing.T) {
+	t.Parallel()
+	tracker := &datacenterTracker{stablePeriod: 30 * time.Second}
+	now := time.Now()
+	dc := func(name string, reachable bool) *consulapi.DatacenterInfo {
+		return &consulapi.DatacenterInfo{Datacenter: name, Servers: 1, Reachable: reachable}
+	}
+
+	// Everything is reported as added the first time.
+	changes, ok := tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc2", true)}, now)
+	require.True(t, ok)
+	require.Equal(t, []string{"dc1", "dc2"}, changes.Added)
+	require.Len(t, changes.Datacenters, 2)
+
+	// A datacenter flapping within the stable period isn't reported.
+	_, ok = tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc2", false)}, now.Add(time.Second))
+	require.False(t, ok)
+	_, ok = tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc2", true)}, now.Add(2*time.Second))
+	require.False(t, ok)
+	_, ok = tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc2", false)}, now.Add(3*time.Second))
+	require.False(t, ok)
+
+	// Once the state held long enough it's reported.
+	changes, ok = tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc2", false), dc("dc3", true)}, now.Add(33*time.Second))
+	require.True(t, ok)
+	require.Equal(t, []string{"dc2"}, changes.Unreachable)
+	require.Empty(t, changes.Added)
+	require.False(t, changes.Datacenters[1].Reachable)
+
+	changes, ok = tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc3", true)}, now.Add(63*time.Second))
+	require.True(t, ok)
+	require.Equal(t, []string{"dc3"}, changes.Added)
+	require.Empty(t, changes.Removed)
+
+	changes, ok = tracker.update([]*consulapi.DatacenterInfo{dc("dc1", true), dc("dc3", true)}, now.Add(94*time.Second))
+	require.True(t, ok)
+	require.Equal(t, []string{"dc2"}, changes.Removed)
+	require.Len(t, changes.Datacenters, 2)
+
+	// Server failures that don't change the reachability are reported too.
+	degraded := &consulapi.DatacenterInfo{Datacenter: "dc1", Servers: 3, FailedServers: 1, Reachable: true}
+	_, ok = tracker.update([]*consulapi.DatacenterInfo{degraded, dc("dc3", true)}, now.Add(95*time.Second))
+	require.False(t, ok)
+	changes, ok = tracker.update([]*consulapi.DatacenterInfo{degraded, dc("dc3", true)}, now.Add(125*time.Second))
+	require.True(t, ok)
+	require.Equal(t, []string{"dc1"}, changes.Changed)
+	require.Empty(t, changes.Reachable)
+	require.Empty(t, changes.Unreachable)
+	require.Equal(t, 1, changes.Datacenters[0].FailedServers)
+}
+
 func makeParams(t *testing.T, s string) map[string]interface{} {
 	var out map[string]interface{}
 	dec := json.NewDecoder(bytes.NewReader([]byte(s)))